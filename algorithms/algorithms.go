@@ -1,81 +1,68 @@
 package algorithms
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"sync"
+	"fmt"
 	"time"
 
+	"github.com/aleibovici/cryptopump/cache"
+	"github.com/aleibovici/cryptopump/engine"
 	"github.com/aleibovici/cryptopump/exchange"
 	"github.com/aleibovici/cryptopump/functions"
+	"github.com/aleibovici/cryptopump/guards"
 	"github.com/aleibovici/cryptopump/logger"
 	"github.com/aleibovici/cryptopump/markets"
-	"github.com/aleibovici/cryptopump/mysql"
+	"github.com/aleibovici/cryptopump/money"
+	"github.com/aleibovici/cryptopump/pause"
 	"github.com/aleibovici/cryptopump/plotter"
+	"github.com/aleibovici/cryptopump/risk"
+	"github.com/aleibovici/cryptopump/rules"
+	"github.com/aleibovici/cryptopump/schedule"
 	"github.com/aleibovici/cryptopump/threads"
+	"github.com/aleibovici/cryptopump/trades"
 	"github.com/aleibovici/cryptopump/types"
 
 	"github.com/adshao/go-binance/v2"
 )
 
-// Channel control goroutine channel operations
-type Channel struct {
-	name string
-}
+/* Maximum age of the order book used to size a BUY */
+const bookMaxAge = 5 * time.Second
 
-// Stop a websocket channel and decrease waiting group
-func (c Channel) Stop(
-	channel chan struct{},
-	wg *sync.WaitGroup,
-	configData *types.Config,
-	sessionData *types.Session) {
+/* Maximum number of orders a BUY is split into when buy_slippage_split is enabled */
+const maxSplitOrders = 5
 
-	logger.LogEntry{ /* Log Entry */
-		Config:   configData,
-		Market:   nil,
-		Session:  sessionData,
-		Order:    &types.Order{},
-		Message:  "Stopping Websocket channel" + c.name,
-		LogLevel: "DebugLevel",
-	}.Do()
+/* Wait between retries of an order write that failed */
+const faultRetry = 10 * time.Second
 
-	defer wg.Done()       /* Decrease waiting group upon completion */
-	channel <- struct{}{} /* Stop websocket channel */
-
-	logger.LogEntry{ /* Log Entry */
-		Config:   configData,
-		Market:   nil,
-		Session:  sessionData,
-		Order:    &types.Order{},
-		Message:  c.name + "stopped",
-		LogLevel: "DebugLevel",
-	}.Do()
-
-	return
-
-}
-
-// SetTrue set all goroutines to stop
-func (c Channel) SetTrue(sessionData *types.Session) {
+// Channel control goroutine channel operations
+type Channel struct{}
 
-	logger.LogEntry{ /* Log Entry */
-		Config:   nil,
-		Market:   nil,
-		Session:  sessionData,
-		Order:    &types.Order{},
-		Message:  "Setting all websocket channels to stop",
-		LogLevel: "DebugLevel",
-	}.Do()
+// Close stop a websocket channel without waiting for the channel to read the stop request when it already
+// stopped
+func (c Channel) Close(channel chan struct{}) {
 
-	sessionData.StopWs = true /* Set all goroutine channels to stop */
+	select {
+	case channel <- struct{}{}:
+	case <-time.After(time.Second):
+	}
 
 }
 
-/* Modify profit based on sell transaction count  */
+/* Modify profit based on sell transaction count, or on market volatility when adaptive mode is enabled */
 func calculateProfit(
 	configData *types.Config,
+	marketData *types.Market,
 	sessionData *types.Session) (profit float64) {
 
+	/* Adaptive mode: quiet markets take smaller, faster profits and volatile markets wait for larger moves */
+	if profit = markets.AdaptiveProfit(configData, marketData); profit > 0 {
+
+		return profit
+
+	}
+
 	profit = configData.ProfitMin
 
 	switch {
@@ -97,7 +84,8 @@ func calculateProfit(
 
 }
 
-// UpdatePendingOrders Routine to fill rogue and not up-to-date orders in the db and update
+// UpdatePendingOrders Routine to fill rogue and not up-to-date orders in the db and update. It runs on the owner
+// goroutine of the thread, after orders being placed by BuyTicker or SellTicker are completed.
 func UpdatePendingOrders(
 	configData *types.Config,
 	sessionData *types.Session) {
@@ -106,10 +94,10 @@ func UpdatePendingOrders(
 	var order types.Order
 	var orderStatus *types.Order
 
-	if order, err = mysql.GetOrderTransactionPending(sessionData); err != nil {
+	/* Pending orders are updated again on the next run once the database recovers */
+	if order, err = sessionData.Storage.GetOrderTransactionPending(context.Background(), sessionData); err != nil {
 
-		/* Cleanly exit ThreadID */
-		threads.Thread{}.Terminate(sessionData, functions.GetFunctionName()+" - "+err.Error())
+		return
 
 	}
 
@@ -125,7 +113,8 @@ func UpdatePendingOrders(
 		}
 
 		/* Update order status */
-		if err := mysql.UpdateOrder(
+		if err := sessionData.Storage.UpdateOrder(
+			context.Background(),
 			sessionData,
 			int64(orderStatus.OrderID),
 			orderStatus.CumulativeQuoteQuantity,
@@ -133,8 +122,225 @@ func UpdatePendingOrders(
 			orderStatus.Price,
 			string(orderStatus.Status)); err != nil {
 
-			/* Cleanly exit ThreadID */
-			threads.Thread{}.Terminate(sessionData, functions.GetFunctionName()+" - "+err.Error())
+			return
+
+		}
+
+		/* Complete the Thread transaction of a pending order that is now FILLED */
+		RecoverOrders(configData, sessionData)
+
+	}
+
+}
+
+// RecoverOrders Repair order sequences left half-written by a crash or a database error. A FILLED BUY with no
+// Thread transaction and no SELL gets its Thread transaction back, and the Thread transaction of a BUY sold by a
+// FILLED SELL is deleted. Runs when a thread resumes and after pending orders are updated, and returns the number
+// of Thread transactions repaired.
+func RecoverOrders(
+	configData *types.Config,
+	sessionData *types.Session) (repaired int) {
+
+	var err error
+	var missing, sold []types.Order
+
+	/* Orders may have been written by a previous run or another node, reload the cached thread reads */
+	cache.Invalidate(sessionData)
+
+	if missing, err = sessionData.Storage.GetThreadTransactionMissing(context.Background(), sessionData); err != nil {
+
+		return 0
+
+	}
+
+	if sold, err = sessionData.Storage.GetThreadTransactionSold(context.Background(), sessionData); err != nil {
+
+		return 0
+
+	}
+
+	if len(missing) == 0 && len(sold) == 0 {
+
+		return 0
+
+	}
+
+	if err = sessionData.Storage.OrderTx(context.Background(), sessionData, func(tx types.OrderTx) error {
+
+		for _, order := range missing {
+
+			if err := tx.SaveThreadTransaction(
+				int64(order.OrderID),
+				order.CumulativeQuoteQuantity,
+				order.Price,
+				order.ExecutedQuantity); err != nil {
+
+				return err
+
+			}
+
+		}
+
+		for _, order := range sold {
+
+			if err := tx.DeleteThreadTransactionByOrderID(order.OrderIDSource); err != nil {
+
+				return err
+
+			}
+
+		}
+
+		return nil
+
+	}); err != nil {
+
+		return 0
+
+	}
+
+	for _, order := range missing {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   configData,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{OrderID: order.OrderID, Price: order.Price},
+			Message:  fmt.Sprintf("RECOVERY - restored Thread transaction for BUY order %d", order.OrderID),
+			LogLevel: "InfoLevel",
+		}.Do()
+
+	}
+
+	for _, order := range sold {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   configData,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{OrderID: order.OrderID, OrderIDSource: order.OrderIDSource},
+			Message:  fmt.Sprintf("RECOVERY - deleted Thread transaction for BUY order %d sold by order %d", order.OrderIDSource, order.OrderID),
+			LogLevel: "InfoLevel",
+		}.Do()
+
+	}
+
+	return len(missing) + len(sold)
+
+}
+
+/* Retry saving an order filled on the exchange and not saved to the database (Session.Fault) every faultRetry.
+Once it is saved, order sequences are repaired and BUY and SELL decisions resume. */
+func recoverFault(
+	configData *types.Config,
+	marketData *types.Market,
+	sessionData *types.Session) {
+
+	fault := sessionData.Fault
+
+	if time.Since(fault.Retried) < faultRetry {
+
+		return
+
+	}
+
+	if err := sessionData.Storage.OrderTx(context.Background(), sessionData, fault.Write); err != nil {
+
+		fault.Retried = time.Now()
+
+		return
+
+	}
+
+	sessionData.Fault = nil
+
+	RecoverOrders(configData, sessionData)
+
+	sessionData.ThreadCount, _ = sessionData.Storage.GetThreadTransactionCount(context.Background(), sessionData)
+
+	logger.LogEntry{ /* Log Entry */
+		Config:   configData,
+		Market:   marketData,
+		Session:  sessionData,
+		Order:    &types.Order{OrderID: fault.OrderID},
+		Message:  fmt.Sprintf("DATABASE - order %d saved, trading resumed", fault.OrderID),
+		LogLevel: "InfoLevel",
+	}.Do()
+
+}
+
+/* Execute a BUY of buyQuantityFiat within buy_max_slippage. The expected slippage is estimated from
+the local order book. A larger BUY is capped to the quantity available within buy_max_slippage, or
+split into up to maxSplitOrders orders when buy_slippage_split is enabled. The orders are sized from one
+walk of the book and sent back to back, the book isn't updated while the owner goroutine sends them. */
+func buyLiquidity(
+	buyQuantityFiat float64,
+	configData *types.Config,
+	marketData *types.Market,
+	sessionData *types.Session) {
+
+	if configData.BuyMaxSlippage <= 0 {
+
+		exchange.BuyTicker(buyQuantityFiat, configData, marketData, sessionData)
+
+		return
+
+	}
+
+	if time.Since(marketData.Book.Time) > bookMaxAge {
+
+		sessionData.BuyDecisionTreeResult = "Order book not available"
+
+		return
+
+	}
+
+	quantities := []float64{buyQuantityFiat}
+
+	if bps, filled := markets.BuySlippage(marketData.Book, buyQuantityFiat); !filled || bps > configData.BuyMaxSlippage {
+
+		orders := 1
+		if configData.BuySlippageSplit {
+
+			orders = maxSplitOrders
+
+		}
+
+		quantities = markets.SplitBuy(marketData.Book, buyQuantityFiat, configData.BuyMaxSlippage, orders)
+
+		var total money.Decimal
+		for _, quantity := range quantities {
+
+			total = total.Add(money.New(quantity))
+
+		}
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   configData,
+			Market:   marketData,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  "BUY of " + functions.Float64ToStr(buyQuantityFiat, 2) + " limited to " + total.String(2) + " in " + fmt.Sprint(len(quantities)) + " orders by slippage (" + functions.Float64ToStr(bps, 1) + " bps)",
+			LogLevel: "DebugLevel",
+		}.Do()
+
+	}
+
+	if len(quantities) == 0 {
+
+		sessionData.BuyDecisionTreeResult = "Not enough liquidity"
+
+		return
+
+	}
+
+	for _, quantity := range quantities {
+
+		exchange.BuyTicker(quantity, configData, marketData, sessionData)
+
+		if sessionData.Fault != nil {
+
+			return
 
 		}
 
@@ -142,13 +348,93 @@ func UpdatePendingOrders(
 
 }
 
+/* Build the variables available to rule expressions. Order variables are only
+populated when an order is provided (SELL decisions). */
+func ruleVariables(
+	marketData *types.Market,
+	sessionData *types.Session,
+	order *types.Order) map[string]float64 {
+
+	variables := map[string]float64{
+		"price":                marketData.Price,
+		"rsi3":                 marketData.Rsi3,
+		"rsi7":                 marketData.Rsi7,
+		"rsi14":                marketData.Rsi14,
+		"macd":                 marketData.MACD,
+		"ma7":                  marketData.Ma7,
+		"ma14":                 marketData.Ma14,
+		"high24h":              marketData.PriceChangeStatsHighPrice,
+		"low24h":               marketData.PriceChangeStatsLowPrice,
+		"direction":            float64(marketData.Direction),
+		"volatility":           marketData.Volatility,
+		"imbalance":            marketData.BookImbalance,
+		"threadcount":          float64(sessionData.ThreadCount),
+		"selltransactioncount": sessionData.SellTransactionCount,
+		"fiatfunds":            sessionData.SymbolFiatFunds,
+		"symbolfunds":          sessionData.SymbolFunds,
+		"latency":              float64(sessionData.Latency),
+	}
+
+	if order != nil && order.Price > 0 {
+
+		variables["order_price"] = order.Price
+		variables["order_quantity"] = order.ExecutedQuantity
+		variables["order_age"] = time.Since(time.Unix(order.TransactTime/1000, 0)).Seconds()
+		variables["order_profit"] = (marketData.Price / order.Price) - 1
+
+	}
+
+	return variables
+
+}
+
+/* Evaluate a rule expression and return the reason when it blocks a decision */
+func isRuleAllowed(
+	expression *rules.Expression,
+	variables map[string]float64) (bool, string) {
+
+	is, blocked, err := expression.Eval(variables)
+	if err != nil {
+
+		return false, "Rule error: " + err.Error()
+
+	}
+
+	if !is {
+
+		return false, "Rule blocked: " + blocked
+
+	}
+
+	return true, ""
+
+}
+
+/* Validate a BUY with the risk manager and display the rule that tripped */
+func isRiskAllowed(
+	configData *types.Config,
+	sessionData *types.Session,
+	buyQuantityFiat float64) bool {
+
+	if !(risk.Manager{}).IsBuyAllowed(configData, sessionData, buyQuantityFiat) {
+
+		sessionData.BuyDecisionTreeResult = "Risk: " + sessionData.Risk.Reason
+
+		return false
+
+	}
+
+	return true
+
+}
+
 /* Check if ticker price lower than 24hs high price */
 func is24hsHighPrice(
 	configData *types.Config,
 	marketData *types.Market,
 	sessionData *types.Session) bool {
 
-	return marketData.Price >= (marketData.PriceChangeStatsHighPrice * (1 - configData.Buy24hsHighpriceEntry))
+	return money.New(marketData.Price).Cmp(money.Markup(marketData.PriceChangeStatsHighPrice, -configData.Buy24hsHighpriceEntry)) >= 0
 
 }
 
@@ -222,7 +508,8 @@ func isBuyUpmarket(
 
 	}
 
-	if lastOrderTransactionPrice, err = mysql.GetLastOrderTransactionPrice(
+	if lastOrderTransactionPrice, err = sessionData.Storage.GetLastOrderTransactionPrice(
+		context.Background(),
 		sessionData,
 		"SELL"); err != nil {
 
@@ -233,7 +520,7 @@ func isBuyUpmarket(
 	}
 
 	/* Test if event price is lower than last Sell price plus threshold up */
-	if marketData.Price < lastOrderTransactionPrice*(1+configData.BuyRepeatThresholdUp) {
+	if money.New(marketData.Price).Cmp(money.Markup(lastOrderTransactionPrice, configData.BuyRepeatThresholdUp)) < 0 {
 
 		sessionData.BuyDecisionTreeResult = "Upmarket price lower than last sale"
 
@@ -243,7 +530,7 @@ func isBuyUpmarket(
 
 	/* Retrieve the last transaction side and if it's a BUY exit.
 	This avoid double BUY on the UP side */
-	if lastOrderTransactionSide, err = mysql.GetLastOrderTransactionSide(sessionData); err != nil {
+	if lastOrderTransactionSide, err = sessionData.Storage.GetLastOrderTransactionSide(context.Background(), sessionData); err != nil {
 
 		sessionData.BuyDecisionTreeResult = "Error"
 
@@ -263,7 +550,7 @@ func isBuyUpmarket(
 	/* 	This function retrieve the next transaction from Thread database and verify that
 	the ticker price is not half profit close to the transaction.This function avoid multiple
 	upmarket buy close to each other. */
-	if order, err = mysql.GetThreadLastTransaction(sessionData); err != nil {
+	if order, err = sessionData.Storage.GetThreadLastTransaction(context.Background(), sessionData); err != nil {
 
 		sessionData.BuyDecisionTreeResult = "Error"
 
@@ -273,14 +560,14 @@ func isBuyUpmarket(
 
 	/* See comment above */
 	if marketData.Price > order.Price &&
-		marketData.Price < (order.Price*(1+(configData.ProfitMin/2))) {
+		money.New(marketData.Price).Cmp(money.Markup(order.Price, configData.ProfitMin/2)) < 0 {
 
 		sessionData.BuyDecisionTreeResult = "Target price too close to next target up"
 
 		return false, 0
 
 	} else if marketData.Price < order.Price &&
-		marketData.Price > (order.Price*(1-(configData.ProfitMin/2))) {
+		money.New(marketData.Price).Cmp(money.Markup(order.Price, -configData.ProfitMin/2)) > 0 {
 
 		sessionData.BuyDecisionTreeResult = "Target price too close to next target up"
 
@@ -290,9 +577,10 @@ func isBuyUpmarket(
 
 	/* 		This function retrieve the number of thread transactions with price bigger than current price times buy_repeat_threshold_up.
 	   		It servers the purpose of ensuring the algorithm does not buy above the biggest buy. If more more than 1 transaction will not execute buy. */
-	if threadTransactiontUpmarketPriceCount, err = mysql.GetThreadTransactiontUpmarketPriceCount(
+	if threadTransactiontUpmarketPriceCount, err = sessionData.Storage.GetThreadTransactiontUpmarketPriceCount(
+		context.Background(),
 		sessionData,
-		(marketData.Price * (1 + configData.BuyRepeatThresholdUp))); err != nil {
+		money.Markup(marketData.Price, configData.BuyRepeatThresholdUp).Float()); err != nil {
 
 		sessionData.BuyDecisionTreeResult = "Error"
 
@@ -372,9 +660,25 @@ func isBuyDownmarket(
 
 	}
 
-	/* Ensure funds are not deployed less than buy_repeat_threshold_down from each other */
+	/* Ensure funds are not deployed less than buy_repeat_threshold_down from each other.
+	In adaptive mode the threshold follows market volatility, spacing buys wider in volatile markets. */
 	buyRepeatThresholdDown := configData.BuyRepeatThresholdDown
-	if lastOrderTransactionPrice, err = mysql.GetLastOrderTransactionPrice(
+	buyRepeatThresholdDownSecond := configData.BuyRepeatThresholdDownSecond
+	if adaptive := markets.AdaptiveBuyRepeatThresholdDown(configData, marketData); adaptive > 0 {
+
+		/* Keep the configured proportion between the 2nd down and down thresholds */
+		if buyRepeatThresholdDown > 0 {
+
+			buyRepeatThresholdDownSecond = adaptive * (buyRepeatThresholdDownSecond / buyRepeatThresholdDown)
+
+		}
+
+		buyRepeatThresholdDown = adaptive
+
+	}
+
+	if lastOrderTransactionPrice, err = sessionData.Storage.GetLastOrderTransactionPrice(
+		context.Background(),
 		sessionData,
 		"BUY"); err != nil {
 
@@ -385,7 +689,7 @@ func isBuyDownmarket(
 	}
 
 	/* Test with with buy_repeat_threshold_down to reduce sql queries */
-	if marketData.Price > (lastOrderTransactionPrice * (1 - buyRepeatThresholdDown)) {
+	if money.New(marketData.Price).Cmp(money.Markup(lastOrderTransactionPrice, -buyRepeatThresholdDown)) > 0 {
 
 		sessionData.BuyDecisionTreeResult = "Threshold down not reached"
 
@@ -394,7 +698,7 @@ func isBuyDownmarket(
 	}
 
 	/* Change percentage if last and 2nd orders are BUY */
-	if side1, side2, err = mysql.GetOrderTransactionSideLastTwo(sessionData); err != nil {
+	if side1, side2, err = sessionData.Storage.GetOrderTransactionSideLastTwo(context.Background(), sessionData); err != nil {
 
 		sessionData.BuyDecisionTreeResult = "Error"
 
@@ -405,12 +709,12 @@ func isBuyDownmarket(
 	if side1 == "BUY" &&
 		side2 == "BUY" {
 
-		buyRepeatThresholdDown = configData.BuyRepeatThresholdDownSecond
+		buyRepeatThresholdDown = buyRepeatThresholdDownSecond
 
 	}
 
 	/* Test with new buy_repeat_threshold_down */
-	if marketData.Price > (lastOrderTransactionPrice * (1 - buyRepeatThresholdDown)) {
+	if money.New(marketData.Price).Cmp(money.Markup(lastOrderTransactionPrice, -buyRepeatThresholdDown)) > 0 {
 
 		sessionData.BuyDecisionTreeResult = "Threshold 2nd down not reached"
 
@@ -458,22 +762,203 @@ func isBuyInitial(
 
 	}
 
-	return false, 0
-
+	return false, 0
+
+}
+
+// WsUserDataServe Websocket routine to retrieve realtime user data. configData and sessionData are copies read by
+// the websocket goroutine, balances are updated on the owner goroutine e.
+func WsUserDataServe(
+	configData *types.Config,
+	sessionData *types.Session,
+	e *engine.Engine) {
+
+	var doneC chan struct{}
+	var stopC chan struct{}
+	var err error
+
+	/* Retrieve listen key for user stream service, kept alive by the owner goroutine */
+	if sessionData.ListenKey, err = exchange.GetUserStreamServiceListenKey(configData, sessionData); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   configData,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+	}
+
+	listenKey := sessionData.ListenKey
+	e.Post(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+		sessionData.ListenKey = listenKey
+	})
+
+	wsHandler := &types.WsHandler{}
+	wsHandler.BinanceWsUserDataServe = func(message []byte) {
+
+		now := time.Now()
+
+		/* User data events are handled in order */
+		e.Post(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+
+			/* This session variable stores the time of the last WsUserDataServe used for status check */
+			sessionData.LastWsUserDataServeTime = now
+
+			userData(configData, sessionData, message)
+
+		})
+
+	}
+
+	errHandler := func(err error) {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   configData,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+	}
+
+	for {
+
+		doneC, stopC, err = exchange.WsUserDataServe(configData, sessionData, wsHandler, errHandler) /* Start websocket channel */
+
+		if err != nil { /* If websocket channel is not connected */
+
+			threads.Thread{}.Terminate(sessionData, threads.ExitError, functions.GetFunctionName()+" - "+err.Error())
+
+			return
+
+		}
+
+		select {
+		case <-doneC:
+		case <-threads.Thread{}.Context(sessionData).Done():
+
+			Channel{}.Close(stopC) /* Stop websocket channel on shutdown */
+
+			return
+
+		}
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   configData,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + "websocket channel disconnected, trying to re-establish",
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		time.Sleep(time.Second / 3) /* Sleep for 3 seconds */
+
+	}
+
+}
+
+/* Handle a user data event on the owner goroutine */
+func userData(
+	configData *types.Config,
+	sessionData *types.Session,
+	message []byte) {
+
+	var executionReport = &types.ExecutionReport{}
+	var outboundAccountPosition = &types.OutboundAccountPosition{}
+
+	/* Unmarshal and process executionReport */
+	if err := json.Unmarshal(message, &executionReport); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   configData,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "InfoLevel",
+		}.Do()
+
+	} else if executionReport.EventType == "executionReport" {
+
+		return
+
+	}
+
+	/* Unmarshal and process outboundAccountPosition */
+	if err := json.Unmarshal(message, &outboundAccountPosition); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   configData,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "InfoLevel",
+		}.Do()
+
+	} else if outboundAccountPosition.EventType == "outboundAccountPosition" {
+
+		for key := range outboundAccountPosition.Balances {
+
+			if outboundAccountPosition.Balances[key].Asset == sessionData.SymbolFiat {
+
+				sessionData.SymbolFiatFunds = functions.StrToFloat64(outboundAccountPosition.Balances[key].Free)
+
+				_ = sessionData.Storage.UpdateSession(
+					context.Background(),
+					configData,
+					sessionData)
+
+			}
+
+			/* Update Available crypto funds in exchange */
+			if outboundAccountPosition.Balances[key].Asset == sessionData.Symbol[0:3] {
+
+				sessionData.SymbolFunds = functions.StrToFloat64(outboundAccountPosition.Balances[key].Free)
+
+			}
+
+		}
+
+	}
+
 }
 
-// WsUserDataServe Websocket routine to retrieve realtime user data
-func WsUserDataServe(
+// WsKline The Kline/Candlestick Stream push updates to the current klines/candlestick every second. configData
+// and sessionData are copies read by the websocket goroutine, market data is updated on the owner goroutine e.
+func WsKline(
 	configData *types.Config,
 	sessionData *types.Session,
-	wg *sync.WaitGroup) {
+	e *engine.Engine) {
 
 	var doneC chan struct{}
 	var stopC chan struct{}
 	var err error
 
-	/* Retrieve listen key for user stream service */
-	if sessionData.ListenKey, err = exchange.GetUserStreamServiceListenKey(configData, sessionData); err != nil {
+	wsHandler := &types.WsHandler{}
+	wsHandler.BinanceWsKline = func(event *binance.WsKlineEvent) {
+
+		now := time.Now()
+
+		/* Kline updates are handled in order, market direction counts every update */
+		e.Post(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+
+			/* This session variable stores the time of the last WsKline used for status check */
+			sessionData.LastWsKlineTime = now
+
+			kline(configData, marketData, sessionData, event)
+
+		})
+
+	}
+
+	errHandler := func(err error) {
 
 		logger.LogEntry{ /* Log Entry */
 			Config:   configData,
@@ -486,83 +971,119 @@ func WsUserDataServe(
 
 	}
 
-	wsHandler := &types.WsHandler{}
-	wsHandler.BinanceWsUserDataServe = func(message []byte) {
+	for {
 
-		/* This session variable stores the time of the last WsUserDataServe used for status check */
-		sessionData.LastWsUserDataServeTime = time.Now()
+		doneC, stopC, err = exchange.WsKlineServe(configData, sessionData, wsHandler, errHandler) /* Start websocket channel */
 
-		/* Stop Ws channel */
-		if sessionData.StopWs {
+		if err != nil { /* If websocket channel is not connected */
 
-			Channel{
-				name: "WsUserDataServe",
-			}.Stop(stopC, wg, configData, sessionData) /* Stop websocket channel */
+			threads.Thread{}.Terminate(sessionData, threads.ExitError, functions.GetFunctionName()+" - "+err.Error())
 
 			return
 
 		}
 
-		var executionReport = &types.ExecutionReport{}
-		var outboundAccountPosition = &types.OutboundAccountPosition{}
-
-		/* Unmarshal and process executionReport */
-		if err := json.Unmarshal(message, &executionReport); err != nil {
-
-			logger.LogEntry{ /* Log Entry */
-				Config:   configData,
-				Market:   nil,
-				Session:  sessionData,
-				Order:    &types.Order{},
-				Message:  functions.GetFunctionName() + " - " + err.Error(),
-				LogLevel: "InfoLevel",
-			}.Do()
+		select {
+		case <-doneC:
+		case <-threads.Thread{}.Context(sessionData).Done():
 
-		} else if executionReport.EventType == "executionReport" {
+			Channel{}.Close(stopC) /* Stop websocket channel on shutdown */
 
 			return
 
 		}
 
-		/* Unmarshal and process outboundAccountPosition */
-		if err := json.Unmarshal(message, &outboundAccountPosition); err != nil {
+		logger.LogEntry{ /* Log Entry */
+			Config:   configData,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + "websocket channel disconnected, trying to re-establish",
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		time.Sleep(time.Second / 3) /* Sleep for 3 seconds */
 
-			logger.LogEntry{ /* Log Entry */
-				Config:   configData,
-				Market:   nil,
-				Session:  sessionData,
-				Order:    &types.Order{},
-				Message:  functions.GetFunctionName() + " - " + err.Error(),
-				LogLevel: "InfoLevel",
-			}.Do()
+	}
+
+}
 
-		} else if outboundAccountPosition.EventType == "outboundAccountPosition" {
+/* Handle a kline update on the owner goroutine */
+func kline(
+	configData *types.Config,
+	marketData *types.Market,
+	sessionData *types.Session,
+	event *binance.WsKlineEvent) {
 
-			for key := range outboundAccountPosition.Balances {
+	/* Store the close of the current kline, used by the price jump guard */
+	marketData.KlineClose = functions.StrToFloat64(event.Kline.Close)
+	marketData.KlineCloseTime = time.Now()
 
-				if outboundAccountPosition.Balances[key].Asset == sessionData.SymbolFiat {
+	/* Analyse Volume kline direction and create marketData.Direction. 0 = SELL / 1+ BUY */
+	activeSellVolume := (functions.StrToFloat64(event.Kline.Volume) - functions.StrToFloat64(event.Kline.ActiveBuyVolume))
+	if activeSellVolume > functions.StrToFloat64(event.Kline.ActiveBuyVolume) {
 
-					sessionData.SymbolFiatFunds = functions.StrToFloat64(outboundAccountPosition.Balances[key].Free)
+		marketData.Direction = 0
 
-					_ = mysql.UpdateSession(
-						configData,
-						sessionData)
+	} else {
 
-				}
+		marketData.Direction++
 
-				/* Update Available crypto funds in exchange */
-				if outboundAccountPosition.Balances[key].Asset == sessionData.Symbol[0:3] {
+	}
 
-					sessionData.SymbolFunds = functions.StrToFloat64(outboundAccountPosition.Balances[key].Free)
+	if event.Kline.IsFinal {
 
-				}
+		/* Load Final kline for technical analysis */
+		markets.Data{
+			Kline: exchange.BinanceMapWsKline(event.Kline),
+		}.LoadKline(
+			configData,
+			sessionData,
+			marketData)
 
-			}
+		/* Load Final kline for e-chart plotting */
+		plotter.Data{
+			Kline: exchange.BinanceMapWsKline(event.Kline),
+		}.LoadKline(
+			sessionData,
+			marketData)
+
+	}
+
+}
+
+// WsPartialDepth The Partial Book Depth Stream push the top bids and asks of the order book every 100ms.
+// Each update replaces the local order book on the owner goroutine e, an update waiting for the owner is
+// dropped. configData and sessionData are copies read by the websocket goroutine.
+func WsPartialDepth(
+	configData *types.Config,
+	sessionData *types.Session,
+	e *engine.Engine) {
+
+	var doneC chan struct{}
+	var stopC chan struct{}
+	var err error
+
+	wsHandler := &types.WsHandler{}
+	wsHandler.BinanceWsPartialDepth = func(event *binance.WsPartialDepthEvent) {
+
+		if event == nil {
 
 			return
 
 		}
 
+		book := exchange.BinanceMapWsPartialDepth(event)
+
+		e.Update("WsPartialDepth", func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+
+			/* Load order book snapshot and book imbalance */
+			markets.Data{
+				Book: book,
+			}.LoadBook(marketData)
+
+		})
+
 	}
 
 	errHandler := func(err error) {
@@ -580,15 +1101,25 @@ func WsUserDataServe(
 
 	for {
 
-		doneC, stopC, err = exchange.WsUserDataServe(configData, sessionData, wsHandler, errHandler) /* Start websocket channel */
+		doneC, stopC, err = exchange.WsPartialDepthServe(configData, sessionData, wsHandler, errHandler) /* Start websocket channel */
 
 		if err != nil { /* If websocket channel is not connected */
 
-			panic(err) /* Panic */
+			threads.Thread{}.Terminate(sessionData, threads.ExitError, functions.GetFunctionName()+" - "+err.Error())
+
+			return
 
 		}
 
-		<-doneC
+		select {
+		case <-doneC:
+		case <-threads.Thread{}.Context(sessionData).Done():
+
+			Channel{}.Close(stopC) /* Stop websocket channel on shutdown */
+
+			return
+
+		}
 
 		logger.LogEntry{ /* Log Entry */
 			Config:   configData,
@@ -605,64 +1136,34 @@ func WsUserDataServe(
 
 }
 
-// WsKline The Kline/Candlestick Stream push updates to the current klines/candlestick every second.
-func WsKline(
+// WsBookTicker Pushes any update to the best bid or asks price or quantity in real-time for a specified symbol.
+// Buy and sell decisions are made on the owner goroutine e with the last update, updates received while an
+// order is placed are dropped. configData and sessionData are copies read by the websocket goroutine.
+func WsBookTicker(
 	configData *types.Config,
-	marketData *types.Market,
 	sessionData *types.Session,
-	wg *sync.WaitGroup) {
+	e *engine.Engine) {
 
 	var doneC chan struct{}
 	var stopC chan struct{}
 	var err error
 
 	wsHandler := &types.WsHandler{}
-	wsHandler.BinanceWsKline = func(event *binance.WsKlineEvent) {
-
-		/* This session variable stores the time of the last WsKline used for status check */
-		sessionData.LastWsKlineTime = time.Now()
-
-		/* Stop Ws channel */
-		if sessionData.StopWs {
-
-			Channel{
-				name: "WsKline",
-			}.Stop(stopC, wg, configData, sessionData) /* Stop websocket channel */
-
-			return
-
-		}
-
-		/* Analyse Volume kline direction and create marketData.Direction. 0 = SELL / 1+ BUY */
-		activeSellVolume := (functions.StrToFloat64(event.Kline.Volume) - functions.StrToFloat64(event.Kline.ActiveBuyVolume))
-		if activeSellVolume > functions.StrToFloat64(event.Kline.ActiveBuyVolume) {
-
-			marketData.Direction = 0
-
-		} else {
+	wsHandler.BinanceWsBookTicker = func(event *binance.WsBookTickerEvent) {
 
-			marketData.Direction++
+		/* Record requests-per-second increment used with github.com/paulbellamy/ratecounter */
+		sessionData.RateCounter.Incr(1)
 
-		}
+		now := time.Now()
 
-		if event.Kline.IsFinal {
+		e.Update("WsBookTicker", func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
 
-			/* Load Final kline for technical analysis */
-			markets.Data{
-				Kline: exchange.BinanceMapWsKline(event.Kline),
-			}.LoadKline(
-				configData,
-				sessionData,
-				marketData)
+			/* This session variable stores the time of the last WsBookTicker used for status check */
+			sessionData.LastWsBookTickerTime = now
 
-			/* Load Final kline for e-chart plotting */
-			plotter.Data{
-				Kline: exchange.BinanceMapWsKline(event.Kline),
-			}.LoadKline(
-				sessionData,
-				marketData)
+			bookTicker(configData, marketData, sessionData, event)
 
-		}
+		})
 
 	}
 
@@ -670,7 +1171,7 @@ func WsKline(
 
 		logger.LogEntry{ /* Log Entry */
 			Config:   configData,
-			Market:   marketData,
+			Market:   nil,
 			Session:  sessionData,
 			Order:    &types.Order{},
 			Message:  functions.GetFunctionName() + " - " + err.Error(),
@@ -681,19 +1182,29 @@ func WsKline(
 
 	for {
 
-		doneC, stopC, err = exchange.WsKlineServe(configData, sessionData, wsHandler, errHandler) /* Start websocket channel */
+		doneC, stopC, err = exchange.WsBookTickerServe(configData, sessionData, wsHandler, errHandler) /* Start websocket channel */
 
 		if err != nil { /* If websocket channel is not connected */
 
-			panic(err) /* Panic */
+			threads.Thread{}.Terminate(sessionData, threads.ExitError, functions.GetFunctionName()+" - "+err.Error())
+
+			return
 
 		}
 
-		<-doneC
+		select {
+		case <-doneC:
+		case <-threads.Thread{}.Context(sessionData).Done():
+
+			Channel{}.Close(stopC) /* Stop websocket channel on shutdown */
+
+			return
+
+		}
 
 		logger.LogEntry{ /* Log Entry */
 			Config:   configData,
-			Market:   marketData,
+			Market:   nil,
 			Session:  sessionData,
 			Order:    &types.Order{},
 			Message:  functions.GetFunctionName() + " - " + "websocket channel disconnected, trying to re-establish",
@@ -706,143 +1217,143 @@ func WsKline(
 
 }
 
-// WsBookTicker Pushes any update to the best bid or asks price or quantity in real-time for a specified symbol
-func WsBookTicker(
-	viperData *types.ViperData,
+/* Run the buy and sell decisions for a book ticker update on the owner goroutine */
+func bookTicker(
 	configData *types.Config,
 	marketData *types.Market,
 	sessionData *types.Session,
-	wg *sync.WaitGroup) {
+	event *binance.WsBookTickerEvent) {
 
-	var doneC chan struct{}
-	var stopC chan struct{}
-	var err error
+	/* No new orders once a shutdown is requested */
+	if (threads.Thread{}).Stopping(sessionData) {
 
-	wsHandler := &types.WsHandler{}
-	wsHandler.BinanceWsBookTicker = func(event *binance.WsBookTickerEvent) {
+		return
 
-		/* Record requests-per-second increment used with github.com/paulbellamy/ratecounter */
-		sessionData.RateCounter.Incr(1)
+	}
 
-		/* This session variable stores the time of the last WsBookTicker used for status check */
-		sessionData.LastWsBookTickerTime = time.Now()
+	/* If there are 0 ThreadID transactions and configData.Exit is True the ThreadID is gracefully
+	finalized, and the ThreadID is unlocked. */
+	if sessionData.ThreadCount == 0 &&
+		configData.Exit {
 
-		/* Stop Ws channel */
-		if sessionData.StopWs {
+		/* Delete configuration file for ThreadID */
+		functions.DeleteConfigFile(sessionData)
 
-			Channel{
-				name: "WsBookTicker",
-			}.Stop(stopC, wg, configData, sessionData) /* Stop websocket channel */
+		/* Cleanly exit ThreadID */
+		threads.Thread{}.Terminate(sessionData, threads.ExitOK, "Exit - no Thread transactions left")
 
-			return
+		return
 
-		}
+	}
 
-		/* If there are 0 ThreadID transactions and configData.Exit is True the ThreadID is gracefully
-		finalized, and the ThreadID is unlocked. */
-		if sessionData.ThreadCount == 0 &&
-			configData.Exit {
+	/* Test if event or event.BestAskPrice or marketData are empty or nil before proceeding.
+	This test tries to prevent errors where multiple BUYS are executed in a row.
+	The source of the problem is unknown but it might be caused by nil data in the event or market data. */
+	if event == nil || event.BestAskPrice == "" || marketData == nil {
 
-			/* Delete configuration file for ThreadID */
-			functions.DeleteConfigFile(sessionData)
+		return
 
-			/* Cleanly exit ThreadID */
-			threads.Thread{}.Terminate(sessionData, functions.GetFunctionName()+" - "+err.Error())
+	}
 
-		}
+	marketData.Price = functions.StrToFloat64(event.BestAskPrice) /* Add current BestAskPrice to marketData struct for wide system use */
+	marketData.BestAsk = functions.StrToFloat64(event.BestAskPrice)
+	marketData.BestBid = functions.StrToFloat64(event.BestBidPrice)
 
-		/* Test if event or event.BestAskPrice or marketData are empty or nil before proceeding.
-		This test tries to prevent errors where multiple BUYS are executed in a row.
-		The source of the problem is unknown but it might be caused by nil data in the event or market data. */
-		if event == nil || event.BestAskPrice == "" || marketData == nil {
+	/* Retry saving an order not saved to the database, decisions are blocked until it is saved */
+	if sessionData.Fault != nil {
 
-			return
+		recoverFault(configData, marketData, sessionData)
 
-		}
+	}
+
+	/* Pre-trade guard blocks BUY and SELL on abnormal market data. Force sell is not blocked. */
+	if !sessionData.ForceSell &&
+		!(guards.Guard{}).IsTradeAllowed(configData, marketData, sessionData) {
 
-		marketData.Price = functions.StrToFloat64(event.BestAskPrice) /* Add current BestAskPrice to marketData struct for wide system use */
+		sessionData.BuyDecisionTreeResult = "Guard: " + sessionData.Guard.Reason
+		sessionData.SellDecisionTreeResult = "Guard: " + sessionData.Guard.Reason
+
+	} else if is, buyQuantityFiat := BuyDecisionTree( /* Execute decision algorithms for buy and sell */
+		configData,
+		marketData,
+		sessionData); is {
 
-		/* Execute decision algorithms for buy and sell */
-		if is, buyQuantityFiat := BuyDecisionTree(
+		buyLiquidity(
+			buyQuantityFiat,
 			configData,
 			marketData,
-			sessionData); is {
+			sessionData)
 
-			exchange.BuyTicker(
-				buyQuantityFiat,
-				configData,
-				marketData,
-				sessionData)
+		/* Update ThreadCount after BUY */
+		sessionData.ThreadCount, _ = sessionData.Storage.GetThreadTransactionCount(context.Background(), sessionData)
+
+	} else if is, order := SellDecisionTree(
+		configData,
+		marketData,
+		sessionData); is {
 
-			/* Update ThreadCount after BUY */
-			sessionData.ThreadCount, err = mysql.GetThreadTransactionCount(sessionData)
+		threadCount := sessionData.ThreadCount
 
-		} else if is, order := SellDecisionTree(
+		if err := exchange.SellTicker(
+			order,
 			configData,
 			marketData,
-			sessionData); is {
-
-			exchange.SellTicker(
-				order,
-				configData,
-				marketData,
-				sessionData)
+			sessionData); err != nil {
 
-			/* Update ThreadCount after SELL */
-			sessionData.ThreadCount, err = mysql.GetThreadTransactionCount(sessionData)
+			threads.Thread{}.Terminate(sessionData, threads.ExitError, functions.GetFunctionName()+" - "+err.Error())
 
-			/* Update Number of Sale Transactions per hour */
-			sessionData.SellTransactionCount, err = mysql.GetOrderTransactionCount(sessionData, "SELL")
+			return
 
 		}
 
-		/* Reload config data every 10 seconds */
-		if time.Now().Second()%10 == 0 {
-
-			configData = functions.GetConfigData(viperData, sessionData)
+		/* Update ThreadCount after SELL */
+		sessionData.ThreadCount, _ = sessionData.Storage.GetThreadTransactionCount(context.Background(), sessionData)
 
-		}
+		/* Record filled sales with the risk manager for stop-out cooldown and daily loss */
+		if sessionData.ThreadCount < threadCount {
 
-	}
+			risk.Manager{}.Sold(
+				configData,
+				sessionData,
+				sessionData.SellDecisionTreeResult == "Stoploss sale",
+				marketData.Price > order.Price)
 
-	errHandler := func(err error) {
+		}
 
-		logger.LogEntry{ /* Log Entry */
-			Config:   configData,
-			Market:   marketData,
-			Session:  sessionData,
-			Order:    &types.Order{},
-			Message:  functions.GetFunctionName() + " - " + err.Error(),
-			LogLevel: "DebugLevel",
-		}.Do()
+		/* Update Number of Sale Transactions per hour */
+		sessionData.SellTransactionCount, _ = sessionData.Storage.GetOrderTransactionCount(context.Background(), sessionData, "SELL")
 
 	}
 
-	for {
-
-		doneC, stopC, err = exchange.WsBookTickerServe(configData, sessionData, wsHandler, errHandler) /* Start websocket channel */
+}
 
-		if err != nil { /* If websocket channel is not connected */
+// Return the behavior of the trading schedule now and the entry setting it. Transitions are logged with the
+// next transition.
+func scheduleMode(
+	configData *types.Config,
+	marketData *types.Market,
+	sessionData *types.Session) (mode string, reason string) {
 
-			panic(err) /* Panic */
+	mode, reason = configData.ScheduleSpec.Mode(time.Now())
 
-		}
+	if configData.ScheduleSpec != nil &&
+		(mode != sessionData.Schedule.Mode || reason != sessionData.Schedule.Reason) {
 
-		<-doneC
+		sessionData.Schedule = configData.ScheduleSpec.At(time.Now())
 
 		logger.LogEntry{ /* Log Entry */
 			Config:   configData,
 			Market:   marketData,
 			Session:  sessionData,
 			Order:    &types.Order{},
-			Message:  functions.GetFunctionName() + " - " + "websocket channel disconnected, trying to re-establish",
-			LogLevel: "DebugLevel",
+			Message:  "SCHEDULE - " + sessionData.Schedule.String(),
+			LogLevel: "InfoLevel",
 		}.Do()
 
-		time.Sleep(time.Second / 3) /* Sleep for 3 seconds */
-
 	}
 
+	return mode, reason
+
 }
 
 // BuyDecisionTree BUY decision routine
@@ -858,6 +1369,26 @@ func BuyDecisionTree(
 
 	}
 
+	/* An order not saved to the database stops trading until it is saved, Force Buy included */
+	if sessionData.Fault != nil {
+
+		sessionData.ForceBuy = false
+		sessionData.BuyDecisionTreeResult = "Fault: " + sessionData.Fault.Reason
+
+		return false, 0
+
+	}
+
+	/* A paused thread makes no BUY, Force Buy included */
+	if !(pause.Control{}).IsBuyAllowed(sessionData) {
+
+		sessionData.ForceBuy = false
+		sessionData.BuyDecisionTreeResult = pause.Describe(sessionData.Pause)
+
+		return false, 0
+
+	}
+
 	/* Validate available funds to buy */
 	if !functions.IsFundsAvailable(
 		configData,
@@ -869,11 +1400,17 @@ func BuyDecisionTree(
 
 	}
 
-	/* Trigger Force Buy */
+	/* Trigger Force Buy. Force Buy must also pass the risk manager. */
 	if sessionData.ForceBuy {
 
 		sessionData.ForceBuy = false
 
+		if !isRiskAllowed(configData, sessionData, configData.BuyQuantityFiatInit) {
+
+			return false, 0
+
+		}
+
 		return true, configData.BuyQuantityFiatInit
 
 	}
@@ -887,6 +1424,24 @@ func BuyDecisionTree(
 
 	}
 
+	/* An invalid schedule stops BUY until the configuration is fixed */
+	if configData.ScheduleError != "" {
+
+		sessionData.BuyDecisionTreeResult = "Schedule error: " + configData.ScheduleError
+
+		return false, 0
+
+	}
+
+	/* BUY only in full trading windows of the schedule */
+	if mode, reason := scheduleMode(configData, marketData, sessionData); mode != schedule.ModeTrade {
+
+		sessionData.BuyDecisionTreeResult = "Schedule: " + schedule.Status{Mode: mode, Reason: reason}.String()
+
+		return false, 0
+
+	}
+
 	/* Validate marketData not older than 100 seconds */
 	if time.Since(marketData.TimeStamp).Seconds() > 100 {
 
@@ -918,6 +1473,26 @@ func BuyDecisionTree(
 
 	}
 
+	/* An invalid buy_rules expression stops BUY until the configuration is fixed */
+	if configData.BuyRules != "" && configData.BuyRulesExpression == nil {
+
+		sessionData.BuyDecisionTreeResult = "Rule error: " + configData.RulesError
+
+		return false, 0
+
+	}
+
+	/* Validate buy_rules expression. The built-in decision tree below must also agree to BUY. */
+	if is, reason := isRuleAllowed(
+		configData.BuyRulesExpression,
+		ruleVariables(marketData, sessionData, nil)); !is {
+
+		sessionData.BuyDecisionTreeResult = reason
+
+		return false, 0
+
+	}
+
 	/* Check for subsequent BUY */
 	if sessionData.ThreadCount > 0 {
 
@@ -927,7 +1502,7 @@ func BuyDecisionTree(
 			marketData,
 			sessionData); is {
 
-			return true, buyQuantityFiat
+			return isRiskAllowed(configData, sessionData, buyQuantityFiat), buyQuantityFiat
 
 		}
 
@@ -937,7 +1512,7 @@ func BuyDecisionTree(
 			marketData,
 			sessionData); is {
 
-			return true, buyQuantityFiat
+			return isRiskAllowed(configData, sessionData, buyQuantityFiat), buyQuantityFiat
 
 		}
 
@@ -954,7 +1529,7 @@ func BuyDecisionTree(
 			marketData,
 			sessionData); is {
 
-			return true, buyQuantityFiat
+			return isRiskAllowed(configData, sessionData, buyQuantityFiat), buyQuantityFiat
 
 		}
 
@@ -974,6 +1549,17 @@ func SellDecisionTree(
 
 	var err error
 
+	/* An order not saved to the database stops trading until it is saved, Force Sell included */
+	if sessionData.Fault != nil {
+
+		sessionData.ForceSell = false
+		sessionData.ForceSellOrderID = 0
+		sessionData.SellDecisionTreeResult = "Fault: " + sessionData.Fault.Reason
+
+		return false, order
+
+	}
+
 	/* Return false if no transactions found */
 	if sessionData.ThreadCount == 0 {
 
@@ -981,24 +1567,46 @@ func SellDecisionTree(
 
 	}
 
+	/* A frozen thread makes no SELL, Force Sell included */
+	if !(pause.Control{}).IsSellAllowed(sessionData) {
+
+		sessionData.ForceSell = false
+		sessionData.ForceSellOrderID = 0
+		sessionData.SellDecisionTreeResult = pause.Describe(sessionData.Pause)
+
+		return false, order
+
+	}
+
 	/* Check for Force Sell */
 	if sessionData.ForceSell {
 
 		if sessionData.ForceSellOrderID != 0 { /* Force sell a specific orderID */
 
-			order, err = mysql.GetOrderByOrderID(sessionData) /* Get order details */
-			sessionData.ForceSellOrderID = 0                  /* Clear Force sell OrderID */
+			order, err = sessionData.Storage.GetOrderByOrderID(context.Background(), sessionData) /* Get order details */
+			sessionData.ForceSellOrderID = 0                                                      /* Clear Force sell OrderID */
+			sessionData.ExitReason = trades.ExitForce
 			return true, order
 
 		} else if sessionData.ForceSellOrderID == 0 { /* Force Sell Most recent open order*/
 
-			order, err = mysql.GetThreadLastTransaction(sessionData) /* Get order details */
+			order, err = sessionData.Storage.GetThreadLastTransaction(context.Background(), sessionData) /* Get order details */
+			sessionData.ExitReason = trades.ExitForce
 			return true, order
 
 		}
 
 	}
 
+	/* No SELL in paused windows of the schedule */
+	if mode, reason := scheduleMode(configData, marketData, sessionData); mode == schedule.ModePaused {
+
+		sessionData.SellDecisionTreeResult = "Schedule: " + schedule.Status{Mode: mode, Reason: reason}.String()
+
+		return false, order
+
+	}
+
 	/* Validate marketData is not older than 100 seconds */
 	if time.Since(marketData.TimeStamp).Seconds() > 100 {
 
@@ -1023,14 +1631,16 @@ func SellDecisionTree(
 	if !configData.Exit && /* Doesn't force sell if system is in Exit mode */
 		configData.SellToCover { /* Doesn't force sell if SellToCover is False */
 
-		if (sessionData.SymbolFiatFunds - configData.SymbolFiatStash) < configData.BuyQuantityFiatDown {
+		if money.New(sessionData.SymbolFiatFunds).Sub(money.New(configData.SymbolFiatStash)).Cmp(money.New(configData.BuyQuantityFiatDown)) < 0 {
 
 			/* Retrieve the last 'active' BUY transaction for a Thread */
-			order, err = mysql.GetThreadLastTransaction(sessionData)
+			order, err = sessionData.Storage.GetThreadLastTransaction(context.Background(), sessionData)
 
-			if marketData.Price < (order.Price * (1 - configData.BuyRepeatThresholdDown)) {
+			if money.New(marketData.Price).Cmp(money.Markup(order.Price, -configData.BuyRepeatThresholdDown)) < 0 &&
+				(risk.Manager{}).IsSellToCoverAllowed(configData, sessionData) {
 
 				sessionData.SellDecisionTreeResult = "Attempting cover sale"
+				sessionData.ExitReason = trades.ExitCover
 
 				return true, order
 
@@ -1043,8 +1653,8 @@ func SellDecisionTree(
 	Returns the highert Thread order above marketData.Price treshold.*/
 	if configData.Stoploss > 0 {
 
-		if order, err := mysql.GetThreadTransactionByPriceHigher(marketData, sessionData); err == nil &&
-			(marketData.Price <= (order.Price * (1 - configData.Stoploss))) {
+		if order, err := sessionData.Storage.GetThreadTransactionByPriceHigher(context.Background(), marketData, sessionData); err == nil &&
+			money.New(marketData.Price).Cmp(money.Markup(order.Price, -configData.Stoploss)) <= 0 {
 
 			logger.LogEntry{ /* Log Entry */
 				Config:   configData,
@@ -1056,6 +1666,7 @@ func SellDecisionTree(
 			}.Do()
 
 			sessionData.SellDecisionTreeResult = "Stoploss sale"
+			sessionData.ExitReason = trades.ExitStoploss
 
 			return true, order
 
@@ -1064,7 +1675,7 @@ func SellDecisionTree(
 	}
 
 	/* Retrieve lowest price order from Thread database */
-	if order, err = mysql.GetThreadTransactionByPrice(marketData, sessionData); err != nil {
+	if order, err = sessionData.Storage.GetThreadTransactionByPrice(context.Background(), marketData, sessionData); err != nil {
 
 		sessionData.SellDecisionTreeResult = "Error"
 
@@ -1099,7 +1710,12 @@ func SellDecisionTree(
 
 		sessionData.SellDecisionTreeResult = "Not enough symbol funds to execute sale"
 
-		if !configData.Exit { /* Doesn't force buy if system is in Exit mode */
+		mode, _ := scheduleMode(configData, marketData, sessionData)
+
+		if !configData.Exit && /* Doesn't force buy if system is in Exit mode */
+			(pause.Control{}).IsBuyAllowed(sessionData) && /* Doesn't buy if the thread is paused */
+			mode == schedule.ModeTrade && /* Doesn't buy in sell-only windows and blackouts of the schedule */
+			(risk.Manager{}).IsBuyAllowed(configData, sessionData, configData.BuyQuantityFiatInit) {
 
 			exchange.BuyTicker(
 				configData.BuyQuantityFiatInit,
@@ -1117,8 +1733,8 @@ func SellDecisionTree(
 
 	/* Current price is higher than BUY price + profits */
 	/* Modify profit based on sell transaction count  */
-	if (marketData.Price*(1+configData.ExchangeComission)) >=
-		(order.Price*(1+calculateProfit(configData, sessionData))) &&
+	if money.Markup(marketData.Price, configData.ExchangeComission).Cmp(
+		money.Markup(order.Price, calculateProfit(configData, marketData, sessionData))) >= 0 &&
 		order.OrderID != 0 {
 
 		/* Hold sale if RSI3 above defined threshold.
@@ -1131,7 +1747,29 @@ func SellDecisionTree(
 
 		}
 
+		/* An invalid sell_rules expression holds profit sales until the configuration is fixed */
+		if configData.SellRules != "" && configData.SellRulesExpression == nil {
+
+			sessionData.SellDecisionTreeResult = "Rule error: " + configData.RulesError
+
+			return false, order
+
+		}
+
+		/* Validate sell_rules expression. Only profit sales are subject to sell_rules;
+		force sell, sell-to-cover and stoploss are not held by rules. */
+		if is, reason := isRuleAllowed(
+			configData.SellRulesExpression,
+			ruleVariables(marketData, sessionData, &order)); !is {
+
+			sessionData.SellDecisionTreeResult = reason
+
+			return false, order
+
+		}
+
 		sessionData.SellDecisionTreeResult = "Attemtping profit sale"
+		sessionData.ExitReason = trades.ExitProfit
 
 		return true, order
 
@@ -0,0 +1,330 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+	"github.com/aleibovici/cryptopump/pause"
+	"github.com/aleibovici/cryptopump/rules"
+	"github.com/aleibovici/cryptopump/schedule"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+/* Storage backend with one open Thread transaction. Order writes fail while down is set. */
+type fakeStorage struct {
+	types.Storage
+	order  types.Order
+	down   bool
+	writes int
+}
+
+func (f *fakeStorage) OrderTx(ctx context.Context, sessionData *types.Session, fn func(tx types.OrderTx) error) error {
+
+	if f.down {
+		return errors.New("database is down")
+	}
+
+	f.writes++
+
+	return fn(nopTx{})
+
+}
+
+/* Order writes doing nothing */
+type nopTx struct{}
+
+func (nopTx) SaveOrder(order *types.Order, orderIDSource int64, orderPrice float64) error { return nil }
+
+func (nopTx) UpdateOrder(OrderID int64, CumulativeQuoteQuantity float64, ExecutedQuantity float64, Price float64, Status string) error {
+	return nil
+}
+
+func (nopTx) SaveThreadTransaction(OrderID int64, CumulativeQuoteQuantity float64, Price float64, ExecutedQuantity float64) error {
+	return nil
+}
+
+func (nopTx) DeleteThreadTransactionByOrderID(orderID int) error { return nil }
+
+func (nopTx) SaveTrade(trade *types.Trade) error { return nil }
+
+/* Connect the session to a test Binance API server filling every order, and return the number of orders created */
+func newExchange(t *testing.T, sessionData *types.Session) *int {
+
+	orders := new(int)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != "POST" || r.URL.Path != "/api/v3/order" {
+			http.NotFound(w, r)
+			return
+		}
+
+		*orders++
+
+		fmt.Fprintf(w, `{"symbol":"BTCUSDT","orderId":%d,"executedQty":"0.002","cummulativeQuoteQty":"120","status":"FILLED","type":"MARKET","side":"%s"}`,
+			*orders+1, r.FormValue("side"))
+
+	}))
+
+	t.Cleanup(server.Close)
+
+	sessionData.Clients.Binance = binance.NewClient("", "")
+	sessionData.Clients.Binance.BaseURL = server.URL
+
+	return orders
+
+}
+
+func (f *fakeStorage) GetThreadTransactionMissing(ctx context.Context, sessionData *types.Session) ([]types.Order, error) {
+
+	return nil, nil
+
+}
+
+func (f *fakeStorage) GetThreadTransactionSold(ctx context.Context, sessionData *types.Session) ([]types.Order, error) {
+
+	return nil, nil
+
+}
+
+func (f *fakeStorage) GetThreadTransactionCount(ctx context.Context, sessionData *types.Session) (int, error) {
+
+	return 1, nil
+
+}
+
+func (f *fakeStorage) GetThreadTransactionByPrice(ctx context.Context, marketData *types.Market, sessionData *types.Session) (types.Order, error) {
+
+	return f.order, nil
+
+}
+
+/* Return a thread with the Thread transaction of a BUY at 50000 executed 10 minutes ago, and a market at 60000 */
+func newThread() (*types.Config, *types.Market, *types.Session) {
+
+	configData := &types.Config{
+		ProfitMin:      0.01,
+		SellHoldOnRSI3: 100,
+	}
+
+	marketData := &types.Market{
+		Price:     60000,
+		Rsi7:      50,
+		TimeStamp: time.Now(),
+	}
+
+	sessionData := &types.Session{
+		Symbol:      "BTCUSDT",
+		SymbolFiat:  "USDT",
+		ThreadCount: 1,
+		SymbolFunds: 1,
+		Storage: &fakeStorage{order: types.Order{
+			OrderID:                 1,
+			Price:                   50000,
+			ExecutedQuantity:        0.002,
+			CumulativeQuoteQuantity: 100,
+			TransactTime:            time.Now().Add(-10*time.Minute).UnixNano() / int64(time.Millisecond),
+		}},
+	}
+
+	return configData, marketData, sessionData
+
+}
+
+func TestSellDecisionTree(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		sellRules  string
+		want       bool
+		wantResult string
+	}{
+		{"profit sale", "", true, "Attemtping profit sale"},
+		{"sell rules allow", "order_profit > 0.1 && rsi7 > 40", true, "Attemtping profit sale"},
+		{"sell rules hold", "rsi7 < 30", false, "Rule blocked: rsi7 < 30"},
+		{"invalid sell rules hold", "rsi7 <", false, "Rule error: sell_rules: "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			configData, marketData, sessionData := newThread()
+
+			configData.SellRules = tt.sellRules
+			if expression, err := rules.Parse(tt.sellRules); err != nil {
+				configData.RulesError = "sell_rules: " + err.Error()
+			} else {
+				configData.SellRulesExpression = expression
+			}
+
+			got, order := SellDecisionTree(configData, marketData, sessionData)
+
+			if got != tt.want || order.OrderID != 1 {
+				t.Errorf("SellDecisionTree() = %v, order %v, want %v, order 1", got, order.OrderID, tt.want)
+			}
+
+			if !strings.HasPrefix(sessionData.SellDecisionTreeResult, tt.wantResult) {
+				t.Errorf("SellDecisionTreeResult = %q, want %q", sessionData.SellDecisionTreeResult, tt.wantResult)
+			}
+
+		})
+	}
+
+}
+
+func TestSellDecisionTree_target(t *testing.T) {
+
+	configData, marketData, sessionData := newThread()
+
+	/* 50500*(1+0.001) and 50050*(1+0.01) are both 50550.5, 50550.49999999999 and 50550.5 in float64 */
+	configData.ExchangeComission = 0.001
+	marketData.Price = 50500
+	sessionData.Storage.(*fakeStorage).order.Price = 50050
+
+	if got, _ := SellDecisionTree(configData, marketData, sessionData); !got {
+		t.Errorf("SellDecisionTree() = %v at the profit target, want true, result %q", got, sessionData.SellDecisionTreeResult)
+	}
+
+	marketData.Price = 50499.99
+	if got, _ := SellDecisionTree(configData, marketData, sessionData); got {
+		t.Errorf("SellDecisionTree() = %v below the profit target, want false", got)
+	}
+
+}
+
+func TestRecoverFault(t *testing.T) {
+
+	configData, marketData, sessionData := newThread()
+	storage := sessionData.Storage.(*fakeStorage)
+	storage.down = true
+
+	saved := 0
+	sessionData.Fault = &types.Fault{
+		OrderID: 2,
+		Reason:  "BUY not saved: database is down",
+		Write:   func(tx types.OrderTx) error { saved++; return nil },
+		Retried: time.Now(),
+	}
+
+	/* No BUY or SELL while an order is not saved, Force Sell included */
+	sessionData.ForceSell = true
+	if got, _ := SellDecisionTree(configData, marketData, sessionData); got || sessionData.ForceSell || sessionData.SellDecisionTreeResult != "Fault: BUY not saved: database is down" {
+		t.Errorf("SellDecisionTree() = %v, result %q, want false", got, sessionData.SellDecisionTreeResult)
+	}
+
+	if got, _ := BuyDecisionTree(configData, marketData, sessionData); got || sessionData.BuyDecisionTreeResult != "Fault: BUY not saved: database is down" {
+		t.Errorf("BuyDecisionTree() = %v, result %q, want false", got, sessionData.BuyDecisionTreeResult)
+	}
+
+	/* Retried every faultRetry */
+	storage.down = false
+	recoverFault(configData, marketData, sessionData)
+	if sessionData.Fault == nil || storage.writes != 0 {
+		t.Fatalf("recoverFault() retried before faultRetry, writes %v", storage.writes)
+	}
+
+	sessionData.Fault.Retried = time.Now().Add(-faultRetry)
+	storage.down = true
+	recoverFault(configData, marketData, sessionData)
+	if sessionData.Fault == nil || time.Since(sessionData.Fault.Retried) > time.Second {
+		t.Fatalf("recoverFault() with the database down = %+v, want the fault retried later", sessionData.Fault)
+	}
+
+	sessionData.Fault.Retried = time.Now().Add(-faultRetry)
+	storage.down = false
+	recoverFault(configData, marketData, sessionData)
+	if sessionData.Fault != nil || saved != 1 {
+		t.Fatalf("recoverFault() = %+v, saved %v, want the order saved once", sessionData.Fault, saved)
+	}
+
+	if got, _ := SellDecisionTree(configData, marketData, sessionData); !got {
+		t.Errorf("SellDecisionTree() = %v after recovery, want true, result %q", got, sessionData.SellDecisionTreeResult)
+	}
+
+}
+
+func TestSellDecisionTree_funds(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		pause      string
+		schedule   string
+		wantOrders int
+	}{
+		{"buy", "", "", 1},
+		{"buy in trading window", "", "* 00:00-24:00", 1},
+		{"no buy while paused", pause.ModeNoBuy, "", 0},
+		{"no buy in sell-only window", "", "* 00:00-24:00 sellonly", 0},
+		{"no buy in sell-only blackout", "", "* 00:00-24:00; blackout " + time.Now().UTC().Format("2006-01-02") + " sellonly", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			configData, marketData, sessionData := newThread()
+			configData.ExchangeName = "binance"
+			configData.BuyQuantityFiatInit = 120
+			sessionData.SymbolFunds = 0.001 /* Less than the BUY of the Thread transaction */
+			sessionData.Pause.Mode = tt.pause
+			orders := newExchange(t, sessionData)
+
+			var err error
+			if configData.ScheduleSpec, err = schedule.Parse(tt.schedule); err != nil {
+				t.Fatalf("schedule.Parse() error = %v", err)
+			}
+
+			/* Symbol funds are bought with buy_quantity_fiat_init */
+			if got, _ := SellDecisionTree(configData, marketData, sessionData); got || *orders != tt.wantOrders {
+				t.Errorf("SellDecisionTree() = %v, %v orders, want false, %v orders", got, *orders, tt.wantOrders)
+			}
+
+		})
+	}
+
+}
+
+func TestBuyLiquidity(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		split      bool
+		bookAge    time.Duration
+		wantOrders int
+	}{
+		{"capped", false, 0, 1},
+		{"split", true, 0, 3},
+		{"stale book", true, time.Minute, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			configData, marketData, sessionData := newThread()
+			configData.ExchangeName = "binance"
+			configData.BuyMaxSlippage = 0.01
+			configData.BuySlippageSplit = tt.split
+			marketData.Book = types.OrderBook{
+				Asks: []types.BookLevel{{Price: 100, Quantity: 1}, {Price: 101, Quantity: 1}, {Price: 102, Quantity: 1}},
+				Time: time.Now().Add(-tt.bookAge),
+			}
+			orders := newExchange(t, sessionData)
+
+			/* The orders are sized from one walk of the book, without waiting on the owner goroutine for a new one */
+			start := time.Now()
+			buyLiquidity(300, configData, marketData, sessionData)
+
+			if *orders != tt.wantOrders || time.Since(start) > time.Second {
+				t.Errorf("buyLiquidity() = %v orders in %v, want %v orders", *orders, time.Since(start), tt.wantOrders)
+			}
+
+		})
+	}
+
+}
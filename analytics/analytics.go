@@ -0,0 +1,242 @@
+package analytics
+
+/* This package implements the performance analytics. A scheduled job saves a daily equity snapshot
+for the thread, and for all threads on the Master Node, replacing the snapshot of the current day
+until the day closes. Equity curve, drawdown, Sharpe and Sortino ratios are computed from the daily
+snapshots and win rate, average win and loss, profit factor and holding time from the trades ledger. */
+
+import (
+	"context"
+	"html/template"
+	"math"
+	"time"
+
+	"github.com/aleibovici/cryptopump/nodes"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+/* Daily returns are annualized over 365 days, crypto markets trade every day */
+const periodsPerYear = 365
+
+// Performance struct define the performance analytics for a thread or all threads
+type Performance struct {
+	Equity       []types.Equity `json:"equity"`       /* Equity curve */
+	Drawdown     []float64      `json:"drawdown"`     /* Distance to the previous equity peak for each snapshot */
+	MaxDrawdown  float64        `json:"maxDrawdown"`  /* Largest distance between an equity peak and a later low */
+	Trades       int            `json:"trades"`       /* Number of closed trades */
+	WinRate      float64        `json:"winRate"`      /* Ratio of winning trades */
+	AvgWin       float64        `json:"avgWin"`       /* Average profit of winning trades */
+	AvgLoss      float64        `json:"avgLoss"`      /* Average loss of losing trades as a positive value */
+	ProfitFactor float64        `json:"profitFactor"` /* Gross profit divided by gross loss, 0 without losses */
+	Sharpe       float64        `json:"sharpe"`       /* Annualized Sharpe ratio of daily equity changes */
+	Sortino      float64        `json:"sortino"`      /* Annualized Sortino ratio of daily equity changes */
+	AvgHoldTime  int64          `json:"avgHoldTime"`  /* Average trade holding time in seconds */
+	Chart        template.HTML  `json:"-"`            /* Equity and drawdown chart for the web UI */
+}
+
+// HoldTime return the average trade holding time as duration, i.e. 1h2m3s
+func (p Performance) HoldTime() string {
+
+	return (time.Duration(p.AvgHoldTime) * time.Second).String()
+
+}
+
+// Report struct define the performance analytics for the thread and for all threads
+type Report struct {
+	ThreadID string      `json:"threadId"`
+	Thread   Performance `json:"thread"`
+	Global   Performance `json:"global"`
+}
+
+// Snapshot save today's equity snapshot for the thread and, on the Master Node, for all threads.
+// Profit values are the ones refreshed by the web UI autoloader.
+func Snapshot(sessionData *types.Session) (err error) {
+
+	if sessionData.ThreadID == "" {
+
+		return nil
+
+	}
+
+	date := time.Now().UTC().Format("2006-01-02")
+
+	/* Thread net profit includes the unrealized difference of open orders */
+	if err = sessionData.Storage.SaveEquity(context.Background(), sessionData, types.Equity{
+		ThreadID:   sessionData.ThreadID,
+		Date:       date,
+		Realized:   sessionData.Global.ProfitThreadID - sessionData.DiffTotal,
+		Unrealized: sessionData.DiffTotal,
+		Equity:     sessionData.Global.ProfitThreadID,
+	}); err != nil {
+
+		return err
+
+	}
+
+	if !(nodes.Node{}).IsMaster(sessionData) {
+
+		return nil
+
+	}
+
+	return sessionData.Storage.SaveEquity(context.Background(), sessionData, types.Equity{
+		ThreadID:   "",
+		Date:       date,
+		Realized:   sessionData.Global.Profit,
+		Unrealized: sessionData.Global.ProfitNet - sessionData.Global.Profit,
+		Equity:     sessionData.Global.ProfitNet,
+	})
+
+}
+
+// GetReport return the performance analytics for the thread and for all threads
+func GetReport(sessionData *types.Session) (report Report, err error) {
+
+	report.ThreadID = sessionData.ThreadID
+
+	if report.ThreadID != "" {
+
+		if report.Thread, err = Get(sessionData, report.ThreadID); err != nil {
+
+			return report, err
+
+		}
+
+	}
+
+	report.Global, err = Get(sessionData, "")
+
+	return report, err
+
+}
+
+// Get return the performance analytics for threadID, or for all threads when threadID is empty
+func Get(
+	sessionData *types.Session,
+	threadID string) (performance Performance, err error) {
+
+	var equity []types.Equity
+	var stats types.TradeStats
+
+	if equity, err = sessionData.Storage.GetEquity(context.Background(), sessionData, threadID); err != nil {
+
+		return performance, err
+
+	}
+
+	if stats, err = sessionData.Storage.GetTradeStats(context.Background(), sessionData, threadID); err != nil {
+
+		return performance, err
+
+	}
+
+	return Compute(equity, stats), nil
+
+}
+
+// Compute return the performance analytics for daily equity snapshots, oldest first, and trades ledger totals
+func Compute(
+	equity []types.Equity,
+	stats types.TradeStats) (performance Performance) {
+
+	performance.Equity = equity
+	performance.Drawdown = make([]float64, len(equity))
+	performance.Trades = stats.Count
+
+	/* Drawdown from the running equity peak */
+	peak := math.Inf(-1)
+	for i, snapshot := range equity {
+
+		peak = math.Max(peak, snapshot.Equity)
+
+		performance.Drawdown[i] = peak - snapshot.Equity
+		performance.MaxDrawdown = math.Max(performance.MaxDrawdown, performance.Drawdown[i])
+
+	}
+
+	/* Daily equity changes */
+	changes := make([]float64, 0, len(equity))
+	for i := 1; i < len(equity); i++ {
+
+		changes = append(changes, equity[i].Equity-equity[i-1].Equity)
+
+	}
+
+	performance.Sharpe, performance.Sortino = ratios(changes)
+
+	losses := stats.Count - stats.Wins
+
+	if stats.Count > 0 {
+
+		performance.WinRate = float64(stats.Wins) / float64(stats.Count)
+		performance.AvgHoldTime = int64(math.Round(stats.HoldingTime))
+
+	}
+
+	if stats.Wins > 0 {
+
+		performance.AvgWin = stats.GrossProfit / float64(stats.Wins)
+
+	}
+
+	if losses > 0 {
+
+		performance.AvgLoss = stats.GrossLoss / float64(losses)
+
+	}
+
+	if stats.GrossLoss > 0 {
+
+		performance.ProfitFactor = stats.GrossProfit / stats.GrossLoss
+
+	}
+
+	return performance
+
+}
+
+/* Return the annualized Sharpe and Sortino ratios of daily changes, 0 without enough data */
+func ratios(changes []float64) (sharpe float64, sortino float64) {
+
+	n := float64(len(changes))
+	if n < 2 {
+
+		return 0, 0
+
+	}
+
+	var mean, variance, downside float64
+
+	for _, change := range changes {
+
+		mean += change / n
+
+	}
+
+	for _, change := range changes {
+
+		variance += (change - mean) * (change - mean) / (n - 1)
+
+		if change < 0 {
+
+			downside += change * change / n
+
+		}
+
+	}
+
+	if variance > 0 {
+
+		sharpe = mean / math.Sqrt(variance) * math.Sqrt(periodsPerYear)
+
+	}
+
+	if downside > 0 {
+
+		sortino = mean / math.Sqrt(downside) * math.Sqrt(periodsPerYear)
+
+	}
+
+	return sharpe, sortino
+
+}
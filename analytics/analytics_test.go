@@ -0,0 +1,173 @@
+package analytics
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aleibovici/cryptopump/migrations"
+	"github.com/aleibovici/cryptopump/sqlite"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+/* Daily equity snapshots with values */
+func curve(values ...float64) (equity []types.Equity) {
+
+	for i, value := range values {
+
+		equity = append(equity, types.Equity{Date: time.Date(2021, 1, i+1, 0, 0, 0, 0, time.UTC).Format("2006-01-02"), Equity: value})
+
+	}
+
+	return equity
+
+}
+
+func TestCompute(t *testing.T) {
+
+	tests := []struct {
+		name             string
+		equity           []types.Equity
+		stats            types.TradeStats
+		wantDrawdown     []float64
+		wantMaxDrawdown  float64
+		wantWinRate      float64
+		wantAvgWin       float64
+		wantAvgLoss      float64
+		wantProfitFactor float64
+		wantSharpe       int /* Sign of the Sharpe ratio */
+		wantSortino      int /* Sign of the Sortino ratio */
+	}{
+		{
+			name:         "empty",
+			wantDrawdown: []float64{},
+		},
+		{
+			name:         "steady gains",
+			equity:       curve(0, 1, 2, 3),
+			stats:        types.TradeStats{Count: 3, Wins: 3, GrossProfit: 3},
+			wantDrawdown: []float64{0, 0, 0, 0},
+			wantWinRate:  1,
+			wantAvgWin:   1,
+		},
+		{
+			name:             "drawdown",
+			equity:           curve(0, 10, 4, 12, 9, 15),
+			stats:            types.TradeStats{Count: 4, Wins: 3, GrossProfit: 24, GrossLoss: 9, HoldingTime: 59.6},
+			wantDrawdown:     []float64{0, 0, 6, 0, 3, 0},
+			wantMaxDrawdown:  6,
+			wantWinRate:      0.75,
+			wantAvgWin:       8,
+			wantAvgLoss:      9,
+			wantProfitFactor: 24.0 / 9,
+			wantSharpe:       1,
+			wantSortino:      1,
+		},
+		{
+			name:            "losses",
+			equity:          curve(0, -2, -1, -5),
+			stats:           types.TradeStats{Count: 2, GrossLoss: 5},
+			wantDrawdown:    []float64{0, 2, 1, 5},
+			wantMaxDrawdown: 5,
+			wantAvgLoss:     2.5,
+			wantSharpe:      -1,
+			wantSortino:     -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compute(tt.equity, tt.stats)
+			if len(got.Drawdown) != len(tt.wantDrawdown) {
+				t.Fatalf("Compute() drawdown = %v, want %v", got.Drawdown, tt.wantDrawdown)
+			}
+			for i := range tt.wantDrawdown {
+				if math.Abs(got.Drawdown[i]-tt.wantDrawdown[i]) > 1e-9 {
+					t.Errorf("Compute() drawdown = %v, want %v", got.Drawdown, tt.wantDrawdown)
+				}
+			}
+			if math.Abs(got.MaxDrawdown-tt.wantMaxDrawdown) > 1e-9 ||
+				math.Abs(got.WinRate-tt.wantWinRate) > 1e-9 ||
+				math.Abs(got.AvgWin-tt.wantAvgWin) > 1e-9 ||
+				math.Abs(got.AvgLoss-tt.wantAvgLoss) > 1e-9 ||
+				math.Abs(got.ProfitFactor-tt.wantProfitFactor) > 1e-9 {
+				t.Errorf("Compute() = %+v", got)
+			}
+			if sign(got.Sharpe) != tt.wantSharpe || sign(got.Sortino) != tt.wantSortino {
+				t.Errorf("Compute() sharpe, sortino = %v, %v, want sign %v, %v", got.Sharpe, got.Sortino, tt.wantSharpe, tt.wantSortino)
+			}
+			if got.Trades != tt.stats.Count || got.AvgHoldTime != int64(math.Round(tt.stats.HoldingTime)) {
+				t.Errorf("Compute() trades, hold time = %v, %v", got.Trades, got.AvgHoldTime)
+			}
+		})
+	}
+}
+
+func TestPerformance_HoldTime(t *testing.T) {
+
+	if got := (Performance{AvgHoldTime: 3723}).HoldTime(); got != "1h2m3s" {
+		t.Errorf("Performance.HoldTime() = %v, want 1h2m3s", got)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+
+	db, err := sqlite.DBInit(filepath.Join(t.TempDir(), "cryptopump.db"))
+	if err != nil {
+		t.Fatalf("DBInit() error = %v", err)
+	}
+	defer db.Close()
+
+	if _, err := (migrations.Migrator{Db: db, Backend: "sqlite"}).Up(); err != nil {
+		t.Fatalf("Migrator.Up() error = %v", err)
+	}
+
+	sessionData := &types.Session{Db: db, Storage: sqlite.Storage{}, ThreadID: "c683ok5mk1u1120gnmmg", DiffTotal: -2,
+		Global: &types.Global{ProfitThreadID: 8, Profit: 20, ProfitNet: 15}}
+
+	/* Only the Master Node saves the snapshot of all threads */
+	if err := Snapshot(sessionData); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+
+	if report, err := GetReport(sessionData); err != nil || len(report.Thread.Equity) != 1 || len(report.Global.Equity) != 0 {
+		t.Fatalf("GetReport() = %+v, %v, want thread snapshot only", report, err)
+	}
+
+	/* A later snapshot replaces the snapshot of the day */
+	sessionData.MasterNode = true
+	sessionData.MasterUntil = time.Now().Add(time.Minute)
+	sessionData.Global.ProfitThreadID = 10
+
+	if err := Snapshot(sessionData); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+
+	report, err := GetReport(sessionData)
+	if err != nil {
+		t.Fatalf("GetReport() error = %v", err)
+	}
+
+	if len(report.Thread.Equity) != 1 || report.Thread.Equity[0] != (types.Equity{ThreadID: sessionData.ThreadID, Date: time.Now().UTC().Format("2006-01-02"), Realized: 12, Unrealized: -2, Equity: 10}) {
+		t.Errorf("GetReport() thread equity = %+v", report.Thread.Equity)
+	}
+
+	if len(report.Global.Equity) != 1 || report.Global.Equity[0].Realized != 20 || report.Global.Equity[0].Unrealized != -5 || report.Global.Equity[0].Equity != 15 {
+		t.Errorf("GetReport() global equity = %+v", report.Global.Equity)
+	}
+}
+
+/* Return the sign of v */
+func sign(v float64) int {
+
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+
+	return 0
+
+}
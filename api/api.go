@@ -0,0 +1,664 @@
+package api
+
+/* This package implements the versioned JSON API under /api/v1, so scripts and other tools can drive
+CryptoPump without the web UI forms. Requests read the snapshot published by the owner goroutine and change
+the thread data with engine commands, as the web UI does. Responses are JSON, errors are {"error": "..."}
+with the HTTP status of the failure. */
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aleibovici/cryptopump/auth"
+	"github.com/aleibovici/cryptopump/engine"
+	"github.com/aleibovici/cryptopump/functions"
+	"github.com/aleibovici/cryptopump/logger"
+	"github.com/aleibovici/cryptopump/markets"
+	"github.com/aleibovici/cryptopump/pause"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+// Prefix of the API paths
+const Prefix = "/api/v1"
+
+/* Default and maximum number of orders returned by GET /orders */
+const (
+	defaultOrders = 50
+	maxOrders     = 1000
+)
+
+/* Maximum size of a request body */
+const maxBody = 1 << 20
+
+/* errNotRunning is returned by actions on the session thread when no thread is running */
+var errNotRunning = errors.New("no thread running")
+
+// Handler serve the JSON API for the session thread owned by Engine
+type Handler struct {
+	Engine    *engine.Engine
+	ViperData *types.ViperData
+}
+
+// Session is the session state returned by GET /session
+type Session struct {
+	ThreadID               string      `json:"threadID"` /* Empty when no thread is running */
+	ThreadIDSession        string      `json:"threadIDSession"`
+	Running                bool        `json:"running"`
+	Exchange               string      `json:"exchange"`
+	Symbol                 string      `json:"symbol"`
+	SymbolFunds            float64     `json:"symbolFunds"`
+	SymbolFiat             string      `json:"symbolFiat"`
+	SymbolFiatFunds        float64     `json:"symbolFiatFunds"`
+	Price                  float64     `json:"price"`
+	Positions              int         `json:"positions"` /* Open Thread transactions */
+	DiffTotal              float64     `json:"diffTotal"` /* Total difference between target and market price */
+	ProfitThread           float64     `json:"profitThread"`
+	ProfitThreadPct        float64     `json:"profitThreadPct"`
+	Profit                 float64     `json:"profit"`
+	ProfitNet              float64     `json:"profitNet"`
+	ProfitPct              float64     `json:"profitPct"`
+	ThreadCount            int         `json:"threadCount"` /* Running threads */
+	BuyDecisionTreeResult  string      `json:"buyDecision"`
+	SellDecisionTreeResult string      `json:"sellDecision"`
+	RiskReason             string      `json:"riskReason"`
+	GuardReason            string      `json:"guardReason"`
+	Pause                  types.Pause `json:"pause"` /* Empty mode when not paused */
+	Schedule               string      `json:"schedule"`
+	Exit                   bool        `json:"exit"`   /* Exit mode, the thread stops once its transactions are sold */
+	DryRun                 bool        `json:"dryRun"` /* Dry Run mode */
+	Master                 bool        `json:"master"` /* Master Node */
+	Latency                int64       `json:"latency"`
+	Version                string      `json:"version"`
+}
+
+// Position is an open Thread transaction returned by GET /positions
+type Position struct {
+	types.Order
+	Target float64 `json:"target"` /* Target price */
+	Value  float64 `json:"value"`  /* Market value minus the exchange commission */
+	Diff   float64 `json:"diff"`   /* Difference between market value and cost */
+}
+
+// Action is the response of buy, sell and exit
+type Action struct {
+	Action   string `json:"action"`
+	ThreadID string `json:"threadID"`
+	OrderID  int    `json:"orderID,omitempty"`
+}
+
+/* errorBody is the response of failed requests */
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+/* fail return the status and body of a failed request */
+func fail(status int, format string, a ...interface{}) (int, interface{}) {
+
+	return status, errorBody{Error: fmt.Sprintf(format, a...)}
+
+}
+
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	var status int
+	var body interface{}
+
+	/* Requests read a copy of the thread data published by the owner goroutine */
+	snapshot := h.Engine.Snapshot()
+
+	/* Methods allowed for each path */
+	methods := map[string][]string{
+		"/session":   {"GET"},
+		"/positions": {"GET"},
+		"/orders":    {"GET"},
+		"/config":    {"GET", "PATCH"},
+		"/buy":       {"POST"},
+		"/sell":      {"POST"},
+		"/pause":     {"POST"},
+		"/resume":    {"POST"},
+		"/exit":      {"POST"},
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, Prefix)
+
+	switch allowed, ok := methods[path]; {
+	case !ok:
+
+		status, body = fail(http.StatusNotFound, "%s not found", r.URL.Path)
+
+	case !contains(allowed, r.Method):
+
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		status, body = fail(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+
+	default:
+
+		switch r.Method + " " + path {
+		case "GET /session":
+
+			status, body = h.session(&snapshot)
+
+		case "GET /positions":
+
+			status, body = h.positions(&snapshot)
+
+		case "GET /orders":
+
+			status, body = h.orders(r, &snapshot)
+
+		case "GET /config":
+
+			functions.GetConfigData(h.ViperData, &snapshot.Session) /* Select the thread configuration file */
+			status, body = http.StatusOK, getConfig(h.ViperData)
+
+		case "PATCH /config":
+
+			status, body = h.patchConfig(r, &snapshot)
+
+		case "POST /buy":
+
+			status, body = h.buy(r, &snapshot)
+
+		case "POST /sell":
+
+			status, body = h.sell(r, &snapshot)
+
+		case "POST /pause", "POST /resume":
+
+			status, body = h.pause(r, path, &snapshot)
+
+		case "POST /exit":
+
+			status, body = h.exit(r, &snapshot)
+
+		}
+
+	}
+
+	w.Header().Set("Content-Type", "application/json")  /* Set the Content-Type header */
+	w.Header().Set("X-Content-Type-Options", "nosniff") /* Add X-Content-Type-Options header */
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  &snapshot.Session,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+	}
+
+}
+
+/* Return the session state */
+func (h Handler) session(snapshot *engine.Snapshot) (int, interface{}) {
+
+	sessionData := &snapshot.Session
+	configData := functions.GetConfigData(h.ViperData, sessionData)
+
+	session := Session{
+		ThreadID:               sessionData.ThreadID,
+		ThreadIDSession:        sessionData.ThreadIDSession,
+		Running:                sessionData.ThreadID != "",
+		Exchange:               configData.ExchangeName,
+		Symbol:                 sessionData.Symbol,
+		SymbolFunds:            sessionData.SymbolFunds,
+		SymbolFiat:             sessionData.SymbolFiat,
+		SymbolFiatFunds:        sessionData.SymbolFiatFunds,
+		Price:                  snapshot.Market.Price,
+		DiffTotal:              sessionData.DiffTotal,
+		ProfitThread:           sessionData.Global.ProfitThreadID,
+		ProfitThreadPct:        sessionData.Global.ProfitThreadIDPct,
+		Profit:                 sessionData.Global.Profit,
+		ProfitNet:              sessionData.Global.ProfitNet,
+		ProfitPct:              sessionData.Global.ProfitPct,
+		ThreadCount:            sessionData.Global.ThreadCount,
+		BuyDecisionTreeResult:  sessionData.BuyDecisionTreeResult,
+		SellDecisionTreeResult: sessionData.SellDecisionTreeResult,
+		RiskReason:             sessionData.Risk.Reason,
+		GuardReason:            sessionData.Guard.Reason,
+		Pause:                  sessionData.Pause,
+		Exit:                   configData.Exit,
+		DryRun:                 configData.DryRun,
+		Master:                 sessionData.MasterNode,
+		Latency:                sessionData.Latency,
+		Version:                sessionData.Version,
+	}
+
+	session.Pause.ThreadID = sessionData.ThreadID
+
+	/* Trading schedule behavior with the next transition, empty without a schedule */
+	if configData.ScheduleError != "" {
+
+		session.Schedule = "Schedule error: " + configData.ScheduleError
+
+	} else if configData.ScheduleSpec != nil {
+
+		session.Schedule = configData.ScheduleSpec.At(time.Now()).String()
+
+	}
+
+	if session.Running {
+
+		orders, err := sessionData.Storage.GetThreadTransactionByThreadID(context.Background(), sessionData)
+		if err != nil {
+
+			return h.internal(sessionData, err, "unable to retrieve positions")
+
+		}
+
+		session.Positions = len(orders)
+
+	}
+
+	return http.StatusOK, session
+
+}
+
+/* Return the open Thread transactions of the session thread with their target price and unrealized profit */
+func (h Handler) positions(snapshot *engine.Snapshot) (int, interface{}) {
+
+	sessionData := &snapshot.Session
+	positions := []Position{}
+
+	if sessionData.ThreadID == "" {
+
+		return http.StatusOK, positions
+
+	}
+
+	configData := functions.GetConfigData(h.ViperData, sessionData)
+
+	orders, err := sessionData.Storage.GetThreadTransactionByThreadID(context.Background(), sessionData)
+	if err != nil {
+
+		return h.internal(sessionData, err, "unable to retrieve positions")
+
+	}
+
+	/* Target profit follows market volatility in adaptive mode */
+	profit := configData.ProfitMin
+	if adaptive := markets.AdaptiveProfit(configData, &snapshot.Market); adaptive > 0 {
+
+		profit = adaptive
+
+	}
+
+	for _, order := range orders {
+
+		position := Position{Order: order, Target: order.Price * (1 + profit)}
+
+		if snapshot.Market.Price > 0 { /* The market price is known once the websockets are up */
+
+			position.Value = order.ExecutedQuantity * snapshot.Market.Price * (1 - configData.ExchangeComission)
+			position.Diff = position.Value - order.CumulativeQuoteQuantity
+
+		}
+
+		positions = append(positions, position)
+
+	}
+
+	return http.StatusOK, positions
+
+}
+
+/* Return the orders matching the thread, side, status and limit query parameters, most recent first */
+func (h Handler) orders(r *http.Request, snapshot *engine.Snapshot) (int, interface{}) {
+
+	sessionData := &snapshot.Session
+	query := r.URL.Query()
+
+	filter := types.OrderFilter{
+		ThreadID: query.Get("thread"),
+		Side:     strings.ToUpper(query.Get("side")),
+		Status:   strings.ToUpper(query.Get("status")),
+		Limit:    defaultOrders,
+	}
+
+	if filter.Side != "" && filter.Side != "BUY" && filter.Side != "SELL" {
+
+		return fail(http.StatusBadRequest, "side must be BUY or SELL")
+
+	}
+
+	if value := query.Get("limit"); value != "" {
+
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxOrders {
+
+			return fail(http.StatusBadRequest, "limit must be between 1 and %d", maxOrders)
+
+		}
+
+		filter.Limit = limit
+
+	}
+
+	orders, err := sessionData.Storage.GetOrders(context.Background(), sessionData, filter)
+	if err != nil {
+
+		return h.internal(sessionData, err, "unable to retrieve orders")
+
+	}
+
+	if orders == nil {
+
+		orders = []types.Order{}
+
+	}
+
+	return http.StatusOK, orders
+
+}
+
+/* Change the configuration keys in the request body, and apply them to the running thread */
+func (h Handler) patchConfig(r *http.Request, snapshot *engine.Snapshot) (int, interface{}) {
+
+	var values map[string]interface{}
+
+	if err := decode(r, &values); err != nil {
+
+		return fail(http.StatusBadRequest, "%s", err)
+
+	}
+
+	functions.GetConfigData(h.ViperData, &snapshot.Session) /* Select the thread configuration file */
+
+	if err := saveConfig(h.ViperData, values, snapshot.Session.ThreadID != ""); err != nil {
+
+		if err, ok := err.(configError); ok {
+
+			return fail(err.status, "%s", err.message)
+
+		}
+
+		return h.internal(&snapshot.Session, err, "unable to save the configuration")
+
+	}
+
+	h.reload()
+
+	return http.StatusOK, getConfig(h.ViperData)
+
+}
+
+/* Force a BUY on the session thread */
+func (h Handler) buy(r *http.Request, snapshot *engine.Snapshot) (int, interface{}) {
+
+	sessionData := &snapshot.Session
+
+	if err := decode(r, &struct{}{}); err != nil {
+
+		return fail(http.StatusBadRequest, "%s", err)
+
+	}
+
+	switch {
+	case sessionData.ThreadID == "":
+
+		return fail(http.StatusConflict, "%s", errNotRunning)
+
+	case !pause.Control{}.IsBuyAllowed(sessionData):
+
+		return fail(http.StatusConflict, "%s", pause.Describe(sessionData.Pause))
+
+	case functions.GetConfigData(h.ViperData, sessionData).Exit:
+
+		return fail(http.StatusConflict, "thread is in exit mode")
+
+	}
+
+	h.Engine.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+		sessionData.ForceBuy = true /* Force buy */
+	})
+
+	return http.StatusAccepted, Action{Action: "buy", ThreadID: sessionData.ThreadID}
+
+}
+
+/* Force the SELL of the open Thread transaction in the request body {"orderID": n} */
+func (h Handler) sell(r *http.Request, snapshot *engine.Snapshot) (int, interface{}) {
+
+	var request struct {
+		OrderID int `json:"orderID"`
+	}
+
+	sessionData := &snapshot.Session
+
+	if err := decode(r, &request); err != nil {
+
+		return fail(http.StatusBadRequest, "%s", err)
+
+	}
+
+	if request.OrderID <= 0 {
+
+		return fail(http.StatusBadRequest, "orderID is required")
+
+	}
+
+	if sessionData.ThreadID == "" {
+
+		return fail(http.StatusConflict, "%s", errNotRunning)
+
+	}
+
+	if !(pause.Control{}).IsSellAllowed(sessionData) {
+
+		return fail(http.StatusConflict, "%s", pause.Describe(sessionData.Pause))
+
+	}
+
+	orders, err := sessionData.Storage.GetThreadTransactionByThreadID(context.Background(), sessionData)
+	if err != nil {
+
+		return h.internal(sessionData, err, "unable to retrieve positions")
+
+	}
+
+	found := false
+	for _, order := range orders {
+
+		if order.OrderID == request.OrderID {
+
+			found = true
+
+			break
+
+		}
+
+	}
+
+	if !found {
+
+		return fail(http.StatusNotFound, "order %d is not an open Thread transaction of thread %s", request.OrderID, sessionData.ThreadID)
+
+	}
+
+	h.Engine.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+		sessionData.ForceSellOrderID = request.OrderID
+		sessionData.ForceSell = true /* Force sell */
+	})
+
+	return http.StatusAccepted, Action{Action: "sell", ThreadID: sessionData.ThreadID, OrderID: request.OrderID}
+
+}
+
+/* Pause or resume the thread in the request body {"thread": id, "mode": "nobuy|frozen", "confirm": bool} */
+func (h Handler) pause(r *http.Request, path string, snapshot *engine.Snapshot) (int, interface{}) {
+
+	var request struct {
+		ThreadID string `json:"thread"`
+		Mode     string `json:"mode"`
+		Confirm  bool   `json:"confirm"`
+	}
+
+	var pauseData types.Pause
+	var err error
+
+	sessionData := &snapshot.Session
+
+	if err := decode(r, &request); err != nil {
+
+		return fail(http.StatusBadRequest, "%s", err)
+
+	}
+
+	if request.ThreadID == "" { /* The session thread by default */
+
+		request.ThreadID = sessionData.ThreadID
+
+	}
+
+	if request.ThreadID == "" {
+
+		return fail(http.StatusConflict, "%s", errNotRunning)
+
+	}
+
+	if path == "/pause" {
+
+		pauseData, err = pause.Control{}.Pause(sessionData, request.ThreadID, request.Mode, "api "+auth.Actor(r), request.Confirm)
+
+	} else {
+
+		pauseData, err = pause.Control{}.Resume(sessionData, request.ThreadID, "api "+auth.Actor(r), request.Confirm)
+
+	}
+
+	if err != nil {
+
+		return fail(PauseStatus(err), "%s", err)
+
+	}
+
+	if request.ThreadID == sessionData.ThreadID { /* Enforce the pause of the session thread at once */
+
+		h.Engine.Do(func(configData *types.Config, marketData *types.Market, owner *types.Session) {
+			owner.Pause = sessionData.Pause
+		})
+
+	}
+
+	return http.StatusOK, pauseData
+
+}
+
+/* Set exit mode on the session thread. It stops buying and exits once its transactions are sold. */
+func (h Handler) exit(r *http.Request, snapshot *engine.Snapshot) (int, interface{}) {
+
+	sessionData := &snapshot.Session
+
+	if err := decode(r, &struct{}{}); err != nil {
+
+		return fail(http.StatusBadRequest, "%s", err)
+
+	}
+
+	if sessionData.ThreadID == "" {
+
+		return fail(http.StatusConflict, "%s", errNotRunning)
+
+	}
+
+	functions.GetConfigData(h.ViperData, sessionData) /* Select the thread configuration file */
+
+	if err := saveConfig(h.ViperData, map[string]interface{}{"exit": true}, true); err != nil {
+
+		return h.internal(sessionData, err, "unable to save the configuration")
+
+	}
+
+	h.reload()
+
+	return http.StatusAccepted, Action{Action: "exit", ThreadID: sessionData.ThreadID}
+
+}
+
+/* Reload the configuration of the owner goroutine */
+func (h Handler) reload() {
+
+	h.Engine.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+		*configData = *functions.GetConfigData(h.ViperData, sessionData)
+	})
+
+}
+
+/* Log err and return an internal server error with message */
+func (h Handler) internal(sessionData *types.Session, err error, message string) (int, interface{}) {
+
+	logger.LogEntry{ /* Log Entry */
+		Config:   nil,
+		Market:   nil,
+		Session:  sessionData,
+		Order:    &types.Order{},
+		Message:  functions.GetFunctionName() + " - " + err.Error(),
+		LogLevel: "DebugLevel",
+	}.Do()
+
+	return fail(http.StatusInternalServerError, "%s", message)
+
+}
+
+// PauseStatus return the HTTP status of a pause or resume error
+func PauseStatus(err error) int {
+
+	switch err {
+	case pause.ErrMode:
+
+		return http.StatusBadRequest
+
+	case pause.ErrConfirm, pause.ErrNotPaused:
+
+		return http.StatusConflict
+
+	}
+
+	return http.StatusInternalServerError
+
+}
+
+/* Decode the JSON request body into v. An empty body leaves v unchanged. */
+func decode(r *http.Request, v interface{}) error {
+
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxBody))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil && err != io.EOF {
+
+		return errors.New("invalid request body: " + err.Error())
+
+	}
+
+	return nil
+
+}
+
+/* Return true when list contains s */
+func contains(list []string, s string) bool {
+
+	for _, item := range list {
+
+		if item == s {
+
+			return true
+
+		}
+
+	}
+
+	return false
+
+}
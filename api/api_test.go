@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aleibovici/cryptopump/engine"
+	"github.com/aleibovici/cryptopump/migrations"
+	"github.com/aleibovici/cryptopump/sqlite"
+	"github.com/aleibovici/cryptopump/types"
+	"github.com/spf13/viper"
+)
+
+/* Return a handler for a running thread with open Thread transaction 1, with ./config/config.yml and a migrated database */
+func newHandler(t *testing.T) (Handler, *engine.Engine) {
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Chdir() error = %v", err)
+	}
+
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if err := os.Mkdir("config", 0755); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join("config", "config.yml"), []byte("config:\n  buy_wait: \"60\"\n  symbol: BTCUSDT\n  exit: \"false\"\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	viperData := &types.ViperData{V1: viper.New(), V2: viper.New()}
+	viperData.V1.SetConfigFile(filepath.Join("config", "config.yml"))
+	if err := viperData.V1.ReadInConfig(); err != nil {
+		t.Fatalf("ReadInConfig() error = %v", err)
+	}
+
+	db, err := sqlite.DBInit(filepath.Join(dir, "cryptopump.db"))
+	if err != nil {
+		t.Fatalf("DBInit() error = %v", err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := (migrations.Migrator{Db: db, Backend: "sqlite"}).Up(); err != nil {
+		t.Fatalf("Migrator.Up() error = %v", err)
+	}
+
+	sessionData := &types.Session{
+		ThreadID:        "c683ok5mk1u1120gnmmg",
+		ThreadIDSession: "c683ok5mk1u1120gnmn0",
+		Symbol:          "BTCUSDT",
+		SymbolFiat:      "USDT",
+		Db:              db,
+		Storage:         sqlite.Storage{},
+		Global:          &types.Global{},
+		Guard:           types.Guard{Count: map[string]int{}},
+	}
+
+	if err := sessionData.Storage.SaveOrder(context.Background(), sessionData, &types.Order{
+		OrderID: 1, CumulativeQuoteQuantity: 100, ExecutedQuantity: 0.002, Price: 50000, Side: "BUY", Status: "FILLED", Symbol: "BTCUSDT", TransactTime: 1,
+	}, 0, 50000); err != nil {
+		t.Fatalf("SaveOrder() error = %v", err)
+	}
+
+	if err := sessionData.Storage.SaveThreadTransaction(context.Background(), sessionData, 1, 100, 50000, 0.002); err != nil {
+		t.Fatalf("SaveThreadTransaction() error = %v", err)
+	}
+
+	e := engine.New(&types.Config{}, &types.Market{Price: 60000}, sessionData)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go e.Run(ctx)
+
+	t.Cleanup(func() {
+		cancel()
+		<-e.Done()
+	})
+
+	return Handler{Engine: e, ViperData: viperData}, e
+
+}
+
+func TestHandler_ServeHTTP(t *testing.T) {
+
+	h, e := newHandler(t)
+
+	/* Requests run in order on the same thread */
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		want     int
+		wantBody string
+	}{
+		{"session", "GET", "/session", "", http.StatusOK, `"running":true`},
+		{"session positions", "GET", "/session", "", http.StatusOK, `"positions":1`},
+		{"positions", "GET", "/positions", "", http.StatusOK, `"orderId":1`},
+		{"positions diff", "GET", "/positions", "", http.StatusOK, `"diff":20`},
+		{"orders", "GET", "/orders?side=buy&limit=10", "", http.StatusOK, `"side":"BUY"`},
+		{"orders side", "GET", "/orders?side=hold", "", http.StatusBadRequest, `"error":"side must be BUY or SELL"`},
+		{"orders limit", "GET", "/orders?limit=0", "", http.StatusBadRequest, `"error":"limit must be between 1 and 1000"`},
+		{"config", "GET", "/config", "", http.StatusOK, `"buy_wait":60`},
+		{"config patch", "PATCH", "/config", `{"buy_wait": 30, "stoploss": 0.05}`, http.StatusOK, `"buy_wait":30`},
+		{"config patch saved", "GET", "/config", "", http.StatusOK, `"stoploss":0.05`},
+		{"config not integer", "PATCH", "/config", `{"buy_wait": 1.5}`, http.StatusUnprocessableEntity, "buy_wait must be an integer"},
+		{"config negative", "PATCH", "/config", `{"profit_min": -1}`, http.StatusUnprocessableEntity, "profit_min must not be negative"},
+		{"config type", "PATCH", "/config", `{"buy_wait": "30"}`, http.StatusBadRequest, "buy_wait must be a number"},
+		{"config unknown", "PATCH", "/config", `{"buy_wait": 30, "apikey": "x"}`, http.StatusBadRequest, "unknown configuration key apikey"},
+		{"config running", "PATCH", "/config", `{"symbol": "ETHUSDT"}`, http.StatusConflict, "symbol can't be changed while a thread is running"},
+		{"config rules", "PATCH", "/config", `{"buy_rules": "rsi7 <"}`, http.StatusUnprocessableEntity, "buy_rules: "},
+		{"config buy rules order", "PATCH", "/config", `{"buy_rules": "order_profit > 0.02"}`, http.StatusUnprocessableEntity, "buy_rules: variable 'order_profit' at position 1 is only available in sell_rules"},
+		{"config schedule", "PATCH", "/config", `{"schedule": "mon-fri 25:00-26:00"}`, http.StatusUnprocessableEntity, "schedule: "},
+		{"config malformed", "PATCH", "/config", `{"buy_wait": }`, http.StatusBadRequest, "invalid request body"},
+		{"config unchanged", "GET", "/config", "", http.StatusOK, `"buy_wait":30`},
+		{"sell no order", "POST", "/sell", "", http.StatusBadRequest, "orderID is required"},
+		{"sell not open", "POST", "/sell", `{"orderID": 2}`, http.StatusNotFound, "order 2 is not an open Thread transaction"},
+		{"sell", "POST", "/sell", `{"orderID": 1}`, http.StatusAccepted, `"orderID":1`},
+		{"pause mode", "POST", "/pause", `{"mode": "all"}`, http.StatusBadRequest, "pause mode must be nobuy or frozen"},
+		{"pause", "POST", "/pause", `{"mode": "nobuy"}`, http.StatusOK, `"mode":"nobuy"`},
+		{"buy paused", "POST", "/buy", "", http.StatusConflict, "Paused (no new buys)"},
+		{"resume", "POST", "/resume", "", http.StatusOK, `"mode":"nobuy"`},
+		{"resume not paused", "POST", "/resume", "", http.StatusConflict, "thread is not paused"},
+		{"buy", "POST", "/buy", "", http.StatusAccepted, `"action":"buy"`},
+		{"exit", "POST", "/exit", "", http.StatusAccepted, `"action":"exit"`},
+		{"session exit", "GET", "/session", "", http.StatusOK, `"exit":true`},
+		{"buy exit", "POST", "/buy", "", http.StatusConflict, "thread is in exit mode"},
+		{"method", "GET", "/buy", "", http.StatusMethodNotAllowed, "method GET not allowed"},
+		{"not found", "GET", "/sessions", "", http.StatusNotFound, "/api/v1/sessions not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(tt.method, Prefix+tt.path, strings.NewReader(tt.body)))
+
+			if w.Code != tt.want {
+				t.Errorf("ServeHTTP() status = %v, want %v, body %q", w.Code, tt.want, w.Body.String())
+			}
+
+			if w.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("ServeHTTP() Content-Type = %q, want application/json", w.Header().Get("Content-Type"))
+			}
+
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("ServeHTTP() body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+
+		})
+	}
+
+	/* Actions are handed to the owner goroutine */
+	if session := e.Session(); !session.ForceBuy || !session.ForceSell || session.ForceSellOrderID != 1 {
+		t.Errorf("Session() ForceBuy = %v, ForceSell = %v, ForceSellOrderID = %v, want true, true, 1",
+			session.ForceBuy, session.ForceSell, session.ForceSellOrderID)
+	}
+
+}
@@ -0,0 +1,286 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/aleibovici/cryptopump/functions"
+	"github.com/aleibovici/cryptopump/rules"
+	"github.com/aleibovici/cryptopump/schedule"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+/* Kind of the keys of the config section of the session configuration file, as read by functions.GetConfigData */
+var configKeys = func() map[string]int {
+
+	keys := make(map[string]int, len(functions.ConfigKeys))
+
+	for _, key := range functions.ConfigKeys {
+
+		keys[key.Name] = key.Kind()
+
+	}
+
+	return keys
+
+}()
+
+/* Keys that can't be changed while a thread is running, they are disabled in the web UI */
+var startKeys = map[string]bool{
+	"exchangename": true,
+	"newsession":   true,
+	"symbol":       true,
+	"symbol_fiat":  true,
+	"testnet":      true,
+}
+
+/* configError is a configuration change rejected with an HTTP status */
+type configError struct {
+	status  int
+	message string
+}
+
+func (e configError) Error() string {
+
+	return e.message
+
+}
+
+/* Return the configuration keys of the session configuration file with their typed values */
+func getConfig(viperData *types.ViperData) map[string]interface{} {
+
+	viperData.Mutex.Lock()
+	defer viperData.Mutex.Unlock()
+
+	return configValues(viperData)
+
+}
+
+/* Return the configuration keys with their typed values. viperData.Mutex must be held. */
+func configValues(viperData *types.ViperData) map[string]interface{} {
+
+	values := make(map[string]interface{}, len(configKeys))
+
+	for key, kind := range configKeys {
+
+		switch kind {
+		case functions.ConfigBool:
+
+			values[key] = viperData.V1.GetBool("config." + key)
+
+		case functions.ConfigInt:
+
+			values[key] = viperData.V1.GetInt64("config." + key)
+
+		case functions.ConfigFloat:
+
+			values[key] = viperData.V1.GetFloat64("config." + key)
+
+		case functions.ConfigString:
+
+			values[key] = viperData.V1.GetString("config." + key)
+
+		}
+
+	}
+
+	return values
+
+}
+
+/* Validate changes and save them in the session configuration file. Errors of invalid changes are configError. */
+func saveConfig(viperData *types.ViperData, changes map[string]interface{}, running bool) error {
+
+	viperData.Mutex.Lock()
+	defer viperData.Mutex.Unlock()
+
+	values := configValues(viperData)
+
+	/* Sorted so the first invalid key reported is always the same */
+	keys := make([]string, 0, len(changes))
+	for key := range changes {
+
+		keys = append(keys, key)
+
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+
+		kind, ok := configKeys[key]
+		if !ok {
+
+			return configError{http.StatusBadRequest, "unknown configuration key " + key}
+
+		}
+
+		if running && startKeys[key] {
+
+			return configError{http.StatusConflict, key + " can't be changed while a thread is running"}
+
+		}
+
+		value, err := configValue(key, kind, changes[key])
+		if err != nil {
+
+			return err
+
+		}
+
+		values[key] = value
+
+	}
+
+	if err := validateConfig(values, changes); err != nil {
+
+		return err
+
+	}
+
+	for _, key := range keys {
+
+		viperData.V1.Set("config."+key, values[key])
+
+	}
+
+	return viperData.V1.WriteConfig()
+
+}
+
+/* Return value of a JSON request converted to the kind of key */
+func configValue(key string, kind int, value interface{}) (interface{}, error) {
+
+	switch kind {
+	case functions.ConfigBool:
+
+		if b, ok := value.(bool); ok {
+
+			return b, nil
+
+		}
+
+		return nil, configError{http.StatusBadRequest, key + " must be a boolean"}
+
+	case functions.ConfigInt, functions.ConfigFloat:
+
+		f, ok := value.(float64)
+		if !ok {
+
+			return nil, configError{http.StatusBadRequest, key + " must be a number"}
+
+		}
+
+		if f < 0 {
+
+			return nil, configError{http.StatusUnprocessableEntity, key + " must not be negative"}
+
+		}
+
+		if kind == functions.ConfigFloat {
+
+			return f, nil
+
+		}
+
+		if f != math.Trunc(f) || f > math.MaxInt32 {
+
+			return nil, configError{http.StatusUnprocessableEntity, key + " must be an integer"}
+
+		}
+
+		return int64(f), nil
+
+	}
+
+	if s, ok := value.(string); ok {
+
+		return strings.TrimSpace(s), nil
+
+	}
+
+	return nil, configError{http.StatusBadRequest, key + " must be a string"}
+
+}
+
+/* Validate the values of the rule expressions, the schedule and the market when they are changed */
+func validateConfig(values map[string]interface{}, changes map[string]interface{}) error {
+
+	changed := func(keys ...string) bool {
+
+		for _, key := range keys {
+
+			if _, ok := changes[key]; ok {
+
+				return true
+
+			}
+
+		}
+
+		return false
+
+	}
+
+	for _, key := range []string{"buy_rules", "sell_rules"} {
+
+		if !changed(key) {
+
+			continue
+
+		}
+
+		parse := rules.Parse
+		if key == "buy_rules" {
+
+			parse = rules.ParseBuy
+
+		}
+
+		if _, err := parse(values[key].(string)); err != nil {
+
+			return configError{http.StatusUnprocessableEntity, key + ": " + err.Error()}
+
+		}
+
+	}
+
+	if changed("schedule", "time_enforce", "time_start", "time_stop") {
+
+		var err error
+
+		/* Same precedence as functions.GetConfigData */
+		if values["schedule"] == "" && values["time_enforce"] == true {
+
+			_, err = schedule.Legacy(values["time_start"].(string), values["time_stop"].(string))
+
+		} else {
+
+			_, err = schedule.Parse(values["schedule"].(string))
+
+		}
+
+		if err != nil {
+
+			return configError{http.StatusUnprocessableEntity, "schedule: " + err.Error()}
+
+		}
+
+	}
+
+	for _, key := range []string{"exchangename", "symbol", "symbol_fiat"} {
+
+		if changed(key) && values[key] == "" {
+
+			return configError{http.StatusUnprocessableEntity, fmt.Sprintf("%s must not be empty", key)}
+
+		}
+
+	}
+
+	return nil
+
+}
@@ -0,0 +1,901 @@
+package auth
+
+/* This package implements the authentication and authorization of the web UI and the JSON API. Users sign in
+with a password hashed with bcrypt and get a session cookie, scripts send an API token in the Authorization
+header. Users and tokens are kept in config_global.yml and have a role: a viewer is read-only, a trader can
+start, stop, buy, sell, pause and change the configuration, and an admin can also change the exchange API keys
+and the Telegram token. Requests changing anything must carry the CSRF token of their session, and are saved in
+the audit log. Failed logins of a client are limited. Authentication is disabled until a user or a token is
+added, the web UI is then used as an anonymous admin from localhost only, still with CSRF protection. */
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aleibovici/cryptopump/engine"
+	"github.com/aleibovici/cryptopump/functions"
+	"github.com/aleibovici/cryptopump/logger"
+	"github.com/aleibovici/cryptopump/types"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Roles, each role can do what the previous roles can
+const (
+	RoleViewer = "viewer" /* Read-only */
+	RoleTrader = "trader" /* Start, stop, buy, sell, pause and change the configuration */
+	RoleAdmin  = "admin"  /* Change the exchange API keys and the Telegram token */
+)
+
+/* Rank of each role, 0 for unknown roles */
+var ranks = map[string]int{RoleViewer: 1, RoleTrader: 2, RoleAdmin: 3}
+
+const (
+	cookieName  = "cryptopump_session"
+	csrfCookie  = "cryptopump_csrf" /* Nonce of the CSRF token of the login page and of the anonymous admin */
+	sessionTTL  = 12 * time.Hour    /* Sessions expire after 12 hours without requests */
+	maxSessions = 1000              /* The sessions expiring first are dropped beyond 1000 */
+	csrfField   = "csrf"            /* CSRF token form field */
+	csrfHeader  = "X-CSRF-Token"    /* CSRF token header of API requests with a session cookie */
+)
+
+const (
+	loginAttempts = 5                /* Failed logins of a client before its logins are refused */
+	loginWindow   = 15 * time.Minute /* Failed logins are counted, and logins refused, for 15 minutes */
+	maxClients    = 10000            /* The clients unblocked first are dropped beyond 10000 */
+)
+
+// Anonymous is the name of the identity when authentication is disabled
+const Anonymous = "anonymous"
+
+/* Compared with the password of unknown users, so their login takes as long as the others */
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("cryptopump"), bcrypt.DefaultCost)
+
+// User struct define a user of config_global.yml
+type User struct {
+	Name     string `mapstructure:"name"`
+	Password string `mapstructure:"password"` /* bcrypt hash */
+	Role     string `mapstructure:"role"`
+}
+
+// Token struct define an API token of config_global.yml
+type Token struct {
+	Name string `mapstructure:"name"`
+	Hash string `mapstructure:"hash"` /* SHA-256 of the token, hex encoded */
+	Role string `mapstructure:"role"`
+}
+
+// Identity struct define who sent a request
+type Identity struct {
+	Name string /* User, "token <name>" or anonymous when authentication is disabled */
+	Role string
+	CSRF string /* CSRF token of the web UI session, empty for API tokens */
+}
+
+/* contextKey is the request context key of the Identity */
+type contextKey struct{}
+
+/* session is the web UI session of a signed in user */
+type session struct {
+	user    string
+	csrf    string
+	expires time.Time
+}
+
+/* failure counts the failed logins of a client until reset */
+type failure struct {
+	count int
+	reset time.Time
+}
+
+// Auth authenticate and authorize the web UI and API requests of the node
+type Auth struct {
+	viperData *types.ViperData
+	engine    *engine.Engine /* Session thread of the audit log */
+	key       []byte         /* HMAC key of the CSRF tokens of requests without a session */
+	mutex     sync.Mutex     /* Protect sessions and failures */
+	sessions  map[string]*session
+	failures  map[string]*failure /* Failed logins by client IP address */
+}
+
+// New return an Auth reading the users and tokens of viperData on every request
+func New(viperData *types.ViperData, e *engine.Engine) *Auth {
+
+	key := make([]byte, 32)
+	_, _ = rand.Read(key) /* crypto/rand doesn't fail on supported platforms */
+
+	return &Auth{
+		viperData: viperData,
+		engine:    e,
+		key:       key,
+		sessions:  make(map[string]*session),
+		failures:  make(map[string]*failure),
+	}
+
+}
+
+// FromRequest return the identity of a request served by Protect
+func FromRequest(r *http.Request) Identity {
+
+	identity, _ := r.Context().Value(contextKey{}).(Identity)
+
+	return identity
+
+}
+
+// Actor return who sent a request served by Protect, for the records of the changes it makes: the name
+// of its identity and the IP address of the client.
+func Actor(r *http.Request) string {
+
+	if name := FromRequest(r).Name; name != "" {
+
+		return name + " from " + clientIP(r)
+
+	}
+
+	return clientIP(r)
+
+}
+
+// Protect serve the requests of next authorized for the role of their identity. Unauthenticated web UI
+// requests are redirected to /login, requests changing anything are checked for CSRF and audited.
+func (a *Auth) Protect(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		identity, ok := a.authenticate(w, r)
+		if !ok {
+
+			return
+
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), contextKey{}, identity))
+
+		if safe(r) {
+
+			if ranks[identity.Role] < ranks[RoleViewer] {
+
+				a.deny(w, r, http.StatusForbidden, "role "+identity.Role+" is not allowed")
+
+				return
+
+			}
+
+			next.ServeHTTP(w, r)
+
+			return
+
+		}
+
+		action := action(r)
+
+		if required := required(r); ranks[identity.Role] < ranks[required] {
+
+			Audit(a.engine.Session(), r, "denied", action+" requires role "+required)
+			a.deny(w, r, http.StatusForbidden, action+" requires role "+required)
+
+			return
+
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		Audit(a.engine.Session(), r, action, "status "+strconv.Itoa(recorder.status))
+
+	})
+
+}
+
+// Login serve the login page on GET and sign in on POST
+func (a *Auth) Login(w http.ResponseWriter, r *http.Request) {
+
+	users, tokens := Load(a.viperData)
+
+	if len(users) == 0 && len(tokens) == 0 { /* Authentication is disabled */
+
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+
+		return
+
+	}
+
+	data := struct {
+		CSRF  string
+		Error string
+	}{}
+
+	/* The session is started on login, the login page carries the CSRF token of the csrf cookie */
+	if r.Method != "POST" {
+
+		var err error
+
+		if data.CSRF, err = a.newCSRF(w, r); err != nil {
+
+			a.fail(w, r, err)
+
+			return
+
+		}
+
+		functions.ExecuteNamedTemplate(w, "login.html", data) /* This is the template execution for 'login' */
+
+		return
+
+	}
+
+	if data.CSRF = a.cookieCSRF(r); !validCSRF(r.PostFormValue(csrfField), data.CSRF) {
+
+		http.Error(w, "Invalid CSRF token, reload the page", http.StatusForbidden)
+
+		return
+
+	}
+
+	if wait := a.blocked(r); wait > 0 {
+
+		data.Error = "Too many failed logins, try again later"
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait/time.Second)+1))
+		w.WriteHeader(http.StatusTooManyRequests)
+		functions.ExecuteNamedTemplate(w, "login.html", data) /* This is the template execution for 'login' */
+
+		return
+
+	}
+
+	name := r.PostFormValue("user")
+
+	user, found := findUser(users, name)
+	if !found {
+
+		user.Password = string(dummyHash)
+
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(r.PostFormValue("password"))); err != nil || !found {
+
+		a.failed(r)
+		Audit(a.engine.Session(), r, "login failed", "user "+name)
+
+		data.Error = "Invalid user or password"
+		w.WriteHeader(http.StatusUnauthorized)
+		functions.ExecuteNamedTemplate(w, "login.html", data) /* This is the template execution for 'login' */
+
+		return
+
+	}
+
+	a.succeeded(r)
+
+	/* A new session on login, so a session id set before login is never signed in */
+	a.remove(r)
+
+	if _, err := a.newSession(w, r, user.Name); err != nil {
+
+		a.fail(w, r, err)
+
+		return
+
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), contextKey{}, Identity{Name: user.Name, Role: user.Role}))
+	Audit(a.engine.Session(), r, "login", "")
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+
+}
+
+// Logout sign out the session of a POST request carrying its CSRF token
+func (a *Auth) Logout(w http.ResponseWriter, r *http.Request) {
+
+	s := a.session(r)
+
+	if r.Method != "POST" || s == nil || !validCSRF(r.PostFormValue(csrfField), s.csrf) {
+
+		http.Error(w, "Invalid CSRF token, reload the page", http.StatusForbidden)
+
+		return
+
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), contextKey{}, Identity{Name: s.user}))
+	Audit(a.engine.Session(), r, "logout", "")
+
+	a.remove(r)
+
+	http.SetCookie(w, &http.Cookie{Name: cookieName, Value: "", Path: "/", MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteStrictMode})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+
+}
+
+// Audit log action on behalf of the identity of r and save it in the audit log of the session thread
+func Audit(sessionData *types.Session, r *http.Request, action string, detail string) {
+
+	identity := FromRequest(r)
+
+	if identity.Name != "" {
+
+		detail += " by " + identity.Name
+
+	}
+
+	if identity.Role != "" {
+
+		detail += " (" + identity.Role + ")"
+
+	}
+
+	detail = strings.TrimSpace(detail + " from " + clientIP(r))
+
+	logger.LogEntry{ /* Log Entry */
+		Config:   nil,
+		Market:   nil,
+		Session:  sessionData,
+		Order:    &types.Order{},
+		Message:  "AUDIT - " + action + " " + detail,
+		LogLevel: "InfoLevel",
+	}.Do()
+
+	if len(action) > 45 { /* Size of the Action column */
+
+		action = action[:45]
+
+	}
+
+	hostname, _ := os.Hostname()
+
+	/* The request is served even when the audit record is not saved */
+	_ = sessionData.Storage.SaveAudit(context.Background(), sessionData, types.Audit{
+		Time:     time.Now().UnixNano() / int64(time.Millisecond),
+		ThreadID: sessionData.ThreadID,
+		Node:     net.JoinHostPort(hostname, sessionData.Port),
+		Action:   action,
+		Detail:   detail,
+	})
+
+}
+
+// Mask return secret with all but its last 4 characters hidden, for display
+func Mask(secret string) string {
+
+	switch {
+	case secret == "":
+
+		return ""
+
+	case len(secret) <= 8:
+
+		return strings.Repeat("*", len(secret))
+
+	}
+
+	return strings.Repeat("*", 8) + secret[len(secret)-4:]
+
+}
+
+/* Return the identity of r, or write the failure and return false */
+func (a *Auth) authenticate(w http.ResponseWriter, r *http.Request) (Identity, bool) {
+
+	users, tokens := Load(a.viperData)
+	disabled := len(users) == 0 && len(tokens) == 0
+
+	/* API token */
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+
+		sum := hash(strings.TrimPrefix(header, "Bearer "))
+
+		for _, token := range tokens {
+
+			if subtle.ConstantTimeCompare([]byte(sum), []byte(token.Hash)) == 1 {
+
+				return Identity{Name: "token " + token.Name, Role: token.Role}, true
+
+			}
+
+		}
+
+		a.deny(w, r, http.StatusUnauthorized, "invalid API token")
+
+		return Identity{}, false
+
+	}
+
+	identity := Identity{Name: Anonymous, Role: RoleAdmin}
+
+	s := a.session(r)
+
+	switch {
+	case s != nil:
+
+		user, found := findUser(users, s.user)
+		if !found { /* Removed since login */
+
+			a.remove(r)
+			a.deny(w, r, http.StatusUnauthorized, "authentication required")
+
+			return Identity{}, false
+
+		}
+
+		identity = Identity{Name: user.Name, Role: user.Role, CSRF: s.csrf}
+
+	case !disabled:
+
+		a.deny(w, r, http.StatusUnauthorized, "authentication required")
+
+		return Identity{}, false
+
+	case !local(r): /* Anyone reaching the node would be an admin */
+
+		a.deny(w, r, http.StatusForbidden, "authentication is disabled, add a user with cryptopump user add to connect from other hosts")
+
+		return Identity{}, false
+
+	case safe(r): /* The CSRF token of the csrf cookie while authentication is disabled */
+
+		var err error
+
+		if identity.CSRF, err = a.newCSRF(w, r); err != nil {
+
+			a.fail(w, r, err)
+
+			return Identity{}, false
+
+		}
+
+	case isAPI(r) && strings.HasPrefix(r.Header.Get("Content-Type"), "application/json"):
+
+		/* Browsers don't send JSON across sites without CORS, scripts can use the API without a CSRF token */
+		return identity, true
+
+	default:
+
+		identity.CSRF = a.cookieCSRF(r)
+
+	}
+
+	if !safe(r) {
+
+		token := r.Header.Get(csrfHeader)
+		if token == "" {
+
+			token = r.PostFormValue(csrfField)
+
+		}
+
+		if !validCSRF(token, identity.CSRF) {
+
+			a.deny(w, r, http.StatusForbidden, "invalid CSRF token, reload the page")
+
+			return Identity{}, false
+
+		}
+
+	}
+
+	return identity, true
+
+}
+
+/* Write a failed request, as JSON for the API. Unauthenticated web UI requests are redirected to /login. */
+func (a *Auth) deny(w http.ResponseWriter, r *http.Request, status int, message string) {
+
+	if isAPI(r) {
+
+		if status == http.StatusUnauthorized {
+
+			w.Header().Set("WWW-Authenticate", "Bearer")
+
+		}
+
+		w.Header().Set("Content-Type", "application/json") /* Set the Content-Type header */
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(struct {
+			Error string `json:"error"`
+		}{message})
+
+		return
+
+	}
+
+	if status == http.StatusUnauthorized {
+
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+
+		return
+
+	}
+
+	http.Error(w, message, status)
+
+}
+
+/* Log err and write an internal server error */
+func (a *Auth) fail(w http.ResponseWriter, r *http.Request, err error) {
+
+	logger.LogEntry{ /* Log Entry */
+		Config:   nil,
+		Market:   nil,
+		Session:  nil,
+		Order:    &types.Order{},
+		Message:  functions.GetFunctionName() + " - " + err.Error(),
+		LogLevel: "DebugLevel",
+	}.Do()
+
+	a.deny(w, r, http.StatusInternalServerError, "unable to start a session")
+
+}
+
+/* Return the unexpired session of the cookie of r, renewed, or nil */
+func (a *Auth) session(r *http.Request) *session {
+
+	cookie, err := r.Cookie(cookieName)
+	if err != nil {
+
+		return nil
+
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	s, ok := a.sessions[cookie.Value]
+	if !ok || time.Now().After(s.expires) {
+
+		delete(a.sessions, cookie.Value)
+
+		return nil
+
+	}
+
+	s.expires = time.Now().Add(sessionTTL)
+	copied := *s
+
+	return &copied
+
+}
+
+/* Start a session for user and set its cookie */
+func (a *Auth) newSession(w http.ResponseWriter, r *http.Request, user string) (*session, error) {
+
+	id, err := random()
+	if err != nil {
+
+		return nil, err
+
+	}
+
+	s := &session{user: user, expires: time.Now().Add(sessionTTL)}
+	if s.csrf, err = random(); err != nil {
+
+		return nil, err
+
+	}
+
+	a.mutex.Lock()
+
+	for key, expired := range a.sessions { /* Drop expired sessions */
+
+		if time.Now().After(expired.expires) {
+
+			delete(a.sessions, key)
+
+		}
+
+	}
+
+	for len(a.sessions) >= maxSessions { /* Drop the session expiring first */
+
+		first := ""
+		for key, other := range a.sessions {
+
+			if first == "" || other.expires.Before(a.sessions[first].expires) {
+
+				first = key
+
+			}
+
+		}
+
+		delete(a.sessions, first)
+
+	}
+
+	a.sessions[id] = s
+	copied := *s
+
+	a.mutex.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	return &copied, nil
+
+}
+
+/* Remove the session of the cookie of r */
+func (a *Auth) remove(r *http.Request) {
+
+	if cookie, err := r.Cookie(cookieName); err == nil {
+
+		a.mutex.Lock()
+		delete(a.sessions, cookie.Value)
+		a.mutex.Unlock()
+
+	}
+
+}
+
+/* Return the CSRF token of the csrf cookie of r, empty without the cookie */
+func (a *Auth) cookieCSRF(r *http.Request) string {
+
+	cookie, err := r.Cookie(csrfCookie)
+	if err != nil || cookie.Value == "" {
+
+		return ""
+
+	}
+
+	return a.sign(cookie.Value)
+
+}
+
+// Return the CSRF token of the csrf cookie of r, setting a new csrf cookie when r has none. The token is
+// an HMAC of the cookie, so no server state is kept for clients without a session and a cookie set by
+// another site is of no use without the token.
+func (a *Auth) newCSRF(w http.ResponseWriter, r *http.Request) (string, error) {
+
+	if csrf := a.cookieCSRF(r); csrf != "" {
+
+		return csrf, nil
+
+	}
+
+	nonce, err := random()
+	if err != nil {
+
+		return "", err
+
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    nonce,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	return a.sign(nonce), nil
+
+}
+
+/* Return the CSRF token of the nonce of a csrf cookie */
+func (a *Auth) sign(nonce string) string {
+
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(nonce))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+}
+
+/* Return how long logins of the client of r are refused, 0 when they are not */
+func (a *Auth) blocked(r *http.Request) time.Duration {
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	f, ok := a.failures[clientIP(r)]
+	if !ok || f.count < loginAttempts {
+
+		return 0
+
+	}
+
+	if wait := time.Until(f.reset); wait > 0 {
+
+		return wait
+
+	}
+
+	return 0
+
+}
+
+/* Count a failed login of the client of r */
+func (a *Auth) failed(r *http.Request) {
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	client := clientIP(r)
+
+	f, ok := a.failures[client]
+	if ok && time.Now().Before(f.reset) {
+
+		f.count++
+
+		return
+
+	}
+
+	for key, expired := range a.failures { /* Drop the clients of past windows */
+
+		if time.Now().After(expired.reset) {
+
+			delete(a.failures, key)
+
+		}
+
+	}
+
+	for len(a.failures) >= maxClients { /* Drop the client unblocked first */
+
+		first := ""
+		for key, other := range a.failures {
+
+			if first == "" || other.reset.Before(a.failures[first].reset) {
+
+				first = key
+
+			}
+
+		}
+
+		delete(a.failures, first)
+
+	}
+
+	a.failures[client] = &failure{count: 1, reset: time.Now().Add(loginWindow)}
+
+}
+
+/* Forget the failed logins of the client of r after a login */
+func (a *Auth) succeeded(r *http.Request) {
+
+	a.mutex.Lock()
+	delete(a.failures, clientIP(r))
+	a.mutex.Unlock()
+
+}
+
+/* statusRecorder keep the status written by a handler for the audit log */
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+
+}
+
+/* Return the role required by a request changing something */
+func required(r *http.Request) string {
+
+	switch r.PostFormValue("submitselect") {
+	case "adminEnter", "adminExit": /* Exchange API keys and Telegram token */
+
+		return RoleAdmin
+
+	}
+
+	return RoleTrader
+
+}
+
+/* Return the audit log action of a request changing something, i.e. "web buy" or "api POST /api/v1/buy" */
+func action(r *http.Request) string {
+
+	if isAPI(r) {
+
+		return "api " + r.Method + " " + r.URL.Path
+
+	}
+
+	if r.URL.Path == "/" {
+
+		return "web " + r.PostFormValue("submitselect")
+
+	}
+
+	return "web " + r.Method + " " + r.URL.Path
+
+}
+
+/* Return true when r doesn't change anything */
+func safe(r *http.Request) bool {
+
+	return r.Method == "GET" || r.Method == "HEAD"
+
+}
+
+/* Return true for JSON API requests */
+func isAPI(r *http.Request) bool {
+
+	return strings.HasPrefix(r.URL.Path, "/api/")
+
+}
+
+/* Return the IP address of the client of r. X-Forwarded-For is not used, clients can set it. */
+func clientIP(r *http.Request) string {
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+
+		return r.RemoteAddr
+
+	}
+
+	return host
+
+}
+
+/* Return true when the client of r is on this host */
+func local(r *http.Request) bool {
+
+	ip := net.ParseIP(clientIP(r))
+
+	return ip != nil && ip.IsLoopback()
+
+}
+
+/* Return true when token is the CSRF token of the session */
+func validCSRF(token string, csrf string) bool {
+
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(csrf)) == 1
+
+}
+
+/* Return the user called name */
+func findUser(users []User, name string) (User, bool) {
+
+	for _, user := range users {
+
+		if user.Name == name && name != "" {
+
+			return user, true
+
+		}
+
+	}
+
+	return User{}, false
+
+}
+
+/* Return 32 random bytes, base64 encoded */
+func random() (string, error) {
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+
+		return "", err
+
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+
+}
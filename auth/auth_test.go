@@ -0,0 +1,432 @@
+package auth
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aleibovici/cryptopump/engine"
+	"github.com/aleibovici/cryptopump/migrations"
+	"github.com/aleibovici/cryptopump/sqlite"
+	"github.com/aleibovici/cryptopump/types"
+	"github.com/spf13/viper"
+)
+
+/* Return an Auth reading a new config_global.yml, with the audit log in a new migrated database */
+func newAuth(t *testing.T) (*Auth, *types.Session) {
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config_global.yml")
+
+	if err := ioutil.WriteFile(path, []byte("config_global:\n  storage: sqlite\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	viperData := &types.ViperData{V1: viper.New(), V2: viper.New()}
+	viperData.V2.SetConfigFile(path)
+	if err := viperData.V2.ReadInConfig(); err != nil {
+		t.Fatalf("ReadInConfig() error = %v", err)
+	}
+
+	db, err := sqlite.DBInit(filepath.Join(dir, "cryptopump.db"))
+	if err != nil {
+		t.Fatalf("DBInit() error = %v", err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := (migrations.Migrator{Db: db, Backend: "sqlite"}).Up(); err != nil {
+		t.Fatalf("Migrator.Up() error = %v", err)
+	}
+
+	sessionData := &types.Session{Db: db, Storage: sqlite.Storage{}, Global: &types.Global{}, Port: "8080"}
+
+	e := engine.New(&types.Config{}, &types.Market{}, sessionData)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go e.Run(ctx)
+
+	t.Cleanup(func() {
+		cancel()
+		<-e.Done()
+	})
+
+	return New(viperData, e), e.Session()
+
+}
+
+/* Handler writing the identity of the request */
+var next = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	identity := FromRequest(r)
+	w.Write([]byte(identity.Name + " " + identity.Role))
+})
+
+/* Send a request from localhost with form values, the session cookie and the headers to handler */
+func send(handler http.Handler, method string, path string, form url.Values, cookie *http.Cookie, headers map[string]string) *httptest.ResponseRecorder {
+
+	return sendFrom("127.0.0.1:1234", handler, method, path, form, cookie, headers)
+
+}
+
+/* Send a request from addr with form values, the session cookie and the headers to handler */
+func sendFrom(addr string, handler http.Handler, method string, path string, form url.Values, cookie *http.Cookie, headers map[string]string) *httptest.ResponseRecorder {
+
+	r := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	r.RemoteAddr = addr
+	if form != nil {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	for key, value := range headers {
+		r.Header.Set(key, value)
+	}
+
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	return w
+
+}
+
+/* Return the session cookie set by w and its CSRF token */
+func sessionCookie(t *testing.T, a *Auth, w *httptest.ResponseRecorder) (*http.Cookie, string) {
+
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == cookieName {
+			a.mutex.Lock()
+			defer a.mutex.Unlock()
+			return cookie, a.sessions[cookie.Value].csrf
+		}
+	}
+
+	t.Fatalf("no session cookie, status %v", w.Code)
+
+	return nil, ""
+
+}
+
+/* Return the csrf cookie set by w and its CSRF token */
+func csrfCookieOf(t *testing.T, a *Auth, w *httptest.ResponseRecorder) (*http.Cookie, string) {
+
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == csrfCookie {
+			return cookie, a.sign(cookie.Value)
+		}
+	}
+
+	t.Fatalf("no csrf cookie, status %v", w.Code)
+
+	return nil, ""
+
+}
+
+/* Post the login form of name from addr with the csrf cookie of the login page */
+func postLogin(t *testing.T, a *Auth, addr string, name string, password string) *httptest.ResponseRecorder {
+
+	cookie, csrf := csrfCookieOf(t, a, sendFrom(addr, http.HandlerFunc(a.Login), "GET", "/login", nil, nil, nil))
+
+	return sendFrom(addr, http.HandlerFunc(a.Login), "POST", "/login", url.Values{"csrf": {csrf}, "user": {name}, "password": {password}}, cookie, nil)
+
+}
+
+/* Sign in name and return the session cookie and its CSRF token */
+func login(t *testing.T, a *Auth, name string, password string) (*http.Cookie, string) {
+
+	w := postLogin(t, a, "127.0.0.1:1234", name, password)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("Login() status = %v, want %v", w.Code, http.StatusSeeOther)
+	}
+
+	return sessionCookie(t, a, w)
+
+}
+
+func TestAuth_Protect(t *testing.T) {
+
+	a, sessionData := newAuth(t)
+
+	for _, user := range []User{{"alice", "alice-password", RoleAdmin}, {"bob", "bob-password", RoleViewer}, {"carol", "carol-password", RoleTrader}} {
+		if err := SetUser(a.viperData, user.Name, user.Password, user.Role); err != nil {
+			t.Fatalf("SetUser() error = %v", err)
+		}
+	}
+
+	token, err := NewToken(a.viperData, "script", RoleTrader)
+	if err != nil {
+		t.Fatalf("NewToken() error = %v", err)
+	}
+
+	cookies := map[string]*http.Cookie{}
+	csrf := map[string]string{}
+	for _, name := range []string{"alice", "bob", "carol"} {
+		cookies[name], csrf[name] = login(t, a, name, name+"-password")
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		form     url.Values
+		user     string
+		headers  map[string]string
+		want     int
+		wantBody string
+	}{
+		{"unauthenticated", "GET", "/", nil, "", nil, http.StatusSeeOther, ""},
+		{"unauthenticated api", "GET", "/api/v1/session", nil, "", nil, http.StatusUnauthorized, `{"error":"authentication required"}`},
+		{"viewer", "GET", "/", nil, "bob", nil, http.StatusOK, "bob viewer"},
+		{"viewer buy", "POST", "/", url.Values{"submitselect": {"buy"}}, "bob", nil, http.StatusForbidden, "web buy requires role trader"},
+		{"trader invalid csrf", "POST", "/", url.Values{"submitselect": {"buy"}, "csrf": {"x"}}, "carol", nil, http.StatusForbidden, "invalid CSRF token"},
+		{"trader buy", "POST", "/", url.Values{"submitselect": {"buy"}}, "carol", nil, http.StatusOK, "carol trader"},
+		{"trader admin", "POST", "/", url.Values{"submitselect": {"adminEnter"}}, "carol", nil, http.StatusForbidden, "web adminEnter requires role admin"},
+		{"admin", "POST", "/", url.Values{"submitselect": {"adminEnter"}}, "alice", nil, http.StatusOK, "alice admin"},
+		{"api session csrf", "POST", "/api/v1/buy", nil, "carol", nil, http.StatusForbidden, "invalid CSRF token"},
+		{"api session", "POST", "/api/v1/buy", nil, "carol", map[string]string{csrfHeader: ""}, http.StatusOK, "carol trader"},
+		{"api token", "POST", "/api/v1/buy", nil, "", map[string]string{"Authorization": "Bearer " + token, "X-Forwarded-For": "10.0.0.1"}, http.StatusOK, "token script trader"},
+		{"api invalid token", "POST", "/api/v1/buy", nil, "", map[string]string{"Authorization": "Bearer x"}, http.StatusUnauthorized, `{"error":"invalid API token"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			headers := tt.headers
+			if _, ok := headers[csrfHeader]; ok {
+				headers = map[string]string{csrfHeader: csrf[tt.user]}
+			}
+
+			form := tt.form
+			if form != nil && form.Get("csrf") == "" {
+				form.Set("csrf", csrf[tt.user])
+			}
+
+			w := send(a.Protect(next), tt.method, tt.path, form, cookies[tt.user], headers)
+
+			if w.Code != tt.want {
+				t.Errorf("Protect() status = %v, want %v, body %q", w.Code, tt.want, w.Body.String())
+			}
+
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("Protect() body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+
+		})
+	}
+
+	/* Wrong password */
+	if w := postLogin(t, a, "127.0.0.1:1234", "carol", "x"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Login() status = %v, want %v", w.Code, http.StatusUnauthorized)
+	}
+
+	/* The login page and failed logins don't start sessions */
+	if len(a.sessions) != 3 {
+		t.Errorf("sessions = %v, want 3", len(a.sessions))
+	}
+
+	/* Logout ends the session */
+	if w := send(http.HandlerFunc(a.Logout), "POST", "/logout", url.Values{"csrf": {csrf["bob"]}}, cookies["bob"], nil); w.Code != http.StatusSeeOther {
+		t.Errorf("Logout() status = %v, want %v", w.Code, http.StatusSeeOther)
+	}
+
+	if w := send(a.Protect(next), "GET", "/", nil, cookies["bob"], nil); w.Code != http.StatusSeeOther {
+		t.Errorf("Protect() after logout status = %v, want %v", w.Code, http.StatusSeeOther)
+	}
+
+	/* Changes, denied requests and logins are audited */
+	audit, err := sessionData.Storage.GetAudit(context.Background(), sessionData, 0)
+	if err != nil {
+		t.Fatalf("GetAudit() error = %v", err)
+	}
+
+	want := map[string]string{
+		"web buy":              "status 200 by carol (trader)",
+		"denied":               "web buy requires role trader by bob (viewer)",
+		"api POST /api/v1/buy": "status 200 by token script (trader) from 127.0.0.1", /* X-Forwarded-For is not trusted */
+		"login failed":         "user carol",
+		"logout":               "by bob",
+	}
+
+	for _, record := range audit {
+		if detail, ok := want[record.Action]; ok && strings.Contains(record.Detail, detail) {
+			delete(want, record.Action)
+		}
+	}
+
+	if len(want) != 0 {
+		t.Errorf("GetAudit() = %+v, missing %v", audit, want)
+	}
+
+}
+
+func TestAuth_Protect_disabled(t *testing.T) {
+
+	a, _ := newAuth(t)
+
+	/* The first page sets the csrf cookie of its CSRF token */
+	w := send(a.Protect(next), "GET", "/", nil, nil, nil)
+	if w.Code != http.StatusOK || w.Body.String() != "anonymous admin" {
+		t.Errorf("Protect() = %v %q, want %v %q", w.Code, w.Body.String(), http.StatusOK, "anonymous admin")
+	}
+
+	cookie, csrf := csrfCookieOf(t, a, w)
+
+	if len(a.sessions) != 0 {
+		t.Errorf("sessions = %v, want none", len(a.sessions))
+	}
+
+	tests := []struct {
+		name    string
+		addr    string
+		method  string
+		path    string
+		form    url.Values
+		cookie  *http.Cookie
+		headers map[string]string
+		want    int
+	}{
+		{"without cookie", "127.0.0.1:1234", "POST", "/", url.Values{"submitselect": {"buy"}, "csrf": {csrf}}, nil, nil, http.StatusForbidden},
+		{"without csrf", "127.0.0.1:1234", "POST", "/", url.Values{"submitselect": {"buy"}}, cookie, nil, http.StatusForbidden},
+		{"with csrf", "127.0.0.1:1234", "POST", "/", url.Values{"submitselect": {"adminEnter"}, "csrf": {csrf}}, cookie, nil, http.StatusOK},
+		{"with ipv6 localhost", "[::1]:1234", "POST", "/", url.Values{"submitselect": {"adminEnter"}, "csrf": {csrf}}, cookie, nil, http.StatusOK},
+		{"with forged cookie", "127.0.0.1:1234", "POST", "/", url.Values{"submitselect": {"buy"}, "csrf": {cookie.Value}}, cookie, nil, http.StatusForbidden},
+		{"api json", "127.0.0.1:1234", "POST", "/api/v1/buy", nil, nil, map[string]string{"Content-Type": "application/json"}, http.StatusOK},
+		{"api form", "127.0.0.1:1234", "POST", "/api/v1/buy", url.Values{}, nil, nil, http.StatusForbidden},
+		{"remote page", "192.0.2.1:1234", "GET", "/", nil, nil, nil, http.StatusForbidden},
+		{"remote with csrf", "192.0.2.1:1234", "POST", "/", url.Values{"submitselect": {"buy"}, "csrf": {csrf}}, cookie, nil, http.StatusForbidden},
+		{"remote api json", "192.0.2.1:1234", "POST", "/api/v1/buy", nil, nil, map[string]string{"Content-Type": "application/json"}, http.StatusForbidden},
+		{"remote forwarded for localhost", "192.0.2.1:1234", "GET", "/", nil, nil, map[string]string{"X-Forwarded-For": "127.0.0.1"}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			if w := sendFrom(tt.addr, a.Protect(next), tt.method, tt.path, tt.form, tt.cookie, tt.headers); w.Code != tt.want {
+				t.Errorf("Protect() status = %v, want %v, body %q", w.Code, tt.want, w.Body.String())
+			}
+
+		})
+	}
+
+	if w := send(http.HandlerFunc(a.Login), "GET", "/login", nil, nil, nil); w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/" {
+		t.Errorf("Login() = %v %q, want a redirect to /", w.Code, w.Header().Get("Location"))
+	}
+
+}
+
+func TestAuth_Login_blocked(t *testing.T) {
+
+	a, _ := newAuth(t)
+
+	if err := SetUser(a.viperData, "alice", "alice-password", RoleAdmin); err != nil {
+		t.Fatalf("SetUser() error = %v", err)
+	}
+
+	for i := 0; i < loginAttempts; i++ {
+		if w := postLogin(t, a, "192.0.2.1:1234", "alice", "x"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("Login() attempt %v status = %v, want %v", i+1, w.Code, http.StatusUnauthorized)
+		}
+	}
+
+	/* Refused with the right password until the window is over */
+	w := postLogin(t, a, "192.0.2.1:5678", "alice", "alice-password")
+	if retry, err := strconv.Atoi(w.Header().Get("Retry-After")); w.Code != http.StatusTooManyRequests || err != nil || retry < 1 || retry > 900 {
+		t.Errorf("Login() = %v, Retry-After %q, want %v, 1 to 900", w.Code, w.Header().Get("Retry-After"), http.StatusTooManyRequests)
+	}
+
+	/* Other clients are not refused */
+	if w := postLogin(t, a, "192.0.2.2:1234", "alice", "alice-password"); w.Code != http.StatusSeeOther {
+		t.Errorf("Login() from another client status = %v, want %v", w.Code, http.StatusSeeOther)
+	}
+
+	a.failures["192.0.2.1"].reset = time.Now().Add(-time.Second)
+
+	if w := postLogin(t, a, "192.0.2.1:1234", "alice", "alice-password"); w.Code != http.StatusSeeOther {
+		t.Errorf("Login() after the window status = %v, want %v", w.Code, http.StatusSeeOther)
+	}
+
+	if _, ok := a.failures["192.0.2.1"]; ok {
+		t.Errorf("failures = %+v after login, want the client forgotten", a.failures)
+	}
+
+}
+
+func TestAuth_newSession(t *testing.T) {
+
+	a, _ := newAuth(t)
+
+	if err := SetUser(a.viperData, "alice", "alice-password", RoleAdmin); err != nil {
+		t.Fatalf("SetUser() error = %v", err)
+	}
+
+	/* The session expiring first is dropped */
+	for i := 0; i < maxSessions; i++ {
+		a.sessions[strconv.Itoa(i)] = &session{user: "alice", expires: time.Now().Add(sessionTTL + time.Duration(i)*time.Second)}
+	}
+
+	a.sessions["0"].expires = time.Now().Add(time.Minute)
+
+	login(t, a, "alice", "alice-password")
+
+	if _, ok := a.sessions["0"]; ok || len(a.sessions) != maxSessions {
+		t.Errorf("sessions = %v, session 0 kept %v, want %v without session 0", len(a.sessions), ok, maxSessions)
+	}
+
+}
+
+func TestActor(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		identity Identity
+		want     string
+	}{
+		{"user", Identity{Name: "alice", Role: RoleAdmin}, "alice from 192.0.2.1"},
+		{"token", Identity{Name: "token script", Role: RoleTrader}, "token script from 192.0.2.1"},
+		{"no identity", Identity{}, "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			r := httptest.NewRequest("POST", "/pause", nil)
+			r.RemoteAddr = "192.0.2.1:1234"
+			r.Header.Set("X-Forwarded-For", "10.0.0.1") /* Set by the client, not trusted */
+			r = r.WithContext(context.WithValue(r.Context(), contextKey{}, tt.identity))
+
+			if got := Actor(r); got != tt.want {
+				t.Errorf("Actor() = %q, want %q", got, tt.want)
+			}
+
+		})
+	}
+
+}
+
+func TestMask(t *testing.T) {
+
+	tests := []struct {
+		secret string
+		want   string
+	}{
+		{"", ""},
+		{"abc", "***"},
+		{"vmPUZE6mv9SD5VNHk4HlWFsOr6aKE2zvsw0MuIgwCIPy6utIco14y7Ju91duEh8A", "********Eh8A"},
+	}
+
+	for _, tt := range tests {
+		if got := Mask(tt.secret); got != tt.want {
+			t.Errorf("Mask(%q) = %q, want %q", tt.secret, got, tt.want)
+		}
+	}
+
+}
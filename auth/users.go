@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/aleibovici/cryptopump/types"
+	"golang.org/x/crypto/bcrypt"
+)
+
+/* Minimum password length */
+const minPassword = 8
+
+var (
+	// ErrRole is returned for a role other than viewer, trader or admin
+	ErrRole = errors.New("role must be viewer, trader or admin")
+
+	// ErrName is returned for an empty user or token name
+	ErrName = errors.New("name is required")
+
+	// ErrPassword is returned for a password shorter than 8 characters
+	ErrPassword = errors.New("password must have at least 8 characters")
+
+	// ErrNotFound is returned when removing an unknown user or token
+	ErrNotFound = errors.New("not found")
+)
+
+// Load return the users and tokens of config_global.yml
+func Load(viperData *types.ViperData) (users []User, tokens []Token) {
+
+	viperData.Mutex.Lock()
+	defer viperData.Mutex.Unlock()
+
+	return load(viperData)
+
+}
+
+// SetUser add the user name with password and role to config_global.yml, or change its password and role
+func SetUser(viperData *types.ViperData, name string, password string, role string) error {
+
+	switch {
+	case name == "":
+
+		return ErrName
+
+	case ranks[role] == 0:
+
+		return ErrRole
+
+	case len(password) < minPassword:
+
+		return ErrPassword
+
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+
+		return err
+
+	}
+
+	viperData.Mutex.Lock()
+	defer viperData.Mutex.Unlock()
+
+	users, tokens := load(viperData)
+
+	users = append(removeUser(users, name), User{Name: name, Password: string(hash), Role: role})
+
+	return save(viperData, users, tokens)
+
+}
+
+// RemoveUser remove the user name from config_global.yml
+func RemoveUser(viperData *types.ViperData, name string) error {
+
+	viperData.Mutex.Lock()
+	defer viperData.Mutex.Unlock()
+
+	users, tokens := load(viperData)
+
+	remaining := removeUser(users, name)
+	if len(remaining) == len(users) {
+
+		return ErrNotFound
+
+	}
+
+	return save(viperData, remaining, tokens)
+
+}
+
+// NewToken add the API token name with role to config_global.yml, replacing a token with the same name, and
+// return it. Only its hash is saved, the token can't be displayed again.
+func NewToken(viperData *types.ViperData, name string, role string) (string, error) {
+
+	switch {
+	case name == "":
+
+		return "", ErrName
+
+	case ranks[role] == 0:
+
+		return "", ErrRole
+
+	}
+
+	token, err := random()
+	if err != nil {
+
+		return "", err
+
+	}
+
+	viperData.Mutex.Lock()
+	defer viperData.Mutex.Unlock()
+
+	users, tokens := load(viperData)
+
+	tokens = append(removeToken(tokens, name), Token{Name: name, Hash: hash(token), Role: role})
+
+	return token, save(viperData, users, tokens)
+
+}
+
+// RemoveToken remove the API token name from config_global.yml
+func RemoveToken(viperData *types.ViperData, name string) error {
+
+	viperData.Mutex.Lock()
+	defer viperData.Mutex.Unlock()
+
+	users, tokens := load(viperData)
+
+	remaining := removeToken(tokens, name)
+	if len(remaining) == len(tokens) {
+
+		return ErrNotFound
+
+	}
+
+	return save(viperData, users, remaining)
+
+}
+
+/* Return the users and tokens of config_global.yml. viperData.Mutex must be held. */
+func load(viperData *types.ViperData) (users []User, tokens []Token) {
+
+	_ = viperData.V2.UnmarshalKey("config_global.users", &users)
+	_ = viperData.V2.UnmarshalKey("config_global.tokens", &tokens)
+
+	return users, tokens
+
+}
+
+/* Write users and tokens to config_global.yml. viperData.Mutex must be held. */
+func save(viperData *types.ViperData, users []User, tokens []Token) error {
+
+	userList := make([]interface{}, 0, len(users))
+	for _, user := range users {
+
+		userList = append(userList, map[string]interface{}{"name": user.Name, "password": user.Password, "role": user.Role})
+
+	}
+
+	tokenList := make([]interface{}, 0, len(tokens))
+	for _, token := range tokens {
+
+		tokenList = append(tokenList, map[string]interface{}{"name": token.Name, "hash": token.Hash, "role": token.Role})
+
+	}
+
+	/* Lists replace the previous lists as a whole, so removed users and tokens are not written */
+	viperData.V2.Set("config_global.users", userList)
+	viperData.V2.Set("config_global.tokens", tokenList)
+
+	return viperData.V2.WriteConfig()
+
+}
+
+/* Return users without the user name */
+func removeUser(users []User, name string) []User {
+
+	remaining := []User{}
+	for _, user := range users {
+
+		if user.Name != name {
+
+			remaining = append(remaining, user)
+
+		}
+
+	}
+
+	return remaining
+
+}
+
+/* Return tokens without the token name */
+func removeToken(tokens []Token, name string) []Token {
+
+	remaining := []Token{}
+	for _, token := range tokens {
+
+		if token.Name != name {
+
+			remaining = append(remaining, token)
+
+		}
+
+	}
+
+	return remaining
+
+}
+
+/* Return the SHA-256 of token, hex encoded */
+func hash(token string) string {
+
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/aleibovici/cryptopump/types"
+	"github.com/spf13/viper"
+)
+
+func TestSetUser(t *testing.T) {
+
+	a, _ := newAuth(t)
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		role     string
+		wantErr  error
+	}{
+		{"admin", "alice", "alice-password", RoleAdmin, nil},
+		{"change role", "alice", "alice-password2", RoleViewer, nil},
+		{"trader", "carol", "carol-password", RoleTrader, nil},
+		{"no name", "", "password", RoleViewer, ErrName},
+		{"unknown role", "bob", "bob-password", "root", ErrRole},
+		{"short password", "bob", "bob", RoleViewer, ErrPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetUser(a.viperData, tt.user, tt.password, tt.role); err != tt.wantErr {
+				t.Errorf("SetUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := NewToken(a.viperData, "script", RoleTrader); err != nil {
+		t.Fatalf("NewToken() error = %v", err)
+	}
+
+	if err := RemoveUser(a.viperData, "carol"); err != nil {
+		t.Errorf("RemoveUser() error = %v", err)
+	}
+
+	if err := RemoveUser(a.viperData, "carol"); err != ErrNotFound {
+		t.Errorf("RemoveUser() error = %v, want %v", err, ErrNotFound)
+	}
+
+	/* Saved in config_global.yml, with the other settings */
+	viperData := &types.ViperData{V1: viper.New(), V2: viper.New()}
+	viperData.V2.SetConfigFile(a.viperData.V2.ConfigFileUsed())
+	if err := viperData.V2.ReadInConfig(); err != nil {
+		t.Fatalf("ReadInConfig() error = %v", err)
+	}
+
+	users, tokens := Load(viperData)
+	if len(users) != 1 || users[0].Name != "alice" || users[0].Role != RoleViewer || users[0].Password == "alice-password2" {
+		t.Errorf("Load() users = %+v, want alice as a viewer with a hashed password", users)
+	}
+
+	if len(tokens) != 1 || tokens[0].Name != "script" || tokens[0].Role != RoleTrader {
+		t.Errorf("Load() tokens = %+v, want script as a trader", tokens)
+	}
+
+	if viperData.V2.GetString("config_global.storage") != "sqlite" {
+		t.Errorf("config_global.storage = %q, want sqlite", viperData.V2.GetString("config_global.storage"))
+	}
+
+	if err := RemoveToken(viperData, "script"); err != nil {
+		t.Errorf("RemoveToken() error = %v", err)
+	}
+
+	if _, tokens := Load(viperData); len(tokens) != 0 {
+		t.Errorf("Load() tokens = %+v after RemoveToken(), want none", tokens)
+	}
+
+}
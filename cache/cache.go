@@ -0,0 +1,444 @@
+package cache
+
+/* This package implements an in-memory cache of the thread reads made by the decision trees on every
+ticker event: last order prices and sides, and the Thread transactions (open positions). It wraps a
+storage backend, loads each value from the database on first use and drops the cached values of a
+thread on every write made through the storage, so the database stays the source of truth. Writes
+made outside the storage (another node, manual changes) are picked up after the cache expiry, or
+immediately with Invalidate. */
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aleibovici/cryptopump/types"
+)
+
+/* Time a cached value is used before it is loaded again from the database */
+const expiry = 30 * time.Second
+
+/* Cache keys */
+const (
+	keyPositions = "positions" /* Thread transactions, lowest price first */
+	keyLastSide  = "lastSide"  /* Last FILLED order side */
+	keyLastTwo   = "lastTwo"   /* Last two order sides */
+	keyLastPrice = "lastPrice" /* Last order price, followed by the side */
+)
+
+// Storage struct define a storage backend with cached thread reads. Methods not defined here are
+// those of the wrapped storage backend.
+type Storage struct {
+	types.Storage
+	mutex   sync.Mutex
+	threads map[string]*thread
+	expiry  time.Duration
+}
+
+/* Cached values of a thread */
+type thread struct {
+	generation int /* Incremented by every invalidation, so that values loaded before a write are not cached */
+	values     map[string]entry
+}
+
+/* Cached value */
+type entry struct {
+	value   interface{}
+	expires time.Time
+}
+
+var _ types.Storage = &Storage{} /* Storage must implement types.Storage */
+
+// New return storage with cached thread reads
+func New(storage types.Storage) *Storage {
+
+	return &Storage{
+		Storage: storage,
+		threads: map[string]*thread{},
+		expiry:  expiry,
+	}
+
+}
+
+// Degraded return true when the storage cached is in degraded mode
+func (s *Storage) Degraded() bool {
+
+	if storage, ok := s.Storage.(types.Degrader); ok {
+
+		return storage.Degraded()
+
+	}
+
+	return false
+
+}
+
+// Invalidate drop the cached values of the session thread when the session storage is cached.
+// It is the hook for writes made outside the storage.
+func Invalidate(sessionData *types.Session) {
+
+	if storage, ok := sessionData.Storage.(*Storage); ok {
+
+		storage.Invalidate(sessionData.ThreadID)
+
+	}
+
+}
+
+// Invalidate drop the cached values of threadID, or of all threads when threadID is empty
+func (s *Storage) Invalidate(threadID string) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for id, t := range s.threads {
+
+		if threadID == "" || id == threadID {
+
+			t.generation++
+			t.values = map[string]entry{}
+
+		}
+
+	}
+
+}
+
+/* Return the cached value of key for threadID, loading and caching it when missing or expired */
+func (s *Storage) get(
+	threadID string,
+	key string,
+	load func() (interface{}, error)) (interface{}, error) {
+
+	s.mutex.Lock()
+
+	t, ok := s.threads[threadID]
+	if !ok {
+
+		t = &thread{values: map[string]entry{}}
+		s.threads[threadID] = t
+
+	}
+
+	if e, ok := t.values[key]; ok && time.Now().Before(e.expires) {
+
+		s.mutex.Unlock()
+
+		return e.value, nil
+
+	}
+
+	generation := t.generation
+
+	s.mutex.Unlock()
+
+	/* Load without holding the lock, the database is slower than the ticker */
+	value, err := load()
+	if err != nil {
+
+		return nil, err
+
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if t.generation == generation {
+
+		t.values[key] = entry{value: value, expires: time.Now().Add(s.expiry)}
+
+	}
+
+	return value, nil
+
+}
+
+/* Return the cached Thread transactions of the session thread, lowest price first */
+func (s *Storage) positions(ctx context.Context, sessionData *types.Session) ([]types.Order, error) {
+
+	value, err := s.get(sessionData.ThreadID, keyPositions, func() (interface{}, error) {
+		return s.Storage.GetThreadTransactionAll(ctx, sessionData)
+	})
+
+	if err != nil {
+
+		return nil, err
+
+	}
+
+	return value.([]types.Order), nil
+
+}
+
+// GetLastOrderTransactionPrice return the cached price of the last order for Side
+func (s *Storage) GetLastOrderTransactionPrice(
+	ctx context.Context,
+	sessionData *types.Session,
+	Side string) (float64, error) {
+
+	value, err := s.get(sessionData.ThreadID, keyLastPrice+Side, func() (interface{}, error) {
+		return s.Storage.GetLastOrderTransactionPrice(ctx, sessionData, Side)
+	})
+
+	if err != nil {
+
+		return 0, err
+
+	}
+
+	return value.(float64), nil
+
+}
+
+// GetLastOrderTransactionSide return the cached side of the last FILLED order
+func (s *Storage) GetLastOrderTransactionSide(
+	ctx context.Context,
+	sessionData *types.Session) (string, error) {
+
+	value, err := s.get(sessionData.ThreadID, keyLastSide, func() (interface{}, error) {
+		return s.Storage.GetLastOrderTransactionSide(ctx, sessionData)
+	})
+
+	if err != nil {
+
+		return "", err
+
+	}
+
+	return value.(string), nil
+
+}
+
+// GetOrderTransactionSideLastTwo return the cached sides of the last two orders
+func (s *Storage) GetOrderTransactionSideLastTwo(
+	ctx context.Context,
+	sessionData *types.Session) (string, string, error) {
+
+	value, err := s.get(sessionData.ThreadID, keyLastTwo, func() (interface{}, error) {
+		side1, side2, err := s.Storage.GetOrderTransactionSideLastTwo(ctx, sessionData)
+		return [2]string{side1, side2}, err
+	})
+
+	if err != nil {
+
+		return "", "", err
+
+	}
+
+	sides := value.([2]string)
+
+	return sides[0], sides[1], nil
+
+}
+
+// GetThreadTransactionAll return a copy of the cached Thread transactions, lowest price first
+func (s *Storage) GetThreadTransactionAll(
+	ctx context.Context,
+	sessionData *types.Session) ([]types.Order, error) {
+
+	orders, err := s.positions(ctx, sessionData)
+	if err != nil {
+
+		return nil, err
+
+	}
+
+	return append([]types.Order(nil), orders...), nil
+
+}
+
+// GetThreadTransactionByPrice return the lowest price Thread transaction below market price
+func (s *Storage) GetThreadTransactionByPrice(
+	ctx context.Context,
+	marketData *types.Market,
+	sessionData *types.Session) (types.Order, error) {
+
+	orders, err := s.positions(ctx, sessionData)
+	if err != nil {
+
+		return types.Order{}, err
+
+	}
+
+	for _, order := range orders {
+
+		if order.Price < marketData.Price {
+
+			return order, nil
+
+		}
+
+	}
+
+	return types.Order{}, nil
+
+}
+
+// GetThreadTransactionByPriceHigher return the highest price Thread transaction above market price
+func (s *Storage) GetThreadTransactionByPriceHigher(
+	ctx context.Context,
+	marketData *types.Market,
+	sessionData *types.Session) (types.Order, error) {
+
+	orders, err := s.positions(ctx, sessionData)
+	if err != nil {
+
+		return types.Order{}, err
+
+	}
+
+	if n := len(orders); n > 0 && orders[n-1].Price > marketData.Price {
+
+		return orders[n-1], nil
+
+	}
+
+	return types.Order{}, nil
+
+}
+
+// GetThreadLastTransaction return the lowest price Thread transaction
+func (s *Storage) GetThreadLastTransaction(
+	ctx context.Context,
+	sessionData *types.Session) (types.Order, error) {
+
+	orders, err := s.positions(ctx, sessionData)
+	if err != nil || len(orders) == 0 {
+
+		return types.Order{}, err
+
+	}
+
+	return orders[0], nil
+
+}
+
+// GetThreadTransactionCount return the number of Thread transactions
+func (s *Storage) GetThreadTransactionCount(
+	ctx context.Context,
+	sessionData *types.Session) (int, error) {
+
+	orders, err := s.positions(ctx, sessionData)
+
+	return len(orders), err
+
+}
+
+// GetThreadTransactiontUpmarketPriceCount return the number of Thread transactions below price
+func (s *Storage) GetThreadTransactiontUpmarketPriceCount(
+	ctx context.Context,
+	sessionData *types.Session,
+	price float64) (count int, err error) {
+
+	orders, err := s.positions(ctx, sessionData)
+	if err != nil {
+
+		return 0, err
+
+	}
+
+	for _, order := range orders {
+
+		if order.Price < price {
+
+			count++
+
+		}
+
+	}
+
+	return count, nil
+
+}
+
+// SaveOrder save order and drop the cached values of the thread
+func (s *Storage) SaveOrder(
+	ctx context.Context,
+	sessionData *types.Session,
+	order *types.Order,
+	orderIDSource int64,
+	orderPrice float64) error {
+
+	defer s.Invalidate(sessionData.ThreadID)
+
+	return s.Storage.SaveOrder(ctx, sessionData, order, orderIDSource, orderPrice)
+
+}
+
+// UpdateOrder update order and drop the cached values of the thread
+func (s *Storage) UpdateOrder(
+	ctx context.Context,
+	sessionData *types.Session,
+	OrderID int64,
+	CumulativeQuoteQuantity float64,
+	ExecutedQuantity float64,
+	Price float64,
+	Status string) error {
+
+	defer s.Invalidate(sessionData.ThreadID)
+
+	return s.Storage.UpdateOrder(ctx, sessionData, OrderID, CumulativeQuoteQuantity, ExecutedQuantity, Price, Status)
+
+}
+
+// SaveThreadTransaction save Thread transaction and drop the cached values of the thread
+func (s *Storage) SaveThreadTransaction(
+	ctx context.Context,
+	sessionData *types.Session,
+	OrderID int64,
+	CumulativeQuoteQuantity float64,
+	Price float64,
+	ExecutedQuantity float64) error {
+
+	defer s.Invalidate(sessionData.ThreadID)
+
+	return s.Storage.SaveThreadTransaction(ctx, sessionData, OrderID, CumulativeQuoteQuantity, Price, ExecutedQuantity)
+
+}
+
+// DeleteThreadTransactionByOrderID delete Thread transaction and drop the cached values of the thread
+func (s *Storage) DeleteThreadTransactionByOrderID(
+	ctx context.Context,
+	sessionData *types.Session,
+	orderID int) error {
+
+	defer s.Invalidate(sessionData.ThreadID)
+
+	return s.Storage.DeleteThreadTransactionByOrderID(ctx, sessionData, orderID)
+
+}
+
+// OrderTx run f in one database transaction and drop the cached values of the thread, committed or not
+func (s *Storage) OrderTx(
+	ctx context.Context,
+	sessionData *types.Session,
+	f func(tx types.OrderTx) error) error {
+
+	defer s.Invalidate(sessionData.ThreadID)
+
+	return s.Storage.OrderTx(ctx, sessionData, f)
+
+}
+
+// ArchiveOrders archive closed orders and drop the cached values of all threads
+func (s *Storage) ArchiveOrders(
+	ctx context.Context,
+	sessionData *types.Session,
+	before int64) (int64, error) {
+
+	defer s.Invalidate("")
+
+	return s.Storage.ArchiveOrders(ctx, sessionData, before)
+
+}
+
+// DeleteSession delete session and drop the cached values of the thread
+func (s *Storage) DeleteSession(
+	ctx context.Context,
+	sessionData *types.Session) error {
+
+	defer s.Invalidate(sessionData.ThreadID)
+
+	return s.Storage.DeleteSession(ctx, sessionData)
+
+}
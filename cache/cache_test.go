@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aleibovici/cryptopump/migrations"
+	"github.com/aleibovici/cryptopump/sqlite"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+/* Return a session on a migrated SQLite database with cached storage */
+func newSession(t *testing.T) (*types.Session, *Storage) {
+
+	db, err := sqlite.DBInit(filepath.Join(t.TempDir(), "cryptopump.db"))
+	if err != nil {
+		t.Fatalf("DBInit() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := (migrations.Migrator{Db: db, Backend: "sqlite"}).Up(); err != nil {
+		t.Fatalf("Migrator.Up() error = %v", err)
+	}
+
+	storage := New(sqlite.Storage{})
+
+	return &types.Session{ThreadID: "c683ok5mk1u1120gnmmg", ThreadIDSession: "c683ok5mk1u1120gnmn0", Db: db, Storage: storage}, storage
+
+}
+
+/* Save a FILLED BUY order at price and its Thread transaction */
+func buy(t *testing.T, sessionData *types.Session, orderID int, price float64, transactTime int64) {
+
+	if err := sessionData.Storage.OrderTx(context.Background(), sessionData, func(tx types.OrderTx) error {
+		if err := tx.SaveOrder(&types.Order{OrderID: orderID, Side: "BUY", Status: "FILLED", Symbol: "BTCUSDT", Price: price, ExecutedQuantity: 1, CumulativeQuoteQuantity: price, TransactTime: transactTime}, 0, price); err != nil {
+			return err
+		}
+		return tx.SaveThreadTransaction(int64(orderID), price, price, 1)
+	}); err != nil {
+		t.Fatalf("OrderTx() error = %v", err)
+	}
+
+}
+
+func TestStorage_ThreadTransactions(t *testing.T) {
+
+	sessionData, storage := newSession(t)
+	backend := sqlite.Storage{}
+
+	buy(t, sessionData, 1, 100, 1000)
+	buy(t, sessionData, 2, 90, 2000)
+	buy(t, sessionData, 3, 110, 3000)
+
+	for _, price := range []float64{80, 90, 95, 105, 120} {
+
+		marketData := &types.Market{Price: price}
+
+		got, err := storage.GetThreadTransactionByPrice(context.Background(), marketData, sessionData)
+		want, _ := backend.GetThreadTransactionByPrice(context.Background(), marketData, sessionData)
+		if err != nil || got != want {
+			t.Errorf("GetThreadTransactionByPrice(context.Background(), %v) = %+v, %v, want %+v", price, got, err, want)
+		}
+
+		got, err = storage.GetThreadTransactionByPriceHigher(context.Background(), marketData, sessionData)
+		want, _ = backend.GetThreadTransactionByPriceHigher(context.Background(), marketData, sessionData)
+		if err != nil || got != want {
+			t.Errorf("GetThreadTransactionByPriceHigher(context.Background(), %v) = %+v, %v, want %+v", price, got, err, want)
+		}
+
+		count, err := storage.GetThreadTransactiontUpmarketPriceCount(context.Background(), sessionData, price)
+		wantCount, _ := backend.GetThreadTransactiontUpmarketPriceCount(context.Background(), sessionData, price)
+		if err != nil || count != wantCount {
+			t.Errorf("GetThreadTransactiontUpmarketPriceCount(context.Background(), %v) = %v, %v, want %v", price, count, err, wantCount)
+		}
+
+	}
+
+	got, err := storage.GetThreadLastTransaction(context.Background(), sessionData)
+	want, _ := backend.GetThreadLastTransaction(context.Background(), sessionData)
+	if err != nil || got != want || got.TransactTime != 2000 {
+		t.Errorf("GetThreadLastTransaction() = %+v, %v, want %+v", got, err, want)
+	}
+
+	/* Writes through the storage drop the cached Thread transactions */
+	if err := storage.DeleteThreadTransactionByOrderID(context.Background(), sessionData, 2); err != nil {
+		t.Fatalf("DeleteThreadTransactionByOrderID() error = %v", err)
+	}
+
+	if count, err := storage.GetThreadTransactionCount(context.Background(), sessionData); err != nil || count != 2 {
+		t.Errorf("GetThreadTransactionCount() after delete = %v, %v, want 2", count, err)
+	}
+
+	if got, err := storage.GetThreadLastTransaction(context.Background(), sessionData); err != nil || got.OrderID != 1 {
+		t.Errorf("GetThreadLastTransaction() after delete = %+v, %v, want order 1", got, err)
+	}
+
+}
+
+func TestStorage_Orders(t *testing.T) {
+
+	sessionData, storage := newSession(t)
+
+	buy(t, sessionData, 1, 100, 1000)
+
+	if price, err := storage.GetLastOrderTransactionPrice(context.Background(), sessionData, "BUY"); err != nil || price != 100 {
+		t.Errorf("GetLastOrderTransactionPrice() = %v, %v, want 100", price, err)
+	}
+
+	if err := storage.SaveOrder(context.Background(), sessionData, &types.Order{OrderID: 11, OrderIDSource: 1, Side: "SELL", Status: "FILLED", Symbol: "BTCUSDT", TransactTime: 2000}, 1, 120); err != nil {
+		t.Fatalf("SaveOrder() error = %v", err)
+	}
+
+	if price, err := storage.GetLastOrderTransactionPrice(context.Background(), sessionData, "SELL"); err != nil || price != 120 {
+		t.Errorf("GetLastOrderTransactionPrice() after SaveOrder() = %v, %v, want 120", price, err)
+	}
+
+	if side, err := storage.GetLastOrderTransactionSide(context.Background(), sessionData); err != nil || side != "SELL" {
+		t.Errorf("GetLastOrderTransactionSide() = %v, %v, want SELL", side, err)
+	}
+
+	if side1, side2, err := storage.GetOrderTransactionSideLastTwo(context.Background(), sessionData); err != nil || side1 != "SELL" || side2 != "BUY" {
+		t.Errorf("GetOrderTransactionSideLastTwo() = %v, %v, %v, want SELL, BUY", side1, side2, err)
+	}
+
+}
+
+func TestInvalidate(t *testing.T) {
+
+	sessionData, storage := newSession(t)
+
+	buy(t, sessionData, 1, 100, 1000)
+
+	if count, _ := storage.GetThreadTransactionCount(context.Background(), sessionData); count != 1 {
+		t.Fatalf("GetThreadTransactionCount() = %v, want 1", count)
+	}
+
+	/* Write made outside the storage */
+	if _, err := sessionData.Db.Exec(`DELETE FROM thread`); err != nil {
+		t.Fatalf("DELETE error = %v", err)
+	}
+
+	if count, _ := storage.GetThreadTransactionCount(context.Background(), sessionData); count != 1 {
+		t.Errorf("GetThreadTransactionCount() before Invalidate() = %v, want cached 1", count)
+	}
+
+	Invalidate(sessionData)
+
+	if count, _ := storage.GetThreadTransactionCount(context.Background(), sessionData); count != 0 {
+		t.Errorf("GetThreadTransactionCount() after Invalidate() = %v, want 0", count)
+	}
+
+	/* Expired values are loaded again */
+	storage.expiry = 0
+	buy(t, sessionData, 2, 100, 2000)
+	if _, err := sessionData.Db.Exec(`DELETE FROM thread`); err != nil {
+		t.Fatalf("DELETE error = %v", err)
+	}
+
+	if count, _ := storage.GetThreadTransactionCount(context.Background(), sessionData); count != 0 {
+		t.Errorf("GetThreadTransactionCount() expired = %v, want 0", count)
+	}
+
+}
+
+/* Storage whose GetThreadTransactionAll runs during hook */
+type slowStorage struct {
+	types.Storage
+	hook func()
+}
+
+func (s slowStorage) GetThreadTransactionAll(ctx context.Context, sessionData *types.Session) ([]types.Order, error) {
+
+	s.hook()
+
+	return []types.Order{{OrderID: 1}}, nil
+
+}
+
+func TestStorage_InvalidateDuringLoad(t *testing.T) {
+
+	var storage *Storage
+	loads := 0
+
+	storage = New(slowStorage{hook: func() {
+		loads++
+		if loads == 1 {
+			storage.Invalidate("a") /* Write while the first load runs */
+		}
+	}})
+
+	sessionData := &types.Session{ThreadID: "a"}
+
+	for i := 0; i < 2; i++ {
+		if orders, err := storage.GetThreadTransactionAll(context.Background(), sessionData); err != nil || !reflect.DeepEqual(orders, []types.Order{{OrderID: 1}}) {
+			t.Fatalf("GetThreadTransactionAll() = %v, %v", orders, err)
+		}
+	}
+
+	/* The first load is not cached, the second is */
+	if _, err := storage.GetThreadTransactionAll(context.Background(), sessionData); err != nil || loads != 2 {
+		t.Errorf("GetThreadTransactionAll() loads = %v, %v, want 2", loads, err)
+	}
+
+}
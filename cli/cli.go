@@ -0,0 +1,938 @@
+package cli
+
+/* This package implements the command line interface. Commands read and change the database selected
+in config_global.yml with the same packages as the web UI, so CryptoPump can be run and managed without
+a browser, i.e. in Docker or on a Raspberry Pi. Sell and reconcile take the thread lease first and never
+act on a thread run by a node. */
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aleibovici/cryptopump/algorithms"
+	"github.com/aleibovici/cryptopump/auth"
+	"github.com/aleibovici/cryptopump/exchange"
+	"github.com/aleibovici/cryptopump/functions"
+	"github.com/aleibovici/cryptopump/nodes"
+	"github.com/aleibovici/cryptopump/pause"
+	"github.com/aleibovici/cryptopump/threads"
+	"github.com/aleibovici/cryptopump/trades"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+/* Command line usage */
+const usage = `usage: cryptopump [command]
+
+Without a command CryptoPump starts the web UI and opens it in the browser, and trading starts from the web UI.
+
+commands:
+  run [--config file] [--headless]    start trading at once, with the web UI unless headless
+  status                              nodes of the cluster and profit
+  sessions list                       threads of the session table
+  orders list [--thread id] [--side BUY|SELL] [--status status] [--limit n]
+  sell <orderID>                      sell an open Thread transaction at market price
+  reconcile [--thread id]             update pending orders from the exchange and repair order sequences
+  export [--thread id] [--from YYYY-MM-DD] [--to YYYY-MM-DD] [--reason reason] [--format csv|json] [--output file]
+  tax [--from YYYY-MM-DD] [--to YYYY-MM-DD] [--method fifo|lifo|specific] [--format csv|json] [--output file]
+  migrate status|up|down
+  user add <name> --role role         add a web UI user or change its password, read from stdin
+  user remove <name>                  remove a web UI user
+  user list                           web UI users and API tokens with their role
+  token add <name> --role role        add an API token and print it
+  token remove <name>                 remove an API token
+
+Roles are viewer (read-only), trader (start, stop, buy, sell, pause and configuration) and admin (API keys).
+`
+
+/* Default number of orders listed */
+const defaultOrders = 50
+
+/* errUsage is returned by commands with invalid arguments */
+var errUsage = errors.New("invalid command line")
+
+// Options struct define the options of the run command
+type Options struct {
+	Config   string /* Session configuration file, ./config/config.yml when empty */
+	Headless bool   /* Trade without the web UI */
+}
+
+// CLI struct define the command line interface
+type CLI struct {
+	ViperData   *types.ViperData
+	SessionData *types.Session /* Session connected to the database, with no thread */
+	Stdin       io.Reader      /* Password of user add */
+	Stdout      io.Writer
+	Stderr      io.Writer
+}
+
+// Usage write the command line usage to w
+func Usage(w io.Writer) {
+
+	fmt.Fprint(w, usage)
+
+}
+
+// ParseRun parse the arguments of the run command
+func ParseRun(args []string, stderr io.Writer) (options Options, err error) {
+
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&options.Config, "config", "", "session configuration file (default ./config/config.yml)")
+	flags.BoolVar(&options.Headless, "headless", false, "trade without the web UI")
+
+	if err = flags.Parse(args); err != nil {
+
+		return options, err
+
+	}
+
+	if flags.NArg() != 0 {
+
+		fmt.Fprintln(stderr, "run: unexpected argument "+strconv.Quote(flags.Arg(0)))
+
+		return options, errUsage
+
+	}
+
+	return options, nil
+
+}
+
+// IsCommand return true when name is a command run by CLI.Run
+func IsCommand(name string) bool {
+
+	switch name {
+	case "status", "sessions", "orders", "sell", "reconcile", "export", "user", "token":
+
+		return true
+
+	}
+
+	return false
+
+}
+
+// Run run the command args[0] with its arguments and return the process exit code
+func (c CLI) Run(args []string) int {
+
+	var err error
+
+	switch {
+	case len(args) == 0:
+
+		err = errUsage
+
+	case args[0] == "status":
+
+		err = c.status(args[1:])
+
+	case args[0] == "sessions" && len(args) > 1 && args[1] == "list":
+
+		err = c.sessions(args[2:])
+
+	case args[0] == "orders" && len(args) > 1 && args[1] == "list":
+
+		err = c.orders(args[2:])
+
+	case args[0] == "sell":
+
+		err = c.sell(args[1:])
+
+	case args[0] == "reconcile":
+
+		err = c.reconcile(args[1:])
+
+	case args[0] == "export":
+
+		err = c.export(args[1:])
+
+	case args[0] == "user" || args[0] == "token":
+
+		err = c.users(args)
+
+	default:
+
+		err = errUsage
+
+	}
+
+	switch {
+	case err == nil:
+
+		return threads.ExitOK
+
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+
+		Usage(c.Stderr)
+
+		return threads.ExitUsage
+
+	case errors.Is(err, threads.ErrRunning):
+
+		fmt.Fprintln(c.Stderr, err)
+
+		return threads.ExitRunning
+
+	}
+
+	fmt.Fprintln(c.Stderr, err)
+
+	return threads.ExitError
+
+}
+
+/* Print the nodes of the cluster, the sessions and the profit */
+func (c CLI) status(args []string) error {
+
+	if len(args) != 0 {
+
+		return errUsage
+
+	}
+
+	cluster, err := nodes.Node{}.GetCluster(c.SessionData)
+	if err != nil {
+
+		return err
+
+	}
+
+	sessions, err := c.SessionData.Storage.GetSessions(context.Background(), c.SessionData)
+	if err != nil {
+
+		return err
+
+	}
+
+	profit, profitNet, profitPct, err := c.SessionData.Storage.GetProfit(context.Background(), c.SessionData)
+	if err != nil {
+
+		return err
+
+	}
+
+	w := tabwriter.NewWriter(c.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "THREAD\tNODE\tSYMBOL\tVERSION\tSTARTED\tLAST SEEN\tSTATE")
+
+	for _, member := range cluster.Nodes {
+
+		state := "up"
+		if member.Down {
+
+			state = "down"
+
+		}
+
+		fmt.Fprintf(w, "%s\t%s:%s\t%s\t%s\t%s\t%s\t%s\n",
+			member.ThreadID, member.Hostname, member.Port, member.Symbol, member.Version, member.Start, member.LastSeen, state)
+
+	}
+
+	if err = w.Flush(); err != nil {
+
+		return err
+
+	}
+
+	threadCount := 0
+	for _, session := range sessions {
+
+		threadCount += session.ThreadCount
+
+	}
+
+	fmt.Fprintf(c.Stdout, "\nNodes: %d up, %d down\n", cluster.Up, cluster.Down)
+	fmt.Fprintf(c.Stdout, "Sessions: %d, open Thread transactions: %d\n", len(sessions), threadCount)
+	fmt.Fprintf(c.Stdout, "Profit: %.2f, net %.2f, %.2f%%\n", profit, profitNet, profitPct)
+
+	return nil
+
+}
+
+/* Print the threads of the session table with the state of their node */
+func (c CLI) sessions(args []string) error {
+
+	if len(args) != 0 {
+
+		return errUsage
+
+	}
+
+	sessions, err := c.SessionData.Storage.GetSessions(context.Background(), c.SessionData)
+	if err != nil {
+
+		return err
+
+	}
+
+	cluster, err := nodes.Node{}.GetCluster(c.SessionData)
+	if err != nil {
+
+		return err
+
+	}
+
+	w := tabwriter.NewWriter(c.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "THREAD\tSESSION\tEXCHANGE\tFIAT\tFUNDS\tOPEN\tSTATE")
+
+	for _, session := range sessions {
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%.2f\t%d\t%s\n",
+			session.ThreadID, session.ThreadIDSession, session.Exchange, session.SymbolFiat, session.SymbolFiatFunds, session.ThreadCount,
+			state(cluster, session))
+
+	}
+
+	return w.Flush()
+
+}
+
+/* Return the state of the thread of session, i.e. "running on host:8080" */
+func state(cluster nodes.Cluster, session types.SessionRecord) string {
+
+	for _, member := range cluster.Nodes {
+
+		if member.ThreadID != session.ThreadID {
+
+			continue
+
+		}
+
+		if member.Down {
+
+			return "down, last seen " + member.LastSeen + " ago"
+
+		}
+
+		return "running on " + member.Hostname + ":" + member.Port
+
+	}
+
+	if session.Status {
+
+		return "stopped, system error"
+
+	}
+
+	return "stopped"
+
+}
+
+/* Print the orders matching the command line filter, most recent first */
+func (c CLI) orders(args []string) error {
+
+	var filter types.OrderFilter
+
+	flags := flag.NewFlagSet("orders list", flag.ContinueOnError)
+	flags.SetOutput(c.Stderr)
+	flags.StringVar(&filter.ThreadID, "thread", "", "thread id (default all threads)")
+	flags.StringVar(&filter.Side, "side", "", "BUY or SELL (default both)")
+	flags.StringVar(&filter.Status, "status", "", "order status, i.e. FILLED (default all)")
+	flags.IntVar(&filter.Limit, "limit", defaultOrders, "maximum number of orders, 0 for all")
+
+	if err := flags.Parse(args); err != nil {
+
+		return err
+
+	}
+
+	if flags.NArg() != 0 || filter.Limit < 0 {
+
+		return errUsage
+
+	}
+
+	filter.Side = strings.ToUpper(filter.Side)
+	filter.Status = strings.ToUpper(filter.Status)
+
+	orders, err := c.SessionData.Storage.GetOrders(context.Background(), c.SessionData, filter)
+	if err != nil {
+
+		return err
+
+	}
+
+	w := tabwriter.NewWriter(c.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "ORDER\tSOURCE\tSIDE\tSTATUS\tSYMBOL\tPRICE\tQUANTITY\tQUOTE\tTIME")
+
+	for _, order := range orders {
+
+		source := ""
+		if order.OrderIDSource != 0 {
+
+			source = strconv.Itoa(order.OrderIDSource)
+
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%.2f\t%s\n",
+			order.OrderID, source, order.Side, order.Status, order.Symbol,
+			strconv.FormatFloat(order.Price, 'f', -1, 64),
+			strconv.FormatFloat(order.ExecutedQuantity, 'f', -1, 64),
+			order.CumulativeQuoteQuantity,
+			time.Unix(0, order.TransactTime*int64(time.Millisecond)).UTC().Format("2006-01-02 15:04:05"))
+
+	}
+
+	return w.Flush()
+
+}
+
+/* Sell an open Thread transaction at market price, as Force Sell from the web UI, when its thread is not running */
+func (c CLI) sell(args []string) error {
+
+	if len(args) != 1 {
+
+		return errUsage
+
+	}
+
+	orderID, err := strconv.Atoi(args[0])
+	if err != nil || orderID <= 0 {
+
+		return errUsage
+
+	}
+
+	sessionData := *c.SessionData
+
+	if found, err := c.findThread(&sessionData, orderID); err != nil {
+
+		return err
+
+	} else if !found {
+
+		return fmt.Errorf("order %d is not an open Thread transaction", orderID)
+
+	}
+
+	configData, err := c.acquire(&sessionData)
+	if err != nil {
+
+		return err
+
+	}
+
+	defer nodes.Node{}.ReleaseThread(&sessionData) /* Released, or expires 60 seconds after the last renewal */
+
+	if sessionData.Pause, err = sessionData.Storage.GetPause(context.Background(), &sessionData, sessionData.ThreadID); err != nil {
+
+		return err
+
+	}
+
+	/* A frozen thread makes no SELL, Force Sell included */
+	if !(pause.Control{}).IsSellAllowed(&sessionData) {
+
+		return fmt.Errorf("thread %s: %s", sessionData.ThreadID, pause.Describe(sessionData.Pause))
+
+	}
+
+	sessionData.ForceSellOrderID = orderID
+
+	order, err := sessionData.Storage.GetOrderByOrderID(context.Background(), &sessionData)
+	if err != nil {
+
+		return err
+
+	}
+
+	if err = exchange.GetClient(configData, &sessionData); err != nil {
+
+		return err
+
+	}
+
+	exchange.GetLotSize(configData, &sessionData)
+
+	/* The market price of the last minute is recorded as the order price */
+	klines, err := exchange.GetKlines(configData, &sessionData)
+	if err != nil {
+
+		return err
+
+	} else if len(klines) == 0 {
+
+		return errors.New("no market price for " + sessionData.Symbol)
+
+	}
+
+	marketData := &types.Market{Price: functions.StrToFloat64(klines[len(klines)-1].Close), TimeStamp: time.Now()}
+
+	sessionData.ForceSell = true /* Market order */
+	sessionData.ExitReason = trades.ExitForce
+
+	if err = exchange.SellTicker(order, configData, marketData, &sessionData); err != nil {
+
+		return err
+
+	} else if sessionData.Fault != nil {
+
+		return fmt.Errorf("order %d sold by order %d on the exchange and not saved: %s", orderID, sessionData.Fault.OrderID, sessionData.Fault.Reason)
+
+	}
+
+	if configData.DryRun {
+
+		fmt.Fprintf(c.Stdout, "dry run, order %d not sold\n", orderID)
+
+		return nil
+
+	}
+
+	/* SellTicker logs a rejected or canceled order, the Thread transaction is then left open */
+	if found, err := c.findThread(&sessionData, orderID); err != nil {
+
+		return err
+
+	} else if found {
+
+		return fmt.Errorf("order %d not sold, see cryptopump_debug.log", orderID)
+
+	}
+
+	fmt.Fprintf(c.Stdout, "order %d of thread %s sold\n", orderID, sessionData.ThreadID)
+
+	return nil
+
+}
+
+/* Set the thread and session of sessionData to the thread with the open Thread transaction orderID */
+func (c CLI) findThread(sessionData *types.Session, orderID int) (bool, error) {
+
+	sessions, err := sessionData.Storage.GetSessions(context.Background(), sessionData)
+	if err != nil {
+
+		return false, err
+
+	}
+
+	for _, session := range sessions {
+
+		sessionData.ThreadID = session.ThreadID
+		sessionData.ThreadIDSession = session.ThreadIDSession
+
+		orders, err := sessionData.Storage.GetThreadTransactionByThreadID(context.Background(), sessionData)
+		if err != nil {
+
+			return false, err
+
+		}
+
+		for _, order := range orders {
+
+			if order.OrderID == orderID {
+
+				return true, nil
+
+			}
+
+		}
+
+	}
+
+	return false, nil
+
+}
+
+/* Update pending orders from the exchange and repair order sequences of the threads not running */
+func (c CLI) reconcile(args []string) error {
+
+	var threadID string
+
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	flags.SetOutput(c.Stderr)
+	flags.StringVar(&threadID, "thread", "", "thread id (default all threads)")
+
+	if err := flags.Parse(args); err != nil {
+
+		return err
+
+	}
+
+	if flags.NArg() != 0 {
+
+		return errUsage
+
+	}
+
+	sessions, err := c.SessionData.Storage.GetSessions(context.Background(), c.SessionData)
+	if err != nil {
+
+		return err
+
+	}
+
+	found, failed := false, 0
+
+	for _, session := range sessions {
+
+		if threadID != "" && session.ThreadID != threadID {
+
+			continue
+
+		}
+
+		found = true
+
+		sessionData := *c.SessionData
+		sessionData.ThreadID = session.ThreadID
+		sessionData.ThreadIDSession = session.ThreadIDSession
+
+		updated, repaired, err := c.reconcileThread(&sessionData)
+
+		switch {
+		case errors.Is(err, threads.ErrRunning): /* Its node reconciles the thread */
+
+			fmt.Fprintf(c.Stdout, "%s\tskipped, running\n", session.ThreadID)
+
+		case err != nil:
+
+			fmt.Fprintf(c.Stdout, "%s\tfailed, %v\n", session.ThreadID, err)
+			failed++
+
+		default:
+
+			fmt.Fprintf(c.Stdout, "%s\t%d pending orders updated, %d Thread transactions repaired\n", session.ThreadID, updated, repaired)
+
+		}
+
+	}
+
+	if threadID != "" && !found {
+
+		return fmt.Errorf("thread %s not found", threadID)
+
+	}
+
+	if failed > 0 {
+
+		return fmt.Errorf("%d threads not reconciled", failed)
+
+	}
+
+	return nil
+
+}
+
+/* Reconcile the session thread and return the number of pending orders updated and Thread transactions repaired */
+func (c CLI) reconcileThread(sessionData *types.Session) (updated int, repaired int, err error) {
+
+	configData, err := c.acquire(sessionData)
+	if err != nil {
+
+		return 0, 0, err
+
+	}
+
+	defer nodes.Node{}.ReleaseThread(sessionData) /* Released, or expires 60 seconds after the last renewal */
+
+	if err = exchange.GetClient(configData, sessionData); err != nil {
+
+		return 0, 0, err
+
+	}
+
+	/* Orders still open on the exchange stay pending, each pending order is checked once */
+	checked := map[int]bool{}
+
+	for {
+
+		order, err := sessionData.Storage.GetOrderTransactionPending(context.Background(), sessionData)
+		if err != nil {
+
+			return updated, 0, err
+
+		}
+
+		if order.OrderID == 0 || checked[order.OrderID] {
+
+			break
+
+		}
+
+		checked[order.OrderID] = true
+
+		orderStatus, err := exchange.GetOrder(configData, sessionData, int64(order.OrderID))
+		if err != nil {
+
+			return updated, 0, err
+
+		}
+
+		if err = sessionData.Storage.UpdateOrder(
+			context.Background(),
+			sessionData,
+			int64(orderStatus.OrderID),
+			orderStatus.CumulativeQuoteQuantity,
+			orderStatus.ExecutedQuantity,
+			orderStatus.Price,
+			orderStatus.Status); err != nil {
+
+			return updated, 0, err
+
+		}
+
+		if orderStatus.Status != order.Status {
+
+			updated++
+
+		}
+
+	}
+
+	return updated, algorithms.RecoverOrders(configData, sessionData), nil
+
+}
+
+/* Take the lease of the session thread, so no node runs it meanwhile, and return its configuration */
+func (c CLI) acquire(sessionData *types.Session) (*types.Config, error) {
+
+	if acquired, err := (nodes.Node{}).AcquireThread(sessionData); err != nil {
+
+		return nil, err
+
+	} else if !acquired {
+
+		return nil, fmt.Errorf("thread %s: %w", sessionData.ThreadID, threads.ErrRunning)
+
+	}
+
+	/* The thread is run with its own configuration, GetConfigData would create it from the default one */
+	if _, err := os.Stat("./config/" + sessionData.ThreadID + ".yml"); err != nil {
+
+		_ = nodes.Node{}.ReleaseThread(sessionData)
+
+		return nil, fmt.Errorf("thread %s: no configuration file", sessionData.ThreadID)
+
+	}
+
+	configData := functions.GetConfigData(c.ViperData, sessionData)
+
+	var err error
+
+	if sessionData.Symbol, err = sessionData.Storage.GetOrderSymbol(context.Background(), sessionData); err == nil {
+
+		sessionData.SymbolFiat, err = algorithms.ParseSymbolFiat(sessionData)
+
+	}
+
+	if err != nil {
+
+		_ = nodes.Node{}.ReleaseThread(sessionData)
+
+		return nil, err
+
+	}
+
+	return configData, nil
+
+}
+
+/* Write the trades ledger matching the command line filter as csv or json to stdout or a file */
+func (c CLI) export(args []string) error {
+
+	values := url.Values{}
+
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(c.Stderr)
+	thread := flags.String("thread", "", "thread id (default all threads)")
+	from := flags.String("from", "", "first exit date YYYY-MM-DD")
+	to := flags.String("to", "", "last exit date YYYY-MM-DD")
+	reason := flags.String("reason", "", "exit reason profit, stoploss, cover or force (default all)")
+	format := flags.String("format", trades.FormatCSV, "output format csv or json")
+	output := flags.String("output", "", "output file (default stdout)")
+
+	if err := flags.Parse(args); err != nil {
+
+		return err
+
+	}
+
+	if flags.NArg() != 0 || (*format != trades.FormatCSV && *format != trades.FormatJSON) {
+
+		return errUsage
+
+	}
+
+	values.Set("thread", *thread)
+	values.Set("from", *from)
+	values.Set("to", *to)
+	values.Set("reason", *reason)
+
+	filter, err := trades.ParseFilter(values)
+	if err != nil {
+
+		fmt.Fprintln(c.Stderr, err)
+
+		return errUsage
+
+	}
+
+	filter.Limit = 0 /* The whole ledger */
+
+	report, err := trades.GetReport(c.SessionData, filter)
+	if err != nil {
+
+		return err
+
+	}
+
+	w := c.Stdout
+
+	if *output != "" {
+
+		f, err := os.Create(*output)
+		if err != nil {
+
+			return err
+
+		}
+
+		defer f.Close()
+
+		w = f
+
+	}
+
+	return trades.Write(w, *format, report)
+
+}
+
+/* Add, remove and list the web UI users and API tokens of config_global.yml */
+func (c CLI) users(args []string) error {
+
+	if len(args) < 2 {
+
+		return errUsage
+
+	}
+
+	if args[1] == "list" {
+
+		if len(args) != 2 || args[0] != "user" {
+
+			return errUsage
+
+		}
+
+		users, tokens := auth.Load(c.ViperData)
+
+		w := tabwriter.NewWriter(c.Stdout, 0, 0, 2, ' ', 0)
+
+		fmt.Fprintln(w, "NAME\tTYPE\tROLE")
+
+		for _, user := range users {
+
+			fmt.Fprintf(w, "%s\tuser\t%s\n", user.Name, user.Role)
+
+		}
+
+		for _, token := range tokens {
+
+			fmt.Fprintf(w, "%s\ttoken\t%s\n", token.Name, token.Role)
+
+		}
+
+		return w.Flush()
+
+	}
+
+	flags := flag.NewFlagSet(args[0]+" "+args[1], flag.ContinueOnError)
+	flags.SetOutput(c.Stderr)
+	role := flags.String("role", "", "viewer, trader or admin")
+
+	/* The name comes before the flags */
+	if len(args) < 3 || strings.HasPrefix(args[2], "-") {
+
+		return errUsage
+
+	}
+
+	if err := flags.Parse(args[3:]); err != nil {
+
+		return err
+
+	}
+
+	if flags.NArg() != 0 {
+
+		return errUsage
+
+	}
+
+	name := args[2]
+
+	switch args[0] + " " + args[1] {
+	case "user add":
+
+		fmt.Fprint(c.Stderr, "Password: ")
+
+		password, err := bufio.NewReader(c.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+
+			return err
+
+		}
+
+		if err := auth.SetUser(c.ViperData, name, strings.TrimRight(password, "\r\n"), *role); err != nil {
+
+			return fmt.Errorf("user %s: %w", name, err)
+
+		}
+
+		fmt.Fprintf(c.Stdout, "User %s saved with role %s\n", name, *role)
+
+	case "user remove":
+
+		if err := auth.RemoveUser(c.ViperData, name); err != nil {
+
+			return fmt.Errorf("user %s: %w", name, err)
+
+		}
+
+		fmt.Fprintf(c.Stdout, "User %s removed\n", name)
+
+	case "token add":
+
+		token, err := auth.NewToken(c.ViperData, name, *role)
+		if err != nil {
+
+			return fmt.Errorf("token %s: %w", name, err)
+
+		}
+
+		fmt.Fprintf(c.Stderr, "Token %s saved with role %s, it can't be displayed again:\n", name, *role)
+		fmt.Fprintln(c.Stdout, token)
+
+	case "token remove":
+
+		if err := auth.RemoveToken(c.ViperData, name); err != nil {
+
+			return fmt.Errorf("token %s: %w", name, err)
+
+		}
+
+		fmt.Fprintf(c.Stdout, "Token %s removed\n", name)
+
+	default:
+
+		return errUsage
+
+	}
+
+	return nil
+
+}
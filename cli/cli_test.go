@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aleibovici/cryptopump/migrations"
+	"github.com/aleibovici/cryptopump/sqlite"
+	"github.com/aleibovici/cryptopump/threads"
+	"github.com/aleibovici/cryptopump/types"
+	"github.com/spf13/viper"
+)
+
+func TestParseRun(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    Options
+		wantErr bool
+	}{
+		{"default", nil, Options{}, false},
+		{"config", []string{"--config", "config/x.yml"}, Options{Config: "config/x.yml"}, false},
+		{"headless", []string{"--config=x.yml", "--headless"}, Options{Config: "x.yml", Headless: true}, false},
+		{"unknown flag", []string{"--web"}, Options{}, true},
+		{"argument", []string{"x.yml"}, Options{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			var stderr strings.Builder
+
+			got, err := ParseRun(tt.args, &stderr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRun() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRun() = %+v, want %+v", got, tt.want)
+			}
+
+		})
+	}
+
+}
+
+/* Return a session connected to a new migrated database with two threads. The first thread has the open Thread
+transaction 1 and runs on another node. */
+func newSession(t *testing.T) (*types.Session, string, string) {
+
+	db, err := sqlite.DBInit(filepath.Join(t.TempDir(), "cryptopump.db"))
+	if err != nil {
+		t.Fatalf("DBInit() error = %v", err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := (migrations.Migrator{Db: db, Backend: "sqlite"}).Up(); err != nil {
+		t.Fatalf("Migrator.Up() error = %v", err)
+	}
+
+	sessionData := &types.Session{Db: db, Storage: sqlite.Storage{}, Global: &types.Global{}}
+	running, stopped := *sessionData, *sessionData
+	running.ThreadID, running.ThreadIDSession, running.SymbolFiat = "c683ok5mk1u1120gnmmg", "c683ok5mk1u1120gnmn0", "USDT"
+	stopped.ThreadID, stopped.ThreadIDSession, stopped.SymbolFiat = "c683ok5mk1u1120gnmo0", "c683ok5mk1u1120gnmp0", "USDT"
+
+	for _, s := range []*types.Session{&running, &stopped} {
+		if err := s.Storage.SaveSession(context.Background(), &types.Config{ExchangeName: "binance"}, s); err != nil {
+			t.Fatalf("SaveSession() error = %v", err)
+		}
+	}
+
+	if err := running.Storage.SaveOrder(context.Background(), &running, &types.Order{
+		OrderID: 1, CumulativeQuoteQuantity: 100, ExecutedQuantity: 0.002, Price: 50000, Side: "BUY", Status: "FILLED", Symbol: "BTCUSDT", TransactTime: 1,
+	}, 0, 50000); err != nil {
+		t.Fatalf("SaveOrder() error = %v", err)
+	}
+
+	if err := running.Storage.SaveThreadTransaction(context.Background(), &running, 1, 100, 50000, 0.002); err != nil {
+		t.Fatalf("SaveThreadTransaction() error = %v", err)
+	}
+
+	if err := running.Storage.SaveNode(context.Background(), &running, types.Node{
+		ThreadID: running.ThreadID, Hostname: "pi", Port: "8080", Version: "dev", Symbol: "BTCUSDT", Started: time.Now().UnixNano() / int64(time.Millisecond),
+	}); err != nil {
+		t.Fatalf("SaveNode() error = %v", err)
+	}
+
+	if _, err := running.Storage.AcquireLease(context.Background(), &running, "thread:"+running.ThreadID, "pi:8080", 60000); err != nil {
+		t.Fatalf("AcquireLease() error = %v", err)
+	}
+
+	return sessionData, running.ThreadID, stopped.ThreadID
+
+}
+
+func TestCLI_Run(t *testing.T) {
+
+	sessionData, running, stopped := newSession(t)
+
+	tests := []struct {
+		name       string
+		args       []string
+		want       int
+		wantStdout []string
+		wantStderr string
+	}{
+		{
+			name:       "status",
+			args:       []string{"status"},
+			want:       threads.ExitOK,
+			wantStdout: []string{running + "  pi:8080", "Nodes: 1 up, 0 down", "Sessions: 2, open Thread transactions: 1"},
+		},
+		{
+			name:       "sessions list",
+			args:       []string{"sessions", "list"},
+			want:       threads.ExitOK,
+			wantStdout: []string{"running on pi:8080", stopped, "stopped"},
+		},
+		{
+			name:       "orders list",
+			args:       []string{"orders", "list", "--thread", running, "--side", "buy"},
+			want:       threads.ExitOK,
+			wantStdout: []string{"BUY   FILLED  BTCUSDT  50000  0.002     100.00  1970-01-01 00:00:00"},
+		},
+		{
+			name:       "export",
+			args:       []string{"export", "--format", "json"},
+			want:       threads.ExitOK,
+			wantStdout: []string{`"count": 0`},
+		},
+		{
+			name:       "sell running thread",
+			args:       []string{"sell", "1"},
+			want:       threads.ExitRunning,
+			wantStderr: threads.ErrRunning.Error(),
+		},
+		{
+			name:       "sell not open",
+			args:       []string{"sell", "2"},
+			want:       threads.ExitError,
+			wantStderr: "order 2 is not an open Thread transaction",
+		},
+		{
+			name:       "reconcile",
+			args:       []string{"reconcile"},
+			want:       threads.ExitError,
+			wantStdout: []string{running + "\tskipped, running", stopped + "\tfailed, thread " + stopped + ": no configuration file"},
+			wantStderr: "1 threads not reconciled",
+		},
+		{
+			name:       "reconcile unknown thread",
+			args:       []string{"reconcile", "--thread", "none"},
+			want:       threads.ExitError,
+			wantStderr: "thread none not found",
+		},
+		{
+			name:       "invalid order",
+			args:       []string{"sell", "x"},
+			want:       threads.ExitUsage,
+			wantStderr: "usage: cryptopump",
+		},
+		{
+			name:       "invalid format",
+			args:       []string{"export", "--format", "xml"},
+			want:       threads.ExitUsage,
+			wantStderr: "usage: cryptopump",
+		},
+		{
+			name:       "unknown command",
+			args:       []string{"sessions", "delete"},
+			want:       threads.ExitUsage,
+			wantStderr: "usage: cryptopump",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			var stdout, stderr strings.Builder
+
+			c := CLI{
+				ViperData:   &types.ViperData{V1: viper.New(), V2: viper.New()},
+				SessionData: sessionData,
+				Stdout:      &stdout,
+				Stderr:      &stderr,
+			}
+
+			if got := c.Run(tt.args); got != tt.want {
+				t.Errorf("Run() = %v, want %v, stderr %q", got, tt.want, stderr.String())
+			}
+
+			for _, want := range tt.wantStdout {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("Run() stdout = %q, want %q", stdout.String(), want)
+				}
+			}
+
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("Run() stderr = %q, want %q", stderr.String(), tt.wantStderr)
+			}
+
+		})
+	}
+
+}
+
+func TestCLI_export(t *testing.T) {
+
+	sessionData, running, _ := newSession(t)
+
+	/* One trade more than the largest page of a ledger query */
+	if _, err := sessionData.Db.Exec(`WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 10001)
+		INSERT INTO trades (ThreadID, ThreadIDSession, Symbol, BuyOrderID, SellOrderID, Quantity, EntryPrice, ExitPrice, EntryQuote, ExitQuote, Fees, Profit, ProfitPct, EntryTime, ExitTime, HoldingTime, ExitReason)
+		SELECT ?, ?, 'BTCUSDT', i, 100000 + i, 0.002, 50000, 50500, 100, 101, 0.2, 0.8, 0.008, i * 1000, i * 1000 + 60000, 60, 'profit' FROM n`,
+		running, running); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+
+	var stdout, stderr strings.Builder
+
+	c := CLI{
+		ViperData:   &types.ViperData{V1: viper.New(), V2: viper.New()},
+		SessionData: sessionData,
+		Stdout:      &stdout,
+		Stderr:      &stderr,
+	}
+
+	if got := c.Run([]string{"export", "--format", "csv"}); got != threads.ExitOK {
+		t.Fatalf("Run() = %v, want %v, stderr %q", got, threads.ExitOK, stderr.String())
+	}
+
+	if rows := strings.Count(stdout.String(), "\n") - 1; rows != 10001 { /* Without the header */
+		t.Errorf("Run() exported %v trades, want 10001", rows)
+	}
+
+}
@@ -0,0 +1,278 @@
+package engine
+
+/* This package implements the owner goroutine of the thread. The configuration, market and session
+data are only read and changed by the owner goroutine: websocket handlers, scheduled functions, the
+web UI and Telegram send it commands through channels, and read the immutable snapshot it publishes.
+Market data streams are conflated, a stream update waiting for the owner is replaced by the next one,
+so a BUY or SELL in progress never backs up the websocket handlers. */
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aleibovici/cryptopump/types"
+)
+
+/* Commands waiting for the owner before Post and Do block */
+const queueSize = 256
+
+/* Snapshots are published at most this often after stream updates, and after every Do */
+const publishInterval = 500 * time.Millisecond
+
+// Command read and change the thread data on the owner goroutine
+type Command func(configData *types.Config, marketData *types.Market, sessionData *types.Session)
+
+// Snapshot is a copy of the thread data published by the owner goroutine. Copies returned by Snapshot share
+// KlineData, Guard.Count and Global with the published snapshot and must not change them.
+type Snapshot struct {
+	Config  types.Config
+	Market  types.Market /* Series is nil, it is only used by the owner */
+	Session types.Session
+}
+
+// Engine owner goroutine of the thread data
+type Engine struct {
+	configData  *types.Config
+	marketData  *types.Market
+	sessionData *types.Session
+	commands    chan Command  /* Commands waiting for the owner, in order */
+	updates     chan struct{} /* Signal stream updates waiting for the owner */
+	mutex       sync.Mutex    /* Protect streams */
+	streams     []stream      /* Last update of each stream waiting for the owner */
+	snapshot    atomic.Value  /* Last published *Snapshot */
+	done        chan struct{} /* Closed when the owner goroutine exits */
+}
+
+/* stream is the last update of a market data stream */
+type stream struct {
+	name    string
+	command Command
+}
+
+// New return an engine owning configData, marketData and sessionData. They must not be used by other
+// goroutines once Run is started.
+func New(
+	configData *types.Config,
+	marketData *types.Market,
+	sessionData *types.Session) *Engine {
+
+	e := &Engine{
+		configData:  configData,
+		marketData:  marketData,
+		sessionData: sessionData,
+		commands:    make(chan Command, queueSize),
+		updates:     make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+
+	e.publish()
+
+	return e
+
+}
+
+// Run the owner goroutine until ctx is cancelled. A command in progress when ctx is cancelled completes.
+func (e *Engine) Run(ctx context.Context) {
+
+	defer close(e.done)
+
+	ticker := time.NewTicker(publishInterval)
+	defer ticker.Stop()
+
+	changed := false
+
+	for {
+
+		select {
+		case <-ctx.Done():
+
+			e.publish()
+
+			return
+
+		case command := <-e.commands:
+
+			command(e.configData, e.marketData, e.sessionData)
+			changed = true
+
+		case <-e.updates:
+
+			for _, s := range e.pending() {
+
+				s.command(e.configData, e.marketData, e.sessionData)
+
+			}
+
+			changed = true
+
+		case <-ticker.C:
+
+			if changed {
+
+				e.publish()
+				changed = false
+
+			}
+
+		}
+
+	}
+
+}
+
+// Do run command on the owner goroutine and wait for it to complete. It returns false when the owner stopped
+// before running command. Do must not be called by a command, it would wait for itself.
+func (e *Engine) Do(command Command) bool {
+
+	completed := make(chan struct{})
+
+	e.Post(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+
+		command(configData, marketData, sessionData)
+		e.publish() /* Readers see the change once Do returns */
+		close(completed)
+
+	})
+
+	select {
+	case <-completed:
+
+		return true
+
+	case <-e.done:
+
+		/* The command may have completed just before the owner stopped */
+		select {
+		case <-completed:
+
+			return true
+
+		default:
+
+			return false
+
+		}
+
+	}
+
+}
+
+// Post queue command for the owner goroutine without waiting for it. Commands run in the order they are
+// posted. Post blocks while the queue is full, and drops command once the owner stopped.
+func (e *Engine) Post(command Command) {
+
+	select {
+	case e.commands <- command:
+	case <-e.done:
+	}
+
+}
+
+// Update set the last update of the market data stream name. An update still waiting for the owner goroutine
+// is replaced, streams are updated in the order they first posted.
+func (e *Engine) Update(name string, command Command) {
+
+	e.mutex.Lock()
+
+	found := false
+	for i := range e.streams {
+
+		if e.streams[i].name == name {
+
+			e.streams[i].command = command
+			found = true
+
+			break
+
+		}
+
+	}
+
+	if !found {
+
+		e.streams = append(e.streams, stream{name: name, command: command})
+
+	}
+
+	e.mutex.Unlock()
+
+	/* The owner reads all waiting updates on one signal */
+	select {
+	case e.updates <- struct{}{}:
+	default:
+	}
+
+}
+
+// Snapshot return a copy of the last snapshot published by the owner goroutine
+func (e *Engine) Snapshot() Snapshot {
+
+	return *e.snapshot.Load().(*Snapshot)
+
+}
+
+// Session return a copy of the session data of the last snapshot, i.e. to call storage functions
+func (e *Engine) Session() *types.Session {
+
+	snapshot := e.Snapshot()
+
+	return &snapshot.Session
+
+}
+
+// Done return a channel closed when the owner goroutine exits
+func (e *Engine) Done() <-chan struct{} {
+
+	return e.done
+
+}
+
+/* Take the stream updates waiting for the owner */
+func (e *Engine) pending() []stream {
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	streams := e.streams
+	e.streams = nil
+
+	return streams
+
+}
+
+// Publish a snapshot of the thread data. Run by the owner goroutine, or by New before it starts. Maps, slices
+// and pointers changed in place by the owner are copied.
+func (e *Engine) publish() {
+
+	snapshot := &Snapshot{
+		Config:  *e.configData,
+		Market:  *e.marketData,
+		Session: *e.sessionData,
+	}
+
+	snapshot.Market.Series = nil
+	snapshot.Session.KlineData = append([]types.KlineData(nil), e.sessionData.KlineData...)
+
+	if e.sessionData.Global != nil {
+
+		global := *e.sessionData.Global
+		snapshot.Session.Global = &global
+
+	}
+
+	if e.sessionData.Guard.Count != nil {
+
+		snapshot.Session.Guard.Count = make(map[string]int, len(e.sessionData.Guard.Count))
+		for guard, count := range e.sessionData.Guard.Count {
+
+			snapshot.Session.Guard.Count[guard] = count
+
+		}
+
+	}
+
+	e.snapshot.Store(snapshot)
+
+}
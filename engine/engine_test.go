@@ -0,0 +1,160 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aleibovici/cryptopump/types"
+)
+
+/* Return a running engine stopped at the end of the test */
+func newEngine(t *testing.T) (*Engine, context.CancelFunc) {
+
+	e := New(
+		&types.Config{},
+		&types.Market{},
+		&types.Session{Global: &types.Global{}, Guard: types.Guard{Count: map[string]int{}}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go e.Run(ctx)
+
+	t.Cleanup(func() {
+		cancel()
+		<-e.Done()
+	})
+
+	return e, cancel
+
+}
+
+func TestEngine_Do(t *testing.T) {
+
+	e, _ := newEngine(t)
+
+	/* Commands from many goroutines, with readers of the snapshot */
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+					sessionData.ThreadCount++
+					sessionData.Global.ThreadCount++
+					sessionData.Guard.Count["spread"]++
+					sessionData.KlineData = append(sessionData.KlineData, types.KlineData{Date: int64(j)})
+				})
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				snapshot := e.Snapshot()
+				_ = snapshot.Session.Global.ThreadCount + snapshot.Session.Guard.Count["spread"] + len(snapshot.Session.KlineData)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	/* Do returns once the change is published */
+	if snapshot := e.Snapshot(); snapshot.Session.ThreadCount != 800 || snapshot.Session.Global.ThreadCount != 800 ||
+		snapshot.Session.Guard.Count["spread"] != 800 || len(snapshot.Session.KlineData) != 800 {
+		t.Errorf("Snapshot() ThreadCount = %v, Global = %v, Guard = %v, KlineData = %v, want 800",
+			snapshot.Session.ThreadCount, snapshot.Session.Global.ThreadCount, snapshot.Session.Guard.Count["spread"], len(snapshot.Session.KlineData))
+	}
+
+	/* Snapshots are copies */
+	e.Session().ThreadCount = 0
+	if e.Session().ThreadCount != 800 {
+		t.Errorf("Session() change seen by the next copy")
+	}
+
+}
+
+func TestEngine_Update(t *testing.T) {
+
+	e, _ := newEngine(t)
+
+	/* Hold the owner, as a BUY or SELL in progress */
+	release := make(chan struct{})
+	e.Post(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+		<-release
+	})
+
+	var runs []float64
+	for i := 1; i <= 10; i++ {
+		price := float64(i)
+		e.Update("WsBookTicker", func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+			marketData.Price = price
+			runs = append(runs, price)
+		})
+	}
+
+	e.Update("WsPartialDepth", func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+		marketData.BookImbalance = 0.5
+	})
+
+	close(release)
+
+	/* Wait for the updates, the command runs after them */
+	time.Sleep(50 * time.Millisecond)
+	e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {})
+
+	if snapshot := e.Snapshot(); snapshot.Market.Price != 10 || snapshot.Market.BookImbalance != 0.5 || len(runs) != 1 {
+		t.Errorf("Update() Price = %v, BookImbalance = %v, runs = %v, want 10, 0.5, [10]", snapshot.Market.Price, snapshot.Market.BookImbalance, runs)
+	}
+
+}
+
+func TestEngine_Post(t *testing.T) {
+
+	e, _ := newEngine(t)
+
+	for i := 0; i < 100; i++ {
+		date := int64(i)
+		e.Post(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+			sessionData.KlineData = append(sessionData.KlineData, types.KlineData{Date: date})
+		})
+	}
+
+	e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {})
+
+	klineData := e.Session().KlineData
+	for i := range klineData {
+		if klineData[i].Date != int64(i) {
+			t.Fatalf("Post() KlineData[%d] = %v, want commands in order", i, klineData[i].Date)
+		}
+	}
+
+}
+
+func TestEngine_Stop(t *testing.T) {
+
+	e, cancel := newEngine(t)
+
+	started := make(chan struct{})
+	go e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		sessionData.ThreadCount = 1
+	})
+
+	/* A command in progress completes */
+	<-started
+	cancel()
+	<-e.Done()
+
+	if e.Session().ThreadCount != 1 {
+		t.Errorf("Session() ThreadCount = %v after stop, want 1", e.Session().ThreadCount)
+	}
+
+	if e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {}) {
+		t.Errorf("Do() = true after stop, want false")
+	}
+
+}
@@ -3,11 +3,11 @@ package exchange
 import (
 	"context"
 	"flag"
+	"fmt"
 	"time"
 
 	"github.com/aleibovici/cryptopump/functions"
 	"github.com/aleibovici/cryptopump/logger"
-	"github.com/aleibovici/cryptopump/threads"
 	"github.com/aleibovici/cryptopump/types"
 
 	"github.com/adshao/go-binance/v2"
@@ -114,6 +114,35 @@ func BinanceMapWsKline(from binance.WsKline) (to types.WsKline) {
 
 }
 
+// BinanceMapWsPartialDepth Map binance.WsPartialDepthEvent types to OrderBook type
+func BinanceMapWsPartialDepth(from *binance.WsPartialDepthEvent) (to types.OrderBook) {
+
+	to = types.OrderBook{}
+	to.LastUpdateID = from.LastUpdateID
+	to.Time = time.Now()
+
+	for _, bid := range from.Bids {
+
+		to.Bids = append(to.Bids, types.BookLevel{
+			Price:    functions.StrToFloat64(bid.Price),
+			Quantity: functions.StrToFloat64(bid.Quantity),
+		})
+
+	}
+
+	for _, ask := range from.Asks {
+
+		to.Asks = append(to.Asks, types.BookLevel{
+			Price:    functions.StrToFloat64(ask.Price),
+			Quantity: functions.StrToFloat64(ask.Quantity),
+		})
+
+	}
+
+	return to
+
+}
+
 /* Map binance.PriceChangeStats types to Kline type */
 func binanceMapPriceChangeStats(from []*binance.PriceChangeStats) (to []*types.PriceChangeStats) {
 
@@ -145,6 +174,7 @@ func binanceMapExchangeInfo(sessionData *types.Session, from *binance.ExchangeIn
 			to.MaxQuantity = from.Symbols[key].LotSizeFilter().MaxQuantity
 			to.MinQuantity = from.Symbols[key].LotSizeFilter().MinQuantity
 			to.StepSize = from.Symbols[key].LotSizeFilter().StepSize
+			to.Status = from.Symbols[key].Status
 
 		}
 
@@ -325,10 +355,7 @@ func binanceGetSymbolFunds(
 
 	}
 
-	/* Cleanly exit ThreadID */
-	threads.Thread{}.Terminate(sessionData, "Balance or Pair not found for symbol "+sessionData.Symbol[0:3])
-
-	return 0, err
+	return 0, fmt.Errorf("%w %s", ErrBalanceNotFound, sessionData.Symbol[0:3])
 
 }
 
@@ -450,6 +477,18 @@ func binanceWsKlineServe(
 
 }
 
+/* WsPartialDepthServe serve websocket partial depth handler with the top 20 levels updated every 100ms */
+func binanceWsPartialDepthServe(
+	sessionData *types.Session,
+	wsHandler *types.WsHandler,
+	errHandler func(err error)) (doneC chan struct{}, stopC chan struct{}, err error) {
+
+	doneC, stopC, err = binance.WsPartialDepthServe100Ms(sessionData.Symbol, "20", wsHandler.BinanceWsPartialDepth, errHandler)
+
+	return doneC, stopC, err
+
+}
+
 /* WsUserDataServe serve user data handler with listen key */
 func binanceWsUserDataServe(
 	sessionData *types.Session,
@@ -1,18 +1,22 @@
 package exchange
 
 import (
+	"context"
 	"errors"
-	"math"
 	"strings"
 	"time"
 
 	"github.com/aleibovici/cryptopump/functions"
 	"github.com/aleibovici/cryptopump/logger"
-	"github.com/aleibovici/cryptopump/mysql"
+	"github.com/aleibovici/cryptopump/money"
 	"github.com/aleibovici/cryptopump/threads"
+	"github.com/aleibovici/cryptopump/trades"
 	"github.com/aleibovici/cryptopump/types"
 )
 
+// ErrBalanceNotFound is returned by GetSymbolFunds when the account has no balance for the symbol coin
+var ErrBalanceNotFound = errors.New("Balance or Pair not found for symbol")
+
 // GetClient Define the exchange to be used
 func GetClient(
 	configData *types.Config,
@@ -132,6 +136,19 @@ func GetLotSize(
 
 }
 
+// GetSymbolStatus Retrieve symbol trading status
+func GetSymbolStatus(
+	configData *types.Config,
+	sessionData *types.Session) {
+
+	if info, err := GetInfo(configData, sessionData); err == nil && info != nil {
+
+		sessionData.SymbolStatus = info.Status
+
+	}
+
+}
+
 // GetSymbolFiatFunds Retrieve symbol fiat funds available
 func GetSymbolFiatFunds(
 	configData *types.Config,
@@ -206,9 +223,9 @@ func GetPriceChangeStats(
 /* Calculate the correct quantity to SELL according to the exchange lotSizeStep */
 func getSellQuantity(
 	order types.Order,
-	sessionData *types.Session) (quantity float64) {
+	sessionData *types.Session) (quantity string) {
 
-	return math.Round(order.ExecutedQuantity/sessionData.StepSize) * sessionData.StepSize
+	return functions.StepToStr(order.ExecutedQuantity, sessionData.StepSize)
 
 }
 
@@ -216,9 +233,9 @@ func getSellQuantity(
 func getBuyQuantity(
 	marketData *types.Market,
 	sessionData *types.Session,
-	fiatQuantity float64) (quantity float64) {
+	fiatQuantity float64) (quantity string) {
 
-	return math.Round((fiatQuantity/marketData.Price)/sessionData.StepSize) * sessionData.StepSize
+	return money.New(fiatQuantity).Div(money.New(marketData.Price)).Step(sessionData.StepSize)
 
 }
 
@@ -306,6 +323,24 @@ func WsKlineServe(
 
 }
 
+// WsPartialDepthServe serve websocket that pushes the top bids and asks of the order book for a specified symbol.
+func WsPartialDepthServe(
+	configData *types.Config,
+	sessionData *types.Session,
+	wsHandler *types.WsHandler,
+	errHandler func(err error)) (doneC chan struct{}, stopC chan struct{}, err error) {
+
+	switch strings.ToLower(configData.ExchangeName) {
+	case "binance":
+
+		return binanceWsPartialDepthServe(sessionData, wsHandler, errHandler)
+
+	}
+
+	return
+
+}
+
 // WsUserDataServe serve user data handler with listen key
 func WsUserDataServe(
 	configData *types.Config,
@@ -335,12 +370,7 @@ func BuyTicker(
 	var orderPrice float64
 	var orderExecutedQuantity float64
 	var isCanceled bool
-
-	/* Enter and defer exiting busy mode */
-	sessionData.Busy = true
-	defer func() {
-		sessionData.Busy = false
-	}()
+	var isUpdated bool
 
 	/* Exit if DryRun mode set to true */
 	if configData.DryRun {
@@ -363,7 +393,7 @@ func BuyTicker(
 	orderResponse, err := BuyOrder(
 		configData,
 		sessionData,
-		functions.Float64ToStr(getBuyQuantity(marketData, sessionData, quantity), 4)) /* Get the correct quantity according to lotSizeMin and lotSizeStep */
+		getBuyQuantity(marketData, sessionData, quantity)) /* Get the correct quantity according to lotSizeMin and lotSizeStep */
 
 	/* Test orderResponse for  errors */
 	if (orderResponse == nil && err != nil) ||
@@ -384,22 +414,25 @@ func BuyTicker(
 
 	}
 
-	/* Check if result is nil and set as zero */
-	if orderPrice = orderResponse.CumulativeQuoteQuantity / orderResponse.ExecutedQuantity; math.IsNaN(orderPrice) {
-		orderPrice = 0
-	}
+	/* Average fill price, zero when nothing was executed */
+	orderPrice = money.New(orderResponse.CumulativeQuoteQuantity).Div(money.New(orderResponse.ExecutedQuantity)).Float()
 
 	orderExecutedQuantity = orderResponse.ExecutedQuantity
 
-	/* Save order to database */
-	if err := mysql.SaveOrder(
-		sessionData,
-		orderResponse,
-		0, /* OrderIDSource */
-		orderPrice /* OrderPrice */); err != nil {
+	/* Save an order still open on the exchange while waiting for it to fill. UpdatePendingOrders completes it after a crash. */
+	isSaved := orderResponse.Status == "NEW"
+	if isSaved {
 
-		/* Cleanly exit ThreadID */
-		threads.Thread{}.Terminate(sessionData, functions.GetFunctionName()+" - "+err.Error())
+		if err := sessionData.Storage.SaveOrder(
+			context.Background(),
+			sessionData,
+			orderResponse,
+			0, /* OrderIDSource */
+			orderPrice /* OrderPrice */); err != nil {
+
+			isSaved = false /* Saved with the Thread transaction once filled */
+
+		}
 
 	}
 
@@ -418,7 +451,10 @@ S:
 		for orderStatus, err = GetOrder(
 			configData,
 			sessionData,
-			int64(orderResponse.OrderID)); orderStatus == nil || orderStatus.Status == "NEW"; {
+			int64(orderResponse.OrderID)); orderStatus == nil || orderStatus.Status == "NEW"; orderStatus, err = GetOrder(
+			configData,
+			sessionData,
+			int64(orderResponse.OrderID)) {
 
 			if err != nil {
 
@@ -426,30 +462,36 @@ S:
 
 			}
 
-			time.Sleep(3000 * time.Millisecond)
+			/* On shutdown the saved order is completed by UpdatePendingOrders when the thread resumes */
+			if isSaved && (threads.Thread{}).Stopping(sessionData) {
+
+				return
+
+			}
+
+			select {
+			case <-time.After(3000 * time.Millisecond):
+			case <-threads.Thread{}.Context(sessionData).Done():
+
+				/* An order not saved is still waited for on shutdown, so it is saved once filled */
+				if !isSaved {
+
+					time.Sleep(3000 * time.Millisecond)
+
+				}
+
+			}
 
 		}
 
 		switch orderStatus.Status {
 		case "FILLED", "PARTIALLY_FILLED":
 
-			orderPrice = orderStatus.CumulativeQuoteQuantity / orderStatus.ExecutedQuantity
+			orderPrice = money.New(orderStatus.CumulativeQuoteQuantity).Div(money.New(orderStatus.ExecutedQuantity)).Float()
 
 			orderExecutedQuantity = orderStatus.ExecutedQuantity
 
-			/* Update order status and price & Save Thread Transaction */
-			if err := mysql.UpdateOrder(
-				sessionData,
-				int64(orderResponse.OrderID),
-				orderResponse.CumulativeQuoteQuantity,
-				orderResponse.ExecutedQuantity,
-				orderPrice,
-				string(orderStatus.Status)); err != nil {
-
-				/* Cleanly exit ThreadID */
-				threads.Thread{}.Terminate(sessionData, functions.GetFunctionName()+" - "+err.Error())
-
-			}
+			isUpdated = true
 
 		case "CANCELED":
 
@@ -463,16 +505,65 @@ S:
 
 	if !isCanceled {
 
-		/* Save Thread Transaction */
-		if err := mysql.SaveThreadTransaction(
-			sessionData,
-			int64(orderResponse.OrderID),
-			orderResponse.CumulativeQuoteQuantity,
-			orderPrice,
-			orderExecutedQuantity); err != nil {
+		/* Save or update the order and save the Thread Transaction in one database transaction */
+		write := func(tx types.OrderTx) error {
+
+			if !isSaved {
+
+				if err := tx.SaveOrder(orderResponse, 0, orderPrice); err != nil {
 
-			/* Cleanly exit ThreadID */
-			threads.Thread{}.Terminate(sessionData, functions.GetFunctionName()+" - "+err.Error())
+					return err
+
+				}
+
+			}
+
+			if isUpdated {
+
+				if err := tx.UpdateOrder(
+					int64(orderResponse.OrderID),
+					orderResponse.CumulativeQuoteQuantity,
+					orderResponse.ExecutedQuantity,
+					orderPrice,
+					string(orderStatus.Status)); err != nil {
+
+					return err
+
+				}
+
+			}
+
+			return tx.SaveThreadTransaction(
+				int64(orderResponse.OrderID),
+				orderResponse.CumulativeQuoteQuantity,
+				orderPrice,
+				orderExecutedQuantity)
+
+		}
+
+		if err := sessionData.Storage.OrderTx(context.Background(), sessionData, write); err != nil {
+
+			logger.LogEntry{ /* Log Entry */
+				Config:  configData,
+				Market:  marketData,
+				Session: sessionData,
+				Order: &types.Order{
+					OrderID: int(orderResponse.OrderID),
+					Price:   orderPrice,
+				},
+				Message:  "DATABASE - BUY not saved: " + err.Error(),
+				LogLevel: "InfoLevel",
+			}.Do()
+
+			/* The BUY is filled on the exchange, trading stops until it is saved */
+			sessionData.Fault = &types.Fault{
+				OrderID: int(orderResponse.OrderID),
+				Reason:  "BUY not saved: " + err.Error(),
+				Write:   write,
+				Retried: time.Now(),
+			}
+
+			return
 
 		}
 
@@ -490,6 +581,19 @@ S:
 
 	} else if isCanceled {
 
+		/* Save order to database */
+		if !isSaved {
+
+			/* A canceled order left unsaved has no Thread transaction to repair */
+			_ = sessionData.Storage.SaveOrder(
+				context.Background(),
+				sessionData,
+				orderResponse,
+				0, /* OrderIDSource */
+				orderPrice /* OrderPrice */)
+
+		}
+
 		logger.LogEntry{ /* Log Entry */
 			Config:  configData,
 			Market:  marketData,
@@ -511,22 +615,17 @@ func SellTicker(
 	order types.Order,
 	configData *types.Config,
 	marketData *types.Market,
-	sessionData *types.Session) {
+	sessionData *types.Session) error {
 
 	var orderResponse *types.Order
 	var orderStatus *types.Order
 
 	var cancelOrderResponse *types.Order
 	var isCanceled bool
+	var isUpdated bool
 	var err error
 	var i int
 
-	/* Enter and defer exiting busy mode */
-	sessionData.Busy = true
-	defer func() {
-		sessionData.Busy = false
-	}()
-
 	/* Exit if DryRun mode set to true */
 	if configData.DryRun {
 
@@ -541,7 +640,7 @@ func SellTicker(
 			LogLevel: "InfoLevel",
 		}.Do()
 
-		return
+		return nil
 
 	}
 
@@ -549,7 +648,7 @@ func SellTicker(
 		configData,
 		marketData,
 		sessionData,
-		functions.Float64ToStr(getSellQuantity(order, sessionData), 6) /* Get correct quantity to sell according to the lotSizeStep */)
+		getSellQuantity(order, sessionData) /* Get correct quantity to sell according to the lotSizeStep */)
 
 	/* Test orderResponse for  errors */
 	if (orderResponse == nil && err != nil) ||
@@ -564,19 +663,24 @@ func SellTicker(
 			LogLevel: "DebugLevel",
 		}.Do()
 
-		return
+		return nil
 
 	}
 
-	/* Save order to database */
-	if err := mysql.SaveOrder(
-		sessionData,
-		orderResponse,
-		int64(order.OrderID), /* OrderIDSource */
-		marketData.Price /* OrderPrice */); err != nil {
+	/* Save an order still open on the exchange while waiting for it to fill. UpdatePendingOrders completes it after a crash. */
+	isSaved := orderResponse.Status == "NEW" || orderResponse.Status == "PARTIALLY_FILLED"
+	if isSaved {
+
+		if err := sessionData.Storage.SaveOrder(
+			context.Background(),
+			sessionData,
+			orderResponse,
+			int64(order.OrderID), /* OrderIDSource */
+			marketData.Price /* OrderPrice */); err != nil {
 
-		/* Cleanly exit ThreadID */
-		threads.Thread{}.Terminate(sessionData, functions.GetFunctionName()+" - "+err.Error())
+			isSaved = false /* Saved with the sale once filled */
+
+		}
 
 	}
 
@@ -602,8 +706,8 @@ S:
 
 			if err != nil {
 
-				/* Cleanly exit ThreadID */
-				threads.Thread{}.Terminate(sessionData, functions.GetFunctionName()+" - "+err.Error())
+				/* The order is saved as pending and completed by UpdatePendingOrders when the thread resumes */
+				return err
 
 			}
 
@@ -622,8 +726,8 @@ S:
 
 			i++ /* increment iterations before order cancel */
 
-			/* Initiate order cancel after 10 iterations */
-			if i == 9 {
+			/* Initiate order cancel after 10 iterations, or at once on shutdown */
+			if i == 9 || (threads.Thread{}).Stopping(sessionData) {
 
 				if cancelOrderResponse, err = CancelOrder(
 					configData,
@@ -641,8 +745,8 @@ S:
 							sessionData,
 							int64(orderResponse.OrderID)); err != nil {
 
-							/* Cleanly exit ThreadID */
-							threads.Thread{}.Terminate(sessionData, functions.GetFunctionName()+" - "+err.Error())
+							/* The order is saved as pending and completed by UpdatePendingOrders when the thread resumes */
+							return err
 
 						}
 
@@ -656,8 +760,8 @@ S:
 							sessionData,
 							int64(orderResponse.OrderID)); err != nil {
 
-							/* Cleanly exit ThreadID */
-							threads.Thread{}.Terminate(sessionData, functions.GetFunctionName()+" - "+err.Error())
+							/* The order is saved as pending and completed by UpdatePendingOrders when the thread resumes */
+							return err
 
 						}
 
@@ -694,8 +798,8 @@ S:
 						sessionData,
 						int64(orderResponse.OrderID)); err != nil {
 
-						/* Cleanly exit ThreadID */
-						threads.Thread{}.Terminate(sessionData, functions.GetFunctionName()+" - "+err.Error())
+						/* The order is saved as pending and completed by UpdatePendingOrders when the thread resumes */
+						return err
 
 					}
 
@@ -722,40 +826,98 @@ S:
 			}
 
 			/* Wait time between iterations (i++). There are ten iterations and the total waiting time define the amount od time before an order is canceled. configData.SellWaitBeforeCancel is divided by then converted into seconds. */
-			time.Sleep(
-				time.Duration(
-					configData.SellWaitBeforeCancel/10) * time.Second)
+			select {
+			case <-time.After(time.Duration(configData.SellWaitBeforeCancel/10) * time.Second):
+			case <-threads.Thread{}.Context(sessionData).Done():
+			}
 
 		}
 
-		/* Update order status and price */
-		if err := mysql.UpdateOrder(
-			sessionData,
-			int64(orderResponse.OrderID),
-			orderStatus.CumulativeQuoteQuantity,
-			orderStatus.ExecutedQuantity,
-			marketData.Price,
-			string(orderStatus.Status)); err != nil {
+		isUpdated = true
+
+	}
+
+	/* Save or update the order and remove the sold Thread transaction in one database transaction */
+	write := func(tx types.OrderTx) error {
+
+		if !isSaved {
+
+			if err := tx.SaveOrder(orderResponse, int64(order.OrderID), marketData.Price); err != nil {
 
-			/* Cleanly exit ThreadID */
-			threads.Thread{}.Terminate(sessionData, functions.GetFunctionName()+" - "+err.Error())
+				return err
+
+			}
 
 		}
 
-	}
+		if isUpdated {
 
-	if !isCanceled {
+			if err := tx.UpdateOrder(
+				int64(orderResponse.OrderID),
+				orderStatus.CumulativeQuoteQuantity,
+				orderStatus.ExecutedQuantity,
+				marketData.Price,
+				string(orderStatus.Status)); err != nil {
 
-		/* Remove Thread transaction from database */
-		if err := mysql.DeleteThreadTransactionByOrderID(
-			sessionData,
-			order.OrderID); err != nil {
+				return err
+
+			}
 
-			/* Cleanly exit ThreadID */
-			threads.Thread{}.Terminate(sessionData, functions.GetFunctionName()+" - "+err.Error())
+		}
+
+		if isCanceled {
+
+			return nil
+
+		}
+
+		if err := tx.DeleteThreadTransactionByOrderID(order.OrderID); err != nil {
+
+			return err
+
+		}
+
+		/* Record the closed round trip in the trades ledger */
+		sale := orderResponse
+		if isUpdated {
+
+			sale = orderStatus
+
+		}
+
+		return tx.SaveTrade(trades.New(order, sale, configData, sessionData))
+
+	}
+
+	if err := sessionData.Storage.OrderTx(context.Background(), sessionData, write); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:  configData,
+			Market:  marketData,
+			Session: sessionData,
+			Order: &types.Order{
+				OrderID:       int(orderResponse.OrderID),
+				Price:         marketData.Price,
+				OrderIDSource: order.OrderID,
+			},
+			Message:  "DATABASE - SELL not saved: " + err.Error(),
+			LogLevel: "InfoLevel",
+		}.Do()
 
+		/* The SELL is completed on the exchange, trading stops until it is saved */
+		sessionData.Fault = &types.Fault{
+			OrderID: int(orderResponse.OrderID),
+			Reason:  "SELL not saved: " + err.Error(),
+			Write:   write,
+			Retried: time.Now(),
 		}
 
+		return nil
+
+	}
+
+	if !isCanceled {
+
 		logger.LogEntry{ /* Log Entry */
 			Config:  configData,
 			Market:  marketData,
@@ -786,4 +948,6 @@ S:
 
 	}
 
+	return nil
+
 }
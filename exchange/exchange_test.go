@@ -1,9 +1,17 @@
 package exchange
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/adshao/go-binance/v2"
 	"github.com/aleibovici/cryptopump/functions"
 	"github.com/aleibovici/cryptopump/logger"
 	"github.com/aleibovici/cryptopump/types"
@@ -322,9 +330,16 @@ func Test_getSellQuantity(t *testing.T) {
 	tests := []struct {
 		name         string
 		args         args
-		wantQuantity float64
+		wantQuantity string
 	}{
-		// TODO: Add test cases.
+		{
+			name: "float quantity",
+			args: args{
+				order:       types.Order{ExecutedQuantity: 0.0012299999},
+				sessionData: &types.Session{StepSize: 0.00001},
+			},
+			wantQuantity: "0.00123",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -344,16 +359,34 @@ func Test_getBuyQuantity(t *testing.T) {
 	tests := []struct {
 		name         string
 		args         args
-		wantQuantity float64
+		wantQuantity string
 	}{
 		{
 			name: "success",
 			args: args{
 				marketData:   marketData,
-				sessionData:  sessionData,
+				sessionData:  &types.Session{StepSize: 0.000001},
 				fiatQuantity: 0,
 			},
-			wantQuantity: 0,
+			wantQuantity: "0.000000",
+		},
+		{
+			name: "lot size step",
+			args: args{
+				marketData:   marketData,
+				sessionData:  &types.Session{StepSize: 0.00001},
+				fiatQuantity: 100,
+			},
+			wantQuantity: "0.00250",
+		},
+		{
+			name: "half lot size step",
+			args: args{
+				marketData:   marketData,
+				sessionData:  &types.Session{StepSize: 0.0001},
+				fiatQuantity: 6, /* 0.00015, 1.4999999999999998 steps in float64 */
+			},
+			wantQuantity: "0.0002",
 		},
 	}
 	for _, tt := range tests {
@@ -364,3 +397,196 @@ func Test_getBuyQuantity(t *testing.T) {
 		})
 	}
 }
+
+/* Order writes recorded by a database transaction */
+type recordTx struct {
+	calls []string
+}
+
+func (r *recordTx) SaveOrder(order *types.Order, orderIDSource int64, orderPrice float64) error {
+	r.calls = append(r.calls, fmt.Sprintf("SaveOrder %d", order.OrderID))
+	return nil
+}
+
+func (r *recordTx) UpdateOrder(OrderID int64, CumulativeQuoteQuantity float64, ExecutedQuantity float64, Price float64, Status string) error {
+	r.calls = append(r.calls, fmt.Sprintf("UpdateOrder %d %s", OrderID, Status))
+	return nil
+}
+
+func (r *recordTx) SaveThreadTransaction(OrderID int64, CumulativeQuoteQuantity float64, Price float64, ExecutedQuantity float64) error {
+	r.calls = append(r.calls, fmt.Sprintf("SaveThreadTransaction %d", OrderID))
+	return nil
+}
+
+func (r *recordTx) DeleteThreadTransactionByOrderID(orderID int) error {
+	r.calls = append(r.calls, fmt.Sprintf("DeleteThreadTransactionByOrderID %d", orderID))
+	return nil
+}
+
+func (r *recordTx) SaveTrade(trade *types.Trade) error {
+	r.calls = append(r.calls, fmt.Sprintf("SaveTrade %d %d", trade.BuyOrderID, trade.SellOrderID))
+	return nil
+}
+
+/* Storage backend whose order writes fail until up is set */
+type faultStorage struct {
+	types.Storage
+	up bool
+	tx recordTx
+}
+
+func (f *faultStorage) SaveOrder(ctx context.Context, sessionData *types.Session, order *types.Order, orderIDSource int64, orderPrice float64) error {
+
+	if !f.up {
+		return errors.New("database is down")
+	}
+
+	f.tx.calls = append(f.tx.calls, fmt.Sprintf("SaveOrder %d %s pending", order.OrderID, order.Status))
+
+	return nil
+
+}
+
+func (f *faultStorage) OrderTx(ctx context.Context, sessionData *types.Session, fn func(tx types.OrderTx) error) error {
+
+	if !f.up {
+		return errors.New("database is down")
+	}
+
+	return fn(&f.tx)
+
+}
+
+// Return a session on a test Binance API server creating orders with status[0], each order query returns the next
+// status. queries counts the order queries.
+func newExchange(t *testing.T, storage types.Storage, status ...string) (sessionData *types.Session, queries *int) {
+
+	queries = new(int)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.URL.Path != "/api/v3/order" {
+			http.NotFound(w, r)
+			return
+		}
+
+		if r.Method == "GET" {
+			*queries++
+		}
+
+		if len(status) > 1 && r.Method == "GET" {
+			status = status[1:]
+		}
+
+		fmt.Fprintf(w, `{"symbol":"BTCUSDT","orderId":1,"transactTime":%d,"price":"0","origQty":"0.00250","executedQty":"0.00250","cummulativeQuoteQty":"100","status":"%s","type":"MARKET","side":"%s"}`,
+			time.Now().UnixNano()/int64(time.Millisecond), status[0], r.FormValue("side"))
+
+	}))
+
+	t.Cleanup(server.Close)
+
+	client := binance.NewClient("", "")
+	client.BaseURL = server.URL
+
+	return &types.Session{
+		ThreadID:   "c683ok5mk1u1120gnmmg",
+		Symbol:     "BTCUSDT",
+		SymbolFiat: "USDT",
+		StepSize:   0.00001,
+		Storage:    storage,
+		Clients:    types.Client{Binance: client},
+	}, queries
+
+}
+
+func TestTicker_fault(t *testing.T) {
+
+	tests := []struct {
+		name string
+		side string
+		want []string
+	}{
+		{"buy", "BUY", []string{"SaveOrder 1", "SaveThreadTransaction 1"}},
+		{"sell", "SELL", []string{"SaveOrder 1", "DeleteThreadTransactionByOrderID 10", "SaveTrade 10 1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			storage := &faultStorage{}
+			sessionData, _ := newExchange(t, storage, "FILLED")
+			configData := &types.Config{ExchangeName: "binance", ExchangeComission: 0.001}
+			marketData := &types.Market{Price: 40000}
+
+			if tt.side == "BUY" {
+
+				BuyTicker(100, configData, marketData, sessionData)
+
+			} else if err := SellTicker(types.Order{OrderID: 10, Price: 39000, CumulativeQuoteQuantity: 97.5, ExecutedQuantity: 0.0025}, configData, marketData, sessionData); err != nil {
+
+				t.Fatalf("SellTicker() error = %v", err)
+
+			}
+
+			/* The order is filled on the exchange and not saved, trading stops */
+			if sessionData.Fault == nil || sessionData.Fault.OrderID != 1 || !strings.HasPrefix(sessionData.Fault.Reason, tt.side+" not saved") {
+				t.Fatalf("Session.Fault = %+v, want %s order 1 not saved", sessionData.Fault, tt.side)
+			}
+
+			/* The fault write saves the order once the database recovers */
+			storage.up = true
+			if err := storage.OrderTx(context.Background(), sessionData, sessionData.Fault.Write); err != nil || !reflect.DeepEqual(storage.tx.calls, tt.want) {
+				t.Errorf("Fault.Write() = %v, %v, want %v", err, storage.tx.calls, tt.want)
+			}
+
+		})
+	}
+
+}
+
+func TestBuyTicker_new(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		stopping bool /* Shutdown requested */
+		up       bool /* Database up */
+		want     []string
+	}{
+		{"filled", false, true, []string{"SaveOrder 1 NEW pending", "UpdateOrder 1 FILLED", "SaveThreadTransaction 1"}},
+		{"not saved on shutdown", true, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			storage := &faultStorage{up: tt.up}
+			sessionData, queries := newExchange(t, storage, "NEW", "NEW", "FILLED")
+			configData := &types.Config{ExchangeName: "binance"}
+
+			if tt.stopping {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				sessionData.Ctx = ctx
+			}
+
+			start := time.Now()
+			BuyTicker(100, configData, &types.Market{Price: 40000}, sessionData)
+
+			/* The order is queried again until it is filled, every 3 seconds on shutdown too */
+			if *queries != 2 || time.Since(start) < 3*time.Second {
+				t.Errorf("BuyTicker() queried the order %v times in %v, want 2 times in 3s", *queries, time.Since(start))
+			}
+
+			if !reflect.DeepEqual(storage.tx.calls, tt.want) {
+				t.Errorf("BuyTicker() writes = %v, want %v", storage.tx.calls, tt.want)
+			}
+
+			/* The order not saved on shutdown is kept for the thread to retry */
+			if (sessionData.Fault != nil) != !tt.up {
+				t.Errorf("Session.Fault = %+v, want a fault %v", sessionData.Fault, !tt.up)
+			}
+
+		})
+	}
+
+}
@@ -4,7 +4,6 @@ import (
 	"html/template"
 	"io"
 	"io/ioutil"
-	"log"
 	"net"
 	"net/http"
 	"path/filepath"
@@ -16,6 +15,9 @@ import (
 	"strconv"
 
 	"github.com/aleibovici/cryptopump/logger"
+	"github.com/aleibovici/cryptopump/money"
+	"github.com/aleibovici/cryptopump/rules"
+	"github.com/aleibovici/cryptopump/schedule"
 	"github.com/aleibovici/cryptopump/types"
 	"github.com/tcnksm/go-httpstat"
 
@@ -54,6 +56,15 @@ func Float64ToStr(value float64, prec int) string {
 
 }
 
+// StepToStr function
+/* This public function rounds value to a multiple of step and formats it with the decimal places of step,
+so quantities sent to the exchange or compared carry no binary float residue. Without step 8 decimal places are used. */
+func StepToStr(value float64, step float64) string {
+
+	return money.New(value).Step(step)
+
+}
+
 // IntToFloat64 convert Int to Float64
 func IntToFloat64(value int) float64 {
 
@@ -124,59 +135,12 @@ func GetThreadID() string {
 
 }
 
-/* Convert Strign to Time */
-func stringToTime(str string) (r time.Time) {
-
-	var err error
-
-	if r, err = time.Parse(time.Kitchen, str); err != nil {
-
-		logger.LogEntry{ /* Log Entry */
-			Config:   nil,
-			Market:   nil,
-			Session:  nil,
-			Order:    &types.Order{},
-			Message:  GetFunctionName() + " - " + err.Error(),
-			LogLevel: "DebugLevel",
-		}.Do()
-
-	}
-
-	return r
-
-}
-
-// IsInTimeRange Check if time is in a specific range
-func IsInTimeRange(startTimeString string, endTimeString string) bool {
-
-	t := time.Now()
-	timeNowString := t.Format(time.Kitchen)
-	timeNow := stringToTime(timeNowString)
-	start := stringToTime(startTimeString)
-	end := stringToTime(endTimeString)
-
-	if timeNow.Before(start) {
-
-		return false
-
-	}
-
-	if timeNow.After(end) {
-
-		return false
-
-	}
-
-	return true
-
-}
-
 // IsFundsAvailable Validate available funds to buy
 func IsFundsAvailable(
 	configData *types.Config,
 	sessionData *types.Session) bool {
 
-	return (sessionData.SymbolFiatFunds - configData.SymbolFiatStash) >= configData.BuyQuantityFiatDown
+	return money.New(sessionData.SymbolFiatFunds).Sub(money.New(configData.SymbolFiatStash)).Cmp(money.New(configData.BuyQuantityFiatDown)) >= 0
 
 }
 
@@ -208,20 +172,18 @@ func ExecuteTemplate(
 	data interface{},
 	sessionData *types.Session) {
 
-	var tlp *template.Template
-	var err error
-
-	if tlp, err = template.ParseGlob("./templates/*"); err != nil {
-
-		defer os.Exit(1)
+	ExecuteNamedTemplate(wr, selectTemplate(sessionData), data)
 
-	}
-
-	if err = tlp.ExecuteTemplate(wr, selectTemplate(sessionData), data); err != nil {
+}
 
-		defer os.Exit(1)
+// ExecuteNamedTemplate execute the template file name from the templates folder
+func ExecuteNamedTemplate(
+	wr io.Writer,
+	name string,
+	data interface{}) {
 
-	}
+	var tlp *template.Template
+	var err error
 
 	/* Conditional defer logging when there is an error retriving data */
 	defer func() {
@@ -237,6 +199,14 @@ func ExecuteTemplate(
 		}
 	}()
 
+	if tlp, err = template.ParseGlob("./templates/*"); err != nil {
+
+		return
+
+	}
+
+	err = tlp.ExecuteTemplate(wr, name, data)
+
 }
 
 // GetFunctionName Retrieve current function name
@@ -304,17 +274,40 @@ func DeleteConfigFile(sessionData *types.Session) {
 
 }
 
-// SaveConfigGlobalData save viper configuration from html
+// SaveConfigGlobalData save the secrets changed on the admin page and return their keys
 func SaveConfigGlobalData(
 	viperData *types.ViperData,
 	r *http.Request,
-	sessionData *types.Session) {
+	sessionData *types.Session) (changed []string) {
+
+	viperData.Mutex.Lock()
+	defer viperData.Mutex.Unlock()
+
+	/* Form fields and keys of the secrets. The admin page displays them masked, an empty field keeps the saved value. */
+	fields := []struct{ field, key string }{
+		{"Apikey", "apiKey"},                     /* Api Key */
+		{"Secretkey", "secretKey"},               /* Secret Key */
+		{"ApikeyTestNet", "apiKeyTestNet"},       /* Api Key TestNet */
+		{"SecretkeyTestNet", "secretKeyTestNet"}, /* Secret Key TestNet */
+		{"TgBotApikey", "tgbotapikey"},           /* Tg Bot Api Key */
+	}
+
+	for _, f := range fields {
+
+		if value := r.FormValue(f.field); value != "" && value != viperData.V2.GetString("config_global."+f.key) {
+
+			viperData.V2.Set("config_global."+f.key, value)
+			changed = append(changed, f.key)
+
+		}
+
+	}
+
+	if len(changed) == 0 {
+
+		return changed
 
-	viperData.V2.Set("config_global.apiKey", r.FormValue("Apikey"))                     /* Api Key */
-	viperData.V2.Set("config_global.secretKey", r.FormValue("Secretkey"))               /* Secret Key */
-	viperData.V2.Set("config_global.apiKeyTestNet", r.FormValue("ApikeyTestNet"))       /* Api Key TestNet */
-	viperData.V2.Set("config_global.secretKeyTestNet", r.FormValue("SecretkeyTestNet")) /* Secret Key TestNet */
-	viperData.V2.Set("config_global.tgbotapikey", r.FormValue("TgBotApikey"))           /* Tg Bot Api Key */
+	}
 
 	if err := viperData.V2.WriteConfig(); err != nil { /* Write configuration file */
 
@@ -338,6 +331,8 @@ func SaveConfigGlobalData(
 		LogLevel: "InfoLevel",
 	}.Do()
 
+	return changed
+
 }
 
 // GetConfigData Retrieve or create config file based on ThreadID
@@ -345,6 +340,9 @@ func GetConfigData(
 	viperData *types.ViperData,
 	sessionData *types.Session) *types.Config {
 
+	viperData.Mutex.Lock()
+	defer viperData.Mutex.Unlock()
+
 	configData := loadConfigData(viperData, sessionData)
 
 	if sessionData.ThreadID != "" {
@@ -441,8 +439,6 @@ func getConfigTemplateList(sessionData *types.Session) []string {
 			LogLevel: "DebugLevel",
 		}.Do()
 
-		os.Exit(1)
-
 	}
 
 	return files
@@ -455,6 +451,9 @@ func LoadConfigTemplate(
 	viperData *types.ViperData,
 	sessionData *types.Session) *types.Config {
 
+	viperData.Mutex.Lock()
+	defer viperData.Mutex.Unlock()
+
 	var filename string
 
 	/* Retrieve the list of configuration templates */
@@ -504,46 +503,108 @@ func LoadConfigTemplate(
 
 }
 
+// Kind of the value of a configuration key
+const (
+	ConfigBool = iota
+	ConfigInt
+	ConfigFloat
+	ConfigString
+)
+
+// ConfigKey is a key of the config section of the session configuration file and the types.Config field it is read into
+type ConfigKey struct {
+	Name  string
+	field func(c *types.Config) interface{}
+}
+
+// ConfigKeys are the keys of the config section read by GetConfigData and changed by the API
+var ConfigKeys = []ConfigKey{
+	{"adaptive", func(c *types.Config) interface{} { return &c.Adaptive }},
+	{"adaptive_period", func(c *types.Config) interface{} { return &c.AdaptivePeriod }},
+	{"adaptive_profit_max", func(c *types.Config) interface{} { return &c.AdaptiveProfitMax }},
+	{"adaptive_profit_min", func(c *types.Config) interface{} { return &c.AdaptiveProfitMin }},
+	{"adaptive_profit_multiplier", func(c *types.Config) interface{} { return &c.AdaptiveProfitMultiplier }},
+	{"adaptive_threshold_down_max", func(c *types.Config) interface{} { return &c.AdaptiveThresholdDownMax }},
+	{"adaptive_threshold_down_min", func(c *types.Config) interface{} { return &c.AdaptiveThresholdDownMin }},
+	{"adaptive_threshold_down_multiplier", func(c *types.Config) interface{} { return &c.AdaptiveThresholdDownMultiplier }},
+	{"buy_24hs_highprice_entry", func(c *types.Config) interface{} { return &c.Buy24hsHighpriceEntry }},
+	{"buy_direction_down", func(c *types.Config) interface{} { return &c.BuyDirectionDown }},
+	{"buy_direction_up", func(c *types.Config) interface{} { return &c.BuyDirectionUp }},
+	{"buy_max_slippage", func(c *types.Config) interface{} { return &c.BuyMaxSlippage }},
+	{"buy_quantity_fiat_down", func(c *types.Config) interface{} { return &c.BuyQuantityFiatDown }},
+	{"buy_quantity_fiat_init", func(c *types.Config) interface{} { return &c.BuyQuantityFiatInit }},
+	{"buy_quantity_fiat_up", func(c *types.Config) interface{} { return &c.BuyQuantityFiatUp }},
+	{"buy_repeat_threshold_down", func(c *types.Config) interface{} { return &c.BuyRepeatThresholdDown }},
+	{"buy_repeat_threshold_down_second", func(c *types.Config) interface{} { return &c.BuyRepeatThresholdDownSecond }},
+	{"buy_repeat_threshold_down_second_start_count", func(c *types.Config) interface{} { return &c.BuyRepeatThresholdDownSecondStartCount }},
+	{"buy_repeat_threshold_up", func(c *types.Config) interface{} { return &c.BuyRepeatThresholdUp }},
+	{"buy_rsi7_entry", func(c *types.Config) interface{} { return &c.BuyRsi7Entry }},
+	{"buy_rules", func(c *types.Config) interface{} { return &c.BuyRules }},
+	{"buy_slippage_split", func(c *types.Config) interface{} { return &c.BuySlippageSplit }},
+	{"buy_wait", func(c *types.Config) interface{} { return &c.BuyWait }},
+	{"debug", func(c *types.Config) interface{} { return &c.Debug }},
+	{"dryrun", func(c *types.Config) interface{} { return &c.DryRun }},
+	{"exchange_comission", func(c *types.Config) interface{} { return &c.ExchangeComission }},
+	{"exchangename", func(c *types.Config) interface{} { return &c.ExchangeName }},
+	{"exit", func(c *types.Config) interface{} { return &c.Exit }},
+	{"guard_max_jump", func(c *types.Config) interface{} { return &c.GuardMaxJump }},
+	{"guard_max_latency", func(c *types.Config) interface{} { return &c.GuardMaxLatency }},
+	{"guard_max_spread", func(c *types.Config) interface{} { return &c.GuardMaxSpread }},
+	{"guard_max_ws_age", func(c *types.Config) interface{} { return &c.GuardMaxWsAge }},
+	{"newsession", func(c *types.Config) interface{} { return &c.NewSession }},
+	{"profit_min", func(c *types.Config) interface{} { return &c.ProfitMin }},
+	{"risk_max_daily_loss", func(c *types.Config) interface{} { return &c.RiskMaxDailyLoss }},
+	{"risk_max_deployed", func(c *types.Config) interface{} { return &c.RiskMaxDeployed }},
+	{"risk_max_positions", func(c *types.Config) interface{} { return &c.RiskMaxPositions }},
+	{"risk_stopout_cooldown", func(c *types.Config) interface{} { return &c.RiskStopOutCooldown }},
+	{"risk_stopout_count", func(c *types.Config) interface{} { return &c.RiskStopOutCount }},
+	{"schedule", func(c *types.Config) interface{} { return &c.Schedule }},
+	{"sell_rules", func(c *types.Config) interface{} { return &c.SellRules }},
+	{"sellholdonrsi3", func(c *types.Config) interface{} { return &c.SellHoldOnRSI3 }},
+	{"selltocover", func(c *types.Config) interface{} { return &c.SellToCover }},
+	{"sellwaitaftercancel", func(c *types.Config) interface{} { return &c.SellWaitAfterCancel }},
+	{"sellwaitbeforecancel", func(c *types.Config) interface{} { return &c.SellWaitBeforeCancel }},
+	{"stoploss", func(c *types.Config) interface{} { return &c.Stoploss }},
+	{"symbol", func(c *types.Config) interface{} { return &c.Symbol }},
+	{"symbol_fiat", func(c *types.Config) interface{} { return &c.SymbolFiat }},
+	{"symbol_fiat_stash", func(c *types.Config) interface{} { return &c.SymbolFiatStash }},
+	{"testnet", func(c *types.Config) interface{} { return &c.TestNet }},
+	{"time_enforce", func(c *types.Config) interface{} { return &c.TimeEnforce }},
+	{"time_start", func(c *types.Config) interface{} { return &c.TimeStart }},
+	{"time_stop", func(c *types.Config) interface{} { return &c.TimeStop }},
+}
+
+// Kind return the kind of the value of the key, from the type of its types.Config field
+func (k ConfigKey) Kind() int {
+
+	switch k.field(&types.Config{}).(type) {
+	case *bool:
+
+		return ConfigBool
+
+	case *int, *int64:
+
+		return ConfigInt
+
+	case *float64:
+
+		return ConfigFloat
+
+	}
+
+	return ConfigString
+
+}
+
 /* This routine load viper configuration data into map[string]interface{} */
 func loadConfigData(
 	viperData *types.ViperData,
 	sessionData *types.Session) *types.Config {
 
 	configData := &types.Config{
-		ThreadID:                               sessionData.ThreadID,
-		Buy24hsHighpriceEntry:                  viperData.V1.GetFloat64("config.buy_24hs_highprice_entry"),
-		BuyDirectionDown:                       viperData.V1.GetInt("config.buy_direction_down"),
-		BuyDirectionUp:                         viperData.V1.GetInt("config.buy_direction_up"),
-		BuyQuantityFiatUp:                      viperData.V1.GetFloat64("config.buy_quantity_fiat_up"),
-		BuyQuantityFiatDown:                    viperData.V1.GetFloat64("config.buy_quantity_fiat_down"),
-		BuyQuantityFiatInit:                    viperData.V1.GetFloat64("config.buy_quantity_fiat_init"),
-		BuyRepeatThresholdDown:                 viperData.V1.GetFloat64("config.buy_repeat_threshold_down"),
-		BuyRepeatThresholdDownSecond:           viperData.V1.GetFloat64("config.buy_repeat_threshold_down_second"),
-		BuyRepeatThresholdDownSecondStartCount: viperData.V1.GetInt("config.buy_repeat_threshold_down_second_start_count"),
-		BuyRepeatThresholdUp:                   viperData.V1.GetFloat64("config.buy_repeat_threshold_up"),
-		BuyRsi7Entry:                           viperData.V1.GetFloat64("config.buy_rsi7_entry"),
-		BuyWait:                                viperData.V1.GetInt("config.buy_wait"),
-		ExchangeComission:                      viperData.V1.GetFloat64("config.exchange_comission"),
-		ProfitMin:                              viperData.V1.GetFloat64("config.profit_min"),
-		SellWaitBeforeCancel:                   viperData.V1.GetInt("config.sellwaitbeforecancel"),
-		SellWaitAfterCancel:                    viperData.V1.GetInt("config.sellwaitaftercancel"),
-		SellToCover:                            viperData.V1.GetBool("config.selltocover"),
-		SellHoldOnRSI3:                         viperData.V1.GetFloat64("config.sellholdonrsi3"),
-		Stoploss:                               viperData.V1.GetFloat64("config.stoploss"),
-		SymbolFiat:                             viperData.V1.GetString("config.symbol_fiat"),
-		SymbolFiatStash:                        viperData.V1.GetFloat64("config.symbol_fiat_stash"),
-		Symbol:                                 viperData.V1.GetString("config.symbol"),
-		TimeEnforce:                            viperData.V1.GetBool("config.time_enforce"),
-		TimeStart:                              viperData.V1.GetString("config.time_start"),
-		TimeStop:                               viperData.V1.GetString("config.time_stop"),
-		Debug:                                  viperData.V1.GetBool("config.debug"),
-		Exit:                                   viperData.V1.GetBool("config.exit"),
-		DryRun:                                 viperData.V1.GetBool("config.dryrun"),
-		NewSession:                             viperData.V1.GetBool("config.newsession"),
-		ConfigTemplateList:                     getConfigTemplateList(sessionData),
-		ExchangeName:                           viperData.V1.GetString("config.exchangename"),
-		TestNet:                                viperData.V1.GetBool("config.testnet"),
-		HTMLSnippet:                            nil,
+		ThreadID:           sessionData.ThreadID,
+		ConfigTemplateList: getConfigTemplateList(sessionData),
+		HTMLSnippet:        nil,
 		ConfigGlobal: &types.ConfigGlobal{
 			Apikey:           viperData.V2.GetString("config_global.apiKey"),
 			Secretkey:        viperData.V2.GetString("config_global.secretKey"),
@@ -552,16 +613,118 @@ func loadConfigData(
 			TgBotApikey:      viperData.V2.GetString("config_global.tgbotapikey")},
 	}
 
+	for _, key := range ConfigKeys {
+
+		switch field := key.field(configData).(type) {
+		case *bool:
+
+			*field = viperData.V1.GetBool("config." + key.Name)
+
+		case *int:
+
+			*field = viperData.V1.GetInt("config." + key.Name)
+
+		case *int64:
+
+			*field = viperData.V1.GetInt64("config." + key.Name)
+
+		case *float64:
+
+			*field = viperData.V1.GetFloat64("config." + key.Name)
+
+		case *string:
+
+			*field = viperData.V1.GetString("config." + key.Name)
+
+		}
+
+	}
+
+	parseRules(configData)
+	parseSchedule(configData)
+
 	return configData
 
 }
 
+/* Parse buy_rules and sell_rules expressions. Parse errors are logged and kept in
+configData.RulesError so they are displayed when the template is loaded. */
+func parseRules(configData *types.Config) {
+
+	var err error
+	var errs []string
+
+	if configData.BuyRulesExpression, err = rules.ParseBuy(configData.BuyRules); err != nil {
+
+		errs = append(errs, "buy_rules: "+err.Error())
+
+	}
+
+	if configData.SellRulesExpression, err = rules.Parse(configData.SellRules); err != nil {
+
+		errs = append(errs, "sell_rules: "+err.Error())
+
+	}
+
+	if len(errs) > 0 {
+
+		configData.RulesError = strings.Join(errs, " / ")
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  nil,
+			Order:    &types.Order{},
+			Message:  GetFunctionName() + " - " + configData.RulesError,
+			LogLevel: "DebugLevel",
+		}.Do()
+
+	}
+
+}
+
+/* Parse the schedule, or time_start and time_stop when time_enforce is true and the schedule is empty. Parse
+errors are logged and kept in configData.ScheduleError so they are displayed when the template is loaded. */
+func parseSchedule(configData *types.Config) {
+
+	var err error
+
+	if configData.Schedule == "" && configData.TimeEnforce {
+
+		configData.ScheduleSpec, err = schedule.Legacy(configData.TimeStart, configData.TimeStop)
+
+	} else {
+
+		configData.ScheduleSpec, err = schedule.Parse(configData.Schedule)
+
+	}
+
+	if err != nil {
+
+		configData.ScheduleError = err.Error()
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  nil,
+			Order:    &types.Order{},
+			Message:  GetFunctionName() + " - schedule: " + configData.ScheduleError,
+			LogLevel: "DebugLevel",
+		}.Do()
+
+	}
+
+}
+
 // SaveConfigData save viper configuration from html
 func SaveConfigData(
 	viperData *types.ViperData,
 	r *http.Request,
 	sessionData *types.Session) {
 
+	viperData.Mutex.Lock()
+	defer viperData.Mutex.Unlock()
+
 	viperData.V1.Set("config.buy_24hs_highprice_entry", r.PostFormValue("buy24hsHighpriceEntry"))
 	viperData.V1.Set("config.buy_direction_down", r.PostFormValue("buyDirectionDown"))
 	viperData.V1.Set("config.buy_direction_up", r.PostFormValue("buyDirectionUp"))
@@ -574,6 +737,7 @@ func SaveConfigData(
 	viperData.V1.Set("config.buy_repeat_threshold_down_second", r.PostFormValue("buyRepeatThresholdDownSecond"))
 	viperData.V1.Set("config.buy_repeat_threshold_down_second_start_count", r.PostFormValue("buyRepeatThresholdDownSecondStartCount"))
 	viperData.V1.Set("config.buy_repeat_threshold_up", r.PostFormValue("buyRepeatThresholdUp"))
+	viperData.V1.Set("config.buy_rules", r.PostFormValue("buyRules"))
 	viperData.V1.Set("config.exchange_comission", r.PostFormValue("exchangeComission"))
 	if r.PostFormValue("exchangename") != "" { /* Test for disabled input in index_nostart.html where return is nil */
 		viperData.V1.Set("config.exchangename", r.PostFormValue("exchangename"))
@@ -583,6 +747,26 @@ func SaveConfigData(
 	viperData.V1.Set("config.sellwaitaftercancel", r.PostFormValue("sellwaitaftercancel"))
 	viperData.V1.Set("config.selltocover", r.PostFormValue("selltocover"))
 	viperData.V1.Set("config.sellholdonrsi3", r.PostFormValue("sellholdonrsi3"))
+	viperData.V1.Set("config.sell_rules", r.PostFormValue("sellRules"))
+	viperData.V1.Set("config.adaptive", r.PostFormValue("adaptive"))
+	viperData.V1.Set("config.adaptive_period", r.PostFormValue("adaptivePeriod"))
+	viperData.V1.Set("config.adaptive_profit_multiplier", r.PostFormValue("adaptiveProfitMultiplier"))
+	viperData.V1.Set("config.adaptive_profit_min", r.PostFormValue("adaptiveProfitMin"))
+	viperData.V1.Set("config.adaptive_profit_max", r.PostFormValue("adaptiveProfitMax"))
+	viperData.V1.Set("config.adaptive_threshold_down_multiplier", r.PostFormValue("adaptiveThresholdDownMultiplier"))
+	viperData.V1.Set("config.adaptive_threshold_down_min", r.PostFormValue("adaptiveThresholdDownMin"))
+	viperData.V1.Set("config.adaptive_threshold_down_max", r.PostFormValue("adaptiveThresholdDownMax"))
+	viperData.V1.Set("config.risk_max_positions", r.PostFormValue("riskMaxPositions"))
+	viperData.V1.Set("config.risk_max_deployed", r.PostFormValue("riskMaxDeployed"))
+	viperData.V1.Set("config.risk_max_daily_loss", r.PostFormValue("riskMaxDailyLoss"))
+	viperData.V1.Set("config.risk_stopout_count", r.PostFormValue("riskStopOutCount"))
+	viperData.V1.Set("config.risk_stopout_cooldown", r.PostFormValue("riskStopOutCooldown"))
+	viperData.V1.Set("config.guard_max_spread", r.PostFormValue("guardMaxSpread"))
+	viperData.V1.Set("config.guard_max_jump", r.PostFormValue("guardMaxJump"))
+	viperData.V1.Set("config.guard_max_latency", r.PostFormValue("guardMaxLatency"))
+	viperData.V1.Set("config.guard_max_ws_age", r.PostFormValue("guardMaxWsAge"))
+	viperData.V1.Set("config.buy_max_slippage", r.PostFormValue("buyMaxSlippage"))
+	viperData.V1.Set("config.buy_slippage_split", r.PostFormValue("buySlippageSplit"))
 	viperData.V1.Set("config.Stoploss", r.PostFormValue("stoploss"))
 	if r.PostFormValue("exchangename") != "" { /* Test for disabled input in index_nostart.html where return is nil */
 		viperData.V1.Set("config.symbol", r.PostFormValue("symbol"))
@@ -594,6 +778,7 @@ func SaveConfigData(
 	viperData.V1.Set("config.time_enforce", r.PostFormValue("timeEnforce"))
 	viperData.V1.Set("config.time_start", r.PostFormValue("timeStart"))
 	viperData.V1.Set("config.time_stop", r.PostFormValue("timeStop"))
+	viperData.V1.Set("config.schedule", r.PostFormValue("schedule"))
 	if r.PostFormValue("exchangename") != "" { /* Test for disabled input in index_nostart.html where return is nil */
 		viperData.V1.Set("config.testnet", r.PostFormValue("testnet"))
 	}
@@ -615,7 +800,6 @@ func SaveConfigData(
 			LogLevel: "DebugLevel",
 		}.Do()
 
-		os.Exit(1)
 	}
 
 }
@@ -647,10 +831,14 @@ func GetExchangeLatency(sessionData *types.Session) (latency int64, err error) {
 
 	}
 
-	if _, err := io.Copy(ioutil.Discard, res.Body); err != nil {
-		log.Fatal(err)
+	defer res.Body.Close()
+
+	if _, err = io.Copy(ioutil.Discard, res.Body); err != nil {
+
+		return 0, err
+
 	}
-	res.Body.Close()
+
 	result.End(time.Now())
 
 	return result.ServerProcessing.Milliseconds(), err
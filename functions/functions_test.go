@@ -1,7 +1,11 @@
 package functions
 
 import (
+	"math"
 	"testing"
+
+	"github.com/aleibovici/cryptopump/types"
+	"github.com/spf13/viper"
 )
 
 func TestFloat64ToStr(t *testing.T) {
@@ -32,6 +36,82 @@ func TestFloat64ToStr(t *testing.T) {
 	}
 }
 
+func TestStepToStr(t *testing.T) {
+	type args struct {
+		value float64
+		step  float64
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "BTC lot step",
+			args: args{
+				value: 0.0023456789,
+				step:  0.00001,
+			},
+			want: "0.00235",
+		},
+		{
+			name: "float residue",
+			args: args{
+				value: 0.1 + 0.2,
+				step:  0.1,
+			},
+			want: "0.3",
+		},
+		{
+			name: "whole step",
+			args: args{
+				value: 12.6,
+				step:  1,
+			},
+			want: "13",
+		},
+		{
+			name: "negative",
+			args: args{
+				value: 0.00123 - 0.00123000004,
+				step:  0.00001,
+			},
+			want: "0.00000",
+		},
+		{
+			name: "half step",
+			args: args{
+				value: 0.00015,
+				step:  0.0001,
+			},
+			want: "0.0002",
+		},
+		{
+			name: "no step",
+			args: args{
+				value: 1.123456789,
+				step:  0,
+			},
+			want: "1.12345679",
+		},
+		{
+			name: "NaN",
+			args: args{
+				value: math.NaN(),
+				step:  0.001,
+			},
+			want: "0.000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StepToStr(tt.args.value, tt.args.step); got != tt.want {
+				t.Errorf("StepToStr() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestIntToFloat64(t *testing.T) {
 	type args struct {
 		value int
@@ -128,3 +208,47 @@ func TestGetThreadID(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadConfigData(t *testing.T) {
+
+	viperData := &types.ViperData{V1: viper.New(), V2: viper.New()}
+
+	values := map[int]interface{}{ConfigBool: true, ConfigInt: 7, ConfigFloat: 0.5, ConfigString: "x"}
+	for _, key := range ConfigKeys {
+		viperData.V1.Set("config."+key.Name, values[key.Kind()])
+	}
+
+	configData := loadConfigData(viperData, &types.Session{})
+
+	names := map[string]bool{}
+	for _, key := range ConfigKeys {
+
+		if names[key.Name] {
+			t.Errorf("ConfigKeys %v is duplicated", key.Name)
+		}
+
+		names[key.Name] = true
+
+		var got interface{}
+		switch field := key.field(configData).(type) {
+		case *bool:
+			got = *field
+		case *int:
+			got = *field
+		case *int64:
+			got = int(*field)
+		case *float64:
+			got = *field
+		case *string:
+			got = *field
+		default:
+			t.Fatalf("ConfigKeys %v is a %T field", key.Name, field)
+		}
+
+		if got != values[key.Kind()] {
+			t.Errorf("loadConfigData() %v = %v, want %v", key.Name, got, values[key.Kind()])
+		}
+
+	}
+
+}
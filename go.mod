@@ -10,7 +10,7 @@ require (
 	github.com/go-sql-driver/mysql v1.6.0
 	github.com/go-telegram-bot-api/telegram-bot-api v4.6.4+incompatible
 	github.com/gorilla/websocket v1.4.2 // indirect
-	github.com/jtaczanowski/go-scheduler v0.1.0
+	github.com/mattn/go-sqlite3 v1.14.10
 	github.com/paulbellamy/ratecounter v0.2.0
 	github.com/rs/xid v1.3.0
 	github.com/sdcoffey/big v0.7.0
@@ -21,6 +21,7 @@ require (
 	github.com/spf13/viper v1.8.1
 	github.com/tcnksm/go-httpstat v0.2.0
 	github.com/technoweenie/multipartstreamer v1.0.1 // indirect
+	golang.org/x/crypto v0.0.0-20211117183948-ae814b36b871
 	golang.org/x/sys v0.0.0-20211019181941-9d821ace8654 // indirect
 	golang.org/x/text v0.3.7 // indirect
 )
@@ -0,0 +1,138 @@
+package guards
+
+/* This package implements the pre-trade guard. The guard blocks BUY and SELL decisions
+when market data looks abnormal: wide bid/ask spread, sudden price jump from the last
+kline close, high exchange latency, stale websocket data or a symbol that is not trading.
+Each block is recorded with its reason so a guarded market can be told apart from a
+quiet one. */
+
+import (
+	"time"
+
+	"github.com/aleibovici/cryptopump/functions"
+	"github.com/aleibovici/cryptopump/logger"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+/* Maximum age of the kline close used by the price jump guard */
+const klineCloseAge = 60 * time.Second
+
+// Guard pre-trade market anomaly guard
+type Guard struct{}
+
+// IsTradeAllowed test the market anomaly guards. The guard that blocked is stored in sessionData.Guard.
+func (g Guard) IsTradeAllowed(
+	configData *types.Config,
+	marketData *types.Market,
+	sessionData *types.Session) bool {
+
+	name, reason := g.check(configData, marketData, sessionData)
+
+	if reason != "" {
+
+		sessionData.Guard.LastBlock = time.Now()
+
+	}
+
+	if reason != sessionData.Guard.Reason {
+
+		if sessionData.Guard.Count == nil {
+
+			sessionData.Guard.Count = make(map[string]int)
+
+		}
+
+		message := "GUARD - orders resumed"
+		if reason != "" {
+
+			sessionData.Guard.Count[name]++ /* Count each time a guard starts blocking */
+			message = "GUARD - orders blocked: " + reason
+
+		}
+
+		sessionData.Guard.Reason = reason
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   configData,
+			Market:   marketData,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  message,
+			LogLevel: "InfoLevel",
+		}.Do()
+
+	}
+
+	return reason == ""
+
+}
+
+/* Return the name and reason of the first guard blocking orders */
+func (g Guard) check(
+	configData *types.Config,
+	marketData *types.Market,
+	sessionData *types.Session) (name string, reason string) {
+
+	/* Symbol not TRADING as reported by the exchange (i.e. BREAK, HALT) */
+	if sessionData.SymbolStatus != "" &&
+		sessionData.SymbolStatus != "TRADING" {
+
+		return "status", "Symbol status " + sessionData.SymbolStatus
+
+	}
+
+	/* Bid/ask spread as a ratio of the ask price */
+	if configData.GuardMaxSpread > 0 &&
+		marketData.BestAsk > 0 &&
+		marketData.BestBid > 0 {
+
+		if spread := (marketData.BestAsk - marketData.BestBid) / marketData.BestAsk; spread > configData.GuardMaxSpread {
+
+			return "spread", "Spread " + functions.Float64ToStr(spread*100, 3) + "% above threshold"
+
+		}
+
+	}
+
+	/* Price jump from the last kline close. Kline updates arrive every few seconds,
+	so an old kline close is not compared. */
+	if configData.GuardMaxJump > 0 &&
+		marketData.KlineClose > 0 &&
+		marketData.Price > 0 &&
+		time.Since(marketData.KlineCloseTime) < klineCloseAge {
+
+		jump := (marketData.Price - marketData.KlineClose) / marketData.KlineClose
+		if jump < 0 {
+
+			jump = -jump
+
+		}
+
+		if jump > configData.GuardMaxJump {
+
+			return "jump", "Price jump " + functions.Float64ToStr(jump*100, 3) + "% from last kline close"
+
+		}
+
+	}
+
+	/* Exchange latency in milliseconds */
+	if configData.GuardMaxLatency > 0 &&
+		sessionData.Latency > configData.GuardMaxLatency {
+
+		return "latency", "Latency " + functions.Float64ToStr(float64(sessionData.Latency), 0) + "ms above threshold"
+
+	}
+
+	/* Age of the last kline websocket update */
+	if configData.GuardMaxWsAge > 0 &&
+		!sessionData.LastWsKlineTime.IsZero() &&
+		time.Since(sessionData.LastWsKlineTime).Seconds() > float64(configData.GuardMaxWsAge) {
+
+		return "websocket", "Kline websocket older than " + functions.Float64ToStr(float64(configData.GuardMaxWsAge), 0) + " seconds"
+
+	}
+
+	return "", ""
+
+}
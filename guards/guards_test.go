@@ -0,0 +1,114 @@
+package guards
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aleibovici/cryptopump/types"
+)
+
+func TestGuard_IsTradeAllowed(t *testing.T) {
+
+	configData := &types.Config{
+		GuardMaxSpread:  0.002,
+		GuardMaxJump:    0.02,
+		GuardMaxLatency: 500,
+		GuardMaxWsAge:   30,
+	}
+
+	type args struct {
+		marketData  *types.Market
+		sessionData *types.Session
+	}
+
+	tests := []struct {
+		name       string
+		args       args
+		want       bool
+		wantReason string
+		wantCount  string
+	}{
+		{
+			name: "normal market",
+			args: args{
+				marketData:  &types.Market{Price: 100, BestAsk: 100, BestBid: 99.9, KlineClose: 100.5, KlineCloseTime: time.Now()},
+				sessionData: &types.Session{SymbolStatus: "TRADING", Latency: 100, LastWsKlineTime: time.Now()},
+			},
+			want:       true,
+			wantReason: "",
+		},
+		{
+			name: "symbol not trading",
+			args: args{
+				marketData:  &types.Market{Price: 100, BestAsk: 100, BestBid: 99.9},
+				sessionData: &types.Session{SymbolStatus: "HALT"},
+			},
+			want:       false,
+			wantReason: "Symbol status HALT",
+			wantCount:  "status",
+		},
+		{
+			name: "spread",
+			args: args{
+				marketData:  &types.Market{Price: 100, BestAsk: 100, BestBid: 99.5},
+				sessionData: &types.Session{},
+			},
+			want:       false,
+			wantReason: "Spread 0.500% above threshold",
+			wantCount:  "spread",
+		},
+		{
+			name: "price jump",
+			args: args{
+				marketData:  &types.Market{Price: 95, BestAsk: 95, BestBid: 95, KlineClose: 100, KlineCloseTime: time.Now()},
+				sessionData: &types.Session{},
+			},
+			want:       false,
+			wantReason: "Price jump 5.000% from last kline close",
+			wantCount:  "jump",
+		},
+		{
+			name: "old kline close",
+			args: args{
+				marketData:  &types.Market{Price: 95, BestAsk: 95, BestBid: 95, KlineClose: 100, KlineCloseTime: time.Now().Add(-time.Hour)},
+				sessionData: &types.Session{},
+			},
+			want:       true,
+			wantReason: "",
+		},
+		{
+			name: "latency",
+			args: args{
+				marketData:  &types.Market{Price: 100, BestAsk: 100, BestBid: 100},
+				sessionData: &types.Session{Latency: 900},
+			},
+			want:       false,
+			wantReason: "Latency 900ms above threshold",
+			wantCount:  "latency",
+		},
+		{
+			name: "websocket age",
+			args: args{
+				marketData:  &types.Market{Price: 100, BestAsk: 100, BestBid: 100},
+				sessionData: &types.Session{LastWsKlineTime: time.Now().Add(-time.Minute)},
+			},
+			want:       false,
+			wantReason: "Kline websocket older than 30 seconds",
+			wantCount:  "websocket",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Guard{}).IsTradeAllowed(configData, tt.args.marketData, tt.args.sessionData); got != tt.want {
+				t.Errorf("Guard.IsTradeAllowed() = %v, want %v", got, tt.want)
+			}
+			if tt.args.sessionData.Guard.Reason != tt.wantReason {
+				t.Errorf("Guard.IsTradeAllowed() reason = %v, want %v", tt.args.sessionData.Guard.Reason, tt.wantReason)
+			}
+			if tt.wantCount != "" && tt.args.sessionData.Guard.Count[tt.wantCount] != 1 {
+				t.Errorf("Guard.IsTradeAllowed() count = %v, want 1", tt.args.sessionData.Guard.Count)
+			}
+		})
+	}
+}
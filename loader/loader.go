@@ -5,6 +5,7 @@ that is made available to the javascript autoloader for html output. This data
 is commonly loaded via the webserver using GET/sessiondata */
 
 import (
+	"context"
 	"encoding/json"
 	"math"
 	"strconv"
@@ -12,7 +13,8 @@ import (
 
 	"github.com/aleibovici/cryptopump/functions"
 	"github.com/aleibovici/cryptopump/logger"
-	"github.com/aleibovici/cryptopump/mysql"
+	"github.com/aleibovici/cryptopump/markets"
+	"github.com/aleibovici/cryptopump/pause"
 	"github.com/aleibovici/cryptopump/types"
 )
 
@@ -23,21 +25,14 @@ func LoadSessionDataAdditionalComponents(
 	configData *types.Config) ([]byte, error) {
 
 	type Market struct {
-		Rsi3      float64 /* Relative Strength Index for 3 periods */
-		Rsi7      float64 /* Relative Strength Index for 7 periods */
-		Rsi14     float64 /* Relative Strength Index for 14 periods */
-		MACD      float64 /* Moving average convergence divergence */
-		Price     float64 /* Market Price */
-		Direction int     /* Market Direction */
-	}
-
-	type Order struct {
-		OrderID  string  /* Order ID */
-		Quantity float64 /* Order Quantity */
-		Quote    float64 /* Quote price */
-		Price    float64 /* Acquisition Price */
-		Target   float64 /* Target Price */
-		Diff     float64 /* Difference between target and market price */
+		Rsi3       float64 /* Relative Strength Index for 3 periods */
+		Rsi7       float64 /* Relative Strength Index for 7 periods */
+		Rsi14      float64 /* Relative Strength Index for 14 periods */
+		MACD       float64 /* Moving average convergence divergence */
+		Price      float64 /* Market Price */
+		Direction  int     /* Market Direction */
+		Volatility float64 /* Average True Range as a ratio of price */
+		Imbalance  float64 /* Order book bid/ask quantity imbalance */
 	}
 
 	type Session struct {
@@ -58,9 +53,16 @@ func LoadSessionDataAdditionalComponents(
 		RateCounter            int64   /* Average Number of transactions per second proccessed by WsBookTicker */
 		BuyDecisionTreeResult  string  /* Hold BuyDecisionTree result */
 		SellDecisionTreeResult string  /* Hold SellDecisionTree result */
+		RiskReason             string  /* Risk rule pausing BUY */
+		GuardReason            string  /* Guard blocking orders */
+		Master                 string  /* ThreadID of the Master Node */
+		Pause                  string  /* Trading pause with who paused the thread and when, Running when not paused */
+		PauseMode              string  /* Trading pause mode, empty when not paused */
+		PauseConfirm           bool    /* The resume requires confirmation */
+		Schedule               string  /* Trading schedule behavior with the next transition */
 		QuantityOffset         float64 /* Quantity offset */
 		DiffTotal              float64 /* Total difference between target and market price */
-		Orders                 []Order
+		Orders                 []order
 	}
 
 	type Update struct {
@@ -76,6 +78,8 @@ func LoadSessionDataAdditionalComponents(
 	sessiondata.Market.MACD = math.Round(marketData.MACD*10000) / 10000
 	sessiondata.Market.Price = math.Round(marketData.Price*1000) / 1000
 	sessiondata.Market.Direction = marketData.Direction
+	sessiondata.Market.Volatility = math.Round(marketData.Volatility*1000000) / 10000 /* Volatility percentage */
+	sessiondata.Market.Imbalance = math.Round(marketData.BookImbalance*100) / 100
 
 	sessiondata.Session.Latency = sessionData.Latency /* Latency between the exchange and client */
 	sessiondata.Session.ThreadID = sessionData.ThreadID
@@ -87,7 +91,24 @@ func LoadSessionDataAdditionalComponents(
 	sessiondata.Session.RateCounter = sessionData.RateCounter.Rate() / 5            /* Average Number of transactions per second proccessed by WsBookTicker */
 	sessiondata.Session.BuyDecisionTreeResult = sessionData.BuyDecisionTreeResult   /* Hold BuyDecisionTree result*/
 	sessiondata.Session.SellDecisionTreeResult = sessionData.SellDecisionTreeResult /* Hold SellDecisionTree result */
-	sessiondata.Session.QuantityOffset = sessiondata.Session.SymbolFunds            /* Quantity offset */
+	sessiondata.Session.RiskReason = sessionData.Risk.Reason                        /* Risk rule pausing BUY */
+	sessiondata.Session.GuardReason = sessionData.Guard.Reason                      /* Guard blocking orders */
+	sessiondata.Session.Master = sessionData.Lease.Holder                           /* ThreadID of the Master Node */
+	sessiondata.Session.Pause = pause.Describe(sessionData.Pause)                   /* Trading pause */
+	sessiondata.Session.PauseMode = sessionData.Pause.Mode                          /* Trading pause mode */
+	sessiondata.Session.PauseConfirm = sessionData.Pause.Confirm                    /* The resume requires confirmation */
+	sessiondata.Session.QuantityOffset = sessionData.SymbolFunds                    /* Quantity offset */
+
+	/* Trading schedule behavior with the next transition, empty without a schedule */
+	if configData.ScheduleError != "" {
+
+		sessiondata.Session.Schedule = "Schedule error: " + configData.ScheduleError
+
+	} else if configData.ScheduleSpec != nil {
+
+		sessiondata.Session.Schedule = configData.ScheduleSpec.At(time.Now()).String()
+
+	}
 
 	sessiondata.Session.Profit = math.Round(sessionData.Global.Profit*100) / 100                       /* Sessions.Global loaded from mySQL via loadSessionDataAdditionalComponentsAsync */
 	sessiondata.Session.ProfitNet = math.Round(sessionData.Global.ProfitNet*100) / 100                 /* Sessions.Global loaded from mySQL via loadSessionDataAdditionalComponentsAsync */
@@ -97,56 +118,116 @@ func LoadSessionDataAdditionalComponents(
 	sessiondata.Session.ThreadCount = sessionData.Global.ThreadCount                                   /* Sessions.Global loaded from mySQL via loadSessionDataAdditionalComponentsAsync */
 	sessiondata.Session.ThreadAmount = math.Round(sessionData.Global.ThreadAmount*100) / 100           /* Sessions.Global loaded from mySQL via loadSessionDataAdditionalComponentsAsync */
 
-	if orders, err := mysql.GetThreadTransactionByThreadID(sessionData); err == nil {
+	if orders, diffTotal, quantityOffset, err := positions(sessionData, marketData, configData); err == nil {
 
-		for _, key := range orders {
+		sessiondata.Session.Orders = orders
+		sessiondata.Session.DiffTotal = diffTotal
+		sessiondata.Session.QuantityOffset = 0
 
-			tmp := Order{}
-			tmp.OrderID = strconv.Itoa(key.OrderID)                                                                                                         /* Order ID */
-			tmp.Quantity = key.ExecutedQuantity                                                                                                             /* Order Quantity */
-			tmp.Quote = math.Round(key.CumulativeQuoteQuantity*100) / 100                                                                                   /* Quote price */
-			tmp.Price = math.Round(key.Price*10000) / 10000                                                                                                 /* Acquisition Price */
-			tmp.Target = math.Round((tmp.Price*(1+configData.ProfitMin))*1000) / 1000                                                                       /* Target price */
-			tmp.Diff = math.Round((((key.ExecutedQuantity*sessiondata.Market.Price)*(1+configData.ExchangeComission))-key.CumulativeQuoteQuantity)*10) / 10 /* Difference between target and market price */
+		if quantityOffset < 0 { /* Only display Quantity offset if negative */
 
-			sessiondata.Session.Orders = append(sessiondata.Session.Orders, tmp)
-			sessiondata.Session.QuantityOffset -= tmp.Quantity /* Quantity offset */
+			sessiondata.Session.QuantityOffset = math.Round(quantityOffset*100) / 100 /* Quantity offset */
 
-			sessiondata.Session.DiffTotal += (tmp.Diff * (1 - configData.ExchangeComission)) /* Total difference between target and market price (minus ExchangeComission used for visual aid in UI) */
 		}
 
-		sessiondata.Session.DiffTotal = math.Round(sessiondata.Session.DiffTotal*1) / 1 /* Total difference between target and market price round up  for session (local function variable)*/
-		sessionData.DiffTotal = sessiondata.Session.DiffTotal                           /* Total difference between target and market price for session (tranfer value to sessionData struct)*/
+	}
 
-		if sessiondata.Session.QuantityOffset >= 0 { /* Only display Quantity offset if negative */
+	return json.Marshal(sessiondata)
 
-			sessiondata.Session.QuantityOffset = 0
-			sessionData.QuantityOffsetFlag = false
+}
 
-		} else if sessiondata.Session.QuantityOffset < 0 { /* Only display Quantity offset if negative */
+// LoadPositions set DiffTotal and QuantityOffsetFlag of the session from its Thread transactions, and log a
+// quantity offset when it appears. It runs on the owner goroutine, the web UI only reads them.
+func LoadPositions(
+	sessionData *types.Session,
+	marketData *types.Market,
+	configData *types.Config) {
 
-			sessiondata.Session.QuantityOffset = math.Round(sessiondata.Session.QuantityOffset*100) / 100 /* Quantity offset */
+	_, diffTotal, quantityOffset, err := positions(sessionData, marketData, configData)
+	if err != nil {
 
-			if !sessionData.QuantityOffsetFlag { /* Only log Quantity offset error if first time */
+		return
 
-				logger.LogEntry{ /* Log Entry */
-					Config:   configData,
-					Market:   nil,
-					Session:  sessionData,
-					Order:    &types.Order{},
-					Message:  "Quantity offset: " + strconv.FormatFloat(sessiondata.Session.QuantityOffset, 'f', 2, 64),
-					LogLevel: "DebugLevel",
-				}.Do()
+	}
 
-			}
+	sessionData.DiffTotal = diffTotal /* Saved in the session table */
 
-			sessionData.QuantityOffsetFlag = true /* Quantity offset flag */
+	if quantityOffset < 0 && !sessionData.QuantityOffsetFlag { /* Only log Quantity offset error if first time */
 
-		}
+		logger.LogEntry{ /* Log Entry */
+			Config:   configData,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  "Quantity offset: " + strconv.FormatFloat(quantityOffset, 'f', 2, 64),
+			LogLevel: "DebugLevel",
+		}.Do()
 
 	}
 
-	return json.Marshal(sessiondata)
+	sessionData.QuantityOffsetFlag = quantityOffset < 0 /* Quantity offset flag */
+
+}
+
+/* order is a Thread transaction of the web UI */
+type order struct {
+	OrderID  string  /* Order ID */
+	Quantity float64 /* Order Quantity */
+	Quote    float64 /* Quote price */
+	Price    float64 /* Acquisition Price */
+	Target   float64 /* Target Price */
+	Diff     float64 /* Difference between target and market price */
+}
+
+// Return the Thread transactions of the session with their target and difference to the market price, the total
+// difference (minus ExchangeComission used for visual aid in UI) and the quantity offset rounded to the lot size
+// step, negative when the symbol funds are lower than the quantity of the Thread transactions
+func positions(
+	sessionData *types.Session,
+	marketData *types.Market,
+	configData *types.Config) (orders []order, diffTotal float64, quantityOffset float64, err error) {
+
+	/* Target profit follows market volatility in adaptive mode */
+	profit := configData.ProfitMin
+	if adaptive := markets.AdaptiveProfit(configData, marketData); adaptive > 0 {
+
+		profit = adaptive
+
+	}
+
+	transactions, err := sessionData.Storage.GetThreadTransactionByThreadID(context.Background(), sessionData)
+	if err != nil {
+
+		return nil, 0, 0, err
+
+	}
+
+	price := math.Round(marketData.Price*1000) / 1000 /* Market price displayed */
+	quantityOffset = sessionData.SymbolFunds
+
+	for _, key := range transactions {
+
+		tmp := order{}
+		tmp.OrderID = strconv.Itoa(key.OrderID)                                                                                      /* Order ID */
+		tmp.Quantity = key.ExecutedQuantity                                                                                          /* Order Quantity */
+		tmp.Quote = math.Round(key.CumulativeQuoteQuantity*100) / 100                                                                /* Quote price */
+		tmp.Price = math.Round(key.Price*10000) / 10000                                                                              /* Acquisition Price */
+		tmp.Target = math.Round((tmp.Price*(1+profit))*1000) / 1000                                                                  /* Target price */
+		tmp.Diff = math.Round((((key.ExecutedQuantity*price)*(1+configData.ExchangeComission))-key.CumulativeQuoteQuantity)*10) / 10 /* Difference between target and market price */
+
+		orders = append(orders, tmp)
+		quantityOffset -= tmp.Quantity /* Quantity offset */
+
+		diffTotal += (tmp.Diff * (1 - configData.ExchangeComission)) /* Total difference between target and market price */
+
+	}
+
+	diffTotal = math.Round(diffTotal*1) / 1 /* Total difference between target and market price round up */
+
+	/* Round to the lot size step so float residue isn't reported as a Quantity offset */
+	quantityOffset = functions.StrToFloat64(functions.StepToStr(quantityOffset, sessionData.StepSize))
+
+	return orders, diffTotal, quantityOffset, nil
 
 }
 
@@ -171,9 +252,9 @@ func LoadSessionDataAdditionalComponentsAsync(sessionData *types.Session) {
 	}()
 
 	/* Get global data and execute GetProfit if more than 10 seconds since last update.
-	This function is used to prevent multiple threads from running mysql.GetProfit and
+	This function is used to prevent multiple threads from running GetProfit and
 	overloading mySQL server since this is a high cost SQL statement. */
-	if profit, profitnet, profitPct, transactTime, err := mysql.GetGlobal(sessionData); err == nil {
+	if profit, profitnet, profitPct, transactTime, err := sessionData.Storage.GetGlobal(context.Background(), sessionData); err == nil {
 
 		sessionData.Global.Profit = profit       /* Load global profit from db */
 		sessionData.Global.ProfitNet = profitnet /* Load global net profit from db */
@@ -181,7 +262,7 @@ func LoadSessionDataAdditionalComponentsAsync(sessionData *types.Session) {
 
 		if transactTime == 0 { /* If transactTime is 0 then this is the first time this function is called and insert record into db */
 
-			if err := mysql.SaveGlobal(sessionData); err != nil {
+			if err := sessionData.Storage.SaveGlobal(context.Background(), sessionData); err != nil {
 
 				return /* Return if error */
 
@@ -191,13 +272,13 @@ func LoadSessionDataAdditionalComponentsAsync(sessionData *types.Session) {
 
 		if time.Since(time.Unix(transactTime, 0)).Seconds() > 10 { /* Only execute GetProfit if more than 10 seconds since last update */
 
-			if sessionData.Global.Profit, sessionData.Global.ProfitNet, sessionData.Global.ProfitPct, err = mysql.GetProfit(sessionData); err != nil { /* Recalculate total profit and total profit percentage  */
+			if sessionData.Global.Profit, sessionData.Global.ProfitNet, sessionData.Global.ProfitPct, err = sessionData.Storage.GetProfit(context.Background(), sessionData); err != nil { /* Recalculate total profit and total profit percentage  */
 
 				return /* Return if error */
 
 			}
 
-			if err = mysql.UpdateGlobal(sessionData); err != nil { /* Update global data */
+			if err = sessionData.Storage.UpdateGlobal(context.Background(), sessionData); err != nil { /* Update global data */
 
 				return /* Return if error */
 
@@ -208,21 +289,21 @@ func LoadSessionDataAdditionalComponentsAsync(sessionData *types.Session) {
 	}
 
 	/* Load total thread profit and total thread profit percentage  */
-	if sessionData.Global.ProfitThreadID, sessionData.Global.ProfitThreadIDPct, err = mysql.GetProfitByThreadID(sessionData); err != nil {
+	if sessionData.Global.ProfitThreadID, sessionData.Global.ProfitThreadIDPct, err = sessionData.Storage.GetProfitByThreadID(context.Background(), sessionData); err != nil {
 
 		return
 
 	}
 
 	/* Load running thread count */
-	if sessionData.Global.ThreadCount, err = mysql.GetThreadCount(sessionData); err != nil {
+	if sessionData.Global.ThreadCount, err = sessionData.Storage.GetThreadCount(context.Background(), sessionData); err != nil {
 
 		return
 
 	}
 
 	/* Load total thread dollar amount */
-	if sessionData.Global.ThreadAmount, err = mysql.GetThreadAmount(sessionData); err != nil {
+	if sessionData.Global.ThreadAmount, err = sessionData.Storage.GetThreadAmount(context.Background(), sessionData); err != nil {
 
 		return
 
@@ -1,6 +1,7 @@
 package loader
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"regexp"
@@ -8,6 +9,7 @@ import (
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aleibovici/cryptopump/mysql"
 	"github.com/aleibovici/cryptopump/types"
 	"github.com/paulbellamy/ratecounter"
 )
@@ -46,6 +48,7 @@ func TestLoadSessionDataAdditionalComponents(t *testing.T) {
 					ThreadID:    "c683ok5mk1u1120gnmmg",
 					Symbol:      "BTCUSD",
 					Db:          db,
+					Storage:     mysql.Storage{},
 					RateCounter: ratecounter.NewRateCounter(5 * time.Second),
 					Global:      &types.Global{Profit: 0},
 				},
@@ -90,8 +93,9 @@ func TestLoadSessionDataAdditionalComponentsAsync(t *testing.T) {
 			name: "success",
 			args: args{
 				sessionData: &types.Session{
-					Db:     db,
-					Global: &types.Global{},
+					Db:      db,
+					Storage: mysql.Storage{},
+					Global:  &types.Global{},
 				},
 			},
 		},
@@ -108,3 +112,53 @@ func TestLoadSessionDataAdditionalComponentsAsync(t *testing.T) {
 		})
 	}
 }
+
+/* Storage backend with the Thread transactions of a thread */
+type fakeStorage struct {
+	types.Storage
+	orders []types.Order
+}
+
+func (f fakeStorage) GetThreadTransactionByThreadID(ctx context.Context, sessionData *types.Session) ([]types.Order, error) {
+
+	return f.orders, nil
+
+}
+
+func TestLoadPositions(t *testing.T) {
+
+	sessionData := &types.Session{
+		ThreadID:    "c683ok5mk1u1120gnmmg",
+		Symbol:      "BTCUSDT",
+		SymbolFunds: 0.001, /* Less than the Thread transaction */
+		StepSize:    0.00001,
+		RateCounter: ratecounter.NewRateCounter(5 * time.Second),
+		Global:      &types.Global{},
+		Storage:     fakeStorage{orders: []types.Order{{OrderID: 1, Price: 50000, ExecutedQuantity: 0.002, CumulativeQuoteQuantity: 100}}},
+	}
+	marketData := &types.Market{Price: 60000}
+	configData := &types.Config{ProfitMin: 0.01}
+
+	/* The web UI reads a snapshot and leaves the session to the owner goroutine */
+	if _, err := LoadSessionDataAdditionalComponents(sessionData, marketData, configData); err != nil {
+		t.Fatalf("LoadSessionDataAdditionalComponents() error = %v", err)
+	}
+
+	if sessionData.DiffTotal != 0 || sessionData.QuantityOffsetFlag {
+		t.Errorf("LoadSessionDataAdditionalComponents() DiffTotal = %v, QuantityOffsetFlag = %v, want the session unchanged", sessionData.DiffTotal, sessionData.QuantityOffsetFlag)
+	}
+
+	LoadPositions(sessionData, marketData, configData)
+
+	if sessionData.DiffTotal != 20 || !sessionData.QuantityOffsetFlag {
+		t.Errorf("LoadPositions() DiffTotal = %v, QuantityOffsetFlag = %v, want 20, true", sessionData.DiffTotal, sessionData.QuantityOffsetFlag)
+	}
+
+	sessionData.SymbolFunds = 0.002
+	LoadPositions(sessionData, marketData, configData)
+
+	if sessionData.QuantityOffsetFlag {
+		t.Errorf("LoadPositions() QuantityOffsetFlag = true without a quantity offset, want false")
+	}
+
+}
@@ -1,28 +1,47 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"math/rand"
 	"net/http"
 	"os"
 	"os/exec"
-	"sync"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/aleibovici/cryptopump/algorithms"
+	"github.com/aleibovici/cryptopump/analytics"
+	"github.com/aleibovici/cryptopump/api"
+	"github.com/aleibovici/cryptopump/auth"
+	"github.com/aleibovici/cryptopump/cache"
+	"github.com/aleibovici/cryptopump/cli"
+	"github.com/aleibovici/cryptopump/engine"
 	"github.com/aleibovici/cryptopump/exchange"
 	"github.com/aleibovici/cryptopump/functions"
 	"github.com/aleibovici/cryptopump/loader"
 	"github.com/aleibovici/cryptopump/logger"
 	"github.com/aleibovici/cryptopump/markets"
+	"github.com/aleibovici/cryptopump/migrations"
 	"github.com/aleibovici/cryptopump/mysql"
 	"github.com/aleibovici/cryptopump/nodes"
+	"github.com/aleibovici/cryptopump/pause"
 	"github.com/aleibovici/cryptopump/plotter"
+	"github.com/aleibovici/cryptopump/retention"
+	"github.com/aleibovici/cryptopump/retry"
+	"github.com/aleibovici/cryptopump/scheduler"
+	"github.com/aleibovici/cryptopump/sqlite"
+	"github.com/aleibovici/cryptopump/tax"
 	"github.com/aleibovici/cryptopump/telegram"
 	"github.com/aleibovici/cryptopump/threads"
+	"github.com/aleibovici/cryptopump/trades"
 	"github.com/aleibovici/cryptopump/types"
-	"github.com/jtaczanowski/go-scheduler"
 	"github.com/paulbellamy/ratecounter"
 	"github.com/sdcoffey/techan"
 	"github.com/skratchdot/open-golang/open"
@@ -31,23 +50,67 @@ import (
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 )
 
+/* CryptoPump version, set at build time with -ldflags "-X main.version=<version>" */
+var version = "dev"
+
 type myHandler struct {
-	sessionData *types.Session
-	marketData  *types.Market
-	configData  *types.Config
-	viperData   *types.ViperData
+	engine    *engine.Engine /* Owner goroutine of the thread data */
+	viperData *types.ViperData
 }
 
 func main() {
 
+	var options cli.Options /* Options of the run command */
+	var command string
+
+	if len(os.Args) > 1 {
+
+		command = os.Args[1]
+
+	}
+
+	switch {
+	case command == "", command == "migrate", command == "tax", cli.IsCommand(command):
+	case command == "run": /* Command line "run [--config file] [--headless]" starts trading at once */
+
+		var err error
+
+		if options, err = cli.ParseRun(os.Args[2:], os.Stderr); err != nil {
+
+			os.Exit(threads.ExitUsage)
+
+		}
+
+	case command == "help", command == "-h", command == "--help":
+
+		cli.Usage(os.Stdout)
+		os.Exit(threads.ExitOK)
+
+	default:
+
+		cli.Usage(os.Stderr)
+		os.Exit(threads.ExitUsage)
+
+	}
+
 	viperData := &types.ViperData{ /* Viper Configuration */
 		V1: viper.New(), /* Session configurations file */
 		V2: viper.New(), /* Global configurations file */
 	}
 
-	viperData.V1.SetConfigType("yml")      /* Set the type of the configurations file */
-	viperData.V1.AddConfigPath("./config") /* Set the path to look for the configurations file */
-	viperData.V1.SetConfigName("config")   /* Set the file name of the configurations file */
+	viperData.V1.SetConfigType("yml") /* Set the type of the configurations file */
+
+	if options.Config != "" { /* Session configurations file of the run command */
+
+		viperData.V1.SetConfigFile(options.Config)
+
+	} else {
+
+		viperData.V1.AddConfigPath("./config") /* Set the path to look for the configurations file */
+		viperData.V1.SetConfigName("config")   /* Set the file name of the configurations file */
+
+	}
+
 	if err := viperData.V1.ReadInConfig(); err != nil {
 
 		logger.LogEntry{ /* Log Entry */
@@ -59,6 +122,13 @@ func main() {
 			LogLevel: "DebugLevel",
 		}.Do()
 
+		if options.Config != "" {
+
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(threads.ExitStartup)
+
+		}
+
 	}
 	viperData.V1.WatchConfig()
 
@@ -79,6 +149,18 @@ func main() {
 	}
 	viperData.V2.WatchConfig()
 
+	/* Command line user and token commands only change config_global.yml and don't need the database */
+	if command == "user" || command == "token" {
+
+		os.Exit(cli.CLI{
+			ViperData: viperData,
+			Stdin:     os.Stdin,
+			Stdout:    os.Stdout,
+			Stderr:    os.Stderr,
+		}.Run(os.Args[1:]))
+
+	}
+
 	sessionData := &types.Session{
 		ThreadID:                "",
 		ThreadIDSession:         "",
@@ -104,8 +186,6 @@ func main() {
 		Db:                      &sql.DB{},
 		Clients:                 types.Client{},
 		KlineData:               []types.KlineData{},
-		StopWs:                  false,
-		Busy:                    false,
 		MinQuantity:             0,
 		MaxQuantity:             0,
 		StepSize:                0,
@@ -119,8 +199,13 @@ func main() {
 		Global:                  &types.Global{},
 		Admin:                   false,
 		Port:                    "",
+		Version:                 version,
+		ExitCode:                new(int),
 	}
 
+	/* Root context cancelled on SIGINT/SIGTERM, stop from the web UI and fatal errors */
+	sessionData.Ctx, sessionData.Cancel = context.WithCancel(context.Background())
+
 	marketData := &types.Market{
 		Rsi3:                      0,
 		Rsi7:                      0,
@@ -136,91 +221,741 @@ func main() {
 		Ma14:                      0,
 	}
 
-	configData := &types.Config{}
+	configData := &types.Config{}
+	migrator := migrations.Migrator{}
+
+	/* Deadline of every database call (db_timeout in config_global.yml, in seconds) */
+	dbTimeout := time.Duration(viperData.V2.GetInt("config_global.db_timeout")) * time.Second
+
+	var err error
+
+	/* Initialize DB connection for the storage backend selected in config_global.yml */
+	switch strings.ToLower(viperData.V2.GetString("config_global.storage")) {
+	case "sqlite":
+
+		sessionData.Db, err = sqlite.DBInit(viperData.V2.GetString("config_global.storage_path"))
+		sessionData.Storage = cache.New(retry.New(sqlite.Storage{}, dbTimeout))
+		migrator.Backend = "sqlite"
+
+	default:
+
+		sessionData.Db, err = mysql.DBInit()
+		sessionData.Storage = cache.New(retry.New(mysql.Storage{}, dbTimeout))
+		migrator.Backend = "mysql"
+
+	}
+
+	if err != nil { /* Logged by DBInit */
+
+		os.Exit(threads.ExitStartup)
+
+	}
+
+	migrator.Db = sessionData.Db
+
+	/* Command line "migrate status|up|down" runs migrations and exits without starting CryptoPump */
+	if command == "migrate" {
+
+		os.Exit(migrate(migrator, os.Args[2:]))
+
+	}
+
+	/* Apply pending schema migrations. Nodes starting together wait on the migration lock. */
+	if _, err := migrator.Up(); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  nil,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		os.Exit(threads.ExitStartup)
+
+	}
+
+	/* Command line "tax" exports realized gains and exits without starting CryptoPump */
+	if command == "tax" {
+
+		os.Exit(exportTax(functions.GetConfigData(viperData, sessionData), sessionData, os.Args[2:]))
+
+	}
+
+	/* Command line status, sessions, orders, sell, reconcile and export commands exit without starting CryptoPump */
+	if cli.IsCommand(command) {
+
+		os.Exit(cli.CLI{
+			ViperData:   viperData,
+			SessionData: sessionData,
+			Stdin:       os.Stdin,
+			Stdout:      os.Stdout,
+			Stderr:      os.Stderr,
+		}.Run(os.Args[1:]))
+
+	}
+
+	/* Determine port for HTTP service. A headless node keeps a port as its name in the cluster node registry. */
+	sessionData.Port = functions.GetPort()
+
+	if !options.Headless {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   configData,
+			Market:   marketData,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  "Listening on port " + sessionData.Port,
+			LogLevel: "InfoLevel",
+		}.Do()
+
+	}
+
+	/* The owner goroutine handles the thread data from now on, until the root context is cancelled */
+	configData = functions.GetConfigData(viperData, sessionData)
+	e := engine.New(configData, marketData, sessionData)
+	go e.Run(sessionData.Ctx)
+
+	myHandler := &myHandler{
+		engine:    e,
+		viperData: viperData,
+	}
+
+	go standby(myHandler) /* Take over threads of down nodes while no thread is running */
+
+	var server *http.Server
+
+	if !options.Headless {
+
+		/* Users, API tokens and roles of config_global.yml protect the web UI and the JSON API */
+		authData := auth.New(viperData, e)
+
+		if users, tokens := auth.Load(viperData); len(users) == 0 && len(tokens) == 0 {
+
+			logger.LogEntry{ /* Log Entry */
+				Config:   configData,
+				Market:   marketData,
+				Session:  sessionData,
+				Order:    &types.Order{},
+				Message:  "Authentication disabled, only localhost is served until a user is added with cryptopump user add",
+				LogLevel: "InfoLevel",
+			}.Do()
+
+		}
+
+		http.Handle("/", authData.Protect(http.HandlerFunc(myHandler.handler)))
+		http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
+		http.Handle(api.Prefix+"/", authData.Protect(api.Handler{Engine: e, ViperData: viperData})) /* JSON API for scripts and other tools */
+		http.HandleFunc("/login", authData.Login)
+		http.HandleFunc("/logout", authData.Logout)
+
+		server = &http.Server{Addr: fmt.Sprintf(":%s", sessionData.Port)}
+
+		go func() { /* Start HTTP service. */
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				threads.Thread{}.Terminate(e.Session(), threads.ExitStartup, functions.GetFunctionName()+" - "+err.Error())
+			}
+		}()
+
+	}
+
+	/* SIGINT (Ctrl+C) and SIGTERM (docker stop) shut down gracefully, a second signal exits at once */
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-signals
+		signal.Stop(signals)
+		threads.Thread{}.Terminate(e.Session(), threads.ExitOK, "Shutting down on "+sig.String())
+	}()
+
+	if command == "run" {
+
+		go myHandler.start() /* Start trading without waiting for the web UI */
+
+	} else {
+
+		open.Run("http://localhost:" + sessionData.Port) /* Open URI using the OS's default browser */
+
+	}
+
+	<-sessionData.Ctx.Done()
+
+	/* Stop the web UI, requests in progress complete */
+	if server != nil {
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_ = server.Shutdown(ctx)
+		cancel()
+
+	}
+
+	/* The owner goroutine completes the order in flight and stops */
+	os.Exit(threads.Thread{}.Shutdown(sessionData, e.Done()))
+
+}
+
+// Take over a thread of a down node every 30 seconds while no thread is running and failover is enabled
+// (config_global.yml). The setting is read on every run.
+func standby(fh *myHandler) {
+
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+
+		select {
+		case <-fh.engine.Done():
+
+			return
+
+		case <-ticker.C:
+		}
+
+		if fh.engine.Session().ThreadID != "" { /* A thread is running */
+
+			return
+
+		}
+
+		fh.viperData.Mutex.Lock()
+		failover := fh.viperData.V2.GetBool("config_global.failover")
+		fh.viperData.Mutex.Unlock()
+
+		if !failover {
+
+			continue
+
+		}
+
+		started := false
+
+		fh.engine.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+
+			if sessionData.ThreadID != "" { /* Started from the web UI */
+
+				started = true
+
+				return
+
+			}
+
+			if takeover, err := (nodes.Node{}).Takeover(sessionData); err == nil && takeover {
+
+				/* Resume the thread with its own configuration */
+				*configData = *functions.GetConfigData(fh.viperData, sessionData)
+				run(fh.viperData, configData, sessionData, marketData, fh.engine)
+
+				started = true
+
+			}
+
+		})
+
+		if started {
+
+			return
+
+		}
+
+	}
+
+}
+
+// Start the thread on the owner goroutine with the configuration saved from the web UI, or the configuration file
+// of the run command
+func (fh *myHandler) start() {
+
+	fh.engine.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+
+		if sessionData.ThreadID != "" { /* Already running */
+
+			return
+
+		}
+
+		*configData = *functions.GetConfigData(fh.viperData, sessionData)
+		run(fh.viperData, configData, sessionData, marketData, fh.engine)
+
+	})
+
+}
+
+// Execute the index or admin template for the identity of r. The admin page is only displayed to admins, with the
+// secrets masked.
+func (fh *myHandler) page(w http.ResponseWriter, r *http.Request, configData *types.Config, sessionData *types.Session) {
+
+	identity := auth.FromRequest(r)
+
+	configData.CSRF = identity.CSRF
+	if identity.Name != auth.Anonymous {
+
+		configData.User = identity.Name + " (" + identity.Role + ")"
+
+	}
+
+	if identity.Role != auth.RoleAdmin {
+
+		sessionData.Admin = false
+
+	}
+
+	if configData.ConfigGlobal != nil {
+
+		configData.ConfigGlobal = &types.ConfigGlobal{
+			Apikey:           auth.Mask(configData.ConfigGlobal.Apikey),
+			Secretkey:        auth.Mask(configData.ConfigGlobal.Secretkey),
+			ApikeyTestNet:    auth.Mask(configData.ConfigGlobal.ApikeyTestNet),
+			SecretkeyTestNet: auth.Mask(configData.ConfigGlobal.SecretkeyTestNet),
+			TgBotApikey:      auth.Mask(configData.ConfigGlobal.TgBotApikey),
+		}
+
+	}
+
+	functions.ExecuteTemplate(w, configData, sessionData)
+
+}
+
+// Reload the configuration of the owner goroutine
+func (fh *myHandler) reload() {
+
+	fh.engine.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+		*configData = *functions.GetConfigData(fh.viperData, sessionData)
+	})
+
+}
+
+// Enforce at once the pause of the session thread set or lifted on sessionData, a copy of the owner session
+func (fh *myHandler) setPause(sessionData *types.Session) {
+
+	fh.engine.Do(func(configData *types.Config, marketData *types.Market, owner *types.Session) {
+		owner.Pause = sessionData.Pause
+	})
+
+}
+
+/* Run the "migrate status|up|down" command and return the process exit code */
+func migrate(migrator migrations.Migrator, args []string) int {
+
+	if len(args) != 1 {
+
+		fmt.Fprintln(os.Stderr, "usage: cryptopump migrate status|up|down")
+		return threads.ExitUsage
+
+	}
+
+	switch args[0] {
+	case "status":
+
+		status, err := migrator.Status()
+		if err != nil {
+
+			fmt.Fprintln(os.Stderr, err)
+			return threads.ExitError
+
+		}
+
+		for _, migration := range status {
+
+			state := "pending"
+			if migration.AppliedAt != 0 {
+
+				state = "applied " + time.Unix(0, migration.AppliedAt*int64(time.Millisecond)).Format(time.RFC3339)
+
+			}
+
+			fmt.Printf("%04d_%s\t%s\n", migration.Version, migration.Name, state)
+
+		}
+
+	case "up":
+
+		applied, err := migrator.Up()
+
+		for _, migration := range applied {
+
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+
+		}
+
+		if err != nil {
+
+			fmt.Fprintln(os.Stderr, err)
+			return threads.ExitError
+
+		}
+
+		if len(applied) == 0 {
+
+			fmt.Println("schema is up to date")
+
+		}
+
+	case "down":
+
+		reverted, err := migrator.Down()
+		if err != nil {
+
+			fmt.Fprintln(os.Stderr, err)
+			return threads.ExitError
+
+		}
+
+		fmt.Printf("reverted %04d_%s\n", reverted.Version, reverted.Name)
+
+	default:
+
+		fmt.Fprintln(os.Stderr, "usage: cryptopump migrate status|up|down")
+		return threads.ExitUsage
+
+	}
+
+	return threads.ExitOK
+
+}
+
+/* Run the "tax" command writing the realized gains export to stdout or a file and return the process exit code */
+func exportTax(configData *types.Config, sessionData *types.Session, args []string) int {
+
+	var report tax.Report
+	var options tax.Options
+	var err error
+
+	flags := flag.NewFlagSet("tax", flag.ContinueOnError)
+	from := flags.String("from", "", "first disposal date YYYY-MM-DD (default January 1st of the current year)")
+	to := flags.String("to", "", "last disposal date YYYY-MM-DD (default December 31st of the current year)")
+	method := flags.String("method", tax.MethodFIFO, "lot matching method fifo, lifo or specific")
+	format := flags.String("format", tax.FormatCSV, "output format csv or json")
+	output := flags.String("output", "", "output file (default stdout)")
+
+	if err = flags.Parse(args); err != nil {
+
+		return threads.ExitUsage
+
+	}
+
+	if options, err = tax.NewOptions(*from, *to, *method, *format, configData.ExchangeComission); err != nil {
+
+		fmt.Fprintln(os.Stderr, err)
+		return threads.ExitUsage
+
+	}
+
+	if report, err = tax.Export(sessionData, options); err != nil {
+
+		fmt.Fprintln(os.Stderr, err)
+		return threads.ExitError
+
+	}
+
+	w := os.Stdout
+
+	if *output != "" {
+
+		if w, err = os.Create(*output); err != nil {
+
+			fmt.Fprintln(os.Stderr, err)
+			return threads.ExitError
+
+		}
+
+		defer w.Close()
+
+	}
+
+	if err = tax.Write(w, options.Format, report); err != nil {
+
+		fmt.Fprintln(os.Stderr, err)
+		return threads.ExitError
+
+	}
+
+	return threads.ExitOK
+
+}
+
+func (fh *myHandler) handler(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Content-Type", "text/html")                                        /* Set the Content-Type header */
+	w.Header().Set("X-Content-Type-Options", "nosniff")                                /* Add X-Content-Type-Options header */
+	w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains") /* Add Strict-Transport-Security header */
+	w.Header().Add("X-Frame-Options", "DENY")                                          /* Add X-Frame-Options header */
+
+	/* Requests read a copy of the thread data published by the owner goroutine, and change it with commands */
+	snapshot := fh.engine.Snapshot()
+	sessionData := &snapshot.Session
+	marketData := &snapshot.Market
+
+	configData := functions.GetConfigData(fh.viperData, sessionData) /* Get configuration data */
+
+	switch r.Method {
+	case "GET":
+
+		/* Determine the URI path to de taken */
+		switch r.URL.Path {
+		case "/":
+
+			configData.HTMLSnippet = plotter.Data{}.Plot(sessionData) /* Load dynamic components in configData */
+			fh.page(w, r, configData, sessionData)                    /* This is the template execution for 'index' */
+
+		case "/sessiondata":
+
+			var tmp []byte
+			var err error
+
+			w.Header().Set("Content-Type", "application/json") /* Set the Content-Type header */
+
+			if tmp, err = loader.LoadSessionDataAdditionalComponents(sessionData, marketData, configData); err != nil { /* Load dynamic components for javascript autoloader for html output */
+
+				logger.LogEntry{ /* Log Entry */
+					Config:   configData,
+					Market:   marketData,
+					Session:  sessionData,
+					Order:    &types.Order{},
+					Message:  functions.GetFunctionName() + " - " + err.Error(),
+					LogLevel: "DebugLevel",
+				}.Do()
+
+				return
+
+			}
+
+			if _, err := w.Write(tmp); err != nil { /* Write writes the data to the connection as part of an HTTP reply. */
+
+				logger.LogEntry{ /* Log Entry */
+					Config:   configData,
+					Market:   marketData,
+					Session:  sessionData,
+					Order:    &types.Order{},
+					Message:  functions.GetFunctionName() + " - " + err.Error(),
+					LogLevel: "DebugLevel",
+				}.Do()
+
+				return
+
+			}
+
+		case "/trades", "/tradesdata":
+
+			var filter types.TradeFilter
+			var report trades.Report
+			var err error
+
+			/* Parse thread, from, to, reason and limit query parameters */
+			if filter, err = trades.ParseFilter(r.URL.Query()); err != nil {
+
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+
+			}
+
+			if report, err = trades.GetReport(sessionData, filter); err != nil {
+
+				logger.LogEntry{ /* Log Entry */
+					Config:   configData,
+					Market:   marketData,
+					Session:  sessionData,
+					Order:    &types.Order{},
+					Message:  functions.GetFunctionName() + " - " + err.Error(),
+					LogLevel: "DebugLevel",
+				}.Do()
+
+				http.Error(w, "Unable to retrieve trades", http.StatusInternalServerError)
+
+				return
+
+			}
+
+			if r.URL.Path == "/trades" {
+
+				report.Query = r.URL.Query()
+				functions.ExecuteNamedTemplate(w, "trades.html", report) /* This is the template execution for 'trades' */
+
+				return
+
+			}
+
+			w.Header().Set("Content-Type", "application/json") /* Set the Content-Type header */
+
+			if err := json.NewEncoder(w).Encode(report); err != nil { /* Write the trades ledger as JSON */
+
+				logger.LogEntry{ /* Log Entry */
+					Config:   configData,
+					Market:   marketData,
+					Session:  sessionData,
+					Order:    &types.Order{},
+					Message:  functions.GetFunctionName() + " - " + err.Error(),
+					LogLevel: "DebugLevel",
+				}.Do()
+
+			}
+
+		case "/performance", "/performancedata":
+
+			var report analytics.Report
+			var err error
+
+			if report, err = analytics.GetReport(sessionData); err != nil {
+
+				logger.LogEntry{ /* Log Entry */
+					Config:   configData,
+					Market:   marketData,
+					Session:  sessionData,
+					Order:    &types.Order{},
+					Message:  functions.GetFunctionName() + " - " + err.Error(),
+					LogLevel: "DebugLevel",
+				}.Do()
+
+				http.Error(w, "Unable to retrieve performance", http.StatusInternalServerError)
+
+				return
+
+			}
+
+			if r.URL.Path == "/performance" {
+
+				report.Thread.Chart = plotter.Data{}.PlotEquity(report.Thread.Equity, report.Thread.Drawdown) /* Load thread equity chart */
+				report.Global.Chart = plotter.Data{}.PlotEquity(report.Global.Equity, report.Global.Drawdown) /* Load global equity chart */
+				functions.ExecuteNamedTemplate(w, "performance.html", report)                                 /* This is the template execution for 'performance' */
+
+				return
+
+			}
+
+			w.Header().Set("Content-Type", "application/json") /* Set the Content-Type header */
+
+			if err := json.NewEncoder(w).Encode(report); err != nil { /* Write the performance analytics as JSON */
+
+				logger.LogEntry{ /* Log Entry */
+					Config:   configData,
+					Market:   marketData,
+					Session:  sessionData,
+					Order:    &types.Order{},
+					Message:  functions.GetFunctionName() + " - " + err.Error(),
+					LogLevel: "DebugLevel",
+				}.Do()
+
+			}
+
+		case "/cluster", "/clusterdata":
+
+			var cluster nodes.Cluster
+			var err error
+
+			if cluster, err = (nodes.Node{}).GetCluster(sessionData); err != nil {
+
+				logger.LogEntry{ /* Log Entry */
+					Config:   configData,
+					Market:   marketData,
+					Session:  sessionData,
+					Order:    &types.Order{},
+					Message:  functions.GetFunctionName() + " - " + err.Error(),
+					LogLevel: "DebugLevel",
+				}.Do()
+
+				http.Error(w, "Unable to retrieve cluster nodes", http.StatusInternalServerError)
 
-	sessionData.Db = mysql.DBInit() /* Initialize DB connection */
+				return
 
-	myHandler := &myHandler{
-		sessionData: sessionData,
-		marketData:  marketData,
-		configData:  configData,
-		viperData:   viperData,
-	}
+			}
 
-	sessionData.Port = functions.GetPort() /* Determine port for HTTP service. */
+			if r.URL.Path == "/cluster" {
 
-	logger.LogEntry{ /* Log Entry */
-		Config:   configData,
-		Market:   marketData,
-		Session:  sessionData,
-		Order:    &types.Order{},
-		Message:  "Listening on port " + sessionData.Port,
-		LogLevel: "InfoLevel",
-	}.Do()
+				functions.ExecuteNamedTemplate(w, "cluster.html", cluster) /* This is the template execution for 'cluster' */
 
-	http.HandleFunc("/", myHandler.handler)
-	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
+				return
 
-	open.Run("http://localhost:" + sessionData.Port) /* Open URI using the OS's default browser */
+			}
 
-	http.ListenAndServe(fmt.Sprintf(":%s", sessionData.Port), nil) /* Start HTTP service. */
+			w.Header().Set("Content-Type", "application/json") /* Set the Content-Type header */
 
-}
+			if err := json.NewEncoder(w).Encode(cluster); err != nil { /* Write the cluster nodes as JSON */
 
-func (fh *myHandler) handler(w http.ResponseWriter, r *http.Request) {
+				logger.LogEntry{ /* Log Entry */
+					Config:   configData,
+					Market:   marketData,
+					Session:  sessionData,
+					Order:    &types.Order{},
+					Message:  functions.GetFunctionName() + " - " + err.Error(),
+					LogLevel: "DebugLevel",
+				}.Do()
 
-	w.Header().Set("Content-Type", "text/html")                                        /* Set the Content-Type header */
-	w.Header().Set("X-Content-Type-Options", "nosniff")                                /* Add X-Content-Type-Options header */
-	w.Header().Add("Strict-Transport-Security", "max-age=63072000; includeSubDomains") /* Add Strict-Transport-Security header */
-	w.Header().Add("X-Frame-Options", "DENY")                                          /* Add X-Frame-Options header */
+			}
 
-	fh.configData = functions.GetConfigData(fh.viperData, fh.sessionData) /* Get configuration data */
+		case "/pausedata":
 
-	switch r.Method {
-	case "GET":
+			pauseData := sessionData.Pause
+			pauseData.ThreadID = sessionData.ThreadID
 
-		/* Determine the URI path to de taken */
-		switch r.URL.Path {
-		case "/":
+			w.Header().Set("Content-Type", "application/json") /* Set the Content-Type header */
 
-			fh.configData.HTMLSnippet = plotter.Data{}.Plot(fh.sessionData) /* Load dynamic components in configData */
-			functions.ExecuteTemplate(w, fh.configData, fh.sessionData)     /* This is the template execution for 'index' */
+			if err := json.NewEncoder(w).Encode(pauseData); err != nil { /* Write the trading pause as JSON */
 
-		case "/sessiondata":
+				logger.LogEntry{ /* Log Entry */
+					Config:   configData,
+					Market:   marketData,
+					Session:  sessionData,
+					Order:    &types.Order{},
+					Message:  functions.GetFunctionName() + " - " + err.Error(),
+					LogLevel: "DebugLevel",
+				}.Do()
 
-			var tmp []byte
+			}
+
+		case "/tax":
+
+			var options tax.Options
+			var report tax.Report
 			var err error
 
-			w.Header().Set("Content-Type", "application/json") /* Set the Content-Type header */
+			/* Parse from, to, method and format query parameters */
+			if options, err = tax.NewOptions(
+				r.URL.Query().Get("from"),
+				r.URL.Query().Get("to"),
+				r.URL.Query().Get("method"),
+				r.URL.Query().Get("format"),
+				configData.ExchangeComission); err != nil {
+
+				http.Error(w, err.Error(), http.StatusBadRequest)
 
-			if tmp, err = loader.LoadSessionDataAdditionalComponents(fh.sessionData, fh.marketData, fh.configData); err != nil { /* Load dynamic components for javascript autoloader for html output */
+				return
+
+			}
+
+			if report, err = tax.Export(sessionData, options); err != nil {
 
 				logger.LogEntry{ /* Log Entry */
-					Config:   fh.configData,
-					Market:   fh.marketData,
-					Session:  fh.sessionData,
+					Config:   configData,
+					Market:   marketData,
+					Session:  sessionData,
 					Order:    &types.Order{},
 					Message:  functions.GetFunctionName() + " - " + err.Error(),
 					LogLevel: "DebugLevel",
 				}.Do()
 
+				http.Error(w, "Unable to export realized gains", http.StatusInternalServerError)
+
 				return
 
 			}
 
-			if _, err := w.Write(tmp); err != nil { /* Write writes the data to the connection as part of an HTTP reply. */
+			/* Download as cryptopump-tax-<from>-<to>-<method>.<format> */
+			w.Header().Set("Content-Type", map[string]string{tax.FormatCSV: "text/csv", tax.FormatJSON: "application/json"}[options.Format])
+			w.Header().Set("Content-Disposition", "attachment; filename=cryptopump-tax-"+report.From+"-"+report.To+"-"+report.Method+"."+options.Format)
+
+			if err := tax.Write(w, options.Format, report); err != nil {
 
 				logger.LogEntry{ /* Log Entry */
-					Config:   fh.configData,
-					Market:   fh.marketData,
-					Session:  fh.sessionData,
+					Config:   configData,
+					Market:   marketData,
+					Session:  sessionData,
 					Order:    &types.Order{},
 					Message:  functions.GetFunctionName() + " - " + err.Error(),
 					LogLevel: "DebugLevel",
 				}.Do()
 
-				return
-
 			}
 
 		}
@@ -234,9 +969,9 @@ func (fh *myHandler) handler(w http.ResponseWriter, r *http.Request) {
 			if err := r.ParseForm(); err != nil {
 
 				logger.LogEntry{ /* Log Entry */
-					Config:   fh.configData,
+					Config:   configData,
 					Market:   nil,
-					Session:  fh.sessionData,
+					Session:  sessionData,
 					Order:    &types.Order{},
 					Message:  functions.GetFunctionName() + " - " + err.Error(),
 					LogLevel: "DebugLevel",
@@ -251,15 +986,30 @@ func (fh *myHandler) handler(w http.ResponseWriter, r *http.Request) {
 			switch r.PostFormValue("submitselect") {
 			case "adminEnter":
 
-				fh.sessionData.Admin = true                                 /* Set admin flag */
-				functions.ExecuteTemplate(w, fh.configData, fh.sessionData) /* This is the template execution for 'admin' */
+				fh.engine.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+					sessionData.Admin = true /* Set admin flag */
+				})
+
+				sessionData.Admin = true
+				fh.page(w, r, configData, sessionData) /* This is the template execution for 'admin' */
 
 			case "adminExit":
 
-				fh.sessionData.Admin = false                                    /* Unset admin flag */
-				functions.SaveConfigGlobalData(fh.viperData, r, fh.sessionData) /* Save global data */
-				functions.GetConfigData(fh.viperData, fh.sessionData)           /* Get Config Data */
-				functions.ExecuteTemplate(w, fh.configData, fh.sessionData)     /* This is the template execution for 'index' */
+				fh.engine.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+					sessionData.Admin = false /* Unset admin flag */
+				})
+
+				sessionData.Admin = false
+
+				/* Save the secrets changed, the audit log keeps their names */
+				if changed := functions.SaveConfigGlobalData(fh.viperData, r, sessionData); len(changed) > 0 {
+
+					auth.Audit(sessionData, r, "web keys", strings.Join(changed, ", ")+" changed")
+
+				}
+
+				functions.GetConfigData(fh.viperData, sessionData) /* Get Config Data */
+				fh.page(w, r, configData, sessionData)             /* This is the template execution for 'index' */
 
 			case "new":
 
@@ -270,9 +1020,9 @@ func (fh *myHandler) handler(w http.ResponseWriter, r *http.Request) {
 				if path, err = os.Executable(); err != nil { /* Get the path of the executable */
 
 					logger.LogEntry{ /* Log Entry */
-						Config:   fh.configData,
+						Config:   configData,
 						Market:   nil,
-						Session:  fh.sessionData,
+						Session:  sessionData,
 						Order:    &types.Order{},
 						Message:  functions.GetFunctionName() + " - " + err.Error(),
 						LogLevel: "DebugLevel",
@@ -287,9 +1037,9 @@ func (fh *myHandler) handler(w http.ResponseWriter, r *http.Request) {
 				if err = cmd.Start(); err != nil { /* Start the new process */
 
 					logger.LogEntry{ /* Log error */
-						Config:   fh.configData,
+						Config:   configData,
 						Market:   nil,
-						Session:  fh.sessionData,
+						Session:  sessionData,
 						Order:    &types.Order{},
 						Message:  functions.GetFunctionName() + " - " + err.Error(),
 						LogLevel: "DebugLevel",
@@ -297,49 +1047,149 @@ func (fh *myHandler) handler(w http.ResponseWriter, r *http.Request) {
 
 				}
 
-				functions.ExecuteTemplate(w, fh.configData, fh.sessionData) /* This is the template execution for 'index' */
+				fh.page(w, r, configData, sessionData) /* This is the template execution for 'index' */
 
 			case "start":
 
-				go execution(fh.viperData, fh.configData, fh.sessionData, fh.marketData) /* Start the execution process */
-				time.Sleep(2 * time.Second)                                              /* Sleep time to wait for ThreadID to start */
-				http.Redirect(w, r, fmt.Sprintf(r.URL.Path), 301)                        /* Redirect to root 'index' */
+				go fh.start()                                     /* Start the execution process */
+				time.Sleep(2 * time.Second)                       /* Sleep time to wait for ThreadID to start */
+				http.Redirect(w, r, fmt.Sprintf(r.URL.Path), 301) /* Redirect to root 'index' */
 
 			case "stop":
 
-				threads.Thread{}.Terminate(fh.sessionData, "") /* Terminate ThreadID */
+				threads.Thread{}.Terminate(sessionData, threads.ExitOK, "Stopped from the web UI") /* Terminate ThreadID */
+				fmt.Fprint(w, "CryptoPump is shutting down")
 
 			case "update":
 
-				functions.SaveConfigData(fh.viperData, r, fh.sessionData) /* Save the configuration data */
-				http.Redirect(w, r, fmt.Sprintf(r.URL.Path), 301)         /* Redirect to root 'index' */
+				functions.SaveConfigData(fh.viperData, r, sessionData) /* Save the configuration data */
+				fh.reload()                                            /* Apply it to the running thread */
+				http.Redirect(w, r, fmt.Sprintf(r.URL.Path), 301)      /* Redirect to root 'index' */
 
 			case "buy":
 
-				fh.sessionData.ForceBuy = true                    /* Force buy */
+				fh.engine.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+					sessionData.ForceBuy = true /* Force buy */
+				})
+
 				http.Redirect(w, r, fmt.Sprintf(r.URL.Path), 301) /* Redirect to root 'index' */
 
 			case "sell":
 
-				if r.PostFormValue("orderID") == "" { /* Check if the orderID is empty */
+				orderID := 0                          /* Force sell most recent order */
+				if r.PostFormValue("orderID") != "" { /* Check if the orderID is empty */
+
+					orderID = functions.StrToInt(r.PostFormValue("orderID")) /* Force sell a specific orderID */
+
+				}
+
+				fh.engine.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+					sessionData.ForceSellOrderID = orderID
+					sessionData.ForceSell = true /* Force sell */
+				})
+
+				http.Redirect(w, r, fmt.Sprintf(r.URL.Path), 301) /* Redirect to root 'index' */
+
+			case "pause", "freeze":
+
+				mode := map[string]string{"pause": pause.ModeNoBuy, "freeze": pause.ModeFrozen}[r.PostFormValue("submitselect")]
+
+				if _, err := (pause.Control{}).Pause(sessionData, sessionData.ThreadID, mode, "web "+auth.Actor(r), r.PostFormValue("confirmResume") != ""); err != nil {
+
+					http.Error(w, err.Error(), api.PauseStatus(err))
+
+					return
+
+				}
+
+				fh.setPause(sessionData)
 
-					fh.sessionData.ForceSellOrderID = 0 /* Force sell most recent order */
-					fh.sessionData.ForceSell = true     /* Force sell */
+				http.Redirect(w, r, fmt.Sprintf(r.URL.Path), 301) /* Redirect to root 'index' */
+
+			case "resume":
 
-				} else {
+				if _, err := (pause.Control{}).Resume(sessionData, sessionData.ThreadID, "web "+auth.Actor(r), r.PostFormValue("confirm") == "true"); err != nil {
 
-					fh.sessionData.ForceSellOrderID = functions.StrToInt(r.PostFormValue("orderID")) /* Force sell a specific orderID */
-					fh.sessionData.ForceSell = true                                                  /* Force sell */
+					http.Error(w, err.Error(), api.PauseStatus(err))
+
+					return
 
 				}
 
+				fh.setPause(sessionData)
+
 				http.Redirect(w, r, fmt.Sprintf(r.URL.Path), 301) /* Redirect to root 'index' */
 
 			case "configTemplate":
 
-				fh.sessionData.ConfigTemplate = functions.StrToInt(r.PostFormValue("configTemplateList")) /* Retrieve Configuration Template Key selection */
-				configData := functions.LoadConfigTemplate(fh.viperData, fh.sessionData)                  /* Load the configuration data */
-				functions.ExecuteTemplate(w, configData, fh.sessionData)                                  /* This is the template execution for 'index' */
+				sessionData.ConfigTemplate = functions.StrToInt(r.PostFormValue("configTemplateList")) /* Retrieve Configuration Template Key selection */
+				fh.engine.Do(func(configData *types.Config, marketData *types.Market, owner *types.Session) {
+					owner.ConfigTemplate = sessionData.ConfigTemplate
+				})
+
+				configData := functions.LoadConfigTemplate(fh.viperData, sessionData) /* Load the configuration data */
+				fh.page(w, r, configData, sessionData)                                /* This is the template execution for 'index' */
+
+			}
+
+		case "/pause", "/resume":
+
+			var pauseData types.Pause
+			var err error
+
+			/* Pause or resume the thread in the thread parameter, the session thread by default. Mode is nobuy or
+			frozen, confirm=true requires the resume to be confirmed and confirms a resume. */
+			threadID := r.FormValue("thread")
+			if threadID == "" {
+
+				threadID = sessionData.ThreadID
+
+			}
+
+			if threadID == "" {
+
+				http.Error(w, "No thread running", http.StatusBadRequest)
+
+				return
+
+			}
+
+			if r.URL.Path == "/pause" {
+
+				pauseData, err = pause.Control{}.Pause(sessionData, threadID, r.FormValue("mode"), "api "+auth.Actor(r), r.FormValue("confirm") == "true")
+
+			} else {
+
+				pauseData, err = pause.Control{}.Resume(sessionData, threadID, "api "+auth.Actor(r), r.FormValue("confirm") == "true")
+
+			}
+
+			if err != nil {
+
+				http.Error(w, err.Error(), api.PauseStatus(err))
+
+				return
+
+			}
+
+			if threadID == sessionData.ThreadID {
+
+				fh.setPause(sessionData)
+
+			}
+
+			w.Header().Set("Content-Type", "application/json") /* Set the Content-Type header */
+
+			if err := json.NewEncoder(w).Encode(pauseData); err != nil { /* Write the pause set or lifted as JSON */
+
+				logger.LogEntry{ /* Log Entry */
+					Config:   configData,
+					Market:   marketData,
+					Session:  sessionData,
+					Order:    &types.Order{},
+					Message:  functions.GetFunctionName() + " - " + err.Error(),
+					LogLevel: "DebugLevel",
+				}.Do()
 
 			}
 		}
@@ -348,46 +1198,89 @@ func (fh *myHandler) handler(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// Start the thread on the owner goroutine e, or request the shutdown of the process when it fails to start. A
+// thread running on another node or process exits with ExitRunning and is left untouched.
+func run(
+	viperData *types.ViperData,
+	configData *types.Config,
+	sessionData *types.Session,
+	marketData *types.Market,
+	e *engine.Engine) {
+
+	switch err := execution(viperData, configData, sessionData, marketData, e); {
+	case err == nil: /* Started, the owner goroutine handles the thread events */
+	case errors.Is(err, threads.ErrRunning):
+
+		threads.Thread{}.Terminate(sessionData, threads.ExitRunning, "FAILOVER - "+err.Error())
+
+	default:
+
+		threads.Thread{}.Terminate(sessionData, threads.ExitError, err.Error())
+
+	}
+
+}
+
+// Start the thread: resume or initialize the session and start the websocket routines and scheduled functions,
+// which send events to the owner goroutine e. It runs on the owner goroutine.
 func execution(
 	viperData *types.ViperData,
 	configData *types.Config,
 	sessionData *types.Session,
-	marketData *types.Market) {
+	marketData *types.Market,
+	e *engine.Engine) error {
 
 	var err error /* Error handling */
 
 	/* Connect to Exchange */
 	if err = exchange.GetClient(configData, sessionData); err != nil { /* GetClient returns an error if the connection to the exchange is not successful */
 
-		threads.Thread{}.Terminate(sessionData, functions.GetFunctionName()+" - "+err.Error()) /* Terminate ThreadID */
+		return fmt.Errorf("%s - %w", functions.GetFunctionName(), err)
 
 	}
 
-	/* Routine to resume operations */
+	/* Routine to resume operations. A thread taken over from a down node is already selected. */
 	var threadIDSessionDB string
 
-	if sessionData.ThreadID, threadIDSessionDB, err = mysql.GetThreadTransactionDistinct(sessionData); err != nil { /* GetThreadTransactionDistinct returns an error if the connection to the database is not successful */
+	takeover := sessionData.ThreadID != ""
 
-		threads.Thread{}.Terminate(sessionData, functions.GetFunctionName()+" - "+err.Error()) /* Terminate ThreadID */
+	if takeover {
+
+		threadIDSessionDB = sessionData.ThreadIDSession
+
+	} else if sessionData.ThreadID, threadIDSessionDB, err = sessionData.Storage.GetThreadTransactionDistinct(context.Background(), sessionData); err != nil { /* GetThreadTransactionDistinct returns an error if the connection to the database is not successful */
+
+		return fmt.Errorf("%s - %w", functions.GetFunctionName(), err)
 
 	}
 
-	if sessionData.ThreadID != "" && !configData.NewSession { /* If ThreadID is not empty and NewSession is false */
+	if sessionData.ThreadID != "" && (takeover || !configData.NewSession) { /* If ThreadID is not empty and NewSession is false */
+
+		/* Take the thread lease and lock the thread file, replacing the lock file of a down node taken over */
+		if err = (threads.Thread{}).Acquire(sessionData, takeover); errors.Is(err, threads.ErrRunning) {
 
-		threads.Thread{}.Lock(sessionData) /* Lock thread file */
+			return err
 
-		configData = functions.GetConfigData(viperData, sessionData) /* Get Config Data */
+		} else if err != nil {
 
-		if sessionData.Symbol, err = mysql.GetOrderSymbol(sessionData); err != nil { /* GetOrderSymbol returns an error if the connection to the database is not successful */
+			sessionData.ThreadID = "" /* The thread may run on another node, Shutdown leaves it untouched */
 
-			threads.Thread{}.Terminate(sessionData, functions.GetFunctionName()+" - "+err.Error()) /* Terminate ThreadID */
+			return fmt.Errorf("%s - %w", functions.GetFunctionName(), err)
+
+		}
+
+		*configData = *functions.GetConfigData(viperData, sessionData) /* Get Config Data */
+
+		if sessionData.Symbol, err = sessionData.Storage.GetOrderSymbol(context.Background(), sessionData); err != nil { /* GetOrderSymbol returns an error if the connection to the database is not successful */
+
+			return fmt.Errorf("%s - %w", functions.GetFunctionName(), err)
 
 		}
 
 		/* Select the symbol coin to be used from sessionData.Symbol */
 		if sessionData.SymbolFiat, err = algorithms.ParseSymbolFiat(sessionData); err != nil { /* ParseSymbolFiat returns an error if the symbol is not valid */
 
-			threads.Thread{}.Terminate(sessionData, functions.GetFunctionName()+" - "+err.Error()) /* Terminate ThreadID */
+			return fmt.Errorf("%s - %w", functions.GetFunctionName(), err)
 
 		}
 
@@ -400,13 +1293,15 @@ func execution(
 			LogLevel: "InfoLevel",
 		}.Do()
 
+		algorithms.RecoverOrders(configData, sessionData) /* Repair order sequences left half-written by a crash */
+
 	} else { /* If ThreadID is empty or NewSession is true */
 
 		sessionData.ThreadID = functions.GetThreadID() /* Get ThreadID */
 
 		if !(threads.Thread{}.Lock(sessionData)) { /* Lock thread file */
 
-			os.Exit(1)
+			return fmt.Errorf("thread %s: %w", sessionData.ThreadID, threads.ErrRunning)
 
 		}
 
@@ -425,7 +1320,9 @@ func execution(
 
 	}
 
-	asyncFunctions(viperData, configData, sessionData) /* Starts async functions that are executed at specific intervals */
+	sessionData.Started = time.Now() /* Start time saved in the cluster node registry */
+
+	asyncFunctions(viperData, configData, sessionData, e) /* Starts async functions that are executed at specific intervals */
 
 	/* Retrieve available fiat funds and update database
 	This is only used for retrieving balances for the first time, and is then followed by
@@ -433,7 +1330,8 @@ func execution(
 	if sessionData.SymbolFiatFunds, err = exchange.GetSymbolFiatFunds( /* GetSymbolFiatFunds returns an error if the connection to the exchange is not successful */
 		configData,
 		sessionData); err == nil { /* If the connection to the exchange is successful */
-		_ = mysql.UpdateSession( /* Update database with available fiat funds */
+		_ = sessionData.Storage.UpdateSession( /* Update database with available fiat funds */
+			context.Background(),
 			configData,
 			sessionData)
 	}
@@ -441,245 +1339,275 @@ func execution(
 	/* Retrieve available symbol funds
 	This is only used for retrieving balances for the first time, ans is then followed by
 	the Websocket routine to retrieve realtime user data  */
-	sessionData.SymbolFunds, err = exchange.GetSymbolFunds(configData, sessionData)
+	if sessionData.SymbolFunds, err = exchange.GetSymbolFunds(configData, sessionData); errors.Is(err, exchange.ErrBalanceNotFound) {
 
-	/* Retrieve exchange lot size for ticker and store in sessionData */
-	exchange.GetLotSize(configData, sessionData)
+		return fmt.Errorf("%s - %w", functions.GetFunctionName(), err)
 
-	sum := 0
-	for {
+	}
 
-		/* Check start/stop times of operation */
-		if configData.TimeEnforce { /* If TimeEnforce is true */
+	/* Retrieve exchange lot size for ticker and store in sessionData */
+	exchange.GetLotSize(configData, sessionData)
 
-			for !functions.IsInTimeRange(configData.TimeStart, configData.TimeStop) { /* If current time is not in the time range */
+	/* Update ThreadCount */
+	sessionData.ThreadCount, _ = sessionData.Storage.GetThreadTransactionCount(context.Background(), sessionData)
 
-				logger.LogEntry{ /* Log Entry */
-					Config:   configData,
-					Market:   marketData,
-					Session:  sessionData,
-					Order:    &types.Order{},
-					Message:  "Sleeping",
-					LogLevel: "InfoLevel",
-				}.Do()
+	/* Update Number of Sale Transactions per hour */
+	sessionData.SellTransactionCount, _ = sessionData.Storage.GetOrderTransactionCount(context.Background(), sessionData, "SELL")
 
-				time.Sleep(300000 * time.Millisecond) /* Sleep for 5 minutes */
+	/* This routine is executed when no transaction cycle has initiated (ThreadCount = 0) */
+	if sessionData.ThreadCount == 0 { /* If ThreadCount is 0 */
 
-			}
+		sessionData.ThreadIDSession = functions.GetThreadID() /* Get ThreadID */
 
-		}
+	} else if threadIDSessionDB != "" { /* Retrieve existing Thread ID Session */
 
-		/* Update ThreadCount */
-		sessionData.ThreadCount, err = mysql.GetThreadTransactionCount(sessionData)
+		sessionData.ThreadIDSession = threadIDSessionDB
 
-		/* Update Number of Sale Transactions per hour */
-		sessionData.SellTransactionCount, err = mysql.GetOrderTransactionCount(sessionData, "SELL")
+	}
 
-		/* This routine is executed when no transaction cycle has initiated (ThreadCount = 0) */
-		if sessionData.ThreadCount == 0 { /* If ThreadCount is 0 */
+	/* Save new session to Session table. */
+	if sessionData.ThreadCount == 0 || threadIDSessionDB != "" {
 
-			sessionData.ThreadIDSession = functions.GetThreadID() /* Get ThreadID */
+		if err := sessionData.Storage.SaveSession(
+			context.Background(),
+			configData,
+			sessionData); err != nil {
 
-			/* Save new session to Session table. */
-			if err := mysql.SaveSession(
+			/* Update existing session on Session table. CheckStatus updates it again every 10 seconds. */
+			_ = sessionData.Storage.UpdateSession(
+				context.Background(),
 				configData,
-				sessionData); err != nil {
-
-				/* Update existing session on Session table */
-				if err := mysql.UpdateSession(
-					configData,
-					sessionData); err != nil {
-
-					threads.Thread{}.Terminate(sessionData, functions.GetFunctionName()+" - "+err.Error()) /* Terminate ThreadID */
-
-				}
-
-			}
-
-		} else {
-
-			/* Retrieve existing Thread ID Session if first time */
-			if threadIDSessionDB != "" {
-
-				sessionData.ThreadIDSession = threadIDSessionDB
-				threadIDSessionDB = ""
-
-				/* Save new session to Session table then update if fail */
-				if err := mysql.SaveSession(
-					configData,
-					sessionData); err != nil {
-
-					/* Update existing session on Session table */
-					if err := mysql.UpdateSession(
-						configData,
-						sessionData); err != nil {
-
-						/* Cleanly exit ThreadID */
-						threads.Thread{}.Terminate(sessionData, functions.GetFunctionName()+" - "+err.Error())
-
-					}
-
-				}
-
-			}
-
-		}
-
-		/* Conditional used in case this is the first run in the cycle go get past market data */
-		if marketData.PriceChangeStatsHighPrice == 0 { /* If PriceChangeStatsHighPrice is 0 */
-
-			markets.Data{}.LoadKlinePast(configData, marketData, sessionData) /* Load Kline Past */
+				sessionData)
 
 		}
 
-		wg := &sync.WaitGroup{} /* WaitGroup to stop inside Channels */
-		wg.Add(3)               /* WaitGroup to stop inside Channels */
-
-		go telegram.CheckUpdates( /* Check for Telegram updates */
-			configData,
-			sessionData,
-			wg)
-
-		go algorithms.WsKline( /* Websocket routine to retrieve realtime candle data */
-			configData,
-			marketData,
-			sessionData,
-			wg)
+	}
 
-		go algorithms.WsUserDataServe( /* Websocket routine to retrieve realtime user data */
-			configData,
-			sessionData,
-			wg)
+	/* Conditional used in case this is the first run in the cycle go get past market data */
+	if marketData.PriceChangeStatsHighPrice == 0 { /* If PriceChangeStatsHighPrice is 0 */
 
-		go algorithms.WsBookTicker( /* Websocket routine to retrieve realtime ticker prices */
-			viperData,
-			configData,
-			marketData,
-			sessionData,
-			wg)
+		markets.Data{}.LoadKlinePast(configData, marketData, sessionData) /* Load Kline Past */
 
-		wg.Wait() /* Wait for the goroutines to finish */
+	}
 
-		logger.LogEntry{ /* Log Entry */
-			Config:   configData,
-			Market:   nil,
-			Session:  sessionData,
-			Order:    &types.Order{},
-			Message:  "All websocket channels stopped",
-			LogLevel: "DebugLevel",
-		}.Do()
+	/* Websocket and Telegram goroutines read copies of the configuration and session data, and send events and
+	commands to the owner goroutine */
+	wsConfig, wsSession := *configData, *sessionData
+	userSession := wsSession /* WsUserDataServe sets the listen key of its copy */
 
-		sessionData.StopWs = false /* Reset goroutine channels */
+	go telegram.CheckUpdates(&wsConfig, e) /* Check for Telegram updates */
 
-		/* Reload configuration in case of WsBookTicker broken connection */
-		configData = functions.GetConfigData(viperData, sessionData) /* Get Config Data */
+	go algorithms.WsKline(&wsConfig, &wsSession, e) /* Websocket routine to retrieve realtime candle data */
 
-		time.Sleep(3000 * time.Millisecond) /* Sleep for 3 seconds */
+	go algorithms.WsPartialDepth(&wsConfig, &wsSession, e) /* Websocket routine to retrieve realtime order book depth */
 
-		logger.LogEntry{ /* Log Entry */
-			Config:   configData,
-			Market:   nil,
-			Session:  sessionData,
-			Order:    &types.Order{},
-			Message:  "Restarting",
-			LogLevel: "DebugLevel",
-		}.Do()
+	go algorithms.WsUserDataServe(&wsConfig, &userSession, e) /* Websocket routine to retrieve realtime user data */
 
-		/* repeated forever */
-		sum++
+	go algorithms.WsBookTicker(&wsConfig, &wsSession, e) /* Websocket routine to retrieve realtime ticker prices */
 
-	}
+	return nil
 
 }
 
-// asyncFunctions starts async functions that are executed at specific intervals
+// asyncFunctions starts async functions that are executed at specific intervals. It runs on the owner goroutine
+// e, scheduled functions change the thread data with commands and read copies for slow database and network
+// calls.
 func asyncFunctions(
 	viperData *types.ViperData,
 	configData *types.Config,
-	sessionData *types.Session) {
+	sessionData *types.Session,
+	e *engine.Engine) {
+
+	ctx := sessionData.Ctx /* Scheduled functions stop on shutdown */
 
 	/* Synchronize time with Binance every 5 minutes */
 	_ = exchange.NewSetServerTimeService(configData, sessionData)
 	scheduler.RunTaskAtInterval(
-		func() { _ = exchange.NewSetServerTimeService(configData, sessionData) },
+		ctx,
+		func() {
+			e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+				_ = exchange.NewSetServerTimeService(configData, sessionData)
+			})
+		},
 		time.Second*300,
 		time.Second*0)
 
 	/* Retrieve config data every 10 seconds. */
 	scheduler.RunTaskAtInterval(
-		func() { configData = functions.GetConfigData(viperData, sessionData) },
+		ctx,
+		func() {
+			e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+				*configData = *functions.GetConfigData(viperData, sessionData)
+			})
+		},
 		time.Second*10,
 		time.Second*0)
 
 	/* run function UpdatePendingOrders() every 180 seconds */
 	rand.Seed(time.Now().UnixNano())
 	scheduler.RunTaskAtInterval(
-		func() { algorithms.UpdatePendingOrders(configData, sessionData) },
+		ctx,
+		func() {
+			e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+				algorithms.UpdatePendingOrders(configData, sessionData)
+			})
+		},
 		time.Second*180,
 		time.Second*time.Duration(rand.Intn(180-1+1)+1),
 	)
 
-	/* Retrieve initial node role and then every 60 seconds */
+	/* Retrieve initial node role and then renew the Master Node lease every 10 seconds */
 	nodes.Node{}.GetRole(configData, sessionData)
 	scheduler.RunTaskAtInterval(
+		ctx,
 		func() {
-			nodes.Node{}.GetRole(configData, sessionData)
+			e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+				nodes.Node{}.GetRole(configData, sessionData)
+			})
 		},
-		time.Second*60,
-		time.Second*0)
+		time.Second*10,
+		time.Second*10)
 
 	/* Keep user stream service alive every 60 seconds */
 	scheduler.RunTaskAtInterval(
-		func() { _ = exchange.KeepAliveUserStreamServiceListenKey(configData, sessionData) },
+		ctx,
+		func() {
+			snapshot := e.Snapshot()
+			_ = exchange.KeepAliveUserStreamServiceListenKey(&snapshot.Config, &snapshot.Session)
+		},
 		time.Second*60,
 		time.Second*0)
 
 	/* Update Number of Sale Transactions per hour every 3 minutes.
 	The same function is executed after each sale, and when initiating cycle. */
 	scheduler.RunTaskAtInterval(
+		ctx,
 		func() {
-			sessionData.SellTransactionCount, _ = mysql.GetOrderTransactionCount(sessionData, "SELL")
+			e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+				sessionData.SellTransactionCount, _ = sessionData.Storage.GetOrderTransactionCount(context.Background(), sessionData, "SELL")
+			})
 		},
 		time.Second*180,
 		time.Second*0)
 
 	/* Update exchange latency every 5 seconds. */
 	scheduler.RunTaskAtInterval(
+		ctx,
 		func() {
-			sessionData.Latency, _ = functions.GetExchangeLatency(sessionData)
+			latency, _ := functions.GetExchangeLatency(e.Session())
+			e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+				sessionData.Latency = latency
+			})
 		},
 		time.Second*5,
 		time.Second*0)
 
-	/* Check system status every 10 seconds. */
+	/* Retrieve initial symbol trading status and then every 60 seconds */
+	exchange.GetSymbolStatus(configData, sessionData)
+	scheduler.RunTaskAtInterval(
+		ctx,
+		func() {
+			e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+				exchange.GetSymbolStatus(configData, sessionData)
+			})
+		},
+		time.Second*60,
+		time.Second*0)
+
+	/* Load the trading pause saved before the restart or on another node and then every 10 seconds */
+	pause.Control{}.Load(sessionData)
+	scheduler.RunTaskAtInterval(
+		ctx,
+		func() {
+			e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+				pause.Control{}.Load(sessionData)
+			})
+		},
+		time.Second*10,
+		time.Second*10)
+
+	/* Check system status every 10 seconds. A thread taken over by another node exits. */
 	scheduler.RunTaskAtInterval(
+		ctx,
 		func() {
-			nodes.Node{}.CheckStatus(configData, sessionData)
+			e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+				if err := (nodes.Node{}).CheckStatus(configData, sessionData); err == nodes.ErrTakenOver {
+					threads.Thread{}.Terminate(sessionData, threads.ExitRunning, "FAILOVER - "+err.Error())
+				}
+			})
 		},
 		time.Second*10,
 		time.Second*0)
 
-	/* Send Telegram message with system error (only Master Node) every 60 seconds. */
+	/* Send Telegram message with system error and thread takeovers (only Master Node) every 60 seconds. */
+	audited := time.Now().UnixNano() / int64(time.Millisecond)
 	scheduler.RunTaskAtInterval(
+		ctx,
 		func() {
-			if sessionData.MasterNode && sessionData.TgBotAPIChatID != 0 {
-				if threadID, err := mysql.GetSessionStatus(sessionData); err == nil {
+			sessionData := e.Session()
+			if (nodes.Node{}).IsMaster(sessionData) && sessionData.TgBotAPIChatID != 0 {
+				if threadID, err := sessionData.Storage.GetSessionStatus(context.Background(), sessionData); err == nil {
 					if threadID != "" {
 						telegram.Message{
 							Text: "\f" + "System Fault @ " + threadID,
 						}.Send(sessionData)
 					}
 				}
+				if audit, err := sessionData.Storage.GetAudit(context.Background(), sessionData, audited); err == nil {
+					for _, record := range audit {
+						if record.Action == nodes.ActionTakeover {
+							telegram.Message{
+								Text: "\f" + "Failover @ " + record.ThreadID + " taken over by " + record.Node + " " + record.Detail,
+							}.Send(sessionData)
+						}
+						audited = record.Time
+					}
+				}
 			}
 		}, time.Second*60,
 		time.Second*0)
 
-	/* Load mySQL dynamic components for javascript autoloader every 10 seconds. */
+	/* Load mySQL dynamic components for javascript autoloader every 10 seconds. The global profit is read on a
+	copy of the session and set by the owner goroutine, which also updates the difference to target saved in the
+	session table and the quantity offset flag. */
 	scheduler.RunTaskAtInterval(
+		ctx,
 		func() {
-			loader.LoadSessionDataAdditionalComponentsAsync(sessionData)
+			sessionCopy := e.Session()
+			global := *sessionCopy.Global
+			sessionCopy.Global = &global
+			loader.LoadSessionDataAdditionalComponentsAsync(sessionCopy)
+			e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+				*sessionData.Global = global
+				loader.LoadPositions(sessionData, marketData, configData)
+			})
 		},
 		time.Second*10,
 		time.Second*0)
 
+	/* Archive closed orders older than retention_days (config_global.yml) every hour (only Master Node).
+	The setting is read on every run, 0 keeps all orders. */
+	scheduler.RunTaskAtInterval(
+		ctx,
+		func() {
+			if sessionData := e.Session(); (nodes.Node{}).IsMaster(sessionData) {
+				viperData.Mutex.Lock()
+				days := viperData.V2.GetInt("config_global.retention_days")
+				viperData.Mutex.Unlock()
+				_, _ = retention.Archive(sessionData, days)
+			}
+		},
+		time.Second*3600,
+		time.Second*120)
+
+	/* Save daily equity snapshots every 10 minutes, after the first profit refresh by the autoloader.
+	The last snapshot of the day is the daily close. */
+	scheduler.RunTaskAtInterval(
+		ctx,
+		func() {
+			_ = analytics.Snapshot(e.Session())
+		},
+		time.Second*600,
+		time.Second*60)
+
 }
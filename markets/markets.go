@@ -17,10 +17,12 @@ import (
 // Data struct host temporal market data
 type Data struct {
 	Kline types.WsKline
+	Book  types.OrderBook
 }
 
 /* Technical analysis Calculations */
 func calculate(
+	configData *types.Config,
 	closePrices techan.Indicator,
 	priceChangeStats []*types.PriceChangeStats,
 	sessionData *types.Session,
@@ -32,6 +34,7 @@ func calculate(
 	marketData.MACD = calculateMACD(closePrices, marketData.Series, 12, 26)
 	marketData.Ma7 = calculateMA(closePrices, marketData.Series, 7)
 	marketData.Ma14 = calculateMA(closePrices, marketData.Series, 14)
+	marketData.Volatility = calculateVolatility(closePrices, marketData.Series, configData.AdaptivePeriod)
 	if priceChangeStats != nil {
 		marketData.PriceChangeStatsHighPrice = calculatePriceChangeStatsHighPrice(priceChangeStats)
 		marketData.PriceChangeStatsLowPrice = calculatePriceChangeStatsLowPrice(priceChangeStats)
@@ -92,6 +95,7 @@ func (d Data) LoadKline(
 	}
 
 	calculate(
+		configData,
 		techan.NewClosePriceIndicator(marketData.Series),
 		priceChangeStats,
 		sessionData,
@@ -161,6 +165,7 @@ func (d Data) LoadKlinePast(
 	}
 
 	calculate(
+		configData,
 		techan.NewClosePriceIndicator(marketData.Series),
 		priceChangeStats,
 		sessionData,
@@ -194,6 +199,252 @@ func calculateMA(
 	return techan.NewSimpleMovingAverage(closePrices, window).Calculate(series.LastIndex() - 1).Float()
 }
 
+/* Calculate Average True Range for window periods as a ratio of the close price.
+Returns 0 while the series has fewer than window candles. */
+func calculateVolatility(
+	closePrices techan.Indicator,
+	series *techan.TimeSeries,
+	window int) float64 {
+
+	if window <= 0 {
+
+		window = 14 /* Default to 14 periods when adaptive_period is not set */
+
+	}
+
+	if series.LastIndex() < 1 {
+
+		return 0
+
+	}
+
+	closePrice := closePrices.Calculate(series.LastIndex() - 1).Float()
+	if closePrice == 0 {
+
+		return 0
+
+	}
+
+	return techan.NewAverageTrueRangeIndicator(series, window).Calculate(series.LastIndex()-1).Float() / closePrice
+}
+
+// AdaptiveProfit return the profit target ratio following market volatility (adaptive_profit_multiplier
+// times volatility) within adaptive_profit_min and adaptive_profit_max. Returns 0 when adaptive mode
+// is disabled or volatility is not available yet.
+func AdaptiveProfit(
+	configData *types.Config,
+	marketData *types.Market) float64 {
+
+	if !configData.Adaptive || marketData.Volatility <= 0 {
+
+		return 0
+
+	}
+
+	return clamp(
+		marketData.Volatility*configData.AdaptiveProfitMultiplier,
+		configData.AdaptiveProfitMin,
+		configData.AdaptiveProfitMax)
+}
+
+// AdaptiveBuyRepeatThresholdDown return the buy repeat threshold down ratio following market volatility
+// (adaptive_threshold_down_multiplier times volatility) within adaptive_threshold_down_min and
+// adaptive_threshold_down_max. Returns 0 when adaptive mode is disabled or volatility is not available yet.
+func AdaptiveBuyRepeatThresholdDown(
+	configData *types.Config,
+	marketData *types.Market) float64 {
+
+	if !configData.Adaptive || marketData.Volatility <= 0 {
+
+		return 0
+
+	}
+
+	return clamp(
+		marketData.Volatility*configData.AdaptiveThresholdDownMultiplier,
+		configData.AdaptiveThresholdDownMin,
+		configData.AdaptiveThresholdDownMax)
+}
+
+/* Limit value to floor and ceiling. A ceiling of 0 means no ceiling. */
+func clamp(value float64, floor float64, ceiling float64) float64 {
+
+	if value < floor {
+
+		return floor
+
+	}
+
+	if ceiling > 0 && value > ceiling {
+
+		return ceiling
+
+	}
+
+	return value
+}
+
+// LoadBook store an order book snapshot from the partial depth stream and calculate the book imbalance
+func (d Data) LoadBook(
+	marketData *types.Market) {
+
+	marketData.Book = d.Book
+	marketData.BookImbalance = calculateImbalance(d.Book)
+
+}
+
+/* Calculate the bid/ask quantity imbalance for the levels in the book, from -1 (all asks)
+to 1 (all bids). Returns 0 for an empty book. */
+func calculateImbalance(
+	book types.OrderBook) float64 {
+
+	var bids, asks float64
+
+	for _, level := range book.Bids {
+
+		bids += level.Quantity
+
+	}
+
+	for _, level := range book.Asks {
+
+		asks += level.Quantity
+
+	}
+
+	if bids+asks == 0 {
+
+		return 0
+
+	}
+
+	return (bids - asks) / (bids + asks)
+}
+
+// BuySlippage estimate the slippage in basis points of a market BUY of quoteQuantity (fiat) walking
+// the book asks. Returns false when the book does not have enough depth to fill the order.
+func BuySlippage(
+	book types.OrderBook,
+	quoteQuantity float64) (bps float64, filled bool) {
+
+	var spent, bought float64
+
+	if len(book.Asks) == 0 || quoteQuantity <= 0 {
+
+		return 0, false
+
+	}
+
+	for _, level := range book.Asks {
+
+		remaining := quoteQuantity - spent
+
+		if level.Price*level.Quantity >= remaining {
+
+			spent += remaining
+			bought += remaining / level.Price
+			filled = true
+
+			break
+
+		}
+
+		spent += level.Price * level.Quantity
+		bought += level.Quantity
+
+	}
+
+	bps = ((spent/bought)/book.Asks[0].Price - 1) * 10000
+
+	return bps, filled
+}
+
+// MaxBuyQuantity return the largest market BUY in quote quantity (fiat) with an expected slippage
+// of at most maxBps basis points. The result is limited to the depth available in the book.
+func MaxBuyQuantity(
+	book types.OrderBook,
+	maxBps float64) float64 {
+
+	var spent, bought float64
+
+	if len(book.Asks) == 0 {
+
+		return 0
+
+	}
+
+	limit := book.Asks[0].Price * (1 + maxBps/10000) /* Highest average price allowed */
+
+	for _, level := range book.Asks {
+
+		if (spent+level.Price*level.Quantity)/(bought+level.Quantity) > limit {
+
+			/* Take part of the level so the average price equals the limit:
+			(spent + price*x) / (bought + x) = limit */
+			return spent + level.Price*(limit*bought-spent)/(level.Price-limit)
+
+		}
+
+		spent += level.Price * level.Quantity
+		bought += level.Quantity
+
+	}
+
+	return spent
+}
+
+// SplitBuy return the quote quantities (fiat) of up to maxOrders market BUY sent back to back to buy
+// quoteQuantity, each with an expected slippage of at most maxBps basis points from the best ask left by the
+// previous orders. The book is walked once and the result is limited to the depth available in the book.
+func SplitBuy(
+	book types.OrderBook,
+	quoteQuantity float64,
+	maxBps float64,
+	maxOrders int) (quantities []float64) {
+
+	asks := append([]types.BookLevel(nil), book.Asks...) /* Levels left by the previous orders */
+	remaining := quoteQuantity
+
+	for len(quantities) < maxOrders && remaining > 0 && len(asks) > 0 {
+
+		quantity := MaxBuyQuantity(types.OrderBook{Asks: asks}, maxBps)
+		if quantity > remaining {
+
+			quantity = remaining
+
+		}
+
+		if quantity <= 0 {
+
+			break
+
+		}
+
+		quantities = append(quantities, quantity)
+		remaining -= quantity
+
+		/* Take the quantity bought from the book */
+		for len(asks) > 0 && quantity > 0 {
+
+			level := asks[0].Price * asks[0].Quantity
+			if level > quantity {
+
+				asks[0].Quantity -= quantity / asks[0].Price
+
+				break
+
+			}
+
+			quantity -= level
+			asks = asks[1:]
+
+		}
+
+	}
+
+	return quantities
+}
+
 /* Calculate High price for 1 period */
 func calculatePriceChangeStatsHighPrice(
 	priceChangeStats []*types.PriceChangeStats) float64 {
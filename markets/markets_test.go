@@ -1,6 +1,7 @@
 package markets
 
 import (
+	"math"
 	"testing"
 
 	"github.com/aleibovici/cryptopump/exchange"
@@ -124,3 +125,306 @@ func TestData_LoadKline(t *testing.T) {
 		})
 	}
 }
+
+func TestAdaptiveProfit(t *testing.T) {
+	type args struct {
+		configData *types.Config
+		marketData *types.Market
+	}
+	tests := []struct {
+		name string
+		args args
+		want float64
+	}{
+		{
+			name: "disabled",
+			args: args{
+				configData: &types.Config{Adaptive: false, AdaptiveProfitMultiplier: 3, AdaptiveProfitMin: 0.001, AdaptiveProfitMax: 0.01},
+				marketData: &types.Market{Volatility: 0.002},
+			},
+			want: 0,
+		},
+		{
+			name: "volatility not available",
+			args: args{
+				configData: &types.Config{Adaptive: true, AdaptiveProfitMultiplier: 3, AdaptiveProfitMin: 0.001, AdaptiveProfitMax: 0.01},
+				marketData: &types.Market{Volatility: 0},
+			},
+			want: 0,
+		},
+		{
+			name: "floor",
+			args: args{
+				configData: &types.Config{Adaptive: true, AdaptiveProfitMultiplier: 3, AdaptiveProfitMin: 0.001, AdaptiveProfitMax: 0.01},
+				marketData: &types.Market{Volatility: 0.0001},
+			},
+			want: 0.001,
+		},
+		{
+			name: "ceiling",
+			args: args{
+				configData: &types.Config{Adaptive: true, AdaptiveProfitMultiplier: 3, AdaptiveProfitMin: 0.001, AdaptiveProfitMax: 0.01},
+				marketData: &types.Market{Volatility: 0.005},
+			},
+			want: 0.01,
+		},
+		{
+			name: "no ceiling",
+			args: args{
+				configData: &types.Config{Adaptive: true, AdaptiveProfitMultiplier: 3, AdaptiveProfitMin: 0.001, AdaptiveProfitMax: 0},
+				marketData: &types.Market{Volatility: 0.005},
+			},
+			want: 0.015,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AdaptiveProfit(tt.args.configData, tt.args.marketData); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("AdaptiveProfit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdaptiveBuyRepeatThresholdDown(t *testing.T) {
+	type args struct {
+		configData *types.Config
+		marketData *types.Market
+	}
+	tests := []struct {
+		name string
+		args args
+		want float64
+	}{
+		{
+			name: "disabled",
+			args: args{
+				configData: &types.Config{Adaptive: false, AdaptiveThresholdDownMultiplier: 6, AdaptiveThresholdDownMin: 0.002, AdaptiveThresholdDownMax: 0.02},
+				marketData: &types.Market{Volatility: 0.001},
+			},
+			want: 0,
+		},
+		{
+			name: "success",
+			args: args{
+				configData: &types.Config{Adaptive: true, AdaptiveThresholdDownMultiplier: 6, AdaptiveThresholdDownMin: 0.002, AdaptiveThresholdDownMax: 0.02},
+				marketData: &types.Market{Volatility: 0.001},
+			},
+			want: 0.006,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AdaptiveBuyRepeatThresholdDown(tt.args.configData, tt.args.marketData); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("AdaptiveBuyRepeatThresholdDown() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestData_LoadBook(t *testing.T) {
+	type args struct {
+		book types.OrderBook
+	}
+	tests := []struct {
+		name string
+		args args
+		want float64
+	}{
+		{
+			name: "empty",
+			args: args{book: types.OrderBook{}},
+			want: 0,
+		},
+		{
+			name: "bids",
+			args: args{book: types.OrderBook{
+				Bids: []types.BookLevel{{Price: 99, Quantity: 2}, {Price: 98, Quantity: 1}},
+				Asks: []types.BookLevel{{Price: 100, Quantity: 1}},
+			}},
+			want: 0.5,
+		},
+		{
+			name: "asks",
+			args: args{book: types.OrderBook{
+				Bids: []types.BookLevel{{Price: 99, Quantity: 1}},
+				Asks: []types.BookLevel{{Price: 100, Quantity: 3}},
+			}},
+			want: -0.5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			marketData := &types.Market{}
+			Data{Book: tt.args.book}.LoadBook(marketData)
+			if marketData.BookImbalance != tt.want {
+				t.Errorf("Data.LoadBook() BookImbalance = %v, want %v", marketData.BookImbalance, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuySlippage(t *testing.T) {
+	book := types.OrderBook{
+		Asks: []types.BookLevel{{Price: 100, Quantity: 1}, {Price: 101, Quantity: 1}, {Price: 102, Quantity: 1}},
+	}
+	type args struct {
+		book          types.OrderBook
+		quoteQuantity float64
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantBps    float64
+		wantFilled bool
+	}{
+		{
+			name:       "first level",
+			args:       args{book: book, quoteQuantity: 100},
+			wantBps:    0,
+			wantFilled: true,
+		},
+		{
+			name:       "second level",
+			args:       args{book: book, quoteQuantity: 150},
+			wantBps:    (150/(1+50.0/101)/100 - 1) * 10000,
+			wantFilled: true,
+		},
+		{
+			name:       "not enough depth",
+			args:       args{book: book, quoteQuantity: 1000},
+			wantBps:    (303.0/3/100 - 1) * 10000,
+			wantFilled: false,
+		},
+		{
+			name:       "empty book",
+			args:       args{book: types.OrderBook{}, quoteQuantity: 100},
+			wantBps:    0,
+			wantFilled: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotBps, gotFilled := BuySlippage(tt.args.book, tt.args.quoteQuantity)
+			if math.Abs(gotBps-tt.wantBps) > 1e-9 {
+				t.Errorf("BuySlippage() bps = %v, want %v", gotBps, tt.wantBps)
+			}
+			if gotFilled != tt.wantFilled {
+				t.Errorf("BuySlippage() filled = %v, want %v", gotFilled, tt.wantFilled)
+			}
+		})
+	}
+}
+
+func TestMaxBuyQuantity(t *testing.T) {
+	book := types.OrderBook{
+		Asks: []types.BookLevel{{Price: 100, Quantity: 1}, {Price: 101, Quantity: 1}, {Price: 102, Quantity: 1}},
+	}
+	type args struct {
+		book   types.OrderBook
+		maxBps float64
+	}
+	tests := []struct {
+		name string
+		args args
+		want float64
+	}{
+		{
+			name: "partial level",
+			args: args{book: book, maxBps: 25},
+			want: 100 + 101*(0.25/0.75),
+		},
+		{
+			name: "full levels",
+			args: args{book: book, maxBps: 50},
+			want: 201,
+		},
+		{
+			name: "limited to book depth",
+			args: args{book: book, maxBps: 1000},
+			want: 303,
+		},
+		{
+			name: "empty book",
+			args: args{book: types.OrderBook{}, maxBps: 25},
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MaxBuyQuantity(tt.args.book, tt.args.maxBps)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("MaxBuyQuantity() = %v, want %v", got, tt.want)
+			}
+			if tt.want > 0 && tt.want < 303 {
+				if bps, _ := BuySlippage(tt.args.book, got); bps > tt.args.maxBps+1e-9 {
+					t.Errorf("BuySlippage(MaxBuyQuantity()) = %v, want <= %v", bps, tt.args.maxBps)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitBuy(t *testing.T) {
+	book := types.OrderBook{
+		Asks: []types.BookLevel{{Price: 100, Quantity: 1}, {Price: 101, Quantity: 1}, {Price: 102, Quantity: 1}},
+	}
+	type args struct {
+		book          types.OrderBook
+		quoteQuantity float64
+		maxBps        float64
+		maxOrders     int
+	}
+	tests := []struct {
+		name string
+		args args
+		want []float64
+	}{
+		{
+			name: "one order within slippage",
+			args: args{book: book, quoteQuantity: 150, maxBps: 50, maxOrders: 5},
+			want: []float64{150},
+		},
+		{
+			name: "one order per level",
+			args: args{book: book, quoteQuantity: 303, maxBps: 0, maxOrders: 5},
+			want: []float64{100, 101, 102},
+		},
+		{
+			name: "limited to max orders",
+			args: args{book: book, quoteQuantity: 303, maxBps: 0, maxOrders: 2},
+			want: []float64{100, 101},
+		},
+		{
+			name: "partial level left by the previous order",
+			args: args{book: book, quoteQuantity: 150, maxBps: 25, maxOrders: 5},
+			want: []float64{100 + 101*(0.25/0.75), 150 - 100 - 101*(0.25/0.75)},
+		},
+		{
+			name: "limited to book depth",
+			args: args{book: book, quoteQuantity: 1000, maxBps: 1000, maxOrders: 5},
+			want: []float64{303},
+		},
+		{
+			name: "empty book",
+			args: args{book: types.OrderBook{}, quoteQuantity: 100, maxBps: 25, maxOrders: 5},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitBuy(tt.args.book, tt.args.quoteQuantity, tt.args.maxBps, tt.args.maxOrders)
+			if len(got) != len(tt.want) {
+				t.Fatalf("SplitBuy() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+					t.Errorf("SplitBuy() = %v, want %v", got, tt.want)
+				}
+			}
+			if len(book.Asks) != 3 || book.Asks[0].Quantity != 1 {
+				t.Errorf("SplitBuy() changed the book to %v", book.Asks)
+			}
+		})
+	}
+}
@@ -0,0 +1,456 @@
+package migrations
+
+/* This package applies the numbered schema migrations embedded in the binary. Migration files
+are named NNNN_name.up.sql and NNNN_name.down.sql under a directory per storage backend, and
+applied versions are recorded in the schema_version table. Migrations run under a database lock
+so several CryptoPump nodes starting at the same time don't race. */
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aleibovici/cryptopump/logger"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+//go:embed mysql/*.sql sqlite/*.sql
+var files embed.FS
+
+/* MySQL named lock held while migrations run and how long to wait for it in seconds */
+const (
+	lockName    = "cryptopump_migrations"
+	lockTimeout = 60
+)
+
+/* Table recording the applied migrations for each backend */
+var versionTable = map[string]string{
+	"mysql":  "CREATE TABLE IF NOT EXISTS `schema_version` (`Version` int NOT NULL, `Name` varchar(100) NOT NULL, `AppliedAt` bigint NOT NULL, PRIMARY KEY (`Version`)) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+	"sqlite": "CREATE TABLE IF NOT EXISTS schema_version (Version INTEGER NOT NULL PRIMARY KEY, Name TEXT NOT NULL, AppliedAt INTEGER NOT NULL)",
+}
+
+/* Migration file name, e.g. 0001_initial.up.sql */
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a numbered schema change
+type Migration struct {
+	Version   int
+	Name      string
+	Up        string
+	Down      string
+	AppliedAt int64 /* Unix time in milliseconds, 0 when pending */
+}
+
+// Migrator applies migrations for a storage backend (mysql or sqlite)
+type Migrator struct {
+	Db      *sql.DB
+	Backend string
+}
+
+// Load return the migrations embedded for the backend ordered by version
+func (m Migrator) Load() ([]Migration, error) {
+
+	entries, err := fs.ReadDir(files, m.Backend)
+	if err != nil {
+
+		return nil, fmt.Errorf("no migrations for storage %q", m.Backend)
+
+	}
+
+	index := make(map[int]*Migration)
+
+	for _, entry := range entries {
+
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+
+			continue
+
+		}
+
+		content, err := files.ReadFile(m.Backend + "/" + entry.Name())
+		if err != nil {
+
+			return nil, err
+
+		}
+
+		version, _ := strconv.Atoi(match[1])
+
+		migration, ok := index[version]
+		if !ok {
+
+			migration = &Migration{Version: version, Name: match[2]}
+			index[version] = migration
+
+		} else if migration.Name != match[2] {
+
+			return nil, fmt.Errorf("migration %04d has two names: %s and %s", version, migration.Name, match[2])
+
+		}
+
+		if match[3] == "up" {
+
+			migration.Up = string(content)
+
+		} else {
+
+			migration.Down = string(content)
+
+		}
+
+	}
+
+	migrations := make([]Migration, 0, len(index))
+	for _, migration := range index {
+
+		if migration.Up == "" {
+
+			return nil, fmt.Errorf("migration %04d_%s has no up file", migration.Version, migration.Name)
+
+		}
+
+		migrations = append(migrations, *migration)
+
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+
+}
+
+// Status return all migrations with the time each one was applied
+func (m Migrator) Status() (migrations []Migration, err error) {
+
+	err = m.locked(func(ctx context.Context, conn *sql.Conn, applied map[int]int64) error {
+
+		if migrations, err = m.Load(); err != nil {
+
+			return err
+
+		}
+
+		for i := range migrations {
+
+			migrations[i].AppliedAt = applied[migrations[i].Version]
+
+		}
+
+		return nil
+
+	})
+
+	return migrations, err
+
+}
+
+// Up apply all pending migrations in version order and return the ones applied
+func (m Migrator) Up() (applied []Migration, err error) {
+
+	err = m.locked(func(ctx context.Context, conn *sql.Conn, done map[int]int64) error {
+
+		migrations, err := m.Load()
+		if err != nil {
+
+			return err
+
+		}
+
+		for _, migration := range migrations {
+
+			if _, ok := done[migration.Version]; ok {
+
+				continue
+
+			}
+
+			if err := execScript(ctx, conn, migration.Up); err != nil {
+
+				return fmt.Errorf("migration %04d_%s up: %w", migration.Version, migration.Name, err)
+
+			}
+
+			migration.AppliedAt = time.Now().UnixNano() / int64(time.Millisecond)
+
+			if _, err := conn.ExecContext(ctx, "INSERT INTO schema_version (Version, Name, AppliedAt) VALUES (?, ?, ?)",
+				migration.Version, migration.Name, migration.AppliedAt); err != nil {
+
+				return err
+
+			}
+
+			m.log(fmt.Sprintf("Applied migration %04d_%s", migration.Version, migration.Name))
+
+			applied = append(applied, migration)
+
+		}
+
+		return nil
+
+	})
+
+	return applied, err
+
+}
+
+// Down revert the latest applied migration and return it
+func (m Migrator) Down() (reverted Migration, err error) {
+
+	err = m.locked(func(ctx context.Context, conn *sql.Conn, done map[int]int64) error {
+
+		migrations, err := m.Load()
+		if err != nil {
+
+			return err
+
+		}
+
+		for i := len(migrations) - 1; i >= 0; i-- {
+
+			if _, ok := done[migrations[i].Version]; !ok {
+
+				continue
+
+			}
+
+			reverted = migrations[i]
+
+			if reverted.Down == "" {
+
+				return fmt.Errorf("migration %04d_%s has no down file", reverted.Version, reverted.Name)
+
+			}
+
+			if err := execScript(ctx, conn, reverted.Down); err != nil {
+
+				return fmt.Errorf("migration %04d_%s down: %w", reverted.Version, reverted.Name, err)
+
+			}
+
+			if _, err := conn.ExecContext(ctx, "DELETE FROM schema_version WHERE Version = ?", reverted.Version); err != nil {
+
+				return err
+
+			}
+
+			m.log(fmt.Sprintf("Reverted migration %04d_%s", reverted.Version, reverted.Name))
+
+			return nil
+
+		}
+
+		return errors.New("no migrations applied")
+
+	})
+
+	return reverted, err
+
+}
+
+/* Run f on one connection holding the migration lock (MySQL named lock, SQLite immediate transaction) */
+func (m Migrator) locked(f func(ctx context.Context, conn *sql.Conn, applied map[int]int64) error) (err error) {
+
+	table, ok := versionTable[m.Backend]
+	if !ok {
+
+		return fmt.Errorf("no migrations for storage %q", m.Backend)
+
+	}
+
+	ctx := context.Background()
+
+	conn, err := m.Db.Conn(ctx)
+
+	/* Opening a SQLite connection switches the journal to WAL. On a new database file this fails
+	without waiting for the busy timeout while another node holds the lock, so retry until lockTimeout. */
+	for deadline := time.Now().Add(lockTimeout * time.Second); m.Backend == "sqlite" && err != nil &&
+		strings.Contains(err.Error(), "database is locked") && time.Now().Before(deadline); {
+
+		time.Sleep(100 * time.Millisecond)
+		conn, err = m.Db.Conn(ctx)
+
+	}
+
+	if err != nil {
+
+		return err
+
+	}
+	defer conn.Close()
+
+	switch m.Backend {
+	case "sqlite":
+
+		if _, err = conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+
+			return err
+
+		}
+
+		defer func() {
+			if err != nil {
+
+				_, _ = conn.ExecContext(ctx, "ROLLBACK")
+
+			} else {
+
+				_, err = conn.ExecContext(ctx, "COMMIT")
+
+			}
+		}()
+
+	default:
+
+		var got sql.NullInt64
+		if err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, lockTimeout).Scan(&got); err != nil {
+
+			return err
+
+		}
+
+		if !got.Valid || got.Int64 != 1 {
+
+			return fmt.Errorf("timeout waiting %d seconds for lock %s", lockTimeout, lockName)
+
+		}
+
+		defer func() {
+			var released sql.NullInt64
+			_ = conn.QueryRowContext(ctx, "SELECT RELEASE_LOCK(?)", lockName).Scan(&released)
+		}()
+
+	}
+
+	if _, err = conn.ExecContext(ctx, table); err != nil {
+
+		return err
+
+	}
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+
+		return err
+
+	}
+
+	return f(ctx, conn, applied)
+
+}
+
+/* Return the applied versions and the time each one was applied */
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]int64, error) {
+
+	rows, err := conn.QueryContext(ctx, "SELECT Version, AppliedAt FROM schema_version")
+	if err != nil {
+
+		return nil, err
+
+	}
+	defer rows.Close()
+
+	applied := make(map[int]int64)
+	for rows.Next() {
+
+		var version int
+		var appliedAt int64
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+
+			return nil, err
+
+		}
+
+		applied[version] = appliedAt
+
+	}
+
+	return applied, rows.Err()
+
+}
+
+/* Execute each statement of a migration script */
+func execScript(ctx context.Context, conn *sql.Conn, script string) error {
+
+	for _, statement := range statements(script) {
+
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+
+			return err
+
+		}
+
+	}
+
+	return nil
+
+}
+
+/* Split a script into statements ending with the current delimiter, which DELIMITER lines change as in the mysql client */
+func statements(script string) []string {
+
+	var list []string
+	var statement strings.Builder
+	delimiter := ";"
+
+	for _, line := range strings.Split(script, "\n") {
+
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(strings.ToUpper(trimmed), "DELIMITER ") {
+
+			delimiter = strings.TrimSpace(trimmed[len("DELIMITER "):])
+			continue
+
+		}
+
+		if statement.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")) {
+
+			continue
+
+		}
+
+		if strings.HasSuffix(trimmed, delimiter) {
+
+			statement.WriteString(strings.TrimSuffix(trimmed, delimiter))
+			list = append(list, strings.TrimSpace(statement.String()))
+			statement.Reset()
+			continue
+
+		}
+
+		statement.WriteString(line + "\n")
+
+	}
+
+	if last := strings.TrimSpace(statement.String()); last != "" {
+
+		list = append(list, last)
+
+	}
+
+	return list
+
+}
+
+/* Log migration progress */
+func (m Migrator) log(message string) {
+
+	logger.LogEntry{ /* Log Entry */
+		Config:   nil,
+		Market:   nil,
+		Session:  nil,
+		Order:    &types.Order{},
+		Message:  message,
+		LogLevel: "InfoLevel",
+	}.Do()
+
+}
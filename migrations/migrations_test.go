@@ -0,0 +1,219 @@
+package migrations
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // This blank entry is required to enable sqlite connectivity
+)
+
+/* Return a migrator for a new SQLite database in a temporary directory */
+func newMigrator(t *testing.T, path string) Migrator {
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?_busy_timeout=10000&_journal_mode=WAL")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return Migrator{Db: db, Backend: "sqlite"}
+
+}
+
+func Test_statements(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "semicolon",
+			script: "-- comment\n\nCREATE TABLE a (ID int);\nCREATE TABLE b (\n\tID int\n);\n",
+			want:   []string{"CREATE TABLE a (ID int)", "CREATE TABLE b (\n\tID int\n)"},
+		},
+		{
+			name:   "delimiter",
+			script: "DELIMITER ;;\nDROP PROCEDURE IF EXISTS `p` ;;\nCREATE PROCEDURE `p`()\nBEGIN\n\tSELECT 1;\nEND ;;\nDELIMITER ;\nSELECT 2;\n",
+			want:   []string{"DROP PROCEDURE IF EXISTS `p`", "CREATE PROCEDURE `p`()\nBEGIN\n\tSELECT 1;\nEND", "SELECT 2"},
+		},
+		{
+			name:   "missing final delimiter",
+			script: "SELECT 1",
+			want:   []string{"SELECT 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statements(tt.script); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("statements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrator_Load(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		backend        string
+		wantStatements int
+		wantErr        bool
+	}{
+		{
+			name:           "mysql tables and procedures",
+			backend:        "mysql",
+			wantStatements: 4 + 2*32,
+		},
+		{
+			name:           "sqlite tables",
+			backend:        "sqlite",
+			wantStatements: 5,
+		},
+		{
+			name:    "unknown storage",
+			backend: "postgres",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (Migrator{Backend: tt.backend}).Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Migrator.Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got[0].Version != 1 || got[0].Down == "" {
+				t.Errorf("Migrator.Load() first migration = %v %v, want version 1 with down", got[0].Version, got[0].Name)
+			}
+			if n := len(statements(got[0].Up)); n != tt.wantStatements {
+				t.Errorf("Migrator.Load() baseline statements = %v, want %v", n, tt.wantStatements)
+			}
+		})
+	}
+}
+
+func TestMigrator_UpDown(t *testing.T) {
+
+	m := newMigrator(t, filepath.Join(t.TempDir(), "cryptopump.db"))
+
+	all, err := m.Load()
+	if err != nil {
+		t.Fatalf("Migrator.Load() error = %v", err)
+	}
+
+	if applied, err := m.Up(); err != nil || len(applied) != len(all) {
+		t.Fatalf("Migrator.Up() = %v migrations, %v, want %v", len(applied), err, len(all))
+	}
+
+	if applied, err := m.Up(); err != nil || len(applied) != 0 {
+		t.Errorf("Migrator.Up() again = %v migrations, %v, want none", len(applied), err)
+	}
+
+	if _, err := m.Db.Exec("INSERT INTO session (ThreadID, ThreadIDSession, Exchange, FiatSymbol, FiatFunds, DiffTotal, Status) VALUES ('a', 'b', 'c', 'd', 0, 0, 0)"); err != nil {
+		t.Errorf("baseline schema insert error = %v", err)
+	}
+
+	status, err := m.Status()
+	if err != nil {
+		t.Fatalf("Migrator.Status() error = %v", err)
+	}
+	for _, migration := range status {
+		if migration.AppliedAt == 0 {
+			t.Errorf("Migrator.Status() %04d_%s pending, want applied", migration.Version, migration.Name)
+		}
+	}
+
+	latest := all[len(all)-1]
+	if reverted, err := m.Down(); err != nil || reverted.Version != latest.Version {
+		t.Fatalf("Migrator.Down() = %v, %v, want %v", reverted.Version, err, latest.Version)
+	}
+
+	if status, _ := m.Status(); status[len(status)-1].AppliedAt != 0 {
+		t.Errorf("Migrator.Status() after Down latest applied, want pending")
+	}
+
+	if applied, err := m.Up(); err != nil || len(applied) != 1 {
+		t.Errorf("Migrator.Up() after Down = %v migrations, %v, want 1", len(applied), err)
+	}
+
+}
+
+func TestMigrator_UpConcurrent(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "cryptopump.db")
+	nodes := []Migrator{newMigrator(t, path), newMigrator(t, path), newMigrator(t, path)}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	total := 0
+
+	for _, m := range nodes {
+		wg.Add(1)
+		go func(m Migrator) {
+			defer wg.Done()
+			applied, err := m.Up()
+			if err != nil {
+				t.Errorf("Migrator.Up() error = %v", err)
+			}
+			mu.Lock()
+			total += len(applied)
+			mu.Unlock()
+		}(m)
+	}
+
+	wg.Wait()
+
+	if all, _ := nodes[0].Load(); total != len(all) {
+		t.Errorf("Migrator.Up() applied %v migrations across nodes, want %v", total, len(all))
+	}
+
+}
+
+func TestMigrator_UpLocked(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "cryptopump.db")
+
+	/* Another node writing to the new database file before it is switched to WAL */
+	other, err := sql.Open("sqlite3", "file:"+path)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+
+	defer other.Close()
+
+	tx, err := other.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+
+	if _, err := tx.Exec("CREATE TABLE node (ID int)"); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		tx.Commit()
+	}()
+
+	m := newMigrator(t, path)
+
+	applied, err := m.Up()
+	if err != nil {
+		t.Fatalf("Migrator.Up() error = %v", err)
+	}
+
+	if all, _ := m.Load(); len(applied) != len(all) {
+		t.Errorf("Migrator.Up() = %v migrations, want %v", len(applied), len(all))
+	}
+
+}
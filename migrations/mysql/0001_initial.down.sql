@@ -0,0 +1,36 @@
+DROP PROCEDURE IF EXISTS `DeleteSession`;
+DROP PROCEDURE IF EXISTS `DeleteThreadTransactionAll`;
+DROP PROCEDURE IF EXISTS `DeleteThreadTransactionByOrderID`;
+DROP PROCEDURE IF EXISTS `GetGlobal`;
+DROP PROCEDURE IF EXISTS `GetLastOrderTransactionPrice`;
+DROP PROCEDURE IF EXISTS `GetLastOrderTransactionSide`;
+DROP PROCEDURE IF EXISTS `GetOrderByOrderID`;
+DROP PROCEDURE IF EXISTS `GetOrderSymbol`;
+DROP PROCEDURE IF EXISTS `GetOrderTransactionCount`;
+DROP PROCEDURE IF EXISTS `GetOrderTransactionPending`;
+DROP PROCEDURE IF EXISTS `GetOrderTransactionSideLastTwo`;
+DROP PROCEDURE IF EXISTS `GetOrderTransactionTimeByOrderID`;
+DROP PROCEDURE IF EXISTS `GetProfit`;
+DROP PROCEDURE IF EXISTS `GetProfitByThreadID`;
+DROP PROCEDURE IF EXISTS `GetProfitByThreadIDSince`;
+DROP PROCEDURE IF EXISTS `GetSessionStatus`;
+DROP PROCEDURE IF EXISTS `GetThreadCount`;
+DROP PROCEDURE IF EXISTS `GetThreadLastTransaction`;
+DROP PROCEDURE IF EXISTS `GetThreadTransactionAmount`;
+DROP PROCEDURE IF EXISTS `GetThreadTransactionByPrice`;
+DROP PROCEDURE IF EXISTS `GetThreadTransactionByPriceHigher`;
+DROP PROCEDURE IF EXISTS `GetThreadTransactionByThreadID`;
+DROP PROCEDURE IF EXISTS `GetThreadTransactionCount`;
+DROP PROCEDURE IF EXISTS `GetThreadTransactionDistinct`;
+DROP PROCEDURE IF EXISTS `GetThreadTransactiontUpmarketPriceCount`;
+DROP PROCEDURE IF EXISTS `SaveGlobal`;
+DROP PROCEDURE IF EXISTS `SaveOrder`;
+DROP PROCEDURE IF EXISTS `SaveSession`;
+DROP PROCEDURE IF EXISTS `SaveThreadTransaction`;
+DROP PROCEDURE IF EXISTS `UpdateGlobal`;
+DROP PROCEDURE IF EXISTS `UpdateOrder`;
+DROP PROCEDURE IF EXISTS `UpdateSession`;
+DROP TABLE IF EXISTS `global`;
+DROP TABLE IF EXISTS `orders`;
+DROP TABLE IF EXISTS `session`;
+DROP TABLE IF EXISTS `thread`;
@@ -0,0 +1,528 @@
+-- Baseline schema. Tables are created only when missing and procedures are replaced, so
+-- existing databases imported from cryptopump.sql or cryptopump-mariadb.sql upgrade in place.
+
+CREATE TABLE IF NOT EXISTS `global` (
+  `ID` int NOT NULL AUTO_INCREMENT,
+  `Profit` float NOT NULL,
+  `ProfitNet` float NOT NULL,
+  `ProfitPct` float NOT NULL,
+  `TransactTime` varchar(45) NOT NULL,
+  PRIMARY KEY (`ID`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `orders` (
+  `ClientOrderId` varchar(45) NOT NULL,
+  `CummulativeQuoteQty` float NOT NULL,
+  `ExecutedQuantity` float NOT NULL,
+  `OrderID` bigint NOT NULL,
+  `OrderIDSource` bigint NOT NULL,
+  `Price` float NOT NULL,
+  `Side` varchar(45) NOT NULL,
+  `Status` varchar(45) NOT NULL,
+  `Symbol` varchar(45) NOT NULL,
+  `TransactTime` bigint NOT NULL,
+  `ThreadID` varchar(45) NOT NULL,
+  `ThreadIDSession` varchar(45) NOT NULL,
+  PRIMARY KEY (`OrderID`),
+  UNIQUE KEY `OrderID_UNIQUE` (`OrderID`),
+  KEY `orders_idx_side_status` (`Side`,`Status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `session` (
+  `ID` int NOT NULL AUTO_INCREMENT,
+  `ThreadID` varchar(45) NOT NULL,
+  `ThreadIDSession` varchar(45) NOT NULL,
+  `Exchange` varchar(45) NOT NULL,
+  `FiatSymbol` varchar(45) NOT NULL,
+  `FiatFunds` float NOT NULL,
+  `DiffTotal` float NOT NULL,
+  `Status` tinyint(1) NOT NULL,
+  PRIMARY KEY (`ID`),
+  UNIQUE KEY `ThreadID_UNIQUE` (`ThreadID`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `thread` (
+  `ID` int NOT NULL AUTO_INCREMENT,
+  `ThreadID` varchar(45) NOT NULL,
+  `ThreadIDSession` varchar(45) NOT NULL,
+  `OrderID` bigint DEFAULT NULL,
+  `CummulativeQuoteQty` float NOT NULL,
+  `Price` float NOT NULL,
+  `ExecutedQuantity` float NOT NULL,
+  PRIMARY KEY (`ID`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DELIMITER ;;
+
+DROP PROCEDURE IF EXISTS `DeleteSession` ;;
+CREATE PROCEDURE `DeleteSession`(IN in_ThreadID varchar(45))
+BEGIN
+	DECLARE ThreadID varchar(45);
+	SET SQL_SAFE_UPDATES = 0;
+	SET ThreadID = in_ThreadID;
+	DELETE FROM session ft
+	WHERE ft.ThreadID = in_ThreadID;
+	SET SQL_SAFE_UPDATES = 1;
+END ;;
+
+DROP PROCEDURE IF EXISTS `DeleteThreadTransactionAll` ;;
+CREATE PROCEDURE `DeleteThreadTransactionAll`()
+BEGIN
+    SET SQL_SAFE_UPDATES = 0;
+    DELETE FROM thread ft;
+    SET SQL_SAFE_UPDATES = 1;
+END ;;
+
+DROP PROCEDURE IF EXISTS `DeleteThreadTransactionByOrderID` ;;
+CREATE PROCEDURE `DeleteThreadTransactionByOrderID`(IN in_param_OrderID bigint)
+BEGIN
+	DECLARE declared_in_param_OrderID bigint;
+    SET SQL_SAFE_UPDATES = 0;
+    SET declared_in_param_OrderID = in_param_OrderID;
+    DELETE FROM thread ft
+    WHERE ft.OrderID = in_param_OrderID;
+    SET SQL_SAFE_UPDATES = 1;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetGlobal` ;;
+CREATE PROCEDURE `GetGlobal`()
+BEGIN
+SELECT 
+    `global`.`Profit` AS `Profit`,
+    `global`.`ProfitNet` AS `ProfitNet`,
+    `global`.`ProfitPct` AS `ProfitPct`,
+    `global`.`TransactTime` AS `TransactTime`
+FROM
+    `global`
+WHERE
+    `global`.`ID` = 1
+LIMIT 1;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetLastOrderTransactionPrice` ;;
+CREATE PROCEDURE `GetLastOrderTransactionPrice`(IN in_param_ThreadID varchar(45), IN in_param_Side varchar(45))
+BEGIN
+	DECLARE declared_in_param_ThreadID CHAR(45);
+    DECLARE declared_in_param_Side CHAR(45);
+    SET declared_in_param_ThreadID = in_param_ThreadID;
+    SET declared_in_param_Side = in_param_Side;
+    SELECT `orders`.`Price` AS `Price`
+	FROM `orders`
+	WHERE (`orders`.`ThreadID` = declared_in_param_ThreadID
+		AND `orders`.`Side` = declared_in_param_Side AND (`orders`.`Status` <> 'CANCELED'
+		OR `orders`.`Status` IS NULL))
+	ORDER BY from_unixtime((`orders`.`TransactTime` / 1000)) DESC
+	LIMIT 1;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetLastOrderTransactionSide` ;;
+CREATE PROCEDURE `GetLastOrderTransactionSide`(IN in_param_ThreadID varchar(45))
+BEGIN
+	DECLARE declared_in_param_ThreadID CHAR(45);
+    SET declared_in_param_ThreadID = in_param_ThreadID;
+	SELECT `orders`.`Side` AS `Side`
+	FROM `orders`
+	WHERE (`orders`.`ThreadID` = declared_in_param_ThreadID
+	   AND `orders`.`Status` = 'FILLED')
+	ORDER BY from_unixtime((`orders`.`TransactTime` / 1000)) DESC
+	LIMIT 1;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetOrderByOrderID` ;;
+CREATE PROCEDURE `GetOrderByOrderID`(IN in_param_OrderID bigint, IN in_param_ThreadID varchar(45))
+BEGIN
+DECLARE declared_in_param_orderid BIGINT; 
+DECLARE declared_in_param_threadid CHAR(50); 
+SET declared_in_param_orderid = in_param_orderid; 
+SET declared_in_param_threadid = in_param_threadid;
+SELECT 
+    `orders`.`orderid` AS `OrderID`,
+    `orders`.`price` AS `Price`,
+    `orders`.`executedquantity` AS `ExecutedQuantity`,
+    `orders`.`cummulativequoteqty` AS `CummulativeQuoteQty`,
+    `orders`.`transacttime` AS `TransactTime`
+FROM
+    `orders`
+WHERE
+    (`orders`.`orderid` = declared_in_param_orderid
+        AND `orders`.`threadid` = declared_in_param_threadid)
+LIMIT 1; 
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetOrderSymbol` ;;
+CREATE PROCEDURE `GetOrderSymbol`(IN in_param varchar(45))
+BEGIN
+	DECLARE declared_in_param CHAR(45);
+    SET declared_in_param = in_param;
+	SELECT Symbol from orders ft 
+    WHERE ft.ThreadID = declared_in_param
+    ORDER BY TransactTime DESC LIMIT 1;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetOrderTransactionCount` ;;
+CREATE PROCEDURE `GetOrderTransactionCount`(IN in_param_ThreadID varchar(45), IN in_param_Side varchar(45), IN in_param_Minutes int)
+BEGIN
+	DECLARE declared_in_param_ThreadID CHAR(45);
+    DECLARE declared_in_param_Side CHAR(45);
+    DECLARE declared_in_param_Minutes int;
+    SET declared_in_param_ThreadID = in_param_ThreadID;
+    SET declared_in_param_Side = in_param_Side;
+    SET declared_in_param_Minutes = in_param_Minutes;
+SELECT COALESCE(count(*),0) AS `count`
+FROM `orders`
+WHERE (`orders`.`Side` = declared_in_param_Side
+   AND `orders`.`Status` = 'FILLED' AND str_to_date(date_format(CAST(from_unixtime((`orders`.`TransactTime` / 1000)) AS DATETIME), '%Y-%m-%d %H:%i'), '%Y-%m-%d %H:%i') BETWEEN str_to_date(date_format(CAST(date_add(now(6), INTERVAL declared_in_param_Minutes minute) AS DATETIME), '%Y-%m-%d %H:%i'), '%Y-%m-%d %H:%i') AND str_to_date(date_format(CAST(now(6) AS DATETIME), '%Y-%m-%d %H:%i'), '%Y-%m-%d %H:%i') AND `orders`.`ThreadID` = declared_in_param_ThreadID);
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetOrderTransactionPending` ;;
+CREATE PROCEDURE `GetOrderTransactionPending`(IN in_param_ThreadID varchar(45))
+BEGIN
+	DECLARE declared_in_param_ThreadID CHAR(45);
+    SET declared_in_param_ThreadID = in_param_ThreadID;
+SELECT `orders`.`OrderID` AS `OrderID`, `orders`.`Symbol` AS `Symbol`
+FROM `orders`
+WHERE (`orders`.`ThreadID` = declared_in_param_ThreadID
+   AND (`orders`.`Status` <> 'FILLED'
+    OR `orders`.`Status` IS NULL) AND (`orders`.`Status` <> 'CANCELED' OR `orders`.`Status` IS NULL) AND `orders`.`Status` IS NOT NULL AND (`orders`.`Status` <> '' OR `orders`.`Status` IS NULL))
+ORDER BY from_unixtime((`orders`.`TransactTime` / 1000)) ASC
+LIMIT 1;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetOrderTransactionSideLastTwo` ;;
+CREATE PROCEDURE `GetOrderTransactionSideLastTwo`(IN in_param_ThreadID varchar(45))
+BEGIN
+	DECLARE declared_in_param_ThreadID CHAR(45);
+    SET declared_in_param_ThreadID = in_param_ThreadID;
+	SELECT `A`.`Side` AS `Last`, `B`.`Side` AS `SecondLast` FROM (
+	(SELECT `orders`.`Side` AS `Side`
+	FROM `orders`
+	WHERE (`orders`.`ThreadID` = declared_in_param_ThreadID
+	   AND (`orders`.`Status` <> 'CANCELED'
+		OR `orders`.`Status` IS NULL))
+	ORDER BY from_unixtime((`orders`.`TransactTime` / 1000)) DESC
+	LIMIT 1) A
+	INNER JOIN
+	(SELECT `orders`.`Side` AS `Side`
+	FROM `orders`
+	WHERE (`orders`.`ThreadID` = declared_in_param_ThreadID
+	   AND (`orders`.`Status` <> 'CANCELED'
+		OR `orders`.`Status` IS NULL))
+	ORDER BY from_unixtime((`orders`.`TransactTime` / 1000)) DESC
+	LIMIT 1,1) B
+	);
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetOrderTransactionTimeByOrderID` ;;
+CREATE PROCEDURE `GetOrderTransactionTimeByOrderID`(IN in_param_OrderID bigint)
+BEGIN
+	DECLARE declared_in_param_OrderID bigint;
+    SET declared_in_param_OrderID = in_param_OrderID;
+	SELECT `orders`.`TransactTime` AS `TransactTime`
+	FROM `orders`
+	WHERE `orders`.`OrderID` = declared_in_param_OrderID
+	LIMIT 1;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetProfit` ;;
+CREATE PROCEDURE `GetProfit`()
+BEGIN
+SELECT
+        SUM(`source`.`Profit`) AS `profit`,
+        SUM(`source`.`Profit`) + (`source`.`Diff`) AS `netprofit`,
+        AVG(`source`.`Percentage`) AS `avg` 
+    FROM
+        (SELECT
+            `orders`.`Side` AS `Side`,
+            `Orders`.`Side` AS `Orders__Side`,
+            `orders`.`Status` AS `Status`,
+            `Orders`.`Status` AS `Orders__Status`,
+            `orders`.`ThreadID` AS `ThreadID`,
+            `Orders`.`CummulativeQuoteQty` AS `Orders__CummulativeQuoteQty`,
+            `orders`.`CummulativeQuoteQty` AS `CummulativeQuoteQty`,
+            (`Orders`.`CummulativeQuoteQty` - `orders`.`CummulativeQuoteQty`) AS `Profit`,
+            ((`Orders`.`CummulativeQuoteQty` - `orders`.`CummulativeQuoteQty`) / CASE 
+                WHEN `Orders`.`CummulativeQuoteQty` = 0 THEN NULL 
+                ELSE `Orders`.`CummulativeQuoteQty` END) AS `Percentage`,
+(SELECT
+    sum(`session`.`DiffTotal`) AS `sum` 
+FROM
+    `session`) AS `Diff` 
+FROM
+`orders` 
+INNER JOIN
+`orders` `Orders` 
+    ON `orders`.`OrderID` = `Orders`.`OrderIDSource` 
+WHERE
+(
+    `orders`.`Side` = 'BUY'
+) 
+AND (
+    `orders`.`Status` = 'FILLED'
+)
+) `source` 
+WHERE
+(
+1 = 1 
+AND `source`.`Orders__Side` = 'SELL' 
+AND 1 = 1 
+AND `source`.`Orders__Status` = 'FILLED'
+);
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetProfitByThreadID` ;;
+CREATE PROCEDURE `GetProfitByThreadID`(IN in_param_ThreadID varchar(45))
+BEGIN
+DECLARE declared_in_param_ThreadID CHAR(50);
+    SET declared_in_param_ThreadID = in_param_ThreadID;
+SELECT 
+    SUM(`source`.`Profit`) + (`source`.`Diff`) AS `sum`,
+    AVG(`source`.`Percentage`) AS `avg`
+FROM
+    (SELECT 
+        `orders`.`Side` AS `Side`,
+            `Orders`.`Side` AS `Orders__Side`,
+            `orders`.`Status` AS `Status`,
+            `Orders`.`Status` AS `Orders__Status`,
+            `orders`.`ThreadID` AS `ThreadID`,
+            `Orders`.`CummulativeQuoteQty` AS `Orders__CummulativeQuoteQty`,
+            `orders`.`CummulativeQuoteQty` AS `CummulativeQuoteQty`,
+            (`Orders`.`CummulativeQuoteQty` - `orders`.`CummulativeQuoteQty`) AS `Profit`,
+            ((`Orders`.`CummulativeQuoteQty` - `orders`.`CummulativeQuoteQty`) / CASE
+                WHEN `Orders`.`CummulativeQuoteQty` = 0 THEN NULL
+                ELSE `Orders`.`CummulativeQuoteQty`
+            END) AS `Percentage`,
+            (SELECT 
+                    SUM(`session`.`DiffTotal`) AS `sum`
+                FROM
+                    `session`
+                WHERE
+                    `session`.`ThreadID` = declared_in_param_ThreadID) AS `Diff`
+    FROM
+        `orders`
+    INNER JOIN `orders` `Orders` ON `orders`.`OrderID` = `Orders`.`OrderIDSource`) `source`
+WHERE
+    (`source`.`Side` = 'BUY'
+        AND `source`.`Orders__Side` = 'SELL'
+        AND `source`.`Status` = 'FILLED'
+        AND `source`.`Orders__Status` = 'FILLED'
+        AND `source`.`ThreadID` = declared_in_param_ThreadID);
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetProfitByThreadIDSince` ;;
+CREATE PROCEDURE `GetProfitByThreadIDSince`(IN in_param_ThreadID varchar(45), IN in_param_TransactTime bigint)
+BEGIN
+DECLARE declared_in_param_ThreadID CHAR(50);
+DECLARE declared_in_param_TransactTime BIGINT;
+    SET declared_in_param_ThreadID = in_param_ThreadID;
+    SET declared_in_param_TransactTime = in_param_TransactTime;
+SELECT 
+    SUM(`Orders`.`CummulativeQuoteQty` - `orders`.`CummulativeQuoteQty`) AS `sum`
+FROM
+    `orders`
+INNER JOIN `orders` `Orders` ON `orders`.`OrderID` = `Orders`.`OrderIDSource`
+WHERE
+    (`orders`.`Side` = 'BUY'
+        AND `Orders`.`Side` = 'SELL'
+        AND `orders`.`Status` = 'FILLED'
+        AND `Orders`.`Status` = 'FILLED'
+        AND `orders`.`ThreadID` = declared_in_param_ThreadID
+        AND `Orders`.`TransactTime` >= declared_in_param_TransactTime);
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetSessionStatus` ;;
+CREATE PROCEDURE `GetSessionStatus`()
+BEGIN
+SELECT `session`.`ThreadID` AS `ThreadID`, `session`.`Status` AS `Status`
+FROM cryptopump.session
+WHERE `session`.`Status` = 1;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetThreadCount` ;;
+CREATE PROCEDURE `GetThreadCount`()
+BEGIN
+SELECT 
+    COUNT(DISTINCT `session`.`ThreadID`) AS `count`
+FROM
+    `cryptopump`.`session`;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetThreadLastTransaction` ;;
+CREATE PROCEDURE `GetThreadLastTransaction`(IN in_param_ThreadID varchar(45))
+BEGIN
+	DECLARE declared_in_param_ThreadID CHAR(50);
+    SET declared_in_param_ThreadID = in_param_ThreadID;
+	SELECT `thread`.`CummulativeQuoteQty` AS `CummulativeQuoteQty`, `thread`.`OrderID` AS `OrderID`, `thread`.`Price` AS `Price`, `thread`.`ExecutedQuantity` AS `ExecutedQuantity`, `Orders`.`TransactTime` AS `TransactTime`
+	FROM `thread`
+	LEFT JOIN `orders` `Orders` ON `thread`.`OrderID` = `Orders`.`OrderID`
+	WHERE (`thread`.`ThreadID` = declared_in_param_ThreadID)
+	ORDER BY `thread`.`Price` ASC
+	LIMIT 1;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetThreadTransactionAmount` ;;
+CREATE PROCEDURE `GetThreadTransactionAmount`()
+BEGIN
+SELECT 
+    SUM(`thread`.`CummulativeQuoteQty`) AS `sum`
+FROM
+    `cryptopump`.`thread`;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetThreadTransactionByPrice` ;;
+CREATE PROCEDURE `GetThreadTransactionByPrice`(IN in_param_ThreadID varchar(45), IN in_param_Price float)
+BEGIN
+	DECLARE declared_in_param_ThreadID CHAR(50);
+	DECLARE declared_in_param_Price FLOAT;
+    SET declared_in_param_ThreadID = in_param_ThreadID;
+    SET declared_in_param_Price = in_param_Price;
+	SELECT `thread`.`CummulativeQuoteQty` AS `CummulativeQuoteQty`, `thread`.`OrderID` AS `OrderID`, `thread`.`Price` AS `Price`, `thread`.`ExecutedQuantity` AS `ExecutedQuantity`, `Orders`.`TransactTime` AS `TransactTime`
+	FROM `thread`
+	LEFT JOIN `orders` `Orders` ON `thread`.`OrderID` = `Orders`.`OrderID`
+	WHERE (`thread`.`ThreadID` = declared_in_param_ThreadID
+	   AND `thread`.`Price` < declared_in_param_Price)
+	ORDER BY `thread`.`Price` ASC
+	LIMIT 1;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetThreadTransactionByPriceHigher` ;;
+CREATE PROCEDURE `GetThreadTransactionByPriceHigher`(IN in_param_ThreadID varchar(45), IN in_param_Price float)
+BEGIN
+	DECLARE declared_in_param_ThreadID CHAR(50);
+	DECLARE declared_in_param_Price FLOAT;
+    SET declared_in_param_ThreadID = in_param_ThreadID;
+    SET declared_in_param_Price = in_param_Price;
+	SELECT 
+    `thread`.`CummulativeQuoteQty` AS `CummulativeQuoteQty`,
+    `thread`.`OrderID` AS `OrderID`,
+    `thread`.`Price` AS `Price`,
+    `thread`.`ExecutedQuantity` AS `ExecutedQuantity`,
+    `Orders`.`TransactTime` AS `TransactTime`
+FROM
+    `thread`
+        LEFT JOIN
+    `orders` `Orders` ON `thread`.`OrderID` = `Orders`.`OrderID`
+WHERE
+    (`thread`.`ThreadID` = declared_in_param_ThreadID
+        AND `thread`.`Price` > declared_in_param_Price)
+ORDER BY `thread`.`Price` DESC
+LIMIT 1;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetThreadTransactionByThreadID` ;;
+CREATE PROCEDURE `GetThreadTransactionByThreadID`(IN in_param_ThreadID varchar(45))
+BEGIN
+	DECLARE declared_in_param_ThreadID CHAR(50);
+    SET declared_in_param_ThreadID = in_param_ThreadID;
+SELECT 
+    `thread`.`OrderID` AS `OrderID`,
+    `thread`.`CummulativeQuoteQty` AS `CummulativeQuoteQty`,
+    `thread`.`Price` AS `Price`,
+    `thread`.`ExecutedQuantity` AS `ExecutedQuantity`
+FROM
+    `thread`
+        LEFT JOIN
+    `orders` `Orders` ON `thread`.`OrderID` = `Orders`.`OrderID`
+WHERE
+    `thread`.`ThreadID` = declared_in_param_ThreadID
+ORDER BY `thread`.`Price` ASC;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetThreadTransactionCount` ;;
+CREATE PROCEDURE `GetThreadTransactionCount`(IN in_param varchar(45))
+BEGIN
+	DECLARE declared_in_param CHAR(50);
+    SET declared_in_param = in_param;
+    SELECT count(*) AS count FROM thread ft
+    WHERE ft.ThreadID = declared_in_param;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetThreadTransactionDistinct` ;;
+CREATE PROCEDURE `GetThreadTransactionDistinct`()
+BEGIN
+	SELECT DISTINCT ThreadID, ThreadIDSession FROM thread;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetThreadTransactiontUpmarketPriceCount` ;;
+CREATE PROCEDURE `GetThreadTransactiontUpmarketPriceCount`(IN in_param_ThreadID varchar(45), IN in_param_Price float)
+BEGIN
+	DECLARE declared_in_param_ThreadID CHAR(45);
+	DECLARE declared_in_param_Price float;
+    SET declared_in_param_ThreadID = in_param_ThreadID;
+    SET declared_in_param_Price = in_param_Price;
+	SELECT count(*) AS `count`
+	FROM `thread`
+	WHERE (`thread`.`Price` < declared_in_param_Price
+	   AND `thread`.`ThreadID` = declared_in_param_ThreadID);
+END ;;
+
+DROP PROCEDURE IF EXISTS `SaveGlobal` ;;
+CREATE PROCEDURE `SaveGlobal`(in_Profit float, in_ProfitNet float, in_ProfitPct float, in_TransactTime bigint)
+BEGIN
+INSERT INTO global (Profit, ProfitNet, ProfitPct, TransactTime)
+VALUES (in_Profit, in_ProfitNet, in_ProfitPct, in_TransactTime);
+END ;;
+
+DROP PROCEDURE IF EXISTS `SaveOrder` ;;
+CREATE PROCEDURE `SaveOrder`(ClientOrderId varchar(45), CummulativeQuoteQty float, ExecutedQuantity float, OrderID bigint, OrderIDSource bigint, Price float, Side varchar(45), Status varchar(45), Symbol varchar(45), TransactTime bigint, ThreadID varchar(45), ThreadIDSession varchar(45))
+BEGIN
+INSERT INTO orders (ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime, ThreadID, ThreadIDSession)
+VALUES (ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime, ThreadID, ThreadIDSession);
+END ;;
+
+DROP PROCEDURE IF EXISTS `SaveSession` ;;
+CREATE PROCEDURE `SaveSession`(in_ThreadID varchar(45), in_ThreadIDSession varchar(45), in_Exchange varchar(45), in_FiatSymbol varchar(45), in_FiatFunds float, in_DiffTotal float, in_Status tinyint(1))
+BEGIN
+INSERT INTO session (ThreadID, ThreadIDSession, Exchange, FiatSymbol, FiatFunds, DiffTotal, Status)
+VALUES (in_ThreadID, in_ThreadIDSession, in_Exchange, in_FiatSymbol, in_FiatFunds, in_DiffTotal, in_Status);
+END ;;
+
+DROP PROCEDURE IF EXISTS `SaveThreadTransaction` ;;
+CREATE PROCEDURE `SaveThreadTransaction`(ThreadID varchar(45), ThreadIDSession varchar(45), OrderID bigint, CummulativeQuoteQty float, Price float, ExecutedQuantity float)
+BEGIN
+INSERT INTO thread (ThreadID, ThreadIDSession, OrderID, CummulativeQuoteQty, Price, ExecutedQuantity)
+VALUES (ThreadID, ThreadIDSession, OrderID, CummulativeQuoteQty, Price, ExecutedQuantity);
+END ;;
+
+DROP PROCEDURE IF EXISTS `UpdateGlobal` ;;
+CREATE PROCEDURE `UpdateGlobal`(in_Profit float, in_ProfitNet float, in_ProfitPct float, in_TransactTime bigint)
+BEGIN
+SET SQL_SAFE_UPDATES = 0;
+UPDATE global 
+SET 
+    Profit = in_Profit,
+    ProfitNet = in_ProfitNet,
+    ProfitPct = in_ProfitPct,
+    TransactTime = in_TransactTime
+WHERE
+    ID = 1;
+SET SQL_SAFE_UPDATES = 1;
+END ;;
+
+DROP PROCEDURE IF EXISTS `UpdateOrder` ;;
+CREATE PROCEDURE `UpdateOrder`(in_OrderID bigint, CummulativeQuoteQty float, ExecutedQuantity float, Price float, Status varchar(45))
+BEGIN
+SET SQL_SAFE_UPDATES = 0;
+UPDATE orders
+SET  CummulativeQuoteQty = CummulativeQuoteQty,
+	ExecutedQuantity = ExecutedQuantity,
+    Price = Price,
+    Status = Status
+WHERE OrderID = in_OrderID;
+SET SQL_SAFE_UPDATES = 1;
+END ;;
+
+DROP PROCEDURE IF EXISTS `UpdateSession` ;;
+CREATE PROCEDURE `UpdateSession`(in_ThreadID varchar(45), in_ThreadIDSession varchar(45), in_Exchange varchar(45), in_FiatSymbol varchar(45), in_FiatFunds float, in_DiffTotal float, in_Status tinyint(1))
+BEGIN
+SET SQL_SAFE_UPDATES = 0;
+	UPDATE `session` 
+SET 
+    `session`.`FiatFunds` = in_FiatFunds,
+    `session`.`DiffTotal` = in_DiffTotal,
+    `session`.`Status` = in_Status
+WHERE
+    `session`.`ThreadID` = in_ThreadID;
+SET SQL_SAFE_UPDATES = 1;
+END ;;
+
+DELIMITER ;
@@ -0,0 +1,146 @@
+-- Store prices, quantities, funds and profit as exact DECIMAL instead of single precision FLOAT.
+-- Existing FLOAT values are converted as stored, procedures take DECIMAL parameters.
+
+ALTER TABLE `global`
+  MODIFY `Profit` DECIMAL(24,8) NOT NULL,
+  MODIFY `ProfitNet` DECIMAL(24,8) NOT NULL,
+  MODIFY `ProfitPct` DECIMAL(24,8) NOT NULL;
+
+ALTER TABLE `orders`
+  MODIFY `CummulativeQuoteQty` DECIMAL(24,8) NOT NULL,
+  MODIFY `ExecutedQuantity` DECIMAL(24,8) NOT NULL,
+  MODIFY `Price` DECIMAL(24,8) NOT NULL;
+
+ALTER TABLE `session`
+  MODIFY `FiatFunds` DECIMAL(24,8) NOT NULL,
+  MODIFY `DiffTotal` DECIMAL(24,8) NOT NULL;
+
+ALTER TABLE `thread`
+  MODIFY `CummulativeQuoteQty` DECIMAL(24,8) NOT NULL,
+  MODIFY `Price` DECIMAL(24,8) NOT NULL,
+  MODIFY `ExecutedQuantity` DECIMAL(24,8) NOT NULL;
+
+DELIMITER ;;
+DROP PROCEDURE IF EXISTS `GetThreadTransactionByPrice` ;;
+CREATE PROCEDURE `GetThreadTransactionByPrice`(IN in_param_ThreadID varchar(45), IN in_param_Price DECIMAL(24,8))
+BEGIN
+	DECLARE declared_in_param_ThreadID CHAR(50);
+	DECLARE declared_in_param_Price DECIMAL(24,8);
+    SET declared_in_param_ThreadID = in_param_ThreadID;
+    SET declared_in_param_Price = in_param_Price;
+	SELECT `thread`.`CummulativeQuoteQty` AS `CummulativeQuoteQty`, `thread`.`OrderID` AS `OrderID`, `thread`.`Price` AS `Price`, `thread`.`ExecutedQuantity` AS `ExecutedQuantity`, `Orders`.`TransactTime` AS `TransactTime`
+	FROM `thread`
+	LEFT JOIN `orders` `Orders` ON `thread`.`OrderID` = `Orders`.`OrderID`
+	WHERE (`thread`.`ThreadID` = declared_in_param_ThreadID
+	   AND `thread`.`Price` < declared_in_param_Price)
+	ORDER BY `thread`.`Price` ASC
+	LIMIT 1;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetThreadTransactionByPriceHigher` ;;
+CREATE PROCEDURE `GetThreadTransactionByPriceHigher`(IN in_param_ThreadID varchar(45), IN in_param_Price DECIMAL(24,8))
+BEGIN
+	DECLARE declared_in_param_ThreadID CHAR(50);
+	DECLARE declared_in_param_Price DECIMAL(24,8);
+    SET declared_in_param_ThreadID = in_param_ThreadID;
+    SET declared_in_param_Price = in_param_Price;
+	SELECT 
+    `thread`.`CummulativeQuoteQty` AS `CummulativeQuoteQty`,
+    `thread`.`OrderID` AS `OrderID`,
+    `thread`.`Price` AS `Price`,
+    `thread`.`ExecutedQuantity` AS `ExecutedQuantity`,
+    `Orders`.`TransactTime` AS `TransactTime`
+FROM
+    `thread`
+        LEFT JOIN
+    `orders` `Orders` ON `thread`.`OrderID` = `Orders`.`OrderID`
+WHERE
+    (`thread`.`ThreadID` = declared_in_param_ThreadID
+        AND `thread`.`Price` > declared_in_param_Price)
+ORDER BY `thread`.`Price` DESC
+LIMIT 1;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetThreadTransactiontUpmarketPriceCount` ;;
+CREATE PROCEDURE `GetThreadTransactiontUpmarketPriceCount`(IN in_param_ThreadID varchar(45), IN in_param_Price DECIMAL(24,8))
+BEGIN
+	DECLARE declared_in_param_ThreadID CHAR(45);
+	DECLARE declared_in_param_Price DECIMAL(24,8);
+    SET declared_in_param_ThreadID = in_param_ThreadID;
+    SET declared_in_param_Price = in_param_Price;
+	SELECT count(*) AS `count`
+	FROM `thread`
+	WHERE (`thread`.`Price` < declared_in_param_Price
+	   AND `thread`.`ThreadID` = declared_in_param_ThreadID);
+END ;;
+
+DROP PROCEDURE IF EXISTS `SaveGlobal` ;;
+CREATE PROCEDURE `SaveGlobal`(in_Profit DECIMAL(24,8), in_ProfitNet DECIMAL(24,8), in_ProfitPct DECIMAL(24,8), in_TransactTime bigint)
+BEGIN
+INSERT INTO global (Profit, ProfitNet, ProfitPct, TransactTime)
+VALUES (in_Profit, in_ProfitNet, in_ProfitPct, in_TransactTime);
+END ;;
+
+DROP PROCEDURE IF EXISTS `SaveOrder` ;;
+CREATE PROCEDURE `SaveOrder`(ClientOrderId varchar(45), CummulativeQuoteQty DECIMAL(24,8), ExecutedQuantity DECIMAL(24,8), OrderID bigint, OrderIDSource bigint, Price DECIMAL(24,8), Side varchar(45), Status varchar(45), Symbol varchar(45), TransactTime bigint, ThreadID varchar(45), ThreadIDSession varchar(45))
+BEGIN
+INSERT INTO orders (ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime, ThreadID, ThreadIDSession)
+VALUES (ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime, ThreadID, ThreadIDSession);
+END ;;
+
+DROP PROCEDURE IF EXISTS `SaveSession` ;;
+CREATE PROCEDURE `SaveSession`(in_ThreadID varchar(45), in_ThreadIDSession varchar(45), in_Exchange varchar(45), in_FiatSymbol varchar(45), in_FiatFunds DECIMAL(24,8), in_DiffTotal DECIMAL(24,8), in_Status tinyint(1))
+BEGIN
+INSERT INTO session (ThreadID, ThreadIDSession, Exchange, FiatSymbol, FiatFunds, DiffTotal, Status)
+VALUES (in_ThreadID, in_ThreadIDSession, in_Exchange, in_FiatSymbol, in_FiatFunds, in_DiffTotal, in_Status);
+END ;;
+
+DROP PROCEDURE IF EXISTS `SaveThreadTransaction` ;;
+CREATE PROCEDURE `SaveThreadTransaction`(ThreadID varchar(45), ThreadIDSession varchar(45), OrderID bigint, CummulativeQuoteQty DECIMAL(24,8), Price DECIMAL(24,8), ExecutedQuantity DECIMAL(24,8))
+BEGIN
+INSERT INTO thread (ThreadID, ThreadIDSession, OrderID, CummulativeQuoteQty, Price, ExecutedQuantity)
+VALUES (ThreadID, ThreadIDSession, OrderID, CummulativeQuoteQty, Price, ExecutedQuantity);
+END ;;
+
+DROP PROCEDURE IF EXISTS `UpdateGlobal` ;;
+CREATE PROCEDURE `UpdateGlobal`(in_Profit DECIMAL(24,8), in_ProfitNet DECIMAL(24,8), in_ProfitPct DECIMAL(24,8), in_TransactTime bigint)
+BEGIN
+SET SQL_SAFE_UPDATES = 0;
+UPDATE global 
+SET 
+    Profit = in_Profit,
+    ProfitNet = in_ProfitNet,
+    ProfitPct = in_ProfitPct,
+    TransactTime = in_TransactTime
+WHERE
+    ID = 1;
+SET SQL_SAFE_UPDATES = 1;
+END ;;
+
+DROP PROCEDURE IF EXISTS `UpdateOrder` ;;
+CREATE PROCEDURE `UpdateOrder`(in_OrderID bigint, CummulativeQuoteQty DECIMAL(24,8), ExecutedQuantity DECIMAL(24,8), Price DECIMAL(24,8), Status varchar(45))
+BEGIN
+SET SQL_SAFE_UPDATES = 0;
+UPDATE orders
+SET  CummulativeQuoteQty = CummulativeQuoteQty,
+	ExecutedQuantity = ExecutedQuantity,
+    Price = Price,
+    Status = Status
+WHERE OrderID = in_OrderID;
+SET SQL_SAFE_UPDATES = 1;
+END ;;
+
+DROP PROCEDURE IF EXISTS `UpdateSession` ;;
+CREATE PROCEDURE `UpdateSession`(in_ThreadID varchar(45), in_ThreadIDSession varchar(45), in_Exchange varchar(45), in_FiatSymbol varchar(45), in_FiatFunds DECIMAL(24,8), in_DiffTotal DECIMAL(24,8), in_Status tinyint(1))
+BEGIN
+SET SQL_SAFE_UPDATES = 0;
+	UPDATE `session` 
+SET 
+    `session`.`FiatFunds` = in_FiatFunds,
+    `session`.`DiffTotal` = in_DiffTotal,
+    `session`.`Status` = in_Status
+WHERE
+    `session`.`ThreadID` = in_ThreadID;
+SET SQL_SAFE_UPDATES = 1;
+END ;;
+DELIMITER ;
@@ -0,0 +1,2 @@
+DROP PROCEDURE IF EXISTS `GetThreadTransactionMissing`;
+DROP PROCEDURE IF EXISTS `GetThreadTransactionSold`;
@@ -0,0 +1,27 @@
+-- Procedures used by the startup check for order sequences left half-written by a crash.
+
+DELIMITER ;;
+DROP PROCEDURE IF EXISTS `GetThreadTransactionMissing` ;;
+CREATE PROCEDURE `GetThreadTransactionMissing`(IN in_param_ThreadID varchar(45))
+BEGIN
+SELECT b.OrderID, b.CummulativeQuoteQty, b.Price, b.ExecutedQuantity
+FROM orders b
+WHERE b.ThreadID = in_param_ThreadID
+	AND b.Side = 'BUY'
+	AND b.Status IN ('FILLED', 'PARTIALLY_FILLED')
+	AND NOT EXISTS (SELECT 1 FROM thread t WHERE t.OrderID = b.OrderID)
+	AND NOT EXISTS (SELECT 1 FROM orders s WHERE s.OrderIDSource = b.OrderID AND s.Side = 'SELL' AND s.Status <> 'CANCELED')
+ORDER BY b.TransactTime ASC;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetThreadTransactionSold` ;;
+CREATE PROCEDURE `GetThreadTransactionSold`(IN in_param_ThreadID varchar(45))
+BEGIN
+SELECT s.OrderID, s.OrderIDSource
+FROM thread t
+INNER JOIN orders s ON s.OrderIDSource = t.OrderID
+WHERE t.ThreadID = in_param_ThreadID
+	AND s.Side = 'SELL'
+	AND s.Status = 'FILLED';
+END ;;
+DELIMITER ;
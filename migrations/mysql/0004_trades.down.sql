@@ -0,0 +1,3 @@
+DROP PROCEDURE IF EXISTS `SaveTrade`;
+DROP PROCEDURE IF EXISTS `GetTrades`;
+DROP TABLE IF EXISTS `trades`;
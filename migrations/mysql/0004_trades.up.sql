@@ -0,0 +1,48 @@
+-- Trades ledger with one row per closed round trip (BUY and the SELL that closed it).
+
+CREATE TABLE IF NOT EXISTS `trades` (
+  `ID` int NOT NULL AUTO_INCREMENT,
+  `ThreadID` varchar(45) NOT NULL,
+  `ThreadIDSession` varchar(45) NOT NULL,
+  `Symbol` varchar(45) NOT NULL,
+  `BuyOrderID` bigint NOT NULL,
+  `SellOrderID` bigint NOT NULL,
+  `Quantity` DECIMAL(24,8) NOT NULL,
+  `EntryPrice` DECIMAL(24,8) NOT NULL,
+  `ExitPrice` DECIMAL(24,8) NOT NULL,
+  `EntryQuote` DECIMAL(24,8) NOT NULL,
+  `ExitQuote` DECIMAL(24,8) NOT NULL,
+  `Fees` DECIMAL(24,8) NOT NULL,
+  `Profit` DECIMAL(24,8) NOT NULL,
+  `ProfitPct` DECIMAL(24,8) NOT NULL,
+  `EntryTime` bigint NOT NULL,
+  `ExitTime` bigint NOT NULL,
+  `HoldingTime` bigint NOT NULL,
+  `ExitReason` varchar(45) NOT NULL,
+  PRIMARY KEY (`ID`),
+  UNIQUE KEY `SellOrderID_UNIQUE` (`SellOrderID`),
+  KEY `trades_idx_exittime` (`ExitTime`),
+  KEY `trades_idx_threadid_exittime` (`ThreadID`, `ExitTime`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DELIMITER ;;
+DROP PROCEDURE IF EXISTS `SaveTrade` ;;
+CREATE PROCEDURE `SaveTrade`(in_ThreadID varchar(45), in_ThreadIDSession varchar(45), in_Symbol varchar(45), in_BuyOrderID bigint, in_SellOrderID bigint, in_Quantity DECIMAL(24,8), in_EntryPrice DECIMAL(24,8), in_ExitPrice DECIMAL(24,8), in_EntryQuote DECIMAL(24,8), in_ExitQuote DECIMAL(24,8), in_Fees DECIMAL(24,8), in_Profit DECIMAL(24,8), in_ProfitPct DECIMAL(24,8), in_EntryTime bigint, in_ExitTime bigint, in_HoldingTime bigint, in_ExitReason varchar(45))
+BEGIN
+INSERT INTO trades (ThreadID, ThreadIDSession, Symbol, BuyOrderID, SellOrderID, Quantity, EntryPrice, ExitPrice, EntryQuote, ExitQuote, Fees, Profit, ProfitPct, EntryTime, ExitTime, HoldingTime, ExitReason)
+VALUES (in_ThreadID, in_ThreadIDSession, in_Symbol, in_BuyOrderID, in_SellOrderID, in_Quantity, in_EntryPrice, in_ExitPrice, in_EntryQuote, in_ExitQuote, in_Fees, in_Profit, in_ProfitPct, in_EntryTime, in_ExitTime, in_HoldingTime, in_ExitReason);
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetTrades` ;;
+CREATE PROCEDURE `GetTrades`(IN in_ThreadID varchar(45), IN in_From bigint, IN in_To bigint, IN in_ExitReason varchar(45), IN in_Limit int)
+BEGIN
+SELECT ThreadID, ThreadIDSession, Symbol, BuyOrderID, SellOrderID, Quantity, EntryPrice, ExitPrice, EntryQuote, ExitQuote, Fees, Profit, ProfitPct, EntryTime, ExitTime, HoldingTime, ExitReason
+FROM trades
+WHERE (in_ThreadID = '' OR ThreadID = in_ThreadID)
+	AND (in_From = 0 OR ExitTime >= in_From)
+	AND (in_To = 0 OR ExitTime < in_To)
+	AND (in_ExitReason = '' OR ExitReason = in_ExitReason)
+ORDER BY ExitTime DESC
+LIMIT in_Limit;
+END ;;
+DELIMITER ;
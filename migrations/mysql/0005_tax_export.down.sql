@@ -0,0 +1,2 @@
+DROP PROCEDURE IF EXISTS `GetOrderTransactionExecuted`;
+DROP INDEX `orders_idx_transacttime` ON `orders`;
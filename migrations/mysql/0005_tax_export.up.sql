@@ -0,0 +1,15 @@
+-- Procedure used by the tax and accounting export to match disposals with acquisitions.
+
+CREATE INDEX `orders_idx_transacttime` ON `orders` (`TransactTime`);
+
+DELIMITER ;;
+DROP PROCEDURE IF EXISTS `GetOrderTransactionExecuted` ;;
+CREATE PROCEDURE `GetOrderTransactionExecuted`(IN in_Before bigint)
+BEGIN
+SELECT ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime
+FROM orders
+WHERE ExecutedQuantity > 0
+	AND (in_Before = 0 OR TransactTime < in_Before)
+ORDER BY TransactTime ASC, OrderID ASC;
+END ;;
+DELIMITER ;
@@ -0,0 +1,4 @@
+DROP PROCEDURE IF EXISTS `SaveEquity`;
+DROP PROCEDURE IF EXISTS `GetEquity`;
+DROP PROCEDURE IF EXISTS `GetTradeStats`;
+DROP TABLE IF EXISTS `equity`;
@@ -0,0 +1,41 @@
+-- Daily equity snapshots used by the performance analytics. ThreadID is empty for the snapshot across all threads.
+
+CREATE TABLE IF NOT EXISTS `equity` (
+  `ThreadID` varchar(45) NOT NULL,
+  `Date` char(10) NOT NULL,
+  `Realized` DECIMAL(24,8) NOT NULL,
+  `Unrealized` DECIMAL(24,8) NOT NULL,
+  `Equity` DECIMAL(24,8) NOT NULL,
+  PRIMARY KEY (`ThreadID`, `Date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DELIMITER ;;
+DROP PROCEDURE IF EXISTS `SaveEquity` ;;
+CREATE PROCEDURE `SaveEquity`(in_ThreadID varchar(45), in_Date char(10), in_Realized DECIMAL(24,8), in_Unrealized DECIMAL(24,8), in_Equity DECIMAL(24,8))
+BEGIN
+INSERT INTO equity (ThreadID, Date, Realized, Unrealized, Equity)
+VALUES (in_ThreadID, in_Date, in_Realized, in_Unrealized, in_Equity)
+ON DUPLICATE KEY UPDATE Realized = in_Realized, Unrealized = in_Unrealized, Equity = in_Equity;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetEquity` ;;
+CREATE PROCEDURE `GetEquity`(IN in_ThreadID varchar(45))
+BEGIN
+SELECT ThreadID, Date, Realized, Unrealized, Equity
+FROM equity
+WHERE ThreadID = in_ThreadID
+ORDER BY Date ASC;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetTradeStats` ;;
+CREATE PROCEDURE `GetTradeStats`(IN in_ThreadID varchar(45))
+BEGIN
+SELECT COUNT(*),
+	COALESCE(SUM(CASE WHEN Profit > 0 THEN 1 ELSE 0 END), 0),
+	COALESCE(SUM(CASE WHEN Profit > 0 THEN Profit ELSE 0 END), 0),
+	COALESCE(-SUM(CASE WHEN Profit <= 0 THEN Profit ELSE 0 END), 0),
+	COALESCE(AVG(HoldingTime), 0)
+FROM trades
+WHERE (in_ThreadID = '' OR ThreadID = in_ThreadID);
+END ;;
+DELIMITER ;
@@ -0,0 +1,140 @@
+-- Archived orders are moved back to the orders table, daily aggregates are dropped and the
+-- procedures reading orders are restored.
+
+INSERT IGNORE INTO `orders` (ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime, ThreadID, ThreadIDSession)
+SELECT ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime, ThreadID, ThreadIDSession FROM `orders_archive`;
+
+DELIMITER ;;
+DROP PROCEDURE IF EXISTS `ArchiveOrders` ;;
+
+DROP PROCEDURE IF EXISTS `GetProfit` ;;
+CREATE PROCEDURE `GetProfit`()
+BEGIN
+SELECT
+        SUM(`source`.`Profit`) AS `profit`,
+        SUM(`source`.`Profit`) + (`source`.`Diff`) AS `netprofit`,
+        AVG(`source`.`Percentage`) AS `avg` 
+    FROM
+        (SELECT
+            `orders`.`Side` AS `Side`,
+            `Orders`.`Side` AS `Orders__Side`,
+            `orders`.`Status` AS `Status`,
+            `Orders`.`Status` AS `Orders__Status`,
+            `orders`.`ThreadID` AS `ThreadID`,
+            `Orders`.`CummulativeQuoteQty` AS `Orders__CummulativeQuoteQty`,
+            `orders`.`CummulativeQuoteQty` AS `CummulativeQuoteQty`,
+            (`Orders`.`CummulativeQuoteQty` - `orders`.`CummulativeQuoteQty`) AS `Profit`,
+            ((`Orders`.`CummulativeQuoteQty` - `orders`.`CummulativeQuoteQty`) / CASE 
+                WHEN `Orders`.`CummulativeQuoteQty` = 0 THEN NULL 
+                ELSE `Orders`.`CummulativeQuoteQty` END) AS `Percentage`,
+(SELECT
+    sum(`session`.`DiffTotal`) AS `sum` 
+FROM
+    `session`) AS `Diff` 
+FROM
+`orders` 
+INNER JOIN
+`orders` `Orders` 
+    ON `orders`.`OrderID` = `Orders`.`OrderIDSource` 
+WHERE
+(
+    `orders`.`Side` = 'BUY'
+) 
+AND (
+    `orders`.`Status` = 'FILLED'
+)
+) `source` 
+WHERE
+(
+1 = 1 
+AND `source`.`Orders__Side` = 'SELL' 
+AND 1 = 1 
+AND `source`.`Orders__Status` = 'FILLED'
+);
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetProfitByThreadID` ;;
+CREATE PROCEDURE `GetProfitByThreadID`(IN in_param_ThreadID varchar(45))
+BEGIN
+DECLARE declared_in_param_ThreadID CHAR(50);
+    SET declared_in_param_ThreadID = in_param_ThreadID;
+SELECT 
+    SUM(`source`.`Profit`) + (`source`.`Diff`) AS `sum`,
+    AVG(`source`.`Percentage`) AS `avg`
+FROM
+    (SELECT 
+        `orders`.`Side` AS `Side`,
+            `Orders`.`Side` AS `Orders__Side`,
+            `orders`.`Status` AS `Status`,
+            `Orders`.`Status` AS `Orders__Status`,
+            `orders`.`ThreadID` AS `ThreadID`,
+            `Orders`.`CummulativeQuoteQty` AS `Orders__CummulativeQuoteQty`,
+            `orders`.`CummulativeQuoteQty` AS `CummulativeQuoteQty`,
+            (`Orders`.`CummulativeQuoteQty` - `orders`.`CummulativeQuoteQty`) AS `Profit`,
+            ((`Orders`.`CummulativeQuoteQty` - `orders`.`CummulativeQuoteQty`) / CASE
+                WHEN `Orders`.`CummulativeQuoteQty` = 0 THEN NULL
+                ELSE `Orders`.`CummulativeQuoteQty`
+            END) AS `Percentage`,
+            (SELECT 
+                    SUM(`session`.`DiffTotal`) AS `sum`
+                FROM
+                    `session`
+                WHERE
+                    `session`.`ThreadID` = declared_in_param_ThreadID) AS `Diff`
+    FROM
+        `orders`
+    INNER JOIN `orders` `Orders` ON `orders`.`OrderID` = `Orders`.`OrderIDSource`) `source`
+WHERE
+    (`source`.`Side` = 'BUY'
+        AND `source`.`Orders__Side` = 'SELL'
+        AND `source`.`Status` = 'FILLED'
+        AND `source`.`Orders__Status` = 'FILLED'
+        AND `source`.`ThreadID` = declared_in_param_ThreadID);
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetOrderTransactionExecuted` ;;
+CREATE PROCEDURE `GetOrderTransactionExecuted`(IN in_Before bigint)
+BEGIN
+SELECT ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime
+FROM orders
+WHERE ExecutedQuantity > 0
+	AND (in_Before = 0 OR TransactTime < in_Before)
+ORDER BY TransactTime ASC, OrderID ASC;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetOrderTransactionCount` ;;
+CREATE PROCEDURE `GetOrderTransactionCount`(IN in_param_ThreadID varchar(45), IN in_param_Side varchar(45), IN in_param_Minutes int)
+BEGIN
+	DECLARE declared_in_param_ThreadID CHAR(45);
+    DECLARE declared_in_param_Side CHAR(45);
+    DECLARE declared_in_param_Minutes int;
+    SET declared_in_param_ThreadID = in_param_ThreadID;
+    SET declared_in_param_Side = in_param_Side;
+    SET declared_in_param_Minutes = in_param_Minutes;
+SELECT COALESCE(count(*),0) AS `count`
+FROM `orders`
+WHERE (`orders`.`Side` = declared_in_param_Side
+   AND `orders`.`Status` = 'FILLED' AND str_to_date(date_format(CAST(from_unixtime((`orders`.`TransactTime` / 1000)) AS DATETIME), '%Y-%m-%d %H:%i'), '%Y-%m-%d %H:%i') BETWEEN str_to_date(date_format(CAST(date_add(now(6), INTERVAL declared_in_param_Minutes minute) AS DATETIME), '%Y-%m-%d %H:%i'), '%Y-%m-%d %H:%i') AND str_to_date(date_format(CAST(now(6) AS DATETIME), '%Y-%m-%d %H:%i'), '%Y-%m-%d %H:%i') AND `orders`.`ThreadID` = declared_in_param_ThreadID);
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetLastOrderTransactionPrice` ;;
+CREATE PROCEDURE `GetLastOrderTransactionPrice`(IN in_param_ThreadID varchar(45), IN in_param_Side varchar(45))
+BEGIN
+	DECLARE declared_in_param_ThreadID CHAR(45);
+    DECLARE declared_in_param_Side CHAR(45);
+    SET declared_in_param_ThreadID = in_param_ThreadID;
+    SET declared_in_param_Side = in_param_Side;
+    SELECT `orders`.`Price` AS `Price`
+	FROM `orders`
+	WHERE (`orders`.`ThreadID` = declared_in_param_ThreadID
+		AND `orders`.`Side` = declared_in_param_Side AND (`orders`.`Status` <> 'CANCELED'
+		OR `orders`.`Status` IS NULL))
+	ORDER BY from_unixtime((`orders`.`TransactTime` / 1000)) DESC
+	LIMIT 1;
+END ;;
+DELIMITER ;
+
+DROP INDEX `orders_idx_threadid_transacttime` ON `orders`;
+DROP INDEX `orders_idx_threadid_side_transacttime` ON `orders`;
+DROP TABLE IF EXISTS `orders_daily`;
+DROP TABLE IF EXISTS `orders_archive`;
@@ -0,0 +1,160 @@
+-- Data retention of the orders table. Closed orders older than retention_days are moved to
+-- orders_archive, and the profit of archived round trips is rolled up into orders_daily so that
+-- profit totals don't change. Indexes serve the per thread queries of the trading loop.
+
+CREATE TABLE IF NOT EXISTS `orders_archive` (
+  `ClientOrderId` varchar(45) NOT NULL,
+  `CummulativeQuoteQty` DECIMAL(24,8) NOT NULL,
+  `ExecutedQuantity` DECIMAL(24,8) NOT NULL,
+  `OrderID` bigint NOT NULL,
+  `OrderIDSource` bigint NOT NULL,
+  `Price` DECIMAL(24,8) NOT NULL,
+  `Side` varchar(45) NOT NULL,
+  `Status` varchar(45) NOT NULL,
+  `Symbol` varchar(45) NOT NULL,
+  `TransactTime` bigint NOT NULL,
+  `ThreadID` varchar(45) NOT NULL,
+  `ThreadIDSession` varchar(45) NOT NULL,
+  PRIMARY KEY (`OrderID`),
+  KEY `orders_archive_idx_transacttime` (`TransactTime`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Day is the UTC day of the SELL in milliseconds. ProfitPct is the sum of the round trip profit ratios.
+CREATE TABLE IF NOT EXISTS `orders_daily` (
+  `Day` bigint NOT NULL,
+  `ThreadID` varchar(45) NOT NULL,
+  `Symbol` varchar(45) NOT NULL,
+  `Trades` int NOT NULL,
+  `BuyQuote` DECIMAL(24,8) NOT NULL,
+  `SellQuote` DECIMAL(24,8) NOT NULL,
+  `Profit` DECIMAL(24,8) NOT NULL,
+  `ProfitPct` DECIMAL(24,8) NOT NULL,
+  PRIMARY KEY (`Day`, `ThreadID`, `Symbol`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE INDEX `orders_idx_threadid_side_transacttime` ON `orders` (`ThreadID`, `Side`, `TransactTime`);
+CREATE INDEX `orders_idx_threadid_transacttime` ON `orders` (`ThreadID`, `TransactTime`);
+
+DELIMITER ;;
+DROP PROCEDURE IF EXISTS `ArchiveOrders` ;;
+CREATE PROCEDURE `ArchiveOrders`(IN in_Before bigint)
+BEGIN
+DECLARE archived bigint DEFAULT 0;
+DECLARE EXIT HANDLER FOR SQLEXCEPTION
+BEGIN
+	ROLLBACK;
+	DROP TEMPORARY TABLE IF EXISTS archive_pairs;
+	RESIGNAL;
+END;
+DROP TEMPORARY TABLE IF EXISTS archive_pairs;
+CREATE TEMPORARY TABLE archive_pairs (BuyOrderID bigint NOT NULL, SellOrderID bigint NOT NULL, PRIMARY KEY (SellOrderID)) ENGINE=InnoDB;
+START TRANSACTION;
+-- Round trips (BUY and the SELL that sold it), keeping open Thread transactions and the last BUY and SELL of each thread
+INSERT INTO archive_pairs (BuyOrderID, SellOrderID)
+SELECT b.OrderID, s.OrderID
+FROM orders b INNER JOIN orders s ON b.OrderID = s.OrderIDSource
+WHERE b.Side = 'BUY' AND s.Side = 'SELL' AND b.Status = 'FILLED' AND s.Status = 'FILLED'
+	AND s.TransactTime < in_Before
+	AND NOT EXISTS (SELECT 1 FROM thread t WHERE t.OrderID = b.OrderID)
+	AND b.OrderID <> (SELECT o.OrderID FROM orders o WHERE o.ThreadID = b.ThreadID AND o.Side = 'BUY' AND o.Status <> 'CANCELED' ORDER BY o.TransactTime DESC LIMIT 1)
+	AND s.OrderID <> (SELECT o.OrderID FROM orders o WHERE o.ThreadID = s.ThreadID AND o.Side = 'SELL' AND o.Status <> 'CANCELED' ORDER BY o.TransactTime DESC LIMIT 1);
+-- Daily profit aggregates, added to the aggregates of previous archivals
+INSERT INTO orders_daily (Day, ThreadID, Symbol, Trades, BuyQuote, SellQuote, Profit, ProfitPct)
+SELECT s.TransactTime - MOD(s.TransactTime, 86400000) AS ArchiveDay, b.ThreadID, b.Symbol,
+	SUM(CASE WHEN s.CummulativeQuoteQty <> 0 THEN 1 ELSE 0 END), SUM(b.CummulativeQuoteQty), SUM(s.CummulativeQuoteQty),
+	SUM(s.CummulativeQuoteQty - b.CummulativeQuoteQty), COALESCE(SUM((s.CummulativeQuoteQty - b.CummulativeQuoteQty) / NULLIF(s.CummulativeQuoteQty, 0)), 0)
+FROM archive_pairs p INNER JOIN orders b ON b.OrderID = p.BuyOrderID INNER JOIN orders s ON s.OrderID = p.SellOrderID
+GROUP BY ArchiveDay, b.ThreadID, b.Symbol
+ON DUPLICATE KEY UPDATE Trades = orders_daily.Trades + VALUES(Trades), BuyQuote = orders_daily.BuyQuote + VALUES(BuyQuote),
+	SellQuote = orders_daily.SellQuote + VALUES(SellQuote), Profit = orders_daily.Profit + VALUES(Profit),
+	ProfitPct = orders_daily.ProfitPct + VALUES(ProfitPct);
+-- Archived round trips and CANCELED orders
+INSERT INTO orders_archive (ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime, ThreadID, ThreadIDSession)
+SELECT o.ClientOrderId, o.CummulativeQuoteQty, o.ExecutedQuantity, o.OrderID, o.OrderIDSource, o.Price, o.Side, o.Status, o.Symbol, o.TransactTime, o.ThreadID, o.ThreadIDSession
+FROM orders o INNER JOIN archive_pairs p ON o.OrderID = p.BuyOrderID OR o.OrderID = p.SellOrderID;
+INSERT INTO orders_archive (ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime, ThreadID, ThreadIDSession)
+SELECT ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime, ThreadID, ThreadIDSession
+FROM orders WHERE Status = 'CANCELED' AND TransactTime < in_Before;
+DELETE o FROM orders o INNER JOIN archive_pairs p ON o.OrderID = p.BuyOrderID OR o.OrderID = p.SellOrderID;
+SET archived = ROW_COUNT();
+DELETE FROM orders WHERE Status = 'CANCELED' AND TransactTime < in_Before;
+SET archived = archived + ROW_COUNT();
+COMMIT;
+DROP TEMPORARY TABLE IF EXISTS archive_pairs;
+SELECT archived AS archived;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetProfit` ;;
+CREATE PROCEDURE `GetProfit`()
+BEGIN
+SELECT
+	SUM(`source`.`Profit`) AS `profit`,
+	SUM(`source`.`Profit`) + (SELECT SUM(`session`.`DiffTotal`) FROM `session`) AS `netprofit`,
+	SUM(`source`.`ProfitPct`) / NULLIF(SUM(`source`.`Trades`), 0) AS `avg`
+FROM (
+	SELECT b.ThreadID, s.CummulativeQuoteQty - b.CummulativeQuoteQty AS Profit,
+		(s.CummulativeQuoteQty - b.CummulativeQuoteQty) / NULLIF(s.CummulativeQuoteQty, 0) AS ProfitPct,
+		CASE WHEN s.CummulativeQuoteQty <> 0 THEN 1 ELSE 0 END AS Trades
+	FROM orders b INNER JOIN orders s ON b.OrderID = s.OrderIDSource
+	WHERE b.Side = 'BUY' AND s.Side = 'SELL' AND b.Status = 'FILLED' AND s.Status = 'FILLED'
+	UNION ALL
+	SELECT ThreadID, Profit, ProfitPct, Trades FROM orders_daily
+) `source`;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetProfitByThreadID` ;;
+CREATE PROCEDURE `GetProfitByThreadID`(IN in_param_ThreadID varchar(45))
+BEGIN
+SELECT
+	SUM(`source`.`Profit`) + (SELECT SUM(`session`.`DiffTotal`) FROM `session` WHERE `session`.`ThreadID` = in_param_ThreadID) AS `sum`,
+	SUM(`source`.`ProfitPct`) / NULLIF(SUM(`source`.`Trades`), 0) AS `avg`
+FROM (
+	SELECT s.CummulativeQuoteQty - b.CummulativeQuoteQty AS Profit,
+		(s.CummulativeQuoteQty - b.CummulativeQuoteQty) / NULLIF(s.CummulativeQuoteQty, 0) AS ProfitPct,
+		CASE WHEN s.CummulativeQuoteQty <> 0 THEN 1 ELSE 0 END AS Trades
+	FROM orders b INNER JOIN orders s ON b.OrderID = s.OrderIDSource
+	WHERE b.Side = 'BUY' AND s.Side = 'SELL' AND b.Status = 'FILLED' AND s.Status = 'FILLED' AND b.ThreadID = in_param_ThreadID
+	UNION ALL
+	SELECT Profit, ProfitPct, Trades FROM orders_daily WHERE ThreadID = in_param_ThreadID
+) `source`;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetOrderTransactionExecuted` ;;
+CREATE PROCEDURE `GetOrderTransactionExecuted`(IN in_Before bigint)
+BEGIN
+SELECT ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime
+FROM orders
+WHERE ExecutedQuantity > 0
+	AND (in_Before = 0 OR TransactTime < in_Before)
+UNION ALL
+SELECT ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime
+FROM orders_archive
+WHERE ExecutedQuantity > 0
+	AND (in_Before = 0 OR TransactTime < in_Before)
+ORDER BY TransactTime ASC, OrderID ASC;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetOrderTransactionCount` ;;
+CREATE PROCEDURE `GetOrderTransactionCount`(IN in_param_ThreadID varchar(45), IN in_param_Side varchar(45), IN in_param_Minutes int)
+BEGIN
+SELECT COUNT(*) AS `count`
+FROM `orders`
+WHERE `orders`.`ThreadID` = in_param_ThreadID
+	AND `orders`.`Side` = in_param_Side
+	AND `orders`.`Status` = 'FILLED'
+	AND `orders`.`TransactTime` >= (FLOOR(UNIX_TIMESTAMP() / 60) + in_param_Minutes) * 60000
+	AND `orders`.`TransactTime` < (FLOOR(UNIX_TIMESTAMP() / 60) + 1) * 60000;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetLastOrderTransactionPrice` ;;
+CREATE PROCEDURE `GetLastOrderTransactionPrice`(IN in_param_ThreadID varchar(45), IN in_param_Side varchar(45))
+BEGIN
+SELECT `orders`.`Price` AS `Price`
+FROM `orders`
+WHERE `orders`.`ThreadID` = in_param_ThreadID
+	AND `orders`.`Side` = in_param_Side
+	AND (`orders`.`Status` <> 'CANCELED' OR `orders`.`Status` IS NULL)
+ORDER BY `orders`.`TransactTime` DESC
+LIMIT 1;
+END ;;
+DELIMITER ;
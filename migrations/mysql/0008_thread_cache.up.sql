@@ -0,0 +1,13 @@
+-- Procedure loading the Thread transactions of a thread into the storage cache.
+
+DELIMITER ;;
+DROP PROCEDURE IF EXISTS `GetThreadTransactionAll` ;;
+CREATE PROCEDURE `GetThreadTransactionAll`(IN in_ThreadID varchar(45))
+BEGIN
+SELECT `thread`.`CummulativeQuoteQty`, `thread`.`OrderID`, `thread`.`Price`, `thread`.`ExecutedQuantity`, COALESCE(`Orders`.`TransactTime`, 0) AS `TransactTime`
+FROM `thread`
+LEFT JOIN `orders` `Orders` ON `thread`.`OrderID` = `Orders`.`OrderID`
+WHERE `thread`.`ThreadID` = in_ThreadID
+ORDER BY `thread`.`Price` ASC;
+END ;;
+DELIMITER ;
@@ -0,0 +1,3 @@
+DROP PROCEDURE IF EXISTS `AcquireLease`;
+DROP PROCEDURE IF EXISTS `ReleaseLease`;
+DROP TABLE IF EXISTS `leases`;
@@ -0,0 +1,34 @@
+-- Leader election leases. The holder renews its lease by heartbeat before Expires, and another node takes over an
+-- expired lease. Token is the fencing token, incremented each time the lease changes hands. Times are in
+-- milliseconds of the database clock, so node clocks don't need to agree.
+
+CREATE TABLE IF NOT EXISTS `leases` (
+  `Name` varchar(45) NOT NULL,
+  `Holder` varchar(45) NOT NULL,
+  `Token` bigint NOT NULL,
+  `Expires` bigint NOT NULL,
+  PRIMARY KEY (`Name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DELIMITER ;;
+DROP PROCEDURE IF EXISTS `AcquireLease` ;;
+CREATE PROCEDURE `AcquireLease`(IN in_Name varchar(45), IN in_Holder varchar(45), IN in_Duration bigint)
+BEGIN
+DECLARE now_ms bigint DEFAULT ROUND(UNIX_TIMESTAMP(NOW(3)) * 1000);
+-- ON DUPLICATE KEY UPDATE assignments see the columns assigned before them: Token and Holder read the previous
+-- Expires, and Expires reads the new Holder.
+INSERT INTO leases (Name, Holder, Token, Expires)
+VALUES (in_Name, in_Holder, 1, now_ms + in_Duration)
+ON DUPLICATE KEY UPDATE
+	Token = IF(Expires < now_ms, Token + 1, Token),
+	Holder = IF(Expires < now_ms, in_Holder, Holder),
+	Expires = IF(Holder = in_Holder, now_ms + in_Duration, Expires);
+SELECT Name, Holder, Token, Expires FROM leases WHERE Name = in_Name;
+END ;;
+
+DROP PROCEDURE IF EXISTS `ReleaseLease` ;;
+CREATE PROCEDURE `ReleaseLease`(IN in_Name varchar(45), IN in_Holder varchar(45))
+BEGIN
+UPDATE leases SET Expires = 0 WHERE Name = in_Name AND Holder = in_Holder;
+END ;;
+DELIMITER ;
@@ -0,0 +1,4 @@
+DROP PROCEDURE IF EXISTS `SaveNode`;
+DROP PROCEDURE IF EXISTS `GetNodes`;
+DROP PROCEDURE IF EXISTS `DeleteNode`;
+DROP TABLE IF EXISTS `nodes`;
@@ -0,0 +1,46 @@
+-- Cluster node registry. Each running thread saves its host, web UI port, version and symbol with a heartbeat
+-- every 10 seconds, so stale nodes can be told apart from quiet ones. Times are in milliseconds, Heartbeat and
+-- Age of the database clock so node clocks don't need to agree.
+
+CREATE TABLE IF NOT EXISTS `nodes` (
+  `ThreadID` varchar(45) NOT NULL,
+  `Hostname` varchar(255) NOT NULL,
+  `Port` varchar(10) NOT NULL,
+  `Version` varchar(45) NOT NULL,
+  `Symbol` varchar(45) NOT NULL,
+  `Started` bigint NOT NULL,
+  `Heartbeat` bigint NOT NULL,
+  PRIMARY KEY (`ThreadID`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DELIMITER ;;
+DROP PROCEDURE IF EXISTS `SaveNode` ;;
+CREATE PROCEDURE `SaveNode`(IN in_ThreadID varchar(45), IN in_Hostname varchar(255), IN in_Port varchar(10), IN in_Version varchar(45), IN in_Symbol varchar(45), IN in_Started bigint)
+BEGIN
+DECLARE now_ms bigint DEFAULT ROUND(UNIX_TIMESTAMP(NOW(3)) * 1000);
+INSERT INTO nodes (ThreadID, Hostname, Port, Version, Symbol, Started, Heartbeat)
+VALUES (in_ThreadID, in_Hostname, in_Port, in_Version, in_Symbol, in_Started, now_ms)
+ON DUPLICATE KEY UPDATE
+	Hostname = in_Hostname,
+	Port = in_Port,
+	Version = in_Version,
+	Symbol = in_Symbol,
+	Started = in_Started,
+	Heartbeat = now_ms;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetNodes` ;;
+CREATE PROCEDURE `GetNodes`()
+BEGIN
+DECLARE now_ms bigint DEFAULT ROUND(UNIX_TIMESTAMP(NOW(3)) * 1000);
+SELECT ThreadID, Hostname, Port, Version, Symbol, Started, Heartbeat, now_ms - Heartbeat AS Age
+FROM nodes
+ORDER BY Started DESC;
+END ;;
+
+DROP PROCEDURE IF EXISTS `DeleteNode` ;;
+CREATE PROCEDURE `DeleteNode`(IN in_ThreadID varchar(45))
+BEGIN
+DELETE FROM nodes WHERE ThreadID = in_ThreadID;
+END ;;
+DELIMITER ;
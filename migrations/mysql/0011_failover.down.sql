@@ -0,0 +1,4 @@
+DROP PROCEDURE IF EXISTS `GetThreadOrphans`;
+DROP PROCEDURE IF EXISTS `SaveAudit`;
+DROP PROCEDURE IF EXISTS `GetAudit`;
+DROP TABLE IF EXISTS `audit`;
@@ -0,0 +1,44 @@
+-- Audit log of actions taken by nodes on threads, i.e. the takeover of a thread from a down node. Time is in
+-- milliseconds.
+
+CREATE TABLE IF NOT EXISTS `audit` (
+  `ID` bigint NOT NULL AUTO_INCREMENT,
+  `Time` bigint NOT NULL,
+  `ThreadID` varchar(45) NOT NULL,
+  `Node` varchar(255) NOT NULL,
+  `Action` varchar(45) NOT NULL,
+  `Detail` varchar(1024) NOT NULL,
+  PRIMARY KEY (`ID`),
+  KEY `audit_idx_time` (`Time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DELIMITER ;;
+DROP PROCEDURE IF EXISTS `GetThreadOrphans` ;;
+CREATE PROCEDURE `GetThreadOrphans`(IN in_Age bigint)
+BEGIN
+DECLARE now_ms bigint DEFAULT ROUND(UNIX_TIMESTAMP(NOW(3)) * 1000);
+-- Threads with Thread transactions whose node registry heartbeat is older than in_Age, longest down first
+SELECT t.ThreadID, MAX(t.ThreadIDSession) AS ThreadIDSession, n.Hostname, n.Port, now_ms - n.Heartbeat AS Age
+FROM thread t
+INNER JOIN nodes n ON n.ThreadID = t.ThreadID
+WHERE n.Heartbeat < now_ms - in_Age
+GROUP BY t.ThreadID, n.Hostname, n.Port, n.Heartbeat
+ORDER BY n.Heartbeat ASC;
+END ;;
+
+DROP PROCEDURE IF EXISTS `SaveAudit` ;;
+CREATE PROCEDURE `SaveAudit`(IN in_Time bigint, IN in_ThreadID varchar(45), IN in_Node varchar(255), IN in_Action varchar(45), IN in_Detail varchar(1024))
+BEGIN
+INSERT INTO audit (Time, ThreadID, Node, Action, Detail)
+VALUES (in_Time, in_ThreadID, in_Node, in_Action, in_Detail);
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetAudit` ;;
+CREATE PROCEDURE `GetAudit`(IN in_Since bigint)
+BEGIN
+SELECT ID, Time, ThreadID, Node, Action, Detail
+FROM audit
+WHERE Time > in_Since
+ORDER BY Time ASC, ID ASC;
+END ;;
+DELIMITER ;
@@ -0,0 +1,4 @@
+DROP PROCEDURE IF EXISTS `SavePause`;
+DROP PROCEDURE IF EXISTS `GetPause`;
+DROP PROCEDURE IF EXISTS `DeletePause`;
+DROP TABLE IF EXISTS `pauses`;
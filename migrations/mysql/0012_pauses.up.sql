@@ -0,0 +1,39 @@
+-- Trading pause per thread, kept across restarts. Mode is nobuy (no new BUY, SELL at target) or frozen (no
+-- orders at all). Confirm requires the resume to be confirmed. Time is in milliseconds.
+
+CREATE TABLE IF NOT EXISTS `pauses` (
+  `ThreadID` varchar(45) NOT NULL,
+  `Mode` varchar(10) NOT NULL,
+  `PausedBy` varchar(255) NOT NULL,
+  `Time` bigint NOT NULL,
+  `Confirm` tinyint(1) NOT NULL DEFAULT 0,
+  PRIMARY KEY (`ThreadID`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DELIMITER ;;
+DROP PROCEDURE IF EXISTS `SavePause` ;;
+CREATE PROCEDURE `SavePause`(IN in_ThreadID varchar(45), IN in_Mode varchar(10), IN in_PausedBy varchar(255), IN in_Time bigint, IN in_Confirm tinyint(1))
+BEGIN
+INSERT INTO pauses (ThreadID, Mode, PausedBy, Time, Confirm)
+VALUES (in_ThreadID, in_Mode, in_PausedBy, in_Time, in_Confirm)
+ON DUPLICATE KEY UPDATE
+	Mode = in_Mode,
+	PausedBy = in_PausedBy,
+	Time = in_Time,
+	Confirm = in_Confirm;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetPause` ;;
+CREATE PROCEDURE `GetPause`(IN in_ThreadID varchar(45))
+BEGIN
+SELECT ThreadID, Mode, PausedBy, Time, Confirm
+FROM pauses
+WHERE ThreadID = in_ThreadID;
+END ;;
+
+DROP PROCEDURE IF EXISTS `DeletePause` ;;
+CREATE PROCEDURE `DeletePause`(IN in_ThreadID varchar(45))
+BEGIN
+DELETE FROM pauses WHERE ThreadID = in_ThreadID;
+END ;;
+DELIMITER ;
@@ -0,0 +1,2 @@
+DROP PROCEDURE IF EXISTS `GetSessions`;
+DROP PROCEDURE IF EXISTS `GetOrders`;
@@ -0,0 +1,25 @@
+-- Command line reads. GetSessions returns the threads of the session table with their open Thread transactions,
+-- GetOrders the orders of a thread (all threads when empty) most recent first.
+
+DELIMITER ;;
+DROP PROCEDURE IF EXISTS `GetSessions` ;;
+CREATE PROCEDURE `GetSessions`()
+BEGIN
+SELECT s.ThreadID, s.ThreadIDSession, s.Exchange, s.FiatSymbol, s.FiatFunds, s.DiffTotal, s.Status,
+	(SELECT COUNT(*) FROM thread t WHERE t.ThreadID = s.ThreadID) AS ThreadCount
+FROM session s
+ORDER BY s.ID ASC;
+END ;;
+
+DROP PROCEDURE IF EXISTS `GetOrders` ;;
+CREATE PROCEDURE `GetOrders`(IN in_ThreadID varchar(45), IN in_Side varchar(45), IN in_Status varchar(45), IN in_Limit int)
+BEGIN
+SELECT ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime
+FROM orders
+WHERE (in_ThreadID = '' OR ThreadID = in_ThreadID)
+	AND (in_Side = '' OR Side = in_Side)
+	AND (in_Status = '' OR Status = in_Status)
+ORDER BY TransactTime DESC, OrderID DESC
+LIMIT in_Limit;
+END ;;
+DELIMITER ;
@@ -0,0 +1,10 @@
+-- Order writes are fenced by the thread lease token: OrderTx locks the lease row and checks that the lease held
+-- by the session didn't change hands before writing.
+
+DELIMITER ;;
+DROP PROCEDURE IF EXISTS `GetLeaseForUpdate` ;;
+CREATE PROCEDURE `GetLeaseForUpdate`(IN in_Name varchar(45))
+BEGIN
+SELECT Name, Holder, Token, Expires FROM leases WHERE Name = in_Name FOR UPDATE;
+END ;;
+DELIMITER ;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS thread;
+DROP TABLE IF EXISTS session;
+DROP INDEX IF EXISTS orders_idx_side_status;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS global;
@@ -0,0 +1,47 @@
+-- Baseline schema. Columns follow the cryptopump MySQL schema.
+
+CREATE TABLE IF NOT EXISTS global (
+	ID INTEGER PRIMARY KEY AUTOINCREMENT,
+	Profit REAL NOT NULL,
+	ProfitNet REAL NOT NULL,
+	ProfitPct REAL NOT NULL,
+	TransactTime INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS orders (
+	ClientOrderId TEXT NOT NULL,
+	CummulativeQuoteQty REAL NOT NULL,
+	ExecutedQuantity REAL NOT NULL,
+	OrderID INTEGER NOT NULL PRIMARY KEY,
+	OrderIDSource INTEGER NOT NULL,
+	Price REAL NOT NULL,
+	Side TEXT NOT NULL,
+	Status TEXT NOT NULL,
+	Symbol TEXT NOT NULL,
+	TransactTime INTEGER NOT NULL,
+	ThreadID TEXT NOT NULL,
+	ThreadIDSession TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS orders_idx_side_status ON orders (Side, Status);
+
+CREATE TABLE IF NOT EXISTS session (
+	ID INTEGER PRIMARY KEY AUTOINCREMENT,
+	ThreadID TEXT NOT NULL UNIQUE,
+	ThreadIDSession TEXT NOT NULL,
+	Exchange TEXT NOT NULL,
+	FiatSymbol TEXT NOT NULL,
+	FiatFunds REAL NOT NULL,
+	DiffTotal REAL NOT NULL,
+	Status INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS thread (
+	ID INTEGER PRIMARY KEY AUTOINCREMENT,
+	ThreadID TEXT NOT NULL,
+	ThreadIDSession TEXT NOT NULL,
+	OrderID INTEGER,
+	CummulativeQuoteQty REAL NOT NULL,
+	Price REAL NOT NULL,
+	ExecutedQuantity REAL NOT NULL
+);
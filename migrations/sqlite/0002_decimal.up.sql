@@ -0,0 +1,2 @@
+-- MySQL converts FLOAT columns to DECIMAL in this version. SQLite columns are REAL, stored as
+-- 8-byte IEEE floating point with the precision of Go float64, so no conversion is needed.
@@ -0,0 +1,25 @@
+-- Trades ledger with one row per closed round trip (BUY and the SELL that closed it).
+
+CREATE TABLE IF NOT EXISTS trades (
+	ID INTEGER PRIMARY KEY AUTOINCREMENT,
+	ThreadID TEXT NOT NULL,
+	ThreadIDSession TEXT NOT NULL,
+	Symbol TEXT NOT NULL,
+	BuyOrderID INTEGER NOT NULL,
+	SellOrderID INTEGER NOT NULL UNIQUE,
+	Quantity REAL NOT NULL,
+	EntryPrice REAL NOT NULL,
+	ExitPrice REAL NOT NULL,
+	EntryQuote REAL NOT NULL,
+	ExitQuote REAL NOT NULL,
+	Fees REAL NOT NULL,
+	Profit REAL NOT NULL,
+	ProfitPct REAL NOT NULL,
+	EntryTime INTEGER NOT NULL,
+	ExitTime INTEGER NOT NULL,
+	HoldingTime INTEGER NOT NULL,
+	ExitReason TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS trades_idx_exittime ON trades (ExitTime);
+CREATE INDEX IF NOT EXISTS trades_idx_threadid_exittime ON trades (ThreadID, ExitTime);
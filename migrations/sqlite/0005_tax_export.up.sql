@@ -0,0 +1,3 @@
+-- Index used by the tax and accounting export to read orders in time order.
+
+CREATE INDEX IF NOT EXISTS orders_idx_transacttime ON orders (TransactTime);
@@ -0,0 +1,10 @@
+-- Daily equity snapshots used by the performance analytics. ThreadID is empty for the snapshot across all threads.
+
+CREATE TABLE IF NOT EXISTS equity (
+	ThreadID TEXT NOT NULL,
+	Date TEXT NOT NULL,
+	Realized REAL NOT NULL,
+	Unrealized REAL NOT NULL,
+	Equity REAL NOT NULL,
+	PRIMARY KEY (ThreadID, Date)
+);
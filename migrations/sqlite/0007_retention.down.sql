@@ -0,0 +1,9 @@
+-- Archived orders are moved back to the orders table, daily aggregates are dropped.
+
+INSERT OR IGNORE INTO orders (ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime, ThreadID, ThreadIDSession)
+SELECT ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime, ThreadID, ThreadIDSession FROM orders_archive;
+
+DROP INDEX IF EXISTS orders_idx_threadid_transacttime;
+DROP INDEX IF EXISTS orders_idx_threadid_side_transacttime;
+DROP TABLE IF EXISTS orders_daily;
+DROP TABLE IF EXISTS orders_archive;
@@ -0,0 +1,36 @@
+-- Data retention of the orders table. Closed orders older than retention_days are moved to
+-- orders_archive, and the profit of archived round trips is rolled up into orders_daily so that
+-- profit totals don't change. Indexes serve the per thread queries of the trading loop.
+
+CREATE TABLE IF NOT EXISTS orders_archive (
+	ClientOrderId TEXT NOT NULL,
+	CummulativeQuoteQty REAL NOT NULL,
+	ExecutedQuantity REAL NOT NULL,
+	OrderID INTEGER NOT NULL PRIMARY KEY,
+	OrderIDSource INTEGER NOT NULL,
+	Price REAL NOT NULL,
+	Side TEXT NOT NULL,
+	Status TEXT NOT NULL,
+	Symbol TEXT NOT NULL,
+	TransactTime INTEGER NOT NULL,
+	ThreadID TEXT NOT NULL,
+	ThreadIDSession TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS orders_archive_idx_transacttime ON orders_archive (TransactTime);
+
+-- Day is the UTC day of the SELL in milliseconds. ProfitPct is the sum of the round trip profit ratios.
+CREATE TABLE IF NOT EXISTS orders_daily (
+	Day INTEGER NOT NULL,
+	ThreadID TEXT NOT NULL,
+	Symbol TEXT NOT NULL,
+	Trades INTEGER NOT NULL,
+	BuyQuote REAL NOT NULL,
+	SellQuote REAL NOT NULL,
+	Profit REAL NOT NULL,
+	ProfitPct REAL NOT NULL,
+	PRIMARY KEY (Day, ThreadID, Symbol)
+);
+
+CREATE INDEX IF NOT EXISTS orders_idx_threadid_side_transacttime ON orders (ThreadID, Side, TransactTime);
+CREATE INDEX IF NOT EXISTS orders_idx_threadid_transacttime ON orders (ThreadID, TransactTime);
@@ -0,0 +1,9 @@
+-- Leader election leases. The holder renews its lease by heartbeat before Expires, and another node takes over an
+-- expired lease. Token is the fencing token, incremented each time the lease changes hands. Times are in milliseconds.
+
+CREATE TABLE IF NOT EXISTS leases (
+	Name TEXT NOT NULL PRIMARY KEY,
+	Holder TEXT NOT NULL,
+	Token INTEGER NOT NULL,
+	Expires INTEGER NOT NULL
+);
@@ -0,0 +1,12 @@
+-- Cluster node registry. Each running thread saves its host, web UI port, version and symbol with a heartbeat
+-- every 10 seconds, so stale nodes can be told apart from quiet ones. Times are in milliseconds.
+
+CREATE TABLE IF NOT EXISTS nodes (
+	ThreadID TEXT NOT NULL PRIMARY KEY,
+	Hostname TEXT NOT NULL,
+	Port TEXT NOT NULL,
+	Version TEXT NOT NULL,
+	Symbol TEXT NOT NULL,
+	Started INTEGER NOT NULL,
+	Heartbeat INTEGER NOT NULL
+);
@@ -0,0 +1,13 @@
+-- Audit log of actions taken by nodes on threads, i.e. the takeover of a thread from a down node. Time is in
+-- milliseconds.
+
+CREATE TABLE IF NOT EXISTS audit (
+	ID INTEGER PRIMARY KEY AUTOINCREMENT,
+	Time INTEGER NOT NULL,
+	ThreadID TEXT NOT NULL,
+	Node TEXT NOT NULL,
+	Action TEXT NOT NULL,
+	Detail TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_idx_time ON audit (Time);
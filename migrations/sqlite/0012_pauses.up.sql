@@ -0,0 +1,10 @@
+-- Trading pause per thread, kept across restarts. Mode is nobuy (no new BUY, SELL at target) or frozen (no
+-- orders at all). Confirm requires the resume to be confirmed. Time is in milliseconds.
+
+CREATE TABLE IF NOT EXISTS pauses (
+	ThreadID TEXT NOT NULL PRIMARY KEY,
+	Mode TEXT NOT NULL,
+	PausedBy TEXT NOT NULL,
+	Time INTEGER NOT NULL,
+	Confirm INTEGER NOT NULL DEFAULT 0
+);
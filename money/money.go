@@ -0,0 +1,144 @@
+package money
+
+/* This package implements the decimal arithmetic of prices, quantities, fees and profit. Order and
+session data keep float64 values, which are converted to exact decimals for arithmetic and comparisons:
+a float64 is read as the shortest decimal representing it, i.e. 0.1 and not 0.1000000000000000055.
+Results are rounded to Scale decimal places, the scale of the DECIMAL(24,8) database columns, so the
+values saved are the values computed and binary float residue doesn't accumulate in totals. */
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Scale decimal places of prices, quantities and amounts saved to the database
+const Scale = 8
+
+// Decimal is an exact decimal number. The zero value is 0.
+type Decimal struct {
+	r *big.Rat
+}
+
+// New return the shortest decimal representing value. NaN and infinities return 0.
+func New(value float64) Decimal {
+
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+
+		return Decimal{}
+
+	}
+
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(value, 'f', -1, 64))
+
+	return Decimal{r: r}
+
+}
+
+// Markup return value*(1+ratio), i.e. a target price. A negative ratio returns a price below value.
+func Markup(value float64, ratio float64) Decimal {
+
+	return New(value).Mul(New(1).Add(New(ratio)))
+
+}
+
+/* Return the rational value, 0 for the zero Decimal */
+func (d Decimal) rat() *big.Rat {
+
+	if d.r == nil {
+
+		return new(big.Rat)
+
+	}
+
+	return d.r
+
+}
+
+// Add return d+other
+func (d Decimal) Add(other Decimal) Decimal {
+
+	return Decimal{r: new(big.Rat).Add(d.rat(), other.rat())}
+
+}
+
+// Sub return d-other
+func (d Decimal) Sub(other Decimal) Decimal {
+
+	return Decimal{r: new(big.Rat).Sub(d.rat(), other.rat())}
+
+}
+
+// Mul return d*other
+func (d Decimal) Mul(other Decimal) Decimal {
+
+	return Decimal{r: new(big.Rat).Mul(d.rat(), other.rat())}
+
+}
+
+// Div return d/other, or 0 when other is 0 (i.e. the price of an order with no executed quantity)
+func (d Decimal) Div(other Decimal) Decimal {
+
+	if other.rat().Sign() == 0 {
+
+		return Decimal{}
+
+	}
+
+	return Decimal{r: new(big.Rat).Quo(d.rat(), other.rat())}
+
+}
+
+// Cmp return -1, 0 or 1 when d is lower than, equal to or greater than other
+func (d Decimal) Cmp(other Decimal) int {
+
+	return d.rat().Cmp(other.rat())
+
+}
+
+// Round return d rounded to places decimal places, halves away from zero
+func (d Decimal) Round(places int) Decimal {
+
+	r, _ := new(big.Rat).SetString(d.String(places))
+
+	return Decimal{r: r}
+
+}
+
+// Step return d rounded to the nearest multiple of step, halves away from zero, and formatted with the
+// decimal places of step. Without step Scale decimal places are used.
+func (d Decimal) Step(step float64) string {
+
+	if step <= 0 || math.IsNaN(step) || math.IsInf(step, 0) {
+
+		return d.String(Scale)
+
+	}
+
+	places := 0
+	if s := strconv.FormatFloat(step, 'f', -1, 64); strings.Contains(s, ".") {
+
+		places = len(s) - strings.Index(s, ".") - 1
+
+	}
+
+	return d.Div(New(step)).Round(0).Mul(New(step)).String(places)
+
+}
+
+// Float return d rounded to Scale decimal places as the nearest float64
+func (d Decimal) Float() float64 {
+
+	f, _ := d.Round(Scale).rat().Float64()
+
+	return f
+
+}
+
+// String return d formatted with places decimal places, halves rounded away from zero
+func (d Decimal) String(places int) string {
+
+	return d.rat().FloatString(places)
+
+}
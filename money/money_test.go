@@ -0,0 +1,76 @@
+package money
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDecimal_Step(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		value Decimal
+		step  float64
+		want  string
+	}{
+		{"exact step", New(0.0025), 0.0001, "0.0025"},
+		{"below half step", New(0.000149), 0.0001, "0.0001"},
+		{"half step rounds up", New(0.00015), 0.0001, "0.0002"}, /* 0.00015/0.0001 is 1.4999999999999998 in float64 */
+		{"half step of a quotient", New(15).Div(New(100000)), 0.0001, "0.0002"},
+		{"quotient below half step", New(100).Div(New(60000)), 0.00001, "0.00167"},
+		{"satoshi step", New(1.234567891), 0.00000001, "1.23456789"},
+		{"integer step", New(2.5), 1, "3"},
+		{"negative half step", New(-0.00015), 0.0001, "-0.0002"},
+		{"no step", New(1.0 / 3), 0, "0.33333333"},
+		{"not a number", New(math.NaN()), 0.01, "0.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.value.Step(tt.step); got != tt.want {
+				t.Errorf("Decimal.Step() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+}
+
+func TestDecimal_Float(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		value Decimal
+		want  float64
+	}{
+		{"sum without float residue", New(0.1).Add(New(0.2)), 0.3},
+		{"average price", New(100.12345678).Div(New(0.002)), 50061.72839},
+		{"rounded to scale", New(2).Div(New(3)), 0.66666667},
+		{"half rounded away from zero", New(0.000000005), 0.00000001},
+		{"fee", New(100.1).Add(New(120.2)).Mul(New(0.00075)), 0.16522500},
+		{"division by zero", New(100).Div(New(0)), 0},
+		{"markup", Markup(50000, 0.0125), 50625},
+		{"markdown", Markup(50000, -0.1), 45000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.value.Float(); got != tt.want {
+				t.Errorf("Decimal.Float() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+}
+
+func TestDecimal_Cmp(t *testing.T) {
+
+	/* 60000 * 1.001 is 60059.99999999999 in float64 */
+	if got := New(60060).Cmp(Markup(60000, 0.001)); got != 0 {
+		t.Errorf("Decimal.Cmp() = %v, want 0", got)
+	}
+
+	if got := New(60059.99).Cmp(Markup(60000, 0.001)); got != -1 {
+		t.Errorf("Decimal.Cmp() = %v, want -1", got)
+	}
+
+}
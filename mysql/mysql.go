@@ -1,6 +1,7 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
 	"flag"
 	"fmt"
@@ -17,10 +18,7 @@ import (
 
 // DBInit export
 /* This function initializes GCP mysql database connectivity */
-func DBInit() *sql.DB {
-
-	var db *sql.DB
-	var err error
+func DBInit() (db *sql.DB, err error) {
 
 	// If the optional DB_TCP_HOST environment variable is set, it contains
 	// the IP address and port number of a TCP connection pool to be created,
@@ -28,19 +26,11 @@ func DBInit() *sql.DB {
 	// connection pool will be created instead.
 	if os.Getenv("DB_TCP_HOST") != "" {
 
-		if db, err = InitTCPConnectionPool(); err != nil {
-
-			defer os.Exit(1)
-
-		}
+		db, err = InitTCPConnectionPool()
 
 	} else {
 
-		if db, err = InitSocketConnectionPool(); err != nil {
-
-			defer os.Exit(1)
-
-		}
+		db, err = InitSocketConnectionPool()
 
 	}
 
@@ -58,7 +48,7 @@ func DBInit() *sql.DB {
 		}
 	}()
 
-	return db
+	return db, err
 
 }
 
@@ -156,6 +146,7 @@ func InitTCPConnectionPool() (*sql.DB, error) {
 
 // SaveOrder Save order to database
 func SaveOrder(
+	ctx context.Context,
 	sessionData *types.Session,
 	order *types.Order,
 	orderIDSource int64, /* OrderIDSource */
@@ -167,7 +158,7 @@ func SaveOrder(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.SaveOrder(?,?,?,?,?,?,?,?,?,?,?,?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.SaveOrder(?,?,?,?,?,?,?,?,?,?,?,?)",
 		order.ClientOrderID,
 		order.CumulativeQuoteQuantity,
 		order.ExecutedQuantity,
@@ -205,6 +196,7 @@ func SaveOrder(
 
 // UpdateOrder Update order
 func UpdateOrder(
+	ctx context.Context,
 	sessionData *types.Session,
 	OrderID int64,
 	CumulativeQuoteQuantity float64,
@@ -218,7 +210,7 @@ func UpdateOrder(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.UpdateOrder(?,?,?,?,?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.UpdateOrder(?,?,?,?,?)",
 		OrderID,
 		CumulativeQuoteQuantity,
 		ExecutedQuantity,
@@ -249,6 +241,7 @@ func UpdateOrder(
 
 // UpdateSession Update existing session on Session table
 func UpdateSession(
+	ctx context.Context,
 	configData *types.Config,
 	sessionData *types.Session) (err error) {
 
@@ -258,7 +251,7 @@ func UpdateSession(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.UpdateSession(?,?,?,?,?,?,?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.UpdateSession(?,?,?,?,?,?,?)",
 		sessionData.ThreadID,
 		sessionData.ThreadIDSession,
 		configData.ExchangeName,
@@ -288,6 +281,7 @@ func UpdateSession(
 
 // UpdateGlobal Update global settings
 func UpdateGlobal(
+	ctx context.Context,
 	sessionData *types.Session) (err error) {
 
 	var rows *sql.Rows /* Rows */
@@ -296,7 +290,7 @@ func UpdateGlobal(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.UpdateGlobal(?,?,?,?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.UpdateGlobal(?,?,?,?)",
 		sessionData.Global.Profit,
 		sessionData.Global.ProfitNet,
 		sessionData.Global.ProfitPct,
@@ -323,6 +317,7 @@ func UpdateGlobal(
 
 // SaveGlobal Save initial global settings
 func SaveGlobal(
+	ctx context.Context,
 	sessionData *types.Session) (err error) {
 
 	var rows *sql.Rows /* Rows */
@@ -331,7 +326,7 @@ func SaveGlobal(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.SaveGlobal(?,?,?,?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.SaveGlobal(?,?,?,?)",
 		sessionData.Global.Profit,
 		sessionData.Global.ProfitNet,
 		sessionData.Global.ProfitPct,
@@ -358,6 +353,7 @@ func SaveGlobal(
 
 // SaveSession Save new session to Session table.
 func SaveSession(
+	ctx context.Context,
 	configData *types.Config,
 	sessionData *types.Session) (err error) {
 
@@ -367,7 +363,7 @@ func SaveSession(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.SaveSession(?,?,?,?,?,?,?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.SaveSession(?,?,?,?,?,?,?)",
 		sessionData.ThreadID,
 		sessionData.ThreadIDSession,
 		configData.ExchangeName,
@@ -397,6 +393,7 @@ func SaveSession(
 
 // DeleteSession Delete session from Session table
 func DeleteSession(
+	ctx context.Context,
 	sessionData *types.Session) (err error) {
 
 	var rows *sql.Rows /* Rows */
@@ -405,7 +402,7 @@ func DeleteSession(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.DeleteSession(?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.DeleteSession(?)",
 		sessionData.ThreadID); err != nil {
 
 		logger.LogEntry{ /* Log Entry */
@@ -429,6 +426,7 @@ func DeleteSession(
 
 // GetSessionStatus check for system error status
 func GetSessionStatus(
+	ctx context.Context,
 	sessionData *types.Session) (threadID string, err error) {
 
 	var rows *sql.Rows /* Rows */
@@ -437,7 +435,7 @@ func GetSessionStatus(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetSessionStatus()"); err != nil {
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetSessionStatus()"); err != nil {
 
 		logger.LogEntry{ /* Log Entry */
 			Config:   nil,
@@ -466,8 +464,63 @@ func GetSessionStatus(
 
 }
 
+// GetSessions call GetSessions stored procedure, returning the threads of the session table with their open
+// Thread transactions
+func GetSessions(
+	ctx context.Context,
+	sessionData *types.Session) (sessions []types.SessionRecord, err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetSessions()"); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return nil, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		session := types.SessionRecord{}
+		if err = rows.Scan(
+			&session.ThreadID,
+			&session.ThreadIDSession,
+			&session.Exchange,
+			&session.SymbolFiat,
+			&session.SymbolFiatFunds,
+			&session.DiffTotal,
+			&session.Status,
+			&session.ThreadCount); err != nil {
+
+			return nil, err
+
+		}
+
+		sessions = append(sessions, session)
+
+	}
+
+	return sessions, rows.Err()
+
+}
+
 // SaveThreadTransaction Save Thread cycle to database
 func SaveThreadTransaction(
+	ctx context.Context,
 	sessionData *types.Session,
 	OrderID int64,
 	CumulativeQuoteQuantity float64,
@@ -480,7 +533,7 @@ func SaveThreadTransaction(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.SaveThreadTransaction(?,?,?,?,?,?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.SaveThreadTransaction(?,?,?,?,?,?)",
 		sessionData.ThreadID,
 		sessionData.ThreadIDSession,
 		OrderID,
@@ -512,6 +565,7 @@ func SaveThreadTransaction(
 
 // DeleteThreadTransactionByOrderID function
 func DeleteThreadTransactionByOrderID(
+	ctx context.Context,
 	sessionData *types.Session,
 	orderID int) (err error) {
 
@@ -521,7 +575,7 @@ func DeleteThreadTransactionByOrderID(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.DeleteThreadTransactionByOrderID(?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.DeleteThreadTransactionByOrderID(?)",
 		orderID); err != nil {
 
 		logger.LogEntry{ /* Log Entry */
@@ -547,6 +601,7 @@ func DeleteThreadTransactionByOrderID(
 
 // GetThreadTransactionCount Get Thread count
 func GetThreadTransactionCount(
+	ctx context.Context,
 	sessionData *types.Session) (count int, err error) {
 
 	var rows *sql.Rows /* Rows */
@@ -555,7 +610,7 @@ func GetThreadTransactionCount(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetThreadTransactionCount(?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetThreadTransactionCount(?)",
 		sessionData.ThreadID); err != nil {
 
 		logger.LogEntry{ /* Log Entry */
@@ -583,6 +638,7 @@ func GetThreadTransactionCount(
 
 // GetLastOrderTransactionPrice Get time for last transaction the ThreadID
 func GetLastOrderTransactionPrice(
+	ctx context.Context,
 	sessionData *types.Session,
 	Side string) (price float64, err error) {
 
@@ -592,7 +648,7 @@ func GetLastOrderTransactionPrice(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetLastOrderTransactionPrice(?,?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetLastOrderTransactionPrice(?,?)",
 		sessionData.ThreadID,
 		Side); err != nil {
 
@@ -621,6 +677,7 @@ func GetLastOrderTransactionPrice(
 
 // GetLastOrderTransactionSide Get Side for last transaction the ThreadID
 func GetLastOrderTransactionSide(
+	ctx context.Context,
 	sessionData *types.Session) (side string, err error) {
 
 	var rows *sql.Rows /* Rows */
@@ -629,7 +686,7 @@ func GetLastOrderTransactionSide(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetLastOrderTransactionSide(?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetLastOrderTransactionSide(?)",
 		sessionData.ThreadID); err != nil {
 
 		logger.LogEntry{ /* Log Entry */
@@ -657,6 +714,7 @@ func GetLastOrderTransactionSide(
 
 // GetOrderTransactionSideLastTwo function
 func GetOrderTransactionSideLastTwo(
+	ctx context.Context,
 	sessionData *types.Session) (side1 string, side2 string, err error) {
 
 	var rows *sql.Rows /* Rows */
@@ -665,7 +723,7 @@ func GetOrderTransactionSideLastTwo(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetOrderTransactionSideLastTwo(?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetOrderTransactionSideLastTwo(?)",
 		sessionData.ThreadID); err != nil {
 
 		logger.LogEntry{ /* Log Entry */
@@ -693,6 +751,7 @@ func GetOrderTransactionSideLastTwo(
 
 // GetOrderSymbol Get symbol for ThreadID
 func GetOrderSymbol(
+	ctx context.Context,
 	sessionData *types.Session) (symbol string, err error) {
 
 	var rows *sql.Rows /* Rows */
@@ -701,7 +760,7 @@ func GetOrderSymbol(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetOrderSymbol(?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetOrderSymbol(?)",
 		sessionData.ThreadID); err != nil {
 
 		logger.LogEntry{ /* Log Entry */
@@ -729,6 +788,7 @@ func GetOrderSymbol(
 
 // GetThreadTransactionDistinct Get Thread Distinct
 func GetThreadTransactionDistinct(
+	ctx context.Context,
 	sessionData *types.Session) (threadID string, threadIDSession string, err error) {
 
 	var rows *sql.Rows /* Rows */
@@ -737,7 +797,7 @@ func GetThreadTransactionDistinct(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetThreadTransactionDistinct()"); err != nil {
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetThreadTransactionDistinct()"); err != nil {
 
 		logger.LogEntry{ /* Log Entry */
 			Config:   nil,
@@ -779,6 +839,7 @@ func GetThreadTransactionDistinct(
 
 // GetOrderTransactionPending Get 1 order with pending FILLED status
 func GetOrderTransactionPending(
+	ctx context.Context,
 	sessionData *types.Session) (order types.Order, err error) {
 
 	var rows *sql.Rows /* Rows */
@@ -787,7 +848,7 @@ func GetOrderTransactionPending(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetOrderTransactionPending(?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetOrderTransactionPending(?)",
 		sessionData.ThreadID); err != nil {
 
 		logger.LogEntry{ /* Log Entry */
@@ -817,6 +878,7 @@ func GetOrderTransactionPending(
 
 // GetThreadTransactionByPrice retrieve lowest price order from Thread database
 func GetThreadTransactionByPrice(
+	ctx context.Context,
 	marketData *types.Market,
 	sessionData *types.Session) (order types.Order, err error) {
 
@@ -826,7 +888,7 @@ func GetThreadTransactionByPrice(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetThreadTransactionByPrice(?,?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetThreadTransactionByPrice(?,?)",
 		sessionData.ThreadID,
 		marketData.Price); err != nil {
 
@@ -861,6 +923,7 @@ func GetThreadTransactionByPrice(
 // GetThreadTransactionByPriceHigher function returns the highert Thread order above a certain treshold.
 // It is used for STOPLOSS Loss as ratio that should trigger a sale
 func GetThreadTransactionByPriceHigher(
+	ctx context.Context,
 	marketData *types.Market,
 	sessionData *types.Session) (order types.Order, err error) {
 
@@ -870,7 +933,7 @@ func GetThreadTransactionByPriceHigher(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetThreadTransactionByPriceHigher(?,?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetThreadTransactionByPriceHigher(?,?)",
 		sessionData.ThreadID,
 		marketData.Price); err != nil {
 
@@ -904,6 +967,7 @@ func GetThreadTransactionByPriceHigher(
 
 // GetThreadLastTransaction function returns the last BUY transaction for a Thread
 func GetThreadLastTransaction(
+	ctx context.Context,
 	sessionData *types.Session) (order types.Order, err error) {
 
 	var rows *sql.Rows /* Rows */
@@ -912,7 +976,7 @@ func GetThreadLastTransaction(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetThreadLastTransaction(?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetThreadLastTransaction(?)",
 		sessionData.ThreadID); err != nil {
 
 		logger.LogEntry{ /* Log Entry */
@@ -945,6 +1009,7 @@ func GetThreadLastTransaction(
 
 // GetOrderByOrderID Return order by OrderID (uses ThreadID as filter)
 func GetOrderByOrderID(
+	ctx context.Context,
 	sessionData *types.Session) (order types.Order, err error) {
 
 	var rows *sql.Rows /* Rows */
@@ -953,7 +1018,7 @@ func GetOrderByOrderID(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetOrderByOrderID(?,?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetOrderByOrderID(?,?)",
 		sessionData.ForceSellOrderID,
 		sessionData.ThreadID); err != nil {
 
@@ -989,6 +1054,7 @@ func GetOrderByOrderID(
 
 // GetThreadTransactiontUpmarketPriceCount function
 func GetThreadTransactiontUpmarketPriceCount(
+	ctx context.Context,
 	sessionData *types.Session,
 	price float64) (count int, err error) {
 
@@ -998,7 +1064,7 @@ func GetThreadTransactiontUpmarketPriceCount(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetThreadTransactiontUpmarketPriceCount(?,?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetThreadTransactiontUpmarketPriceCount(?,?)",
 		sessionData.ThreadID,
 		price); err != nil {
 
@@ -1027,6 +1093,7 @@ func GetThreadTransactiontUpmarketPriceCount(
 
 // GetOrderTransactionCount Retrieve transaction count by Side and minutes
 func GetOrderTransactionCount(
+	ctx context.Context,
 	sessionData *types.Session,
 	side string) (count float64, err error) {
 
@@ -1036,7 +1103,7 @@ func GetOrderTransactionCount(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetOrderTransactionCount(?,?,?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetOrderTransactionCount(?,?,?)",
 		sessionData.ThreadID,
 		side,
 		(60 * -1)); err != nil {
@@ -1066,6 +1133,7 @@ func GetOrderTransactionCount(
 
 // GetThreadTransactionByThreadID  Retrieve transaction count by Side and minutes
 func GetThreadTransactionByThreadID(
+	ctx context.Context,
 	sessionData *types.Session) (orders []types.Order, err error) {
 
 	var rows *sql.Rows /* Rows */
@@ -1076,7 +1144,7 @@ func GetThreadTransactionByThreadID(
 
 	order := types.Order{}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetThreadTransactionByThreadID(?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetThreadTransactionByThreadID(?)",
 		sessionData.ThreadID); err != nil {
 
 		logger.LogEntry{ /* Log Entry */
@@ -1112,18 +1180,19 @@ func GetThreadTransactionByThreadID(
 
 }
 
-// GetProfitByThreadID retrieve total and average percentage profit by ThreadID
-func GetProfitByThreadID(sessionData *types.Session) (fiat float64, percentage float64, err error) {
+// GetThreadTransactionAll call GetThreadTransactionAll stored procedure, returning Thread transactions for the
+// ThreadID joined with the order transaction time, lowest price first
+func GetThreadTransactionAll(
+	ctx context.Context,
+	sessionData *types.Session) (orders []types.Order, err error) {
 
-	var rows *sql.Rows                        /* Rows */
-	var fiatNullFloat64 sql.NullFloat64       /* handle null mysql returns */
-	var percentageNullFloat64 sql.NullFloat64 /* handle null mysql returns */
+	var rows *sql.Rows /* Rows */
 
 	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetProfitByThreadID(?)",
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetThreadTransactionAll(?)",
 		sessionData.ThreadID); err != nil {
 
 		logger.LogEntry{ /* Log Entry */
@@ -1135,22 +1204,1024 @@ func GetProfitByThreadID(sessionData *types.Session) (fiat float64, percentage f
 			LogLevel: "DebugLevel",
 		}.Do()
 
-		return fiatNullFloat64.Float64, percentageNullFloat64.Float64, err
+		return nil, err
 
 	}
 
+	defer rows.Close() /* Close rows */
+
 	for rows.Next() {
-		err = rows.Scan(&fiatNullFloat64, &percentageNullFloat64)
+
+		order := types.Order{}
+		if err = rows.Scan(
+			&order.CumulativeQuoteQuantity,
+			&order.OrderID,
+			&order.Price,
+			&order.ExecutedQuantity,
+			&order.TransactTime); err != nil {
+
+			return nil, err
+
+		}
+
+		orders = append(orders, order)
+
+	}
+
+	return orders, rows.Err()
+
+}
+
+// GetThreadTransactionMissing Get FILLED BUY orders with no thread transaction and no SELL order
+func GetThreadTransactionMissing(
+	ctx context.Context,
+	sessionData *types.Session) (orders []types.Order, err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetThreadTransactionMissing(?)",
+		sessionData.ThreadID); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return nil, err
+
 	}
 
 	defer rows.Close() /* Close rows */
 
-	return fiatNullFloat64.Float64, (percentageNullFloat64.Float64 * 100), err
+	for rows.Next() {
+
+		order := types.Order{}
+		if err = rows.Scan(&order.OrderID, &order.CumulativeQuoteQuantity, &order.Price, &order.ExecutedQuantity); err != nil {
+
+			return nil, err
+
+		}
+
+		orders = append(orders, order)
+
+	}
+
+	return orders, rows.Err()
+
+}
+
+// GetThreadTransactionSold Get FILLED SELL orders (OrderIDSource) whose BUY thread transaction was not deleted
+func GetThreadTransactionSold(
+	ctx context.Context,
+	sessionData *types.Session) (orders []types.Order, err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetThreadTransactionSold(?)",
+		sessionData.ThreadID); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return nil, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		order := types.Order{}
+		if err = rows.Scan(&order.OrderID, &order.OrderIDSource); err != nil {
+
+			return nil, err
+
+		}
+
+		orders = append(orders, order)
+
+	}
+
+	return orders, rows.Err()
+
+}
+
+// GetOrderTransactionExecuted call GetOrderTransactionExecuted stored procedure, returning BUY and SELL
+// orders with executed quantity for all threads created before the TransactTime before (0 for all), oldest first
+func GetOrderTransactionExecuted(
+	ctx context.Context,
+	sessionData *types.Session,
+	before int64) (orders []types.Order, err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetOrderTransactionExecuted(?)",
+		before); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return nil, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		order := types.Order{}
+		if err = rows.Scan(
+			&order.ClientOrderID,
+			&order.CumulativeQuoteQuantity,
+			&order.ExecutedQuantity,
+			&order.OrderID,
+			&order.OrderIDSource,
+			&order.Price,
+			&order.Side,
+			&order.Status,
+			&order.Symbol,
+			&order.TransactTime); err != nil {
+
+			return nil, err
+
+		}
+
+		orders = append(orders, order)
+
+	}
+
+	return orders, rows.Err()
+
+}
+
+// GetOrders call GetOrders stored procedure, returning the orders matching filter most recent first
+func GetOrders(
+	ctx context.Context,
+	sessionData *types.Session,
+	filter types.OrderFilter) (orders []types.Order, err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if filter.Limit <= 0 { /* No limit */
+
+		filter.Limit = math.MaxInt32
+
+	}
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetOrders(?,?,?,?)",
+		filter.ThreadID,
+		filter.Side,
+		filter.Status,
+		filter.Limit); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return nil, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		order := types.Order{}
+		if err = rows.Scan(
+			&order.ClientOrderID,
+			&order.CumulativeQuoteQuantity,
+			&order.ExecutedQuantity,
+			&order.OrderID,
+			&order.OrderIDSource,
+			&order.Price,
+			&order.Side,
+			&order.Status,
+			&order.Symbol,
+			&order.TransactTime); err != nil {
+
+			return nil, err
+
+		}
+
+		orders = append(orders, order)
+
+	}
+
+	return orders, rows.Err()
+
+}
+
+// ArchiveOrders call ArchiveOrders stored procedure, moving closed orders executed before the TransactTime before
+// to orders_archive and returning the number of archived orders
+func ArchiveOrders(
+	ctx context.Context,
+	sessionData *types.Session,
+	before int64) (archived int64, err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.ArchiveOrders(?)",
+		before); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return 0, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		err = rows.Scan(&archived)
+
+	}
+
+	return archived, err
+
+}
+
+// GetTradeStats call GetTradeStats stored procedure, returning trades ledger totals for threadID
+// or all threads when threadID is empty
+func GetTradeStats(
+	ctx context.Context,
+	sessionData *types.Session,
+	threadID string) (stats types.TradeStats, err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetTradeStats(?)",
+		threadID); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return stats, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+		err = rows.Scan(&stats.Count, &stats.Wins, &stats.GrossProfit, &stats.GrossLoss, &stats.HoldingTime)
+	}
+
+	return stats, err
+
+}
+
+// SaveEquity call SaveEquity stored procedure, replacing the snapshot already saved for the same day
+func SaveEquity(
+	ctx context.Context,
+	sessionData *types.Session,
+	equity types.Equity) (err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.SaveEquity(?,?,?,?,?)",
+		equity.ThreadID,
+		equity.Date,
+		equity.Realized,
+		equity.Unrealized,
+		equity.Equity); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	return nil
+
+}
+
+// GetEquity call GetEquity stored procedure, returning daily equity snapshots for threadID
+// or across all threads when threadID is empty, oldest first
+func GetEquity(
+	ctx context.Context,
+	sessionData *types.Session,
+	threadID string) (equity []types.Equity, err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetEquity(?)",
+		threadID); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return nil, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		snapshot := types.Equity{}
+		if err = rows.Scan(&snapshot.ThreadID, &snapshot.Date, &snapshot.Realized, &snapshot.Unrealized, &snapshot.Equity); err != nil {
+
+			return nil, err
+
+		}
+
+		equity = append(equity, snapshot)
+
+	}
+
+	return equity, rows.Err()
+
+}
+
+// AcquireLease call AcquireLease stored procedure, taking lease name for holder when it is free or expired,
+// or renewing it when holder already holds it, for duration milliseconds of the database clock
+func AcquireLease(
+	ctx context.Context,
+	sessionData *types.Session,
+	name string,
+	holder string,
+	duration int64) (lease types.Lease, err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.AcquireLease(?,?,?)",
+		name,
+		holder,
+		duration); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return types.Lease{}, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		if err = rows.Scan(&lease.Name, &lease.Holder, &lease.Token, &lease.Expires); err != nil {
+
+			return types.Lease{}, err
+
+		}
+
+	}
+
+	return lease, rows.Err()
+
+}
+
+// ReleaseLease call ReleaseLease stored procedure, expiring lease name when it is held by holder
+func ReleaseLease(
+	ctx context.Context,
+	sessionData *types.Session,
+	name string,
+	holder string) (err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.ReleaseLease(?,?)",
+		name,
+		holder); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	return nil
+
+}
+
+// SaveNode call SaveNode stored procedure, saving node in the cluster node registry with a new heartbeat
+func SaveNode(
+	ctx context.Context,
+	sessionData *types.Session,
+	node types.Node) (err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.SaveNode(?,?,?,?,?,?)",
+		node.ThreadID,
+		node.Hostname,
+		node.Port,
+		node.Version,
+		node.Symbol,
+		node.Started); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	return nil
+
+}
+
+// GetNodes call GetNodes stored procedure, returning the nodes of the cluster node registry
+// most recently started first
+func GetNodes(
+	ctx context.Context,
+	sessionData *types.Session) (nodes []types.Node, err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetNodes()"); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return nil, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		node := types.Node{}
+		if err = rows.Scan(&node.ThreadID, &node.Hostname, &node.Port, &node.Version, &node.Symbol, &node.Started, &node.Heartbeat, &node.Age); err != nil {
+
+			return nil, err
+
+		}
+
+		nodes = append(nodes, node)
+
+	}
+
+	return nodes, rows.Err()
+
+}
+
+// DeleteNode call DeleteNode stored procedure, deleting node from the cluster node registry
+func DeleteNode(
+	ctx context.Context,
+	sessionData *types.Session,
+	threadID string) (err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.DeleteNode(?)",
+		threadID); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	return nil
+
+}
+
+// GetThreadOrphans call GetThreadOrphans stored procedure, returning the threads with Thread transactions
+// whose node registry heartbeat is older than age milliseconds, longest down first
+func GetThreadOrphans(
+	ctx context.Context,
+	sessionData *types.Session,
+	age int64) (orphans []types.Orphan, err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetThreadOrphans(?)",
+		age); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return nil, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		orphan := types.Orphan{}
+		if err = rows.Scan(&orphan.ThreadID, &orphan.ThreadIDSession, &orphan.Hostname, &orphan.Port, &orphan.Age); err != nil {
+
+			return nil, err
+
+		}
+
+		orphans = append(orphans, orphan)
+
+	}
+
+	return orphans, rows.Err()
+
+}
+
+// SaveAudit call SaveAudit stored procedure
+func SaveAudit(
+	ctx context.Context,
+	sessionData *types.Session,
+	audit types.Audit) (err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.SaveAudit(?,?,?,?,?)",
+		audit.Time,
+		audit.ThreadID,
+		audit.Node,
+		audit.Action,
+		audit.Detail); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	return nil
+
+}
+
+// GetAudit call GetAudit stored procedure, returning the audit log records saved after since milliseconds,
+// oldest first
+func GetAudit(
+	ctx context.Context,
+	sessionData *types.Session,
+	since int64) (audit []types.Audit, err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetAudit(?)",
+		since); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return nil, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		record := types.Audit{}
+		if err = rows.Scan(&record.ID, &record.Time, &record.ThreadID, &record.Node, &record.Action, &record.Detail); err != nil {
+
+			return nil, err
+
+		}
+
+		audit = append(audit, record)
+
+	}
+
+	return audit, rows.Err()
+
+}
+
+// SavePause call SavePause stored procedure
+func SavePause(
+	ctx context.Context,
+	sessionData *types.Session,
+	pause types.Pause) (err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.SavePause(?,?,?,?,?)",
+		pause.ThreadID,
+		pause.Mode,
+		pause.By,
+		pause.Time,
+		pause.Confirm); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	return nil
+
+}
+
+// GetPause call GetPause stored procedure, returning an empty Pause when the thread is not paused
+func GetPause(
+	ctx context.Context,
+	sessionData *types.Session,
+	threadID string) (pause types.Pause, err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetPause(?)",
+		threadID); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return types.Pause{}, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		if err = rows.Scan(&pause.ThreadID, &pause.Mode, &pause.By, &pause.Time, &pause.Confirm); err != nil {
+
+			return types.Pause{}, err
+
+		}
+
+	}
+
+	return pause, rows.Err()
+
+}
+
+// DeletePause call DeletePause stored procedure
+func DeletePause(
+	ctx context.Context,
+	sessionData *types.Session,
+	threadID string) (err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.DeletePause(?)",
+		threadID); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	return nil
+
+}
+
+// GetTrades call GetTrades stored procedure, returning closed trades most recent first
+func GetTrades(
+	ctx context.Context,
+	sessionData *types.Session,
+	filter types.TradeFilter) (trades []types.Trade, err error) {
+
+	var rows *sql.Rows /* Rows */
+
+	if filter.Limit <= 0 { /* No limit */
+
+		filter.Limit = math.MaxInt32
+
+	}
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetTrades(?,?,?,?,?)",
+		filter.ThreadID,
+		filter.From,
+		filter.To,
+		filter.ExitReason,
+		filter.Limit); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return nil, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		trade := types.Trade{}
+		if err = rows.Scan(
+			&trade.ThreadID,
+			&trade.ThreadIDSession,
+			&trade.Symbol,
+			&trade.BuyOrderID,
+			&trade.SellOrderID,
+			&trade.Quantity,
+			&trade.EntryPrice,
+			&trade.ExitPrice,
+			&trade.EntryQuote,
+			&trade.ExitQuote,
+			&trade.Fees,
+			&trade.Profit,
+			&trade.ProfitPct,
+			&trade.EntryTime,
+			&trade.ExitTime,
+			&trade.HoldingTime,
+			&trade.ExitReason); err != nil {
+
+			return nil, err
+
+		}
+
+		trades = append(trades, trade)
+
+	}
+
+	return trades, rows.Err()
+
+}
+
+// GetProfitByThreadID retrieve total and average percentage profit by ThreadID
+func GetProfitByThreadID(ctx context.Context, sessionData *types.Session) (fiat float64, percentage float64, err error) {
+
+	var rows *sql.Rows                        /* Rows */
+	var fiatNullFloat64 sql.NullFloat64       /* handle null mysql returns */
+	var percentageNullFloat64 sql.NullFloat64 /* handle null mysql returns */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetProfitByThreadID(?)",
+		sessionData.ThreadID); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return fiatNullFloat64.Float64, percentageNullFloat64.Float64, err
+
+	}
+
+	for rows.Next() {
+		err = rows.Scan(&fiatNullFloat64, &percentageNullFloat64)
+	}
+
+	defer rows.Close() /* Close rows */
+
+	return fiatNullFloat64.Float64, (percentageNullFloat64.Float64 * 100), err
+
+}
+
+// GetProfitByThreadIDSince retrieve realized profit by ThreadID for sales executed since transactTime (milliseconds)
+func GetProfitByThreadIDSince(
+	ctx context.Context,
+	sessionData *types.Session,
+	transactTime int64) (profit float64, err error) {
+
+	var rows *sql.Rows                    /* Rows */
+	var profitNullFloat64 sql.NullFloat64 /* handle null mysql returns */
+
+	if flag.Lookup("test.v") != nil { /* If the -test.v flag is set, the test database is used */
+		sessionData.Db.Begin() /* Start transaction */
+	}
+
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetProfitByThreadIDSince(?,?)",
+		sessionData.ThreadID,
+		transactTime); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  functions.GetFunctionName() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		return 0, err
+
+	}
+
+	for rows.Next() {
+		err = rows.Scan(&profitNullFloat64)
+	}
+
+	defer rows.Close() /* Close rows */
+
+	return profitNullFloat64.Float64, err
 
 }
 
 // GetProfit retrieve total and average percentage profit
 func GetProfit(
+	ctx context.Context,
 	sessionData *types.Session) (profit float64, profitNet float64, percentage float64, err error) {
 
 	var rows *sql.Rows                        /* Rows */
@@ -1162,7 +2233,7 @@ func GetProfit(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetProfit()"); err != nil {
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetProfit()"); err != nil {
 
 		logger.LogEntry{ /* Log Entry */
 			Config:   nil,
@@ -1187,7 +2258,7 @@ func GetProfit(
 }
 
 // GetGlobal get global data
-func GetGlobal(sessionData *types.Session) (profit float64, profitNet float64, profitPct float64, transactTime int64, err error) {
+func GetGlobal(ctx context.Context, sessionData *types.Session) (profit float64, profitNet float64, profitPct float64, transactTime int64, err error) {
 
 	var rows *sql.Rows /* Rows */
 
@@ -1195,7 +2266,7 @@ func GetGlobal(sessionData *types.Session) (profit float64, profitNet float64, p
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetGlobal()"); err != nil {
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetGlobal()"); err != nil {
 
 		logger.LogEntry{ /* Log Entry */
 			Config:   nil,
@@ -1221,6 +2292,7 @@ func GetGlobal(sessionData *types.Session) (profit float64, profitNet float64, p
 
 // GetThreadCount Retrieve Running Thread Count
 func GetThreadCount(
+	ctx context.Context,
 	sessionData *types.Session) (count int, err error) {
 
 	var rows *sql.Rows /* Rows */
@@ -1229,7 +2301,7 @@ func GetThreadCount(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetThreadCount()"); err != nil {
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetThreadCount()"); err != nil {
 
 		logger.LogEntry{ /* Log Entry */
 			Config:   nil,
@@ -1256,6 +2328,7 @@ func GetThreadCount(
 
 // GetThreadAmount Retrieve Thread Dollar Amount
 func GetThreadAmount(
+	ctx context.Context,
 	sessionData *types.Session) (amount float64, err error) {
 
 	var rows *sql.Rows                    /* Rows */
@@ -1265,7 +2338,7 @@ func GetThreadAmount(
 		sessionData.Db.Begin() /* Start transaction */
 	}
 
-	if rows, err = sessionData.Db.Query("call cryptopump.GetThreadTransactionAmount()"); err != nil {
+	if rows, err = sessionData.Db.QueryContext(ctx, "call cryptopump.GetThreadTransactionAmount()"); err != nil {
 
 		logger.LogEntry{ /* Log Entry */
 			Config:   nil,
@@ -1,6 +1,7 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"regexp"
@@ -57,7 +58,7 @@ func TestGetThreadCount(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotCount, err := GetThreadCount(tt.args.sessionData)
+			gotCount, err := GetThreadCount(context.Background(), tt.args.sessionData)
 			if (err != nil) != tt.wantErr && (gotCount > tt.wantCount) {
 				return
 			}
@@ -99,7 +100,7 @@ func TestGetThreadAmount(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotAmount, err := GetThreadAmount(tt.args.sessionData)
+			gotAmount, err := GetThreadAmount(context.Background(), tt.args.sessionData)
 			if (err == nil) && gotAmount > 0 {
 				return
 			}
@@ -147,7 +148,7 @@ func TestGetSessionStatus(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := GetSessionStatus(tt.args.sessionData)
+			_, err := GetSessionStatus(context.Background(), tt.args.sessionData)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetSessionStatus() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -188,7 +189,7 @@ func TestGetGlobal(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, _, _, _, err := GetGlobal(tt.args.sessiondata)
+			_, _, _, _, err := GetGlobal(context.Background(), tt.args.sessiondata)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetGlobal() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -229,7 +230,7 @@ func TestGetProfit(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, _, _, err := GetProfit(tt.args.sessionData)
+			_, _, _, err := GetProfit(context.Background(), tt.args.sessionData)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetProfit() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -275,7 +276,7 @@ func TestGetProfitByThreadID(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, _, err := GetProfitByThreadID(tt.args.sessionData)
+			_, _, err := GetProfitByThreadID(context.Background(), tt.args.sessionData)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetProfitByThreadID() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -284,6 +285,56 @@ func TestGetProfitByThreadID(t *testing.T) {
 	}
 }
 
+func TestGetProfitByThreadIDSince(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	type args struct {
+		sessionData  *types.Session
+		transactTime int64
+	}
+
+	tests := []struct {
+		name       string
+		args       args
+		wantProfit float64
+		wantErr    bool
+	}{
+		{
+			name: "success",
+			args: args{
+				sessionData: &types.Session{
+					ThreadID: "c683ok5mk1u1120gnmmg",
+					Db:       db,
+				},
+				transactTime: 1642032000000,
+			},
+			wantProfit: -12.5,
+			wantErr:    false,
+		},
+	}
+
+	columns := []string{"profit"}
+	mock.ExpectBegin()                                                                   /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.GetProfitByThreadIDSince(?,?)")). /* call procedure */
+												WithArgs(tests[0].args.sessionData.ThreadID, tests[0].args.transactTime). /* with args */
+												WillReturnRows(sqlmock.NewRows(columns).AddRow(-12.5))                    /* return 1 row */
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotProfit, err := GetProfitByThreadIDSince(context.Background(), tt.args.sessionData, tt.args.transactTime)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetProfitByThreadIDSince() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if gotProfit != tt.wantProfit {
+				t.Errorf("GetProfitByThreadIDSince() = %v, want %v", gotProfit, tt.wantProfit)
+			}
+		})
+	}
+}
+
 func TestGetThreadTransactionByThreadID(t *testing.T) {
 
 	db, mock := NewMock()
@@ -318,7 +369,7 @@ func TestGetThreadTransactionByThreadID(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := GetThreadTransactionByThreadID(tt.args.sessionData)
+			_, err := GetThreadTransactionByThreadID(context.Background(), tt.args.sessionData)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetThreadTransactionByThreadID() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -327,6 +378,56 @@ func TestGetThreadTransactionByThreadID(t *testing.T) {
 	}
 }
 
+func TestGetThreadTransactionAll(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	type args struct {
+		sessionData *types.Session
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "success",
+			args: args{
+				sessionData: &types.Session{
+					ThreadID: "c683ok5mk1u1120gnmmg",
+					Db:       db,
+				},
+			},
+			want:    2,
+			wantErr: false,
+		},
+	}
+
+	columns := []string{"cumulativeQuoteQty", "orderID", "price", "executedQuantity", "transactTime"}
+	mock.ExpectBegin()                                                                /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.GetThreadTransactionAll(?)")). /* call procedure */
+												WithArgs(tests[0].args.sessionData.ThreadID). /* with args */
+												WillReturnRows(sqlmock.NewRows(columns).
+													AddRow(80, 3, 80, 1, 1642000000000).
+													AddRow(100, 1, 100, 1, 1641000000000)) /* return 2 rows */
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetThreadTransactionAll(context.Background(), tt.args.sessionData)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetThreadTransactionAll() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if len(got) != tt.want || got[0].OrderID != 3 || got[0].TransactTime != 1642000000000 {
+				t.Errorf("GetThreadTransactionAll() = %v, want %v orders", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestGetOrderTransactionCount(t *testing.T) {
 
 	db, mock := NewMock()
@@ -367,7 +468,7 @@ func TestGetOrderTransactionCount(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := GetOrderTransactionCount(tt.args.sessionData, tt.args.side)
+			_, err := GetOrderTransactionCount(context.Background(), tt.args.sessionData, tt.args.side)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetOrderTransactionCount() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -415,7 +516,7 @@ func TestGetThreadTransactiontUpmarketPriceCount(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotCount, err := GetThreadTransactiontUpmarketPriceCount(tt.args.sessionData, tt.args.price)
+			gotCount, err := GetThreadTransactiontUpmarketPriceCount(context.Background(), tt.args.sessionData, tt.args.price)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetThreadTransactiontUpmarketPriceCount() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -464,7 +565,7 @@ func TestGetOrderByOrderID(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := GetOrderByOrderID(tt.args.sessionData)
+			_, err := GetOrderByOrderID(context.Background(), tt.args.sessionData)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetOrderByOrderID() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -507,7 +608,7 @@ func TestGetThreadLastTransaction(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := GetThreadLastTransaction(tt.args.sessionData)
+			_, err := GetThreadLastTransaction(context.Background(), tt.args.sessionData)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetThreadLastTransaction() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -556,7 +657,7 @@ func TestGetThreadTransactionByPriceHigher(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := GetThreadTransactionByPriceHigher(tt.args.marketData, tt.args.sessionData)
+			_, err := GetThreadTransactionByPriceHigher(context.Background(), tt.args.marketData, tt.args.sessionData)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetThreadTransactionByPriceHigher() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -605,7 +706,7 @@ func TestGetThreadTransactionByPrice(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := GetThreadTransactionByPrice(tt.args.marketData, tt.args.sessionData)
+			_, err := GetThreadTransactionByPrice(context.Background(), tt.args.marketData, tt.args.sessionData)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetThreadTransactionByPrice() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -648,7 +749,7 @@ func TestGetOrderTransactionPending(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := GetOrderTransactionPending(tt.args.sessionData)
+			_, err := GetOrderTransactionPending(context.Background(), tt.args.sessionData)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetOrderTransactionPending() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -689,7 +790,7 @@ func TestGetThreadTransactionDistinct(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, _, err := GetThreadTransactionDistinct(tt.args.sessionData)
+			_, _, err := GetThreadTransactionDistinct(context.Background(), tt.args.sessionData)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetThreadTransactionDistinct() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -732,7 +833,7 @@ func TestGetOrderSymbol(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := GetOrderSymbol(tt.args.sessionData)
+			_, err := GetOrderSymbol(context.Background(), tt.args.sessionData)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetOrderSymbol() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -775,7 +876,7 @@ func TestGetOrderTransactionSideLastTwo(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, _, err := GetOrderTransactionSideLastTwo(tt.args.sessionData)
+			_, _, err := GetOrderTransactionSideLastTwo(context.Background(), tt.args.sessionData)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetOrderTransactionSideLastTwo() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -818,7 +919,7 @@ func TestGetLastOrderTransactionSide(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := GetLastOrderTransactionSide(tt.args.sessionData)
+			_, err := GetLastOrderTransactionSide(context.Background(), tt.args.sessionData)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetLastOrderTransactionSide() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -865,7 +966,7 @@ func TestGetLastOrderTransactionPrice(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := GetLastOrderTransactionPrice(tt.args.sessionData, tt.args.Side)
+			_, err := GetLastOrderTransactionPrice(context.Background(), tt.args.sessionData, tt.args.Side)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetLastOrderTransactionPrice() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -909,7 +1010,7 @@ func TestGetThreadTransactionCount(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := GetThreadTransactionCount(tt.args.sessionData)
+			_, err := GetThreadTransactionCount(context.Background(), tt.args.sessionData)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetThreadTransactionCount() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -954,7 +1055,7 @@ func TestDeleteThreadTransactionByOrderID(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := DeleteThreadTransactionByOrderID(tt.args.sessionData, tt.args.orderID); (err != nil) != tt.wantErr {
+			if err := DeleteThreadTransactionByOrderID(context.Background(), tt.args.sessionData, tt.args.orderID); (err != nil) != tt.wantErr {
 				t.Errorf("DeleteThreadTransactionByOrderID() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -1026,7 +1127,7 @@ func TestSaveOrder(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := SaveOrder(tt.args.sessionData, tt.args.order, tt.args.orderIDSource, tt.args.orderPrice); (err != nil) != tt.wantErr {
+			if err := SaveOrder(context.Background(), tt.args.sessionData, tt.args.order, tt.args.orderIDSource, tt.args.orderPrice); (err != nil) != tt.wantErr {
 				t.Errorf("SaveOrder() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -1082,7 +1183,7 @@ func TestUpdateOrder(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := UpdateOrder(tt.args.sessionData, tt.args.OrderID, tt.args.CumulativeQuoteQuantity, tt.args.ExecutedQuantity, tt.args.Price, tt.args.Status); (err != nil) != tt.wantErr {
+			if err := UpdateOrder(context.Background(), tt.args.sessionData, tt.args.OrderID, tt.args.CumulativeQuoteQuantity, tt.args.ExecutedQuantity, tt.args.Price, tt.args.Status); (err != nil) != tt.wantErr {
 				t.Errorf("UpdateOrder() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -1139,7 +1240,7 @@ func TestUpdateSession(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := UpdateSession(tt.args.configData, tt.args.sessionData); (err != nil) != tt.wantErr {
+			if err := UpdateSession(context.Background(), tt.args.configData, tt.args.sessionData); (err != nil) != tt.wantErr {
 				t.Errorf("UpdateSession() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -1188,7 +1289,7 @@ func TestUpdateGlobal(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := UpdateGlobal(tt.args.sessionData); (err != nil) != tt.wantErr {
+			if err := UpdateGlobal(context.Background(), tt.args.sessionData); (err != nil) != tt.wantErr {
 				t.Errorf("UpdateGlobal() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -1237,7 +1338,7 @@ func TestSaveGlobal(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := SaveGlobal(tt.args.sessionData); (err != nil) != tt.wantErr {
+			if err := SaveGlobal(context.Background(), tt.args.sessionData); (err != nil) != tt.wantErr {
 				t.Errorf("SaveGlobal() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -1294,7 +1395,7 @@ func TestSaveSession(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := SaveSession(tt.args.configData, tt.args.sessionData); (err != nil) != tt.wantErr {
+			if err := SaveSession(context.Background(), tt.args.configData, tt.args.sessionData); (err != nil) != tt.wantErr {
 				t.Errorf("SaveSession() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -1336,7 +1437,7 @@ func TestDeleteSession(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := DeleteSession(tt.args.sessionData); (err != nil) != tt.wantErr {
+			if err := DeleteSession(context.Background(), tt.args.sessionData); (err != nil) != tt.wantErr {
 				t.Errorf("DeleteSession() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -1390,9 +1491,579 @@ func TestSaveThreadTransaction(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := SaveThreadTransaction(tt.args.sessionData, tt.args.OrderID, tt.args.CumulativeQuoteQuantity, tt.args.Price, tt.args.ExecutedQuantity); (err != nil) != tt.wantErr {
+			if err := SaveThreadTransaction(context.Background(), tt.args.sessionData, tt.args.OrderID, tt.args.CumulativeQuoteQuantity, tt.args.Price, tt.args.ExecutedQuantity); (err != nil) != tt.wantErr {
 				t.Errorf("SaveThreadTransaction() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+func TestGetThreadTransactionMissing(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		ThreadID: "c683ok5mk1u1120gnmmg",
+		Db:       db,
+	}
+
+	columns := []string{"OrderID", "CummulativeQuoteQty", "Price", "ExecutedQuantity"}
+	mock.ExpectBegin()                                                                    /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.GetThreadTransactionMissing(?)")). /* call procedure */
+												WithArgs(sessionData.ThreadID).                                         /* with args */
+												WillReturnRows(sqlmock.NewRows(columns).AddRow(3, "90.5", "90.5", "1")) /* return 1 row */
+
+	orders, err := GetThreadTransactionMissing(context.Background(), sessionData)
+	if err != nil {
+		t.Fatalf("GetThreadTransactionMissing() error = %v", err)
+	}
+
+	if len(orders) != 1 || orders[0].OrderID != 3 || orders[0].Price != 90.5 {
+		t.Errorf("GetThreadTransactionMissing() = %v, want BUY order 3", orders)
+	}
+}
+
+func TestGetThreadTransactionSold(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		ThreadID: "c683ok5mk1u1120gnmmg",
+		Db:       db,
+	}
+
+	columns := []string{"OrderID", "OrderIDSource"}
+	mock.ExpectBegin()                                                                 /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.GetThreadTransactionSold(?)")). /* call procedure */
+												WithArgs(sessionData.ThreadID).                          /* with args */
+												WillReturnRows(sqlmock.NewRows(columns).AddRow(1001, 1)) /* return 1 row */
+
+	orders, err := GetThreadTransactionSold(context.Background(), sessionData)
+	if err != nil {
+		t.Fatalf("GetThreadTransactionSold() error = %v", err)
+	}
+
+	if len(orders) != 1 || orders[0].OrderID != 1001 || orders[0].OrderIDSource != 1 {
+		t.Errorf("GetThreadTransactionSold() = %v, want SELL order 1001 of BUY order 1", orders)
+	}
+}
+
+func TestStorage_OrderTx(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		threadErr  error
+		token      int64 /* Token of the thread lease in the database */
+		wantCommit bool
+	}{
+		{
+			name:       "commit",
+			wantCommit: true,
+		},
+		{
+			name:       "rollback",
+			threadErr:  sql.ErrConnDone,
+			wantCommit: false,
+		},
+		{
+			name:       "lease held",
+			token:      1,
+			wantCommit: true,
+		},
+		{
+			name:       "lease taken over",
+			token:      2,
+			wantCommit: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			db, mock := NewMock()
+			defer db.Close()
+
+			sessionData := &types.Session{
+				ThreadID:        "c683ok5mk1u1120gnmmg",
+				ThreadIDSession: "c683ok5mk1u1120gnmn0",
+				Db:              db,
+			}
+
+			mock.ExpectBegin()
+
+			if tt.token != 0 {
+				sessionData.ThreadLease = types.Lease{Name: "thread:c683ok5mk1u1120gnmmg", Holder: "node:8080", Token: 1}
+				mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.GetLeaseForUpdate(?)")).
+					WithArgs(sessionData.ThreadLease.Name).
+					WillReturnRows(sqlmock.NewRows([]string{"Name", "Holder", "Token", "Expires"}).AddRow(sessionData.ThreadLease.Name, "node:8080", tt.token, 0))
+			}
+
+			if tt.token > 1 {
+				mock.ExpectRollback()
+			} else {
+				mock.ExpectExec(regexp.QuoteMeta("call cryptopump.SaveOrder(?,?,?,?,?,?,?,?,?,?,?,?)")).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				thread := mock.ExpectExec(regexp.QuoteMeta("call cryptopump.SaveThreadTransaction(?,?,?,?,?,?)")).
+					WithArgs(sessionData.ThreadID, sessionData.ThreadIDSession, 1, 100.0, 100.0, 1.0)
+				if tt.wantCommit {
+					thread.WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectCommit()
+				} else {
+					thread.WillReturnError(tt.threadErr)
+					mock.ExpectRollback()
+				}
+			}
+
+			err := Storage{}.OrderTx(context.Background(), sessionData, func(tx types.OrderTx) error {
+				if err := tx.SaveOrder(&types.Order{OrderID: 1, Side: "BUY", Status: "FILLED"}, 0, 100); err != nil {
+					return err
+				}
+				return tx.SaveThreadTransaction(1, 100, 100, 1)
+			})
+
+			if (err == nil) != tt.wantCommit || (tt.token > 1 && err != types.ErrFenced) {
+				t.Errorf("Storage.OrderTx() error = %v, wantCommit %v", err, tt.wantCommit)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Storage.OrderTx() %v", err)
+			}
+		})
+	}
+}
+
+func TestGetTrades(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		ThreadID: "c683ok5mk1u1120gnmmg",
+		Db:       db,
+	}
+
+	filter := types.TradeFilter{ThreadID: sessionData.ThreadID, From: 1000, To: 2000, ExitReason: "profit", Limit: 10}
+
+	columns := []string{"ThreadID", "ThreadIDSession", "Symbol", "BuyOrderID", "SellOrderID", "Quantity", "EntryPrice", "ExitPrice", "EntryQuote", "ExitQuote", "Fees", "Profit", "ProfitPct", "EntryTime", "ExitTime", "HoldingTime", "ExitReason"}
+	mock.ExpectBegin()                                                          /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.GetTrades(?,?,?,?,?)")). /* call procedure */
+											WithArgs(filter.ThreadID, filter.From, filter.To, filter.ExitReason, filter.Limit). /* with args */
+											WillReturnRows(sqlmock.NewRows(columns).
+												AddRow(sessionData.ThreadID, "c683ok5mk1u1120gnmn0", "BTCUSDT", 1, 1001, 1, 100, 110, 100, 110, 0.21, 9.79, 0.0979, 1000, 1500, 0, "profit")) /* return 1 row */
+
+	trades, err := GetTrades(context.Background(), sessionData, filter)
+	if err != nil {
+		t.Fatalf("GetTrades() error = %v", err)
+	}
+
+	if len(trades) != 1 || trades[0].SellOrderID != 1001 || trades[0].Profit != 9.79 || trades[0].ExitReason != "profit" {
+		t.Errorf("GetTrades() = %v, want trade closed by SELL order 1001", trades)
+	}
+}
+
+func TestOrderTx_SaveTrade(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		ThreadID:        "c683ok5mk1u1120gnmmg",
+		ThreadIDSession: "c683ok5mk1u1120gnmn0",
+		Db:              db,
+	}
+
+	trade := &types.Trade{ThreadID: sessionData.ThreadID, ThreadIDSession: sessionData.ThreadIDSession, Symbol: "BTCUSDT", BuyOrderID: 1, SellOrderID: 1001, ExitReason: "stoploss"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("call cryptopump.DeleteThreadTransactionByOrderID(?)")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("call cryptopump.SaveTrade(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)")).
+		WithArgs(trade.ThreadID, trade.ThreadIDSession, trade.Symbol, trade.BuyOrderID, trade.SellOrderID,
+			0.0, 0.0, 0.0, 0.0, 0.0, 0.0, 0.0, 0.0, int64(0), int64(0), int64(0), trade.ExitReason).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := (Storage{}).OrderTx(context.Background(), sessionData, func(tx types.OrderTx) error {
+		if err := tx.DeleteThreadTransactionByOrderID(1); err != nil {
+			return err
+		}
+		return tx.SaveTrade(trade)
+	}); err != nil {
+		t.Errorf("Storage.OrderTx() SaveTrade error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Storage.OrderTx() %v", err)
+	}
+}
+
+func TestGetOrderTransactionExecuted(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		Db: db,
+	}
+
+	columns := []string{"ClientOrderId", "CummulativeQuoteQty", "ExecutedQuantity", "OrderID", "OrderIDSource", "Price", "Side", "Status", "Symbol", "TransactTime"}
+	mock.ExpectBegin()                                                                    /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.GetOrderTransactionExecuted(?)")). /* call procedure */
+												WithArgs(int64(4000)). /* with args */
+												WillReturnRows(sqlmock.NewRows(columns).
+													AddRow("a", 100, 1, 1, 0, 100, "BUY", "FILLED", "BTCUSDT", 1000).
+													AddRow("b", 110, 1, 2, 1, 110, "SELL", "FILLED", "BTCUSDT", 2000)) /* return 2 rows */
+
+	orders, err := GetOrderTransactionExecuted(context.Background(), sessionData, 4000)
+	if err != nil {
+		t.Fatalf("GetOrderTransactionExecuted() error = %v", err)
+	}
+
+	if len(orders) != 2 || orders[1].Side != "SELL" || orders[1].OrderIDSource != 1 || orders[1].TransactTime != 2000 {
+		t.Errorf("GetOrderTransactionExecuted() = %v, want BUY order 1 and its SELL order 2", orders)
+	}
+}
+
+func TestGetTradeStats(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		ThreadID: "c683ok5mk1u1120gnmmg",
+		Db:       db,
+	}
+
+	columns := []string{"Count", "Wins", "GrossProfit", "GrossLoss", "HoldingTime"}
+	mock.ExpectBegin()                                                      /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.GetTradeStats(?)")). /* call procedure */
+										WithArgs(sessionData.ThreadID).                                    /* with args */
+										WillReturnRows(sqlmock.NewRows(columns).AddRow(3, 2, 12, 4, 3600)) /* return 1 row */
+
+	stats, err := GetTradeStats(context.Background(), sessionData, sessionData.ThreadID)
+	if err != nil {
+		t.Fatalf("GetTradeStats() error = %v", err)
+	}
+
+	if stats != (types.TradeStats{Count: 3, Wins: 2, GrossProfit: 12, GrossLoss: 4, HoldingTime: 3600}) {
+		t.Errorf("GetTradeStats() = %+v, want 3 trades with 2 wins", stats)
+	}
+}
+
+func TestSaveEquity(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		Db: db,
+	}
+
+	equity := types.Equity{ThreadID: "c683ok5mk1u1120gnmmg", Date: "2021-01-02", Realized: 10, Unrealized: -2, Equity: 8}
+
+	mock.ExpectBegin()                                                           /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.SaveEquity(?,?,?,?,?)")). /* call procedure */
+											WithArgs(equity.ThreadID, equity.Date, equity.Realized, equity.Unrealized, equity.Equity). /* with args */
+											WillReturnRows(sqlmock.NewRows([]string{}))                                                /* return no rows */
+
+	if err := SaveEquity(context.Background(), sessionData, equity); err != nil {
+		t.Errorf("SaveEquity() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("SaveEquity() %v", err)
+	}
+}
+
+func TestGetEquity(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		Db: db,
+	}
+
+	columns := []string{"ThreadID", "Date", "Realized", "Unrealized", "Equity"}
+	mock.ExpectBegin()                                                  /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.GetEquity(?)")). /* call procedure */
+										WithArgs(""). /* with args */
+										WillReturnRows(sqlmock.NewRows(columns).
+											AddRow("", "2021-01-01", 5, 0, 5).
+											AddRow("", "2021-01-02", 10, -2, 8)) /* return 2 rows */
+
+	equity, err := GetEquity(context.Background(), sessionData, "")
+	if err != nil {
+		t.Fatalf("GetEquity() error = %v", err)
+	}
+
+	if len(equity) != 2 || equity[1].Date != "2021-01-02" || equity[1].Equity != 8 || equity[1].Unrealized != -2 {
+		t.Errorf("GetEquity() = %v, want 2 daily snapshots", equity)
+	}
+}
+
+func TestAcquireLease(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		Db: db,
+	}
+
+	columns := []string{"Name", "Holder", "Token", "Expires"}
+	mock.ExpectBegin()                                                         /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.AcquireLease(?,?,?)")). /* call procedure */
+											WithArgs("master", "a", int64(30000)).                                           /* with args */
+											WillReturnRows(sqlmock.NewRows(columns).AddRow("master", "b", 2, 1600000030000)) /* return 1 row */
+
+	lease, err := AcquireLease(context.Background(), sessionData, "master", "a", 30000)
+	if err != nil || lease.Holder != "b" || lease.Token != 2 || lease.Expires != 1600000030000 {
+		t.Errorf("AcquireLease() = %+v, %v, want lease held by b", lease, err)
+	}
+}
+
+func TestReleaseLease(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		Db: db,
+	}
+
+	mock.ExpectBegin()                                                       /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.ReleaseLease(?,?)")). /* call procedure */
+											WithArgs("master", "a").                    /* with args */
+											WillReturnRows(sqlmock.NewRows([]string{})) /* return no rows */
+
+	if err := ReleaseLease(context.Background(), sessionData, "master", "a"); err != nil {
+		t.Errorf("ReleaseLease() error = %v", err)
+	}
+}
+
+func TestSaveNode(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		Db: db,
+	}
+
+	mock.ExpectBegin()                                                           /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.SaveNode(?,?,?,?,?,?)")). /* call procedure */
+											WithArgs("c683ok5mk1u1120gnmmg", "host1", "8080", "dev", "BTCUSDT", int64(1600000000000)). /* with args */
+											WillReturnRows(sqlmock.NewRows([]string{}))                                                /* return no rows */
+
+	if err := SaveNode(context.Background(), sessionData, types.Node{
+		ThreadID: "c683ok5mk1u1120gnmmg",
+		Hostname: "host1",
+		Port:     "8080",
+		Version:  "dev",
+		Symbol:   "BTCUSDT",
+		Started:  1600000000000,
+	}); err != nil {
+		t.Errorf("SaveNode() error = %v", err)
+	}
+}
+
+func TestGetNodes(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		Db: db,
+	}
+
+	columns := []string{"ThreadID", "Hostname", "Port", "Version", "Symbol", "Started", "Heartbeat", "Age"}
+	mock.ExpectBegin()                                                /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.GetNodes()")). /* call procedure */
+										WillReturnRows(sqlmock.NewRows(columns).
+											AddRow("c6p5gbdmk1u5ro6ptnp0", "host2", "8081", "dev", "ETHUSDT", 1600000100000, 1600000200000, 5000).
+											AddRow("c683ok5mk1u1120gnmmg", "host1", "8080", "dev", "BTCUSDT", 1600000000000, 1600000100000, 100005000)) /* return 2 rows */
+
+	nodes, err := GetNodes(context.Background(), sessionData)
+	if err != nil {
+		t.Fatalf("GetNodes() error = %v", err)
+	}
+
+	if len(nodes) != 2 || nodes[0].Hostname != "host2" || nodes[1].Port != "8080" || nodes[1].Age != 100005000 {
+		t.Errorf("GetNodes() = %+v, want 2 nodes", nodes)
+	}
+}
+
+func TestDeleteNode(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		Db: db,
+	}
+
+	mock.ExpectBegin()                                                   /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.DeleteNode(?)")). /* call procedure */
+										WithArgs("c683ok5mk1u1120gnmmg").           /* with args */
+										WillReturnRows(sqlmock.NewRows([]string{})) /* return no rows */
+
+	if err := DeleteNode(context.Background(), sessionData, "c683ok5mk1u1120gnmmg"); err != nil {
+		t.Errorf("DeleteNode() error = %v", err)
+	}
+}
+
+func TestGetThreadOrphans(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		Db: db,
+	}
+
+	columns := []string{"ThreadID", "ThreadIDSession", "Hostname", "Port", "Age"}
+	mock.ExpectBegin()                                                         /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.GetThreadOrphans(?)")). /* call procedure */
+											WithArgs(int64(60000)).                                                                                                 /* with args */
+											WillReturnRows(sqlmock.NewRows(columns).AddRow("c683ok5mk1u1120gnmmg", "c683ok5mk1u1120gnmn0", "host1", "8080", 75000)) /* return 1 row */
+
+	orphans, err := GetThreadOrphans(context.Background(), sessionData, 60000)
+	if err != nil || len(orphans) != 1 || orphans[0].ThreadIDSession != "c683ok5mk1u1120gnmn0" || orphans[0].Age != 75000 {
+		t.Errorf("GetThreadOrphans() = %+v, %v, want 1 orphan", orphans, err)
+	}
+}
+
+func TestSaveAudit(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		Db: db,
+	}
+
+	mock.ExpectBegin()                                                          /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.SaveAudit(?,?,?,?,?)")). /* call procedure */
+											WithArgs(int64(1600000000000), "c683ok5mk1u1120gnmmg", "host2:8081", "takeover", "from host1:8080"). /* with args */
+											WillReturnRows(sqlmock.NewRows([]string{}))                                                          /* return no rows */
+
+	if err := SaveAudit(context.Background(), sessionData, types.Audit{
+		Time:     1600000000000,
+		ThreadID: "c683ok5mk1u1120gnmmg",
+		Node:     "host2:8081",
+		Action:   "takeover",
+		Detail:   "from host1:8080",
+	}); err != nil {
+		t.Errorf("SaveAudit() error = %v", err)
+	}
+}
+
+func TestGetAudit(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		Db: db,
+	}
+
+	columns := []string{"ID", "Time", "ThreadID", "Node", "Action", "Detail"}
+	mock.ExpectBegin()                                                 /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.GetAudit(?)")). /* call procedure */
+										WithArgs(int64(0)).                                                                                                                    /* with args */
+										WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1600000000000, "c683ok5mk1u1120gnmmg", "host2:8081", "takeover", "from host1:8080")) /* return 1 row */
+
+	audit, err := GetAudit(context.Background(), sessionData, 0)
+	if err != nil || len(audit) != 1 || audit[0].Node != "host2:8081" || audit[0].Action != "takeover" {
+		t.Errorf("GetAudit() = %+v, %v, want 1 record", audit, err)
+	}
+}
+
+func TestSavePause(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		Db: db,
+	}
+
+	mock.ExpectBegin()                                                          /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.SavePause(?,?,?,?,?)")). /* call procedure */
+											WithArgs("c683ok5mk1u1120gnmmg", "nobuy", "telegram @user", int64(1600000000000), true). /* with args */
+											WillReturnRows(sqlmock.NewRows([]string{}))                                              /* return no rows */
+
+	if err := SavePause(context.Background(), sessionData, types.Pause{
+		ThreadID: "c683ok5mk1u1120gnmmg",
+		Mode:     "nobuy",
+		By:       "telegram @user",
+		Time:     1600000000000,
+		Confirm:  true,
+	}); err != nil {
+		t.Errorf("SavePause() error = %v", err)
+	}
+}
+
+func TestGetPause(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		Db: db,
+	}
+
+	columns := []string{"ThreadID", "Mode", "PausedBy", "Time", "Confirm"}
+	mock.ExpectBegin()                                                 /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.GetPause(?)")). /* call procedure */
+										WithArgs("c683ok5mk1u1120gnmmg").                                                                                      /* with args */
+										WillReturnRows(sqlmock.NewRows(columns).AddRow("c683ok5mk1u1120gnmmg", "frozen", "web 10.0.0.1", 1600000000000, true)) /* return 1 row */
+
+	pause, err := GetPause(context.Background(), sessionData, "c683ok5mk1u1120gnmmg")
+	if err != nil || pause.Mode != "frozen" || pause.By != "web 10.0.0.1" || !pause.Confirm {
+		t.Errorf("GetPause() = %+v, %v, want frozen by web 10.0.0.1", pause, err)
+	}
+}
+
+func TestDeletePause(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		Db: db,
+	}
+
+	mock.ExpectBegin()                                                    /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.DeletePause(?)")). /* call procedure */
+										WithArgs("c683ok5mk1u1120gnmmg").           /* with args */
+										WillReturnRows(sqlmock.NewRows([]string{})) /* return no rows */
+
+	if err := DeletePause(context.Background(), sessionData, "c683ok5mk1u1120gnmmg"); err != nil {
+		t.Errorf("DeletePause() error = %v", err)
+	}
+}
+
+func TestArchiveOrders(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	sessionData := &types.Session{
+		Db: db,
+	}
+
+	mock.ExpectBegin()                                                      /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.ArchiveOrders(?)")). /* call procedure */
+										WithArgs(int64(864000000)).                                     /* with args */
+										WillReturnRows(sqlmock.NewRows([]string{"archived"}).AddRow(5)) /* return 1 row */
+
+	archived, err := ArchiveOrders(context.Background(), sessionData, 864000000)
+	if err != nil || archived != 5 {
+		t.Errorf("ArchiveOrders() = %v, %v, want 5", archived, err)
+	}
+}
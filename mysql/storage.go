@@ -0,0 +1,272 @@
+package mysql
+
+import (
+	"context"
+
+	"github.com/aleibovici/cryptopump/types"
+)
+
+// Storage MySQL storage backend. Every method calls the cryptopump stored procedure of the same name.
+type Storage struct{}
+
+var _ types.Storage = Storage{} /* Storage must implement types.Storage */
+
+// SaveOrder call SaveOrder stored procedure
+func (Storage) SaveOrder(ctx context.Context, sessionData *types.Session, order *types.Order, orderIDSource int64, orderPrice float64) error {
+	return SaveOrder(ctx, sessionData, order, orderIDSource, orderPrice)
+}
+
+// UpdateOrder call UpdateOrder stored procedure
+func (Storage) UpdateOrder(ctx context.Context, sessionData *types.Session, OrderID int64, CumulativeQuoteQuantity float64, ExecutedQuantity float64, Price float64, Status string) error {
+	return UpdateOrder(ctx, sessionData, OrderID, CumulativeQuoteQuantity, ExecutedQuantity, Price, Status)
+}
+
+// GetOrderByOrderID call GetOrderByOrderID stored procedure
+func (Storage) GetOrderByOrderID(ctx context.Context, sessionData *types.Session) (types.Order, error) {
+	return GetOrderByOrderID(ctx, sessionData)
+}
+
+// GetOrderSymbol call GetOrderSymbol stored procedure
+func (Storage) GetOrderSymbol(ctx context.Context, sessionData *types.Session) (string, error) {
+	return GetOrderSymbol(ctx, sessionData)
+}
+
+// GetOrderTransactionPending call GetOrderTransactionPending stored procedure
+func (Storage) GetOrderTransactionPending(ctx context.Context, sessionData *types.Session) (types.Order, error) {
+	return GetOrderTransactionPending(ctx, sessionData)
+}
+
+// GetOrderTransactionCount call GetOrderTransactionCount stored procedure
+func (Storage) GetOrderTransactionCount(ctx context.Context, sessionData *types.Session, side string) (float64, error) {
+	return GetOrderTransactionCount(ctx, sessionData, side)
+}
+
+// GetOrderTransactionExecuted call GetOrderTransactionExecuted stored procedure
+func (Storage) GetOrderTransactionExecuted(ctx context.Context, sessionData *types.Session, before int64) ([]types.Order, error) {
+	return GetOrderTransactionExecuted(ctx, sessionData, before)
+}
+
+// GetOrders call GetOrders stored procedure
+func (Storage) GetOrders(ctx context.Context, sessionData *types.Session, filter types.OrderFilter) ([]types.Order, error) {
+	return GetOrders(ctx, sessionData, filter)
+}
+
+// ArchiveOrders call ArchiveOrders stored procedure
+func (Storage) ArchiveOrders(ctx context.Context, sessionData *types.Session, before int64) (int64, error) {
+	return ArchiveOrders(ctx, sessionData, before)
+}
+
+// GetOrderTransactionSideLastTwo call GetOrderTransactionSideLastTwo stored procedure
+func (Storage) GetOrderTransactionSideLastTwo(ctx context.Context, sessionData *types.Session) (string, string, error) {
+	return GetOrderTransactionSideLastTwo(ctx, sessionData)
+}
+
+// GetLastOrderTransactionPrice call GetLastOrderTransactionPrice stored procedure
+func (Storage) GetLastOrderTransactionPrice(ctx context.Context, sessionData *types.Session, Side string) (float64, error) {
+	return GetLastOrderTransactionPrice(ctx, sessionData, Side)
+}
+
+// GetLastOrderTransactionSide call GetLastOrderTransactionSide stored procedure
+func (Storage) GetLastOrderTransactionSide(ctx context.Context, sessionData *types.Session) (string, error) {
+	return GetLastOrderTransactionSide(ctx, sessionData)
+}
+
+// SaveThreadTransaction call SaveThreadTransaction stored procedure
+func (Storage) SaveThreadTransaction(ctx context.Context, sessionData *types.Session, OrderID int64, CumulativeQuoteQuantity float64, Price float64, ExecutedQuantity float64) error {
+	return SaveThreadTransaction(ctx, sessionData, OrderID, CumulativeQuoteQuantity, Price, ExecutedQuantity)
+}
+
+// DeleteThreadTransactionByOrderID call DeleteThreadTransactionByOrderID stored procedure
+func (Storage) DeleteThreadTransactionByOrderID(ctx context.Context, sessionData *types.Session, orderID int) error {
+	return DeleteThreadTransactionByOrderID(ctx, sessionData, orderID)
+}
+
+// GetThreadTransactionCount call GetThreadTransactionCount stored procedure
+func (Storage) GetThreadTransactionCount(ctx context.Context, sessionData *types.Session) (int, error) {
+	return GetThreadTransactionCount(ctx, sessionData)
+}
+
+// GetThreadTransactionDistinct call GetThreadTransactionDistinct stored procedure
+func (Storage) GetThreadTransactionDistinct(ctx context.Context, sessionData *types.Session) (string, string, error) {
+	return GetThreadTransactionDistinct(ctx, sessionData)
+}
+
+// GetThreadTransactionByPrice call GetThreadTransactionByPrice stored procedure
+func (Storage) GetThreadTransactionByPrice(ctx context.Context, marketData *types.Market, sessionData *types.Session) (types.Order, error) {
+	return GetThreadTransactionByPrice(ctx, marketData, sessionData)
+}
+
+// GetThreadTransactionByPriceHigher call GetThreadTransactionByPriceHigher stored procedure
+func (Storage) GetThreadTransactionByPriceHigher(ctx context.Context, marketData *types.Market, sessionData *types.Session) (types.Order, error) {
+	return GetThreadTransactionByPriceHigher(ctx, marketData, sessionData)
+}
+
+// GetThreadTransactionByThreadID call GetThreadTransactionByThreadID stored procedure
+func (Storage) GetThreadTransactionByThreadID(ctx context.Context, sessionData *types.Session) ([]types.Order, error) {
+	return GetThreadTransactionByThreadID(ctx, sessionData)
+}
+
+// GetThreadTransactionAll call GetThreadTransactionAll stored procedure
+func (Storage) GetThreadTransactionAll(ctx context.Context, sessionData *types.Session) ([]types.Order, error) {
+	return GetThreadTransactionAll(ctx, sessionData)
+}
+
+// GetThreadTransactiontUpmarketPriceCount call GetThreadTransactiontUpmarketPriceCount stored procedure
+func (Storage) GetThreadTransactiontUpmarketPriceCount(ctx context.Context, sessionData *types.Session, price float64) (int, error) {
+	return GetThreadTransactiontUpmarketPriceCount(ctx, sessionData, price)
+}
+
+// GetThreadLastTransaction call GetThreadLastTransaction stored procedure
+func (Storage) GetThreadLastTransaction(ctx context.Context, sessionData *types.Session) (types.Order, error) {
+	return GetThreadLastTransaction(ctx, sessionData)
+}
+
+// GetThreadCount call GetThreadCount stored procedure
+func (Storage) GetThreadCount(ctx context.Context, sessionData *types.Session) (int, error) {
+	return GetThreadCount(ctx, sessionData)
+}
+
+// GetThreadAmount call GetThreadTransactionAmount stored procedure
+func (Storage) GetThreadAmount(ctx context.Context, sessionData *types.Session) (float64, error) {
+	return GetThreadAmount(ctx, sessionData)
+}
+
+// GetThreadTransactionMissing call GetThreadTransactionMissing stored procedure
+func (Storage) GetThreadTransactionMissing(ctx context.Context, sessionData *types.Session) ([]types.Order, error) {
+	return GetThreadTransactionMissing(ctx, sessionData)
+}
+
+// GetThreadTransactionSold call GetThreadTransactionSold stored procedure
+func (Storage) GetThreadTransactionSold(ctx context.Context, sessionData *types.Session) ([]types.Order, error) {
+	return GetThreadTransactionSold(ctx, sessionData)
+}
+
+// GetTrades call GetTrades stored procedure
+func (Storage) GetTrades(ctx context.Context, sessionData *types.Session, filter types.TradeFilter) ([]types.Trade, error) {
+	return GetTrades(ctx, sessionData, filter)
+}
+
+// GetTradeStats call GetTradeStats stored procedure
+func (Storage) GetTradeStats(ctx context.Context, sessionData *types.Session, threadID string) (types.TradeStats, error) {
+	return GetTradeStats(ctx, sessionData, threadID)
+}
+
+// SaveEquity call SaveEquity stored procedure
+func (Storage) SaveEquity(ctx context.Context, sessionData *types.Session, equity types.Equity) error {
+	return SaveEquity(ctx, sessionData, equity)
+}
+
+// GetEquity call GetEquity stored procedure
+func (Storage) GetEquity(ctx context.Context, sessionData *types.Session, threadID string) ([]types.Equity, error) {
+	return GetEquity(ctx, sessionData, threadID)
+}
+
+// AcquireLease call AcquireLease stored procedure
+func (Storage) AcquireLease(ctx context.Context, sessionData *types.Session, name string, holder string, duration int64) (types.Lease, error) {
+	return AcquireLease(ctx, sessionData, name, holder, duration)
+}
+
+// ReleaseLease call ReleaseLease stored procedure
+func (Storage) ReleaseLease(ctx context.Context, sessionData *types.Session, name string, holder string) error {
+	return ReleaseLease(ctx, sessionData, name, holder)
+}
+
+// SaveNode call SaveNode stored procedure
+func (Storage) SaveNode(ctx context.Context, sessionData *types.Session, node types.Node) error {
+	return SaveNode(ctx, sessionData, node)
+}
+
+// GetNodes call GetNodes stored procedure
+func (Storage) GetNodes(ctx context.Context, sessionData *types.Session) ([]types.Node, error) {
+	return GetNodes(ctx, sessionData)
+}
+
+// DeleteNode call DeleteNode stored procedure
+func (Storage) DeleteNode(ctx context.Context, sessionData *types.Session, threadID string) error {
+	return DeleteNode(ctx, sessionData, threadID)
+}
+
+// GetThreadOrphans call GetThreadOrphans stored procedure
+func (Storage) GetThreadOrphans(ctx context.Context, sessionData *types.Session, age int64) ([]types.Orphan, error) {
+	return GetThreadOrphans(ctx, sessionData, age)
+}
+
+// SaveAudit call SaveAudit stored procedure
+func (Storage) SaveAudit(ctx context.Context, sessionData *types.Session, audit types.Audit) error {
+	return SaveAudit(ctx, sessionData, audit)
+}
+
+// GetAudit call GetAudit stored procedure
+func (Storage) GetAudit(ctx context.Context, sessionData *types.Session, since int64) ([]types.Audit, error) {
+	return GetAudit(ctx, sessionData, since)
+}
+
+// SavePause call SavePause stored procedure
+func (Storage) SavePause(ctx context.Context, sessionData *types.Session, pause types.Pause) error {
+	return SavePause(ctx, sessionData, pause)
+}
+
+// GetPause call GetPause stored procedure
+func (Storage) GetPause(ctx context.Context, sessionData *types.Session, threadID string) (types.Pause, error) {
+	return GetPause(ctx, sessionData, threadID)
+}
+
+// DeletePause call DeletePause stored procedure
+func (Storage) DeletePause(ctx context.Context, sessionData *types.Session, threadID string) error {
+	return DeletePause(ctx, sessionData, threadID)
+}
+
+// SaveSession call SaveSession stored procedure
+func (Storage) SaveSession(ctx context.Context, configData *types.Config, sessionData *types.Session) error {
+	return SaveSession(ctx, configData, sessionData)
+}
+
+// UpdateSession call UpdateSession stored procedure
+func (Storage) UpdateSession(ctx context.Context, configData *types.Config, sessionData *types.Session) error {
+	return UpdateSession(ctx, configData, sessionData)
+}
+
+// DeleteSession call DeleteSession stored procedure
+func (Storage) DeleteSession(ctx context.Context, sessionData *types.Session) error {
+	return DeleteSession(ctx, sessionData)
+}
+
+// GetSessionStatus call GetSessionStatus stored procedure
+func (Storage) GetSessionStatus(ctx context.Context, sessionData *types.Session) (string, error) {
+	return GetSessionStatus(ctx, sessionData)
+}
+
+// GetSessions call GetSessions stored procedure
+func (Storage) GetSessions(ctx context.Context, sessionData *types.Session) ([]types.SessionRecord, error) {
+	return GetSessions(ctx, sessionData)
+}
+
+// SaveGlobal call SaveGlobal stored procedure
+func (Storage) SaveGlobal(ctx context.Context, sessionData *types.Session) error {
+	return SaveGlobal(ctx, sessionData)
+}
+
+// UpdateGlobal call UpdateGlobal stored procedure
+func (Storage) UpdateGlobal(ctx context.Context, sessionData *types.Session) error {
+	return UpdateGlobal(ctx, sessionData)
+}
+
+// GetGlobal call GetGlobal stored procedure
+func (Storage) GetGlobal(ctx context.Context, sessionData *types.Session) (float64, float64, float64, int64, error) {
+	return GetGlobal(ctx, sessionData)
+}
+
+// GetProfit call GetProfit stored procedure
+func (Storage) GetProfit(ctx context.Context, sessionData *types.Session) (float64, float64, float64, error) {
+	return GetProfit(ctx, sessionData)
+}
+
+// GetProfitByThreadID call GetProfitByThreadID stored procedure
+func (Storage) GetProfitByThreadID(ctx context.Context, sessionData *types.Session) (float64, float64, error) {
+	return GetProfitByThreadID(ctx, sessionData)
+}
+
+// GetProfitByThreadIDSince call GetProfitByThreadIDSince stored procedure
+func (Storage) GetProfitByThreadIDSince(ctx context.Context, sessionData *types.Session, transactTime int64) (float64, error) {
+	return GetProfitByThreadIDSince(ctx, sessionData, transactTime)
+}
@@ -0,0 +1,187 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/aleibovici/cryptopump/functions"
+	"github.com/aleibovici/cryptopump/logger"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+/* Order writes calling the cryptopump stored procedures on a database transaction */
+type orderTx struct {
+	ctx         context.Context
+	sessionData *types.Session
+	tx          *sql.Tx
+}
+
+// OrderTx run f in one database transaction committed when f returns nil and rolled back otherwise
+func (Storage) OrderTx(
+	ctx context.Context,
+	sessionData *types.Session,
+	f func(tx types.OrderTx) error) (err error) {
+
+	var tx *sql.Tx
+
+	/* Conditional defer logging when the transaction fails */
+	defer func() {
+		if err != nil {
+			logger.LogEntry{ /* Log Entry */
+				Config:   nil,
+				Market:   nil,
+				Session:  sessionData,
+				Order:    &types.Order{},
+				Message:  functions.GetFunctionName() + " - " + err.Error(),
+				LogLevel: "DebugLevel",
+			}.Do()
+		}
+	}()
+
+	if tx, err = sessionData.Db.BeginTx(ctx, nil); err != nil {
+
+		return err
+
+	}
+
+	if err = fence(ctx, sessionData, tx); err != nil {
+
+		_ = tx.Rollback()
+
+		return err
+
+	}
+
+	if err = f(orderTx{ctx: ctx, sessionData: sessionData, tx: tx}); err != nil {
+
+		_ = tx.Rollback()
+
+		return err
+
+	}
+
+	return tx.Commit()
+
+}
+
+// Return ErrFenced when the thread lease held by the session changed hands. GetLeaseForUpdate locks the lease
+// row, so the lease can't change hands before the transaction ends.
+func fence(
+	ctx context.Context,
+	sessionData *types.Session,
+	tx *sql.Tx) error {
+
+	var lease types.Lease
+
+	if sessionData.ThreadLease.Token == 0 {
+
+		return nil
+
+	}
+
+	if err := tx.QueryRowContext(ctx, "call cryptopump.GetLeaseForUpdate(?)",
+		sessionData.ThreadLease.Name).Scan(&lease.Name, &lease.Holder, &lease.Token, &lease.Expires); err == sql.ErrNoRows {
+
+		return types.ErrFenced
+
+	} else if err != nil {
+
+		return err
+
+	}
+
+	if lease.Holder != sessionData.ThreadLease.Holder || lease.Token != sessionData.ThreadLease.Token {
+
+		return types.ErrFenced
+
+	}
+
+	return nil
+
+}
+
+// SaveOrder call SaveOrder stored procedure
+func (o orderTx) SaveOrder(order *types.Order, orderIDSource int64, orderPrice float64) error {
+
+	_, err := o.tx.ExecContext(o.ctx, "call cryptopump.SaveOrder(?,?,?,?,?,?,?,?,?,?,?,?)",
+		order.ClientOrderID,
+		order.CumulativeQuoteQuantity,
+		order.ExecutedQuantity,
+		order.OrderID,
+		orderIDSource, /* OrderIDSource */
+		orderPrice,
+		order.Side,
+		order.Status,
+		order.Symbol,
+		order.TransactTime,
+		o.sessionData.ThreadID,
+		o.sessionData.ThreadIDSession)
+
+	return err
+
+}
+
+// UpdateOrder call UpdateOrder stored procedure
+func (o orderTx) UpdateOrder(OrderID int64, CumulativeQuoteQuantity float64, ExecutedQuantity float64, Price float64, Status string) error {
+
+	_, err := o.tx.ExecContext(o.ctx, "call cryptopump.UpdateOrder(?,?,?,?,?)",
+		OrderID,
+		CumulativeQuoteQuantity,
+		ExecutedQuantity,
+		Price,
+		Status)
+
+	return err
+
+}
+
+// SaveThreadTransaction call SaveThreadTransaction stored procedure
+func (o orderTx) SaveThreadTransaction(OrderID int64, CumulativeQuoteQuantity float64, Price float64, ExecutedQuantity float64) error {
+
+	_, err := o.tx.ExecContext(o.ctx, "call cryptopump.SaveThreadTransaction(?,?,?,?,?,?)",
+		o.sessionData.ThreadID,
+		o.sessionData.ThreadIDSession,
+		OrderID,
+		CumulativeQuoteQuantity,
+		Price,
+		ExecutedQuantity)
+
+	return err
+
+}
+
+// DeleteThreadTransactionByOrderID call DeleteThreadTransactionByOrderID stored procedure
+func (o orderTx) DeleteThreadTransactionByOrderID(orderID int) error {
+
+	_, err := o.tx.ExecContext(o.ctx, "call cryptopump.DeleteThreadTransactionByOrderID(?)",
+		orderID)
+
+	return err
+
+}
+
+// SaveTrade call SaveTrade stored procedure
+func (o orderTx) SaveTrade(trade *types.Trade) error {
+
+	_, err := o.tx.ExecContext(o.ctx, "call cryptopump.SaveTrade(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)",
+		trade.ThreadID,
+		trade.ThreadIDSession,
+		trade.Symbol,
+		trade.BuyOrderID,
+		trade.SellOrderID,
+		trade.Quantity,
+		trade.EntryPrice,
+		trade.ExitPrice,
+		trade.EntryQuote,
+		trade.ExitQuote,
+		trade.Fees,
+		trade.Profit,
+		trade.ProfitPct,
+		trade.EntryTime,
+		trade.ExitTime,
+		trade.HoldingTime,
+		trade.ExitReason)
+
+	return err
+
+}
@@ -0,0 +1,155 @@
+package nodes
+
+import (
+	"context"
+	"net"
+	"os"
+	"time"
+
+	"github.com/aleibovici/cryptopump/types"
+)
+
+/* Node status in the cluster node registry. Heartbeats are saved by CheckStatus every 10 seconds. */
+const (
+	downAfter   = 60 * time.Second /* A node is down when its last heartbeat is older */
+	removeAfter = 24 * time.Hour   /* Down nodes are removed from the registry by the Master Node */
+)
+
+// Cluster struct define the cluster view
+type Cluster struct {
+	ThreadID string   `json:"threadID"` /* ThreadID of this node */
+	Master   string   `json:"master"`   /* ThreadID of the Master Node */
+	Nodes    []Member `json:"nodes"`
+	Up       int      `json:"up"`
+	Down     int      `json:"down"`
+}
+
+// Member struct define a node of the cluster view
+type Member struct {
+	types.Node
+	Down     bool   `json:"down"` /* Last heartbeat older than 60 seconds */
+	Master   bool   `json:"master"`
+	URL      string `json:"url"` /* Web UI of the node */
+	Start    string `json:"-"`   /* Started as YYYY-MM-DD hh:mm:ss UTC */
+	LastSeen string `json:"-"`   /* Age as duration, i.e. 1m2s */
+}
+
+// Register renew the thread lease and save the node of the session thread in the cluster node registry with a
+// new heartbeat. It returns ErrTakenOver when another node took over the thread. The Master Node also removes
+// the nodes down for more than 24 hours.
+func (Node) Register(sessionData *types.Session) error {
+
+	if acquired, err := (Node{}).AcquireThread(sessionData); err != nil {
+
+		return err
+
+	} else if !acquired {
+
+		return ErrTakenOver
+
+	}
+
+	hostname, _ := os.Hostname()
+
+	if err := sessionData.Storage.SaveNode(context.Background(), sessionData, types.Node{
+		ThreadID: sessionData.ThreadID,
+		Hostname: hostname,
+		Port:     sessionData.Port,
+		Version:  sessionData.Version,
+		Symbol:   sessionData.Symbol,
+		Started:  sessionData.Started.UnixNano() / int64(time.Millisecond),
+	}); err != nil {
+
+		return err
+
+	}
+
+	if !(Node{}).IsMaster(sessionData) {
+
+		return nil
+
+	}
+
+	nodes, err := sessionData.Storage.GetNodes(context.Background(), sessionData)
+	if err != nil {
+
+		return err
+
+	}
+
+	for _, node := range nodes {
+
+		if time.Duration(node.Age)*time.Millisecond > removeAfter {
+
+			if err = sessionData.Storage.DeleteNode(context.Background(), sessionData, node.ThreadID); err != nil {
+
+				return err
+
+			}
+
+		}
+
+	}
+
+	return nil
+
+}
+
+// Unregister remove the node of the session thread from the cluster node registry and release the thread lease,
+// so the thread can be resumed on another node without waiting for the lease to expire
+func (Node) Unregister(sessionData *types.Session) error {
+
+	if err := sessionData.Storage.DeleteNode(context.Background(), sessionData, sessionData.ThreadID); err != nil {
+
+		return err
+
+	}
+
+	return Node{}.ReleaseThread(sessionData)
+
+}
+
+// GetCluster return the cluster view, nodes most recently started first
+func (Node) GetCluster(sessionData *types.Session) (cluster Cluster, err error) {
+
+	var nodes []types.Node
+
+	if nodes, err = sessionData.Storage.GetNodes(context.Background(), sessionData); err != nil {
+
+		return Cluster{}, err
+
+	}
+
+	cluster.ThreadID = sessionData.ThreadID
+	cluster.Master = sessionData.Lease.Holder
+
+	for _, node := range nodes {
+
+		age := time.Duration(node.Age) * time.Millisecond
+
+		member := Member{
+			Node:     node,
+			Down:     age > downAfter,
+			Master:   node.ThreadID == cluster.Master,
+			URL:      "http://" + net.JoinHostPort(node.Hostname, node.Port) + "/",
+			Start:    time.Unix(0, node.Started*int64(time.Millisecond)).UTC().Format("2006-01-02 15:04:05"),
+			LastSeen: age.Round(time.Second).String(),
+		}
+
+		if member.Down {
+
+			cluster.Down++
+
+		} else {
+
+			cluster.Up++
+
+		}
+
+		cluster.Nodes = append(cluster.Nodes, member)
+
+	}
+
+	return cluster, nil
+
+}
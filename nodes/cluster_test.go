@@ -0,0 +1,85 @@
+package nodes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aleibovici/cryptopump/types"
+)
+
+func TestNode_GetCluster(t *testing.T) {
+
+	db, a, b := newSessions(t)
+	a.Port, a.Symbol, a.Started = "8080", "BTCUSDT", time.Now().Add(-time.Hour)
+	b.Port, b.Symbol, b.Started = "8081", "ETHUSDT", time.Now()
+
+	Node{}.GetRole(&types.Config{}, a)
+
+	for _, sessionData := range []*types.Session{a, b} {
+		if err := (Node{}).Register(sessionData); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+	}
+
+	/* b stops sending heartbeats */
+	if _, err := db.Exec(`UPDATE nodes SET Heartbeat = Heartbeat - 120000 WHERE ThreadID = ?`, b.ThreadID); err != nil {
+		t.Fatal(err)
+	}
+
+	cluster, err := (Node{}).GetCluster(a)
+	if err != nil {
+		t.Fatalf("GetCluster() error = %v", err)
+	}
+
+	if cluster.Up != 1 || cluster.Down != 1 || len(cluster.Nodes) != 2 {
+		t.Fatalf("GetCluster() = %+v, want 1 node up and 1 down", cluster)
+	}
+
+	tests := []struct {
+		name   string
+		member Member
+		want   Member
+	}{
+		{
+			name:   "down",
+			member: cluster.Nodes[0],
+			want:   Member{Node: types.Node{ThreadID: b.ThreadID, Port: "8081", Symbol: "ETHUSDT"}, Down: true, LastSeen: "2m0s"},
+		},
+		{
+			name:   "master",
+			member: cluster.Nodes[1],
+			want:   Member{Node: types.Node{ThreadID: a.ThreadID, Port: "8080", Symbol: "BTCUSDT"}, Master: true, LastSeen: "0s"},
+		},
+	}
+
+	for _, tt := range tests {
+		got := tt.member
+		if got.ThreadID != tt.want.ThreadID || got.Port != tt.want.Port || got.Symbol != tt.want.Symbol ||
+			got.Down != tt.want.Down || got.Master != tt.want.Master || got.LastSeen != tt.want.LastSeen ||
+			got.URL != "http://"+got.Hostname+":"+got.Port+"/" {
+			t.Errorf("%s: GetCluster() node = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	/* The Master Node removes nodes down for more than 24 hours */
+	if _, err := db.Exec(`UPDATE nodes SET Heartbeat = Heartbeat - 86400000 WHERE ThreadID = ?`, b.ThreadID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (Node{}).Register(a); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	if cluster, err = (Node{}).GetCluster(a); err != nil || len(cluster.Nodes) != 1 || cluster.Nodes[0].ThreadID != a.ThreadID {
+		t.Errorf("GetCluster() after Register() by the Master Node = %+v, %v, want %v only", cluster, err, a.ThreadID)
+	}
+
+	if err := (Node{}).Unregister(a); err != nil {
+		t.Fatalf("Unregister() error = %v", err)
+	}
+
+	if cluster, err = (Node{}).GetCluster(a); err != nil || len(cluster.Nodes) != 0 {
+		t.Errorf("GetCluster() after Unregister() = %+v, %v, want no nodes", cluster, err)
+	}
+
+}
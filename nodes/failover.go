@@ -0,0 +1,147 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/aleibovici/cryptopump/logger"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+// The node running a thread holds the thread lease, renewed with the registry heartbeat, so a thread is never
+// run by two nodes
+const threadLease = "thread:" /* Thread lease name prefix */
+
+// ActionTakeover is the audit log action of a thread taken over from a down node
+const ActionTakeover = "takeover"
+
+// ErrTakenOver is returned by Register when another node took over the thread lease
+var ErrTakenOver = errors.New("thread taken over by another node")
+
+/* Return the node name holding thread leases as hostname:port */
+func instance(sessionData *types.Session) string {
+
+	hostname, _ := os.Hostname()
+
+	return net.JoinHostPort(hostname, sessionData.Port)
+
+}
+
+// AcquireThread take the lease of the session thread when it is free or expired, or renew it when the node
+// already holds it, and return true when the node holds the lease. The lease held is kept in the session, so
+// order writes are fenced by its token (see Storage.OrderTx).
+func (Node) AcquireThread(sessionData *types.Session) (bool, error) {
+
+	lease, err := sessionData.Storage.AcquireLease(
+		context.Background(),
+		sessionData,
+		threadLease+sessionData.ThreadID,
+		instance(sessionData),
+		int64(downAfter/time.Millisecond))
+
+	if err != nil {
+
+		return false, err
+
+	}
+
+	if lease.Holder != instance(sessionData) {
+
+		return false, nil
+
+	}
+
+	sessionData.ThreadLease = lease
+
+	return true, nil
+
+}
+
+// ReleaseThread release the lease of the session thread held by the node, i.e. after a sale from the command line
+func (Node) ReleaseThread(sessionData *types.Session) error {
+
+	return sessionData.Storage.ReleaseLease(context.Background(), sessionData, threadLease+sessionData.ThreadID, instance(sessionData))
+
+}
+
+// Takeover select a thread with Thread transactions whose node is down and with a configuration file on this
+// node, take its lease and set it as the session thread. It returns false when no thread is taken over.
+func (Node) Takeover(sessionData *types.Session) (bool, error) {
+
+	orphans, err := sessionData.Storage.GetThreadOrphans(context.Background(), sessionData, int64(downAfter/time.Millisecond))
+	if err != nil {
+
+		return false, err
+
+	}
+
+	for _, orphan := range orphans {
+
+		/* The thread is resumed with its own configuration */
+		if _, err = os.Stat("./config/" + orphan.ThreadID + ".yml"); err != nil {
+
+			logger.LogEntry{ /* Log Entry */
+				Config:   nil,
+				Market:   nil,
+				Session:  sessionData,
+				Order:    &types.Order{},
+				Message:  "FAILOVER - thread " + orphan.ThreadID + " skipped, no configuration file",
+				LogLevel: "DebugLevel",
+			}.Do()
+
+			continue
+
+		}
+
+		sessionData.ThreadID = orphan.ThreadID
+
+		acquired, err := Node{}.AcquireThread(sessionData)
+		if err != nil || !acquired {
+
+			sessionData.ThreadID = ""
+
+			if err != nil {
+
+				return false, err
+
+			}
+
+			continue
+
+		}
+
+		sessionData.ThreadIDSession = orphan.ThreadIDSession
+
+		detail := fmt.Sprintf("from %s, last heartbeat %s ago",
+			net.JoinHostPort(orphan.Hostname, orphan.Port),
+			(time.Duration(orphan.Age) * time.Millisecond).Round(time.Second))
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  "FAILOVER - thread " + orphan.ThreadID + " taken over " + detail,
+			LogLevel: "InfoLevel",
+		}.Do()
+
+		/* The thread is taken over even when the audit record is not saved */
+		_ = sessionData.Storage.SaveAudit(context.Background(), sessionData, types.Audit{
+			Time:     time.Now().UnixNano() / int64(time.Millisecond),
+			ThreadID: orphan.ThreadID,
+			Node:     instance(sessionData),
+			Action:   ActionTakeover,
+			Detail:   detail,
+		})
+
+		return true, nil
+
+	}
+
+	return false, nil
+
+}
@@ -0,0 +1,97 @@
+package nodes
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/aleibovici/cryptopump/types"
+)
+
+func TestNode_Takeover(t *testing.T) {
+
+	db, a, _ := newSessions(t)
+	a.Port, a.ThreadIDSession = "8080", "c683ok5mk1u1120gnmn0"
+	standby := &types.Session{Db: db, Storage: a.Storage, Port: "8081"}
+
+	/* Configuration files are read from the working directory */
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := (Node{}).Register(a); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	if err := a.Storage.SaveThreadTransaction(context.Background(), a, 1, 100, 100, 1); err != nil {
+		t.Fatalf("SaveThreadTransaction() error = %v", err)
+	}
+
+	/* a stops sending heartbeats */
+	if _, err := db.Exec(`UPDATE nodes SET Heartbeat = Heartbeat - 120000`); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		setup  func()
+		want   bool
+		wantID string
+	}{
+		{
+			name:  "no configuration file",
+			setup: func() {},
+		},
+		{
+			name: "lease not expired",
+			setup: func() {
+				if err := os.Mkdir("config", 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile("config/"+a.ThreadID+".yml", []byte("config:\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "taken over",
+			setup: func() {
+				if _, err := db.Exec(`UPDATE leases SET Expires = 0`); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want:   true,
+			wantID: a.ThreadID,
+		},
+	}
+
+	for _, tt := range tests {
+		tt.setup()
+
+		got, err := (Node{}).Takeover(standby)
+		if err != nil || got != tt.want || standby.ThreadID != tt.wantID {
+			t.Errorf("%s: Takeover() = %v, %v, ThreadID %q, want %v, ThreadID %q", tt.name, got, err, standby.ThreadID, tt.want, tt.wantID)
+		}
+	}
+
+	if standby.ThreadIDSession != a.ThreadIDSession {
+		t.Errorf("Takeover() ThreadIDSession = %v, want %v", standby.ThreadIDSession, a.ThreadIDSession)
+	}
+
+	if audit, err := a.Storage.GetAudit(context.Background(), a, 0); err != nil || len(audit) != 1 || audit[0].Action != ActionTakeover || audit[0].ThreadID != a.ThreadID {
+		t.Errorf("GetAudit() = %+v, %v, want the takeover of %v", audit, err, a.ThreadID)
+	}
+
+	/* The previous node stops at its next heartbeat */
+	if err := (Node{}).Register(a); err != ErrTakenOver {
+		t.Errorf("Register() error = %v, want %v", err, ErrTakenOver)
+	}
+
+	if err := (Node{}).Register(standby); err != nil {
+		t.Errorf("Register() after Takeover() error = %v", err)
+	}
+
+}
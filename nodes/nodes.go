@@ -1,55 +1,53 @@
 package nodes
 
 import (
-	"os"
+	"context"
+	"fmt"
 	"time"
 
 	"github.com/aleibovici/cryptopump/functions"
 	"github.com/aleibovici/cryptopump/logger"
-	"github.com/aleibovici/cryptopump/mysql"
 	"github.com/aleibovici/cryptopump/types"
 )
 
+/* Master Node lease */
+const (
+	leaseName        = "master"
+	leaseNameTestNet = "master-testnet" /* TestNet nodes elect their own Master Node to not affect production systems */
+	leaseDuration    = 30 * time.Second /* Renewed by GetRole every 10 seconds */
+)
+
 // Node functions
 type Node struct{}
 
-// GetRole retrieve correct role for node
+// GetRole renew the Master Node lease, or take it over when it is free or expired. The node is Master Node
+// while it holds the lease, so at most one node of the cluster is Master Node at any time.
 func (Node) GetRole(
 	configData *types.Config,
 	sessionData *types.Session) {
 
-	var filename = "master.lock"
-	var err error
-
-	/* Conditional defer logging when there is an error retriving data */
-	defer func() {
-		if err != nil {
-			logger.LogEntry{ /* Log Entry */
-				Config:   nil,
-				Market:   nil,
-				Session:  sessionData,
-				Order:    &types.Order{},
-				Message:  functions.GetFunctionName() + " - " + err.Error(),
-				LogLevel: "DebugLevel",
-			}.Do()
-		}
-	}()
-
-	/* 	If TestNet is enabled will not check for "master.lock" to not affect production systems */
+	name := leaseName
 	if configData.TestNet {
 
-		sessionData.MasterNode = false
-		return
+		name = leaseNameTestNet
 
 	}
 
-	/* If Master Node already set to True */
-	if sessionData.MasterNode {
+	start := time.Now() /* The lease expires on the database clock no earlier than start plus leaseDuration */
+
+	lease, err := sessionData.Storage.AcquireLease(
+		context.Background(),
+		sessionData,
+		name,
+		sessionData.ThreadID,
+		int64(leaseDuration/time.Millisecond))
+
+	if err != nil {
 
-		/* Set access time and modified time of the file to the current time */
-		if err = os.Chtimes(filename, time.Now().Local(), time.Now().Local()); err != nil {
+		/* Keep the role until the lease expires, no other node can take it over before */
+		if !time.Now().Before(sessionData.MasterUntil) {
 
-			return
+			setRole(sessionData, false)
 
 		}
 
@@ -57,62 +55,76 @@ func (Node) GetRole(
 
 	}
 
-	/* If Master Node set to False */
-	if file, err := os.Stat(filename); err == nil { /* Check if "master.lock" is created and modified time */
+	sessionData.Lease = lease
 
-		sessionData.MasterNode = false
+	if lease.Holder == sessionData.ThreadID {
 
-		if time.Duration(time.Since(file.ModTime()).Seconds()) > 100 { /* Remove "master.lock" if old modified time */
+		sessionData.MasterUntil = start.Add(leaseDuration)
 
-			if err := os.Remove(filename); err != nil {
+	}
 
-				return
+	setRole(sessionData, lease.Holder == sessionData.ThreadID)
 
-			}
+}
 
-		}
+// IsMaster return true when the node is Master Node and its lease is not expired. Master Node duties
+// check IsMaster instead of MasterNode, so they stop when the heartbeat fails to renew the lease.
+func (Node) IsMaster(sessionData *types.Session) bool {
 
-	} else if os.IsNotExist(err) { /* Check if "master.lock" is created and modified time */
+	return sessionData.MasterNode && time.Now().Before(sessionData.MasterUntil)
 
-		var file *os.File
-		file, err = os.Create(filename)
+}
+
+/* Set the node role and log role changes */
+func setRole(
+	sessionData *types.Session,
+	master bool) {
 
-		file.Close()
+	if master == sessionData.MasterNode {
 
-		sessionData.MasterNode = true
+		return
 
 	}
 
+	sessionData.MasterNode = master
+
+	message := "NODE - Master Node lost"
+	if master {
+
+		message = fmt.Sprintf("NODE - Master Node elected (token %d)", sessionData.Lease.Token)
+
+	}
+
+	logger.LogEntry{ /* Log Entry */
+		Config:   nil,
+		Market:   nil,
+		Session:  sessionData,
+		Order:    &types.Order{},
+		Message:  message,
+		LogLevel: "InfoLevel",
+	}.Do()
+
 }
 
-// ReleaseMasterRole Release node role if Master
+// ReleaseMasterRole Release node role if Master, so another node takes it over at its next heartbeat
 func (Node) ReleaseMasterRole(sessionData *types.Session) {
 
 	/* Release node role if Master */
 	if sessionData.MasterNode {
 
-		var filename = "master.lock"
-
-		if err := os.Remove(filename); err != nil {
+		_ = sessionData.Storage.ReleaseLease(context.Background(), sessionData, sessionData.Lease.Name, sessionData.ThreadID)
 
-			logger.LogEntry{ /* Log Entry */
-				Config:   nil,
-				Market:   nil,
-				Session:  sessionData,
-				Order:    &types.Order{},
-				Message:  functions.GetFunctionName() + " - " + err.Error(),
-				LogLevel: "DebugLevel",
-			}.Do()
-
-		}
+		sessionData.MasterNode = false
+		sessionData.MasterUntil = time.Time{}
 
 	}
 
 }
 
-// CheckStatus check for errors on node
+// CheckStatus check for errors on node and save the cluster node registry heartbeat. It returns ErrTakenOver
+// when another node took over the thread.
 func (Node) CheckStatus(configData *types.Config,
-	sessionData *types.Session) {
+	sessionData *types.Session) error {
 
 	/* Check last WsBookTicker */
 	if time.Duration(time.Since(sessionData.LastWsBookTickerTime).Seconds()) > time.Duration(30) {
@@ -129,7 +141,8 @@ func (Node) CheckStatus(configData *types.Config,
 	}
 
 	/* Update Session table */
-	if err := mysql.UpdateSession(
+	if err := sessionData.Storage.UpdateSession(
+		context.Background(),
 		configData,
 		sessionData); err != nil {
 
@@ -145,4 +158,14 @@ func (Node) CheckStatus(configData *types.Config,
 	}
 
 	sessionData.Status = false
+
+	/* Save the cluster node registry heartbeat */
+	if err := (Node{}).Register(sessionData); err == ErrTakenOver {
+
+		return err
+
+	}
+
+	return nil
+
 }
@@ -1,87 +1,100 @@
 package nodes
 
 import (
+	"database/sql"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/aleibovici/cryptopump/migrations"
+	"github.com/aleibovici/cryptopump/sqlite"
 	"github.com/aleibovici/cryptopump/types"
 )
 
-func TestNode_GetRole(t *testing.T) {
-	type args struct {
-		configData  *types.Config
-		sessionData *types.Session
+/* Return two node sessions sharing a migrated SQLite database */
+func newSessions(t *testing.T) (*sql.DB, *types.Session, *types.Session) {
+
+	db, err := sqlite.DBInit(filepath.Join(t.TempDir(), "cryptopump.db"))
+	if err != nil {
+		t.Fatalf("DBInit() error = %v", err)
 	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := (migrations.Migrator{Db: db, Backend: "sqlite"}).Up(); err != nil {
+		t.Fatalf("Migrator.Up() error = %v", err)
+	}
+
+	return db,
+		&types.Session{Db: db, Storage: sqlite.Storage{}, ThreadID: "c683ok5mk1u1120gnmmg"},
+		&types.Session{Db: db, Storage: sqlite.Storage{}, ThreadID: "c6p5gbdmk1u5ro6ptnp0"}
+
+}
+
+func TestNode_GetRole(t *testing.T) {
+
+	_, a, b := newSessions(t)
+	configData := &types.Config{}
+
 	tests := []struct {
-		name string
-		n    Node
-		args args
+		name        string
+		sessionData *types.Session
+		configData  *types.Config
+		release     bool
+		wantA       bool
+		wantB       bool
 	}{
-		{
-			name: "success",
-			n:    Node{},
-			args: args{
-				configData: &types.Config{
-					TestNet: true,
-				},
-				sessionData: &types.Session{
-					MasterNode: true,
-				},
-			},
-		},
-		{
-			name: "success",
-			n:    Node{},
-			args: args{
-				configData: &types.Config{
-					TestNet: false,
-				},
-				sessionData: &types.Session{
-					MasterNode: true,
-				},
-			},
-		},
-		{
-			name: "success",
-			n:    Node{},
-			args: args{
-				configData: &types.Config{},
-				sessionData: &types.Session{
-					MasterNode: false,
-				},
-			},
-		},
+		{name: "testnet", sessionData: b, configData: &types.Config{TestNet: true}, wantB: true},
+		{name: "elected", sessionData: a, configData: configData, wantA: true, wantB: true},
+		{name: "lease held", sessionData: b, configData: configData, wantA: true},
+		{name: "renewed", sessionData: a, configData: configData, wantA: true},
+		{name: "released", sessionData: a, release: true},
+		{name: "taken over", sessionData: b, configData: configData, wantB: true},
+		{name: "lease held by other", sessionData: a, configData: configData, wantB: true},
 	}
+
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			n := Node{}
-			n.GetRole(tt.args.configData, tt.args.sessionData)
-		})
+		if tt.release {
+			Node{}.ReleaseMasterRole(tt.sessionData)
+		} else {
+			Node{}.GetRole(tt.configData, tt.sessionData)
+		}
+
+		if (Node{}).IsMaster(a) != tt.wantA || (Node{}).IsMaster(b) != tt.wantB {
+			t.Errorf("%s: IsMaster() = %v, %v, want %v, %v", tt.name, Node{}.IsMaster(a), Node{}.IsMaster(b), tt.wantA, tt.wantB)
+		}
+	}
+
+	if b.Lease.Name != leaseName || b.Lease.Holder != b.ThreadID || b.Lease.Token != 2 {
+		t.Errorf("GetRole() lease = %+v, want held by %v with token 2", b.Lease, b.ThreadID)
+	}
+
+	if a.Lease.Holder != b.ThreadID {
+		t.Errorf("GetRole() lease seen by other node = %+v, want held by %v", a.Lease, b.ThreadID)
 	}
+
 }
 
-func TestNode_ReleaseMasterRole(t *testing.T) {
-	type args struct {
-		sessionData *types.Session
+func TestNode_GetRoleStorageError(t *testing.T) {
+
+	db, a, _ := newSessions(t)
+
+	Node{}.GetRole(&types.Config{}, a)
+	db.Close()
+
+	/* The node keeps the role until the lease expires */
+	Node{}.GetRole(&types.Config{}, a)
+	if !(Node{}).IsMaster(a) {
+		t.Errorf("IsMaster() = false before lease expiry, want true")
 	}
-	tests := []struct {
-		name string
-		n    Node
-		args args
-	}{
-		{
-			name: "success",
-			n:    Node{},
-			args: args{
-				sessionData: &types.Session{
-					MasterNode: true,
-				},
-			},
-		},
+
+	a.MasterUntil = time.Now()
+	if (Node{}).IsMaster(a) {
+		t.Errorf("IsMaster() = true after lease expiry, want false")
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			n := Node{}
-			n.ReleaseMasterRole(tt.args.sessionData)
-		})
+
+	Node{}.GetRole(&types.Config{}, a)
+	if a.MasterNode {
+		t.Errorf("GetRole() MasterNode = true after lease expiry, want false")
 	}
+
 }
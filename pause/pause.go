@@ -0,0 +1,238 @@
+package pause
+
+/* This package implements the trading pause. A thread is paused without tearing down the
+session: in nobuy mode no new BUY is made and Thread transactions are still sold at target,
+in frozen mode no order is made at all. The pause is saved in the database so it is kept
+across restarts, with who paused the thread and when, and a pause can require the resume
+to be confirmed. Pause and resume are recorded in the audit log. */
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"time"
+
+	"github.com/aleibovici/cryptopump/logger"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+/* Pause modes */
+const (
+	ModeNoBuy  = "nobuy"  /* No new BUY, Thread transactions are sold at target */
+	ModeFrozen = "frozen" /* No orders at all */
+)
+
+/* Audit log actions */
+const (
+	ActionPause  = "pause"
+	ActionResume = "resume"
+)
+
+var (
+	// ErrMode is returned by Pause for an unknown pause mode
+	ErrMode = errors.New("pause mode must be nobuy or frozen")
+
+	// ErrConfirm is returned by Resume when the pause requires the resume to be confirmed
+	ErrConfirm = errors.New("resume requires confirmation")
+
+	// ErrNotPaused is returned by Resume when the thread is not paused
+	ErrNotPaused = errors.New("thread is not paused")
+)
+
+// Control trading pause
+type Control struct{}
+
+// Pause pause threadID in mode on behalf of by, i.e. telegram @username. When confirm is true the resume must
+// be confirmed. Pausing a paused thread changes its mode.
+func (c Control) Pause(
+	sessionData *types.Session,
+	threadID string,
+	mode string,
+	by string,
+	confirm bool) (types.Pause, error) {
+
+	if mode != ModeNoBuy && mode != ModeFrozen {
+
+		return types.Pause{}, ErrMode
+
+	}
+
+	pause := types.Pause{
+		ThreadID: threadID,
+		Mode:     mode,
+		By:       by,
+		Time:     time.Now().UnixNano() / int64(time.Millisecond),
+		Confirm:  confirm,
+	}
+
+	if err := sessionData.Storage.SavePause(context.Background(), sessionData, pause); err != nil {
+
+		return types.Pause{}, err
+
+	}
+
+	if threadID == sessionData.ThreadID {
+
+		sessionData.Pause = pause
+
+	}
+
+	c.audit(sessionData, threadID, ActionPause, Describe(pause))
+
+	return pause, nil
+
+}
+
+// Resume resume threadID on behalf of by. It returns ErrConfirm when the pause requires confirmation and
+// confirm is false, and the pause that was lifted otherwise.
+func (c Control) Resume(
+	sessionData *types.Session,
+	threadID string,
+	by string,
+	confirm bool) (types.Pause, error) {
+
+	pause, err := sessionData.Storage.GetPause(context.Background(), sessionData, threadID)
+	if err != nil {
+
+		return types.Pause{}, err
+
+	}
+
+	if pause.Mode == "" {
+
+		return types.Pause{}, ErrNotPaused
+
+	}
+
+	if pause.Confirm && !confirm {
+
+		return pause, ErrConfirm
+
+	}
+
+	if err = sessionData.Storage.DeletePause(context.Background(), sessionData, threadID); err != nil {
+
+		return types.Pause{}, err
+
+	}
+
+	if threadID == sessionData.ThreadID {
+
+		sessionData.Pause = types.Pause{}
+
+	}
+
+	c.audit(sessionData, threadID, ActionResume, "Resumed by "+by+", was "+Describe(pause))
+
+	return pause, nil
+
+}
+
+// Load reload the pause of the session thread, so a pause set on another node or before a restart is
+// enforced. The last known pause is kept when the database can't be read.
+func (Control) Load(sessionData *types.Session) {
+
+	pause, err := sessionData.Storage.GetPause(context.Background(), sessionData, sessionData.ThreadID)
+	if err != nil {
+
+		return
+
+	}
+
+	if pause.Mode != sessionData.Pause.Mode {
+
+		message := "PAUSE - thread " + sessionData.ThreadID + " resumed"
+		if pause.Mode != "" {
+
+			message = "PAUSE - thread " + sessionData.ThreadID + " " + Describe(pause)
+
+		}
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  message,
+			LogLevel: "InfoLevel",
+		}.Do()
+
+	}
+
+	sessionData.Pause = pause
+
+}
+
+// IsBuyAllowed return false when the session thread is paused
+func (Control) IsBuyAllowed(sessionData *types.Session) bool {
+
+	return sessionData.Pause.Mode == ""
+
+}
+
+// IsSellAllowed return false when the session thread is frozen
+func (Control) IsSellAllowed(sessionData *types.Session) bool {
+
+	return sessionData.Pause.Mode != ModeFrozen
+
+}
+
+// Describe return pause as text, i.e. "Paused (no new buys) by telegram @username at 2021-10-01 10:00:00 UTC".
+// It returns "Running" when the thread is not paused.
+func Describe(pause types.Pause) string {
+
+	var mode string
+
+	switch pause.Mode {
+	case "":
+
+		return "Running"
+
+	case ModeNoBuy:
+
+		mode = "Paused (no new buys)"
+
+	case ModeFrozen:
+
+		mode = "Paused (frozen)"
+
+	default:
+
+		mode = "Paused (" + pause.Mode + ")"
+
+	}
+
+	return mode + " by " + pause.By + " at " +
+		time.Unix(0, pause.Time*int64(time.Millisecond)).UTC().Format("2006-01-02 15:04:05") + " UTC"
+
+}
+
+/* Log the pause action and save it in the audit log */
+func (Control) audit(
+	sessionData *types.Session,
+	threadID string,
+	action string,
+	detail string) {
+
+	logger.LogEntry{ /* Log Entry */
+		Config:   nil,
+		Market:   nil,
+		Session:  sessionData,
+		Order:    &types.Order{},
+		Message:  "PAUSE - thread " + threadID + " " + detail,
+		LogLevel: "InfoLevel",
+	}.Do()
+
+	hostname, _ := os.Hostname()
+
+	/* The pause is in effect even when the audit record is not saved */
+	_ = sessionData.Storage.SaveAudit(context.Background(), sessionData, types.Audit{
+		Time:     time.Now().UnixNano() / int64(time.Millisecond),
+		ThreadID: threadID,
+		Node:     net.JoinHostPort(hostname, sessionData.Port),
+		Action:   action,
+		Detail:   detail,
+	})
+
+}
@@ -0,0 +1,123 @@
+package pause
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/aleibovici/cryptopump/migrations"
+	"github.com/aleibovici/cryptopump/sqlite"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+/* Return a session on a migrated SQLite database */
+func newSession(t *testing.T) *types.Session {
+
+	db, err := sqlite.DBInit(filepath.Join(t.TempDir(), "cryptopump.db"))
+	if err != nil {
+		t.Fatalf("DBInit() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := (migrations.Migrator{Db: db, Backend: "sqlite"}).Up(); err != nil {
+		t.Fatalf("Migrator.Up() error = %v", err)
+	}
+
+	return &types.Session{Db: db, Storage: sqlite.Storage{}, ThreadID: "c683ok5mk1u1120gnmmg", Port: "8080"}
+
+}
+
+func TestControl_Pause(t *testing.T) {
+
+	sessionData := newSession(t)
+
+	tests := []struct {
+		name     string
+		action   string
+		mode     string
+		confirm  bool
+		wantErr  error
+		wantMode string
+		wantBuy  bool
+		wantSell bool
+	}{
+		{name: "unknown mode", action: ActionPause, mode: "stop", wantErr: ErrMode, wantBuy: true, wantSell: true},
+		{name: "not paused", action: ActionResume, wantErr: ErrNotPaused, wantBuy: true, wantSell: true},
+		{name: "no new buys", action: ActionPause, mode: ModeNoBuy, wantMode: ModeNoBuy, wantSell: true},
+		{name: "frozen with confirmation", action: ActionPause, mode: ModeFrozen, confirm: true, wantMode: ModeFrozen},
+		{name: "resume not confirmed", action: ActionResume, wantErr: ErrConfirm, wantMode: ModeFrozen},
+		{name: "resume confirmed", action: ActionResume, confirm: true, wantBuy: true, wantSell: true},
+	}
+
+	for _, tt := range tests {
+		var err error
+		if tt.action == ActionPause {
+			_, err = Control{}.Pause(sessionData, sessionData.ThreadID, tt.mode, "telegram @user", tt.confirm)
+		} else {
+			_, err = Control{}.Resume(sessionData, sessionData.ThreadID, "telegram @user", tt.confirm)
+		}
+
+		if err != tt.wantErr || sessionData.Pause.Mode != tt.wantMode {
+			t.Errorf("%s: error = %v, mode %q, want %v, mode %q", tt.name, err, sessionData.Pause.Mode, tt.wantErr, tt.wantMode)
+		}
+
+		if (Control{}).IsBuyAllowed(sessionData) != tt.wantBuy || (Control{}).IsSellAllowed(sessionData) != tt.wantSell {
+			t.Errorf("%s: IsBuyAllowed() = %v, IsSellAllowed() = %v, want %v, %v", tt.name,
+				Control{}.IsBuyAllowed(sessionData), Control{}.IsSellAllowed(sessionData), tt.wantBuy, tt.wantSell)
+		}
+	}
+
+	audit, err := sessionData.Storage.GetAudit(context.Background(), sessionData, 0)
+	if err != nil || len(audit) != 3 || audit[0].Action != ActionPause || audit[2].Action != ActionResume {
+		t.Errorf("GetAudit() = %+v, %v, want 2 pauses and 1 resume", audit, err)
+	}
+
+}
+
+func TestControl_Load(t *testing.T) {
+
+	sessionData := newSession(t)
+	other := &types.Session{Db: sessionData.Db, Storage: sessionData.Storage, ThreadID: "c6p5gbdmk1u5ro6ptnp0"}
+
+	/* Paused from another node or before a restart */
+	if _, err := (Control{}).Pause(other, sessionData.ThreadID, ModeNoBuy, "web 10.0.0.1", false); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+
+	if other.Pause.Mode != "" {
+		t.Errorf("Pause() of another thread set the session pause to %+v", other.Pause)
+	}
+
+	Control{}.Load(sessionData)
+	if sessionData.Pause.Mode != ModeNoBuy || sessionData.Pause.By != "web 10.0.0.1" {
+		t.Errorf("Load() = %+v, want nobuy by web 10.0.0.1", sessionData.Pause)
+	}
+
+	/* The last known pause is kept when the database can't be read */
+	sessionData.Db.Close()
+	Control{}.Load(sessionData)
+	if sessionData.Pause.Mode != ModeNoBuy {
+		t.Errorf("Load() after a database error = %+v, want nobuy", sessionData.Pause)
+	}
+
+}
+
+func TestDescribe(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		pause types.Pause
+		want  string
+	}{
+		{name: "running", pause: types.Pause{}, want: "Running"},
+		{name: "no new buys", pause: types.Pause{Mode: ModeNoBuy, By: "web 10.0.0.1", Time: 1633082400000}, want: "Paused (no new buys) by web 10.0.0.1 at 2021-10-01 10:00:00 UTC"},
+		{name: "frozen", pause: types.Pause{Mode: ModeFrozen, By: "telegram @user", Time: 0}, want: "Paused (frozen) by telegram @user at 1970-01-01 00:00:00 UTC"},
+	}
+
+	for _, tt := range tests {
+		if got := Describe(tt.pause); got != tt.want {
+			t.Errorf("%s: Describe() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+}
@@ -213,3 +213,65 @@ func lineBase(name string, XAxis []string, lineData []opts.LineData, color strin
 
 	return line
 }
+
+// PlotEquity is responsible for rending the equity curve and drawdown e-chart
+func (d Data) PlotEquity(
+	equity []types.Equity,
+	drawdown []float64) (htmlSnippet template.HTML) {
+
+	x := make([]string, 0)
+	y := make([]opts.LineData, 0)
+	dd := make([]opts.LineData, 0) /* Drawdown plotted below zero */
+
+	for i := 0; i < len(equity); i++ {
+		x = append(x, equity[i].Date)
+		y = append(y, opts.LineData{Value: math.Round(equity[i].Equity*100) / 100})
+		dd = append(dd, opts.LineData{Value: math.Round(-drawdown[i]*100) / 100})
+	}
+
+	line := charts.NewLine()
+	line.SetGlobalOptions(
+		charts.WithXAxisOpts(opts.XAxis{ /* Dates */
+			Type: "category",
+			Show: true,
+		}),
+		charts.WithYAxisOpts(opts.YAxis{ /* Fiat */
+			Type:  "value",
+			Scale: true,
+			SplitLine: &opts.SplitLine{
+				Show: true,
+				LineStyle: &opts.LineStyle{
+					Color: "#777",
+				},
+			},
+		}),
+		charts.WithInitializationOpts(opts.Initialization{
+			PageTitle: "CryptoPump",
+			Width:     "1200px",
+			Height:    "300px",
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{
+			Show:    true,
+			Trigger: "axis",
+		}),
+		charts.WithLegendOpts(opts.Legend{
+			Show: true,
+		}),
+	)
+
+	line.SetXAxis(x).AddSeries("EQUITY", y).
+		SetSeriesOptions(
+			charts.WithLineStyleOpts(opts.LineStyle{
+				Color: "green",
+				Width: 2,
+			}),
+			charts.WithItemStyleOpts(opts.ItemStyle{
+				Color: "green",
+			}),
+		)
+
+	line.Overlap(lineBase("DRAWDOWN", x, dd, "red")) /* Create overlapping drawdown line chart */
+
+	return renderToHTML(line)
+
+}
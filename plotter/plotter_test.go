@@ -2,6 +2,7 @@ package plotter
 
 import (
 	"html/template"
+	"strings"
 	"testing"
 
 	"github.com/aleibovici/cryptopump/types"
@@ -159,3 +160,30 @@ func TestData_LoadKline(t *testing.T) {
 		})
 	}
 }
+
+func TestData_PlotEquity(t *testing.T) {
+	tests := []struct {
+		name     string
+		equity   []types.Equity
+		drawdown []float64
+	}{
+		{
+			name:     "empty",
+			equity:   []types.Equity{},
+			drawdown: []float64{},
+		},
+		{
+			name:     "success",
+			equity:   []types.Equity{{Date: "2021-06-01", Equity: 10}, {Date: "2021-06-02", Equity: 5}},
+			drawdown: []float64{0, 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(Data{}.PlotEquity(tt.equity, tt.drawdown))
+			if !strings.Contains(got, "EQUITY") || !strings.Contains(got, "DRAWDOWN") {
+				t.Errorf("Data.PlotEquity() = %v, want equity and drawdown series", got)
+			}
+		})
+	}
+}
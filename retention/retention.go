@@ -0,0 +1,63 @@
+package retention
+
+/* This package implements the data retention of the orders table. Closed orders older than the
+retention period are moved to the orders archive, and the profit of the archived round trips is
+rolled up into daily aggregates, so profit totals and the tax export don't change while the queries
+of the trading loop only read recent orders. Orders of open Thread transactions and the last BUY
+and SELL of each thread are never archived. */
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aleibovici/cryptopump/logger"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+// Cutoff return the start of the UTC day days days before now in milliseconds. Orders executed
+// before the cutoff are archived, so the current day is never archived.
+func Cutoff(now time.Time, days int) int64 {
+
+	day := now.UTC().AddDate(0, 0, -days)
+
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)
+
+}
+
+// Archive move the closed orders older than days days to the orders archive and return the number
+// of archived orders. days lower than 1 keeps all orders.
+func Archive(
+	sessionData *types.Session,
+	days int) (archived int64, err error) {
+
+	if days < 1 {
+
+		return 0, nil
+
+	}
+
+	cutoff := Cutoff(time.Now(), days)
+
+	if archived, err = sessionData.Storage.ArchiveOrders(context.Background(), sessionData, cutoff); err != nil {
+
+		return 0, err
+
+	}
+
+	if archived > 0 {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  fmt.Sprintf("RETENTION - archived %d orders executed before %s", archived, time.Unix(0, cutoff*int64(time.Millisecond)).UTC().Format("2006-01-02")),
+			LogLevel: "InfoLevel",
+		}.Do()
+
+	}
+
+	return archived, nil
+
+}
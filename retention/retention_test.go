@@ -0,0 +1,94 @@
+package retention
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aleibovici/cryptopump/migrations"
+	"github.com/aleibovici/cryptopump/sqlite"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+func TestCutoff(t *testing.T) {
+
+	tests := []struct {
+		name string
+		now  time.Time
+		days int
+		want time.Time
+	}{
+		{
+			name: "one day",
+			now:  time.Date(2021, 3, 10, 15, 30, 0, 0, time.UTC),
+			days: 1,
+			want: time.Date(2021, 3, 9, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "month boundary",
+			now:  time.Date(2021, 3, 1, 0, 0, 1, 0, time.UTC),
+			days: 30,
+			want: time.Date(2021, 1, 30, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "local time",
+			now:  time.Date(2021, 3, 10, 1, 0, 0, 0, time.FixedZone("UTC+3", 3*3600)),
+			days: 1,
+			want: time.Date(2021, 3, 8, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Cutoff(tt.now, tt.days); got != tt.want.UnixNano()/int64(time.Millisecond) {
+				t.Errorf("Cutoff() = %v, want %v", time.Unix(0, got*int64(time.Millisecond)).UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func TestArchive(t *testing.T) {
+
+	db, err := sqlite.DBInit(filepath.Join(t.TempDir(), "cryptopump.db"))
+	if err != nil {
+		t.Fatalf("DBInit() error = %v", err)
+	}
+	defer db.Close()
+
+	if _, err := (migrations.Migrator{Db: db, Backend: "sqlite"}).Up(); err != nil {
+		t.Fatalf("Migrator.Up() error = %v", err)
+	}
+
+	sessionData := &types.Session{Db: db, Storage: sqlite.Storage{}, ThreadID: "c683ok5mk1u1120gnmmg"}
+	old := time.Now().AddDate(0, 0, -40).UnixNano() / int64(time.Millisecond)
+
+	for _, order := range []types.Order{
+		{OrderID: 1, Side: "BUY", Status: "CANCELED", TransactTime: old},
+		{OrderID: 2, Side: "BUY", Status: "CANCELED", TransactTime: time.Now().UnixNano() / int64(time.Millisecond)},
+	} {
+		order := order
+		if err := sessionData.Storage.SaveOrder(context.Background(), sessionData, &order, 0, 0); err != nil {
+			t.Fatalf("SaveOrder() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		days int
+		want int64
+	}{
+		{name: "disabled", days: 0, want: 0},
+		{name: "recent orders kept", days: 60, want: 0},
+		{name: "old orders archived", days: 30, want: 1},
+		{name: "already archived", days: 30, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Archive(sessionData, tt.days); err != nil || got != tt.want {
+				t.Errorf("Archive() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
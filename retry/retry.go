@@ -0,0 +1,1028 @@
+package retry
+
+/* This package implements the database deadline and retries. It wraps a storage backend so every
+call runs with a deadline (db_timeout in config_global.yml) and transient errors (lost connections,
+deadlocks, lock wait timeouts, busy SQLite database) are retried with exponential backoff. Calls
+timing out or failing after their retries threshold times within window put the storage in degraded
+mode: the risk manager pauses BUY while sales, the web UI and Telegram keep running, and the first
+call that succeeds ends it. The degraded mode is shared by all the sessions using the storage. */
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/aleibovici/cryptopump/logger"
+	"github.com/aleibovici/cryptopump/types"
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Timeout default deadline of a storage call
+const Timeout = 10 * time.Second
+
+/* Attempts of a storage call, and wait before the first retry (doubled on every retry) */
+const (
+	attempts = 4
+	backoff  = 250 * time.Millisecond
+)
+
+/* Failed calls within window that put the storage in degraded mode */
+const (
+	threshold = 3
+	window    = time.Minute
+)
+
+/* Storage call kinds */
+const (
+	idempotent = iota /* Read, or write with the same result when applied twice */
+	insert            /* Write that must not be applied twice */
+)
+
+/* Database error classes */
+const (
+	permanent = iota /* Error returned for the statement (i.e. duplicate key), not retried */
+	unapplied        /* Transient error, the statement was not applied */
+	unknown          /* Transient error, the statement may have been applied */
+)
+
+// Storage struct define a storage backend with a deadline and retries on every call
+type Storage struct {
+	types.Storage
+	timeout  time.Duration
+	attempts int
+	backoff  time.Duration
+	mutex    sync.Mutex
+	failures []time.Time /* Time of the failed calls within window */
+	degraded int32       /* 1 in degraded mode, read without the mutex by every session copy */
+}
+
+var _ types.Storage = &Storage{}  /* Storage must implement types.Storage */
+var _ types.Degrader = &Storage{} /* Storage must implement types.Degrader */
+
+// New return storage running every call with deadline timeout, or Timeout when timeout is not positive
+func New(storage types.Storage, timeout time.Duration) *Storage {
+
+	if timeout <= 0 {
+
+		timeout = Timeout
+
+	}
+
+	return &Storage{
+		Storage:  storage,
+		timeout:  timeout,
+		attempts: attempts,
+		backoff:  backoff,
+	}
+
+}
+
+// do run f with the storage deadline, retrying transient errors. Inserts are only retried when the
+// error guarantees the statement was not applied. A deadline is not retried, so a hung database
+// blocks the caller for at most one deadline.
+func (s *Storage) do(
+	ctx context.Context,
+	sessionData *types.Session,
+	name string,
+	kind int,
+	f func(ctx context.Context) error) (err error) {
+
+	wait := s.backoff
+
+	for attempt := 1; ; attempt++ {
+
+		callCtx, cancel := context.WithTimeout(ctx, s.timeout)
+		err = f(callCtx)
+		timeout := err != nil && callCtx.Err() == context.DeadlineExceeded
+		cancel()
+
+		switch {
+		case err == nil:
+
+			s.recovered(sessionData)
+
+			return nil
+
+		case ctx.Err() != nil: /* Canceled by the caller */
+
+			return err
+
+		case timeout:
+
+			s.degrade(sessionData, name, err)
+
+			return err
+
+		}
+
+		class := classify(err)
+
+		if class == permanent {
+
+			return err
+
+		}
+
+		if attempt >= s.attempts || (class == unknown && kind == insert) {
+
+			s.degrade(sessionData, name, err)
+
+			return err
+
+		}
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  "DATABASE - " + name + " retry " + strconv.Itoa(attempt) + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+
+			return err
+
+		}
+
+		wait *= 2
+
+	}
+
+}
+
+/* Return the class of a database error */
+func classify(err error) int {
+
+	var mysqlErr *mysql.MySQLError
+	var sqliteErr sqlite3.Error
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, driver.ErrBadConn),
+		errors.Is(err, syscall.ECONNREFUSED):
+
+		return unapplied
+
+	case errors.As(err, &mysqlErr):
+
+		/* 1205 Lock wait timeout exceeded, 1213 Deadlock found when trying to get lock. The statement is rolled back. */
+		if mysqlErr.Number == 1205 || mysqlErr.Number == 1213 {
+
+			return unapplied
+
+		}
+
+		return permanent
+
+	case errors.As(err, &sqliteErr):
+
+		if sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked {
+
+			return unapplied
+
+		}
+
+		return permanent
+
+	case errors.Is(err, mysql.ErrInvalidConn),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EPIPE),
+		errors.As(err, &netErr):
+
+		return unknown
+
+	}
+
+	return permanent
+
+}
+
+// Degraded return true when the storage is in degraded mode. The sessions of the process share the storage,
+// so a call failing on a session copy (i.e. an API or Telegram request) degrades the thread session too.
+func (s *Storage) Degraded() bool {
+
+	return atomic.LoadInt32(&s.degraded) == 1
+
+}
+
+/* Count a failed storage call, and enter degraded mode after threshold failed calls within window */
+func (s *Storage) degrade(
+	sessionData *types.Session,
+	name string,
+	err error) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+
+	failures := s.failures[:0]
+	for _, failure := range s.failures {
+
+		if now.Sub(failure) < window {
+
+			failures = append(failures, failure)
+
+		}
+
+	}
+
+	s.failures = append(failures, now)
+
+	if s.Degraded() || len(s.failures) < threshold {
+
+		return
+
+	}
+
+	atomic.StoreInt32(&s.degraded, 1)
+
+	logger.LogEntry{ /* Log Entry */
+		Config:   nil,
+		Market:   nil,
+		Session:  sessionData,
+		Order:    &types.Order{},
+		Message:  "DATABASE - degraded mode, BUY paused: " + name + " - " + err.Error(),
+		LogLevel: "InfoLevel",
+	}.Do()
+
+}
+
+/* Leave degraded mode after a storage call succeeded */
+func (s *Storage) recovered(sessionData *types.Session) {
+
+	/* Successful calls outside degraded mode don't take the mutex */
+	if !s.Degraded() {
+
+		return
+
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.Degraded() {
+
+		return
+
+	}
+
+	atomic.StoreInt32(&s.degraded, 0)
+	s.failures = nil
+
+	logger.LogEntry{ /* Log Entry */
+		Config:   nil,
+		Market:   nil,
+		Session:  sessionData,
+		Order:    &types.Order{},
+		Message:  "DATABASE - recovered",
+		LogLevel: "InfoLevel",
+	}.Do()
+
+}
+
+// SaveOrder call SaveOrder with the storage deadline and retries
+func (s *Storage) SaveOrder(
+	ctx context.Context,
+	sessionData *types.Session,
+	order *types.Order,
+	orderIDSource int64,
+	orderPrice float64) error {
+
+	return s.do(ctx, sessionData, "SaveOrder", insert, func(ctx context.Context) error {
+		return s.Storage.SaveOrder(ctx, sessionData, order, orderIDSource, orderPrice)
+	})
+
+}
+
+// UpdateOrder call UpdateOrder with the storage deadline and retries
+func (s *Storage) UpdateOrder(
+	ctx context.Context,
+	sessionData *types.Session,
+	OrderID int64,
+	CumulativeQuoteQuantity float64,
+	ExecutedQuantity float64,
+	Price float64,
+	Status string) error {
+
+	return s.do(ctx, sessionData, "UpdateOrder", idempotent, func(ctx context.Context) error {
+		return s.Storage.UpdateOrder(ctx, sessionData, OrderID, CumulativeQuoteQuantity, ExecutedQuantity, Price, Status)
+	})
+
+}
+
+// GetOrderByOrderID call GetOrderByOrderID with the storage deadline and retries
+func (s *Storage) GetOrderByOrderID(
+	ctx context.Context,
+	sessionData *types.Session) (order types.Order, err error) {
+
+	err = s.do(ctx, sessionData, "GetOrderByOrderID", idempotent, func(ctx context.Context) (err error) {
+		order, err = s.Storage.GetOrderByOrderID(ctx, sessionData)
+		return err
+	})
+
+	return order, err
+
+}
+
+// GetOrderSymbol call GetOrderSymbol with the storage deadline and retries
+func (s *Storage) GetOrderSymbol(
+	ctx context.Context,
+	sessionData *types.Session) (symbol string, err error) {
+
+	err = s.do(ctx, sessionData, "GetOrderSymbol", idempotent, func(ctx context.Context) (err error) {
+		symbol, err = s.Storage.GetOrderSymbol(ctx, sessionData)
+		return err
+	})
+
+	return symbol, err
+
+}
+
+// GetOrderTransactionPending call GetOrderTransactionPending with the storage deadline and retries
+func (s *Storage) GetOrderTransactionPending(
+	ctx context.Context,
+	sessionData *types.Session) (order types.Order, err error) {
+
+	err = s.do(ctx, sessionData, "GetOrderTransactionPending", idempotent, func(ctx context.Context) (err error) {
+		order, err = s.Storage.GetOrderTransactionPending(ctx, sessionData)
+		return err
+	})
+
+	return order, err
+
+}
+
+// GetOrderTransactionCount call GetOrderTransactionCount with the storage deadline and retries
+func (s *Storage) GetOrderTransactionCount(
+	ctx context.Context,
+	sessionData *types.Session,
+	side string) (count float64, err error) {
+
+	err = s.do(ctx, sessionData, "GetOrderTransactionCount", idempotent, func(ctx context.Context) (err error) {
+		count, err = s.Storage.GetOrderTransactionCount(ctx, sessionData, side)
+		return err
+	})
+
+	return count, err
+
+}
+
+// GetOrderTransactionSideLastTwo call GetOrderTransactionSideLastTwo with the storage deadline and retries
+func (s *Storage) GetOrderTransactionSideLastTwo(
+	ctx context.Context,
+	sessionData *types.Session) (side1 string, side2 string, err error) {
+
+	err = s.do(ctx, sessionData, "GetOrderTransactionSideLastTwo", idempotent, func(ctx context.Context) (err error) {
+		side1, side2, err = s.Storage.GetOrderTransactionSideLastTwo(ctx, sessionData)
+		return err
+	})
+
+	return side1, side2, err
+
+}
+
+// GetLastOrderTransactionPrice call GetLastOrderTransactionPrice with the storage deadline and retries
+func (s *Storage) GetLastOrderTransactionPrice(
+	ctx context.Context,
+	sessionData *types.Session,
+	Side string) (price float64, err error) {
+
+	err = s.do(ctx, sessionData, "GetLastOrderTransactionPrice", idempotent, func(ctx context.Context) (err error) {
+		price, err = s.Storage.GetLastOrderTransactionPrice(ctx, sessionData, Side)
+		return err
+	})
+
+	return price, err
+
+}
+
+// GetLastOrderTransactionSide call GetLastOrderTransactionSide with the storage deadline and retries
+func (s *Storage) GetLastOrderTransactionSide(
+	ctx context.Context,
+	sessionData *types.Session) (side string, err error) {
+
+	err = s.do(ctx, sessionData, "GetLastOrderTransactionSide", idempotent, func(ctx context.Context) (err error) {
+		side, err = s.Storage.GetLastOrderTransactionSide(ctx, sessionData)
+		return err
+	})
+
+	return side, err
+
+}
+
+// GetOrderTransactionExecuted call GetOrderTransactionExecuted with the storage deadline and retries
+func (s *Storage) GetOrderTransactionExecuted(
+	ctx context.Context,
+	sessionData *types.Session,
+	before int64) (orders []types.Order, err error) {
+
+	err = s.do(ctx, sessionData, "GetOrderTransactionExecuted", idempotent, func(ctx context.Context) (err error) {
+		orders, err = s.Storage.GetOrderTransactionExecuted(ctx, sessionData, before)
+		return err
+	})
+
+	return orders, err
+
+}
+
+// GetOrders call GetOrders with the storage deadline and retries
+func (s *Storage) GetOrders(
+	ctx context.Context,
+	sessionData *types.Session,
+	filter types.OrderFilter) (orders []types.Order, err error) {
+
+	err = s.do(ctx, sessionData, "GetOrders", idempotent, func(ctx context.Context) (err error) {
+		orders, err = s.Storage.GetOrders(ctx, sessionData, filter)
+		return err
+	})
+
+	return orders, err
+
+}
+
+// ArchiveOrders call ArchiveOrders with the storage deadline and retries
+func (s *Storage) ArchiveOrders(
+	ctx context.Context,
+	sessionData *types.Session,
+	before int64) (archived int64, err error) {
+
+	err = s.do(ctx, sessionData, "ArchiveOrders", idempotent, func(ctx context.Context) (err error) {
+		archived, err = s.Storage.ArchiveOrders(ctx, sessionData, before)
+		return err
+	})
+
+	return archived, err
+
+}
+
+// SaveThreadTransaction call SaveThreadTransaction with the storage deadline and retries
+func (s *Storage) SaveThreadTransaction(
+	ctx context.Context,
+	sessionData *types.Session,
+	OrderID int64,
+	CumulativeQuoteQuantity float64,
+	Price float64,
+	ExecutedQuantity float64) error {
+
+	return s.do(ctx, sessionData, "SaveThreadTransaction", insert, func(ctx context.Context) error {
+		return s.Storage.SaveThreadTransaction(ctx, sessionData, OrderID, CumulativeQuoteQuantity, Price, ExecutedQuantity)
+	})
+
+}
+
+// DeleteThreadTransactionByOrderID call DeleteThreadTransactionByOrderID with the storage deadline and retries
+func (s *Storage) DeleteThreadTransactionByOrderID(
+	ctx context.Context,
+	sessionData *types.Session,
+	orderID int) error {
+
+	return s.do(ctx, sessionData, "DeleteThreadTransactionByOrderID", idempotent, func(ctx context.Context) error {
+		return s.Storage.DeleteThreadTransactionByOrderID(ctx, sessionData, orderID)
+	})
+
+}
+
+// GetThreadTransactionCount call GetThreadTransactionCount with the storage deadline and retries
+func (s *Storage) GetThreadTransactionCount(
+	ctx context.Context,
+	sessionData *types.Session) (count int, err error) {
+
+	err = s.do(ctx, sessionData, "GetThreadTransactionCount", idempotent, func(ctx context.Context) (err error) {
+		count, err = s.Storage.GetThreadTransactionCount(ctx, sessionData)
+		return err
+	})
+
+	return count, err
+
+}
+
+// GetThreadTransactionDistinct call GetThreadTransactionDistinct with the storage deadline and retries
+func (s *Storage) GetThreadTransactionDistinct(
+	ctx context.Context,
+	sessionData *types.Session) (threadID string, threadIDSession string, err error) {
+
+	err = s.do(ctx, sessionData, "GetThreadTransactionDistinct", idempotent, func(ctx context.Context) (err error) {
+		threadID, threadIDSession, err = s.Storage.GetThreadTransactionDistinct(ctx, sessionData)
+		return err
+	})
+
+	return threadID, threadIDSession, err
+
+}
+
+// GetThreadTransactionByPrice call GetThreadTransactionByPrice with the storage deadline and retries
+func (s *Storage) GetThreadTransactionByPrice(
+	ctx context.Context,
+	marketData *types.Market,
+	sessionData *types.Session) (order types.Order, err error) {
+
+	err = s.do(ctx, sessionData, "GetThreadTransactionByPrice", idempotent, func(ctx context.Context) (err error) {
+		order, err = s.Storage.GetThreadTransactionByPrice(ctx, marketData, sessionData)
+		return err
+	})
+
+	return order, err
+
+}
+
+// GetThreadTransactionByPriceHigher call GetThreadTransactionByPriceHigher with the storage deadline and retries
+func (s *Storage) GetThreadTransactionByPriceHigher(
+	ctx context.Context,
+	marketData *types.Market,
+	sessionData *types.Session) (order types.Order, err error) {
+
+	err = s.do(ctx, sessionData, "GetThreadTransactionByPriceHigher", idempotent, func(ctx context.Context) (err error) {
+		order, err = s.Storage.GetThreadTransactionByPriceHigher(ctx, marketData, sessionData)
+		return err
+	})
+
+	return order, err
+
+}
+
+// GetThreadTransactionByThreadID call GetThreadTransactionByThreadID with the storage deadline and retries
+func (s *Storage) GetThreadTransactionByThreadID(
+	ctx context.Context,
+	sessionData *types.Session) (orders []types.Order, err error) {
+
+	err = s.do(ctx, sessionData, "GetThreadTransactionByThreadID", idempotent, func(ctx context.Context) (err error) {
+		orders, err = s.Storage.GetThreadTransactionByThreadID(ctx, sessionData)
+		return err
+	})
+
+	return orders, err
+
+}
+
+// GetThreadTransactionAll call GetThreadTransactionAll with the storage deadline and retries
+func (s *Storage) GetThreadTransactionAll(
+	ctx context.Context,
+	sessionData *types.Session) (orders []types.Order, err error) {
+
+	err = s.do(ctx, sessionData, "GetThreadTransactionAll", idempotent, func(ctx context.Context) (err error) {
+		orders, err = s.Storage.GetThreadTransactionAll(ctx, sessionData)
+		return err
+	})
+
+	return orders, err
+
+}
+
+// GetThreadTransactiontUpmarketPriceCount call GetThreadTransactiontUpmarketPriceCount with the storage deadline and retries
+func (s *Storage) GetThreadTransactiontUpmarketPriceCount(
+	ctx context.Context,
+	sessionData *types.Session,
+	price float64) (count int, err error) {
+
+	err = s.do(ctx, sessionData, "GetThreadTransactiontUpmarketPriceCount", idempotent, func(ctx context.Context) (err error) {
+		count, err = s.Storage.GetThreadTransactiontUpmarketPriceCount(ctx, sessionData, price)
+		return err
+	})
+
+	return count, err
+
+}
+
+// GetThreadLastTransaction call GetThreadLastTransaction with the storage deadline and retries
+func (s *Storage) GetThreadLastTransaction(
+	ctx context.Context,
+	sessionData *types.Session) (order types.Order, err error) {
+
+	err = s.do(ctx, sessionData, "GetThreadLastTransaction", idempotent, func(ctx context.Context) (err error) {
+		order, err = s.Storage.GetThreadLastTransaction(ctx, sessionData)
+		return err
+	})
+
+	return order, err
+
+}
+
+// GetThreadCount call GetThreadCount with the storage deadline and retries
+func (s *Storage) GetThreadCount(
+	ctx context.Context,
+	sessionData *types.Session) (count int, err error) {
+
+	err = s.do(ctx, sessionData, "GetThreadCount", idempotent, func(ctx context.Context) (err error) {
+		count, err = s.Storage.GetThreadCount(ctx, sessionData)
+		return err
+	})
+
+	return count, err
+
+}
+
+// GetThreadAmount call GetThreadAmount with the storage deadline and retries
+func (s *Storage) GetThreadAmount(
+	ctx context.Context,
+	sessionData *types.Session) (amount float64, err error) {
+
+	err = s.do(ctx, sessionData, "GetThreadAmount", idempotent, func(ctx context.Context) (err error) {
+		amount, err = s.Storage.GetThreadAmount(ctx, sessionData)
+		return err
+	})
+
+	return amount, err
+
+}
+
+// GetThreadTransactionMissing call GetThreadTransactionMissing with the storage deadline and retries
+func (s *Storage) GetThreadTransactionMissing(
+	ctx context.Context,
+	sessionData *types.Session) (orders []types.Order, err error) {
+
+	err = s.do(ctx, sessionData, "GetThreadTransactionMissing", idempotent, func(ctx context.Context) (err error) {
+		orders, err = s.Storage.GetThreadTransactionMissing(ctx, sessionData)
+		return err
+	})
+
+	return orders, err
+
+}
+
+// GetThreadTransactionSold call GetThreadTransactionSold with the storage deadline and retries
+func (s *Storage) GetThreadTransactionSold(
+	ctx context.Context,
+	sessionData *types.Session) (orders []types.Order, err error) {
+
+	err = s.do(ctx, sessionData, "GetThreadTransactionSold", idempotent, func(ctx context.Context) (err error) {
+		orders, err = s.Storage.GetThreadTransactionSold(ctx, sessionData)
+		return err
+	})
+
+	return orders, err
+
+}
+
+// GetTrades call GetTrades with the storage deadline and retries
+func (s *Storage) GetTrades(
+	ctx context.Context,
+	sessionData *types.Session,
+	filter types.TradeFilter) (trades []types.Trade, err error) {
+
+	err = s.do(ctx, sessionData, "GetTrades", idempotent, func(ctx context.Context) (err error) {
+		trades, err = s.Storage.GetTrades(ctx, sessionData, filter)
+		return err
+	})
+
+	return trades, err
+
+}
+
+// GetTradeStats call GetTradeStats with the storage deadline and retries
+func (s *Storage) GetTradeStats(
+	ctx context.Context,
+	sessionData *types.Session,
+	threadID string) (stats types.TradeStats, err error) {
+
+	err = s.do(ctx, sessionData, "GetTradeStats", idempotent, func(ctx context.Context) (err error) {
+		stats, err = s.Storage.GetTradeStats(ctx, sessionData, threadID)
+		return err
+	})
+
+	return stats, err
+
+}
+
+// SaveEquity call SaveEquity with the storage deadline and retries
+func (s *Storage) SaveEquity(
+	ctx context.Context,
+	sessionData *types.Session,
+	equity types.Equity) error {
+
+	return s.do(ctx, sessionData, "SaveEquity", idempotent, func(ctx context.Context) error {
+		return s.Storage.SaveEquity(ctx, sessionData, equity)
+	})
+
+}
+
+// GetEquity call GetEquity with the storage deadline and retries
+func (s *Storage) GetEquity(
+	ctx context.Context,
+	sessionData *types.Session,
+	threadID string) (equity []types.Equity, err error) {
+
+	err = s.do(ctx, sessionData, "GetEquity", idempotent, func(ctx context.Context) (err error) {
+		equity, err = s.Storage.GetEquity(ctx, sessionData, threadID)
+		return err
+	})
+
+	return equity, err
+
+}
+
+// AcquireLease call AcquireLease with the storage deadline and retries
+func (s *Storage) AcquireLease(
+	ctx context.Context,
+	sessionData *types.Session,
+	name string,
+	holder string,
+	duration int64) (lease types.Lease, err error) {
+
+	err = s.do(ctx, sessionData, "AcquireLease", idempotent, func(ctx context.Context) (err error) {
+		lease, err = s.Storage.AcquireLease(ctx, sessionData, name, holder, duration)
+		return err
+	})
+
+	return lease, err
+
+}
+
+// ReleaseLease call ReleaseLease with the storage deadline and retries
+func (s *Storage) ReleaseLease(
+	ctx context.Context,
+	sessionData *types.Session,
+	name string,
+	holder string) error {
+
+	return s.do(ctx, sessionData, "ReleaseLease", idempotent, func(ctx context.Context) error {
+		return s.Storage.ReleaseLease(ctx, sessionData, name, holder)
+	})
+
+}
+
+// SaveNode call SaveNode with the storage deadline and retries
+func (s *Storage) SaveNode(
+	ctx context.Context,
+	sessionData *types.Session,
+	node types.Node) error {
+
+	return s.do(ctx, sessionData, "SaveNode", idempotent, func(ctx context.Context) error {
+		return s.Storage.SaveNode(ctx, sessionData, node)
+	})
+
+}
+
+// GetNodes call GetNodes with the storage deadline and retries
+func (s *Storage) GetNodes(
+	ctx context.Context,
+	sessionData *types.Session) (nodes []types.Node, err error) {
+
+	err = s.do(ctx, sessionData, "GetNodes", idempotent, func(ctx context.Context) (err error) {
+		nodes, err = s.Storage.GetNodes(ctx, sessionData)
+		return err
+	})
+
+	return nodes, err
+
+}
+
+// DeleteNode call DeleteNode with the storage deadline and retries
+func (s *Storage) DeleteNode(
+	ctx context.Context,
+	sessionData *types.Session,
+	threadID string) error {
+
+	return s.do(ctx, sessionData, "DeleteNode", idempotent, func(ctx context.Context) error {
+		return s.Storage.DeleteNode(ctx, sessionData, threadID)
+	})
+
+}
+
+// GetThreadOrphans call GetThreadOrphans with the storage deadline and retries
+func (s *Storage) GetThreadOrphans(
+	ctx context.Context,
+	sessionData *types.Session,
+	age int64) (orphans []types.Orphan, err error) {
+
+	err = s.do(ctx, sessionData, "GetThreadOrphans", idempotent, func(ctx context.Context) (err error) {
+		orphans, err = s.Storage.GetThreadOrphans(ctx, sessionData, age)
+		return err
+	})
+
+	return orphans, err
+
+}
+
+// SaveAudit call SaveAudit with the storage deadline and retries
+func (s *Storage) SaveAudit(
+	ctx context.Context,
+	sessionData *types.Session,
+	audit types.Audit) error {
+
+	return s.do(ctx, sessionData, "SaveAudit", insert, func(ctx context.Context) error {
+		return s.Storage.SaveAudit(ctx, sessionData, audit)
+	})
+
+}
+
+// GetAudit call GetAudit with the storage deadline and retries
+func (s *Storage) GetAudit(
+	ctx context.Context,
+	sessionData *types.Session,
+	since int64) (audit []types.Audit, err error) {
+
+	err = s.do(ctx, sessionData, "GetAudit", idempotent, func(ctx context.Context) (err error) {
+		audit, err = s.Storage.GetAudit(ctx, sessionData, since)
+		return err
+	})
+
+	return audit, err
+
+}
+
+// SavePause call SavePause with the storage deadline and retries
+func (s *Storage) SavePause(
+	ctx context.Context,
+	sessionData *types.Session,
+	pause types.Pause) error {
+
+	return s.do(ctx, sessionData, "SavePause", idempotent, func(ctx context.Context) error {
+		return s.Storage.SavePause(ctx, sessionData, pause)
+	})
+
+}
+
+// GetPause call GetPause with the storage deadline and retries
+func (s *Storage) GetPause(
+	ctx context.Context,
+	sessionData *types.Session,
+	threadID string) (pause types.Pause, err error) {
+
+	err = s.do(ctx, sessionData, "GetPause", idempotent, func(ctx context.Context) (err error) {
+		pause, err = s.Storage.GetPause(ctx, sessionData, threadID)
+		return err
+	})
+
+	return pause, err
+
+}
+
+// DeletePause call DeletePause with the storage deadline and retries
+func (s *Storage) DeletePause(
+	ctx context.Context,
+	sessionData *types.Session,
+	threadID string) error {
+
+	return s.do(ctx, sessionData, "DeletePause", idempotent, func(ctx context.Context) error {
+		return s.Storage.DeletePause(ctx, sessionData, threadID)
+	})
+
+}
+
+// OrderTx call OrderTx with the storage deadline and retries
+func (s *Storage) OrderTx(
+	ctx context.Context,
+	sessionData *types.Session,
+	f func(tx types.OrderTx) error) error {
+
+	return s.do(ctx, sessionData, "OrderTx", insert, func(ctx context.Context) error {
+		return s.Storage.OrderTx(ctx, sessionData, f)
+	})
+
+}
+
+// SaveSession call SaveSession with the storage deadline and retries
+func (s *Storage) SaveSession(
+	ctx context.Context,
+	configData *types.Config,
+	sessionData *types.Session) error {
+
+	return s.do(ctx, sessionData, "SaveSession", insert, func(ctx context.Context) error {
+		return s.Storage.SaveSession(ctx, configData, sessionData)
+	})
+
+}
+
+// UpdateSession call UpdateSession with the storage deadline and retries
+func (s *Storage) UpdateSession(
+	ctx context.Context,
+	configData *types.Config,
+	sessionData *types.Session) error {
+
+	return s.do(ctx, sessionData, "UpdateSession", idempotent, func(ctx context.Context) error {
+		return s.Storage.UpdateSession(ctx, configData, sessionData)
+	})
+
+}
+
+// DeleteSession call DeleteSession with the storage deadline and retries
+func (s *Storage) DeleteSession(
+	ctx context.Context,
+	sessionData *types.Session) error {
+
+	return s.do(ctx, sessionData, "DeleteSession", idempotent, func(ctx context.Context) error {
+		return s.Storage.DeleteSession(ctx, sessionData)
+	})
+
+}
+
+// GetSessionStatus call GetSessionStatus with the storage deadline and retries
+func (s *Storage) GetSessionStatus(
+	ctx context.Context,
+	sessionData *types.Session) (status string, err error) {
+
+	err = s.do(ctx, sessionData, "GetSessionStatus", idempotent, func(ctx context.Context) (err error) {
+		status, err = s.Storage.GetSessionStatus(ctx, sessionData)
+		return err
+	})
+
+	return status, err
+
+}
+
+// GetSessions call GetSessions with the storage deadline and retries
+func (s *Storage) GetSessions(
+	ctx context.Context,
+	sessionData *types.Session) (sessions []types.SessionRecord, err error) {
+
+	err = s.do(ctx, sessionData, "GetSessions", idempotent, func(ctx context.Context) (err error) {
+		sessions, err = s.Storage.GetSessions(ctx, sessionData)
+		return err
+	})
+
+	return sessions, err
+
+}
+
+// SaveGlobal call SaveGlobal with the storage deadline and retries
+func (s *Storage) SaveGlobal(
+	ctx context.Context,
+	sessionData *types.Session) error {
+
+	return s.do(ctx, sessionData, "SaveGlobal", insert, func(ctx context.Context) error {
+		return s.Storage.SaveGlobal(ctx, sessionData)
+	})
+
+}
+
+// UpdateGlobal call UpdateGlobal with the storage deadline and retries
+func (s *Storage) UpdateGlobal(
+	ctx context.Context,
+	sessionData *types.Session) error {
+
+	return s.do(ctx, sessionData, "UpdateGlobal", idempotent, func(ctx context.Context) error {
+		return s.Storage.UpdateGlobal(ctx, sessionData)
+	})
+
+}
+
+// GetGlobal call GetGlobal with the storage deadline and retries
+func (s *Storage) GetGlobal(
+	ctx context.Context,
+	sessionData *types.Session) (profit float64, profitNet float64, profitPct float64, transactTime int64, err error) {
+
+	err = s.do(ctx, sessionData, "GetGlobal", idempotent, func(ctx context.Context) (err error) {
+		profit, profitNet, profitPct, transactTime, err = s.Storage.GetGlobal(ctx, sessionData)
+		return err
+	})
+
+	return profit, profitNet, profitPct, transactTime, err
+
+}
+
+// GetProfit call GetProfit with the storage deadline and retries
+func (s *Storage) GetProfit(
+	ctx context.Context,
+	sessionData *types.Session) (profit float64, profitNet float64, profitPct float64, err error) {
+
+	err = s.do(ctx, sessionData, "GetProfit", idempotent, func(ctx context.Context) (err error) {
+		profit, profitNet, profitPct, err = s.Storage.GetProfit(ctx, sessionData)
+		return err
+	})
+
+	return profit, profitNet, profitPct, err
+
+}
+
+// GetProfitByThreadID call GetProfitByThreadID with the storage deadline and retries
+func (s *Storage) GetProfitByThreadID(
+	ctx context.Context,
+	sessionData *types.Session) (fiat float64, percentage float64, err error) {
+
+	err = s.do(ctx, sessionData, "GetProfitByThreadID", idempotent, func(ctx context.Context) (err error) {
+		fiat, percentage, err = s.Storage.GetProfitByThreadID(ctx, sessionData)
+		return err
+	})
+
+	return fiat, percentage, err
+
+}
+
+// GetProfitByThreadIDSince call GetProfitByThreadIDSince with the storage deadline and retries
+func (s *Storage) GetProfitByThreadIDSince(
+	ctx context.Context,
+	sessionData *types.Session,
+	transactTime int64) (profit float64, err error) {
+
+	err = s.do(ctx, sessionData, "GetProfitByThreadIDSince", idempotent, func(ctx context.Context) (err error) {
+		profit, err = s.Storage.GetProfitByThreadIDSince(ctx, sessionData, transactTime)
+		return err
+	})
+
+	return profit, err
+
+}
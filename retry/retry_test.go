@@ -0,0 +1,301 @@
+package retry
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/aleibovici/cryptopump/engine"
+	"github.com/aleibovici/cryptopump/risk"
+	"github.com/aleibovici/cryptopump/types"
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+)
+
+func Test_classify(t *testing.T) {
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "bad connection", err: driver.ErrBadConn, want: unapplied},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, want: unapplied},
+		{name: "mysql deadlock", err: &mysql.MySQLError{Number: 1213}, want: unapplied},
+		{name: "mysql lock wait timeout", err: fmt.Errorf("SaveOrder: %w", &mysql.MySQLError{Number: 1205}), want: unapplied},
+		{name: "mysql duplicate key", err: &mysql.MySQLError{Number: 1062}, want: permanent},
+		{name: "sqlite busy", err: sqlite3.Error{Code: sqlite3.ErrBusy}, want: unapplied},
+		{name: "sqlite constraint", err: sqlite3.Error{Code: sqlite3.ErrConstraint}, want: permanent},
+		{name: "mysql invalid connection", err: mysql.ErrInvalidConn, want: unknown},
+		{name: "unexpected EOF", err: io.ErrUnexpectedEOF, want: unknown},
+		{name: "connection reset", err: &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, want: unknown},
+		{name: "other", err: errors.New("syntax error"), want: permanent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classify(tt.err); got != tt.want {
+				t.Errorf("classify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStorage_do(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		kind         int
+		errs         []error /* Error returned by each call, nil once exhausted */
+		degraded     bool    /* Storage degraded before the call */
+		failed       int     /* Failed calls within window before the call */
+		wantCalls    int
+		wantErr      bool
+		wantDegraded bool
+	}{
+		{
+			name:      "success",
+			kind:      idempotent,
+			wantCalls: 1,
+		},
+		{
+			name:      "success ends degraded mode",
+			kind:      idempotent,
+			degraded:  true,
+			wantCalls: 1,
+		},
+		{
+			name:      "transient error retried",
+			kind:      insert,
+			errs:      []error{driver.ErrBadConn, &mysql.MySQLError{Number: 1213}},
+			wantCalls: 3,
+		},
+		{
+			name:      "persistent transient error",
+			kind:      idempotent,
+			errs:      []error{driver.ErrBadConn, driver.ErrBadConn, driver.ErrBadConn, driver.ErrBadConn},
+			wantCalls: 4,
+			wantErr:   true,
+		},
+		{
+			name:         "persistent transient error after failed calls",
+			kind:         idempotent,
+			errs:         []error{driver.ErrBadConn, driver.ErrBadConn, driver.ErrBadConn, driver.ErrBadConn},
+			failed:       threshold - 1,
+			wantCalls:    4,
+			wantErr:      true,
+			wantDegraded: true,
+		},
+		{
+			name:      "lost connection retried by idempotent call",
+			kind:      idempotent,
+			errs:      []error{io.ErrUnexpectedEOF},
+			wantCalls: 2,
+		},
+		{
+			name:         "lost connection not retried by insert",
+			kind:         insert,
+			errs:         []error{io.ErrUnexpectedEOF},
+			failed:       threshold - 1,
+			wantCalls:    1,
+			wantErr:      true,
+			wantDegraded: true,
+		},
+		{
+			name:      "permanent error",
+			kind:      insert,
+			errs:      []error{&mysql.MySQLError{Number: 1062}},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s := New(nil, time.Second)
+			s.backoff = time.Millisecond
+			if tt.degraded {
+				s.degraded = 1
+			}
+
+			for i := 0; i < tt.failed; i++ {
+				s.failures = append(s.failures, time.Now())
+			}
+
+			sessionData := &types.Session{}
+			calls := 0
+
+			err := s.do(context.Background(), sessionData, "test", tt.kind, func(ctx context.Context) error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Storage.do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("Storage.do() calls = %v, want %v", calls, tt.wantCalls)
+			}
+			if s.Degraded() != tt.wantDegraded {
+				t.Errorf("Storage.do() Degraded() = %v, want %v", s.Degraded(), tt.wantDegraded)
+			}
+		})
+	}
+}
+
+/* Call blocking until its context is done, as on a hung database */
+func hung(ctx context.Context) error {
+
+	<-ctx.Done()
+
+	return ctx.Err()
+
+}
+
+func TestStorage_doDeadline(t *testing.T) {
+
+	s := New(nil, 20*time.Millisecond)
+	sessionData := &types.Session{}
+
+	start := time.Now()
+	if err := s.do(context.Background(), sessionData, "test", idempotent, hung); err == nil {
+		t.Fatalf("Storage.do() error = nil, want deadline exceeded")
+	}
+
+	/* A deadline is not retried */
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Storage.do() returned after %v, want one deadline", elapsed)
+	}
+
+	if len(s.failures) != 1 || s.Degraded() {
+		t.Errorf("Storage.do() failures = %v, Degraded() = %v, want 1 failure and not degraded", len(s.failures), s.Degraded())
+	}
+
+	/* A call canceled by the caller isn't counted */
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := s.do(ctx, sessionData, "test", idempotent, hung); err == nil || len(s.failures) != 1 {
+		t.Errorf("Storage.do() canceled error = %v, failures = %v, want canceled and 1 failure", err, len(s.failures))
+	}
+
+}
+
+func TestStorage_degrade(t *testing.T) {
+
+	s := New(nil, time.Second)
+	sessionData := &types.Session{}
+	err := errors.New("timeout")
+
+	/* Failures older than window are not counted */
+	s.failures = []time.Time{time.Now().Add(-2 * window), time.Now().Add(-window - time.Second)}
+	s.degrade(sessionData, "test", err)
+	if len(s.failures) != 1 || s.Degraded() {
+		t.Fatalf("Storage.degrade() failures = %v, Degraded() = %v, want 1 failure and not degraded", len(s.failures), s.Degraded())
+	}
+
+	for i := 1; i < threshold; i++ {
+		s.degrade(sessionData, "test", err)
+	}
+
+	if !s.Degraded() {
+		t.Fatalf("Storage.degrade() Degraded() = false after %v failures, want true", threshold)
+	}
+
+	/* The first call that succeeds ends degraded mode and the failures count */
+	s.recovered(sessionData)
+	if s.Degraded() || len(s.failures) != 0 {
+		t.Errorf("Storage.recovered() Degraded() = %v, failures = %v, want not degraded and no failure", s.Degraded(), len(s.failures))
+	}
+
+}
+
+func TestStorage_Degraded(t *testing.T) {
+
+	errs := []error{}
+	for i := 0; i < threshold*attempts; i++ {
+		errs = append(errs, driver.ErrBadConn)
+	}
+
+	s := New(&flakyStorage{errs: errs}, time.Second)
+	s.backoff = time.Millisecond
+
+	e := engine.New(&types.Config{}, &types.Market{}, &types.Session{Storage: s})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go e.Run(ctx)
+
+	defer func() {
+		cancel()
+		<-e.Done()
+	}()
+
+	/* Calls failing on session copies, as made by API and Telegram requests and by scheduled jobs */
+	for i := 0; i < threshold; i++ {
+		sessionData := e.Session()
+		if _, err := sessionData.Storage.GetOrderSymbol(context.Background(), sessionData); err == nil {
+			t.Fatalf("Storage.GetOrderSymbol() error = nil, want %v", driver.ErrBadConn)
+		}
+	}
+
+	/* The thread session pauses BUY */
+	var allowed bool
+	var reason string
+	e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+		allowed = (risk.Manager{}).IsBuyAllowed(configData, sessionData, 50)
+		reason = sessionData.Risk.Reason
+	})
+
+	if allowed || reason != "Database unavailable" {
+		t.Errorf("Manager.IsBuyAllowed() on the thread session = %v, reason %q, want false, Database unavailable", allowed, reason)
+	}
+
+}
+
+/* Storage backend failing the first calls of GetOrderSymbol */
+type flakyStorage struct {
+	types.Storage
+	errs []error
+}
+
+func (f *flakyStorage) GetOrderSymbol(ctx context.Context, sessionData *types.Session) (string, error) {
+
+	if _, ok := ctx.Deadline(); !ok {
+		return "", errors.New("no deadline")
+	}
+
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return "", err
+	}
+
+	return "BTCUSDT", nil
+
+}
+
+func TestStorage_GetOrderSymbol(t *testing.T) {
+
+	s := New(&flakyStorage{errs: []error{sqlite3.Error{Code: sqlite3.ErrBusy}}}, 0)
+	s.backoff = time.Millisecond
+
+	if s.timeout != Timeout {
+		t.Errorf("New() timeout = %v, want %v", s.timeout, Timeout)
+	}
+
+	if symbol, err := s.GetOrderSymbol(context.Background(), &types.Session{}); err != nil || symbol != "BTCUSDT" {
+		t.Errorf("Storage.GetOrderSymbol() = %v, %v, want BTCUSDT", symbol, err)
+	}
+
+}
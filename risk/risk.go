@@ -0,0 +1,232 @@
+package risk
+
+/* This package implements the risk manager. Every BUY approved by the BUY decision tree,
+every forced BUY and every sell-to-cover sale must pass the risk manager before reaching
+the exchange. When a rule trips BUY is paused for the thread, the rule is logged, notified
+via Telegram and displayed in the web UI. BUY is also paused while the database is in degraded
+mode (see package retry). */
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aleibovici/cryptopump/functions"
+	"github.com/aleibovici/cryptopump/logger"
+	"github.com/aleibovici/cryptopump/money"
+	"github.com/aleibovici/cryptopump/telegram"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+/* Interval between realized daily loss refreshes from the database */
+const dailyLossRefresh = 60 * time.Second
+
+// Manager enforce exposure caps and loss circuit breakers
+type Manager struct{}
+
+// IsBuyAllowed test risk rules for a BUY of buyQuantityFiat. The rule that tripped is
+// stored in sessionData.Risk.Reason.
+func (m Manager) IsBuyAllowed(
+	configData *types.Config,
+	sessionData *types.Session,
+	buyQuantityFiat float64) bool {
+
+	reason := m.checkDatabase(sessionData)
+
+	if reason == "" {
+
+		reason = m.checkLoss(configData, sessionData)
+
+	}
+
+	/* Max open positions for the thread */
+	if reason == "" &&
+		configData.RiskMaxPositions > 0 &&
+		sessionData.ThreadCount >= configData.RiskMaxPositions {
+
+		reason = "Max open positions reached (" + strconv.Itoa(configData.RiskMaxPositions) + ")"
+
+	}
+
+	/* Max fiat deployed across all threads */
+	if reason == "" &&
+		configData.RiskMaxDeployed > 0 {
+
+		if amount, err := sessionData.Storage.GetThreadAmount(context.Background(), sessionData); err != nil {
+
+			reason = "Unable to retrieve deployed funds"
+
+		} else if money.New(amount).Add(money.New(buyQuantityFiat)).Cmp(money.New(configData.RiskMaxDeployed)) > 0 {
+
+			reason = "Max deployed funds reached (" + functions.Float64ToStr(configData.RiskMaxDeployed, 2) + ")"
+
+		}
+
+	}
+
+	m.setReason(configData, sessionData, reason)
+
+	return reason == ""
+
+}
+
+// IsSellToCoverAllowed test risk rules for a sell-to-cover sale. Sell-to-cover realizes a loss to
+// fund new BUY, so it is not allowed while a loss rule is pausing BUY.
+func (m Manager) IsSellToCoverAllowed(
+	configData *types.Config,
+	sessionData *types.Session) bool {
+
+	reason := m.checkDatabase(sessionData)
+
+	if reason == "" {
+
+		reason = m.checkLoss(configData, sessionData)
+
+	}
+
+	m.setReason(configData, sessionData, reason)
+
+	return reason == ""
+
+}
+
+// Sold record a filled sale. Stoploss sales are counted and trigger a cooldown after
+// risk_stopout_count consecutive stoploss sales, while profit sales reset the count.
+func (m Manager) Sold(
+	configData *types.Config,
+	sessionData *types.Session,
+	isStoploss bool,
+	isProfit bool) {
+
+	/* Force a realized daily loss refresh on the next check */
+	sessionData.Risk.DailyLossTime = time.Time{}
+
+	switch {
+	case isStoploss:
+
+		sessionData.Risk.StopOutCount++
+
+		if configData.RiskStopOutCount > 0 &&
+			sessionData.Risk.StopOutCount >= configData.RiskStopOutCount {
+
+			sessionData.Risk.StopOutCount = 0
+			sessionData.Risk.CooldownUntil = time.Now().Add(time.Duration(configData.RiskStopOutCooldown) * time.Minute)
+
+		}
+
+	case isProfit:
+
+		sessionData.Risk.StopOutCount = 0
+
+	}
+
+}
+
+/* Test the database degraded mode. BUY is paused until a database call succeeds. */
+func (m Manager) checkDatabase(
+	sessionData *types.Session) (reason string) {
+
+	if storage, ok := sessionData.Storage.(types.Degrader); ok && storage.Degraded() {
+
+		return "Database unavailable"
+
+	}
+
+	return ""
+
+}
+
+/* Test the stop-out cooldown and the max daily realized loss */
+func (m Manager) checkLoss(
+	configData *types.Config,
+	sessionData *types.Session) (reason string) {
+
+	/* Cooldown after risk_stopout_count consecutive stoploss sales */
+	if time.Now().Before(sessionData.Risk.CooldownUntil) {
+
+		return "Stop-out cooldown until " + sessionData.Risk.CooldownUntil.Format("15:04")
+
+	}
+
+	if configData.RiskMaxDailyLoss <= 0 {
+
+		return ""
+
+	}
+
+	/* Refresh realized loss for the current day */
+	now := time.Now()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	if !sessionData.Risk.DailyLossStart.Equal(dayStart) ||
+		time.Since(sessionData.Risk.DailyLossTime) > dailyLossRefresh {
+
+		profit, err := sessionData.Storage.GetProfitByThreadIDSince(context.Background(), sessionData, dayStart.UnixNano()/int64(time.Millisecond))
+		if err != nil {
+
+			return "Unable to retrieve daily realized loss"
+
+		}
+
+		sessionData.Risk.DailyLoss = 0
+		if profit < 0 {
+
+			sessionData.Risk.DailyLoss = -profit
+
+		}
+
+		sessionData.Risk.DailyLossStart = dayStart
+		sessionData.Risk.DailyLossTime = now
+
+	}
+
+	if sessionData.Risk.DailyLoss >= configData.RiskMaxDailyLoss {
+
+		return "Max daily loss reached (" + functions.Float64ToStr(configData.RiskMaxDailyLoss, 2) + ")"
+
+	}
+
+	return ""
+
+}
+
+/* Store the rule that tripped. Log and notify via Telegram when it changes. */
+func (m Manager) setReason(
+	configData *types.Config,
+	sessionData *types.Session,
+	reason string) {
+
+	if reason == sessionData.Risk.Reason {
+
+		return
+
+	}
+
+	sessionData.Risk.Reason = reason
+
+	message := "RISK - BUY resumed"
+	if reason != "" {
+
+		message = "RISK - BUY paused: " + reason
+
+	}
+
+	logger.LogEntry{ /* Log Entry */
+		Config:   configData,
+		Market:   nil,
+		Session:  sessionData,
+		Order:    &types.Order{},
+		Message:  message,
+		LogLevel: "InfoLevel",
+	}.Do()
+
+	/* Telegram is only connected on the thread holding the Telegram bot */
+	if sessionData.TgBotAPI != nil && sessionData.TgBotAPIChatID != 0 && reason != "" {
+
+		telegram.Message{
+			Text: "\f" + "BUY paused @ " + sessionData.ThreadID + ": " + reason,
+		}.Send(sessionData)
+
+	}
+
+}
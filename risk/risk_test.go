@@ -0,0 +1,195 @@
+package risk
+
+import (
+	"database/sql"
+	"log"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aleibovici/cryptopump/mysql"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+// NewMock returns a new mock database and sqlmock.Sqlmock
+func NewMock() (*sql.DB, sqlmock.Sqlmock) {
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return db, mock
+}
+
+/* Storage backend in degraded mode */
+type degradedStorage struct {
+	types.Storage
+}
+
+func (degradedStorage) Degraded() bool {
+
+	return true
+
+}
+
+func TestManager_IsBuyAllowed(t *testing.T) {
+
+	db, mock := NewMock()
+	defer db.Close()
+
+	type args struct {
+		configData      *types.Config
+		sessionData     *types.Session
+		buyQuantityFiat float64
+	}
+
+	tests := []struct {
+		name       string
+		args       args
+		want       bool
+		wantReason string
+	}{
+		{
+			name: "no rules",
+			args: args{
+				configData:      &types.Config{},
+				sessionData:     &types.Session{ThreadCount: 10},
+				buyQuantityFiat: 50,
+			},
+			want:       true,
+			wantReason: "",
+		},
+		{
+			name: "max open positions",
+			args: args{
+				configData:      &types.Config{RiskMaxPositions: 10},
+				sessionData:     &types.Session{ThreadCount: 10},
+				buyQuantityFiat: 50,
+			},
+			want:       false,
+			wantReason: "Max open positions reached (10)",
+		},
+		{
+			name: "stop-out cooldown",
+			args: args{
+				configData: &types.Config{},
+				sessionData: &types.Session{
+					Risk: types.Risk{CooldownUntil: time.Now().Add(time.Hour)},
+				},
+				buyQuantityFiat: 50,
+			},
+			want:       false,
+			wantReason: "Stop-out cooldown until " + time.Now().Add(time.Hour).Format("15:04"),
+		},
+		{
+			name: "database degraded",
+			args: args{
+				configData:      &types.Config{RiskMaxDeployed: 1000},
+				sessionData:     &types.Session{Storage: degradedStorage{}},
+				buyQuantityFiat: 50,
+			},
+			want:       false,
+			wantReason: "Database unavailable",
+		},
+		{
+			name: "max deployed",
+			args: args{
+				configData:      &types.Config{RiskMaxDeployed: 1000},
+				sessionData:     &types.Session{ThreadID: "c683ok5mk1u1120gnmmg", Db: db, Storage: mysql.Storage{}},
+				buyQuantityFiat: 50,
+			},
+			want:       false,
+			wantReason: "Max deployed funds reached (1000.00)",
+		},
+		{
+			name: "max daily loss",
+			args: args{
+				configData:      &types.Config{RiskMaxDailyLoss: 20},
+				sessionData:     &types.Session{ThreadID: "c683ok5mk1u1120gnmmg", Db: db, Storage: mysql.Storage{}},
+				buyQuantityFiat: 50,
+			},
+			want:       false,
+			wantReason: "Max daily loss reached (20.00)",
+		},
+	}
+
+	mock.ExpectBegin()                                                                  /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.GetThreadTransactionAmount()")). /* call procedure */
+												WillReturnRows(sqlmock.NewRows([]string{"amount"}).AddRow(980)) /* return 1 row */
+	mock.ExpectBegin()                                                                   /* begin transaction */
+	mock.ExpectQuery(regexp.QuoteMeta("call cryptopump.GetProfitByThreadIDSince(?,?)")). /* call procedure */
+												WillReturnRows(sqlmock.NewRows([]string{"profit"}).AddRow(-25)) /* return 1 row */
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Manager{}).IsBuyAllowed(tt.args.configData, tt.args.sessionData, tt.args.buyQuantityFiat); got != tt.want {
+				t.Errorf("Manager.IsBuyAllowed() = %v, want %v", got, tt.want)
+			}
+			if tt.args.sessionData.Risk.Reason != tt.wantReason {
+				t.Errorf("Manager.IsBuyAllowed() reason = %v, want %v", tt.args.sessionData.Risk.Reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestManager_Sold(t *testing.T) {
+
+	type args struct {
+		configData  *types.Config
+		sessionData *types.Session
+		isStoploss  bool
+		isProfit    bool
+	}
+
+	tests := []struct {
+		name             string
+		args             args
+		wantStopOutCount int
+		wantCooldown     bool
+	}{
+		{
+			name: "stoploss",
+			args: args{
+				configData:  &types.Config{RiskStopOutCount: 3, RiskStopOutCooldown: 60},
+				sessionData: &types.Session{Risk: types.Risk{StopOutCount: 1}},
+				isStoploss:  true,
+			},
+			wantStopOutCount: 2,
+			wantCooldown:     false,
+		},
+		{
+			name: "stoploss triggers cooldown",
+			args: args{
+				configData:  &types.Config{RiskStopOutCount: 3, RiskStopOutCooldown: 60},
+				sessionData: &types.Session{Risk: types.Risk{StopOutCount: 2}},
+				isStoploss:  true,
+			},
+			wantStopOutCount: 0,
+			wantCooldown:     true,
+		},
+		{
+			name: "profit resets count",
+			args: args{
+				configData:  &types.Config{RiskStopOutCount: 3, RiskStopOutCooldown: 60},
+				sessionData: &types.Session{Risk: types.Risk{StopOutCount: 2}},
+				isProfit:    true,
+			},
+			wantStopOutCount: 0,
+			wantCooldown:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Manager{}.Sold(tt.args.configData, tt.args.sessionData, tt.args.isStoploss, tt.args.isProfit)
+			if tt.args.sessionData.Risk.StopOutCount != tt.wantStopOutCount {
+				t.Errorf("Manager.Sold() StopOutCount = %v, want %v", tt.args.sessionData.Risk.StopOutCount, tt.wantStopOutCount)
+			}
+			if time.Now().Before(tt.args.sessionData.Risk.CooldownUntil) != tt.wantCooldown {
+				t.Errorf("Manager.Sold() CooldownUntil = %v, wantCooldown %v", tt.args.sessionData.Risk.CooldownUntil, tt.wantCooldown)
+			}
+		})
+	}
+}
@@ -0,0 +1,718 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/* Rule expressions are small boolean conditions written in the configuration templates,
+i.e. "rsi7 < 35 && macd > -20 && price < high24h*0.995". They are parsed once when the
+configuration is loaded and evaluated against market, session and order variables by the
+buy and sell decision trees. */
+
+// Variables lists the variable names accepted in rule expressions
+var Variables = []string{
+	"price",                /* Market price (best ask) */
+	"rsi3",                 /* Relative Strength Index for 3 periods */
+	"rsi7",                 /* Relative Strength Index for 7 periods */
+	"rsi14",                /* Relative Strength Index for 14 periods */
+	"macd",                 /* Moving average convergence divergence */
+	"ma7",                  /* Simple Moving Average for 7 periods */
+	"ma14",                 /* Simple Moving Average for 14 periods */
+	"high24h",              /* 24hs high price */
+	"low24h",               /* 24hs low price */
+	"direction",            /* Market direction */
+	"volatility",           /* Average True Range as a ratio of price */
+	"imbalance",            /* Order book bid/ask quantity imbalance from -1 (asks) to 1 (bids) */
+	"threadcount",          /* Number of open thread transactions */
+	"selltransactioncount", /* Number of SELL transactions in the last 60 minutes */
+	"fiatfunds",            /* Available fiat funds in exchange */
+	"symbolfunds",          /* Available crypto funds in exchange */
+	"latency",              /* Latency between the exchange and client in milliseconds */
+	"order_price",          /* Price of the order being evaluated for sale */
+	"order_quantity",       /* Executed quantity of the order being evaluated for sale */
+	"order_age",            /* Seconds since the order being evaluated for sale was executed */
+	"order_profit",         /* Profit ratio of the order being evaluated for sale at market price */
+}
+
+// Expression hold a parsed rule expression
+type Expression struct {
+	Source string /* Original expression text */
+	root   node
+}
+
+/* node is an element of the expression tree. isBool is resolved at parse time so that
+type errors such as "rsi7 && macd" are reported when the configuration is loaded. */
+type node struct {
+	op       string /* Operator, "num" for literals and "var" for variables */
+	value    float64
+	name     string
+	source   string /* Source text used to report the term that blocked a decision */
+	isBool   bool
+	operands []node
+}
+
+type token struct {
+	kind  string /* "num", "ident", "op" or "eof" */
+	text  string
+	value float64
+	pos   int
+}
+
+type parser struct {
+	source string
+	tokens []token
+	pos    int
+	buy    bool /* Order variables are rejected in buy rules */
+}
+
+/* Prefix of the variables of the order being evaluated for sale */
+const orderPrefix = "order_"
+
+// Parse compile a rule expression. An empty expression returns nil and no error.
+func Parse(source string) (*Expression, error) {
+
+	return parse(source, false)
+
+}
+
+// ParseBuy compile a buy_rules expression. Order variables are rejected as there is no order to evaluate
+// before a BUY.
+func ParseBuy(source string) (*Expression, error) {
+
+	return parse(source, true)
+
+}
+
+/* Compile a rule expression, rejecting order variables when buy is true */
+func parse(source string, buy bool) (*Expression, error) {
+
+	if strings.TrimSpace(source) == "" {
+
+		return nil, nil
+
+	}
+
+	tokens, err := tokenize(source)
+	if err != nil {
+
+		return nil, err
+
+	}
+
+	p := &parser{source: source, tokens: tokens, buy: buy}
+
+	root, err := p.parseOr()
+	if err != nil {
+
+		return nil, err
+
+	}
+
+	if t := p.peek(); t.kind != "eof" {
+
+		return nil, fmt.Errorf("unexpected '%s' at position %d", t.text, t.pos+1)
+
+	}
+
+	if !root.isBool {
+
+		return nil, errors.New("expression must be a condition, i.e. rsi7 < 35")
+
+	}
+
+	return &Expression{Source: source, root: root}, nil
+
+}
+
+// Eval evaluate the expression against variables. When the expression is false, blocked
+// returns the first top-level && term that evaluated false.
+func (e *Expression) Eval(variables map[string]float64) (is bool, blocked string, err error) {
+
+	if e == nil {
+
+		return true, "", nil
+
+	}
+
+	/* Report the first failing term of a top-level && chain */
+	terms := []node{e.root}
+	if e.root.op == "&&" {
+
+		terms = flattenAnd(e.root)
+
+	}
+
+	for _, term := range terms {
+
+		value, err := eval(term, variables)
+		if err != nil {
+
+			return false, term.source, err
+
+		}
+
+		if value == 0 {
+
+			return false, term.source, nil
+
+		}
+
+	}
+
+	return true, "", nil
+
+}
+
+/* Flatten nested && nodes into the list of terms in source order */
+func flattenAnd(n node) (terms []node) {
+
+	if n.op != "&&" {
+
+		return []node{n}
+
+	}
+
+	for _, operand := range n.operands {
+
+		terms = append(terms, flattenAnd(operand)...)
+
+	}
+
+	return terms
+
+}
+
+/* Evaluate a node. Booleans are represented as 1 (true) and 0 (false). */
+func eval(n node, variables map[string]float64) (float64, error) {
+
+	switch n.op {
+	case "num":
+
+		return n.value, nil
+
+	case "var":
+
+		value, ok := variables[n.name]
+		if !ok {
+
+			return 0, fmt.Errorf("variable '%s' not available", n.name)
+
+		}
+
+		return value, nil
+
+	case "&&", "||":
+
+		left, err := eval(n.operands[0], variables)
+		if err != nil {
+
+			return 0, err
+
+		}
+
+		/* Short-circuit evaluation */
+		if (n.op == "&&" && left == 0) || (n.op == "||" && left != 0) {
+
+			return boolToFloat(left != 0), nil
+
+		}
+
+		right, err := eval(n.operands[1], variables)
+		if err != nil {
+
+			return 0, err
+
+		}
+
+		return boolToFloat(right != 0), nil
+
+	case "!":
+
+		value, err := eval(n.operands[0], variables)
+		if err != nil {
+
+			return 0, err
+
+		}
+
+		return boolToFloat(value == 0), nil
+
+	case "neg":
+
+		value, err := eval(n.operands[0], variables)
+		if err != nil {
+
+			return 0, err
+
+		}
+
+		return -value, nil
+
+	}
+
+	left, err := eval(n.operands[0], variables)
+	if err != nil {
+
+		return 0, err
+
+	}
+
+	right, err := eval(n.operands[1], variables)
+	if err != nil {
+
+		return 0, err
+
+	}
+
+	switch n.op {
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "*":
+		return left * right, nil
+	case "/":
+		if right == 0 {
+			return 0, fmt.Errorf("division by zero in '%s'", n.source)
+		}
+		return left / right, nil
+	case "<":
+		return boolToFloat(left < right), nil
+	case "<=":
+		return boolToFloat(left <= right), nil
+	case ">":
+		return boolToFloat(left > right), nil
+	case ">=":
+		return boolToFloat(left >= right), nil
+	case "==":
+		return boolToFloat(left == right), nil
+	case "!=":
+		return boolToFloat(left != right), nil
+	}
+
+	return 0, fmt.Errorf("unknown operator '%s'", n.op)
+
+}
+
+func boolToFloat(b bool) float64 {
+
+	if b {
+
+		return 1
+
+	}
+
+	return 0
+
+}
+
+/* Split the expression source into tokens */
+func tokenize(source string) (tokens []token, err error) {
+
+	for i := 0; i < len(source); {
+
+		c := rune(source[i])
+
+		switch {
+		case unicode.IsSpace(c):
+
+			i++
+
+		case unicode.IsDigit(c) || c == '.':
+
+			start := i
+			for i < len(source) && (unicode.IsDigit(rune(source[i])) || source[i] == '.') {
+				i++
+			}
+
+			value, err := strconv.ParseFloat(source[start:i], 64)
+			if err != nil {
+
+				return nil, fmt.Errorf("invalid number '%s' at position %d", source[start:i], start+1)
+
+			}
+
+			tokens = append(tokens, token{kind: "num", text: source[start:i], value: value, pos: start})
+
+		case unicode.IsLetter(c) || c == '_':
+
+			start := i
+			for i < len(source) && (unicode.IsLetter(rune(source[i])) || unicode.IsDigit(rune(source[i])) || source[i] == '_') {
+				i++
+			}
+
+			tokens = append(tokens, token{kind: "ident", text: strings.ToLower(source[start:i]), pos: start})
+
+		default:
+
+			/* Two character operators take precedence over single character operators */
+			if i+1 < len(source) {
+
+				switch source[i : i+2] {
+				case "&&", "||", "<=", ">=", "==", "!=":
+
+					tokens = append(tokens, token{kind: "op", text: source[i : i+2], pos: i})
+					i += 2
+
+					continue
+
+				}
+
+			}
+
+			if !strings.ContainsRune("+-*/<>!()", c) {
+
+				return nil, fmt.Errorf("unexpected '%c' at position %d", c, i+1)
+
+			}
+
+			tokens = append(tokens, token{kind: "op", text: string(c), pos: i})
+			i++
+
+		}
+
+	}
+
+	return append(tokens, token{kind: "eof", text: "end of expression", pos: len(source)}), nil
+
+}
+
+func (p *parser) peek() token {
+
+	return p.tokens[p.pos]
+
+}
+
+func (p *parser) next() token {
+
+	t := p.tokens[p.pos]
+
+	if t.kind != "eof" {
+
+		p.pos++
+
+	}
+
+	return t
+
+}
+
+/* Return the trimmed source text between two token positions */
+func (p *parser) text(start int) string {
+
+	return strings.TrimSpace(p.source[p.tokens[start].pos:p.peek().pos])
+
+}
+
+/* or := and ( "||" and )* */
+func (p *parser) parseOr() (node, error) {
+
+	start := p.pos
+
+	left, err := p.parseAnd()
+	if err != nil {
+
+		return node{}, err
+
+	}
+
+	for p.peek().text == "||" {
+
+		t := p.next()
+
+		right, err := p.parseAnd()
+		if err != nil {
+
+			return node{}, err
+
+		}
+
+		if !left.isBool || !right.isBool {
+
+			return node{}, fmt.Errorf("operands of '||' at position %d must be conditions", t.pos+1)
+
+		}
+
+		left = node{op: "||", isBool: true, operands: []node{left, right}, source: p.text(start)}
+
+	}
+
+	return left, nil
+
+}
+
+/* and := not ( "&&" not )* */
+func (p *parser) parseAnd() (node, error) {
+
+	start := p.pos
+
+	left, err := p.parseNot()
+	if err != nil {
+
+		return node{}, err
+
+	}
+
+	for p.peek().text == "&&" {
+
+		t := p.next()
+
+		right, err := p.parseNot()
+		if err != nil {
+
+			return node{}, err
+
+		}
+
+		if !left.isBool || !right.isBool {
+
+			return node{}, fmt.Errorf("operands of '&&' at position %d must be conditions", t.pos+1)
+
+		}
+
+		left = node{op: "&&", isBool: true, operands: []node{left, right}, source: p.text(start)}
+
+	}
+
+	return left, nil
+
+}
+
+/* not := "!" not | comparison */
+func (p *parser) parseNot() (node, error) {
+
+	start := p.pos
+
+	if p.peek().text == "!" {
+
+		t := p.next()
+
+		operand, err := p.parseNot()
+		if err != nil {
+
+			return node{}, err
+
+		}
+
+		if !operand.isBool {
+
+			return node{}, fmt.Errorf("operand of '!' at position %d must be a condition", t.pos+1)
+
+		}
+
+		return node{op: "!", isBool: true, operands: []node{operand}, source: p.text(start)}, nil
+
+	}
+
+	return p.parseComparison()
+
+}
+
+/* comparison := sum ( ( "<" | "<=" | ">" | ">=" | "==" | "!=" ) sum )? */
+func (p *parser) parseComparison() (node, error) {
+
+	start := p.pos
+
+	left, err := p.parseSum()
+	if err != nil {
+
+		return node{}, err
+
+	}
+
+	switch t := p.peek(); t.text {
+	case "<", "<=", ">", ">=", "==", "!=":
+
+		p.next()
+
+		right, err := p.parseSum()
+		if err != nil {
+
+			return node{}, err
+
+		}
+
+		if left.isBool || right.isBool {
+
+			return node{}, fmt.Errorf("operands of '%s' at position %d must be values", t.text, t.pos+1)
+
+		}
+
+		return node{op: t.text, isBool: true, operands: []node{left, right}, source: p.text(start)}, nil
+
+	}
+
+	return left, nil
+
+}
+
+/* sum := product ( ( "+" | "-" ) product )* */
+func (p *parser) parseSum() (node, error) {
+
+	return p.parseBinary(p.parseProduct, "+", "-")
+
+}
+
+/* product := unary ( ( "*" | "/" ) unary )* */
+func (p *parser) parseProduct() (node, error) {
+
+	return p.parseBinary(p.parseUnary, "*", "/")
+
+}
+
+/* Parse a left associative chain of arithmetic operators */
+func (p *parser) parseBinary(operand func() (node, error), operators ...string) (node, error) {
+
+	start := p.pos
+
+	left, err := operand()
+	if err != nil {
+
+		return node{}, err
+
+	}
+
+	for {
+
+		t := p.peek()
+
+		matched := false
+		for _, operator := range operators {
+			if t.kind == "op" && t.text == operator {
+				matched = true
+			}
+		}
+
+		if !matched {
+
+			return left, nil
+
+		}
+
+		p.next()
+
+		right, err := operand()
+		if err != nil {
+
+			return node{}, err
+
+		}
+
+		if left.isBool || right.isBool {
+
+			return node{}, fmt.Errorf("operands of '%s' at position %d must be values", t.text, t.pos+1)
+
+		}
+
+		left = node{op: t.text, operands: []node{left, right}, source: p.text(start)}
+
+	}
+
+}
+
+/* unary := "-" unary | primary */
+func (p *parser) parseUnary() (node, error) {
+
+	start := p.pos
+
+	if p.peek().text == "-" {
+
+		t := p.next()
+
+		operand, err := p.parseUnary()
+		if err != nil {
+
+			return node{}, err
+
+		}
+
+		if operand.isBool {
+
+			return node{}, fmt.Errorf("operand of '-' at position %d must be a value", t.pos+1)
+
+		}
+
+		return node{op: "neg", operands: []node{operand}, source: p.text(start)}, nil
+
+	}
+
+	return p.parsePrimary()
+
+}
+
+/* primary := number | variable | "true" | "false" | "(" or ")" */
+func (p *parser) parsePrimary() (node, error) {
+
+	t := p.next()
+
+	switch {
+	case t.kind == "num":
+
+		return node{op: "num", value: t.value, source: t.text}, nil
+
+	case t.kind == "ident" && (t.text == "true" || t.text == "false"):
+
+		return node{op: "num", value: boolToFloat(t.text == "true"), isBool: true, source: t.text}, nil
+
+	case t.kind == "ident":
+
+		if !isVariable(t.text) {
+
+			return node{}, fmt.Errorf("unknown variable '%s' at position %d", t.text, t.pos+1)
+
+		}
+
+		if p.buy && strings.HasPrefix(t.text, orderPrefix) {
+
+			return node{}, fmt.Errorf("variable '%s' at position %d is only available in sell_rules", t.text, t.pos+1)
+
+		}
+
+		return node{op: "var", name: t.text, source: t.text}, nil
+
+	case t.text == "(":
+
+		inner, err := p.parseOr()
+		if err != nil {
+
+			return node{}, err
+
+		}
+
+		if closing := p.next(); closing.text != ")" {
+
+			return node{}, fmt.Errorf("missing ')' at position %d", closing.pos+1)
+
+		}
+
+		/* Keep the parenthesis in the source text reported for blocked decisions */
+		inner.source = "(" + inner.source + ")"
+
+		return inner, nil
+
+	}
+
+	return node{}, fmt.Errorf("unexpected '%s' at position %d", t.text, t.pos+1)
+
+}
+
+/* Test if name is a known rule variable */
+func isVariable(name string) bool {
+
+	for _, variable := range Variables {
+
+		if variable == name {
+
+			return true
+
+		}
+
+	}
+
+	return false
+
+}
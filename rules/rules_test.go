@@ -0,0 +1,188 @@
+package rules
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	type args struct {
+		source string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantNil bool
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			args:    args{source: "rsi7 < 35 && macd > -20 && price < high24h*0.995"},
+			wantNil: false,
+			wantErr: false,
+		},
+		{
+			name:    "empty",
+			args:    args{source: "  "},
+			wantNil: true,
+			wantErr: false,
+		},
+		{
+			name:    "unknown variable",
+			args:    args{source: "rsi8 < 35"},
+			wantNil: true,
+			wantErr: true,
+		},
+		{
+			name:    "not a condition",
+			args:    args{source: "rsi7 + 35"},
+			wantNil: true,
+			wantErr: true,
+		},
+		{
+			name:    "missing parenthesis",
+			args:    args{source: "(rsi7 < 35 || rsi3 < 20"},
+			wantNil: true,
+			wantErr: true,
+		},
+		{
+			name:    "invalid character",
+			args:    args{source: "rsi7 < 35 & macd > 0"},
+			wantNil: true,
+			wantErr: true,
+		},
+		{
+			name:    "logical operator on values",
+			args:    args{source: "rsi7 && macd"},
+			wantNil: true,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.args.source)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if (got == nil) != tt.wantNil {
+				t.Errorf("Parse() = %v, wantNil %v", got, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestExpression_Eval(t *testing.T) {
+	variables := map[string]float64{
+		"price":   100,
+		"rsi7":    30,
+		"rsi3":    80,
+		"macd":    -10,
+		"high24h": 110,
+	}
+	type args struct {
+		source string
+	}
+	tests := []struct {
+		name        string
+		args        args
+		want        bool
+		wantBlocked string
+		wantErr     bool
+	}{
+		{
+			name:        "all terms true",
+			args:        args{source: "rsi7 < 35 && macd > -20 && price < high24h*0.995"},
+			want:        true,
+			wantBlocked: "",
+			wantErr:     false,
+		},
+		{
+			name:        "second term blocks",
+			args:        args{source: "rsi7 < 35 && macd > 0 && price < high24h"},
+			want:        false,
+			wantBlocked: "macd > 0",
+			wantErr:     false,
+		},
+		{
+			name:        "or with parenthesis blocks",
+			args:        args{source: "price > 0 && (rsi3 < 20 || rsi7 < 10)"},
+			want:        false,
+			wantBlocked: "(rsi3 < 20 || rsi7 < 10)",
+			wantErr:     false,
+		},
+		{
+			name:        "not and arithmetic precedence",
+			args:        args{source: "!(price - 10 * 2 > 90)"},
+			want:        true,
+			wantBlocked: "",
+			wantErr:     false,
+		},
+		{
+			name:        "division by zero",
+			args:        args{source: "price / (rsi7 - 30) > 1"},
+			want:        false,
+			wantBlocked: "price / (rsi7 - 30) > 1",
+			wantErr:     true,
+		},
+		{
+			name:        "variable not available",
+			args:        args{source: "order_price > 0"},
+			want:        false,
+			wantBlocked: "order_price > 0",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Parse(tt.args.source)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got, blocked, err := e.Eval(variables)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expression.Eval() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Expression.Eval() got = %v, want %v", got, tt.want)
+			}
+			if blocked != tt.wantBlocked {
+				t.Errorf("Expression.Eval() blocked = %v, want %v", blocked, tt.wantBlocked)
+			}
+		})
+	}
+}
+
+func TestParseBuy(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr bool
+	}{
+		{
+			name:   "market variables",
+			source: "rsi7 < 35 && price < high24h*0.995",
+		},
+		{
+			name:    "order variable",
+			source:  "rsi7 < 35 && order_profit > 0.02",
+			wantErr: true,
+		},
+		{
+			name:    "order variable in parenthesis",
+			source:  "(order_age > 60)",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseBuy(tt.source); (err != nil) != tt.wantErr {
+				t.Errorf("ParseBuy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			/* Order variables are accepted in sell rules */
+			if _, err := Parse(tt.source); err != nil {
+				t.Errorf("Parse() error = %v", err)
+			}
+		})
+	}
+}
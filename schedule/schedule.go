@@ -0,0 +1,515 @@
+package schedule
+
+/* A schedule sets when a thread trades. It is written in the configuration templates as
+entries separated by semicolons, i.e.
+
+	tz Europe/London; mon-fri 08:00-16:30; mon-fri 16:30-18:00 sellonly; blackout 2021-12-24..2021-12-26
+
+"tz" sets the IANA time zone of the schedule (UTC by default). A window lists the days of
+the week (mon..sun, ranges and lists, or * for every day), a start and stop time (HH:MM,
+24:00 for midnight, a stop before the start spans midnight) and a behavior: trade (full
+trading, the default), sellonly (no new BUY) or paused (no orders). "blackout" sets dates or
+date ranges (YYYY-MM-DD) when the thread is paused, or a given behavior, i.e. around known
+market events. Blackouts come first, then windows in the order they are written, and the
+thread is paused outside all windows. */
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	_ "time/tzdata" /* IANA time zones on hosts without a time zone database */
+)
+
+/* Behaviors of a schedule window */
+const (
+	ModeTrade    = "trade"    /* Full trading */
+	ModeSellOnly = "sellonly" /* No new BUY, Thread transactions are sold at target */
+	ModePaused   = "paused"   /* No orders */
+)
+
+/* Next transitions are searched up to this many days ahead */
+const horizon = 8
+
+var days = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+var modes = map[string]string{ModeTrade: "Trade", ModeSellOnly: "Sell-only", ModePaused: "Paused"}
+
+// Schedule hold a parsed schedule
+type Schedule struct {
+	Source    string         /* Original schedule text */
+	Location  *time.Location /* Time zone of the schedule */
+	windows   []window
+	blackouts []blackout
+}
+
+/* window is a trading window. Times are minutes from midnight, stop is not included. */
+type window struct {
+	days   [7]bool /* Days of the week the window starts on, Sunday first */
+	start  int
+	stop   int
+	mode   string
+	source string
+}
+
+/* blackout is a range of dates, both included */
+type blackout struct {
+	from   string /* YYYY-MM-DD */
+	to     string /* YYYY-MM-DD */
+	mode   string
+	source string
+}
+
+// Status define the schedule behavior at a time
+type Status struct {
+	Mode     string    /* trade, sellonly or paused */
+	Reason   string    /* Entry setting the behavior, i.e. mon-fri 08:00-16:30 */
+	Next     time.Time /* Time of the next transition in the schedule time zone, zero when none in the next 8 days */
+	NextMode string    /* Behavior after the next transition */
+}
+
+// Parse parse a schedule. It returns a nil Schedule for an empty source.
+func Parse(source string) (*Schedule, error) {
+
+	if strings.TrimSpace(source) == "" {
+
+		return nil, nil
+
+	}
+
+	s := &Schedule{Source: source, Location: time.UTC}
+
+	for _, entry := range strings.Split(source, ";") {
+
+		fields := strings.Fields(strings.ToLower(entry))
+		if len(fields) == 0 {
+
+			continue
+
+		}
+
+		var err error
+
+		switch fields[0] {
+		case "tz":
+
+			if len(fields) != 2 {
+
+				return nil, fmt.Errorf("%q: tz takes a time zone, i.e. tz Europe/London", strings.TrimSpace(entry))
+
+			}
+
+			/* Time zone names are case sensitive */
+			if s.Location, err = time.LoadLocation(strings.Fields(entry)[1]); err != nil {
+
+				return nil, fmt.Errorf("%q: unknown time zone", strings.TrimSpace(entry))
+
+			}
+
+		case "blackout":
+
+			var b blackout
+			if b, err = parseBlackout(fields); err != nil {
+
+				return nil, fmt.Errorf("%q: %w", strings.TrimSpace(entry), err)
+
+			}
+
+			s.blackouts = append(s.blackouts, b)
+
+		default:
+
+			var w window
+			if w, err = parseWindow(fields); err != nil {
+
+				return nil, fmt.Errorf("%q: %w", strings.TrimSpace(entry), err)
+
+			}
+
+			s.windows = append(s.windows, w)
+
+		}
+
+	}
+
+	if len(s.windows) == 0 && len(s.blackouts) == 0 {
+
+		return nil, errors.New("no windows or blackouts")
+
+	}
+
+	return s, nil
+
+}
+
+// Legacy return the schedule of time_enforce: one daily window from start to stop in local time, written as
+// in time.Kitchen, i.e. 04:00AM
+func Legacy(start string, stop string) (*Schedule, error) {
+
+	from, err := time.Parse(time.Kitchen, start)
+	if err != nil {
+
+		return nil, fmt.Errorf("time_start %q: must be as 04:00AM", start)
+
+	}
+
+	to, err := time.Parse(time.Kitchen, stop)
+	if err != nil {
+
+		return nil, fmt.Errorf("time_stop %q: must be as 07:00PM", stop)
+
+	}
+
+	if start == stop {
+
+		return nil, errors.New("time_start and time_stop must differ")
+
+	}
+
+	w := window{
+		start:  from.Hour()*60 + from.Minute(),
+		stop:   to.Hour()*60 + to.Minute(),
+		mode:   ModeTrade,
+		source: "time_start " + start + " - time_stop " + stop,
+	}
+
+	for i := range w.days {
+
+		w.days[i] = true
+
+	}
+
+	return &Schedule{Source: w.source, Location: time.Local, windows: []window{w}}, nil
+
+}
+
+// Mode return the behavior at t and the entry setting it. A nil Schedule always trades.
+func (s *Schedule) Mode(t time.Time) (mode string, reason string) {
+
+	if s == nil {
+
+		return ModeTrade, ""
+
+	}
+
+	t = t.In(s.Location)
+	date := t.Format("2006-01-02")
+
+	for _, b := range s.blackouts {
+
+		if date >= b.from && date <= b.to {
+
+			return b.mode, b.source
+
+		}
+
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	day := int(t.Weekday())
+	yesterday := (day + 6) % 7
+
+	for _, w := range s.windows {
+
+		if w.start < w.stop {
+
+			if w.days[day] && minute >= w.start && minute < w.stop {
+
+				return w.mode, w.source
+
+			}
+
+			continue
+
+		}
+
+		/* The window spans midnight */
+		if (w.days[day] && minute >= w.start) || (w.days[yesterday] && minute < w.stop) {
+
+			return w.mode, w.source
+
+		}
+
+	}
+
+	return ModePaused, "outside trading windows"
+
+}
+
+// At return the behavior at t with the next transition
+func (s *Schedule) At(t time.Time) Status {
+
+	status := Status{}
+	status.Mode, status.Reason = s.Mode(t)
+
+	if s == nil {
+
+		return status
+
+	}
+
+	for _, next := range s.boundaries(t) {
+
+		if mode, reason := s.Mode(next); mode != status.Mode || reason != status.Reason {
+
+			status.Next, status.NextMode = next, mode
+
+			break
+
+		}
+
+	}
+
+	return status
+
+}
+
+// String return the status as text, i.e. "Sell-only (mon-fri 16:30-18:00) until Mon 18:00 BST, then Paused"
+func (status Status) String() string {
+
+	text := modes[status.Mode]
+	if status.Reason != "" {
+
+		text += " (" + status.Reason + ")"
+
+	}
+
+	if !status.Next.IsZero() {
+
+		text += " until " + status.Next.Format("Mon 2006-01-02 15:04 MST") + ", then " + modes[status.NextMode]
+
+	}
+
+	return text
+
+}
+
+// Return the times after t when the behavior can change, in order: window starts and stops and midnights in
+// the schedule time zone over the next 8 days
+func (s *Schedule) boundaries(t time.Time) (times []time.Time) {
+
+	t = t.In(s.Location)
+
+	for d := -1; d <= horizon; d++ {
+
+		year, month, day := t.AddDate(0, 0, d).Date()
+		minutes := []int{0}
+
+		for _, w := range s.windows {
+
+			minutes = append(minutes, w.start, w.stop)
+
+		}
+
+		for _, minute := range minutes {
+
+			if b := time.Date(year, month, day, minute/60, minute%60, 0, 0, s.Location); b.After(t) {
+
+				times = append(times, b)
+
+			}
+
+		}
+
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	return times
+
+}
+
+/* Parse a window: days, HH:MM-HH:MM and an optional behavior */
+func parseWindow(fields []string) (w window, err error) {
+
+	if len(fields) < 2 || len(fields) > 3 {
+
+		return w, errors.New("a window is days HH:MM-HH:MM [trade|sellonly|paused]")
+
+	}
+
+	if w.days, err = parseDays(fields[0]); err != nil {
+
+		return w, err
+
+	}
+
+	times := strings.Split(fields[1], "-")
+	if len(times) != 2 {
+
+		return w, errors.New("times must be HH:MM-HH:MM")
+
+	}
+
+	if w.start, err = parseTime(times[0]); err != nil {
+
+		return w, err
+
+	}
+
+	if w.stop, err = parseTime(times[1]); err != nil {
+
+		return w, err
+
+	}
+
+	if w.start == w.stop || w.start == 24*60 {
+
+		return w, errors.New("empty window")
+
+	}
+
+	if w.mode, err = parseMode(fields[2:], ModeTrade); err != nil {
+
+		return w, err
+
+	}
+
+	w.source = strings.Join(fields, " ")
+
+	return w, nil
+
+}
+
+/* Parse a blackout: blackout YYYY-MM-DD[..YYYY-MM-DD] and an optional behavior */
+func parseBlackout(fields []string) (b blackout, err error) {
+
+	if len(fields) < 2 || len(fields) > 3 {
+
+		return b, errors.New("a blackout is blackout YYYY-MM-DD[..YYYY-MM-DD] [trade|sellonly|paused]")
+
+	}
+
+	dates := strings.SplitN(fields[1], "..", 2)
+	b.from, b.to = dates[0], dates[len(dates)-1]
+
+	for _, date := range dates {
+
+		if _, err = time.Parse("2006-01-02", date); err != nil {
+
+			return b, fmt.Errorf("date %q must be YYYY-MM-DD", date)
+
+		}
+
+	}
+
+	if b.to < b.from {
+
+		return b, errors.New("blackout ends before it starts")
+
+	}
+
+	if b.mode, err = parseMode(fields[2:], ModePaused); err != nil {
+
+		return b, err
+
+	}
+
+	b.source = strings.Join(fields, " ")
+
+	return b, nil
+
+}
+
+/* Parse days of the week as *, a day, a range (mon-fri, fri-mon) or a list of both (mon,wed,fri-sun) */
+func parseDays(text string) (set [7]bool, err error) {
+
+	if text == "*" || text == "daily" {
+
+		for i := range set {
+
+			set[i] = true
+
+		}
+
+		return set, nil
+
+	}
+
+	for _, item := range strings.Split(text, ",") {
+
+		bounds := strings.Split(item, "-")
+		if len(bounds) > 2 {
+
+			return set, fmt.Errorf("days %q must be as mon-fri", item)
+
+		}
+
+		from, to := dayIndex(bounds[0]), dayIndex(bounds[len(bounds)-1])
+		if from < 0 || to < 0 {
+
+			return set, fmt.Errorf("unknown day in %q, days are %s or *", item, strings.Join(days, ","))
+
+		}
+
+		for d := from; ; d = (d + 1) % 7 {
+
+			set[d] = true
+
+			if d == to {
+
+				break
+
+			}
+
+		}
+
+	}
+
+	return set, nil
+
+}
+
+/* Return the index of a day of the week, Sunday first, or -1 */
+func dayIndex(name string) int {
+
+	for i, day := range days {
+
+		if name == day {
+
+			return i
+
+		}
+
+	}
+
+	return -1
+
+}
+
+/* Parse HH:MM as minutes from midnight, up to 24:00 */
+func parseTime(text string) (int, error) {
+
+	var hour, minute int
+
+	if n, err := fmt.Sscanf(text, "%d:%d", &hour, &minute); err != nil || n != 2 || len(text) != 5 ||
+		hour < 0 || minute < 0 || minute > 59 || hour*60+minute > 24*60 {
+
+		return 0, fmt.Errorf("time %q must be HH:MM", text)
+
+	}
+
+	return hour*60 + minute, nil
+
+}
+
+/* Parse an optional behavior */
+func parseMode(fields []string, mode string) (string, error) {
+
+	if len(fields) == 0 {
+
+		return mode, nil
+
+	}
+
+	if _, ok := modes[fields[0]]; !ok {
+
+		return "", fmt.Errorf("behavior %q must be trade, sellonly or paused", fields[0])
+
+	}
+
+	return fields[0], nil
+
+}
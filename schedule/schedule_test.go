@@ -0,0 +1,157 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		source  string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "empty", source: " ", wantNil: true},
+		{name: "window", source: "mon-fri 08:00-16:30"},
+		{name: "full", source: "tz America/New_York; mon,wed,fri-sun 22:00-02:00 sellonly; * 00:00-24:00 paused; blackout 2021-12-24..2021-12-26 sellonly"},
+		{name: "time zone only", source: "tz UTC", wantErr: true},
+		{name: "unknown time zone", source: "tz Mars/Olympus; * 08:00-16:00", wantErr: true},
+		{name: "unknown day", source: "mon-fry 08:00-16:00", wantErr: true},
+		{name: "bad time", source: "mon 8:00-16:00", wantErr: true},
+		{name: "time out of range", source: "mon 08:00-24:30", wantErr: true},
+		{name: "empty window", source: "mon 08:00-08:00", wantErr: true},
+		{name: "unknown behavior", source: "mon 08:00-16:00 buyonly", wantErr: true},
+		{name: "bad date", source: "blackout 2021-13-01", wantErr: true},
+		{name: "blackout backwards", source: "blackout 2021-12-26..2021-12-24", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.source)
+		if (err != nil) != tt.wantErr || (got == nil) != (tt.wantNil || tt.wantErr) {
+			t.Errorf("%s: Parse() = %v, %v, wantErr %v", tt.name, got, err, tt.wantErr)
+		}
+	}
+
+}
+
+func TestSchedule_Mode(t *testing.T) {
+
+	s, err := Parse("tz Europe/London; blackout 2021-10-05; mon-fri 12:00-13:00 paused; mon-fri 08:00-16:30; mon-fri 16:30-18:00 sellonly; fri 22:00-02:00 sellonly")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	london, _ := time.LoadLocation("Europe/London")
+
+	tests := []struct {
+		name       string
+		time       time.Time
+		wantMode   string
+		wantReason string
+	}{
+		{name: "window in UTC", time: time.Date(2021, 10, 1, 7, 30, 0, 0, time.UTC), wantMode: ModeTrade, wantReason: "mon-fri 08:00-16:30"},
+		{name: "before window", time: time.Date(2021, 10, 1, 7, 59, 0, 0, london), wantMode: ModePaused, wantReason: "outside trading windows"},
+		{name: "first window wins", time: time.Date(2021, 10, 1, 12, 30, 0, 0, london), wantMode: ModePaused, wantReason: "mon-fri 12:00-13:00 paused"},
+		{name: "stop not included", time: time.Date(2021, 10, 1, 16, 30, 0, 0, london), wantMode: ModeSellOnly, wantReason: "mon-fri 16:30-18:00 sellonly"},
+		{name: "across midnight", time: time.Date(2021, 10, 2, 1, 0, 0, 0, london), wantMode: ModeSellOnly, wantReason: "fri 22:00-02:00 sellonly"},
+		{name: "weekend", time: time.Date(2021, 10, 2, 10, 0, 0, 0, london), wantMode: ModePaused, wantReason: "outside trading windows"},
+		{name: "blackout", time: time.Date(2021, 10, 5, 10, 0, 0, 0, london), wantMode: ModePaused, wantReason: "blackout 2021-10-05"},
+	}
+
+	for _, tt := range tests {
+		if mode, reason := s.Mode(tt.time); mode != tt.wantMode || reason != tt.wantReason {
+			t.Errorf("%s: Mode() = %v, %q, want %v, %q", tt.name, mode, reason, tt.wantMode, tt.wantReason)
+		}
+	}
+
+	if mode, reason := (*Schedule)(nil).Mode(time.Now()); mode != ModeTrade || reason != "" {
+		t.Errorf("Mode() without schedule = %v, %q, want %v", mode, reason, ModeTrade)
+	}
+
+}
+
+func TestSchedule_At(t *testing.T) {
+
+	s, err := Parse("tz Europe/London; mon-fri 08:00-16:30; mon-fri 16:30-18:00 sellonly")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	london, _ := time.LoadLocation("Europe/London")
+
+	tests := []struct {
+		name     string
+		time     time.Time
+		wantNext time.Time
+		wantMode string
+		want     string
+	}{
+		{
+			name:     "to sell-only",
+			time:     time.Date(2021, 10, 1, 9, 0, 0, 0, london),
+			wantNext: time.Date(2021, 10, 1, 16, 30, 0, 0, london),
+			wantMode: ModeSellOnly,
+			want:     "Trade (mon-fri 08:00-16:30) until Fri 2021-10-01 16:30 BST, then Sell-only",
+		},
+		{
+			name:     "over the weekend",
+			time:     time.Date(2021, 10, 1, 18, 0, 0, 0, london),
+			wantNext: time.Date(2021, 10, 4, 8, 0, 0, 0, london),
+			wantMode: ModeTrade,
+			want:     "Paused (outside trading windows) until Mon 2021-10-04 08:00 BST, then Trade",
+		},
+		{
+			name:     "daylight saving time ends",
+			time:     time.Date(2021, 10, 29, 18, 0, 0, 0, london),
+			wantNext: time.Date(2021, 11, 1, 8, 0, 0, 0, london),
+			wantMode: ModeTrade,
+			want:     "Paused (outside trading windows) until Mon 2021-11-01 08:00 GMT, then Trade",
+		},
+	}
+
+	for _, tt := range tests {
+		got := s.At(tt.time)
+		if !got.Next.Equal(tt.wantNext) || got.NextMode != tt.wantMode || got.String() != tt.want {
+			t.Errorf("%s: At() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if got := (*Schedule)(nil).At(time.Now()); got.String() != "Trade" || !got.Next.IsZero() {
+		t.Errorf("At() without schedule = %q, want Trade", got)
+	}
+
+}
+
+func TestLegacy(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		start   string
+		stop    string
+		time    time.Time
+		want    string
+		wantErr bool
+	}{
+		{name: "inside", start: "04:00AM", stop: "07:00PM", time: time.Date(2021, 10, 1, 12, 0, 0, 0, time.Local), want: ModeTrade},
+		{name: "outside", start: "04:00AM", stop: "07:00PM", time: time.Date(2021, 10, 1, 20, 0, 0, 0, time.Local), want: ModePaused},
+		{name: "overnight", start: "10:00PM", stop: "02:00AM", time: time.Date(2021, 10, 1, 1, 0, 0, 0, time.Local), want: ModeTrade},
+		{name: "bad time", start: "4AM", stop: "07:00PM", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		s, err := Legacy(tt.start, tt.stop)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Legacy() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+
+		if err == nil {
+			if mode, _ := s.Mode(tt.time); mode != tt.want {
+				t.Errorf("%s: Mode() = %v, want %v", tt.name, mode, tt.want)
+			}
+		}
+	}
+
+}
@@ -0,0 +1,42 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+)
+
+// RunTaskAtInterval run funcToRun every interval after startDelay until ctx is cancelled. A run in progress
+// when ctx is cancelled completes.
+func RunTaskAtInterval(ctx context.Context, funcToRun func(), interval time.Duration, startDelay time.Duration) {
+
+	go func() {
+
+		select {
+		case <-ctx.Done():
+
+			return
+
+		case <-time.After(startDelay):
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+
+			select {
+			case <-ctx.Done():
+
+				return
+
+			case <-ticker.C:
+
+				funcToRun()
+
+			}
+
+		}
+
+	}()
+
+}
@@ -0,0 +1,42 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunTaskAtInterval(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		startDelay time.Duration
+		cancel     time.Duration
+		wantMin    int32
+		wantMax    int32
+	}{
+		{name: "runs every interval", startDelay: 0, cancel: 55 * time.Millisecond, wantMin: 1, wantMax: 5},
+		{name: "cancelled before start delay", startDelay: time.Hour, cancel: 20 * time.Millisecond, wantMin: 0, wantMax: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var runs int32
+
+			ctx, cancel := context.WithCancel(context.Background())
+			RunTaskAtInterval(ctx, func() { atomic.AddInt32(&runs, 1) }, 10*time.Millisecond, tt.startDelay)
+
+			time.Sleep(tt.cancel)
+			cancel()
+			got := atomic.LoadInt32(&runs)
+
+			/* No run after cancel */
+			time.Sleep(30 * time.Millisecond)
+			if after := atomic.LoadInt32(&runs); got < tt.wantMin || got > tt.wantMax || after > got+1 {
+				t.Errorf("RunTaskAtInterval() runs = %v (%v after cancel), want %v to %v", got, after, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+
+}
@@ -0,0 +1,1673 @@
+package sqlite
+
+/* This package implements the SQLite storage backend for single node installs. The database
+is a single file created on first use, so no database service is required. Every method runs
+the SQLite equivalent of the cryptopump MySQL stored procedure of the same name. */
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/aleibovici/cryptopump/functions"
+	"github.com/aleibovici/cryptopump/logger"
+	"github.com/aleibovici/cryptopump/types"
+
+	_ "github.com/mattn/go-sqlite3" // This blank entry is required to enable sqlite connectivity
+)
+
+/* Default database file when storage_path is not set */
+const defaultPath = "cryptopump.db"
+
+/* Realized profit of FILLED BUY orders sold by FILLED SELL orders (SELL OrderIDSource is the BUY OrderID) */
+const profitJoin = `FROM orders b INNER JOIN orders s ON b.OrderID = s.OrderIDSource
+	WHERE b.Side = 'BUY' AND s.Side = 'SELL' AND b.Status = 'FILLED' AND s.Status = 'FILLED'`
+
+/* Realized profit, profit ratio and count of profit ratios of round trips in orders and in orders_daily */
+const profitRows = `SELECT b.ThreadID AS ThreadID, s.CummulativeQuoteQty - b.CummulativeQuoteQty AS Profit,
+	(s.CummulativeQuoteQty - b.CummulativeQuoteQty) / NULLIF(s.CummulativeQuoteQty, 0) AS ProfitPct,
+	CASE WHEN s.CummulativeQuoteQty <> 0 THEN 1 ELSE 0 END AS Trades ` + profitJoin + `
+	UNION ALL SELECT ThreadID, Profit, ProfitPct, Trades FROM orders_daily`
+
+/* Orders columns, shared by orders and orders_archive */
+const orderColumns = `ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime, ThreadID, ThreadIDSession`
+
+// Storage SQLite storage backend
+type Storage struct{}
+
+var _ types.Storage = Storage{} /* Storage must implement types.Storage */
+
+// DBInit open the SQLite database file. Tables are created by the migrations package.
+// Multiple threads on the same node share the file.
+func DBInit(path string) (db *sql.DB, err error) {
+
+	if path == "" {
+
+		path = defaultPath
+
+	}
+
+	/* Conditional defer logging when the database can't be opened */
+	defer func() {
+		if err != nil {
+			logger.LogEntry{ /* Log Entry */
+				Config:   nil,
+				Market:   nil,
+				Session:  nil,
+				Order:    &types.Order{},
+				Message:  functions.GetFunctionName() + " - " + err.Error(),
+				LogLevel: "DebugLevel",
+			}.Do()
+		}
+	}()
+
+	/* WAL journal and busy timeout allow concurrent access from several threads */
+	return sql.Open("sqlite3", "file:"+path+"?_busy_timeout=10000&_journal_mode=WAL")
+
+}
+
+// SaveOrder Save order to database
+func (Storage) SaveOrder(
+	ctx context.Context,
+	sessionData *types.Session,
+	order *types.Order,
+	orderIDSource int64, /* OrderIDSource */
+	orderPrice float64 /* OrderPrice */) error {
+
+	return orderTx{ctx: ctx, sessionData: sessionData, db: sessionData.Db}.SaveOrder(order, orderIDSource, orderPrice)
+
+}
+
+// UpdateOrder Update order
+func (Storage) UpdateOrder(
+	ctx context.Context,
+	sessionData *types.Session,
+	OrderID int64,
+	CumulativeQuoteQuantity float64,
+	ExecutedQuantity float64,
+	Price float64,
+	Status string) error {
+
+	return orderTx{ctx: ctx, sessionData: sessionData, db: sessionData.Db}.UpdateOrder(OrderID, CumulativeQuoteQuantity, ExecutedQuantity, Price, Status)
+
+}
+
+// GetOrderByOrderID Return order by OrderID (uses ThreadID as filter)
+func (Storage) GetOrderByOrderID(
+	ctx context.Context,
+	sessionData *types.Session) (order types.Order, err error) {
+
+	err = queryRow(ctx, sessionData,
+		`SELECT OrderID, Price, ExecutedQuantity, CummulativeQuoteQty, TransactTime FROM orders WHERE OrderID = ? AND ThreadID = ? LIMIT 1`,
+		[]interface{}{sessionData.ForceSellOrderID, sessionData.ThreadID},
+		&order.OrderID,
+		&order.Price,
+		&order.ExecutedQuantity,
+		&order.CumulativeQuoteQuantity,
+		&order.TransactTime)
+
+	return order, err
+
+}
+
+// GetOrderSymbol Get symbol for ThreadID
+func (Storage) GetOrderSymbol(
+	ctx context.Context,
+	sessionData *types.Session) (symbol string, err error) {
+
+	err = queryRow(ctx, sessionData,
+		`SELECT Symbol FROM orders WHERE ThreadID = ? ORDER BY TransactTime DESC LIMIT 1`,
+		[]interface{}{sessionData.ThreadID},
+		&symbol)
+
+	return symbol, err
+
+}
+
+// GetOrderTransactionPending Get 1 order with pending FILLED status
+func (Storage) GetOrderTransactionPending(
+	ctx context.Context,
+	sessionData *types.Session) (order types.Order, err error) {
+
+	err = queryRow(ctx, sessionData,
+		`SELECT OrderID, Symbol FROM orders
+		WHERE ThreadID = ? AND Status IS NOT NULL AND Status NOT IN ('FILLED', 'CANCELED', '')
+		ORDER BY TransactTime ASC LIMIT 1`,
+		[]interface{}{sessionData.ThreadID},
+		&order.OrderID,
+		&order.Symbol)
+
+	return order, err
+
+}
+
+// GetOrderTransactionCount Retrieve FILLED transaction count by Side for the last 60 minutes
+func (Storage) GetOrderTransactionCount(
+	ctx context.Context,
+	sessionData *types.Session,
+	side string) (count float64, err error) {
+
+	err = queryRow(ctx, sessionData,
+		`SELECT COUNT(*) FROM orders
+		WHERE ThreadID = ? AND Side = ? AND Status = 'FILLED'
+		AND TransactTime >= (CAST(strftime('%s', 'now') AS INTEGER) / 60 + ?) * 60000
+		AND TransactTime < (CAST(strftime('%s', 'now') AS INTEGER) / 60 + 1) * 60000`,
+		[]interface{}{sessionData.ThreadID, side, (60 * -1)},
+		&count)
+
+	return count, err
+
+}
+
+// GetOrderTransactionSideLastTwo Get Side for the last two transactions for the ThreadID
+func (Storage) GetOrderTransactionSideLastTwo(
+	ctx context.Context,
+	sessionData *types.Session) (side1 string, side2 string, err error) {
+
+	var rows *sql.Rows
+	var sides []string
+
+	if rows, err = query(ctx, sessionData,
+		`SELECT Side FROM orders WHERE ThreadID = ? AND (Status <> 'CANCELED' OR Status IS NULL) ORDER BY TransactTime DESC LIMIT 2`,
+		sessionData.ThreadID); err != nil {
+
+		return "", "", err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		var side string
+		if err = rows.Scan(&side); err != nil {
+
+			return "", "", err
+
+		}
+
+		sides = append(sides, side)
+
+	}
+
+	/* Both sides are returned only when there are two transactions */
+	if len(sides) == 2 {
+
+		return sides[0], sides[1], rows.Err()
+
+	}
+
+	return "", "", rows.Err()
+
+}
+
+// GetLastOrderTransactionPrice Get price for last transaction the ThreadID
+func (Storage) GetLastOrderTransactionPrice(
+	ctx context.Context,
+	sessionData *types.Session,
+	Side string) (price float64, err error) {
+
+	err = queryRow(ctx, sessionData,
+		`SELECT Price FROM orders WHERE ThreadID = ? AND Side = ? AND (Status <> 'CANCELED' OR Status IS NULL) ORDER BY TransactTime DESC LIMIT 1`,
+		[]interface{}{sessionData.ThreadID, Side},
+		&price)
+
+	return price, err
+
+}
+
+// GetOrderTransactionExecuted Get BUY and SELL orders, including archived orders, with executed quantity for
+// all threads created before the TransactTime before (0 for all orders), oldest first
+func (Storage) GetOrderTransactionExecuted(
+	ctx context.Context,
+	sessionData *types.Session,
+	before int64) (orders []types.Order, err error) {
+
+	var rows *sql.Rows
+
+	if rows, err = query(ctx, sessionData,
+		`SELECT ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime
+		FROM (SELECT `+orderColumns+` FROM orders UNION ALL SELECT `+orderColumns+` FROM orders_archive)
+		WHERE ExecutedQuantity > 0 AND (? = 0 OR TransactTime < ?)
+		ORDER BY TransactTime ASC, OrderID ASC`,
+		before, before); err != nil {
+
+		return nil, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		order := types.Order{}
+		if err = rows.Scan(
+			&order.ClientOrderID,
+			&order.CumulativeQuoteQuantity,
+			&order.ExecutedQuantity,
+			&order.OrderID,
+			&order.OrderIDSource,
+			&order.Price,
+			&order.Side,
+			&order.Status,
+			&order.Symbol,
+			&order.TransactTime); err != nil {
+
+			return nil, err
+
+		}
+
+		orders = append(orders, order)
+
+	}
+
+	return orders, rows.Err()
+
+}
+
+// GetOrders Get the orders matching filter, most recent first
+func (Storage) GetOrders(
+	ctx context.Context,
+	sessionData *types.Session,
+	filter types.OrderFilter) (orders []types.Order, err error) {
+
+	var rows *sql.Rows
+
+	if filter.Limit <= 0 { /* No limit */
+
+		filter.Limit = -1
+
+	}
+
+	if rows, err = query(ctx, sessionData,
+		`SELECT ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime
+		FROM orders
+		WHERE (? = '' OR ThreadID = ?) AND (? = '' OR Side = ?) AND (? = '' OR Status = ?)
+		ORDER BY TransactTime DESC, OrderID DESC
+		LIMIT ?`,
+		filter.ThreadID, filter.ThreadID,
+		filter.Side, filter.Side,
+		filter.Status, filter.Status,
+		filter.Limit); err != nil {
+
+		return nil, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		order := types.Order{}
+		if err = rows.Scan(
+			&order.ClientOrderID,
+			&order.CumulativeQuoteQuantity,
+			&order.ExecutedQuantity,
+			&order.OrderID,
+			&order.OrderIDSource,
+			&order.Price,
+			&order.Side,
+			&order.Status,
+			&order.Symbol,
+			&order.TransactTime); err != nil {
+
+			return nil, err
+
+		}
+
+		orders = append(orders, order)
+
+	}
+
+	return orders, rows.Err()
+
+}
+
+// ArchiveOrders Move closed orders executed before the TransactTime before to orders_archive, adding the profit of
+// archived round trips to orders_daily. Orders of open Thread transactions and the last BUY and SELL of each thread
+// are kept. Return the number of archived orders.
+func (Storage) ArchiveOrders(
+	ctx context.Context,
+	sessionData *types.Session,
+	before int64) (archived int64, err error) {
+
+	var tx *sql.Tx
+	var result sql.Result
+
+	/* Conditional defer logging when the archival fails */
+	defer func() {
+		if err != nil {
+			logger.LogEntry{ /* Log Entry */
+				Config:   nil,
+				Market:   nil,
+				Session:  sessionData,
+				Order:    &types.Order{},
+				Message:  functions.GetFunctionName() + " - " + err.Error(),
+				LogLevel: "DebugLevel",
+			}.Do()
+		}
+	}()
+
+	if tx, err = sessionData.Db.BeginTx(ctx, nil); err != nil {
+
+		return 0, err
+
+	}
+
+	defer tx.Rollback() /* No-op after Commit */
+
+	/* Round trips (BUY and the SELL that sold it) to archive */
+	for _, statement := range []string{
+		`CREATE TEMP TABLE IF NOT EXISTS archive_pairs (BuyOrderID INTEGER NOT NULL, SellOrderID INTEGER NOT NULL PRIMARY KEY)`,
+		`DELETE FROM archive_pairs`,
+	} {
+
+		if _, err = tx.ExecContext(ctx, statement); err != nil {
+
+			return 0, err
+
+		}
+
+	}
+
+	if _, err = tx.ExecContext(ctx,
+		`INSERT INTO archive_pairs (BuyOrderID, SellOrderID)
+		SELECT b.OrderID, s.OrderID `+profitJoin+` AND s.TransactTime < ?
+		AND NOT EXISTS (SELECT 1 FROM thread t WHERE t.OrderID = b.OrderID)
+		AND b.OrderID <> (SELECT o.OrderID FROM orders o WHERE o.ThreadID = b.ThreadID AND o.Side = 'BUY' AND o.Status <> 'CANCELED' ORDER BY o.TransactTime DESC LIMIT 1)
+		AND s.OrderID <> (SELECT o.OrderID FROM orders o WHERE o.ThreadID = s.ThreadID AND o.Side = 'SELL' AND o.Status <> 'CANCELED' ORDER BY o.TransactTime DESC LIMIT 1)`,
+		before); err != nil {
+
+		return 0, err
+
+	}
+
+	/* Daily profit aggregates, added to the aggregates of previous archivals */
+	if _, err = tx.ExecContext(ctx,
+		`INSERT INTO orders_daily (Day, ThreadID, Symbol, Trades, BuyQuote, SellQuote, Profit, ProfitPct)
+		SELECT (s.TransactTime / 86400000) * 86400000, b.ThreadID, b.Symbol,
+		SUM(CASE WHEN s.CummulativeQuoteQty <> 0 THEN 1 ELSE 0 END), SUM(b.CummulativeQuoteQty), SUM(s.CummulativeQuoteQty),
+		SUM(s.CummulativeQuoteQty - b.CummulativeQuoteQty), COALESCE(SUM((s.CummulativeQuoteQty - b.CummulativeQuoteQty) / NULLIF(s.CummulativeQuoteQty, 0)), 0)
+		FROM archive_pairs p INNER JOIN orders b ON b.OrderID = p.BuyOrderID INNER JOIN orders s ON s.OrderID = p.SellOrderID
+		WHERE true
+		GROUP BY 1, 2, 3
+		ON CONFLICT (Day, ThreadID, Symbol) DO UPDATE SET
+		Trades = Trades + excluded.Trades, BuyQuote = BuyQuote + excluded.BuyQuote, SellQuote = SellQuote + excluded.SellQuote,
+		Profit = Profit + excluded.Profit, ProfitPct = ProfitPct + excluded.ProfitPct`); err != nil {
+
+		return 0, err
+
+	}
+
+	/* Archived round trips and CANCELED orders */
+	condition := `OrderID IN (SELECT BuyOrderID FROM archive_pairs UNION ALL SELECT SellOrderID FROM archive_pairs)
+		OR (Status = 'CANCELED' AND TransactTime < ?)`
+
+	if _, err = tx.ExecContext(ctx, `INSERT OR REPLACE INTO orders_archive (`+orderColumns+`) SELECT `+orderColumns+` FROM orders WHERE `+condition, before); err != nil {
+
+		return 0, err
+
+	}
+
+	if result, err = tx.ExecContext(ctx, `DELETE FROM orders WHERE `+condition, before); err != nil {
+
+		return 0, err
+
+	}
+
+	if archived, err = result.RowsAffected(); err != nil {
+
+		return 0, err
+
+	}
+
+	if _, err = tx.ExecContext(ctx, `DROP TABLE archive_pairs`); err != nil {
+
+		return 0, err
+
+	}
+
+	return archived, tx.Commit()
+
+}
+
+// GetLastOrderTransactionSide Get Side for last transaction the ThreadID
+func (Storage) GetLastOrderTransactionSide(
+	ctx context.Context,
+	sessionData *types.Session) (side string, err error) {
+
+	err = queryRow(ctx, sessionData,
+		`SELECT Side FROM orders WHERE ThreadID = ? AND Status = 'FILLED' ORDER BY TransactTime DESC LIMIT 1`,
+		[]interface{}{sessionData.ThreadID},
+		&side)
+
+	return side, err
+
+}
+
+// SaveThreadTransaction Save Thread cycle to database
+func (Storage) SaveThreadTransaction(
+	ctx context.Context,
+	sessionData *types.Session,
+	OrderID int64,
+	CumulativeQuoteQuantity float64,
+	Price float64,
+	ExecutedQuantity float64) error {
+
+	return orderTx{ctx: ctx, sessionData: sessionData, db: sessionData.Db}.SaveThreadTransaction(OrderID, CumulativeQuoteQuantity, Price, ExecutedQuantity)
+
+}
+
+// DeleteThreadTransactionByOrderID Delete Thread transaction by OrderID
+func (Storage) DeleteThreadTransactionByOrderID(
+	ctx context.Context,
+	sessionData *types.Session,
+	orderID int) error {
+
+	return orderTx{ctx: ctx, sessionData: sessionData, db: sessionData.Db}.DeleteThreadTransactionByOrderID(orderID)
+
+}
+
+// GetThreadTransactionMissing Get FILLED BUY orders with no thread transaction and no SELL order
+func (Storage) GetThreadTransactionMissing(
+	ctx context.Context,
+	sessionData *types.Session) (orders []types.Order, err error) {
+
+	var rows *sql.Rows
+
+	if rows, err = query(ctx, sessionData,
+		`SELECT b.OrderID, b.CummulativeQuoteQty, b.Price, b.ExecutedQuantity FROM orders b
+		WHERE b.ThreadID = ? AND b.Side = 'BUY' AND b.Status IN ('FILLED', 'PARTIALLY_FILLED')
+		AND NOT EXISTS (SELECT 1 FROM thread t WHERE t.OrderID = b.OrderID)
+		AND NOT EXISTS (SELECT 1 FROM orders s WHERE s.OrderIDSource = b.OrderID AND s.Side = 'SELL' AND s.Status <> 'CANCELED')
+		ORDER BY b.TransactTime ASC`,
+		sessionData.ThreadID); err != nil {
+
+		return nil, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		order := types.Order{}
+		if err = rows.Scan(&order.OrderID, &order.CumulativeQuoteQuantity, &order.Price, &order.ExecutedQuantity); err != nil {
+
+			return nil, err
+
+		}
+
+		orders = append(orders, order)
+
+	}
+
+	return orders, rows.Err()
+
+}
+
+// GetThreadTransactionSold Get FILLED SELL orders (OrderIDSource) whose BUY thread transaction was not deleted
+func (Storage) GetThreadTransactionSold(
+	ctx context.Context,
+	sessionData *types.Session) (orders []types.Order, err error) {
+
+	var rows *sql.Rows
+
+	if rows, err = query(ctx, sessionData,
+		`SELECT s.OrderID, s.OrderIDSource FROM thread t INNER JOIN orders s ON s.OrderIDSource = t.OrderID
+		WHERE t.ThreadID = ? AND s.Side = 'SELL' AND s.Status = 'FILLED'`,
+		sessionData.ThreadID); err != nil {
+
+		return nil, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		order := types.Order{}
+		if err = rows.Scan(&order.OrderID, &order.OrderIDSource); err != nil {
+
+			return nil, err
+
+		}
+
+		orders = append(orders, order)
+
+	}
+
+	return orders, rows.Err()
+
+}
+
+// GetTrades Get closed trades from the trades ledger, most recent first
+func (Storage) GetTrades(
+	ctx context.Context,
+	sessionData *types.Session,
+	filter types.TradeFilter) (trades []types.Trade, err error) {
+
+	var rows *sql.Rows
+
+	if filter.Limit <= 0 { /* No limit */
+
+		filter.Limit = -1
+
+	}
+
+	if rows, err = query(ctx, sessionData,
+		`SELECT ThreadID, ThreadIDSession, Symbol, BuyOrderID, SellOrderID, Quantity, EntryPrice, ExitPrice, EntryQuote, ExitQuote, Fees, Profit, ProfitPct, EntryTime, ExitTime, HoldingTime, ExitReason
+		FROM trades
+		WHERE (? = '' OR ThreadID = ?) AND (? = 0 OR ExitTime >= ?) AND (? = 0 OR ExitTime < ?) AND (? = '' OR ExitReason = ?)
+		ORDER BY ExitTime DESC LIMIT ?`,
+		filter.ThreadID, filter.ThreadID,
+		filter.From, filter.From,
+		filter.To, filter.To,
+		filter.ExitReason, filter.ExitReason,
+		filter.Limit); err != nil {
+
+		return nil, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		trade := types.Trade{}
+		if err = rows.Scan(
+			&trade.ThreadID,
+			&trade.ThreadIDSession,
+			&trade.Symbol,
+			&trade.BuyOrderID,
+			&trade.SellOrderID,
+			&trade.Quantity,
+			&trade.EntryPrice,
+			&trade.ExitPrice,
+			&trade.EntryQuote,
+			&trade.ExitQuote,
+			&trade.Fees,
+			&trade.Profit,
+			&trade.ProfitPct,
+			&trade.EntryTime,
+			&trade.ExitTime,
+			&trade.HoldingTime,
+			&trade.ExitReason); err != nil {
+
+			return nil, err
+
+		}
+
+		trades = append(trades, trade)
+
+	}
+
+	return trades, rows.Err()
+
+}
+
+// GetTradeStats Get trades ledger totals for threadID, or all threads when threadID is empty
+func (Storage) GetTradeStats(
+	ctx context.Context,
+	sessionData *types.Session,
+	threadID string) (stats types.TradeStats, err error) {
+
+	err = queryRow(ctx, sessionData,
+		`SELECT COUNT(*),
+		COALESCE(SUM(CASE WHEN Profit > 0 THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN Profit > 0 THEN Profit ELSE 0 END), 0),
+		COALESCE(-SUM(CASE WHEN Profit <= 0 THEN Profit ELSE 0 END), 0),
+		COALESCE(AVG(HoldingTime), 0)
+		FROM trades WHERE (? = '' OR ThreadID = ?)`,
+		[]interface{}{threadID, threadID},
+		&stats.Count,
+		&stats.Wins,
+		&stats.GrossProfit,
+		&stats.GrossLoss,
+		&stats.HoldingTime)
+
+	return stats, err
+
+}
+
+// SaveEquity Save the daily equity snapshot, replacing the snapshot already saved for the same day
+func (Storage) SaveEquity(
+	ctx context.Context,
+	sessionData *types.Session,
+	equity types.Equity) error {
+
+	return exec(ctx, sessionData, sessionData.Db, &types.Order{},
+		`INSERT INTO equity (ThreadID, Date, Realized, Unrealized, Equity) VALUES (?,?,?,?,?)
+		ON CONFLICT (ThreadID, Date) DO UPDATE SET Realized = excluded.Realized, Unrealized = excluded.Unrealized, Equity = excluded.Equity`,
+		equity.ThreadID,
+		equity.Date,
+		equity.Realized,
+		equity.Unrealized,
+		equity.Equity)
+
+}
+
+// GetEquity Get daily equity snapshots for threadID, or across all threads when threadID is empty, oldest first
+func (Storage) GetEquity(
+	ctx context.Context,
+	sessionData *types.Session,
+	threadID string) (equity []types.Equity, err error) {
+
+	var rows *sql.Rows
+
+	if rows, err = query(ctx, sessionData,
+		`SELECT ThreadID, Date, Realized, Unrealized, Equity FROM equity WHERE ThreadID = ? ORDER BY Date ASC`,
+		threadID); err != nil {
+
+		return nil, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		snapshot := types.Equity{}
+		if err = rows.Scan(&snapshot.ThreadID, &snapshot.Date, &snapshot.Realized, &snapshot.Unrealized, &snapshot.Equity); err != nil {
+
+			return nil, err
+
+		}
+
+		equity = append(equity, snapshot)
+
+	}
+
+	return equity, rows.Err()
+
+}
+
+// AcquireLease take lease name for holder when it is free or expired, or renew it when holder already holds it,
+// for duration milliseconds. SQLite is a single node database, so the clock is the local one.
+func (Storage) AcquireLease(
+	ctx context.Context,
+	sessionData *types.Session,
+	name string,
+	holder string,
+	duration int64) (lease types.Lease, err error) {
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+
+	/* SET expressions read the columns before the update */
+	if err = exec(ctx, sessionData, sessionData.Db, &types.Order{},
+		`INSERT INTO leases (Name, Holder, Token, Expires) VALUES (?,?,1,?)
+		ON CONFLICT (Name) DO UPDATE SET
+		Token = CASE WHEN Expires < ? THEN Token + 1 ELSE Token END,
+		Holder = CASE WHEN Expires < ? THEN excluded.Holder ELSE Holder END,
+		Expires = CASE WHEN Expires < ? OR Holder = excluded.Holder THEN excluded.Expires ELSE Expires END`,
+		name,
+		holder,
+		now+duration,
+		now,
+		now,
+		now); err != nil {
+
+		return types.Lease{}, err
+
+	}
+
+	err = queryRow(ctx, sessionData,
+		`SELECT Name, Holder, Token, Expires FROM leases WHERE Name = ?`,
+		[]interface{}{name},
+		&lease.Name, &lease.Holder, &lease.Token, &lease.Expires)
+
+	return lease, err
+
+}
+
+// ReleaseLease expire lease name when it is held by holder, so another node takes it over at its next heartbeat
+func (Storage) ReleaseLease(
+	ctx context.Context,
+	sessionData *types.Session,
+	name string,
+	holder string) error {
+
+	return exec(ctx, sessionData, sessionData.Db, &types.Order{},
+		`UPDATE leases SET Expires = 0 WHERE Name = ? AND Holder = ?`,
+		name,
+		holder)
+
+}
+
+// SaveNode Save node in the cluster node registry with a new heartbeat
+func (Storage) SaveNode(
+	ctx context.Context,
+	sessionData *types.Session,
+	node types.Node) error {
+
+	return exec(ctx, sessionData, sessionData.Db, &types.Order{},
+		`INSERT INTO nodes (ThreadID, Hostname, Port, Version, Symbol, Started, Heartbeat) VALUES (?,?,?,?,?,?,?)
+		ON CONFLICT (ThreadID) DO UPDATE SET Hostname = excluded.Hostname, Port = excluded.Port, Version = excluded.Version,
+		Symbol = excluded.Symbol, Started = excluded.Started, Heartbeat = excluded.Heartbeat`,
+		node.ThreadID,
+		node.Hostname,
+		node.Port,
+		node.Version,
+		node.Symbol,
+		node.Started,
+		time.Now().UnixNano()/int64(time.Millisecond))
+
+}
+
+// GetNodes Get the nodes of the cluster node registry, most recently started first
+func (Storage) GetNodes(
+	ctx context.Context,
+	sessionData *types.Session) (nodes []types.Node, err error) {
+
+	var rows *sql.Rows
+
+	if rows, err = query(ctx, sessionData,
+		`SELECT ThreadID, Hostname, Port, Version, Symbol, Started, Heartbeat, ? - Heartbeat FROM nodes ORDER BY Started DESC`,
+		time.Now().UnixNano()/int64(time.Millisecond)); err != nil {
+
+		return nil, err
+
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+
+		node := types.Node{}
+		if err = rows.Scan(&node.ThreadID, &node.Hostname, &node.Port, &node.Version, &node.Symbol, &node.Started, &node.Heartbeat, &node.Age); err != nil {
+
+			return nil, err
+
+		}
+
+		nodes = append(nodes, node)
+
+	}
+
+	return nodes, rows.Err()
+
+}
+
+// DeleteNode Delete node from the cluster node registry
+func (Storage) DeleteNode(
+	ctx context.Context,
+	sessionData *types.Session,
+	threadID string) error {
+
+	return exec(ctx, sessionData, sessionData.Db, &types.Order{},
+		`DELETE FROM nodes WHERE ThreadID = ?`,
+		threadID)
+
+}
+
+// GetThreadOrphans Get the threads with Thread transactions whose node registry heartbeat is older than age
+// milliseconds, longest down first
+func (Storage) GetThreadOrphans(
+	ctx context.Context,
+	sessionData *types.Session,
+	age int64) (orphans []types.Orphan, err error) {
+
+	var rows *sql.Rows
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+
+	if rows, err = query(ctx, sessionData,
+		`SELECT t.ThreadID, MAX(t.ThreadIDSession), n.Hostname, n.Port, ? - n.Heartbeat
+		FROM thread t INNER JOIN nodes n ON n.ThreadID = t.ThreadID
+		WHERE n.Heartbeat < ?
+		GROUP BY t.ThreadID, n.Hostname, n.Port, n.Heartbeat
+		ORDER BY n.Heartbeat ASC`,
+		now,
+		now-age); err != nil {
+
+		return nil, err
+
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+
+		orphan := types.Orphan{}
+		if err = rows.Scan(&orphan.ThreadID, &orphan.ThreadIDSession, &orphan.Hostname, &orphan.Port, &orphan.Age); err != nil {
+
+			return nil, err
+
+		}
+
+		orphans = append(orphans, orphan)
+
+	}
+
+	return orphans, rows.Err()
+
+}
+
+// SaveAudit Save audit log record
+func (Storage) SaveAudit(
+	ctx context.Context,
+	sessionData *types.Session,
+	audit types.Audit) error {
+
+	return exec(ctx, sessionData, sessionData.Db, &types.Order{},
+		`INSERT INTO audit (Time, ThreadID, Node, Action, Detail) VALUES (?,?,?,?,?)`,
+		audit.Time,
+		audit.ThreadID,
+		audit.Node,
+		audit.Action,
+		audit.Detail)
+
+}
+
+// GetAudit Get the audit log records saved after since milliseconds, oldest first
+func (Storage) GetAudit(
+	ctx context.Context,
+	sessionData *types.Session,
+	since int64) (audit []types.Audit, err error) {
+
+	var rows *sql.Rows
+
+	if rows, err = query(ctx, sessionData,
+		`SELECT ID, Time, ThreadID, Node, Action, Detail FROM audit WHERE Time > ? ORDER BY Time ASC, ID ASC`,
+		since); err != nil {
+
+		return nil, err
+
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+
+		record := types.Audit{}
+		if err = rows.Scan(&record.ID, &record.Time, &record.ThreadID, &record.Node, &record.Action, &record.Detail); err != nil {
+
+			return nil, err
+
+		}
+
+		audit = append(audit, record)
+
+	}
+
+	return audit, rows.Err()
+
+}
+
+// SavePause Save the trading pause of a thread
+func (Storage) SavePause(
+	ctx context.Context,
+	sessionData *types.Session,
+	pause types.Pause) error {
+
+	return exec(ctx, sessionData, sessionData.Db, &types.Order{},
+		`INSERT INTO pauses (ThreadID, Mode, PausedBy, Time, Confirm) VALUES (?,?,?,?,?)
+		ON CONFLICT (ThreadID) DO UPDATE SET
+			Mode = excluded.Mode,
+			PausedBy = excluded.PausedBy,
+			Time = excluded.Time,
+			Confirm = excluded.Confirm`,
+		pause.ThreadID,
+		pause.Mode,
+		pause.By,
+		pause.Time,
+		pause.Confirm)
+
+}
+
+// GetPause Get the trading pause of a thread, an empty Pause when the thread is not paused
+func (Storage) GetPause(
+	ctx context.Context,
+	sessionData *types.Session,
+	threadID string) (pause types.Pause, err error) {
+
+	err = queryRow(ctx, sessionData,
+		`SELECT ThreadID, Mode, PausedBy, Time, Confirm FROM pauses WHERE ThreadID = ?`,
+		[]interface{}{threadID},
+		&pause.ThreadID, &pause.Mode, &pause.By, &pause.Time, &pause.Confirm)
+
+	return pause, err
+
+}
+
+// DeletePause Delete the trading pause of a thread
+func (Storage) DeletePause(
+	ctx context.Context,
+	sessionData *types.Session,
+	threadID string) error {
+
+	return exec(ctx, sessionData, sessionData.Db, &types.Order{},
+		`DELETE FROM pauses WHERE ThreadID = ?`,
+		threadID)
+
+}
+
+// GetThreadTransactionCount Get Thread count
+func (Storage) GetThreadTransactionCount(
+	ctx context.Context,
+	sessionData *types.Session) (count int, err error) {
+
+	err = queryRow(ctx, sessionData,
+		`SELECT COUNT(*) FROM thread WHERE ThreadID = ?`,
+		[]interface{}{sessionData.ThreadID},
+		&count)
+
+	return count, err
+
+}
+
+// GetThreadTransactionDistinct Get Thread Distinct
+func (Storage) GetThreadTransactionDistinct(
+	ctx context.Context,
+	sessionData *types.Session) (threadID string, threadIDSession string, err error) {
+
+	var rows *sql.Rows
+
+	if rows, err = query(ctx, sessionData,
+		`SELECT DISTINCT ThreadID, ThreadIDSession FROM thread`); err != nil {
+
+		return "", "", err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		err = rows.Scan(
+			&threadID,
+			&threadIDSession)
+
+		/* Verify if lock file for thread exist. If lock file doesn't exist leave function with empty thread */
+		if _, err := os.Stat(threadID + ".lock"); err != nil {
+
+			break
+
+		} else {
+
+			threadID = ""
+			threadIDSession = ""
+
+		}
+
+	}
+
+	return threadID, threadIDSession, err
+
+}
+
+// GetThreadTransactionByPrice retrieve lowest price order below market price from Thread database
+func (Storage) GetThreadTransactionByPrice(
+	ctx context.Context,
+	marketData *types.Market,
+	sessionData *types.Session) (order types.Order, err error) {
+
+	return threadTransaction(ctx, sessionData,
+		`AND thread.Price < ? ORDER BY thread.Price ASC LIMIT 1`,
+		sessionData.ThreadID,
+		marketData.Price)
+
+}
+
+// GetThreadTransactionByPriceHigher function returns the highest Thread order above market price.
+// It is used for STOPLOSS Loss as ratio that should trigger a sale
+func (Storage) GetThreadTransactionByPriceHigher(
+	ctx context.Context,
+	marketData *types.Market,
+	sessionData *types.Session) (order types.Order, err error) {
+
+	return threadTransaction(ctx, sessionData,
+		`AND thread.Price > ? ORDER BY thread.Price DESC LIMIT 1`,
+		sessionData.ThreadID,
+		marketData.Price)
+
+}
+
+// GetThreadLastTransaction function returns the lowest price BUY transaction for a Thread
+func (Storage) GetThreadLastTransaction(
+	ctx context.Context,
+	sessionData *types.Session) (order types.Order, err error) {
+
+	return threadTransaction(ctx, sessionData,
+		`ORDER BY thread.Price ASC LIMIT 1`,
+		sessionData.ThreadID)
+
+}
+
+// GetThreadTransactionByThreadID Retrieve Thread transactions for the ThreadID
+func (Storage) GetThreadTransactionByThreadID(
+	ctx context.Context,
+	sessionData *types.Session) (orders []types.Order, err error) {
+
+	var rows *sql.Rows
+
+	if rows, err = query(ctx, sessionData,
+		`SELECT OrderID, CummulativeQuoteQty, Price, ExecutedQuantity FROM thread WHERE ThreadID = ? ORDER BY Price ASC`,
+		sessionData.ThreadID); err != nil {
+
+		return nil, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		order := types.Order{}
+		if err = rows.Scan(&order.OrderID, &order.CumulativeQuoteQuantity, &order.Price, &order.ExecutedQuantity); err != nil {
+
+			return orders, err
+
+		}
+
+		order.CumulativeQuoteQuantity = math.Round(order.CumulativeQuoteQuantity*100) / 100
+		order.Price = math.Round(order.Price*1000) / 1000
+		orders = append(orders, order)
+
+	}
+
+	return orders, rows.Err()
+
+}
+
+// GetThreadTransactionAll Retrieve Thread transactions for the ThreadID joined with the order transaction time,
+// lowest price first
+func (Storage) GetThreadTransactionAll(
+	ctx context.Context,
+	sessionData *types.Session) (orders []types.Order, err error) {
+
+	var rows *sql.Rows
+
+	if rows, err = query(ctx, sessionData,
+		`SELECT thread.CummulativeQuoteQty, thread.OrderID, thread.Price, thread.ExecutedQuantity, COALESCE(orders.TransactTime, 0)
+		FROM thread LEFT JOIN orders ON thread.OrderID = orders.OrderID
+		WHERE thread.ThreadID = ? ORDER BY thread.Price ASC`,
+		sessionData.ThreadID); err != nil {
+
+		return nil, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		order := types.Order{}
+		if err = rows.Scan(&order.CumulativeQuoteQuantity, &order.OrderID, &order.Price, &order.ExecutedQuantity, &order.TransactTime); err != nil {
+
+			return orders, err
+
+		}
+
+		orders = append(orders, order)
+
+	}
+
+	return orders, rows.Err()
+
+}
+
+// GetThreadTransactiontUpmarketPriceCount Count Thread transactions below price
+func (Storage) GetThreadTransactiontUpmarketPriceCount(
+	ctx context.Context,
+	sessionData *types.Session,
+	price float64) (count int, err error) {
+
+	err = queryRow(ctx, sessionData,
+		`SELECT COUNT(*) FROM thread WHERE Price < ? AND ThreadID = ?`,
+		[]interface{}{price, sessionData.ThreadID},
+		&count)
+
+	return count, err
+
+}
+
+// GetThreadCount Retrieve Running Thread Count
+func (Storage) GetThreadCount(
+	ctx context.Context,
+	sessionData *types.Session) (count int, err error) {
+
+	err = queryRow(ctx, sessionData,
+		`SELECT COUNT(DISTINCT ThreadID) FROM session`,
+		nil,
+		&count)
+
+	return count, err
+
+}
+
+// GetThreadAmount Retrieve Thread Dollar Amount
+func (Storage) GetThreadAmount(
+	ctx context.Context,
+	sessionData *types.Session) (amount float64, err error) {
+
+	var amountNullFloat64 sql.NullFloat64 /* handle null sqlite returns */
+
+	err = queryRow(ctx, sessionData,
+		`SELECT SUM(CummulativeQuoteQty) FROM thread`,
+		nil,
+		&amountNullFloat64)
+
+	return math.Round(amountNullFloat64.Float64*100) / 100, err
+
+}
+
+// SaveSession Save new session to Session table.
+func (Storage) SaveSession(
+	ctx context.Context,
+	configData *types.Config,
+	sessionData *types.Session) error {
+
+	return exec(ctx, sessionData, sessionData.Db, &types.Order{},
+		`INSERT INTO session (ThreadID, ThreadIDSession, Exchange, FiatSymbol, FiatFunds, DiffTotal, Status) VALUES (?,?,?,?,?,?,?)`,
+		sessionData.ThreadID,
+		sessionData.ThreadIDSession,
+		configData.ExchangeName,
+		sessionData.SymbolFiat,
+		sessionData.SymbolFiatFunds,
+		sessionData.DiffTotal,
+		sessionData.Status)
+
+}
+
+// UpdateSession Update existing session on Session table
+func (Storage) UpdateSession(
+	ctx context.Context,
+	configData *types.Config,
+	sessionData *types.Session) error {
+
+	return exec(ctx, sessionData, sessionData.Db, &types.Order{},
+		`UPDATE session SET FiatFunds = ?, DiffTotal = ?, Status = ? WHERE ThreadID = ?`,
+		sessionData.SymbolFiatFunds,
+		sessionData.DiffTotal,
+		sessionData.Status,
+		sessionData.ThreadID)
+
+}
+
+// DeleteSession Delete session from Session table
+func (Storage) DeleteSession(
+	ctx context.Context,
+	sessionData *types.Session) error {
+
+	return exec(ctx, sessionData, sessionData.Db, &types.Order{},
+		`DELETE FROM session WHERE ThreadID = ?`,
+		sessionData.ThreadID)
+
+}
+
+// GetSessionStatus check for system error status
+func (Storage) GetSessionStatus(
+	ctx context.Context,
+	sessionData *types.Session) (threadID string, err error) {
+
+	err = queryRow(ctx, sessionData,
+		`SELECT ThreadID FROM session WHERE Status = 1 LIMIT 1`,
+		nil,
+		&threadID)
+
+	return threadID, err
+
+}
+
+// GetSessions Get the threads of the session table with their open Thread transactions
+func (Storage) GetSessions(
+	ctx context.Context,
+	sessionData *types.Session) (sessions []types.SessionRecord, err error) {
+
+	var rows *sql.Rows
+
+	if rows, err = query(ctx, sessionData,
+		`SELECT s.ThreadID, s.ThreadIDSession, s.Exchange, s.FiatSymbol, s.FiatFunds, s.DiffTotal, s.Status,
+		(SELECT COUNT(*) FROM thread t WHERE t.ThreadID = s.ThreadID)
+		FROM session s ORDER BY s.ID ASC`); err != nil {
+
+		return nil, err
+
+	}
+
+	defer rows.Close() /* Close rows */
+
+	for rows.Next() {
+
+		session := types.SessionRecord{}
+		if err = rows.Scan(
+			&session.ThreadID,
+			&session.ThreadIDSession,
+			&session.Exchange,
+			&session.SymbolFiat,
+			&session.SymbolFiatFunds,
+			&session.DiffTotal,
+			&session.Status,
+			&session.ThreadCount); err != nil {
+
+			return nil, err
+
+		}
+
+		sessions = append(sessions, session)
+
+	}
+
+	return sessions, rows.Err()
+
+}
+
+// SaveGlobal Save initial global settings
+func (Storage) SaveGlobal(
+	ctx context.Context,
+	sessionData *types.Session) error {
+
+	return exec(ctx, sessionData, sessionData.Db, &types.Order{},
+		`INSERT INTO global (Profit, ProfitNet, ProfitPct, TransactTime) VALUES (?,?,?,strftime('%s', 'now'))`,
+		sessionData.Global.Profit,
+		sessionData.Global.ProfitNet,
+		sessionData.Global.ProfitPct)
+
+}
+
+// UpdateGlobal Update global settings
+func (Storage) UpdateGlobal(
+	ctx context.Context,
+	sessionData *types.Session) error {
+
+	return exec(ctx, sessionData, sessionData.Db, &types.Order{},
+		`UPDATE global SET Profit = ?, ProfitNet = ?, ProfitPct = ?, TransactTime = strftime('%s', 'now') WHERE ID = 1`,
+		sessionData.Global.Profit,
+		sessionData.Global.ProfitNet,
+		sessionData.Global.ProfitPct)
+
+}
+
+// GetGlobal get global data
+func (Storage) GetGlobal(
+	ctx context.Context,
+	sessionData *types.Session) (profit float64, profitNet float64, profitPct float64, transactTime int64, err error) {
+
+	err = queryRow(ctx, sessionData,
+		`SELECT Profit, ProfitNet, ProfitPct, TransactTime FROM global WHERE ID = 1 LIMIT 1`,
+		nil,
+		&profit,
+		&profitNet,
+		&profitPct,
+		&transactTime)
+
+	return profit, profitNet, profitPct, transactTime, err
+
+}
+
+// GetProfit retrieve total, net and average percentage profit
+func (Storage) GetProfit(
+	ctx context.Context,
+	sessionData *types.Session) (profit float64, profitNet float64, percentage float64, err error) {
+
+	var profitNullFloat64 sql.NullFloat64     /* handle null sqlite returns */
+	var profitNetNullFloat64 sql.NullFloat64  /* handle null sqlite returns */
+	var percentageNullFloat64 sql.NullFloat64 /* handle null sqlite returns */
+
+	err = queryRow(ctx, sessionData,
+		`SELECT SUM(Profit), SUM(Profit) + (SELECT SUM(DiffTotal) FROM session), SUM(ProfitPct) / NULLIF(SUM(Trades), 0)
+		FROM (`+profitRows+`)`,
+		nil,
+		&profitNullFloat64,
+		&profitNetNullFloat64,
+		&percentageNullFloat64)
+
+	return profitNullFloat64.Float64, profitNetNullFloat64.Float64, (percentageNullFloat64.Float64 * 100), err
+
+}
+
+// GetProfitByThreadID retrieve total and average percentage profit by ThreadID
+func (Storage) GetProfitByThreadID(
+	ctx context.Context,
+	sessionData *types.Session) (fiat float64, percentage float64, err error) {
+
+	var fiatNullFloat64 sql.NullFloat64       /* handle null sqlite returns */
+	var percentageNullFloat64 sql.NullFloat64 /* handle null sqlite returns */
+
+	err = queryRow(ctx, sessionData,
+		`SELECT SUM(Profit) + (SELECT SUM(DiffTotal) FROM session WHERE ThreadID = ?), SUM(ProfitPct) / NULLIF(SUM(Trades), 0)
+		FROM (`+profitRows+`) WHERE ThreadID = ?`,
+		[]interface{}{sessionData.ThreadID, sessionData.ThreadID},
+		&fiatNullFloat64,
+		&percentageNullFloat64)
+
+	return fiatNullFloat64.Float64, (percentageNullFloat64.Float64 * 100), err
+
+}
+
+// GetProfitByThreadIDSince retrieve realized profit by ThreadID for sales executed since transactTime (milliseconds).
+// Archived sales are older than one day and are not included.
+func (Storage) GetProfitByThreadIDSince(
+	ctx context.Context,
+	sessionData *types.Session,
+	transactTime int64) (profit float64, err error) {
+
+	var profitNullFloat64 sql.NullFloat64 /* handle null sqlite returns */
+
+	err = queryRow(ctx, sessionData,
+		`SELECT SUM(s.CummulativeQuoteQty - b.CummulativeQuoteQty) `+profitJoin+` AND b.ThreadID = ? AND s.TransactTime >= ?`,
+		[]interface{}{sessionData.ThreadID, transactTime},
+		&profitNullFloat64)
+
+	return profitNullFloat64.Float64, err
+
+}
+
+/* Return the Thread transaction matching condition, joined with the order transaction time */
+func threadTransaction(
+	ctx context.Context,
+	sessionData *types.Session,
+	condition string,
+	args ...interface{}) (order types.Order, err error) {
+
+	err = queryRow(ctx, sessionData,
+		`SELECT thread.CummulativeQuoteQty, thread.OrderID, thread.Price, thread.ExecutedQuantity, COALESCE(orders.TransactTime, 0)
+		FROM thread LEFT JOIN orders ON thread.OrderID = orders.OrderID
+		WHERE thread.ThreadID = ? `+condition,
+		args,
+		&order.CumulativeQuoteQuantity,
+		&order.OrderID,
+		&order.Price,
+		&order.ExecutedQuantity,
+		&order.TransactTime)
+
+	return order, err
+
+}
+
+/* Database or transaction executing statements */
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+/* Execute a statement that doesn't return rows */
+func exec(
+	ctx context.Context,
+	sessionData *types.Session,
+	db execer,
+	order *types.Order,
+	statement string,
+	args ...interface{}) (err error) {
+
+	if _, err = db.ExecContext(ctx, statement, args...); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    order,
+			Message:  caller() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+	}
+
+	return err
+
+}
+
+/* Execute a query that returns rows */
+func query(
+	ctx context.Context,
+	sessionData *types.Session,
+	statement string,
+	args ...interface{}) (rows *sql.Rows, err error) {
+
+	if rows, err = sessionData.Db.QueryContext(ctx, statement, args...); err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  caller() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+	}
+
+	return rows, err
+
+}
+
+/* Execute a query that returns at most one row and scan it into dest. No rows leaves dest unchanged. */
+func queryRow(
+	ctx context.Context,
+	sessionData *types.Session,
+	statement string,
+	args []interface{},
+	dest ...interface{}) (err error) {
+
+	if err = sessionData.Db.QueryRowContext(ctx, statement, args...).Scan(dest...); err == sql.ErrNoRows {
+
+		return nil
+
+	} else if err != nil {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  caller() + " - " + err.Error(),
+			LogLevel: "DebugLevel",
+		}.Do()
+
+	}
+
+	return err
+
+}
+
+/* Return the name of the Storage method that called the query helper */
+func caller() string {
+
+	pc := make([]uintptr, 15)
+	n := runtime.Callers(3, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	frame, _ := frames.Next()
+
+	/* Skip threadTransaction helper */
+	if frame.Function == "github.com/aleibovici/cryptopump/sqlite.threadTransaction" {
+
+		frame, _ = frames.Next()
+
+	}
+
+	return frame.Function
+
+}
+
+/* Order writes on the database or on a transaction */
+type orderTx struct {
+	ctx         context.Context
+	sessionData *types.Session
+	db          execer
+}
+
+// OrderTx run f in one database transaction committed when f returns nil and rolled back otherwise
+func (Storage) OrderTx(
+	ctx context.Context,
+	sessionData *types.Session,
+	f func(tx types.OrderTx) error) (err error) {
+
+	var tx *sql.Tx
+
+	/* Conditional defer logging when the transaction fails */
+	defer func() {
+		if err != nil {
+			logger.LogEntry{ /* Log Entry */
+				Config:   nil,
+				Market:   nil,
+				Session:  sessionData,
+				Order:    &types.Order{},
+				Message:  functions.GetFunctionName() + " - " + err.Error(),
+				LogLevel: "DebugLevel",
+			}.Do()
+		}
+	}()
+
+	if tx, err = sessionData.Db.BeginTx(ctx, nil); err != nil {
+
+		return err
+
+	}
+
+	if err = fence(ctx, sessionData, tx); err != nil {
+
+		_ = tx.Rollback()
+
+		return err
+
+	}
+
+	if err = f(orderTx{ctx: ctx, sessionData: sessionData, db: tx}); err != nil {
+
+		_ = tx.Rollback()
+
+		return err
+
+	}
+
+	return tx.Commit()
+
+}
+
+// Return ErrFenced when the thread lease held by the session changed hands. Updating the lease row takes the
+// database write lock, so the lease can't change hands before the transaction ends.
+func fence(
+	ctx context.Context,
+	sessionData *types.Session,
+	tx *sql.Tx) error {
+
+	if sessionData.ThreadLease.Token == 0 {
+
+		return nil
+
+	}
+
+	result, err := tx.ExecContext(ctx, `UPDATE leases SET Token = Token WHERE Name = ? AND Holder = ? AND Token = ?`,
+		sessionData.ThreadLease.Name,
+		sessionData.ThreadLease.Holder,
+		sessionData.ThreadLease.Token)
+
+	if err != nil {
+
+		return err
+
+	}
+
+	if rows, err := result.RowsAffected(); err != nil {
+
+		return err
+
+	} else if rows == 0 {
+
+		return types.ErrFenced
+
+	}
+
+	return nil
+
+}
+
+// SaveOrder Save order to database
+func (o orderTx) SaveOrder(
+	order *types.Order,
+	orderIDSource int64, /* OrderIDSource */
+	orderPrice float64 /* OrderPrice */) error {
+
+	return exec(o.ctx, o.sessionData, o.db, &types.Order{OrderID: order.OrderID, Price: orderPrice},
+		`INSERT INTO orders (ClientOrderId, CummulativeQuoteQty, ExecutedQuantity, OrderID, OrderIDSource, Price, Side, Status, Symbol, TransactTime, ThreadID, ThreadIDSession)
+		VALUES (?,?,?,?,?,?,?,?,?,?,?,?)`,
+		order.ClientOrderID,
+		order.CumulativeQuoteQuantity,
+		order.ExecutedQuantity,
+		order.OrderID,
+		orderIDSource,
+		orderPrice,
+		order.Side,
+		order.Status,
+		order.Symbol,
+		order.TransactTime,
+		o.sessionData.ThreadID,
+		o.sessionData.ThreadIDSession)
+
+}
+
+// UpdateOrder Update order
+func (o orderTx) UpdateOrder(
+	OrderID int64,
+	CumulativeQuoteQuantity float64,
+	ExecutedQuantity float64,
+	Price float64,
+	Status string) error {
+
+	return exec(o.ctx, o.sessionData, o.db, &types.Order{OrderID: int(OrderID), Price: Price},
+		`UPDATE orders SET CummulativeQuoteQty = ?, ExecutedQuantity = ?, Price = ?, Status = ? WHERE OrderID = ?`,
+		CumulativeQuoteQuantity,
+		ExecutedQuantity,
+		Price,
+		Status,
+		OrderID)
+
+}
+
+// SaveThreadTransaction Save Thread cycle to database
+func (o orderTx) SaveThreadTransaction(
+	OrderID int64,
+	CumulativeQuoteQuantity float64,
+	Price float64,
+	ExecutedQuantity float64) error {
+
+	return exec(o.ctx, o.sessionData, o.db, &types.Order{OrderID: int(OrderID), Price: Price},
+		`INSERT INTO thread (ThreadID, ThreadIDSession, OrderID, CummulativeQuoteQty, Price, ExecutedQuantity) VALUES (?,?,?,?,?,?)`,
+		o.sessionData.ThreadID,
+		o.sessionData.ThreadIDSession,
+		OrderID,
+		CumulativeQuoteQuantity,
+		Price,
+		ExecutedQuantity)
+
+}
+
+// DeleteThreadTransactionByOrderID Delete Thread transaction by OrderID
+func (o orderTx) DeleteThreadTransactionByOrderID(
+	orderID int) error {
+
+	return exec(o.ctx, o.sessionData, o.db, &types.Order{OrderID: orderID},
+		`DELETE FROM thread WHERE OrderID = ?`,
+		orderID)
+
+}
+
+// SaveTrade Save closed trade to the trades ledger
+func (o orderTx) SaveTrade(
+	trade *types.Trade) error {
+
+	return exec(o.ctx, o.sessionData, o.db, &types.Order{OrderID: trade.SellOrderID, Price: trade.ExitPrice, OrderIDSource: trade.BuyOrderID},
+		`INSERT INTO trades (ThreadID, ThreadIDSession, Symbol, BuyOrderID, SellOrderID, Quantity, EntryPrice, ExitPrice, EntryQuote, ExitQuote, Fees, Profit, ProfitPct, EntryTime, ExitTime, HoldingTime, ExitReason)
+		VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`,
+		trade.ThreadID,
+		trade.ThreadIDSession,
+		trade.Symbol,
+		trade.BuyOrderID,
+		trade.SellOrderID,
+		trade.Quantity,
+		trade.EntryPrice,
+		trade.ExitPrice,
+		trade.EntryQuote,
+		trade.ExitQuote,
+		trade.Fees,
+		trade.Profit,
+		trade.ProfitPct,
+		trade.EntryTime,
+		trade.ExitTime,
+		trade.HoldingTime,
+		trade.ExitReason)
+
+}
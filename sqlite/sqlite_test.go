@@ -0,0 +1,929 @@
+package sqlite
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aleibovici/cryptopump/migrations"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+/* Return a session connected to a new migrated database in a temporary directory */
+func newSession(t *testing.T) *types.Session {
+
+	db, err := DBInit(filepath.Join(t.TempDir(), "cryptopump.db"))
+	if err != nil {
+		t.Fatalf("DBInit() error = %v", err)
+	}
+
+	if _, err := (migrations.Migrator{Db: db, Backend: "sqlite"}).Up(); err != nil {
+		t.Fatalf("Migrator.Up() error = %v", err)
+	}
+
+	return &types.Session{
+		ThreadID:        "c683ok5mk1u1120gnmmg",
+		ThreadIDSession: "c683ok5mk1u1120gnmn0",
+		SymbolFiat:      "USDT",
+		SymbolFiatFunds: 1000,
+		Db:              db,
+		Global:          &types.Global{},
+	}
+
+}
+
+/* Save a BUY order and its thread transaction, and optionally the SELL order that sold it */
+func saveTrade(t *testing.T, sessionData *types.Session, orderID int, price float64, sellPrice float64) {
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+
+	if err := (Storage{}).SaveOrder(context.Background(), sessionData, &types.Order{
+		OrderID:                 orderID,
+		CumulativeQuoteQuantity: price,
+		ExecutedQuantity:        1,
+		Side:                    "BUY",
+		Status:                  "FILLED",
+		Symbol:                  "BTCUSDT",
+		TransactTime:            now,
+	}, 0, price); err != nil {
+		t.Fatalf("SaveOrder() error = %v", err)
+	}
+
+	if sellPrice == 0 {
+
+		if err := (Storage{}).SaveThreadTransaction(context.Background(), sessionData, int64(orderID), price, price, 1); err != nil {
+			t.Fatalf("SaveThreadTransaction() error = %v", err)
+		}
+
+		return
+
+	}
+
+	if err := (Storage{}).SaveOrder(context.Background(), sessionData, &types.Order{
+		OrderID:                 orderID + 1000,
+		CumulativeQuoteQuantity: sellPrice,
+		ExecutedQuantity:        1,
+		Side:                    "SELL",
+		Status:                  "FILLED",
+		Symbol:                  "BTCUSDT",
+		TransactTime:            now + 1,
+	}, int64(orderID), sellPrice); err != nil {
+		t.Fatalf("SaveOrder() error = %v", err)
+	}
+
+}
+
+func TestStorage_Orders(t *testing.T) {
+
+	sessionData := newSession(t)
+	saveTrade(t, sessionData, 1, 100, 110)
+
+	if err := (Storage{}).SaveOrder(context.Background(), sessionData, &types.Order{
+		OrderID:      3,
+		Side:         "BUY",
+		Status:       "NEW",
+		Symbol:       "BTCUSDT",
+		TransactTime: 1,
+	}, 0, 0); err != nil {
+		t.Fatalf("SaveOrder() error = %v", err)
+	}
+
+	if price, err := (Storage{}).GetLastOrderTransactionPrice(context.Background(), sessionData, "BUY"); err != nil || price != 100 {
+		t.Errorf("GetLastOrderTransactionPrice() = %v, %v, want 100", price, err)
+	}
+
+	if side, err := (Storage{}).GetLastOrderTransactionSide(context.Background(), sessionData); err != nil || side != "SELL" {
+		t.Errorf("GetLastOrderTransactionSide() = %v, %v, want SELL", side, err)
+	}
+
+	if side1, side2, err := (Storage{}).GetOrderTransactionSideLastTwo(context.Background(), sessionData); err != nil || side1 != "SELL" || side2 != "BUY" {
+		t.Errorf("GetOrderTransactionSideLastTwo() = %v, %v, %v, want SELL, BUY", side1, side2, err)
+	}
+
+	if symbol, err := (Storage{}).GetOrderSymbol(context.Background(), sessionData); err != nil || symbol != "BTCUSDT" {
+		t.Errorf("GetOrderSymbol() = %v, %v, want BTCUSDT", symbol, err)
+	}
+
+	if count, err := (Storage{}).GetOrderTransactionCount(context.Background(), sessionData, "SELL"); err != nil || count != 1 {
+		t.Errorf("GetOrderTransactionCount() = %v, %v, want 1", count, err)
+	}
+
+	if order, err := (Storage{}).GetOrderTransactionPending(context.Background(), sessionData); err != nil || order.OrderID != 3 {
+		t.Errorf("GetOrderTransactionPending() = %v, %v, want OrderID 3", order.OrderID, err)
+	}
+
+	if err := (Storage{}).UpdateOrder(context.Background(), sessionData, 3, 95, 1, 95, "FILLED"); err != nil {
+		t.Fatalf("UpdateOrder() error = %v", err)
+	}
+
+	sessionData.ForceSellOrderID = 3
+	if order, err := (Storage{}).GetOrderByOrderID(context.Background(), sessionData); err != nil || order.Price != 95 {
+		t.Errorf("GetOrderByOrderID() = %v, %v, want Price 95", order.Price, err)
+	}
+
+	if order, err := (Storage{}).GetOrderTransactionPending(context.Background(), sessionData); err != nil || order.OrderID != 0 {
+		t.Errorf("GetOrderTransactionPending() = %v, %v, want no order", order.OrderID, err)
+	}
+
+}
+
+func TestStorage_ThreadTransactions(t *testing.T) {
+
+	sessionData := newSession(t)
+	saveTrade(t, sessionData, 1, 100, 0)
+	saveTrade(t, sessionData, 2, 90, 0)
+	saveTrade(t, sessionData, 3, 80, 0)
+
+	tests := []struct {
+		name      string
+		get       func(ctx context.Context, marketData *types.Market, sessionData *types.Session) (types.Order, error)
+		price     float64
+		wantOrder int
+	}{
+		{
+			name:      "GetThreadTransactionByPrice lowest below price",
+			get:       Storage{}.GetThreadTransactionByPrice,
+			price:     95,
+			wantOrder: 3,
+		},
+		{
+			name:      "GetThreadTransactionByPrice none below price",
+			get:       Storage{}.GetThreadTransactionByPrice,
+			price:     70,
+			wantOrder: 0,
+		},
+		{
+			name:      "GetThreadTransactionByPriceHigher highest above price",
+			get:       Storage{}.GetThreadTransactionByPriceHigher,
+			price:     85,
+			wantOrder: 1,
+		},
+		{
+			name: "GetThreadLastTransaction lowest price",
+			get: func(ctx context.Context, marketData *types.Market, sessionData *types.Session) (types.Order, error) {
+				return Storage{}.GetThreadLastTransaction(ctx, sessionData)
+			},
+			wantOrder: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := tt.get(context.Background(), &types.Market{Price: tt.price}, sessionData)
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if order.OrderID != tt.wantOrder {
+				t.Errorf("OrderID = %v, want %v", order.OrderID, tt.wantOrder)
+			}
+			if order.OrderID != 0 && order.TransactTime == 0 {
+				t.Errorf("TransactTime = 0, want order transaction time")
+			}
+		})
+	}
+
+	if count, err := (Storage{}).GetThreadTransactionCount(context.Background(), sessionData); err != nil || count != 3 {
+		t.Errorf("GetThreadTransactionCount() = %v, %v, want 3", count, err)
+	}
+
+	if count, err := (Storage{}).GetThreadTransactiontUpmarketPriceCount(context.Background(), sessionData, 95); err != nil || count != 2 {
+		t.Errorf("GetThreadTransactiontUpmarketPriceCount() = %v, %v, want 2", count, err)
+	}
+
+	if amount, err := (Storage{}).GetThreadAmount(context.Background(), sessionData); err != nil || amount != 270 {
+		t.Errorf("GetThreadAmount() = %v, %v, want 270", amount, err)
+	}
+
+	if orders, err := (Storage{}).GetThreadTransactionByThreadID(context.Background(), sessionData); err != nil || len(orders) != 3 || orders[0].OrderID != 3 {
+		t.Errorf("GetThreadTransactionByThreadID() = %v, %v, want 3 orders from lowest price", orders, err)
+	}
+
+	if orders, err := (Storage{}).GetThreadTransactionAll(context.Background(), sessionData); err != nil || len(orders) != 3 || orders[0].OrderID != 3 || orders[2].OrderID != 1 || orders[0].TransactTime == 0 {
+		t.Errorf("GetThreadTransactionAll() = %v, %v, want 3 orders from lowest price", orders, err)
+	}
+
+	if threadID, threadIDSession, err := (Storage{}).GetThreadTransactionDistinct(context.Background(), sessionData); err != nil || threadID != sessionData.ThreadID || threadIDSession != sessionData.ThreadIDSession {
+		t.Errorf("GetThreadTransactionDistinct() = %v, %v, %v", threadID, threadIDSession, err)
+	}
+
+	if err := (Storage{}).DeleteThreadTransactionByOrderID(context.Background(), sessionData, 3); err != nil {
+		t.Fatalf("DeleteThreadTransactionByOrderID() error = %v", err)
+	}
+
+	if count, err := (Storage{}).GetThreadTransactionCount(context.Background(), sessionData); err != nil || count != 2 {
+		t.Errorf("GetThreadTransactionCount() = %v, %v, want 2", count, err)
+	}
+
+}
+
+func TestStorage_Sessions(t *testing.T) {
+
+	sessionData := newSession(t)
+	configData := &types.Config{ExchangeName: "BINANCE"}
+
+	if err := (Storage{}).SaveSession(context.Background(), configData, sessionData); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+
+	/* ThreadID is unique, the caller falls back to UpdateSession */
+	if err := (Storage{}).SaveSession(context.Background(), configData, sessionData); err == nil {
+		t.Errorf("SaveSession() duplicate ThreadID error = nil, want error")
+	}
+
+	if threadID, err := (Storage{}).GetSessionStatus(context.Background(), sessionData); err != nil || threadID != "" {
+		t.Errorf("GetSessionStatus() = %v, %v, want no thread", threadID, err)
+	}
+
+	sessionData.Status = true
+	if err := (Storage{}).UpdateSession(context.Background(), configData, sessionData); err != nil {
+		t.Fatalf("UpdateSession() error = %v", err)
+	}
+
+	if threadID, err := (Storage{}).GetSessionStatus(context.Background(), sessionData); err != nil || threadID != sessionData.ThreadID {
+		t.Errorf("GetSessionStatus() = %v, %v, want %v", threadID, err, sessionData.ThreadID)
+	}
+
+	if count, err := (Storage{}).GetThreadCount(context.Background(), sessionData); err != nil || count != 1 {
+		t.Errorf("GetThreadCount() = %v, %v, want 1", count, err)
+	}
+
+	if err := (Storage{}).DeleteSession(context.Background(), sessionData); err != nil {
+		t.Fatalf("DeleteSession() error = %v", err)
+	}
+
+	if count, err := (Storage{}).GetThreadCount(context.Background(), sessionData); err != nil || count != 0 {
+		t.Errorf("GetThreadCount() = %v, %v, want 0", count, err)
+	}
+
+}
+
+func TestStorage_GetSessions(t *testing.T) {
+
+	sessionData := newSession(t)
+	configData := &types.Config{ExchangeName: "BINANCE"}
+
+	if sessions, err := (Storage{}).GetSessions(context.Background(), sessionData); err != nil || len(sessions) != 0 {
+		t.Fatalf("GetSessions() = %v, %v, want no session", sessions, err)
+	}
+
+	if err := (Storage{}).SaveSession(context.Background(), configData, sessionData); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+
+	saveTrade(t, sessionData, 1, 100, 0)
+	saveTrade(t, sessionData, 2, 90, 0)
+
+	other := *sessionData
+	other.ThreadID = "c683ok5mk1u1120gnmo0"
+	if err := (Storage{}).SaveSession(context.Background(), configData, &other); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+
+	want := []types.SessionRecord{
+		{ThreadID: sessionData.ThreadID, ThreadIDSession: sessionData.ThreadIDSession, Exchange: "BINANCE", SymbolFiat: "USDT", SymbolFiatFunds: 1000, ThreadCount: 2},
+		{ThreadID: other.ThreadID, ThreadIDSession: other.ThreadIDSession, Exchange: "BINANCE", SymbolFiat: "USDT", SymbolFiatFunds: 1000},
+	}
+
+	if sessions, err := (Storage{}).GetSessions(context.Background(), sessionData); err != nil || !reflect.DeepEqual(sessions, want) {
+		t.Errorf("GetSessions() = %v, %v, want %v", sessions, err, want)
+	}
+
+}
+
+func TestStorage_GetOrders(t *testing.T) {
+
+	sessionData := newSession(t)
+
+	other := *sessionData
+	other.ThreadID = "c683ok5mk1u1120gnmo0"
+
+	for _, order := range []struct {
+		sessionData *types.Session
+		order       types.Order
+	}{
+		{sessionData, types.Order{OrderID: 1, Side: "BUY", Status: "FILLED", TransactTime: 1}},
+		{sessionData, types.Order{OrderID: 2, Side: "SELL", Status: "FILLED", TransactTime: 2}},
+		{sessionData, types.Order{OrderID: 3, Side: "BUY", Status: "NEW", TransactTime: 3}},
+		{&other, types.Order{OrderID: 4, Side: "BUY", Status: "FILLED", TransactTime: 4}},
+	} {
+		order.order.Symbol = "BTCUSDT"
+		if err := (Storage{}).SaveOrder(context.Background(), order.sessionData, &order.order, 0, 0); err != nil {
+			t.Fatalf("SaveOrder() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter types.OrderFilter
+		want   []int /* OrderID */
+	}{
+		{"all", types.OrderFilter{}, []int{4, 3, 2, 1}},
+		{"thread", types.OrderFilter{ThreadID: sessionData.ThreadID}, []int{3, 2, 1}},
+		{"side", types.OrderFilter{ThreadID: sessionData.ThreadID, Side: "BUY"}, []int{3, 1}},
+		{"status", types.OrderFilter{Status: "NEW"}, []int{3}},
+		{"limit", types.OrderFilter{Limit: 2}, []int{4, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			orders, err := (Storage{}).GetOrders(context.Background(), sessionData, tt.filter)
+			if err != nil {
+				t.Fatalf("GetOrders() error = %v", err)
+			}
+
+			var got []int
+			for _, order := range orders {
+				got = append(got, order.OrderID)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetOrders() = %v, want %v", got, tt.want)
+			}
+
+		})
+	}
+
+}
+
+func TestStorage_Profit(t *testing.T) {
+
+	sessionData := newSession(t)
+	saveTrade(t, sessionData, 1, 100, 110)
+	saveTrade(t, sessionData, 2, 100, 95)
+
+	if err := (Storage{}).SaveSession(context.Background(), &types.Config{}, sessionData); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+
+	if profit, profitNet, percentage, err := (Storage{}).GetProfit(context.Background(), sessionData); err != nil || profit != 5 || profitNet != 5 {
+		t.Errorf("GetProfit() = %v, %v, %v, %v, want 5, 5", profit, profitNet, percentage, err)
+	}
+
+	if profit, _, err := (Storage{}).GetProfitByThreadID(context.Background(), sessionData); err != nil || profit != 5 {
+		t.Errorf("GetProfitByThreadID() = %v, %v, want 5", profit, err)
+	}
+
+	if profit, err := (Storage{}).GetProfitByThreadIDSince(context.Background(), sessionData, time.Now().Add(time.Hour).UnixNano()/int64(time.Millisecond)); err != nil || profit != 0 {
+		t.Errorf("GetProfitByThreadIDSince() = %v, %v, want 0", profit, err)
+	}
+
+	if _, _, _, transactTime, err := (Storage{}).GetGlobal(context.Background(), sessionData); err != nil || transactTime != 0 {
+		t.Errorf("GetGlobal() = %v, %v, want no global", transactTime, err)
+	}
+
+	sessionData.Global.Profit = 5
+	if err := (Storage{}).SaveGlobal(context.Background(), sessionData); err != nil {
+		t.Fatalf("SaveGlobal() error = %v", err)
+	}
+
+	sessionData.Global.Profit = 7
+	if err := (Storage{}).UpdateGlobal(context.Background(), sessionData); err != nil {
+		t.Fatalf("UpdateGlobal() error = %v", err)
+	}
+
+	if profit, _, _, transactTime, err := (Storage{}).GetGlobal(context.Background(), sessionData); err != nil || profit != 7 || transactTime == 0 {
+		t.Errorf("GetGlobal() = %v, %v, %v, want 7", profit, transactTime, err)
+	}
+
+}
+
+func TestStorage_OrderTx(t *testing.T) {
+
+	sessionData := newSession(t)
+	order := &types.Order{OrderID: 1, Side: "BUY", Status: "FILLED", Symbol: "BTCUSDT", TransactTime: 1}
+
+	/* A failed write rolls back the writes before it */
+	if err := (Storage{}).OrderTx(context.Background(), sessionData, func(tx types.OrderTx) error {
+		if err := tx.SaveOrder(order, 0, 100); err != nil {
+			return err
+		}
+		return tx.SaveOrder(order, 0, 100) /* Duplicate OrderID */
+	}); err == nil {
+		t.Fatalf("OrderTx() duplicate order error = nil, want error")
+	}
+
+	if count, err := (Storage{}).GetOrderTransactionCount(context.Background(), sessionData, "BUY"); err != nil || count != 0 {
+		t.Errorf("GetOrderTransactionCount() after rollback = %v, %v, want 0", count, err)
+	}
+
+	if err := (Storage{}).OrderTx(context.Background(), sessionData, func(tx types.OrderTx) error {
+		if err := tx.SaveOrder(order, 0, 100); err != nil {
+			return err
+		}
+		return tx.SaveThreadTransaction(1, 100, 100, 1)
+	}); err != nil {
+		t.Fatalf("OrderTx() error = %v", err)
+	}
+
+	if count, err := (Storage{}).GetThreadTransactionCount(context.Background(), sessionData); err != nil || count != 1 {
+		t.Errorf("GetThreadTransactionCount() after commit = %v, %v, want 1", count, err)
+	}
+
+}
+
+func TestStorage_OrderTx_fence(t *testing.T) {
+
+	sessionData := newSession(t)
+	write := func(tx types.OrderTx) error { return tx.SaveThreadTransaction(1, 100, 100, 1) }
+
+	var err error
+	if sessionData.ThreadLease, err = (Storage{}).AcquireLease(context.Background(), sessionData, "thread:c683ok5mk1u1120gnmmg", "a", -60000); err != nil {
+		t.Fatalf("AcquireLease() error = %v", err)
+	}
+
+	if err := (Storage{}).OrderTx(context.Background(), sessionData, write); err != nil {
+		t.Fatalf("OrderTx() with the lease held error = %v", err)
+	}
+
+	/* The expired lease is taken over by another node */
+	if _, err := (Storage{}).AcquireLease(context.Background(), sessionData, "thread:c683ok5mk1u1120gnmmg", "b", 60000); err != nil {
+		t.Fatalf("AcquireLease() error = %v", err)
+	}
+
+	if err := (Storage{}).OrderTx(context.Background(), sessionData, write); err != types.ErrFenced {
+		t.Errorf("OrderTx() after takeover error = %v, want %v", err, types.ErrFenced)
+	}
+
+	if count, err := (Storage{}).GetThreadTransactionCount(context.Background(), sessionData); err != nil || count != 1 {
+		t.Errorf("GetThreadTransactionCount() = %v, %v, want 1", count, err)
+	}
+
+}
+
+func TestStorage_ThreadTransactionRecovery(t *testing.T) {
+
+	sessionData := newSession(t)
+	saveTrade(t, sessionData, 1, 100, 0)   /* BUY with Thread transaction */
+	saveTrade(t, sessionData, 2, 100, 110) /* BUY sold, Thread transaction deleted */
+
+	/* BUY saved without its Thread transaction */
+	if err := (Storage{}).SaveOrder(context.Background(), sessionData, &types.Order{OrderID: 3, CumulativeQuoteQuantity: 90, ExecutedQuantity: 1, Side: "BUY", Status: "FILLED", TransactTime: 3}, 0, 90); err != nil {
+		t.Fatalf("SaveOrder() error = %v", err)
+	}
+
+	/* SELL saved without deleting the Thread transaction of its BUY */
+	if err := (Storage{}).SaveOrder(context.Background(), sessionData, &types.Order{OrderID: 1001, Side: "SELL", Status: "FILLED", TransactTime: 4}, 1, 110); err != nil {
+		t.Fatalf("SaveOrder() error = %v", err)
+	}
+
+	/* BUY with an open SELL is left alone */
+	if err := (Storage{}).SaveOrder(context.Background(), sessionData, &types.Order{OrderID: 4, Side: "BUY", Status: "FILLED", TransactTime: 5}, 0, 80); err != nil {
+		t.Fatalf("SaveOrder() error = %v", err)
+	}
+	if err := (Storage{}).SaveOrder(context.Background(), sessionData, &types.Order{OrderID: 1004, Side: "SELL", Status: "NEW", TransactTime: 6}, 4, 85); err != nil {
+		t.Fatalf("SaveOrder() error = %v", err)
+	}
+
+	if orders, err := (Storage{}).GetThreadTransactionMissing(context.Background(), sessionData); err != nil || len(orders) != 1 || orders[0].OrderID != 3 || orders[0].Price != 90 {
+		t.Errorf("GetThreadTransactionMissing() = %v, %v, want BUY order 3", orders, err)
+	}
+
+	if orders, err := (Storage{}).GetThreadTransactionSold(context.Background(), sessionData); err != nil || len(orders) != 1 || orders[0].OrderID != 1001 || orders[0].OrderIDSource != 1 {
+		t.Errorf("GetThreadTransactionSold() = %v, %v, want SELL order 1001 of BUY order 1", orders, err)
+	}
+
+}
+
+func TestStorage_GetTrades(t *testing.T) {
+
+	sessionData := newSession(t)
+
+	ledger := []types.Trade{
+		{ThreadID: "a", BuyOrderID: 1, SellOrderID: 101, Profit: 1, ExitTime: 1000, ExitReason: "profit"},
+		{ThreadID: "a", BuyOrderID: 2, SellOrderID: 102, Profit: -2, ExitTime: 2000, ExitReason: "stoploss"},
+		{ThreadID: "b", BuyOrderID: 3, SellOrderID: 103, Profit: 3, ExitTime: 3000, ExitReason: "profit"},
+	}
+
+	if err := (Storage{}).OrderTx(context.Background(), sessionData, func(tx types.OrderTx) error {
+		for i := range ledger {
+			if err := tx.SaveTrade(&ledger[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("OrderTx() SaveTrade error = %v", err)
+	}
+
+	/* A SELL order closes one trade */
+	if err := (Storage{}).OrderTx(context.Background(), sessionData, func(tx types.OrderTx) error {
+		return tx.SaveTrade(&ledger[0])
+	}); err == nil {
+		t.Errorf("OrderTx() duplicate SaveTrade error = nil, want error")
+	}
+
+	tests := []struct {
+		name   string
+		filter types.TradeFilter
+		want   []int /* SellOrderID most recent first */
+	}{
+		{name: "all", filter: types.TradeFilter{Limit: 10}, want: []int{103, 102, 101}},
+		{name: "thread", filter: types.TradeFilter{ThreadID: "a", Limit: 10}, want: []int{102, 101}},
+		{name: "date range", filter: types.TradeFilter{From: 2000, To: 3000, Limit: 10}, want: []int{102}},
+		{name: "exit reason", filter: types.TradeFilter{ExitReason: "profit", Limit: 10}, want: []int{103, 101}},
+		{name: "limit", filter: types.TradeFilter{Limit: 1}, want: []int{103}},
+		{name: "no limit", filter: types.TradeFilter{}, want: []int{103, 102, 101}},
+		{name: "none", filter: types.TradeFilter{ThreadID: "c", Limit: 10}, want: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (Storage{}).GetTrades(context.Background(), sessionData, tt.filter)
+			if err != nil {
+				t.Fatalf("GetTrades() error = %v", err)
+			}
+			ids := []int{}
+			for _, trade := range got {
+				ids = append(ids, trade.SellOrderID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("GetTrades() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+}
+
+func TestStorage_GetOrderTransactionExecuted(t *testing.T) {
+
+	sessionData := newSession(t)
+
+	for _, order := range []types.Order{
+		{OrderID: 2, Side: "BUY", Status: "FILLED", Symbol: "BTCUSDT", ExecutedQuantity: 1, TransactTime: 2000},
+		{OrderID: 1, Side: "BUY", Status: "FILLED", Symbol: "BTCUSDT", ExecutedQuantity: 1, TransactTime: 1000},
+		{OrderID: 3, Side: "SELL", Status: "CANCELED", Symbol: "BTCUSDT", ExecutedQuantity: 0, TransactTime: 3000},
+		{OrderID: 4, OrderIDSource: 1, Side: "SELL", Status: "FILLED", Symbol: "BTCUSDT", ExecutedQuantity: 1, TransactTime: 4000},
+	} {
+		order := order
+		if err := (Storage{}).SaveOrder(context.Background(), sessionData, &order, int64(order.OrderIDSource), 100); err != nil {
+			t.Fatalf("SaveOrder() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		before int64
+		want   []int
+	}{
+		{name: "all", before: 0, want: []int{1, 2, 4}},
+		{name: "before", before: 4000, want: []int{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders, err := (Storage{}).GetOrderTransactionExecuted(context.Background(), sessionData, tt.before)
+			if err != nil {
+				t.Fatalf("GetOrderTransactionExecuted() error = %v", err)
+			}
+			ids := []int{}
+			for _, order := range orders {
+				ids = append(ids, order.OrderID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("GetOrderTransactionExecuted() = %v, want %v", ids, tt.want)
+			}
+			if len(orders) > 2 && orders[2].OrderIDSource != 1 {
+				t.Errorf("GetOrderTransactionExecuted() OrderIDSource = %v, want 1", orders[2].OrderIDSource)
+			}
+		})
+	}
+
+}
+
+func TestStorage_GetTradeStats(t *testing.T) {
+
+	sessionData := newSession(t)
+
+	if err := (Storage{}).OrderTx(context.Background(), sessionData, func(tx types.OrderTx) error {
+		for i, trade := range []types.Trade{
+			{ThreadID: "a", Profit: 3, HoldingTime: 100},
+			{ThreadID: "a", Profit: -1, HoldingTime: 200},
+			{ThreadID: "b", Profit: 5, HoldingTime: 300},
+		} {
+			trade.SellOrderID = i + 1
+			if err := tx.SaveTrade(&trade); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("OrderTx() SaveTrade error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		threadID string
+		want     types.TradeStats
+	}{
+		{name: "all", threadID: "", want: types.TradeStats{Count: 3, Wins: 2, GrossProfit: 8, GrossLoss: 1, HoldingTime: 200}},
+		{name: "thread", threadID: "a", want: types.TradeStats{Count: 2, Wins: 1, GrossProfit: 3, GrossLoss: 1, HoldingTime: 150}},
+		{name: "none", threadID: "c", want: types.TradeStats{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (Storage{}).GetTradeStats(context.Background(), sessionData, tt.threadID)
+			if err != nil {
+				t.Fatalf("GetTradeStats() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetTradeStats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+}
+
+func TestStorage_SaveEquity(t *testing.T) {
+
+	sessionData := newSession(t)
+
+	for _, equity := range []types.Equity{
+		{ThreadID: "a", Date: "2021-01-02", Equity: 1},
+		{ThreadID: "a", Date: "2021-01-01", Equity: 2},
+		{ThreadID: "", Date: "2021-01-01", Equity: 3},
+		{ThreadID: "a", Date: "2021-01-02", Realized: 5, Unrealized: -1, Equity: 4}, /* Replace the snapshot of the day */
+	} {
+		if err := (Storage{}).SaveEquity(context.Background(), sessionData, equity); err != nil {
+			t.Fatalf("SaveEquity() error = %v", err)
+		}
+	}
+
+	got, err := (Storage{}).GetEquity(context.Background(), sessionData, "a")
+	if err != nil {
+		t.Fatalf("GetEquity() error = %v", err)
+	}
+
+	want := []types.Equity{
+		{ThreadID: "a", Date: "2021-01-01", Equity: 2},
+		{ThreadID: "a", Date: "2021-01-02", Realized: 5, Unrealized: -1, Equity: 4},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetEquity() = %+v, want %+v", got, want)
+	}
+
+	if got, err = (Storage{}).GetEquity(context.Background(), sessionData, ""); err != nil || len(got) != 1 || got[0].Equity != 3 {
+		t.Errorf("GetEquity() all threads = %+v, %v, want 1 snapshot", got, err)
+	}
+
+}
+
+func TestStorage_ArchiveOrders(t *testing.T) {
+
+	sessionData := newSession(t)
+	day := int64(86400000)
+	before := 10 * day
+
+	for _, order := range []types.Order{
+		{OrderID: 1, Side: "BUY", Status: "FILLED", CumulativeQuoteQuantity: 100, ExecutedQuantity: 1, TransactTime: day + 1},
+		{OrderID: 11, OrderIDSource: 1, Side: "SELL", Status: "FILLED", CumulativeQuoteQuantity: 110, ExecutedQuantity: 1, TransactTime: day + 2},
+		{OrderID: 2, Side: "BUY", Status: "FILLED", CumulativeQuoteQuantity: 100, ExecutedQuantity: 1, TransactTime: day + 3},
+		{OrderID: 12, OrderIDSource: 2, Side: "SELL", Status: "FILLED", CumulativeQuoteQuantity: 95, ExecutedQuantity: 1, TransactTime: 2 * day},
+		{OrderID: 3, Side: "BUY", Status: "CANCELED", TransactTime: 2 * day},
+		{OrderID: 4, Side: "BUY", Status: "FILLED", CumulativeQuoteQuantity: 100, ExecutedQuantity: 1, TransactTime: 3 * day}, /* Open Thread transaction */
+		{OrderID: 5, Side: "BUY", Status: "FILLED", CumulativeQuoteQuantity: 100, ExecutedQuantity: 1, TransactTime: 4 * day},
+		{OrderID: 15, OrderIDSource: 5, Side: "SELL", Status: "FILLED", CumulativeQuoteQuantity: 120, ExecutedQuantity: 1, TransactTime: 5 * day}, /* Last SELL */
+		{OrderID: 6, Side: "BUY", Status: "FILLED", CumulativeQuoteQuantity: 100, ExecutedQuantity: 1, TransactTime: before + 1},                  /* After cutoff */
+	} {
+		order := order
+		order.Symbol = "BTCUSDT"
+		if err := (Storage{}).SaveOrder(context.Background(), sessionData, &order, int64(order.OrderIDSource), order.CumulativeQuoteQuantity); err != nil {
+			t.Fatalf("SaveOrder() error = %v", err)
+		}
+	}
+
+	if err := (Storage{}).SaveThreadTransaction(context.Background(), sessionData, 4, 100, 100, 1); err != nil {
+		t.Fatalf("SaveThreadTransaction() error = %v", err)
+	}
+
+	profit, profitNet, percentage, _ := (Storage{}).GetProfit(context.Background(), sessionData)
+	threadProfit, threadPercentage, _ := (Storage{}).GetProfitByThreadID(context.Background(), sessionData)
+	executed, _ := (Storage{}).GetOrderTransactionExecuted(context.Background(), sessionData, 0)
+
+	archived, err := (Storage{}).ArchiveOrders(context.Background(), sessionData, before)
+	if err != nil || archived != 5 {
+		t.Fatalf("ArchiveOrders() = %v, %v, want 5 orders", archived, err)
+	}
+
+	/* Orders 1, 11, 2, 12 and 3 are archived */
+	var count int
+	if err := sessionData.Db.QueryRow(`SELECT COUNT(*) FROM orders WHERE OrderID IN (1, 11, 2, 12, 3)`).Scan(&count); err != nil || count != 0 {
+		t.Errorf("orders after ArchiveOrders() = %v, %v, want 0 archived orders", count, err)
+	}
+	if err := sessionData.Db.QueryRow(`SELECT COUNT(*) FROM orders_archive`).Scan(&count); err != nil || count != 5 {
+		t.Errorf("orders_archive = %v, %v, want 5", count, err)
+	}
+
+	var trades int
+	var dailyProfit float64
+	if err := sessionData.Db.QueryRow(`SELECT Trades, Profit FROM orders_daily WHERE Day = ?`, day).Scan(&trades, &dailyProfit); err != nil || trades != 1 || dailyProfit != 10 {
+		t.Errorf("orders_daily day 1 = %v, %v, %v, want 1 trade with profit 10", trades, dailyProfit, err)
+	}
+
+	/* Profit totals and the tax export include archived orders */
+	if p, n, pct, err := (Storage{}).GetProfit(context.Background(), sessionData); err != nil || math.Abs(p-profit) > 1e-9 || math.Abs(n-profitNet) > 1e-9 || math.Abs(pct-percentage) > 1e-9 {
+		t.Errorf("GetProfit() after ArchiveOrders() = %v, %v, %v, %v, want %v, %v, %v", p, n, pct, err, profit, profitNet, percentage)
+	}
+	if p, pct, err := (Storage{}).GetProfitByThreadID(context.Background(), sessionData); err != nil || math.Abs(p-threadProfit) > 1e-9 || math.Abs(pct-threadPercentage) > 1e-9 {
+		t.Errorf("GetProfitByThreadID() after ArchiveOrders() = %v, %v, %v, want %v, %v", p, pct, err, threadProfit, threadPercentage)
+	}
+	if orders, err := (Storage{}).GetOrderTransactionExecuted(context.Background(), sessionData, 0); err != nil || !reflect.DeepEqual(orders, executed) {
+		t.Errorf("GetOrderTransactionExecuted() after ArchiveOrders() = %v, %v, want %v", orders, err, executed)
+	}
+
+	if archived, err = (Storage{}).ArchiveOrders(context.Background(), sessionData, before); err != nil || archived != 0 {
+		t.Errorf("ArchiveOrders() again = %v, %v, want 0", archived, err)
+	}
+
+}
+
+func TestStorage_AcquireLease(t *testing.T) {
+
+	sessionData := newSession(t)
+	minute := int64(60000)
+
+	for _, step := range []struct {
+		name     string
+		holder   string
+		duration int64
+		release  bool
+		want     string
+		token    int64
+	}{
+		{name: "free", holder: "a", duration: minute, want: "a", token: 1},
+		{name: "held", holder: "b", duration: minute, want: "a", token: 1},
+		{name: "renew", holder: "a", duration: -minute, want: "a", token: 1}, /* Renewed already expired */
+		{name: "expired", holder: "b", duration: minute, want: "b", token: 2},
+		{name: "release not held", holder: "a", release: true},
+		{name: "release", holder: "b", release: true},
+		{name: "released", holder: "a", duration: minute, want: "a", token: 3},
+	} {
+		if step.release {
+			if err := (Storage{}).ReleaseLease(context.Background(), sessionData, "master", step.holder); err != nil {
+				t.Fatalf("%s: ReleaseLease() error = %v", step.name, err)
+			}
+			continue
+		}
+
+		lease, err := (Storage{}).AcquireLease(context.Background(), sessionData, "master", step.holder, step.duration)
+		if err != nil || lease.Name != "master" || lease.Holder != step.want || lease.Token != step.token {
+			t.Errorf("%s: AcquireLease() = %+v, %v, want holder %v token %v", step.name, lease, err, step.want, step.token)
+		}
+	}
+
+}
+
+func TestStorage_SaveNode(t *testing.T) {
+
+	sessionData := newSession(t)
+
+	for _, node := range []types.Node{
+		{ThreadID: "a", Hostname: "host1", Port: "8080", Version: "dev", Symbol: "BTCUSDT", Started: 1},
+		{ThreadID: "b", Hostname: "host2", Port: "8080", Version: "dev", Symbol: "ETHUSDT", Started: 2},
+		{ThreadID: "a", Hostname: "host1", Port: "8081", Version: "dev", Symbol: "BTCUSDT", Started: 3}, /* Heartbeat after restart */
+	} {
+		if err := (Storage{}).SaveNode(context.Background(), sessionData, node); err != nil {
+			t.Fatalf("SaveNode() error = %v", err)
+		}
+	}
+
+	nodes, err := (Storage{}).GetNodes(context.Background(), sessionData)
+	if err != nil || len(nodes) != 2 {
+		t.Fatalf("GetNodes() = %+v, %v, want 2 nodes", nodes, err)
+	}
+
+	if nodes[0].ThreadID != "a" || nodes[0].Port != "8081" || nodes[0].Heartbeat == 0 || nodes[0].Age < 0 || nodes[0].Age > 60000 {
+		t.Errorf("GetNodes() first node = %+v, want a on port 8081 with a recent heartbeat", nodes[0])
+	}
+
+	if err = (Storage{}).DeleteNode(context.Background(), sessionData, "a"); err != nil {
+		t.Fatalf("DeleteNode() error = %v", err)
+	}
+
+	if nodes, err = (Storage{}).GetNodes(context.Background(), sessionData); err != nil || len(nodes) != 1 || nodes[0].ThreadID != "b" {
+		t.Errorf("GetNodes() after DeleteNode() = %+v, %v, want b", nodes, err)
+	}
+
+}
+
+func TestStorage_GetThreadOrphans(t *testing.T) {
+
+	sessionData := newSession(t)
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+
+	for _, node := range []types.Node{
+		{ThreadID: "a", Hostname: "host1", Port: "8080"},
+		{ThreadID: "b", Hostname: "host2", Port: "8080"},
+		{ThreadID: "c", Hostname: "host3", Port: "8080"},
+	} {
+		if err := (Storage{}).SaveNode(context.Background(), sessionData, node); err != nil {
+			t.Fatalf("SaveNode() error = %v", err)
+		}
+	}
+
+	/* a and c stopped sending heartbeats, c has no Thread transactions */
+	if _, err := sessionData.Db.Exec(`UPDATE nodes SET Heartbeat = ? WHERE ThreadID IN ('a', 'c')`, now-120000); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, thread := range []struct{ threadID, threadIDSession string }{{"a", "s1"}, {"a", "s2"}, {"b", "s3"}} {
+		sessionData.ThreadID, sessionData.ThreadIDSession = thread.threadID, thread.threadIDSession
+		if err := (Storage{}).SaveThreadTransaction(context.Background(), sessionData, 1, 100, 100, 1); err != nil {
+			t.Fatalf("SaveThreadTransaction() error = %v", err)
+		}
+	}
+
+	orphans, err := (Storage{}).GetThreadOrphans(context.Background(), sessionData, 60000)
+	if err != nil || len(orphans) != 1 {
+		t.Fatalf("GetThreadOrphans() = %+v, %v, want 1 orphan", orphans, err)
+	}
+
+	if orphans[0].ThreadID != "a" || orphans[0].ThreadIDSession != "s2" || orphans[0].Hostname != "host1" || orphans[0].Age < 120000 {
+		t.Errorf("GetThreadOrphans() = %+v, want a on host1 down for 120 seconds", orphans[0])
+	}
+
+}
+
+func TestStorage_SaveAudit(t *testing.T) {
+
+	sessionData := newSession(t)
+
+	for _, audit := range []types.Audit{
+		{Time: 1, ThreadID: "a", Node: "host2:8080", Action: "takeover", Detail: "from host1:8080"},
+		{Time: 3, ThreadID: "b", Node: "host2:8080", Action: "takeover", Detail: "from host3:8080"},
+	} {
+		if err := (Storage{}).SaveAudit(context.Background(), sessionData, audit); err != nil {
+			t.Fatalf("SaveAudit() error = %v", err)
+		}
+	}
+
+	audit, err := (Storage{}).GetAudit(context.Background(), sessionData, 1)
+	if err != nil || len(audit) != 1 || audit[0].ThreadID != "b" || audit[0].ID != 2 || audit[0].Detail != "from host3:8080" {
+		t.Errorf("GetAudit() = %+v, %v, want the record of b", audit, err)
+	}
+
+}
+
+func TestStorage_SavePause(t *testing.T) {
+
+	sessionData := newSession(t)
+
+	tests := []struct {
+		name   string
+		pause  types.Pause
+		delete bool
+		want   types.Pause
+	}{
+		{name: "not paused", want: types.Pause{}},
+		{
+			name:  "paused",
+			pause: types.Pause{ThreadID: "a", Mode: "nobuy", By: "web 10.0.0.1", Time: 1, Confirm: true},
+			want:  types.Pause{ThreadID: "a", Mode: "nobuy", By: "web 10.0.0.1", Time: 1, Confirm: true},
+		},
+		{
+			name:  "mode changed",
+			pause: types.Pause{ThreadID: "a", Mode: "frozen", By: "telegram @user", Time: 2},
+			want:  types.Pause{ThreadID: "a", Mode: "frozen", By: "telegram @user", Time: 2},
+		},
+		{name: "resumed", delete: true, want: types.Pause{}},
+	}
+
+	for _, tt := range tests {
+		if tt.delete {
+			if err := (Storage{}).DeletePause(context.Background(), sessionData, "a"); err != nil {
+				t.Fatalf("%s: DeletePause() error = %v", tt.name, err)
+			}
+		} else if tt.pause.ThreadID != "" {
+			if err := (Storage{}).SavePause(context.Background(), sessionData, tt.pause); err != nil {
+				t.Fatalf("%s: SavePause() error = %v", tt.name, err)
+			}
+		}
+
+		if got, err := (Storage{}).GetPause(context.Background(), sessionData, "a"); err != nil || got != tt.want {
+			t.Errorf("%s: GetPause() = %+v, %v, want %+v", tt.name, got, err, tt.want)
+		}
+	}
+
+}
+
+func TestStorage_Context(t *testing.T) {
+
+	sessionData := newSession(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := (Storage{}).GetThreadCount(ctx, sessionData); err != context.Canceled {
+		t.Errorf("GetThreadCount() error = %v, want %v", err, context.Canceled)
+	}
+
+	if err := (Storage{}).OrderTx(ctx, sessionData, func(tx types.OrderTx) error { return nil }); err != context.Canceled {
+		t.Errorf("OrderTx() error = %v, want %v", err, context.Canceled)
+	}
+
+}
@@ -0,0 +1,407 @@
+package tax
+
+/* This package implements the tax and accounting export of realized gains. Every SELL is a disposal
+matched with the BUY lots it sold using FIFO, LIFO or specific-ID matching. Specific-ID follows the
+pairing of each SELL with its BUY (OrderIDSource) made by CryptoPump. Lots are matched per symbol
+across all threads, and the orders before the export range are replayed so that lots sold before
+the range are not matched again. */
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aleibovici/cryptopump/money"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+/* Lot matching methods */
+const (
+	MethodFIFO     = "fifo"     /* First in, first out */
+	MethodLIFO     = "lifo"     /* Last in, first out */
+	MethodSpecific = "specific" /* BUY paired with the SELL by CryptoPump (OrderIDSource) */
+)
+
+/* Export formats */
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+/* Quantity below which a lot is considered fully sold */
+const epsilon = 1e-9
+
+// Disposal struct define a sale of (part of) an acquisition lot
+type Disposal struct {
+	Symbol             string  `json:"symbol"`
+	Quantity           float64 `json:"quantity"`
+	Acquired           string  `json:"acquired"` /* BUY date, empty when no acquisition lot was found */
+	Disposed           string  `json:"disposed"` /* SELL date */
+	AcquisitionOrderID int     `json:"acquisitionOrderId"`
+	DisposalOrderID    int     `json:"disposalOrderId"`
+	CostBasis          float64 `json:"costBasis"` /* BUY cost including fees */
+	Proceeds           float64 `json:"proceeds"`  /* SELL proceeds net of fees */
+	Gain               float64 `json:"gain"`
+}
+
+// Report struct define a tax export
+type Report struct {
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+	Method    string     `json:"method"`
+	Disposals []Disposal `json:"disposals"`
+	CostBasis float64    `json:"costBasis"`
+	Proceeds  float64    `json:"proceeds"`
+	Gain      float64    `json:"gain"`
+}
+
+// Options struct define the export date range, lot matching method, format and fee rate
+type Options struct {
+	From    time.Time /* First disposal date, inclusive */
+	To      time.Time /* Last disposal date, inclusive */
+	Method  string
+	Format  string
+	FeeRate float64 /* Fee ratio charged on each order (exchange_comission) */
+}
+
+/* Acquisition lot with the quantity not yet sold */
+type lot struct {
+	order     types.Order
+	remaining float64
+}
+
+// NewOptions parse the from and to dates (YYYY-MM-DD, UTC), the lot matching method and the format.
+// Empty dates default to the current year, an empty method to FIFO and an empty format to CSV.
+func NewOptions(from string, to string, method string, format string, feeRate float64) (options Options, err error) {
+
+	year := time.Now().UTC().Year()
+	options.From = time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	options.To = time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC)
+	options.FeeRate = feeRate
+
+	if from != "" {
+
+		if options.From, err = time.Parse("2006-01-02", from); err != nil {
+
+			return options, errors.New("invalid from date " + strconv.Quote(from))
+
+		}
+
+	}
+
+	if to != "" {
+
+		if options.To, err = time.Parse("2006-01-02", to); err != nil {
+
+			return options, errors.New("invalid to date " + strconv.Quote(to))
+
+		}
+
+	}
+
+	if options.To.Before(options.From) {
+
+		return options, errors.New("to date is before from date")
+
+	}
+
+	switch method {
+	case "":
+
+		options.Method = MethodFIFO
+
+	case MethodFIFO, MethodLIFO, MethodSpecific:
+
+		options.Method = method
+
+	default:
+
+		return options, errors.New("invalid method " + strconv.Quote(method) + ", use fifo, lifo or specific")
+
+	}
+
+	switch format {
+	case "":
+
+		options.Format = FormatCSV
+
+	case FormatCSV, FormatJSON:
+
+		options.Format = format
+
+	default:
+
+		return options, errors.New("invalid format " + strconv.Quote(format) + ", use csv or json")
+
+	}
+
+	return options, nil
+
+}
+
+// Export read orders from storage and return the disposals in the options date range
+func Export(
+	sessionData *types.Session,
+	options Options) (report Report, err error) {
+
+	var orders []types.Order
+
+	if orders, err = sessionData.Storage.GetOrderTransactionExecuted(context.Background(), sessionData, toMillis(options.To.AddDate(0, 0, 1))); err != nil {
+
+		return report, err
+
+	}
+
+	return Match(orders, options), nil
+
+}
+
+// Match return the disposals in the options date range for orders sorted by TransactTime
+func Match(
+	orders []types.Order,
+	options Options) (report Report) {
+
+	from := toMillis(options.From)
+	to := toMillis(options.To.AddDate(0, 0, 1))
+	lots := map[string][]*lot{} /* Acquisition lots per symbol in time order */
+
+	report.From = options.From.Format("2006-01-02")
+	report.To = options.To.Format("2006-01-02")
+	report.Method = options.Method
+	report.Disposals = []Disposal{}
+
+	var costBasis, proceeds, gain money.Decimal /* Summed exactly, the totals are converted once */
+
+	for _, order := range orders {
+
+		if order.ExecutedQuantity <= 0 {
+
+			continue
+
+		}
+
+		switch order.Side {
+		case "BUY":
+
+			lots[order.Symbol] = append(lots[order.Symbol], &lot{order: order, remaining: order.ExecutedQuantity})
+
+		case "SELL":
+
+			disposals := dispose(lots[order.Symbol], order, options)
+
+			/* Drop sold lots */
+			open := lots[order.Symbol][:0]
+			for _, l := range lots[order.Symbol] {
+
+				if l.remaining > epsilon {
+
+					open = append(open, l)
+
+				}
+
+			}
+			lots[order.Symbol] = open
+
+			/* Sales before the range only reduce the lots */
+			if order.TransactTime < from || order.TransactTime >= to {
+
+				continue
+
+			}
+
+			for _, disposal := range disposals {
+
+				costBasis = costBasis.Add(money.New(disposal.CostBasis))
+				proceeds = proceeds.Add(money.New(disposal.Proceeds))
+				gain = gain.Add(money.New(disposal.Gain))
+
+			}
+
+			report.Disposals = append(report.Disposals, disposals...)
+
+		}
+
+	}
+
+	report.CostBasis = costBasis.Float()
+	report.Proceeds = proceeds.Float()
+	report.Gain = gain.Float()
+
+	return report
+
+}
+
+/* Sell the SELL order quantity from lots in the matching method order */
+func dispose(
+	lots []*lot,
+	sale types.Order,
+	options Options) (disposals []Disposal) {
+
+	quantity := sale.ExecutedQuantity
+	candidates := make([]*lot, len(lots))
+	copy(candidates, lots)
+
+	switch options.Method {
+	case MethodLIFO:
+
+		for i, j := 0, len(candidates)-1; i < j; i, j = i+1, j-1 {
+
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+
+		}
+
+	case MethodSpecific:
+
+		/* The BUY sold by CryptoPump first, then FIFO for any remainder */
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].order.OrderID == sale.OrderIDSource && candidates[j].order.OrderID != sale.OrderIDSource
+		})
+
+	}
+
+	for _, l := range candidates {
+
+		if quantity <= epsilon {
+
+			break
+
+		}
+
+		if l.remaining <= epsilon {
+
+			continue
+
+		}
+
+		take := quantity
+		if l.remaining < take {
+
+			take = l.remaining
+
+		}
+
+		cost := money.New(l.order.CumulativeQuoteQuantity).Mul(money.New(take)).Div(money.New(l.order.ExecutedQuantity)).Float()
+
+		disposals = append(disposals, newDisposal(sale, take, cost, options, l.order.OrderID, l.order.TransactTime))
+
+		l.remaining -= take
+		quantity -= take
+
+	}
+
+	/* Quantity sold without an acquisition lot, i.e. bought outside CryptoPump, has no cost basis */
+	if quantity > epsilon {
+
+		disposals = append(disposals, newDisposal(sale, quantity, 0, options, 0, 0))
+
+	}
+
+	return disposals
+
+}
+
+/* Return the disposal of quantity from the SELL order with the lot cost before fees */
+func newDisposal(
+	sale types.Order,
+	quantity float64,
+	cost float64,
+	options Options,
+	acquisitionOrderID int,
+	acquired int64) Disposal {
+
+	proceeds := money.New(sale.CumulativeQuoteQuantity).Mul(money.New(quantity)).Div(money.New(sale.ExecutedQuantity))
+
+	disposal := Disposal{
+		Symbol:             sale.Symbol,
+		Quantity:           quantity,
+		Disposed:           formatDate(sale.TransactTime),
+		AcquisitionOrderID: acquisitionOrderID,
+		DisposalOrderID:    sale.OrderID,
+		CostBasis:          money.Markup(cost, options.FeeRate).Float(),
+		Proceeds:           proceeds.Mul(money.New(1).Sub(money.New(options.FeeRate))).Float(),
+	}
+
+	if acquisitionOrderID != 0 {
+
+		disposal.Acquired = formatDate(acquired)
+
+	}
+
+	disposal.Gain = money.New(disposal.Proceeds).Sub(money.New(disposal.CostBasis)).Float()
+
+	return disposal
+
+}
+
+// Write write the report to w as CSV (one line per disposal) or JSON
+func Write(
+	w io.Writer,
+	format string,
+	report Report) error {
+
+	switch format {
+	case FormatJSON:
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(report)
+
+	case FormatCSV:
+
+		writer := csv.NewWriter(w)
+
+		if err := writer.Write([]string{"Symbol", "Quantity", "Acquired", "Disposed", "AcquisitionOrderID", "DisposalOrderID", "CostBasis", "Proceeds", "Gain"}); err != nil {
+
+			return err
+
+		}
+
+		for _, disposal := range report.Disposals {
+
+			if err := writer.Write([]string{
+				disposal.Symbol,
+				strconv.FormatFloat(disposal.Quantity, 'f', -1, 64),
+				disposal.Acquired,
+				disposal.Disposed,
+				strconv.Itoa(disposal.AcquisitionOrderID),
+				strconv.Itoa(disposal.DisposalOrderID),
+				strconv.FormatFloat(disposal.CostBasis, 'f', 8, 64),
+				strconv.FormatFloat(disposal.Proceeds, 'f', 8, 64),
+				strconv.FormatFloat(disposal.Gain, 'f', 8, 64),
+			}); err != nil {
+
+				return err
+
+			}
+
+		}
+
+		writer.Flush()
+
+		return writer.Error()
+
+	}
+
+	return errors.New("invalid format " + strconv.Quote(format) + ", use csv or json")
+
+}
+
+/* Return a time in milliseconds */
+func toMillis(t time.Time) int64 {
+
+	return t.UnixNano() / int64(time.Millisecond)
+
+}
+
+/* Format a time in milliseconds as an ISO 8601 UTC timestamp */
+func formatDate(msec int64) string {
+
+	return time.Unix(0, msec*int64(time.Millisecond)).UTC().Format(time.RFC3339)
+
+}
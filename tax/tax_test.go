@@ -0,0 +1,205 @@
+package tax
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aleibovici/cryptopump/types"
+)
+
+/* Milliseconds for day of January 2021 */
+func day(d int) int64 {
+
+	return time.Date(2021, 1, d, 12, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)
+
+}
+
+func buy(orderID int, d int, quantity float64, quote float64) types.Order {
+
+	return types.Order{OrderID: orderID, Side: "BUY", Symbol: "BTCUSDT", ExecutedQuantity: quantity, CumulativeQuoteQuantity: quote, TransactTime: day(d)}
+
+}
+
+func sell(orderID int, orderIDSource int, d int, quantity float64, quote float64) types.Order {
+
+	return types.Order{OrderID: orderID, OrderIDSource: orderIDSource, Side: "SELL", Symbol: "BTCUSDT", ExecutedQuantity: quantity, CumulativeQuoteQuantity: quote, TransactTime: day(d)}
+
+}
+
+func TestNewOptions(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		from       string
+		to         string
+		method     string
+		format     string
+		wantMethod string
+		wantFormat string
+		wantErr    bool
+	}{
+		{name: "defaults", wantMethod: MethodFIFO, wantFormat: FormatCSV},
+		{name: "lifo json", from: "2021-01-01", to: "2021-12-31", method: "lifo", format: "json", wantMethod: MethodLIFO, wantFormat: FormatJSON},
+		{name: "invalid date", from: "2021/01/01", wantErr: true},
+		{name: "reversed range", from: "2021-12-31", to: "2021-01-01", wantErr: true},
+		{name: "invalid method", method: "average", wantErr: true},
+		{name: "invalid format", format: "xlsx", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewOptions(tt.from, tt.to, tt.method, tt.format, 0.001)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (got.Method != tt.wantMethod || got.Format != tt.wantFormat || got.FeeRate != 0.001) {
+				t.Errorf("NewOptions() = %+v, want method %v format %v", got, tt.wantMethod, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+
+	type want struct {
+		acquisitionOrderID int
+		quantity           float64
+		costBasis          float64
+		proceeds           float64
+	}
+
+	tests := []struct {
+		name    string
+		orders  []types.Order
+		method  string
+		feeRate float64
+		want    []want
+	}{
+		{
+			name:   "fifo",
+			orders: []types.Order{buy(1, 2, 1, 100), buy(2, 3, 1, 200), sell(11, 2, 4, 1, 150)},
+			method: MethodFIFO,
+			want:   []want{{1, 1, 100, 150}},
+		},
+		{
+			name:   "lifo",
+			orders: []types.Order{buy(1, 2, 1, 100), buy(2, 3, 1, 200), sell(11, 1, 4, 1, 150)},
+			method: MethodLIFO,
+			want:   []want{{2, 1, 200, 150}},
+		},
+		{
+			name:   "specific",
+			orders: []types.Order{buy(1, 2, 1, 100), buy(2, 3, 1, 200), buy(3, 4, 1, 300), sell(11, 2, 5, 1, 150)},
+			method: MethodSpecific,
+			want:   []want{{2, 1, 200, 150}},
+		},
+		{
+			name:   "sale across lots",
+			orders: []types.Order{buy(1, 2, 1, 100), buy(2, 3, 1, 200), sell(11, 1, 4, 1.5, 300)},
+			method: MethodFIFO,
+			want:   []want{{1, 1, 100, 200}, {2, 0.5, 100, 100}},
+		},
+		{
+			name:   "sale before range consumes lot",
+			orders: []types.Order{buy(1, 1, 1, 100), buy(2, 1, 1, 200), sell(11, 1, 1, 1, 150), sell(12, 2, 3, 1, 250)},
+			method: MethodFIFO,
+			want:   []want{{2, 1, 200, 250}},
+		},
+		{
+			name:   "sale without lot",
+			orders: []types.Order{sell(11, 1, 3, 1, 150)},
+			method: MethodSpecific,
+			want:   []want{{0, 1, 0, 150}},
+		},
+		{
+			name:    "fees",
+			orders:  []types.Order{buy(1, 2, 1, 100), sell(11, 1, 3, 1, 200)},
+			method:  MethodSpecific,
+			feeRate: 0.001,
+			want:    []want{{1, 1, 100.1, 199.8}},
+		},
+		{
+			name:   "sale after range",
+			orders: []types.Order{buy(1, 2, 1, 100), sell(11, 1, 20, 1, 200)},
+			method: MethodFIFO,
+			want:   []want{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options, err := NewOptions("2021-01-02", "2021-01-10", tt.method, "", tt.feeRate)
+			if err != nil {
+				t.Fatalf("NewOptions() error = %v", err)
+			}
+			report := Match(tt.orders, options)
+			if len(report.Disposals) != len(tt.want) {
+				t.Fatalf("Match() = %+v, want %d disposals", report.Disposals, len(tt.want))
+			}
+			gain := 0.0
+			for i, w := range tt.want {
+				got := report.Disposals[i]
+				if got.AcquisitionOrderID != w.acquisitionOrderID ||
+					math.Abs(got.Quantity-w.quantity) > 1e-9 ||
+					math.Abs(got.CostBasis-w.costBasis) > 1e-9 ||
+					math.Abs(got.Proceeds-w.proceeds) > 1e-9 ||
+					math.Abs(got.Gain-(w.proceeds-w.costBasis)) > 1e-9 {
+					t.Errorf("Match() disposal %d = %+v, want %+v", i, got, w)
+				}
+				gain += w.proceeds - w.costBasis
+			}
+			if math.Abs(report.Gain-gain) > 1e-9 {
+				t.Errorf("Match() gain = %v, want %v", report.Gain, gain)
+			}
+		})
+	}
+}
+
+func TestMatch_totals(t *testing.T) {
+
+	options, err := NewOptions("2021-01-02", "2021-01-10", MethodFIFO, "", 0)
+	if err != nil {
+		t.Fatalf("NewOptions() error = %v", err)
+	}
+
+	/* Totals are exact, 0.1+0.1+0.1 is 0.30000000000000004 in float64 */
+	report := Match([]types.Order{buy(1, 2, 1, 0.1), buy(2, 2, 1, 0.1), buy(3, 2, 1, 0.1), sell(11, 1, 3, 3, 0.6)}, options)
+
+	if len(report.Disposals) != 3 || report.CostBasis != 0.3 || report.Proceeds != 0.6 || report.Gain != 0.3 {
+		t.Errorf("Match() = %+v, want a cost basis of 0.3, proceeds of 0.6 and a gain of 0.3", report)
+	}
+
+}
+
+func TestWrite(t *testing.T) {
+
+	options, _ := NewOptions("2021-01-01", "2021-12-31", MethodFIFO, "", 0)
+	report := Match([]types.Order{buy(1, 2, 1, 100), sell(11, 1, 3, 1, 150)}, options)
+
+	var csv bytes.Buffer
+	if err := Write(&csv, FormatCSV, report); err != nil {
+		t.Fatalf("Write() csv error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if len(lines) != 2 || lines[1] != "BTCUSDT,1,2021-01-02T12:00:00Z,2021-01-03T12:00:00Z,1,11,100.00000000,150.00000000,50.00000000" {
+		t.Errorf("Write() csv = %q", csv.String())
+	}
+
+	var got Report
+	var buffer bytes.Buffer
+	if err := Write(&buffer, FormatJSON, report); err != nil {
+		t.Fatalf("Write() json error = %v", err)
+	}
+	if err := json.Unmarshal(buffer.Bytes(), &got); err != nil || len(got.Disposals) != 1 || got.Gain != 50 || got.Method != MethodFIFO {
+		t.Errorf("Write() json = %s, %v", buffer.String(), err)
+	}
+
+	if err := Write(&buffer, "xlsx", report); err == nil {
+		t.Errorf("Write() xlsx error = nil, want error")
+	}
+}
@@ -1,14 +1,19 @@
 package telegram
 
 import (
+	"context"
 	"math"
 	"strconv"
-	"sync"
+	"strings"
 	"time"
 
+	"github.com/aleibovici/cryptopump/analytics"
+	"github.com/aleibovici/cryptopump/engine"
 	"github.com/aleibovici/cryptopump/functions"
 	"github.com/aleibovici/cryptopump/logger"
-	"github.com/aleibovici/cryptopump/mysql"
+	"github.com/aleibovici/cryptopump/nodes"
+	"github.com/aleibovici/cryptopump/pause"
+	"github.com/aleibovici/cryptopump/threads"
 	"github.com/aleibovici/cryptopump/types"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
@@ -67,11 +72,11 @@ func (Connect) Do(
 
 }
 
-// CheckUpdates Check for Telegram bot updates
+// CheckUpdates Check for Telegram bot updates. configData is a copy read by the Telegram goroutine, commands
+// change the thread on the owner goroutine e.
 func CheckUpdates(
 	configData *types.Config,
-	sessionData *types.Session,
-	wg *sync.WaitGroup) {
+	e *engine.Engine) {
 
 	var err error
 	var updates tgbotapi.UpdatesChannel
@@ -83,33 +88,93 @@ func CheckUpdates(
 
 	}
 
-	/* Sleep until Master Node is True */
-	for !sessionData.MasterNode {
+	for {
 
-		time.Sleep(30000 * time.Millisecond)
+		/* Sleep until Master Node is True, return on shutdown */
+		for !(nodes.Node{}).IsMaster(e.Session()) {
 
-	}
+			select {
+			case <-time.After(10000 * time.Millisecond):
+			case <-threads.Thread{}.Context(e.Session()).Done():
 
-	/* Establish connectivity to Telegram server */
-	Connect{}.Do(configData, sessionData)
+				return
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+			}
 
-	if updates, err = sessionData.TgBotAPI.GetUpdatesChan(u); err != nil {
+		}
 
-		logger.LogEntry{ /* Log Entry */
-			Config:   configData,
-			Market:   nil,
-			Session:  sessionData,
-			Order:    &types.Order{},
-			Message:  functions.GetFunctionName() + " - " + err.Error(),
-			LogLevel: "DebugLevel",
-		}.Do()
+		sessionData := e.Session()
+
+		/* Establish connectivity to Telegram server */
+		Connect{}.Do(configData, sessionData)
+
+		/* The owner goroutine sends alerts with the bot */
+		bot := sessionData.TgBotAPI
+		e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+			sessionData.TgBotAPI = bot
+		})
+
+		u := tgbotapi.NewUpdate(0)
+		u.Timeout = 60
+
+		if updates, err = bot.GetUpdatesChan(u); err != nil {
+
+			logger.LogEntry{ /* Log Entry */
+				Config:   configData,
+				Market:   nil,
+				Session:  sessionData,
+				Order:    &types.Order{},
+				Message:  functions.GetFunctionName() + " - " + err.Error(),
+				LogLevel: "DebugLevel",
+			}.Do()
+
+		}
+
+		receive(e, bot, updates)
+
+		/* Stop polling when Master Node is lost, the new Master Node polls Telegram */
+		bot.StopReceivingUpdates()
+
+		if (threads.Thread{}).Stopping(sessionData) {
+
+			return
+
+		}
 
 	}
 
-	for update := range updates {
+}
+
+/* Handle Telegram bot updates until Master Node is lost or the process shuts down */
+func receive(
+	e *engine.Engine,
+	bot *tgbotapi.BotAPI,
+	updates tgbotapi.UpdatesChannel) {
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+
+		var update tgbotapi.Update
+
+		select {
+		case <-ticker.C:
+
+			if !(nodes.Node{}).IsMaster(e.Session()) {
+
+				return
+
+			}
+
+			continue
+
+		case <-threads.Thread{}.Context(e.Session()).Done():
+
+			return
+
+		case update = <-updates:
+		}
 
 		/* ignore any non-Message Updates */
 		if update.Message == nil {
@@ -119,9 +184,69 @@ func CheckUpdates(
 		}
 
 		/* Store Telegram ChatID to allow the system to send direct messages to Telegram server */
-		sessionData.TgBotAPIChatID = update.Message.Chat.ID
+		chatID := update.Message.Chat.ID
+		e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+			sessionData.TgBotAPIChatID = chatID
+		})
+
+		/* Commands read a copy of the session */
+		sessionData := e.Session()
+		sessionData.TgBotAPI = bot
+		sessionData.TgBotAPIChatID = chatID
+
+		switch command, args := commandArgs(update.Message); command {
+		case "/pause", "/resume":
+
+			var pauseData types.Pause
+			var err error
+
+			threadID, mode, confirm := parsePause(args)
+			if threadID == "" {
+
+				threadID = sessionData.ThreadID
+
+			}
+
+			by := "telegram"
+			if update.Message.From != nil {
+
+				by += " @" + update.Message.From.UserName
+
+			}
+
+			text := "\f" + "Resumed @ " + threadID
+
+			if command == "/pause" {
+
+				pauseData, err = pause.Control{}.Pause(sessionData, threadID, mode, by, confirm)
+				text = "\f" + pause.Describe(pauseData) + " @ " + threadID
+
+			} else if _, err = (pause.Control{}).Resume(sessionData, threadID, by, confirm); err == pause.ErrConfirm {
+
+				text = "\f" + "Resume @ " + threadID + " requires confirmation, send /resume confirm"
+
+			}
+
+			if err != nil && err != pause.ErrConfirm {
+
+				text = "\f" + "Unable to " + command[1:] + " @ " + threadID + ": " + err.Error()
+
+			}
+
+			/* Enforce the pause of the session thread at once */
+			if err == nil && threadID == sessionData.ThreadID {
+
+				e.Do(func(configData *types.Config, marketData *types.Market, owner *types.Session) {
+					owner.Pause = sessionData.Pause
+				})
+
+			}
+
+			Message{
+				Text:             text,
+				ReplyToMessageID: update.Message.MessageID,
+			}.Send(sessionData)
 
-		switch update.Message.Text {
 		case "/sell":
 
 			Message{
@@ -129,7 +254,9 @@ func CheckUpdates(
 				ReplyToMessageID: update.Message.MessageID,
 			}.Send(sessionData)
 
-			sessionData.ForceSell = true
+			e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+				sessionData.ForceSell = true
+			})
 
 		case "/buy":
 
@@ -138,7 +265,9 @@ func CheckUpdates(
 				ReplyToMessageID: update.Message.MessageID,
 			}.Send(sessionData)
 
-			sessionData.ForceBuy = true
+			e.Do(func(configData *types.Config, marketData *types.Market, sessionData *types.Session) {
+				sessionData.ForceBuy = true
+			})
 
 		case "/report":
 
@@ -147,17 +276,22 @@ func CheckUpdates(
 			var profitPct float64
 			var threadCount int
 			var status string
+			var performance analytics.Performance
 			var err error
 
-			if profit, profitNet, profitPct, err = mysql.GetProfit(sessionData); err != nil {
+			if profit, profitNet, profitPct, err = sessionData.Storage.GetProfit(context.Background(), sessionData); err != nil {
+				return
+			}
+
+			if threadCount, err = sessionData.Storage.GetThreadCount(context.Background(), sessionData); err != nil {
 				return
 			}
 
-			if threadCount, err = mysql.GetThreadCount(sessionData); err != nil {
+			if performance, err = analytics.Get(sessionData, ""); err != nil {
 				return
 			}
 
-			if threadID, err := mysql.GetSessionStatus(sessionData); err == nil {
+			if threadID, err := sessionData.Storage.GetSessionStatus(context.Background(), sessionData); err == nil {
 
 				if threadID != "" {
 					status = "\f" + "System Fault @ " + threadID
@@ -175,18 +309,100 @@ func CheckUpdates(
 					"Net Profit: $" + functions.Float64ToStr(profitNet, 2) + "\n" +
 					"Net ROI: " + functions.Float64ToStr(getROI(profitNet, sessionData), 2) + "%" + "\n" +
 					"Avg. Transaction: " + functions.Float64ToStr(profitPct, 2) + "%" + "\n" +
+					"Max. Drawdown: $" + functions.Float64ToStr(performance.MaxDrawdown, 2) + "\n" +
+					"Win Rate: " + functions.Float64ToStr(performance.WinRate*100, 2) + "%" + "\n" +
+					"Avg. Win/Loss: $" + functions.Float64ToStr(performance.AvgWin, 2) + " / $" + functions.Float64ToStr(performance.AvgLoss, 2) + "\n" +
+					"Profit Factor: " + functions.Float64ToStr(performance.ProfitFactor, 2) + "\n" +
+					"Sharpe / Sortino: " + functions.Float64ToStr(performance.Sharpe, 2) + " / " + functions.Float64ToStr(performance.Sortino, 2) + "\n" +
+					"Avg. Hold Time: " + performance.HoldTime() + "\n" +
 					"Thread Count: " + strconv.Itoa(threadCount) + "\n" +
 					"Status: " + status + "\n" +
+					"Trading: " + pause.Describe(sessionData.Pause) + "\n" +
 					"Master: " + sessionData.ThreadID,
 				ReplyToMessageID: update.Message.MessageID,
 			}.Send(sessionData)
 
+		case "/nodes":
+
+			var cluster nodes.Cluster
+			var err error
+
+			if cluster, err = (nodes.Node{}).GetCluster(sessionData); err != nil {
+				return
+			}
+
+			text := "\f" + "Nodes: " + strconv.Itoa(cluster.Up) + " up, " + strconv.Itoa(cluster.Down) + " down"
+
+			for _, node := range cluster.Nodes {
+
+				status := "up"
+				if node.Down {
+					status = "DOWN"
+				}
+
+				if node.Master {
+					status += ", Master"
+				}
+
+				text += "\n" + node.ThreadID + " " + node.Symbol + " " + node.URL + " " + node.Version +
+					" (" + status + ", last seen " + node.LastSeen + " ago)"
+
+			}
+
+			Message{
+				Text:             text,
+				ReplyToMessageID: update.Message.MessageID,
+			}.Send(sessionData)
+
 		}
 
 	}
 
 }
 
+/* Return the command of a message, i.e. /pause, and its arguments */
+func commandArgs(message *tgbotapi.Message) (string, []string) {
+
+	fields := strings.Fields(message.Text)
+	if len(fields) == 0 {
+
+		return "", nil
+
+	}
+
+	return fields[0], fields[1:]
+
+}
+
+// Parse the arguments of /pause and /resume: an optional thread ID, the pause mode (nobuy by default) and
+// confirm. /pause confirm requires the resume to be confirmed, /resume confirm confirms it.
+func parsePause(args []string) (threadID string, mode string, confirm bool) {
+
+	mode = pause.ModeNoBuy
+
+	for _, arg := range args {
+
+		switch strings.ToLower(arg) {
+		case pause.ModeNoBuy, pause.ModeFrozen:
+
+			mode = strings.ToLower(arg)
+
+		case "confirm":
+
+			confirm = true
+
+		default:
+
+			threadID = arg
+
+		}
+
+	}
+
+	return threadID, mode, confirm
+
+}
+
 // getROI returns the ROI of a given profit
 func getROI(profit float64,
 	sessionData *types.Session) (ROI float64) {
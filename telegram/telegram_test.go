@@ -0,0 +1,29 @@
+package telegram
+
+import (
+	"testing"
+)
+
+func Test_parsePause(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		args         []string
+		wantThreadID string
+		wantMode     string
+		wantConfirm  bool
+	}{
+		{name: "default", args: nil, wantMode: "nobuy"},
+		{name: "frozen", args: []string{"FROZEN"}, wantMode: "frozen"},
+		{name: "confirm", args: []string{"nobuy", "confirm"}, wantMode: "nobuy", wantConfirm: true},
+		{name: "thread", args: []string{"c683ok5mk1u1120gnmmg", "frozen", "confirm"}, wantThreadID: "c683ok5mk1u1120gnmmg", wantMode: "frozen", wantConfirm: true},
+	}
+
+	for _, tt := range tests {
+		threadID, mode, confirm := parsePause(tt.args)
+		if threadID != tt.wantThreadID || mode != tt.wantMode || confirm != tt.wantConfirm {
+			t.Errorf("%s: parsePause() = %q, %q, %v, want %q, %q, %v", tt.name, threadID, mode, confirm, tt.wantThreadID, tt.wantMode, tt.wantConfirm)
+		}
+	}
+
+}
@@ -1,39 +1,135 @@
 package threads
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/aleibovici/cryptopump/functions"
 	"github.com/aleibovici/cryptopump/logger"
-	"github.com/aleibovici/cryptopump/mysql"
 	"github.com/aleibovici/cryptopump/nodes"
 	"github.com/aleibovici/cryptopump/types"
 )
 
+// Process exit codes
+const (
+	ExitOK      = 0 /* Stopped from the web UI, by SIGINT/SIGTERM or once the exit option is met, or a command completed */
+	ExitError   = 1 /* Fatal error, the thread is stopped cleanly and can be resumed */
+	ExitUsage   = 2 /* Invalid command line */
+	ExitRunning = 3 /* The thread runs on another node or process, its state is left untouched */
+	ExitStartup = 4 /* Configuration, database or schema migration failure at startup */
+)
+
+/* Maximum time Shutdown waits for the owner goroutine to complete an order in flight */
+const busyTimeout = 60 * time.Second
+
+// ErrRunning is returned when the thread is locked by another process or its lease is held by another node
+var ErrRunning = errors.New("thread is running on another node or process")
+
+/* Serialize shutdown requests, the first one sets the exit code */
+var terminate sync.Mutex
+
 // Thread locking control
 type Thread struct{}
 
-// Terminate thread
-func (Thread) Terminate(sessionData *types.Session, message string) {
+// Terminate request a graceful shutdown of the process with the exit code. It cancels the root context and
+// returns, main then runs Shutdown. Only the first request sets the exit code. It can be called from any
+// goroutine with a copy of the session.
+func (Thread) Terminate(sessionData *types.Session, code int, message string) {
 
 	if message != "" {
 
+		level := "InfoLevel"
+		if code == ExitError {
+
+			level = "DebugLevel"
+
+		}
+
 		logger.LogEntry{ /* Log Entry */
 			Config:   nil,
 			Market:   nil,
 			Session:  sessionData,
 			Order:    &types.Order{},
 			Message:  message,
-			LogLevel: "DebugLevel",
+			LogLevel: level,
 		}.Do()
 
 	}
 
-	/* Verify wether buying/selling to allow graceful session exit */
-	for sessionData.Busy {
+	terminate.Lock()
+	defer terminate.Unlock()
+
+	if (Thread{}).Stopping(sessionData) {
+
+		return
+
+	}
+
+	if sessionData.ExitCode != nil {
+
+		*sessionData.ExitCode = code
+
+	}
+
+	if sessionData.Cancel != nil {
 
-		time.Sleep(time.Millisecond * 200)
+		sessionData.Cancel()
+
+	}
+
+}
+
+// Context return the root context of the process, cancelled by Terminate
+func (Thread) Context(sessionData *types.Session) context.Context {
+
+	if sessionData.Ctx == nil {
+
+		return context.Background()
+
+	}
+
+	return sessionData.Ctx
+
+}
+
+// Stopping return true once a shutdown of the process is requested. New orders are not placed and orders in
+// flight are brought to a safe state.
+func (Thread) Stopping(sessionData *types.Session) bool {
+
+	return Thread{}.Context(sessionData).Err() != nil
+
+}
+
+// Shutdown wait for the owner goroutine of the thread to stop (stopped is closed), so the order in flight is
+// completed, release the Master Node role, unlock the thread, remove it from the cluster node registry and
+// delete its session, and return the process exit code. A thread running on another node or process
+// (ExitRunning) is left untouched.
+func (Thread) Shutdown(sessionData *types.Session, stopped <-chan struct{}) int {
+
+	select {
+	case <-stopped:
+	case <-time.After(busyTimeout):
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  nil,
+			Order:    &types.Order{},
+			Message:  "Shutdown with an order in flight, it is completed when the thread resumes",
+			LogLevel: "InfoLevel",
+		}.Do()
+
+	}
+
+	code := Thread{}.ExitCode(sessionData)
+
+	if code == ExitRunning {
+
+		return code
 
 	}
 
@@ -44,11 +140,21 @@ func (Thread) Terminate(sessionData *types.Session, message string) {
 
 	}
 
+	/* No thread was started */
+	if sessionData.ThreadID == "" {
+
+		return code
+
+	}
+
 	// Unlock existing thread
 	Thread{}.Unlock(sessionData)
 
+	/* Remove node from the cluster node registry */
+	_ = nodes.Node{}.Unregister(sessionData)
+
 	/* Delete session from Session table */
-	if err := mysql.DeleteSession(sessionData); err != nil {
+	if err := sessionData.Storage.DeleteSession(context.Background(), sessionData); err != nil {
 
 		logger.LogEntry{ /* Log Entry */
 			Config:   nil,
@@ -72,7 +178,23 @@ func (Thread) Terminate(sessionData *types.Session, message string) {
 
 	}
 
-	os.Exit(1)
+	return code
+
+}
+
+// ExitCode return the exit code of the first shutdown request, ExitOK when none was requested
+func (Thread) ExitCode(sessionData *types.Session) int {
+
+	terminate.Lock()
+	defer terminate.Unlock()
+
+	if sessionData.ExitCode == nil {
+
+		return ExitOK
+
+	}
+
+	return *sessionData.ExitCode
 
 }
 
@@ -111,6 +233,53 @@ func (Thread) Lock(sessionData *types.Session) bool {
 
 } // //// // ExitThreadID Cleanly exit a Thread
 
+// Acquire take the lease of the session thread and lock its thread file, or return ErrRunning when another node
+// holds the lease or another process locks the file. The lease of a thread taken over from a down node is
+// authoritative, so the lock file left by the down node is replaced.
+func (Thread) Acquire(sessionData *types.Session, takeover bool) error {
+
+	/* Another node may run the thread, its lease expires 60 seconds after its last heartbeat */
+	acquired, err := nodes.Node{}.AcquireThread(sessionData)
+	if err != nil {
+
+		return err
+
+	}
+
+	if !acquired {
+
+		return fmt.Errorf("thread %s: %w", sessionData.ThreadID, ErrRunning)
+
+	}
+
+	if _, err := os.Stat(sessionData.ThreadID + ".lock"); err == nil && takeover {
+
+		logger.LogEntry{ /* Log Entry */
+			Config:   nil,
+			Market:   nil,
+			Session:  sessionData,
+			Order:    &types.Order{},
+			Message:  "FAILOVER - stale lock file of thread " + sessionData.ThreadID + " replaced",
+			LogLevel: "InfoLevel",
+		}.Do()
+
+		Thread{}.Unlock(sessionData)
+
+	}
+
+	if !(Thread{}.Lock(sessionData)) { /* Lock thread file */
+
+		/* Another process runs the thread, release the lease taken above instead of holding it until it expires */
+		_ = nodes.Node{}.ReleaseThread(sessionData)
+
+		return fmt.Errorf("thread %s: %w", sessionData.ThreadID, ErrRunning)
+
+	}
+
+	return nil
+
+}
+
 // Unlock existing thread
 func (Thread) Unlock(sessionData *types.Session) {
 
@@ -1,8 +1,15 @@
 package threads
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/aleibovici/cryptopump/migrations"
+	"github.com/aleibovici/cryptopump/sqlite"
 	"github.com/aleibovici/cryptopump/types"
 )
 
@@ -85,3 +92,144 @@ func TestThread_Unlock(t *testing.T) {
 		})
 	}
 }
+
+func TestThread_Terminate(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sessionData := &types.Session{Ctx: ctx, Cancel: cancel, ExitCode: new(int)}
+
+	if (Thread{}).Stopping(&types.Session{}) || (Thread{}).Stopping(sessionData) {
+		t.Fatalf("Stopping() = true before Terminate(), want false")
+	}
+
+	tests := []struct {
+		name string
+		code int
+	}{
+		{name: "first request", code: ExitRunning},
+		{name: "later request", code: ExitError},
+	}
+
+	for _, tt := range tests {
+		/* Goroutines other than the owner terminate with a copy of the session */
+		other := *sessionData
+		Thread{}.Terminate(&other, tt.code, "")
+
+		if !(Thread{}).Stopping(sessionData) || (Thread{}).ExitCode(sessionData) != ExitRunning {
+			t.Errorf("%s: Terminate() Stopping() = %v, ExitCode() = %v, want true, %v", tt.name, Thread{}.Stopping(sessionData), Thread{}.ExitCode(sessionData), ExitRunning)
+		}
+	}
+
+}
+
+func TestThread_Shutdown(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		sessionData *types.Session
+		busy        bool
+		want        int
+	}{
+		{
+			name:        "no thread",
+			sessionData: &types.Session{},
+			want:        ExitOK,
+		},
+		{
+			/* Storage is not set, the thread state must be left untouched */
+			name:        "running on another node",
+			sessionData: &types.Session{ThreadID: "c2q3mt84a8024t1f6590", ExitCode: exitCode(ExitRunning)},
+			want:        ExitRunning,
+		},
+		{
+			name:        "order in flight",
+			sessionData: &types.Session{ExitCode: exitCode(ExitError)},
+			busy:        true,
+			want:        ExitError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stopped := make(chan struct{})
+			start := time.Now()
+
+			if tt.busy {
+				time.AfterFunc(300*time.Millisecond, func() { close(stopped) })
+			} else {
+				close(stopped)
+			}
+
+			if got := (Thread{}).Shutdown(tt.sessionData, stopped); got != tt.want {
+				t.Errorf("Thread.Shutdown() = %v, want %v", got, tt.want)
+			}
+
+			if tt.busy && time.Since(start) < 300*time.Millisecond {
+				t.Errorf("Thread.Shutdown() returned before the order in flight completed")
+			}
+		})
+	}
+
+}
+
+/* Return a shared exit code */
+func exitCode(code int) *int {
+
+	return &code
+
+}
+
+func TestThread_Acquire(t *testing.T) {
+
+	db, err := sqlite.DBInit(filepath.Join(t.TempDir(), "cryptopump.db"))
+	if err != nil {
+		t.Fatalf("DBInit() error = %v", err)
+	}
+	defer db.Close()
+
+	if _, err := (migrations.Migrator{Db: db, Backend: "sqlite"}).Up(); err != nil {
+		t.Fatalf("Migrator.Up() error = %v", err)
+	}
+
+	/* Lock files are created in the working directory */
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	down := &types.Session{Db: db, Storage: sqlite.Storage{}, ThreadID: "c683ok5mk1u1120gnmmg", Port: "8080"}
+	standby := &types.Session{Db: db, Storage: sqlite.Storage{}, ThreadID: down.ThreadID, Port: "8081"}
+
+	if err := (Thread{}).Acquire(down, false); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	/* The lease is held by the other node */
+	if err := (Thread{}).Acquire(standby, true); !errors.Is(err, ErrRunning) {
+		t.Errorf("Acquire() with the lease held error = %v, want %v", err, ErrRunning)
+	}
+
+	/* The down node leaves its lock file behind and its lease expires */
+	if _, err := db.Exec(`UPDATE leases SET Expires = 0`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (Thread{}).Acquire(standby, false); !errors.Is(err, ErrRunning) {
+		t.Errorf("Acquire() with the thread locked error = %v, want %v", err, ErrRunning)
+	}
+
+	var expires int64
+	if err := db.QueryRow(`SELECT Expires FROM leases WHERE Name = ?`, "thread:"+standby.ThreadID).Scan(&expires); err != nil || expires != 0 {
+		t.Errorf("lease expires = %v, %v, want the lease released", expires, err)
+	}
+
+	if err := (Thread{}).Acquire(standby, true); err != nil {
+		t.Errorf("Acquire() of a thread taken over error = %v, want the stale lock file replaced", err)
+	}
+
+	if _, err := os.Stat(standby.ThreadID + ".lock"); err != nil {
+		t.Errorf("Stat() error = %v, want the thread locked", err)
+	}
+
+}
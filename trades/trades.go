@@ -0,0 +1,317 @@
+package trades
+
+/* This package implements the trades ledger. A trade is written in the same database transaction
+that removes the sold Thread transaction, recording the round trip from BUY to SELL with entry,
+exit, holding time, fees and exit reason. Reports are built from the ledger instead of re-deriving
+profit from the orders table. */
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/aleibovici/cryptopump/money"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+/* Exit reasons recorded in the trades ledger */
+const (
+	ExitProfit   = "profit"   /* Profit sale */
+	ExitStoploss = "stoploss" /* Stoploss sale */
+	ExitCover    = "cover"    /* Sell-to-Cover sale */
+	ExitForce    = "force"    /* Force sell from the web UI or Telegram */
+)
+
+/* Default and maximum number of trades returned by a query */
+const (
+	defaultLimit = 500
+	maxLimit     = 10000
+)
+
+/* Export formats */
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Report struct define a trades ledger query and its totals
+type Report struct {
+	Filter    types.TradeFilter `json:"-"`
+	Query     url.Values        `json:"-"` /* Query parameters used to fill the web UI filter form */
+	Rows      []Row             `json:"-"`
+	Trades    []types.Trade     `json:"trades"`
+	Count     int               `json:"count"`
+	Wins      int               `json:"wins"`
+	Losses    int               `json:"losses"`
+	Profit    float64           `json:"profit"`
+	Fees      float64           `json:"fees"`
+	ProfitPct float64           `json:"profitPct"` /* Average trade profit ratio */
+}
+
+// Row struct define a trade formatted for the web UI
+type Row struct {
+	types.Trade
+	Entry   string /* EntryTime as YYYY-MM-DD hh:mm:ss UTC */
+	Exit    string /* ExitTime as YYYY-MM-DD hh:mm:ss UTC */
+	Holding string /* HoldingTime as duration, i.e. 1h2m3s */
+}
+
+// New return the trade closing the buy Thread transaction with the filled sale
+func New(
+	buy types.Order,
+	sale *types.Order,
+	configData *types.Config,
+	sessionData *types.Session) *types.Trade {
+
+	trade := &types.Trade{
+		ThreadID:        sessionData.ThreadID,
+		ThreadIDSession: sessionData.ThreadIDSession,
+		Symbol:          sessionData.Symbol,
+		BuyOrderID:      buy.OrderID,
+		SellOrderID:     sale.OrderID,
+		Quantity:        sale.ExecutedQuantity,
+		EntryPrice:      buy.Price,
+		ExitPrice:       sale.Price,
+		EntryQuote:      buy.CumulativeQuoteQuantity,
+		ExitQuote:       sale.CumulativeQuoteQuantity,
+		EntryTime:       buy.TransactTime,
+		ExitTime:        time.Now().UnixNano() / int64(time.Millisecond),
+		ExitReason:      sessionData.ExitReason,
+	}
+
+	quantity := money.New(trade.Quantity)
+	entryQuote := money.New(trade.EntryQuote)
+	exitQuote := money.New(trade.ExitQuote)
+
+	/* Market orders report no price, use the average fill price */
+	if trade.Quantity > 0 {
+
+		trade.ExitPrice = exitQuote.Div(quantity).Float()
+
+	}
+
+	/* Lot step rounding may sell less than the BUY quantity, the cost is prorated to the quantity sold */
+	if buy.ExecutedQuantity > 0 && trade.Quantity < buy.ExecutedQuantity {
+
+		entryQuote = entryQuote.Mul(quantity).Div(money.New(buy.ExecutedQuantity)).Round(money.Scale)
+		trade.EntryQuote = entryQuote.Float()
+
+	}
+
+	if trade.ExitReason == "" {
+
+		trade.ExitReason = ExitProfit
+
+	}
+
+	/* Profit is computed from the rounded fees, so the ledger columns add up */
+	fees := entryQuote.Add(exitQuote).Mul(money.New(configData.ExchangeComission)).Round(money.Scale)
+	profit := exitQuote.Sub(entryQuote).Sub(fees)
+
+	trade.Fees = fees.Float()
+	trade.Profit = profit.Float()
+	trade.ProfitPct = profit.Div(entryQuote).Float()
+
+	if trade.EntryTime > 0 {
+
+		trade.HoldingTime = (trade.ExitTime - trade.EntryTime) / 1000
+
+	}
+
+	return trade
+
+}
+
+// ParseFilter parse a trades ledger query from URL parameters thread, from, to (YYYY-MM-DD, UTC),
+// reason and limit. The to date is inclusive.
+func ParseFilter(values url.Values) (filter types.TradeFilter, err error) {
+
+	var t time.Time
+
+	filter.ThreadID = values.Get("thread")
+	filter.Limit = defaultLimit
+
+	if v := values.Get("from"); v != "" {
+
+		if t, err = time.Parse("2006-01-02", v); err != nil {
+
+			return filter, errors.New("invalid from date " + strconv.Quote(v))
+
+		}
+
+		filter.From = t.UnixNano() / int64(time.Millisecond)
+
+	}
+
+	if v := values.Get("to"); v != "" {
+
+		if t, err = time.Parse("2006-01-02", v); err != nil {
+
+			return filter, errors.New("invalid to date " + strconv.Quote(v))
+
+		}
+
+		filter.To = t.AddDate(0, 0, 1).UnixNano() / int64(time.Millisecond)
+
+	}
+
+	switch v := values.Get("reason"); v {
+	case "", ExitProfit, ExitStoploss, ExitCover, ExitForce:
+
+		filter.ExitReason = v
+
+	default:
+
+		return filter, errors.New("invalid exit reason " + strconv.Quote(v))
+
+	}
+
+	if v := values.Get("limit"); v != "" {
+
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit <= 0 || filter.Limit > maxLimit {
+
+			return filter, errors.New("invalid limit " + strconv.Quote(v))
+
+		}
+
+	}
+
+	return filter, nil
+
+}
+
+// GetReport query the trades ledger and compute the report totals
+func GetReport(
+	sessionData *types.Session,
+	filter types.TradeFilter) (report Report, err error) {
+
+	report.Filter = filter
+
+	if report.Trades, err = sessionData.Storage.GetTrades(context.Background(), sessionData, filter); err != nil {
+
+		return report, err
+
+	}
+
+	if report.Trades == nil { /* Encode an empty ledger as [] */
+
+		report.Trades = []types.Trade{}
+
+	}
+
+	var profit, fees, profitPct money.Decimal /* Summed exactly, the totals are converted once */
+
+	for _, trade := range report.Trades {
+
+		report.Rows = append(report.Rows, Row{
+			Trade:   trade,
+			Entry:   formatTime(trade.EntryTime),
+			Exit:    formatTime(trade.ExitTime),
+			Holding: (time.Duration(trade.HoldingTime) * time.Second).String(),
+		})
+
+		report.Count++
+		profit = profit.Add(money.New(trade.Profit))
+		fees = fees.Add(money.New(trade.Fees))
+		profitPct = profitPct.Add(money.New(trade.ProfitPct))
+
+		if trade.Profit > 0 {
+
+			report.Wins++
+
+		} else {
+
+			report.Losses++
+
+		}
+
+	}
+
+	report.Profit = profit.Float()
+	report.Fees = fees.Float()
+	report.ProfitPct = profitPct.Div(money.New(float64(report.Count))).Float() /* 0 without trades */
+
+	return report, nil
+
+}
+
+// Write write the trades of report to w as csv, one trade per row, or as json with the report totals
+func Write(
+	w io.Writer,
+	format string,
+	report Report) error {
+
+	switch format {
+	case FormatJSON:
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(report)
+
+	case FormatCSV:
+
+		writer := csv.NewWriter(w)
+
+		if err := writer.Write([]string{"ThreadID", "ThreadIDSession", "Symbol", "BuyOrderID", "SellOrderID", "Quantity", "EntryPrice", "ExitPrice",
+			"EntryQuote", "ExitQuote", "Fees", "Profit", "ProfitPct", "Entry", "Exit", "HoldingTime", "ExitReason"}); err != nil {
+
+			return err
+
+		}
+
+		for _, trade := range report.Trades {
+
+			if err := writer.Write([]string{
+				trade.ThreadID,
+				trade.ThreadIDSession,
+				trade.Symbol,
+				strconv.Itoa(trade.BuyOrderID),
+				strconv.Itoa(trade.SellOrderID),
+				strconv.FormatFloat(trade.Quantity, 'f', -1, 64),
+				strconv.FormatFloat(trade.EntryPrice, 'f', -1, 64),
+				strconv.FormatFloat(trade.ExitPrice, 'f', -1, 64),
+				strconv.FormatFloat(trade.EntryQuote, 'f', 8, 64),
+				strconv.FormatFloat(trade.ExitQuote, 'f', 8, 64),
+				strconv.FormatFloat(trade.Fees, 'f', 8, 64),
+				strconv.FormatFloat(trade.Profit, 'f', 8, 64),
+				strconv.FormatFloat(trade.ProfitPct, 'f', 6, 64),
+				formatTime(trade.EntryTime),
+				formatTime(trade.ExitTime),
+				strconv.FormatInt(trade.HoldingTime, 10),
+				trade.ExitReason,
+			}); err != nil {
+
+				return err
+
+			}
+
+		}
+
+		writer.Flush()
+
+		return writer.Error()
+
+	}
+
+	return errors.New("invalid format " + strconv.Quote(format) + ", use csv or json")
+
+}
+
+/* Format a time in milliseconds as YYYY-MM-DD hh:mm:ss UTC */
+func formatTime(msec int64) string {
+
+	if msec == 0 {
+
+		return ""
+
+	}
+
+	return time.Unix(0, msec*int64(time.Millisecond)).UTC().Format("2006-01-02 15:04:05")
+
+}
@@ -0,0 +1,238 @@
+package trades
+
+import (
+	"context"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aleibovici/cryptopump/migrations"
+	"github.com/aleibovici/cryptopump/sqlite"
+	"github.com/aleibovici/cryptopump/types"
+)
+
+func TestNew(t *testing.T) {
+
+	configData := &types.Config{ExchangeComission: 0.001}
+	sessionData := &types.Session{ThreadID: "c683ok5mk1u1120gnmmg", Symbol: "BTCUSDT"}
+
+	tests := []struct {
+		name          string
+		buy           types.Order
+		sale          types.Order
+		exitReason    string
+		fee           float64 /* exchange_comission, 0.001 when 0 */
+		wantExitPrice float64
+		wantFees      float64
+		wantProfit    float64
+		wantReason    string
+	}{
+		{
+			name:          "profit",
+			buy:           types.Order{OrderID: 1, Price: 100, CumulativeQuoteQuantity: 100, ExecutedQuantity: 1},
+			sale:          types.Order{OrderID: 1001, Price: 110, CumulativeQuoteQuantity: 110, ExecutedQuantity: 1},
+			exitReason:    ExitProfit,
+			wantExitPrice: 110,
+			wantFees:      0.21,
+			wantProfit:    9.79,
+			wantReason:    ExitProfit,
+		},
+		{
+			name:          "market order average fill price",
+			buy:           types.Order{OrderID: 1, Price: 100, CumulativeQuoteQuantity: 200, ExecutedQuantity: 2},
+			sale:          types.Order{OrderID: 1001, CumulativeQuoteQuantity: 180, ExecutedQuantity: 2},
+			exitReason:    ExitStoploss,
+			wantExitPrice: 90,
+			wantFees:      0.38,
+			wantProfit:    -20.38,
+			wantReason:    ExitStoploss,
+		},
+		{
+			name:          "lot step remainder",
+			buy:           types.Order{OrderID: 1, Price: 100, CumulativeQuoteQuantity: 100, ExecutedQuantity: 1},
+			sale:          types.Order{OrderID: 1001, CumulativeQuoteQuantity: 55, ExecutedQuantity: 0.5},
+			wantExitPrice: 110,
+			wantFees:      0.105,
+			wantProfit:    4.895,
+			wantReason:    ExitProfit,
+		},
+		{
+			name:          "rounded to 8 decimal places",
+			buy:           types.Order{OrderID: 1, Price: 50061.72839, CumulativeQuoteQuantity: 100.12345678, ExecutedQuantity: 0.002},
+			sale:          types.Order{OrderID: 1001, CumulativeQuoteQuantity: 110.98765432, ExecutedQuantity: 0.002},
+			fee:           0.00075,
+			wantExitPrice: 55493.82716,
+			wantFees:      0.15833333,
+			wantProfit:    10.70586421,
+			wantReason:    ExitProfit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessionData.ExitReason = tt.exitReason
+			configData.ExchangeComission = 0.001
+			if tt.fee != 0 {
+				configData.ExchangeComission = tt.fee
+			}
+			got := New(tt.buy, &tt.sale, configData, sessionData)
+			if got.ExitPrice != tt.wantExitPrice ||
+				got.Fees != tt.wantFees ||
+				got.Profit != tt.wantProfit {
+				t.Errorf("New() exit price, fees, profit = %v, %v, %v, want %v, %v, %v", got.ExitPrice, got.Fees, got.Profit, tt.wantExitPrice, tt.wantFees, tt.wantProfit)
+			}
+			if got.ExitReason != tt.wantReason || got.BuyOrderID != tt.buy.OrderID || got.SellOrderID != tt.sale.OrderID {
+				t.Errorf("New() = %+v, want %v trade of BUY %v and SELL %v", got, tt.wantReason, tt.buy.OrderID, tt.sale.OrderID)
+			}
+		})
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		query   string
+		want    types.TradeFilter
+		wantErr bool
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			want:  types.TradeFilter{Limit: defaultLimit},
+		},
+		{
+			name:  "all parameters",
+			query: "thread=a&from=2021-01-01&to=2021-01-01&reason=stoploss&limit=10",
+			want:  types.TradeFilter{ThreadID: "a", From: 1609459200000, To: 1609545600000, ExitReason: ExitStoploss, Limit: 10},
+		},
+		{
+			name:    "invalid date",
+			query:   "from=01/01/2021",
+			wantErr: true,
+		},
+		{
+			name:    "invalid reason",
+			query:   "reason=unknown",
+			wantErr: true,
+		},
+		{
+			name:    "invalid limit",
+			query:   "limit=0",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			got, err := ParseFilter(values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseFilter() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetReport(t *testing.T) {
+
+	db, err := sqlite.DBInit(filepath.Join(t.TempDir(), "cryptopump.db"))
+	if err != nil {
+		t.Fatalf("DBInit() error = %v", err)
+	}
+	defer db.Close()
+
+	if _, err := (migrations.Migrator{Db: db, Backend: "sqlite"}).Up(); err != nil {
+		t.Fatalf("Migrator.Up() error = %v", err)
+	}
+
+	sessionData := &types.Session{Db: db, Storage: sqlite.Storage{}}
+
+	if err := sessionData.Storage.OrderTx(context.Background(), sessionData, func(tx types.OrderTx) error {
+		for i, profit := range []float64{10, -4, 2} {
+			if err := tx.SaveTrade(&types.Trade{SellOrderID: i + 1, Profit: profit, Fees: 0.1, ProfitPct: profit / 100, ExitTime: int64(i + 1), ExitReason: ExitProfit}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("OrderTx() error = %v", err)
+	}
+
+	report, err := GetReport(sessionData, types.TradeFilter{Limit: defaultLimit})
+	if err != nil {
+		t.Fatalf("GetReport() error = %v", err)
+	}
+
+	/* Totals are exact, 0.1+0.1+0.1 is 0.30000000000000004 in float64 */
+	if report.Count != 3 || report.Wins != 2 || report.Losses != 1 || len(report.Rows) != 3 ||
+		report.Profit != 8 || report.Fees != 0.3 || report.ProfitPct != 0.02666667 {
+		t.Errorf("GetReport() = %+v", report)
+	}
+
+	if report, err = GetReport(sessionData, types.TradeFilter{ThreadID: "none", Limit: defaultLimit}); err != nil || report.Trades == nil || report.Count != 0 {
+		t.Errorf("GetReport() empty ledger = %v, %v, want no trades", report.Trades, err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+
+	report := Report{Trades: []types.Trade{{
+		ThreadID:    "c683ok5mk1u1120gnmmg",
+		Symbol:      "BTCUSDT",
+		BuyOrderID:  1,
+		SellOrderID: 2,
+		Quantity:    0.5,
+		EntryQuote:  100,
+		ExitQuote:   110,
+		Profit:      9.8,
+		ExitTime:    86400000,
+		HoldingTime: 60,
+		ExitReason:  ExitProfit,
+	}}, Count: 1}
+
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "csv",
+			format: FormatCSV,
+			want: "ThreadID,ThreadIDSession,Symbol,BuyOrderID,SellOrderID,Quantity,EntryPrice,ExitPrice,EntryQuote,ExitQuote,Fees,Profit,ProfitPct,Entry,Exit,HoldingTime,ExitReason\n" +
+				"c683ok5mk1u1120gnmmg,,BTCUSDT,1,2,0.5,0,0,100.00000000,110.00000000,0.00000000,9.80000000,0.000000,,1970-01-02 00:00:00,60,profit\n",
+		},
+		{
+			name:   "json",
+			format: FormatJSON,
+			want:   `"count": 1`,
+		},
+		{
+			name:    "invalid format",
+			format:  "xml",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			var b strings.Builder
+
+			if err := Write(&b, tt.format, report); (err != nil) != tt.wantErr {
+				t.Fatalf("Write() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !strings.Contains(b.String(), tt.want) {
+				t.Errorf("Write() = %q, want %q", b.String(), tt.want)
+			}
+
+		})
+	}
+
+}
@@ -1,10 +1,15 @@
 package types
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"sync"
 	"time"
 
 	"github.com/adshao/go-binance/v2"
+	"github.com/aleibovici/cryptopump/rules"
+	"github.com/aleibovici/cryptopump/schedule"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	"github.com/paulbellamy/ratecounter"
 	"github.com/sdcoffey/techan"
@@ -68,6 +73,7 @@ type ExchangeInfo struct {
 	MaxQuantity string `json:"maxQty"`
 	MinQuantity string `json:"minQty"`
 	StepSize    string `json:"stepSize"`
+	Status      string `json:"status"` /* Symbol trading status, i.e. TRADING */
 }
 
 // Session struct define session elements
@@ -91,13 +97,17 @@ type Session struct {
 	ForceSellOrderID        int                      /* This variable stores the OrderID of ForceSell */
 	ListenKey               string                   /* Listen key for user stream service */
 	MasterNode              bool                     /* This boolean is true when Master Node is elected */
+	MasterUntil             time.Time                /* Local time until which the Master Node lease is held */
+	Lease                   Lease                    /* Master Node lease at the last heartbeat */
+	ThreadLease             Lease                    /* Thread lease held by the node, its token fences order writes */
 	TgBotAPI                *tgbotapi.BotAPI         /* This variable holds Telegram session bot */
 	TgBotAPIChatID          int64                    /* This variable holds Telegram chat ID */
-	Db                      *sql.DB                  /* mySQL database connection */
+	Db                      *sql.DB                  /* Database connection */
+	Storage                 Storage                  /* Storage backend (mysql or sqlite) */
 	Clients                 Client                   /* Binance client connection */
 	KlineData               []KlineData              /* kline data format for go-echart plotter */
-	StopWs                  bool                     /* Control when to stop Ws Channels */
-	Busy                    bool                     /* Control wether buy/selling to allow graceful session exit */
+	Pause                   Pause                    /* Trading pause of the thread, reloaded every 10 seconds */
+	Schedule                schedule.Status          /* Trading schedule behavior at the last decision */
 	MinQuantity             float64                  /* Defines the minimum quantity allowed by exchange */
 	MaxQuantity             float64                  /* Defines the maximum quantity allowed by exchange */
 	StepSize                float64                  /* Defines the intervals that a quantity can be increased/decreased by exchange */
@@ -106,11 +116,239 @@ type Session struct {
 	RateCounter             *ratecounter.RateCounter /* Average Number of transactions per second proccessed by WsBookTicker */
 	BuyDecisionTreeResult   string                   /* Hold BuyDecisionTree result for web UI */
 	SellDecisionTreeResult  string                   /* Hold SellDecisionTree result for web UI */
+	ExitReason              string                   /* Hold the SellDecisionTree exit reason recorded in the trades ledger (profit, stoploss, cover, force) */
 	QuantityOffsetFlag      bool                     /* This flag is true when the quantity is offset */
 	DiffTotal               float64                  /* This variable holds the difference between the total funds and the total funds in the last session */
 	Global                  *Global
-	Admin                   bool   /* This flag is true when the admin page is selected */
-	Port                    string /* This variable holds the port number for the web server */
+	Risk                    Risk               /* Risk manager state */
+	Guard                   Guard              /* Pre-trade guard state */
+	Fault                   *Fault             /* Order filled on the exchange and not saved, trading stops until it is saved */
+	SymbolStatus            string             /* Symbol trading status reported by the exchange, i.e. TRADING */
+	Admin                   bool               /* This flag is true when the admin page is selected */
+	Port                    string             /* This variable holds the port number for the web server */
+	Version                 string             /* CryptoPump version, set at build time */
+	Started                 time.Time          /* Time the thread started, saved in the cluster node registry */
+	Ctx                     context.Context    /* Root context, cancelled when the process shuts down */
+	Cancel                  context.CancelFunc /* Cancel the root context, called by threads.Thread{}.Terminate */
+	ExitCode                *int               /* Process exit code of the first shutdown request, shared by copies of the session */
+}
+
+// Storage interface for storage backends. Implementations read and write orders, thread transactions,
+// sessions and global data through sessionData.Db, running every statement with ctx.
+type Storage interface {
+
+	/* Orders */
+	SaveOrder(ctx context.Context, sessionData *Session, order *Order, orderIDSource int64, orderPrice float64) error
+	UpdateOrder(ctx context.Context, sessionData *Session, OrderID int64, CumulativeQuoteQuantity float64, ExecutedQuantity float64, Price float64, Status string) error
+	GetOrderByOrderID(ctx context.Context, sessionData *Session) (Order, error)
+	GetOrderSymbol(ctx context.Context, sessionData *Session) (string, error)
+	GetOrderTransactionPending(ctx context.Context, sessionData *Session) (Order, error)
+	GetOrderTransactionCount(ctx context.Context, sessionData *Session, side string) (float64, error)
+	GetOrderTransactionSideLastTwo(ctx context.Context, sessionData *Session) (string, string, error)
+	GetLastOrderTransactionPrice(ctx context.Context, sessionData *Session, Side string) (float64, error)
+	GetLastOrderTransactionSide(ctx context.Context, sessionData *Session) (string, error)
+	GetOrderTransactionExecuted(ctx context.Context, sessionData *Session, before int64) ([]Order, error)
+	GetOrders(ctx context.Context, sessionData *Session, filter OrderFilter) ([]Order, error)
+	ArchiveOrders(ctx context.Context, sessionData *Session, before int64) (int64, error)
+
+	/* Thread transactions */
+	SaveThreadTransaction(ctx context.Context, sessionData *Session, OrderID int64, CumulativeQuoteQuantity float64, Price float64, ExecutedQuantity float64) error
+	DeleteThreadTransactionByOrderID(ctx context.Context, sessionData *Session, orderID int) error
+	GetThreadTransactionCount(ctx context.Context, sessionData *Session) (int, error)
+	GetThreadTransactionDistinct(ctx context.Context, sessionData *Session) (string, string, error)
+	GetThreadTransactionByPrice(ctx context.Context, marketData *Market, sessionData *Session) (Order, error)
+	GetThreadTransactionByPriceHigher(ctx context.Context, marketData *Market, sessionData *Session) (Order, error)
+	GetThreadTransactionByThreadID(ctx context.Context, sessionData *Session) ([]Order, error)
+	GetThreadTransactionAll(ctx context.Context, sessionData *Session) ([]Order, error)
+	GetThreadTransactiontUpmarketPriceCount(ctx context.Context, sessionData *Session, price float64) (int, error)
+	GetThreadLastTransaction(ctx context.Context, sessionData *Session) (Order, error)
+	GetThreadCount(ctx context.Context, sessionData *Session) (int, error)
+	GetThreadAmount(ctx context.Context, sessionData *Session) (float64, error)
+	GetThreadTransactionMissing(ctx context.Context, sessionData *Session) ([]Order, error)
+	GetThreadTransactionSold(ctx context.Context, sessionData *Session) ([]Order, error)
+
+	/* Trades ledger */
+	GetTrades(ctx context.Context, sessionData *Session, filter TradeFilter) ([]Trade, error)
+	GetTradeStats(ctx context.Context, sessionData *Session, threadID string) (TradeStats, error)
+
+	/* Daily equity snapshots */
+	SaveEquity(ctx context.Context, sessionData *Session, equity Equity) error
+	GetEquity(ctx context.Context, sessionData *Session, threadID string) ([]Equity, error)
+
+	/* Leader election. AcquireLease takes name for holder when it is free or expired, or renews it when holder
+	already holds it, for duration milliseconds. It returns the lease after the call, held by holder or not. */
+	AcquireLease(ctx context.Context, sessionData *Session, name string, holder string, duration int64) (Lease, error)
+	ReleaseLease(ctx context.Context, sessionData *Session, name string, holder string) error
+
+	/* Cluster node registry. SaveNode saves node with a new heartbeat, GetNodes returns the nodes most recently started first. */
+	SaveNode(ctx context.Context, sessionData *Session, node Node) error
+	GetNodes(ctx context.Context, sessionData *Session) ([]Node, error)
+	DeleteNode(ctx context.Context, sessionData *Session, threadID string) error
+
+	/* Failover. GetThreadOrphans returns the threads with Thread transactions whose node registry heartbeat is
+	older than age milliseconds, longest down first. */
+	GetThreadOrphans(ctx context.Context, sessionData *Session, age int64) ([]Orphan, error)
+
+	/* Audit log. GetAudit returns the records saved after since milliseconds, oldest first. */
+	SaveAudit(ctx context.Context, sessionData *Session, audit Audit) error
+	GetAudit(ctx context.Context, sessionData *Session, since int64) ([]Audit, error)
+
+	/* Trading pause. GetPause returns an empty Pause when the thread is not paused. */
+	SavePause(ctx context.Context, sessionData *Session, pause Pause) error
+	GetPause(ctx context.Context, sessionData *Session, threadID string) (Pause, error)
+	DeletePause(ctx context.Context, sessionData *Session, threadID string) error
+
+	/* Atomic order persistence. f runs in one database transaction committed when f returns nil and rolled back otherwise.
+	When the session holds the thread lease, the transaction returns ErrFenced if the lease token changed. */
+	OrderTx(ctx context.Context, sessionData *Session, f func(tx OrderTx) error) error
+
+	/* Sessions */
+	SaveSession(ctx context.Context, configData *Config, sessionData *Session) error
+	UpdateSession(ctx context.Context, configData *Config, sessionData *Session) error
+	DeleteSession(ctx context.Context, sessionData *Session) error
+	GetSessionStatus(ctx context.Context, sessionData *Session) (string, error)
+	GetSessions(ctx context.Context, sessionData *Session) ([]SessionRecord, error)
+
+	/* Global and profit */
+	SaveGlobal(ctx context.Context, sessionData *Session) error
+	UpdateGlobal(ctx context.Context, sessionData *Session) error
+	GetGlobal(ctx context.Context, sessionData *Session) (float64, float64, float64, int64, error)
+	GetProfit(ctx context.Context, sessionData *Session) (float64, float64, float64, error)
+	GetProfitByThreadID(ctx context.Context, sessionData *Session) (float64, float64, error)
+	GetProfitByThreadIDSince(ctx context.Context, sessionData *Session, transactTime int64) (float64, error)
+}
+
+// Degrader storage backend with a degraded mode (see retry.Storage). BUY is paused while Degraded returns true.
+type Degrader interface {
+	Degraded() bool
+}
+
+// OrderTx order and thread transaction writes running in one database transaction (see Storage.OrderTx)
+type OrderTx interface {
+	SaveOrder(order *Order, orderIDSource int64, orderPrice float64) error
+	UpdateOrder(OrderID int64, CumulativeQuoteQuantity float64, ExecutedQuantity float64, Price float64, Status string) error
+	SaveThreadTransaction(OrderID int64, CumulativeQuoteQuantity float64, Price float64, ExecutedQuantity float64) error
+	DeleteThreadTransactionByOrderID(orderID int) error
+	SaveTrade(trade *Trade) error
+}
+
+// Trade struct define a closed round trip (BUY and the SELL that closed it) in the trades ledger
+type Trade struct {
+	ThreadID        string  `json:"threadId"`
+	ThreadIDSession string  `json:"threadIdSession"`
+	Symbol          string  `json:"symbol"`
+	BuyOrderID      int     `json:"buyOrderId"`
+	SellOrderID     int     `json:"sellOrderId"`
+	Quantity        float64 `json:"quantity"`
+	EntryPrice      float64 `json:"entryPrice"`
+	ExitPrice       float64 `json:"exitPrice"`
+	EntryQuote      float64 `json:"entryQuote"`  /* Fiat paid for the BUY */
+	ExitQuote       float64 `json:"exitQuote"`   /* Fiat received for the SELL */
+	Fees            float64 `json:"fees"`        /* Estimated from exchange_comission on both legs */
+	Profit          float64 `json:"profit"`      /* Realized profit net of fees */
+	ProfitPct       float64 `json:"profitPct"`   /* Profit as ratio of EntryQuote */
+	EntryTime       int64   `json:"entryTime"`   /* BUY TransactTime in milliseconds */
+	ExitTime        int64   `json:"exitTime"`    /* SELL close time in milliseconds */
+	HoldingTime     int64   `json:"holdingTime"` /* Seconds between EntryTime and ExitTime */
+	ExitReason      string  `json:"exitReason"`
+}
+
+// TradeStats struct define trades ledger totals for a thread or all threads
+type TradeStats struct {
+	Count       int
+	Wins        int     /* Trades with profit above zero */
+	GrossProfit float64 /* Sum of winning trades profit */
+	GrossLoss   float64 /* Sum of losing trades loss as a positive value */
+	HoldingTime float64 /* Average holding time in seconds */
+}
+
+// Equity struct define a daily equity snapshot. The last snapshot of a day is the daily close.
+type Equity struct {
+	ThreadID   string  `json:"threadId"`   /* Empty for the snapshot across all threads */
+	Date       string  `json:"date"`       /* YYYY-MM-DD UTC */
+	Realized   float64 `json:"realized"`   /* Realized profit */
+	Unrealized float64 `json:"unrealized"` /* Difference between open orders cost and market value */
+	Equity     float64 `json:"equity"`     /* Realized plus unrealized profit */
+}
+
+// ErrFenced is returned by Storage.OrderTx when the thread lease held by the session changed hands
+var ErrFenced = errors.New("thread lease held by another node")
+
+// Lease struct define a leader election lease (see Storage.AcquireLease)
+type Lease struct {
+	Name    string `json:"name"`    /* Lease name, i.e. master */
+	Holder  string `json:"holder"`  /* ThreadID holding the lease */
+	Token   int64  `json:"token"`   /* Fencing token, incremented each time the lease changes hands */
+	Expires int64  `json:"expires"` /* Expiry time in milliseconds of the database clock */
+}
+
+// Node struct define a running thread in the cluster node registry
+type Node struct {
+	ThreadID  string `json:"threadID"`
+	Hostname  string `json:"hostname"`
+	Port      string `json:"port"`    /* Web UI port */
+	Version   string `json:"version"` /* CryptoPump version */
+	Symbol    string `json:"symbol"`
+	Started   int64  `json:"started"`   /* Thread start time in milliseconds */
+	Heartbeat int64  `json:"heartbeat"` /* Last heartbeat in milliseconds of the database clock */
+	Age       int64  `json:"age"`       /* Milliseconds since the last heartbeat on the database clock */
+}
+
+// Orphan struct define a thread with Thread transactions whose node stopped sending heartbeats
+type Orphan struct {
+	ThreadID        string
+	ThreadIDSession string
+	Hostname        string /* Host of the down node */
+	Port            string /* Web UI port of the down node */
+	Age             int64  /* Milliseconds since the last heartbeat on the database clock */
+}
+
+// Audit struct define an audit log record
+type Audit struct {
+	ID       int64  `json:"id"`
+	Time     int64  `json:"time"`     /* Time in milliseconds */
+	ThreadID string `json:"threadID"` /* Thread the action applies to */
+	Node     string `json:"node"`     /* Node taking the action as hostname:port */
+	Action   string `json:"action"`   /* Action, i.e. takeover */
+	Detail   string `json:"detail"`
+}
+
+// Pause struct define the trading pause of a thread. Mode is empty when the thread is not paused.
+type Pause struct {
+	ThreadID string `json:"threadID"`
+	Mode     string `json:"mode"`    /* nobuy or frozen */
+	By       string `json:"by"`      /* Who paused the thread, i.e. telegram @username */
+	Time     int64  `json:"time"`    /* Pause time in milliseconds */
+	Confirm  bool   `json:"confirm"` /* The resume requires confirmation */
+}
+
+// OrderFilter define the orders query, most recent orders first. Empty strings match all orders.
+type OrderFilter struct {
+	ThreadID string
+	Side     string /* BUY or SELL */
+	Status   string /* Order status, i.e. FILLED */
+	Limit    int
+}
+
+// SessionRecord struct define a thread of the session table with its open Thread transactions
+type SessionRecord struct {
+	ThreadID        string  `json:"threadID"`
+	ThreadIDSession string  `json:"threadIDSession"`
+	Exchange        string  `json:"exchange"`
+	SymbolFiat      string  `json:"symbolFiat"`
+	SymbolFiatFunds float64 `json:"symbolFiatFunds"`
+	DiffTotal       float64 `json:"diffTotal"`
+	Status          bool    `json:"status"`      /* System status Good (false) or Bad (true) */
+	ThreadCount     int     `json:"threadCount"` /* Open Thread transactions */
+}
+
+// TradeFilter define the trades ledger query. Empty strings and zero values match all trades.
+type TradeFilter struct {
+	ThreadID   string
+	From       int64 /* ExitTime lower bound in milliseconds, inclusive */
+	To         int64 /* ExitTime upper bound in milliseconds, exclusive */
+	ExitReason string
+	Limit      int /* Zero or less return all trades */
 }
 
 // Global (Session.Global) struct store semi-persistent values to help offload mySQL queries load
@@ -125,6 +363,32 @@ type Global struct {
 	DiffTotal         float64 /* /* This variable holds the difference between purchase price and current value across all sessions */
 }
 
+// Risk (Session.Risk) struct store the risk manager state
+type Risk struct {
+	Reason         string    /* Risk rule pausing BUY, empty when buying is allowed */
+	StopOutCount   int       /* Number of consecutive stoploss sales */
+	CooldownUntil  time.Time /* BUY is paused until this time after risk_stopout_count stoploss sales */
+	DailyLoss      float64   /* Realized loss for the current day */
+	DailyLossTime  time.Time /* Time of the last realized loss refresh */
+	DailyLossStart time.Time /* Start of the day used for realized loss */
+}
+
+// Guard (Session.Guard) struct store the pre-trade guard state
+type Guard struct {
+	Reason    string         /* Guard blocking orders, empty when orders are allowed */
+	Count     map[string]int /* Number of times each guard blocked orders */
+	LastBlock time.Time      /* Time orders were last blocked */
+}
+
+// Fault (Session.Fault) struct store an order filled on the exchange that couldn't be saved to the database.
+// BUY and SELL decisions stop until Write is retried successfully.
+type Fault struct {
+	OrderID int                    /* Exchange order ID */
+	Reason  string                 /* Error of the failed write */
+	Write   func(tx OrderTx) error /* Database transaction saving the order */
+	Retried time.Time              /* Time Write last failed */
+}
+
 // Client struct for client libraries
 type Client struct {
 	Binance *binance.Client
@@ -132,9 +396,24 @@ type Client struct {
 
 // WsHandler struct for websocket handlers for exchanges
 type WsHandler struct {
-	BinanceWsKline         func(event *binance.WsKlineEvent)      /* WsKlineServe serve websocket kline handler */
-	BinanceWsBookTicker    func(event *binance.WsBookTickerEvent) /* WsBookTicker serve websocket kline handler */
-	BinanceWsUserDataServe func(message []byte)                   /* WsUserDataServe serve user data handler with listen key */
+	BinanceWsKline         func(event *binance.WsKlineEvent)        /* WsKlineServe serve websocket kline handler */
+	BinanceWsBookTicker    func(event *binance.WsBookTickerEvent)   /* WsBookTicker serve websocket kline handler */
+	BinanceWsUserDataServe func(message []byte)                     /* WsUserDataServe serve user data handler with listen key */
+	BinanceWsPartialDepth  func(event *binance.WsPartialDepthEvent) /* WsPartialDepthServe serve websocket partial depth handler */
+}
+
+// BookLevel struct define an order book price level
+type BookLevel struct {
+	Price    float64
+	Quantity float64
+}
+
+// OrderBook struct define the local order book maintained from the partial depth stream
+type OrderBook struct {
+	Bids         []BookLevel /* Bids sorted from best (highest) price */
+	Asks         []BookLevel /* Asks sorted from best (lowest) price */
+	LastUpdateID int64       /* Exchange update ID of the last snapshot */
+	Time         time.Time   /* Time of the last snapshot */
 }
 
 // KlineData struct define kline retention for e-charts plotting
@@ -160,6 +439,13 @@ type Market struct {
 	Series                    *techan.TimeSeries /* kline data format for technical analysis */
 	Ma7                       float64            /* Simple Moving Average for 7 periods */
 	Ma14                      float64            /* Simple Moving Average for 14 periods */
+	Volatility                float64            /* Average True Range as a ratio of price for adaptive_period periods */
+	BestBid                   float64            /* Best bid price */
+	BestAsk                   float64            /* Best ask price */
+	KlineClose                float64            /* Close price of the last kline update */
+	KlineCloseTime            time.Time          /* Time of the last kline update */
+	Book                      OrderBook          /* Local order book */
+	BookImbalance             float64            /* Bid/ask quantity imbalance from -1 (asks) to 1 (bids) */
 }
 
 // Config struct for configuration
@@ -184,6 +470,25 @@ type Config struct {
 	SellToCover                            bool    /* Define if will sell to cover low funds */
 	SellHoldOnRSI3                         float64 /* Hold sale if RSI3 above defined threshold */
 	Stoploss                               float64 /* Loss as ratio that should trigger a sale */
+	Adaptive                               bool    /* Profit and buy repeat threshold down follow market volatility */
+	AdaptivePeriod                         int     /* Number of candles used to calculate volatility */
+	AdaptiveProfitMultiplier               float64 /* Profit as a multiple of volatility */
+	AdaptiveProfitMin                      float64 /* Profit floor in adaptive mode */
+	AdaptiveProfitMax                      float64 /* Profit ceiling in adaptive mode (0 for no ceiling) */
+	AdaptiveThresholdDownMultiplier        float64 /* Buy repeat threshold down as a multiple of volatility */
+	AdaptiveThresholdDownMin               float64 /* Buy repeat threshold down floor in adaptive mode */
+	AdaptiveThresholdDownMax               float64 /* Buy repeat threshold down ceiling in adaptive mode (0 for no ceiling) */
+	RiskMaxPositions                       int     /* Maximum open thread transactions (0 to disable) */
+	RiskMaxDeployed                        float64 /* Maximum fiat deployed across all threads (0 to disable) */
+	RiskMaxDailyLoss                       float64 /* Maximum realized fiat loss per day for the thread (0 to disable) */
+	RiskStopOutCount                       int     /* Number of consecutive stoploss sales that trigger a cooldown (0 to disable) */
+	RiskStopOutCooldown                    int     /* Cooldown in minutes after risk_stopout_count stoploss sales */
+	GuardMaxSpread                         float64 /* Maximum bid/ask spread as a ratio of price (0 to disable) */
+	GuardMaxJump                           float64 /* Maximum price change from the last kline close as a ratio (0 to disable) */
+	GuardMaxLatency                        int64   /* Maximum exchange latency in milliseconds (0 to disable) */
+	GuardMaxWsAge                          int     /* Maximum seconds since the last kline websocket update (0 to disable) */
+	BuyMaxSlippage                         float64 /* Maximum expected BUY slippage in basis points (0 to disable) */
+	BuySlippageSplit                       bool    /* Split BUY above buy_max_slippage into several orders instead of capping it */
 	SymbolFiat                             string
 	SymbolFiatStash                        float64
 	Symbol                                 string
@@ -192,12 +497,22 @@ type Config struct {
 	TimeStop                               string
 	Debug                                  bool
 	Exit                                   bool
-	DryRun                                 bool        /* Dry Run mode */
-	NewSession                             bool        /* Force a new session instead of resume */
-	ConfigTemplateList                     interface{} /* List of configuration templates available in ./config folder */
-	ExchangeName                           string      /* Exchange name */
-	TestNet                                bool        /* Use Exchange TestNet */
-	HTMLSnippet                            interface{} /* Store kline plotter graph for html output */
+	DryRun                                 bool               /* Dry Run mode */
+	NewSession                             bool               /* Force a new session instead of resume */
+	ConfigTemplateList                     interface{}        /* List of configuration templates available in ./config folder */
+	ExchangeName                           string             /* Exchange name */
+	TestNet                                bool               /* Use Exchange TestNet */
+	HTMLSnippet                            interface{}        /* Store kline plotter graph for html output */
+	BuyRules                               string             /* Rule expression that must be true to BUY */
+	SellRules                              string             /* Rule expression that must be true for a profit SELL */
+	BuyRulesExpression                     *rules.Expression  /* Parsed BuyRules */
+	SellRulesExpression                    *rules.Expression  /* Parsed SellRules */
+	RulesError                             string             /* Rule expressions parse error for html output */
+	Schedule                               string             /* Trading windows, weekdays, time zone and blackout dates (see schedule package) */
+	ScheduleSpec                           *schedule.Schedule /* Parsed Schedule, or time_start and time_stop when time_enforce is true */
+	ScheduleError                          string             /* Schedule parse error for html output */
+	CSRF                                   string             /* CSRF token of the web UI session for html output */
+	User                                   string             /* Signed in user and role for html output, empty when authentication is disabled */
 	ConfigGlobal                           *ConfigGlobal
 }
 
@@ -263,6 +578,7 @@ type ExecutionReport struct {
 
 // ViperData struct for Viper configuration files
 type ViperData struct {
-	V1 *viper.Viper `json:"v1"` /* Session configurations file */
-	V2 *viper.Viper `json:"v2"` /* Global configurations file */
+	V1    *viper.Viper `json:"v1"` /* Session configurations file */
+	V2    *viper.Viper `json:"v2"` /* Global configurations file */
+	Mutex sync.Mutex   `json:"-"`  /* Serialize access to V1 and V2, viper is not safe for concurrent use */
 }